@@ -116,6 +116,52 @@ func (cs *CronService) LogCurrentStatus() {
 	}
 }
 
+// PurgeEventLog ลบเหตุการณ์ใน outbox ที่ส่งครบแล้วหรือเลิกส่งแล้ว และรายการ inbox ที่เก่าเกินระยะที่ยังอาจถูกส่งซ้ำ
+func (cs *CronService) PurgeEventLog() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// ตารางถูกสร้างโดยบริการที่ใช้ outbox; ยังไม่มีก็ไม่มีอะไรให้ลบ
+	var exists bool
+	if err := cs.db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, cs.schema+".event_outbox").Scan(&exists); err != nil {
+		log.Printf("❌ Error checking event_outbox: %v", err)
+		return
+	}
+	if !exists {
+		return
+	}
+
+	var dead int
+	deadQuery := fmt.Sprintf(`SELECT COUNT(*) FROM %s.event_outbox WHERE dead_at IS NOT NULL`, cs.schema)
+	if err := cs.db.QueryRowContext(ctx, deadQuery).Scan(&dead); err != nil {
+		log.Printf("❌ Error counting undelivered events: %v", err)
+	} else if dead > 0 {
+		log.Printf("⚠️  %d events could not be delivered and were given up", dead)
+	}
+
+	outboxQuery := fmt.Sprintf(`
+		DELETE FROM %s.event_outbox
+		WHERE dispatched_at < NOW() - INTERVAL '14 days'
+		   OR dead_at < NOW() - INTERVAL '30 days'
+	`, cs.schema)
+	result, err := cs.db.ExecContext(ctx, outboxQuery)
+	if err != nil {
+		log.Printf("❌ Error purging event_outbox: %v", err)
+		return
+	}
+	outboxRows, _ := result.RowsAffected()
+
+	// kept longer than any outbox row, so a late redelivery is still recognised as a duplicate
+	inboxQuery := fmt.Sprintf(`DELETE FROM %s.event_inbox WHERE processed_at < NOW() - INTERVAL '45 days'`, cs.schema)
+	result, err = cs.db.ExecContext(ctx, inboxQuery)
+	if err != nil {
+		log.Printf("❌ Error purging event_inbox: %v", err)
+		return
+	}
+	inboxRows, _ := result.RowsAffected()
+	log.Printf("✅ Purged %d outbox events and %d inbox entries", outboxRows, inboxRows)
+}
+
 func main() {
 	// Database connection
 	dbHost := getEnv("DB_HOST", "localhost")
@@ -160,6 +206,12 @@ func main() {
 		log.Fatalf("❌ Failed to schedule status logging: %v", err)
 	}
 
+	// Schedule event log cleanup daily at 01:00 UTC
+	_, err = c.AddFunc("0 1 * * *", cronService.PurgeEventLog)
+	if err != nil {
+		log.Fatalf("❌ Failed to schedule event log cleanup: %v", err)
+	}
+
 	// Run initial update and status check
 	log.Println("🚀 Running initial update...")
 	cronService.UpdateUserDaysRemaining()
@@ -170,6 +222,7 @@ func main() {
 	log.Println("✅ Cron service started successfully")
 	log.Println("📅 Scheduled daily update at midnight UTC (7 AM Thailand time)")
 	log.Println("📅 Scheduled status logging every 6 hours")
+	log.Println("📅 Scheduled event log cleanup daily at 01:00 UTC")
 
 	// Wait for interrupt signal
 	sigChan := make(chan os.Signal, 1)
@@ -9,8 +9,12 @@ import (
 	"syscall"
 	"time"
 
+	"nurseshift/department-service/internal/infrastructure/access"
+	"nurseshift/department-service/internal/infrastructure/audit"
 	"nurseshift/department-service/internal/infrastructure/config"
 	"nurseshift/department-service/internal/infrastructure/database"
+	"nurseshift/department-service/internal/infrastructure/entitlements"
+	"nurseshift/department-service/internal/infrastructure/services"
 	"nurseshift/department-service/internal/interfaces/http/handlers"
 	"nurseshift/department-service/internal/interfaces/http/middleware"
 
@@ -21,6 +25,9 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
+// maxStaffImportRows caps a single staff import file
+const maxStaffImportRows = 1000
+
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -63,7 +70,11 @@ func main() {
 	}
 
 	// Initialize handlers
-	deptHandler := handlers.NewDepartmentHandler(deptRepo)
+	staffImport := services.NewStaffImportService(maxStaffImportRows)
+	checker := entitlements.NewChecker(dbConn.DB, "nurse_shift")
+	auditRepo := audit.NewRepository(dbConn.DB, "nurse_shift")
+	guard := access.NewGuard(access.NewRepository(dbConn.DB, "nurse_shift"))
+	deptHandler := handlers.NewDepartmentHandler(deptRepo, staffImport, checker, guard)
 
 	// Routes
 	api := app.Group("/api/v1")
@@ -71,6 +82,8 @@ func main() {
 	// Protected routes (authentication required)
 	departments := api.Group("/departments")
 	departments.Use(middleware.AuthMiddleware(""))
+	departments.Use(middleware.SubscriptionMiddleware(checker))
+	departments.Use(audit.Middleware(auditRepo, "department"))
 	{
 		departments.Get("/", deptHandler.GetDepartments)
 		departments.Post("/", deptHandler.CreateDepartment)
@@ -80,7 +93,11 @@ func main() {
 		departments.Delete("/:id", deptHandler.DeleteDepartment)
 		departments.Get("/:id/staff", deptHandler.GetDepartmentStaff)
 		departments.Post("/:id/staff", deptHandler.AddDepartmentStaff)
+		departments.Post("/:id/staff/import", deptHandler.ImportDepartmentStaff)
 		departments.Delete("/:id/staff/:staffId", deptHandler.DeleteDepartmentStaff)
+		departments.Get("/:id/members", deptHandler.GetDepartmentMembers)
+		departments.Put("/:id/members/:userId", deptHandler.SetDepartmentMember)
+		departments.Delete("/:id/members/:userId", deptHandler.RemoveDepartmentMember)
 	}
 
 	// Health check
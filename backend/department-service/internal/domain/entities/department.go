@@ -44,6 +44,14 @@ type DepartmentUser struct {
 	AssignedBy     *uuid.UUID `json:"assigned_by" db:"assigned_by"`
 }
 
+// DepartmentMember represents a user assigned to a department, with the user's name
+type DepartmentMember struct {
+	DepartmentUser
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+}
+
 // User basic info for department context
 type User struct {
 	ID         uuid.UUID `json:"id"`
@@ -60,10 +68,11 @@ type User struct {
 // DepartmentWithStats represents department with statistics
 type DepartmentWithStats struct {
 	*Department
-	TotalEmployees  int `json:"total_employees"`
-	ActiveEmployees int `json:"active_employees"`
-	NurseCount      int `json:"nurse_count"`
-	AssistantCount  int `json:"assistant_count"`
+	TotalEmployees  int    `json:"total_employees"`
+	ActiveEmployees int    `json:"active_employees"`
+	NurseCount      int    `json:"nurse_count"`
+	AssistantCount  int    `json:"assistant_count"`
+	DepartmentRole  string `json:"department_role"` // the caller's role in the department
 }
 
 // DepartmentDetail represents detailed department information
@@ -104,6 +113,35 @@ type UpdateDepartmentRequest struct {
 	IsActive      *bool      `json:"is_active"`
 }
 
+// StaffImportRow represents one data row of a staff import file after validation
+type StaffImportRow struct {
+	Row       int      `json:"row"` // 1-based line/row number in the file, header included
+	Name      string   `json:"name"`
+	Position  string   `json:"position"`
+	Phone     string   `json:"phone,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+	Duplicate string   `json:"duplicate,omitempty"` // why the row duplicates another row or existing staff
+	Skipped   bool     `json:"skipped,omitempty"`
+}
+
+// IsValid reports whether the row can be imported
+func (r *StaffImportRow) IsValid() bool {
+	return len(r.Errors) == 0 && r.Duplicate == ""
+}
+
+// StaffImportResult represents the outcome of a staff import (or dry run)
+type StaffImportResult struct {
+	DryRun   bool               `json:"dry_run"`
+	Total    int                `json:"total"`
+	Valid    int                `json:"valid"`
+	Invalid  int                `json:"invalid"`
+	Skipped  int                `json:"skipped"`
+	Imported int                `json:"imported"`
+	Rows     []*StaffImportRow  `json:"rows"`
+	Created  []*DepartmentStaff `json:"created,omitempty"`
+}
+
 // Methods
 
 // GetDisplayName returns formatted department name
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"nurseshift/department-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// GetMembers retrieves the users assigned to a department with their department roles
+func (r *PostgresDepartmentRepository) GetMembers(ctx context.Context, departmentID uuid.UUID) ([]*entities.DepartmentMember, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			du.id, du.department_id, du.user_id, du.department_role, du.assigned_at, du.assigned_by,
+			u.first_name, u.last_name, u.email
+		FROM %s.department_users du
+		JOIN %s.users u ON u.id = du.user_id
+		WHERE du.department_id = $1
+		ORDER BY du.department_role, u.first_name, u.last_name
+	`, r.schema, r.schema)
+
+	rows, err := r.db.QueryContext(ctx, query, departmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query department members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*entities.DepartmentMember{}
+	for rows.Next() {
+		var m entities.DepartmentMember
+		err := rows.Scan(
+			&m.ID, &m.DepartmentID, &m.UserID, &m.DepartmentRole, &m.AssignedAt, &m.AssignedBy,
+			&m.FirstName, &m.LastName, &m.Email,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan department member: %w", err)
+		}
+		members = append(members, &m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating department members: %w", err)
+	}
+
+	return members, nil
+}
+
+// UpsertMember assigns a user to a department, or changes the role of an existing assignment
+func (r *PostgresDepartmentRepository) UpsertMember(ctx context.Context, member *entities.DepartmentUser) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s.department_users (department_id, user_id, department_role, assigned_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (department_id, user_id) DO UPDATE
+		SET department_role = EXCLUDED.department_role, assigned_by = EXCLUDED.assigned_by, assigned_at = NOW()
+		RETURNING id, assigned_at
+	`, r.schema)
+
+	err := r.db.QueryRowContext(ctx, query,
+		member.DepartmentID, member.UserID, member.DepartmentRole, member.AssignedBy,
+	).Scan(&member.ID, &member.AssignedAt)
+	if err != nil {
+		return fmt.Errorf("failed to assign department member: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMember removes a user from a department
+func (r *PostgresDepartmentRepository) DeleteMember(ctx context.Context, departmentID, userID uuid.UUID) error {
+	query := fmt.Sprintf(`
+		DELETE FROM %s.department_users
+		WHERE department_id = $1 AND user_id = $2
+	`, r.schema)
+
+	result, err := r.db.ExecContext(ctx, query, departmentID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove department member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("department member not found")
+	}
+
+	return nil
+}
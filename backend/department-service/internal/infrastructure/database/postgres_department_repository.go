@@ -21,7 +21,11 @@ type DepartmentRepository interface {
 	GetWithStats(ctx context.Context, userID uuid.UUID) ([]*entities.DepartmentWithStats, error)
 	GetStaff(ctx context.Context, departmentID uuid.UUID) ([]*entities.DepartmentStaff, error)
 	CreateStaff(ctx context.Context, staff *entities.DepartmentStaff) error
+	BulkCreateStaff(ctx context.Context, staff []*entities.DepartmentStaff) error
 	DeleteStaff(ctx context.Context, staffID, departmentID uuid.UUID) error
+	GetMembers(ctx context.Context, departmentID uuid.UUID) ([]*entities.DepartmentMember, error)
+	UpsertMember(ctx context.Context, member *entities.DepartmentUser) error
+	DeleteMember(ctx context.Context, departmentID, userID uuid.UUID) error
 }
 
 // PostgresDepartmentRepository implements DepartmentRepository
@@ -186,8 +190,11 @@ func (r *PostgresDepartmentRepository) GetWithStats(ctx context.Context, userID
 			d.settings, d.is_active, d.created_by, d.created_at, d.updated_at,
 			COALESCE(staff_stats.total_employees, 0) as total_employees,
 			COALESCE(staff_stats.nurse_count, 0) as nurse_count,
-			COALESCE(staff_stats.assistant_count, 0) as assistant_count
+			COALESCE(staff_stats.assistant_count, 0) as assistant_count,
+			CASE WHEN d.created_by = $1 OR d.head_user_id = $1 THEN 'head_nurse'
+			     ELSE COALESCE(du.department_role::text, '') END as department_role
 		FROM %s.departments d
+		LEFT JOIN %s.department_users du ON du.department_id = d.id AND du.user_id = $1
 		LEFT JOIN (
 			SELECT 
 				ds.department_id,
@@ -198,9 +205,9 @@ func (r *PostgresDepartmentRepository) GetWithStats(ctx context.Context, userID
 			WHERE ds.is_active = true
 			GROUP BY ds.department_id
 		) staff_stats ON d.id = staff_stats.department_id
-		WHERE d.created_by = $1 AND d.is_active = true
+		WHERE (d.created_by = $1 OR d.head_user_id = $1 OR du.user_id IS NOT NULL) AND d.is_active = true
 		ORDER BY d.created_at DESC
-	`, r.schema, r.schema)
+	`, r.schema, r.schema, r.schema)
 
 	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -212,12 +219,13 @@ func (r *PostgresDepartmentRepository) GetWithStats(ctx context.Context, userID
 	for rows.Next() {
 		var dept entities.Department
 		var totalEmployees, nurseCount, assistantCount int
+		var departmentRole string
 
 		err := rows.Scan(
 			&dept.ID, &dept.Name, &dept.Description, &dept.HeadUserID,
 			&dept.MaxNurses, &dept.MaxAssistants, &dept.Settings, &dept.IsActive,
 			&dept.CreatedBy, &dept.CreatedAt, &dept.UpdatedAt,
-			&totalEmployees, &nurseCount, &assistantCount,
+			&totalEmployees, &nurseCount, &assistantCount, &departmentRole,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan department with stats: %w", err)
@@ -229,6 +237,7 @@ func (r *PostgresDepartmentRepository) GetWithStats(ctx context.Context, userID
 			ActiveEmployees: totalEmployees, // Assuming all are active for now
 			NurseCount:      nurseCount,
 			AssistantCount:  assistantCount,
+			DepartmentRole:  departmentRole,
 		}
 
 		departments = append(departments, deptWithStats)
@@ -299,6 +308,43 @@ func (r *PostgresDepartmentRepository) CreateStaff(ctx context.Context, staff *e
 	return nil
 }
 
+// BulkCreateStaff inserts many staff members in a single transaction; nothing is written if any insert fails
+func (r *PostgresDepartmentRepository) BulkCreateStaff(ctx context.Context, staff []*entities.DepartmentStaff) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s.department_staff (
+			id, department_id, name, position, phone, email,
+			is_active, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, r.schema)
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare staff insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, s := range staff {
+		if _, err := stmt.ExecContext(ctx,
+			s.ID, s.DepartmentID, s.Name, s.Position,
+			s.Phone, s.Email, s.IsActive, s.CreatedAt, s.UpdatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to create department staff %q: %w", s.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit staff import: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteStaff deletes a staff member from a department
 func (r *PostgresDepartmentRepository) DeleteStaff(ctx context.Context, staffID, departmentID uuid.UUID) error {
 	query := fmt.Sprintf(`
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"nurseshift/department-service/internal/domain/entities"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	// ErrUnsupportedImportFile is returned for files that are neither CSV nor XLSX
+	ErrUnsupportedImportFile = errors.New("unsupported file type, use .csv or .xlsx")
+	// ErrImportMissingColumns is returned when the header row lacks the name or position column
+	ErrImportMissingColumns = errors.New("header row must contain name and position columns")
+	// ErrImportEmpty is returned when the file has no data rows
+	ErrImportEmpty = errors.New("file has no data rows")
+)
+
+// StaffImportService interface for bulk staff import operations
+type StaffImportService interface {
+	Parse(filename string, data []byte) ([]*entities.StaffImportRow, error)
+	Validate(rows []*entities.StaffImportRow, existing []*entities.DepartmentStaff, skipDuplicates bool)
+}
+
+// StaffImportServiceImpl implements the StaffImportService interface
+type StaffImportServiceImpl struct {
+	maxRows int
+}
+
+// NewStaffImportService creates a new staff import service accepting up to maxRows data rows
+func NewStaffImportService(maxRows int) StaffImportService {
+	return &StaffImportServiceImpl{
+		maxRows: maxRows,
+	}
+}
+
+// headerAliases maps accepted header labels (English and Thai) to import columns
+var headerAliases = map[string]string{
+	"name":         "name",
+	"full_name":    "name",
+	"fullname":     "name",
+	"ชื่อ":         "name",
+	"ชื่อ-สกุล":    "name",
+	"ชื่อ-นามสกุล": "name",
+	"ชื่อ นามสกุล": "name",
+	"first_name":   "first_name",
+	"firstname":    "first_name",
+	"last_name":    "last_name",
+	"lastname":     "last_name",
+	"นามสกุล":      "last_name",
+	"position":     "position",
+	"role":         "position",
+	"ตำแหน่ง":      "position",
+	"phone":        "phone",
+	"tel":          "phone",
+	"mobile":       "phone",
+	"เบอร์โทร":     "phone",
+	"เบอร์โทรศัพท์": "phone",
+	"โทรศัพท์":      "phone",
+	"email":         "email",
+	"e-mail":        "email",
+	"อีเมล":         "email",
+}
+
+// positionAliases normalizes position values to the department_staff codes
+var positionAliases = map[string]string{
+	"nurse":  "nurse",
+	"rn":     "nurse",
+	"พยาบาล": "nurse",
+	"พยาบาลวิชาชีพ": "nurse",
+	"assistant": "assistant",
+	"na":        "assistant",
+	"ผู้ช่วย":   "assistant",
+	"ผู้ช่วยพยาบาล": "assistant",
+}
+
+// Parse reads a CSV or XLSX file (chosen by extension) into unvalidated rows; blank rows are dropped
+func (s *StaffImportServiceImpl) Parse(filename string, data []byte) ([]*entities.StaffImportRow, error) {
+	var records [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		records, err = readCSV(data)
+	case ".xlsx":
+		records, err = readXLSX(data)
+	default:
+		return nil, ErrUnsupportedImportFile
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the header is the first non-blank row
+	top := 0
+	for top < len(records) && isBlankRecord(records[top]) {
+		top++
+	}
+	records = records[top:]
+	if len(records) == 0 {
+		return nil, ErrImportEmpty
+	}
+
+	cols := map[string]int{}
+	for i, h := range records[0] {
+		key := strings.ToLower(strings.TrimSpace(h))
+		if col, ok := headerAliases[key]; ok {
+			if _, seen := cols[col]; !seen {
+				cols[col] = i
+			}
+		}
+	}
+	if (!hasCol(cols, "name") && !hasCol(cols, "first_name")) || !hasCol(cols, "position") {
+		return nil, ErrImportMissingColumns
+	}
+
+	get := func(rec []string, col string) string {
+		i, ok := cols[col]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	var rows []*entities.StaffImportRow
+	for n, rec := range records[1:] {
+		if isBlankRecord(rec) {
+			continue
+		}
+		name := get(rec, "name")
+		if name == "" {
+			name = strings.TrimSpace(get(rec, "first_name") + " " + get(rec, "last_name"))
+		}
+		rows = append(rows, &entities.StaffImportRow{
+			Row:      top + n + 2,
+			Name:     strings.Join(strings.Fields(name), " "),
+			Position: get(rec, "position"),
+			Phone:    get(rec, "phone"),
+			Email:    get(rec, "email"),
+		})
+		if len(rows) > s.maxRows {
+			return nil, fmt.Errorf("file has more than %d data rows", s.maxRows)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, ErrImportEmpty
+	}
+	return rows, nil
+}
+
+// Validate checks every row, normalizes position/phone/email in place and flags duplicates within the
+// file and against the department's existing staff. With skipDuplicates, rows that already exist in the
+// department are marked skipped instead of duplicate.
+func (s *StaffImportServiceImpl) Validate(rows []*entities.StaffImportRow, existing []*entities.DepartmentStaff, skipDuplicates bool) {
+	existingName := map[string]bool{}
+	existingPhone := map[string]bool{}
+	existingEmail := map[string]bool{}
+	for _, st := range existing {
+		existingName[nameKey(st.Name)] = true
+		if st.Phone != nil && *st.Phone != "" {
+			existingPhone[normalizePhone(*st.Phone)] = true
+		}
+		if st.Email != nil && *st.Email != "" {
+			existingEmail[strings.ToLower(*st.Email)] = true
+		}
+	}
+	seenName := map[string]int{}
+	seenPhone := map[string]int{}
+	seenEmail := map[string]int{}
+
+	for _, r := range rows {
+		if r.Name == "" {
+			r.Errors = append(r.Errors, "ต้องระบุชื่อ")
+		} else if utf8.RuneCountInString(r.Name) > 100 {
+			r.Errors = append(r.Errors, "ชื่อยาวเกิน 100 ตัวอักษร")
+		}
+
+		if r.Position == "" {
+			r.Errors = append(r.Errors, "ต้องระบุตำแหน่ง")
+		} else if p, ok := positionAliases[strings.ToLower(strings.TrimSpace(r.Position))]; ok {
+			r.Position = p
+		} else {
+			r.Errors = append(r.Errors, "ตำแหน่งต้องเป็น nurse (พยาบาล) หรือ assistant (ผู้ช่วยพยาบาล)")
+		}
+
+		if r.Phone != "" {
+			r.Phone = normalizePhone(r.Phone)
+			digits := strings.TrimPrefix(r.Phone, "+")
+			if len(digits) < 9 || len(r.Phone) > 20 || strings.Trim(digits, "0123456789") != "" {
+				r.Errors = append(r.Errors, "เบอร์โทรศัพท์ไม่ถูกต้อง")
+			}
+		}
+
+		if r.Email != "" {
+			r.Email = strings.ToLower(r.Email)
+			if addr, err := mail.ParseAddress(r.Email); err != nil || addr.Address != r.Email || len(r.Email) > 255 {
+				r.Errors = append(r.Errors, "อีเมลไม่ถูกต้อง")
+			}
+		}
+
+		if len(r.Errors) > 0 {
+			continue
+		}
+
+		// duplicates inside the file
+		if prev, ok := seenName[nameKey(r.Name)]; ok {
+			r.Duplicate = fmt.Sprintf("ชื่อซ้ำกับแถวที่ %d", prev)
+		} else if prev, ok := seenPhone[r.Phone]; ok && r.Phone != "" {
+			r.Duplicate = fmt.Sprintf("เบอร์โทรศัพท์ซ้ำกับแถวที่ %d", prev)
+		} else if prev, ok := seenEmail[r.Email]; ok && r.Email != "" {
+			r.Duplicate = fmt.Sprintf("อีเมลซ้ำกับแถวที่ %d", prev)
+		}
+		if r.Duplicate != "" {
+			continue
+		}
+		seenName[nameKey(r.Name)] = r.Row
+		if r.Phone != "" {
+			seenPhone[r.Phone] = r.Row
+		}
+		if r.Email != "" {
+			seenEmail[r.Email] = r.Row
+		}
+
+		// duplicates against staff already in the department
+		switch {
+		case existingName[nameKey(r.Name)]:
+			r.Duplicate = "มีชื่อนี้ในแผนกแล้ว"
+		case r.Phone != "" && existingPhone[r.Phone]:
+			r.Duplicate = "มีเบอร์โทรศัพท์นี้ในแผนกแล้ว"
+		case r.Email != "" && existingEmail[r.Email]:
+			r.Duplicate = "มีอีเมลนี้ในแผนกแล้ว"
+		}
+		if r.Duplicate != "" && skipDuplicates {
+			r.Skipped = true
+		}
+	}
+}
+
+func hasCol(cols map[string]int, col string) bool {
+	_, ok := cols[col]
+	return ok
+}
+
+func isBlankRecord(rec []string) bool {
+	for _, v := range rec {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// nameKey compares names case- and whitespace-insensitively
+func nameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// normalizePhone strips spaces, dashes, dots and parentheses
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	// Excel saves Thai CSV files with a UTF-8 BOM
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("CSV file must be UTF-8 encoded")
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var out [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		// the reader skips empty lines; pad them back so row numbers match the file's lines
+		line, _ := r.FieldPos(0)
+		for len(out) < line-1 {
+			out = append(out, nil)
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// readXLSX reads the first worksheet of the workbook
+func readXLSX(data []byte) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %w", err)
+	}
+	defer f.Close()
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, ErrImportEmpty
+	}
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nurseshift/department-service/internal/domain/entities"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// parsed is the part of a parsed row the Parse tests compare
+type parsed struct {
+	row                          int
+	name, position, phone, email string
+}
+
+func summarize(rows []*entities.StaffImportRow) []parsed {
+	out := make([]parsed, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, parsed{r.Row, r.Name, r.Position, r.Phone, r.Email})
+	}
+	return out
+}
+
+func xlsxFile(t *testing.T, records [][]any) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, rec := range records {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.SetSheetRow("Sheet1", cell, &rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     string
+		want     []parsed
+		err      error
+	}{
+		{
+			name:     "english headers",
+			filename: "staff.csv",
+			data:     "name,position,phone,email\nสมศรี ใจดี,nurse,081-234-5678,SOMSRI@example.com\n",
+			want:     []parsed{{2, "สมศรี ใจดี", "nurse", "081-234-5678", "SOMSRI@example.com"}},
+		},
+		{
+			name:     "thai headers with a BOM and extra columns",
+			filename: "STAFF.CSV",
+			data:     "\xef\xbb\xbfลำดับ,ชื่อ-สกุล,ตำแหน่ง,เบอร์โทรศัพท์,อีเมล\n1,มานี มีนา,พยาบาล,0812345678,\n",
+			want:     []parsed{{2, "มานี มีนา", "พยาบาล", "0812345678", ""}},
+		},
+		{
+			name:     "first and last name columns",
+			filename: "staff.csv",
+			data:     "FirstName,LastName,Role\nปิติ,  รักเรียน ,NA\nชูใจ,,rn\n",
+			want:     []parsed{{2, "ปิติ รักเรียน", "NA", "", ""}, {3, "ชูใจ", "rn", "", ""}},
+		},
+		{
+			name:     "blank rows are dropped and keep the line numbers",
+			filename: "staff.csv",
+			data:     "name,position\n,\nวีระ  กล้าหาญ,nurse\n  ,  \n\nสุดา,assistant\n",
+			want:     []parsed{{3, "วีระ กล้าหาญ", "nurse", "", ""}, {6, "สุดา", "assistant", "", ""}},
+		},
+		{
+			name:     "blank lines above the header",
+			filename: "staff.csv",
+			data:     "\n,,\nname,position\nสุดา,assistant\n",
+			want:     []parsed{{4, "สุดา", "assistant", "", ""}},
+		},
+		{
+			name:     "short records",
+			filename: "staff.csv",
+			data:     "name,position,phone\nสมชาย\n",
+			want:     []parsed{{2, "สมชาย", "", "", ""}},
+		},
+		{name: "missing position", filename: "staff.csv", data: "name,phone\nสมชาย,0812345678\n", err: ErrImportMissingColumns},
+		{name: "missing name", filename: "staff.csv", data: "phone,position\n0812345678,nurse\n", err: ErrImportMissingColumns},
+		{name: "header only", filename: "staff.csv", data: "name,position\n", err: ErrImportEmpty},
+		{name: "only blank rows", filename: "staff.csv", data: "name,position\n,\n", err: ErrImportEmpty},
+		{name: "empty file", filename: "staff.csv", data: "", err: ErrImportEmpty},
+		{name: "unsupported extension", filename: "staff.xls", data: "name,position\nสมชาย,nurse\n", err: ErrUnsupportedImportFile},
+	}
+	s := NewStaffImportService(10)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := s.Parse(tt.filename, []byte(tt.data))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Parse error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarize(rows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidFiles(t *testing.T) {
+	s := NewStaffImportService(10)
+	if _, err := s.Parse("staff.csv", []byte("name,position\n\xff\xfe,nurse\n")); err == nil {
+		t.Error("a CSV that is not UTF-8 was accepted")
+	}
+	if _, err := s.Parse("staff.csv", []byte("name,position\n\"unterminated,nurse\n")); err == nil {
+		t.Error("a malformed CSV was accepted")
+	}
+	if _, err := s.Parse("staff.xlsx", []byte("name,position\n")); err == nil {
+		t.Error("a CSV named .xlsx was accepted")
+	}
+}
+
+func TestParseXLSX(t *testing.T) {
+	data := xlsxFile(t, [][]any{
+		{"ชื่อ", "ตำแหน่ง", "โทรศัพท์", "E-mail"},
+		{"สมศรี ใจดี", "ผู้ช่วยพยาบาล", "02 123 4567", "somsri@example.com"},
+		{nil, nil, nil, nil},
+		{"มานี", "พยาบาลวิชาชีพ"},
+	})
+	rows, err := NewStaffImportService(10).Parse("import.xlsx", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []parsed{
+		{2, "สมศรี ใจดี", "ผู้ช่วยพยาบาล", "02 123 4567", "somsri@example.com"},
+		{4, "มานี", "พยาบาลวิชาชีพ", "", ""},
+	}
+	if got := summarize(rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseMaxRows(t *testing.T) {
+	file := func(n int) []byte {
+		var b strings.Builder
+		b.WriteString("name,position\n")
+		for i := 0; i < n; i++ {
+			b.WriteString("พยาบาล " + strings.Repeat("ก", i+1) + ",nurse\n,\n")
+		}
+		return []byte(b.String())
+	}
+	s := NewStaffImportService(3)
+	if rows, err := s.Parse("staff.csv", file(3)); err != nil || len(rows) != 3 {
+		t.Errorf("a file at the limit: %d rows, %v", len(rows), err)
+	}
+	if _, err := s.Parse("staff.csv", file(4)); err == nil || !strings.Contains(err.Error(), "more than 3") {
+		t.Errorf("a file over the limit: %v, want the limit error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	phone := "081-111-2222"
+	email := "Exist@Example.com"
+	existing := []*entities.DepartmentStaff{
+		{Name: "สมชาย  เดิม"},
+		{Name: "คนเก่า", Phone: &phone},
+		{Name: "คนเก่าอีก", Email: &email},
+	}
+	row := func(n int, name, position, phone, email string) *entities.StaffImportRow {
+		return &entities.StaffImportRow{Row: n, Name: name, Position: position, Phone: phone, Email: email}
+	}
+
+	type want struct {
+		position, phone, email string
+		errors                 []string
+		duplicate              string
+		skipped                bool
+	}
+	tests := []struct {
+		name           string
+		rows           []*entities.StaffImportRow
+		skipDuplicates bool
+		want           []want
+	}{
+		{
+			name: "positions and contacts are normalised",
+			rows: []*entities.StaffImportRow{
+				row(2, "ก", "พยาบาล", "(081) 234-5678", "A@Example.COM"),
+				row(3, "ข", " NA ", "+66 81.234.5679", ""),
+				row(4, "ค", "ผู้ช่วยพยาบาล", "", ""),
+			},
+			want: []want{
+				{position: "nurse", phone: "0812345678", email: "a@example.com"},
+				{position: "assistant", phone: "+66812345679"},
+				{position: "assistant"},
+			},
+		},
+		{
+			name: "invalid rows",
+			rows: []*entities.StaffImportRow{
+				row(2, "", "", "", ""),
+				row(3, strings.Repeat("ก", 101), "doctor", "", ""),
+				row(4, "ค", "nurse", "081-23", ""),
+				row(5, "ง", "nurse", "08x2345678", "not-an-email"),
+				row(6, "จ", "nurse", "", "Name <e@example.com>"),
+			},
+			want: []want{
+				{errors: []string{"ต้องระบุชื่อ", "ต้องระบุตำแหน่ง"}},
+				{position: "doctor", errors: []string{"ชื่อยาวเกิน 100 ตัวอักษร", "ตำแหน่งต้องเป็น nurse (พยาบาล) หรือ assistant (ผู้ช่วยพยาบาล)"}},
+				{position: "nurse", phone: "08123", errors: []string{"เบอร์โทรศัพท์ไม่ถูกต้อง"}},
+				{position: "nurse", phone: "08x2345678", email: "not-an-email", errors: []string{"เบอร์โทรศัพท์ไม่ถูกต้อง", "อีเมลไม่ถูกต้อง"}},
+				{position: "nurse", email: "name <e@example.com>", errors: []string{"อีเมลไม่ถูกต้อง"}},
+			},
+		},
+		{
+			name: "duplicates within the file",
+			rows: []*entities.StaffImportRow{
+				row(2, "มานี มีนา", "nurse", "0812345678", "manee@example.com"),
+				row(3, "มานี  มีนา", "nurse", "", ""),
+				row(4, "ปิติ", "nurse", "081 234 5678", ""),
+				row(5, "ชูใจ", "nurse", "", "MANEE@example.com"),
+				row(6, "", "nurse", "0812345678", ""), // invalid rows are not compared
+			},
+			want: []want{
+				{position: "nurse", phone: "0812345678", email: "manee@example.com"},
+				{position: "nurse", duplicate: "ชื่อซ้ำกับแถวที่ 2"},
+				{position: "nurse", phone: "0812345678", duplicate: "เบอร์โทรศัพท์ซ้ำกับแถวที่ 2"},
+				{position: "nurse", email: "manee@example.com", duplicate: "อีเมลซ้ำกับแถวที่ 2"},
+				{position: "nurse", phone: "0812345678", errors: []string{"ต้องระบุชื่อ"}},
+			},
+		},
+		{
+			name: "duplicates of existing staff",
+			rows: []*entities.StaffImportRow{
+				row(2, "สมชาย เดิม", "nurse", "", ""),
+				row(3, "ใหม่", "nurse", "0811112222", ""),
+				row(4, "ใหม่อีก", "nurse", "", "exist@example.com"),
+				row(5, "ใหม่จริง", "nurse", "", ""),
+			},
+			want: []want{
+				{position: "nurse", duplicate: "มีชื่อนี้ในแผนกแล้ว"},
+				{position: "nurse", phone: "0811112222", duplicate: "มีเบอร์โทรศัพท์นี้ในแผนกแล้ว"},
+				{position: "nurse", email: "exist@example.com", duplicate: "มีอีเมลนี้ในแผนกแล้ว"},
+				{position: "nurse"},
+			},
+		},
+		{
+			name: "skipDuplicates skips existing staff but not repeats within the file",
+			rows: []*entities.StaffImportRow{
+				row(2, "สมชาย เดิม", "nurse", "", ""),
+				row(3, "ใหม่", "nurse", "", ""),
+				row(4, "ใหม่", "nurse", "", ""),
+			},
+			skipDuplicates: true,
+			want: []want{
+				{position: "nurse", duplicate: "มีชื่อนี้ในแผนกแล้ว", skipped: true},
+				{position: "nurse"},
+				{position: "nurse", duplicate: "ชื่อซ้ำกับแถวที่ 3"},
+			},
+		},
+	}
+	s := NewStaffImportService(100)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Validate(tt.rows, existing, tt.skipDuplicates)
+			for i, r := range tt.rows {
+				got := want{r.Position, r.Phone, r.Email, r.Errors, r.Duplicate, r.Skipped}
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("row %d = %+v, want %+v", r.Row, got, tt.want[i])
+				}
+				if valid := len(tt.want[i].errors) == 0 && tt.want[i].duplicate == ""; r.IsValid() != valid {
+					t.Errorf("row %d IsValid = %v, want %v", r.Row, r.IsValid(), valid)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+
+	"nurseshift/department-service/internal/infrastructure/access"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// authorize answers with a department access denial unless the caller's role in the department
+// allows act on it
+func (h *DepartmentHandler) authorize(c *fiber.Ctx, departmentID string, act access.Action) (bool, error) {
+	userID, _ := c.Locals("userID").(string)
+	role, _ := c.Locals("role").(string)
+	if err := h.access.Check(c.Context(), userID, role, departmentID, access.ResourceDepartment, act); err != nil {
+		return false, accessError(c, err)
+	}
+	return true, nil
+}
+
+// accessError renders a failed department access check: the denial, or a lookup failure
+func accessError(c *fiber.Ctx, err error) error {
+	var denial *access.Denial
+	if errors.As(err, &denial) {
+		return c.Status(denial.Status).JSON(denial.Body())
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"status":  "error",
+		"message": "ไม่สามารถตรวจสอบสิทธิ์ในแผนกได้",
+		"error":   err.Error(),
+	})
+}
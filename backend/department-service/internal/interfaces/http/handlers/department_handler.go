@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"strconv"
 	"time"
 
 	"nurseshift/department-service/internal/domain/entities"
+	"nurseshift/department-service/internal/infrastructure/access"
+	"nurseshift/department-service/internal/infrastructure/audit"
 	"nurseshift/department-service/internal/infrastructure/database"
+	"nurseshift/department-service/internal/infrastructure/entitlements"
+	"nurseshift/department-service/internal/infrastructure/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -15,12 +21,18 @@ import (
 type DepartmentHandler struct {
 	// departmentUseCase usecases.DepartmentUseCase
 	departmentRepo database.DepartmentRepository
+	staffImport    services.StaffImportService
+	entitlements   *entitlements.Checker
+	access         *access.Guard
 }
 
 // NewDepartmentHandler creates a new department handler
-func NewDepartmentHandler(repo database.DepartmentRepository) *DepartmentHandler {
+func NewDepartmentHandler(repo database.DepartmentRepository, staffImport services.StaffImportService, checker *entitlements.Checker, guard *access.Guard) *DepartmentHandler {
 	return &DepartmentHandler{
 		departmentRepo: repo,
+		staffImport:    staffImport,
+		entitlements:   checker,
+		access:         guard,
 	}
 }
 
@@ -63,6 +75,9 @@ func (h *DepartmentHandler) GetDepartment(c *fiber.Ctx) error {
 			"message": "รหัสแผนกไม่ถูกต้อง",
 		})
 	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionRead); !ok {
+		return err
+	}
 
 	// Get department from database
 	department, err := h.departmentRepo.GetByID(c.Context(), departmentID)
@@ -102,6 +117,12 @@ func (h *DepartmentHandler) CreateDepartment(c *fiber.Ctx) error {
 		})
 	}
 
+	if !isAdmin(c) {
+		if err := h.entitlements.CheckDepartmentQuota(c.Context(), userID); err != nil {
+			return entitlementError(c, err)
+		}
+	}
+
 	// Create department entity
 	department := &entities.Department{
 		ID:            uuid.New(),
@@ -126,6 +147,12 @@ func (h *DepartmentHandler) CreateDepartment(c *fiber.Ctx) error {
 		})
 	}
 
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("department.create")
+	rec.Resource("department", department.ID.String())
+	rec.Department(department.ID.String())
+	rec.After(department)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"status":  "success",
 		"message": "สร้างแผนกสำเร็จ",
@@ -143,6 +170,9 @@ func (h *DepartmentHandler) UpdateDepartment(c *fiber.Ctx) error {
 			"message": "รหัสแผนกไม่ถูกต้อง",
 		})
 	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionMutate); !ok {
+		return err
+	}
 
 	var req entities.UpdateDepartmentRequest
 
@@ -163,6 +193,8 @@ func (h *DepartmentHandler) UpdateDepartment(c *fiber.Ctx) error {
 		})
 	}
 
+	before := *existingDept
+
 	// Update fields
 	if req.Name != nil {
 		existingDept.Name = *req.Name
@@ -196,6 +228,12 @@ func (h *DepartmentHandler) UpdateDepartment(c *fiber.Ctx) error {
 		})
 	}
 
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("department.update")
+	rec.Department(departmentIDStr)
+	rec.Before(before)
+	rec.After(existingDept)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "อัปเดตแผนกสำเร็จ",
@@ -213,6 +251,16 @@ func (h *DepartmentHandler) DeleteDepartment(c *fiber.Ctx) error {
 			"message": "รหัสแผนกไม่ถูกต้อง",
 		})
 	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("department.delete")
+	rec.Department(departmentIDStr)
+	if before, err := h.departmentRepo.GetByID(c.Context(), departmentID); err == nil {
+		rec.Before(before)
+	}
 
 	// Soft delete from database
 	if err := h.departmentRepo.Delete(c.Context(), departmentID); err != nil {
@@ -239,6 +287,9 @@ func (h *DepartmentHandler) GetDepartmentStaff(c *fiber.Ctx) error {
 			"message": "รหัสแผนกไม่ถูกต้อง",
 		})
 	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionRead); !ok {
+		return err
+	}
 
 	// Get staff from database
 	staff, err := h.departmentRepo.GetStaff(c.Context(), departmentID)
@@ -278,6 +329,9 @@ func (h *DepartmentHandler) AddDepartmentStaff(c *fiber.Ctx) error {
 			"message": "รหัสแผนกไม่ถูกต้อง",
 		})
 	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionMutate); !ok {
+		return err
+	}
 
 	var req struct {
 		FirstName      string `json:"first_name" validate:"required"`
@@ -304,6 +358,12 @@ func (h *DepartmentHandler) AddDepartmentStaff(c *fiber.Ctx) error {
 		})
 	}
 
+	if !isAdmin(c) {
+		if err := h.entitlements.CheckStaffQuota(c.Context(), c.Locals("userID").(string), 1); err != nil {
+			return entitlementError(c, err)
+		}
+	}
+
 	// Create staff member
 	staff := &entities.DepartmentStaff{
 		ID:           uuid.New(),
@@ -326,6 +386,12 @@ func (h *DepartmentHandler) AddDepartmentStaff(c *fiber.Ctx) error {
 		})
 	}
 
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("department.add_staff")
+	rec.Resource("staff", staff.ID.String())
+	rec.Department(departmentIDStr)
+	rec.After(staff)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"status":  "success",
 		"message": "เพิ่มพนักงานสำเร็จ",
@@ -333,6 +399,163 @@ func (h *DepartmentHandler) AddDepartmentStaff(c *fiber.Ctx) error {
 	})
 }
 
+// ImportDepartmentStaff imports staff from an uploaded CSV or XLSX file (form field "file").
+// With dryRun=true the rows are only validated; otherwise every valid row is inserted in one
+// transaction, and nothing is inserted if any row is invalid.
+func (h *DepartmentHandler) ImportDepartmentStaff(c *fiber.Ctx) error {
+	departmentIDStr := c.Params("id")
+	departmentID, err := uuid.Parse(departmentIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "รหัสแผนกไม่ถูกต้อง",
+		})
+	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionMutate); !ok {
+		return err
+	}
+
+	if _, err := h.departmentRepo.GetByID(c.Context(), departmentID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบแผนกที่ต้องการ",
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "กรุณาแนบไฟล์ CSV หรือ XLSX ในฟิลด์ file",
+		})
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถอ่านไฟล์ได้",
+			"error":   err.Error(),
+		})
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถอ่านไฟล์ได้",
+			"error":   err.Error(),
+		})
+	}
+
+	rows, err := h.staffImport.Parse(fileHeader.Filename, data)
+	if err != nil {
+		message := "ไฟล์ไม่ถูกต้อง"
+		switch {
+		case errors.Is(err, services.ErrUnsupportedImportFile):
+			message = "รองรับเฉพาะไฟล์ .csv และ .xlsx"
+		case errors.Is(err, services.ErrImportMissingColumns):
+			message = "แถวหัวตารางต้องมีคอลัมน์ name และ position"
+		case errors.Is(err, services.ErrImportEmpty):
+			message = "ไม่พบข้อมูลพนักงานในไฟล์"
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+
+	existing, err := h.departmentRepo.GetStaff(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลพนักงานได้",
+			"error":   err.Error(),
+		})
+	}
+
+	dryRun := c.QueryBool("dryRun", false)
+	h.staffImport.Validate(rows, existing, c.QueryBool("skipDuplicates", false))
+
+	result := &entities.StaffImportResult{DryRun: dryRun, Total: len(rows), Rows: rows}
+	now := time.Now()
+	var staff []*entities.DepartmentStaff
+	for _, r := range rows {
+		switch {
+		case r.Skipped:
+			result.Skipped++
+			continue
+		case !r.IsValid():
+			result.Invalid++
+			continue
+		}
+		result.Valid++
+		s := &entities.DepartmentStaff{
+			ID:           uuid.New(),
+			DepartmentID: departmentID,
+			Name:         r.Name,
+			Position:     r.Position,
+			IsActive:     true,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if r.Phone != "" {
+			phone := r.Phone
+			s.Phone = &phone
+		}
+		if r.Email != "" {
+			email := r.Email
+			s.Email = &email
+		}
+		staff = append(staff, s)
+	}
+
+	if dryRun {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "success",
+			"message": "ตรวจสอบไฟล์นำเข้าพนักงานสำเร็จ",
+			"data":    result,
+		})
+	}
+
+	if result.Invalid > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"status":  "error",
+			"message": "มีแถวที่ไม่ถูกต้องหรือซ้ำ ยังไม่ได้นำเข้าพนักงาน",
+			"data":    result,
+		})
+	}
+
+	if len(staff) > 0 && !isAdmin(c) {
+		if err := h.entitlements.CheckStaffQuota(c.Context(), c.Locals("userID").(string), len(staff)); err != nil {
+			return entitlementError(c, err)
+		}
+	}
+
+	if len(staff) > 0 {
+		if err := h.departmentRepo.BulkCreateStaff(c.Context(), staff); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ไม่สามารถนำเข้าพนักงานได้",
+				"error":   err.Error(),
+			})
+		}
+	}
+	result.Imported = len(staff)
+	result.Created = staff
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("department.import_staff")
+	rec.Department(departmentIDStr)
+	rec.After(fiber.Map{"file": fileHeader.Filename, "imported": result.Imported, "skipped": result.Skipped, "staff": staff})
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"message": "นำเข้าพนักงานสำเร็จ",
+		"data":    result,
+	})
+}
+
 // DeleteDepartmentStaff deletes a staff member from a department
 func (h *DepartmentHandler) DeleteDepartmentStaff(c *fiber.Ctx) error {
 	departmentIDStr := c.Params("id")
@@ -353,6 +576,13 @@ func (h *DepartmentHandler) DeleteDepartmentStaff(c *fiber.Ctx) error {
 			"message": "รหัสพนักงานไม่ถูกต้อง",
 		})
 	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("department.delete_staff")
+	rec.Department(departmentIDStr)
 
 	// Delete staff from database
 	if err := h.departmentRepo.DeleteStaff(c.Context(), staffID, departmentID); err != nil {
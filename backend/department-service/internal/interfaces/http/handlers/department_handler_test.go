@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nurseshift/department-service/internal/domain/entities"
+	"nurseshift/department-service/internal/infrastructure/access"
+	"nurseshift/department-service/internal/infrastructure/database"
+	"nurseshift/department-service/internal/infrastructure/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// importRepo is the part of DepartmentRepository a staff import touches
+type importRepo struct {
+	database.DepartmentRepository
+	existing []*entities.DepartmentStaff
+	created  []*entities.DepartmentStaff
+}
+
+func (r *importRepo) GetByID(_ context.Context, id uuid.UUID) (*entities.Department, error) {
+	return &entities.Department{ID: id}, nil
+}
+
+func (r *importRepo) GetStaff(context.Context, uuid.UUID) ([]*entities.DepartmentStaff, error) {
+	return r.existing, nil
+}
+
+func (r *importRepo) BulkCreateStaff(_ context.Context, staff []*entities.DepartmentStaff) error {
+	r.created = append(r.created, staff...)
+	return nil
+}
+
+type importResponse struct {
+	Status string                     `json:"status"`
+	Data   entities.StaffImportResult `json:"data"`
+}
+
+// postImport uploads csv as an admin, who passes the access and package checks
+func postImport(t *testing.T, repo *importRepo, query, csv string) (int, importResponse) {
+	t.Helper()
+	h := NewDepartmentHandler(repo, services.NewStaffImportService(100), nil, access.NewGuard(nil))
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", uuid.NewString())
+		c.Locals("role", "admin")
+		return c.Next()
+	})
+	app.Post("/departments/:id/staff/import", h.ImportDepartmentStaff)
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "staff.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(csv))
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/departments/"+uuid.NewString()+"/staff/import"+query, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out importResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, out
+}
+
+func TestImportDepartmentStaff(t *testing.T) {
+	existing := []*entities.DepartmentStaff{{Name: "สมชาย เดิม"}}
+	valid := "name,position,phone\nมานี,พยาบาล,081-234-5678\nปิติ,NA,\nสมชาย เดิม,nurse,\n"
+	invalid := "name,position\nมานี,พยาบาล\nชูใจ,doctor\n"
+
+	tests := []struct {
+		name                            string
+		query, csv                      string
+		status                          int
+		valid, invalid, skipped, create int
+	}{
+		{"dry run reports without creating", "?dryRun=true", valid, fiber.StatusOK, 2, 1, 0, 0},
+		{"dry run of an invalid file", "?dryRun=true", invalid, fiber.StatusOK, 1, 1, 0, 0},
+		{"commit refuses a file with a duplicate", "", valid, fiber.StatusUnprocessableEntity, 2, 1, 0, 0},
+		{"commit refuses a file with an invalid row", "", invalid, fiber.StatusUnprocessableEntity, 1, 1, 0, 0},
+		{"commit skipping existing staff", "?skipDuplicates=true", valid, fiber.StatusCreated, 2, 0, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &importRepo{existing: existing}
+			status, resp := postImport(t, repo, tt.query, tt.csv)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			r := resp.Data
+			if r.Valid != tt.valid || r.Invalid != tt.invalid || r.Skipped != tt.skipped || r.Total != len(r.Rows) {
+				t.Errorf("result = valid %d invalid %d skipped %d total %d, want %d %d %d %d",
+					r.Valid, r.Invalid, r.Skipped, r.Total, tt.valid, tt.invalid, tt.skipped, len(r.Rows))
+			}
+			if r.DryRun != (tt.query == "?dryRun=true") {
+				t.Errorf("dry_run = %v", r.DryRun)
+			}
+			if len(repo.created) != tt.create || r.Imported != tt.create {
+				t.Errorf("created %d staff (imported %d), want %d", len(repo.created), r.Imported, tt.create)
+			}
+		})
+	}
+
+	repo := &importRepo{existing: existing}
+	postImport(t, repo, "?skipDuplicates=true", valid)
+	if len(repo.created) != 2 {
+		t.Fatalf("created %d staff, want 2", len(repo.created))
+	}
+	manee, piti := repo.created[0], repo.created[1]
+	if manee.Name != "มานี" || manee.Position != "nurse" || manee.Phone == nil || *manee.Phone != "0812345678" || !manee.IsActive {
+		t.Errorf("first created staff = %+v", manee)
+	}
+	if piti.Position != "assistant" || piti.Phone != nil || piti.Email != nil {
+		t.Errorf("second created staff = %+v", piti)
+	}
+}
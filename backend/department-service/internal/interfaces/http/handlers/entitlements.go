@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+
+	"nurseshift/department-service/internal/infrastructure/entitlements"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// isAdmin reports whether the caller is a system admin; admins are not bound by package limits
+func isAdmin(c *fiber.Ctx) bool {
+	return c.Locals("role") == "admin"
+}
+
+// entitlementError renders a failed package check: the denial with its upgrade hint, or a lookup failure
+func entitlementError(c *fiber.Ctx, err error) error {
+	var denial *entitlements.Denial
+	if errors.As(err, &denial) {
+		return c.Status(denial.Status).JSON(denial.Body())
+	}
+	if errors.Is(err, entitlements.ErrUnknownAccount) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบบัญชีผู้ใช้",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"status":  "error",
+		"message": "ไม่สามารถตรวจสอบสิทธิ์แพ็คเกจได้",
+		"error":   err.Error(),
+	})
+}
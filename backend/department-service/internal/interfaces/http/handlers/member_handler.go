@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"nurseshift/department-service/internal/domain/entities"
+	"nurseshift/department-service/internal/infrastructure/access"
+	"nurseshift/department-service/internal/infrastructure/audit"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetDepartmentMembers returns the users assigned to a department with their department roles
+func (h *DepartmentHandler) GetDepartmentMembers(c *fiber.Ctx) error {
+	departmentIDStr := c.Params("id")
+	departmentID, err := uuid.Parse(departmentIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "รหัสแผนกไม่ถูกต้อง",
+		})
+	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionRead); !ok {
+		return err
+	}
+
+	members, err := h.departmentRepo.GetMembers(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลสมาชิกแผนกได้",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ดึงข้อมูลสมาชิกแผนกสำเร็จ",
+		"data": fiber.Map{
+			"members": members,
+			"roles":   access.Roles,
+		},
+	})
+}
+
+// SetDepartmentMember assigns a user to a department or changes the user's department role
+func (h *DepartmentHandler) SetDepartmentMember(c *fiber.Ctx) error {
+	departmentIDStr := c.Params("id")
+	departmentID, err := uuid.Parse(departmentIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "รหัสแผนกไม่ถูกต้อง",
+		})
+	}
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "รหัสผู้ใช้ไม่ถูกต้อง",
+		})
+	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionMutate); !ok {
+		return err
+	}
+
+	var req struct {
+		DepartmentRole string `json:"department_role" validate:"required,oneof=head_nurse nurse assistant viewer"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+			"error":   err.Error(),
+		})
+	}
+	if !access.IsRole(req.DepartmentRole) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "role ในแผนกต้องเป็น head_nurse, nurse, assistant หรือ viewer",
+		})
+	}
+
+	member := &entities.DepartmentUser{
+		DepartmentID:   departmentID,
+		UserID:         userID,
+		DepartmentRole: req.DepartmentRole,
+	}
+	if assignedBy, err := uuid.Parse(c.Locals("userID").(string)); err == nil {
+		member.AssignedBy = &assignedBy
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("department.set_member")
+	rec.Resource("user", userID.String())
+	rec.Department(departmentIDStr)
+	if members, err := h.departmentRepo.GetMembers(c.Context(), departmentID); err == nil {
+		for _, m := range members {
+			if m.UserID == userID {
+				rec.Before(m.DepartmentUser)
+			}
+		}
+	}
+
+	if err := h.departmentRepo.UpsertMember(c.Context(), member); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถกำหนด role ในแผนกได้",
+			"error":   err.Error(),
+		})
+	}
+	rec.After(member)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "กำหนด role ในแผนกสำเร็จ",
+		"data":    member,
+	})
+}
+
+// RemoveDepartmentMember removes a user from a department
+func (h *DepartmentHandler) RemoveDepartmentMember(c *fiber.Ctx) error {
+	departmentIDStr := c.Params("id")
+	departmentID, err := uuid.Parse(departmentIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "รหัสแผนกไม่ถูกต้อง",
+		})
+	}
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "รหัสผู้ใช้ไม่ถูกต้อง",
+		})
+	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("department.remove_member")
+	rec.Resource("user", userID.String())
+	rec.Department(departmentIDStr)
+
+	if err := h.departmentRepo.DeleteMember(c.Context(), departmentID, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถนำสมาชิกออกจากแผนกได้",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "นำสมาชิกออกจากแผนกสำเร็จ",
+	})
+}
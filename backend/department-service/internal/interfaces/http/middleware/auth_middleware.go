@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"nurseshift/department-service/internal/infrastructure/entitlements"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -83,3 +86,35 @@ func AdminOnlyMiddleware() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// SubscriptionMiddleware blocks write requests of suspended or expired accounts; reads and admins pass
+func SubscriptionMiddleware(checker *entitlements.Checker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if c.Locals("role") == "admin" {
+			return c.Next()
+		}
+		userID, _ := c.Locals("userID").(string)
+		err := checker.CheckWritable(c.Context(), userID)
+		var denial *entitlements.Denial
+		switch {
+		case err == nil:
+			return c.Next()
+		case errors.As(err, &denial):
+			return c.Status(denial.Status).JSON(denial.Body())
+		case errors.Is(err, entitlements.ErrUnknownAccount):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ไม่พบบัญชีผู้ใช้",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถตรวจสอบสถานะแพ็คเกจได้",
+			"error":   err.Error(),
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -9,9 +10,13 @@ import (
 	"syscall"
 	"time"
 
+	"nurseshift/employee-leave-service/internal/domain/entities"
 	"nurseshift/employee-leave-service/internal/domain/usecases"
+	"nurseshift/employee-leave-service/internal/infrastructure/access"
+	"nurseshift/employee-leave-service/internal/infrastructure/audit"
 	"nurseshift/employee-leave-service/internal/infrastructure/config"
 	"nurseshift/employee-leave-service/internal/infrastructure/database"
+	"nurseshift/employee-leave-service/internal/infrastructure/eventbus"
 	"nurseshift/employee-leave-service/internal/infrastructure/repositories"
 	"nurseshift/employee-leave-service/internal/interfaces/http/handlers"
 	"nurseshift/employee-leave-service/internal/interfaces/http/routes"
@@ -49,14 +54,30 @@ func main() {
 		schema = "nurse_shift"
 	}
 
+	// Leave decisions are enqueued in the outbox with the status change and delivered from there:
+	// schedule-service repairs the roster, notification-service tells the staff member
+	outbox := eventbus.NewOutbox(dbConn.GetDB(), schema, "employee-leave-service")
+	dispatcher := eventbus.NewDispatcher(outbox, eventbus.DispatcherConfig{})
+	dispatcher.Subscribe(eventbus.NewWebhook("schedule-service",
+		strings.TrimRight(cfg.Services.ScheduleURL, "/")+"/api/v1/internal/events", cfg.Services.InternalToken),
+		eventbus.LeaveApproved)
+	dispatcher.Subscribe(eventbus.NewWebhook("notification-service",
+		strings.TrimRight(cfg.Services.NotificationURL, "/")+"/api/v1/internal/events", cfg.Services.InternalToken),
+		eventbus.LeaveApproved, eventbus.LeaveRejected)
+	dispatchCtx, stopDispatcher := context.WithCancel(context.Background())
+	go dispatcher.Run(dispatchCtx)
+
 	// Initialize repository
-	leaveRepo := repositories.NewPostgresLeaveRepository(dbConn.GetDB(), schema)
+	leaveRepo := repositories.NewPostgresLeaveRepository(dbConn.GetDB(), schema, outbox)
+
+	auditRepo := audit.NewRepository(dbConn.GetDB(), schema)
 
 	// Initialize use case
-	leaveUseCase := usecases.NewLeaveUseCase(leaveRepo)
+	leaveUseCase := usecases.NewLeaveUseCase(leaveRepo, entities.CoveragePolicy(cfg.Leave.CoveragePolicy))
 
 	// Initialize handler
-	leaveHandler := handlers.NewLeaveHandler(leaveUseCase, dbConn)
+	guard := access.NewGuard(access.NewRepository(dbConn.GetDB(), schema))
+	leaveHandler := handlers.NewLeaveHandler(leaveUseCase, dbConn, guard)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -81,7 +102,7 @@ func main() {
 	}
 
 	// Setup routes
-	routes.SetupRoutes(app, leaveHandler)
+	routes.SetupRoutes(app, leaveHandler, auditRepo)
 
 	// Get port from environment (Railway requires PORT env var)
 	port := os.Getenv("PORT")
@@ -113,6 +134,7 @@ func main() {
 	<-c
 
 	fmt.Println("\n🛑 Shutting down Employee Leave Service...")
+	stopDispatcher()
 
 	// Graceful shutdown
 	if err := app.Shutdown(); err != nil {
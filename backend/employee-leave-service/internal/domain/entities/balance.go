@@ -0,0 +1,183 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors of the leave rules
+var (
+	ErrLeaveOverlap        = errors.New("leave overlaps another pending or approved leave")
+	ErrInsufficientBalance = errors.New("not enough leave balance")
+	ErrInvalidLeaveType    = errors.New("invalid leave type")
+	ErrInvalidQuota        = errors.New("invalid leave quota")
+	ErrQuotaNotFound       = errors.New("leave quota not found")
+	ErrStaffNotFound       = errors.New("staff not found")
+)
+
+// IsValid reports whether t is one of the leave_type values
+func (t LeaveType) IsValid() bool {
+	switch t {
+	case LeaveTypeSick, LeaveTypePersonal, LeaveTypeVacation, LeaveTypeEmergency, LeaveTypeMaternity:
+		return true
+	}
+	return false
+}
+
+// OverlapError lists the leave a request collides with; it matches ErrLeaveOverlap
+type OverlapError struct {
+	Leaves []LeaveRequest
+}
+
+func (e *OverlapError) Error() string { return ErrLeaveOverlap.Error() }
+
+// Unwrap lets errors.Is match ErrLeaveOverlap
+func (e *OverlapError) Unwrap() error { return ErrLeaveOverlap }
+
+// BalanceError says how far a request exceeds the balance of one year; it matches ErrInsufficientBalance
+type BalanceError struct {
+	LeaveType LeaveType `json:"leaveType"`
+	Year      int       `json:"year"`
+	Requested float64   `json:"requested"`
+	Available float64   `json:"available"`
+}
+
+func (e *BalanceError) Error() string {
+	return fmt.Sprintf("%s: %s leave in %d needs %.1f days, %.1f available", ErrInsufficientBalance, e.LeaveType, e.Year, e.Requested, e.Available)
+}
+
+// Unwrap lets errors.Is match ErrInsufficientBalance
+func (e *BalanceError) Unwrap() error { return ErrInsufficientBalance }
+
+// AccrualMethod says how a year's quota becomes available
+type AccrualMethod string
+
+const (
+	AccrualUpfront AccrualMethod = "upfront" // the whole quota on 1 January
+	AccrualMonthly AccrualMethod = "monthly" // a twelfth at the start of each month
+)
+
+// IsValid reports whether m is a known accrual method
+func (m AccrualMethod) IsValid() bool {
+	return m == AccrualUpfront || m == AccrualMonthly
+}
+
+// LeaveQuota is a department's yearly entitlement to one type of leave. Leave types without a quota
+// are not limited.
+type LeaveQuota struct {
+	ID               uuid.UUID     `json:"id"`
+	DepartmentID     uuid.UUID     `json:"departmentId"`
+	LeaveType        LeaveType     `json:"leaveType"`
+	AnnualDays       float64       `json:"annualDays"`
+	Accrual          AccrualMethod `json:"accrual"`
+	CarryOverMaxDays float64       `json:"carryOverMaxDays"` // unused days of last year's quota kept, at most
+	CreatedAt        time.Time     `json:"createdAt"`
+	UpdatedAt        time.Time     `json:"updatedAt"`
+}
+
+// Validate checks the quota's numbers and enums
+func (q LeaveQuota) Validate() error {
+	switch {
+	case !q.LeaveType.IsValid():
+		return ErrInvalidLeaveType
+	case !q.Accrual.IsValid(), q.AnnualDays < 0, q.AnnualDays > 366, q.CarryOverMaxDays < 0:
+		return ErrInvalidQuota
+	}
+	return nil
+}
+
+// LeaveUsage is the days of pending and approved leave of one type that fall in one year
+type LeaveUsage struct {
+	LeaveType LeaveType
+	Year      int
+	Approved  float64
+	Pending   float64
+}
+
+// LeaveBalance is where a staff member stands with one type of leave in one year
+type LeaveBalance struct {
+	LeaveType   LeaveType     `json:"leaveType"`
+	Year        int           `json:"year"`
+	Limited     bool          `json:"limited"` // false: no quota, the figures below are usage only
+	AnnualDays  float64       `json:"annualDays"`
+	Accrual     AccrualMethod `json:"accrual,omitempty"`
+	Accrued     float64       `json:"accrued"`     // of this year's quota, as of AsOf
+	CarriedOver float64       `json:"carriedOver"` // from last year
+	Used        float64       `json:"used"`        // approved
+	Pending     float64       `json:"pending"`
+	Available   float64       `json:"available"` // accrued + carried over - used - pending
+	AsOf        string        `json:"asOf"`
+}
+
+// StaffInfo is what the leave rules need to know about a staff member
+type StaffInfo struct {
+	ID           uuid.UUID
+	DepartmentID uuid.UUID
+	Role         string // nurse or assistant, see StaffRole
+	CreatedAt    time.Time
+}
+
+// ComputeBalance works out a year's balance as of asOf (clamped into the year). Monthly accrual
+// grants a twelfth of the quota for every month started; last year's unused quota carries over up to
+// the quota's limit when the staff member already worked here before the year began. Carried days
+// do not carry over again.
+func ComputeBalance(q *LeaveQuota, staff *StaffInfo, year int, asOf time.Time, usage, previous LeaveUsage) LeaveBalance {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+	asOf = time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	if asOf.Before(start) {
+		asOf = start
+	}
+	if asOf.After(end) {
+		asOf = end
+	}
+
+	b := LeaveBalance{
+		LeaveType: usage.LeaveType,
+		Year:      year,
+		Used:      usage.Approved,
+		Pending:   usage.Pending,
+		AsOf:      asOf.Format("2006-01-02"),
+	}
+	if q == nil {
+		return b
+	}
+	b.LeaveType = q.LeaveType
+	b.Limited = true
+	b.AnnualDays = q.AnnualDays
+	b.Accrual = q.Accrual
+	b.Accrued = q.AnnualDays
+	if q.Accrual == AccrualMonthly {
+		b.Accrued = roundDays(q.AnnualDays * float64(asOf.Month()) / 12)
+	}
+	if q.CarryOverMaxDays > 0 && staff != nil && staff.CreatedAt.Before(start) {
+		unused := q.AnnualDays - previous.Approved
+		b.CarriedOver = roundDays(math.Max(0, math.Min(q.CarryOverMaxDays, unused)))
+	}
+	b.Available = roundDays(b.Accrued + b.CarriedOver - b.Used - b.Pending)
+	return b
+}
+
+// DaysByYear counts the calendar days from start to end, both included, in each year they span
+func DaysByYear(start, end time.Time) map[int]float64 {
+	out := map[int]float64{}
+	d := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	for !d.After(last) {
+		yearEnd := time.Date(d.Year(), 12, 31, 0, 0, 0, 0, time.UTC)
+		if yearEnd.After(last) {
+			yearEnd = last
+		}
+		out[d.Year()] += yearEnd.Sub(d).Hours()/24 + 1
+		d = yearEnd.AddDate(0, 0, 1)
+	}
+	return out
+}
+
+func roundDays(v float64) float64 {
+	return math.Round(v*100) / 100
+}
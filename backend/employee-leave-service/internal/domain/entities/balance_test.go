@@ -0,0 +1,95 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestComputeBalance(t *testing.T) {
+	veteran := &StaffInfo{CreatedAt: date(2020, 3, 1)}
+	newcomer := &StaffInfo{CreatedAt: date(2025, 2, 1)}
+
+	tests := []struct {
+		name      string
+		quota     *LeaveQuota
+		staff     *StaffInfo
+		asOf      time.Time
+		usage     LeaveUsage
+		previous  LeaveUsage
+		accrued   float64
+		carried   float64
+		available float64
+	}{
+		{
+			name:      "upfront",
+			quota:     &LeaveQuota{LeaveType: LeaveTypeVacation, AnnualDays: 10, Accrual: AccrualUpfront},
+			staff:     veteran,
+			asOf:      date(2025, 2, 10),
+			usage:     LeaveUsage{Approved: 3, Pending: 1},
+			accrued:   10,
+			available: 6,
+		},
+		{
+			name:      "monthly accrues a twelfth per month started",
+			quota:     &LeaveQuota{LeaveType: LeaveTypeVacation, AnnualDays: 12, Accrual: AccrualMonthly},
+			staff:     veteran,
+			asOf:      date(2025, 4, 1),
+			usage:     LeaveUsage{Approved: 1},
+			accrued:   4,
+			available: 3,
+		},
+		{
+			name:      "carry over capped",
+			quota:     &LeaveQuota{LeaveType: LeaveTypeVacation, AnnualDays: 10, Accrual: AccrualUpfront, CarryOverMaxDays: 5},
+			staff:     veteran,
+			asOf:      date(2025, 6, 1),
+			previous:  LeaveUsage{Approved: 2},
+			accrued:   10,
+			carried:   5,
+			available: 15,
+		},
+		{
+			name:      "no carry over for staff who joined this year",
+			quota:     &LeaveQuota{LeaveType: LeaveTypeVacation, AnnualDays: 10, Accrual: AccrualUpfront, CarryOverMaxDays: 5},
+			staff:     newcomer,
+			asOf:      date(2025, 6, 1),
+			accrued:   10,
+			available: 10,
+		},
+		{
+			name:      "as of clamped into the year",
+			quota:     &LeaveQuota{LeaveType: LeaveTypeSick, AnnualDays: 6, Accrual: AccrualMonthly},
+			staff:     veteran,
+			asOf:      date(2026, 3, 1),
+			accrued:   6,
+			available: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := ComputeBalance(tt.quota, tt.staff, 2025, tt.asOf, tt.usage, tt.previous)
+			if !b.Limited || b.Accrued != tt.accrued || b.CarriedOver != tt.carried || b.Available != tt.available {
+				t.Errorf("got accrued %v carried %v available %v, want %v %v %v",
+					b.Accrued, b.CarriedOver, b.Available, tt.accrued, tt.carried, tt.available)
+			}
+		})
+	}
+
+	if b := ComputeBalance(nil, veteran, 2025, date(2025, 1, 1), LeaveUsage{LeaveType: LeaveTypeSick, Approved: 2}, LeaveUsage{}); b.Limited || b.Used != 2 {
+		t.Errorf("leave type without a quota: got %+v", b)
+	}
+}
+
+func TestDaysByYear(t *testing.T) {
+	got := DaysByYear(date(2025, 12, 30), date(2026, 1, 2))
+	if len(got) != 2 || got[2025] != 2 || got[2026] != 2 {
+		t.Errorf("got %v, want 2025:2 2026:2", got)
+	}
+	if got := DaysByYear(date(2025, 5, 1), date(2025, 5, 1)); got[2025] != 1 {
+		t.Errorf("one-day leave: got %v", got)
+	}
+}
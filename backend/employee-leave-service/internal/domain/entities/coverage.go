@@ -0,0 +1,152 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnderstaffed is returned when approving leave would leave a day without enough staff on duty
+var ErrUnderstaffed = errors.New("approving the leave leaves the department understaffed")
+
+// CoveragePolicy says what approving leave does when the department would be understaffed
+type CoveragePolicy string
+
+const (
+	CoverageBlock CoveragePolicy = "block" // refuse unless the approver overrides
+	CoverageWarn  CoveragePolicy = "warn"  // approve and report the shortfall
+)
+
+// Staff roles counted against shift requirements
+const (
+	RoleNurse     = "nurse"
+	RoleAssistant = "assistant"
+)
+
+// StaffRole maps a department_staff position to the role it fills, the way the schedule generator
+// does: assistants by name, everyone else as a nurse
+func StaffRole(position string) string {
+	switch strings.TrimSpace(position) {
+	case "assistant", "ผู้ช่วยพยาบาล", "ผู้ช่วย":
+		return RoleAssistant
+	}
+	return RoleNurse
+}
+
+// CoverageStaff is an active staff member of the department
+type CoverageStaff struct {
+	ID   uuid.UUID
+	Role string
+}
+
+// DateRange is a span of days, both included
+type DateRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// CoverageInputs is what a department needs each day and who could be on duty, over a range of days
+type CoverageInputs struct {
+	RequiredNurses     int          // per working day, summed over active shifts
+	RequiredAssistants int          // per working day, summed over active shifts
+	WorkingDays        map[int]bool // by weekday, 0 = Sunday; missing days are working days
+	Holidays           []DateRange
+	Staff              []CoverageStaff
+	Leaves             []LeaveRequest // other approved leave in the range
+}
+
+// CoverageDay is the projected staffing of one day of the leave
+type CoverageDay struct {
+	Date                string `json:"date"`
+	Working             bool   `json:"working"` // false on the department's days off
+	Holiday             bool   `json:"holiday"` // no shifts are scheduled on days off and holidays
+	RequiredNurses      int    `json:"requiredNurses"`
+	RequiredAssistants  int    `json:"requiredAssistants"`
+	AvailableNurses     int    `json:"availableNurses"`
+	AvailableAssistants int    `json:"availableAssistants"`
+	OnLeave             int    `json:"onLeave"` // other staff on approved leave that day
+	Covered             bool   `json:"covered"`
+}
+
+// Coverage is the projected staffing over the days of a leave, were it approved
+type Coverage struct {
+	LeaveID   uuid.UUID     `json:"leaveId"`
+	Role      string        `json:"role"` // of the staff member taking leave
+	Covered   bool          `json:"covered"`
+	ShortDays []string      `json:"shortDays"`
+	Days      []CoverageDay `json:"days"`
+}
+
+// CoverageError carries the projection of leave refused for understaffing; it matches ErrUnderstaffed
+type CoverageError struct {
+	Coverage *Coverage
+}
+
+func (e *CoverageError) Error() string {
+	return ErrUnderstaffed.Error() + " on " + strings.Join(e.Coverage.ShortDays, ", ")
+}
+
+// Unwrap lets errors.Is match ErrUnderstaffed
+func (e *CoverageError) Unwrap() error { return ErrUnderstaffed }
+
+// LeaveDetail is a leave request with the staffing it would leave behind
+type LeaveDetail struct {
+	LeaveRequestWithDetails
+	Coverage *Coverage `json:"coverage"`
+}
+
+// ProjectCoverage works out, for each day of the leave, whether the staff left once the leave and the
+// other approved leave are taken out can still fill every shift. Each staff member works at most one
+// shift a day, as in the generated schedules.
+func ProjectCoverage(leave LeaveRequest, role string, in CoverageInputs) *Coverage {
+	cov := &Coverage{LeaveID: leave.ID, Role: role, Covered: true, ShortDays: []string{}}
+
+	for d, end := dayOf(leave.StartDate), dayOf(leave.EndDate); !d.After(end); d = d.AddDate(0, 0, 1) {
+		day := CoverageDay{Date: d.Format("2006-01-02"), Working: true, Covered: true}
+		if w, ok := in.WorkingDays[int(d.Weekday())]; ok {
+			day.Working = w
+		}
+		for _, h := range in.Holidays {
+			if !d.Before(dayOf(h.Start)) && !d.After(dayOf(h.End)) {
+				day.Holiday = true
+				break
+			}
+		}
+
+		away := map[uuid.UUID]bool{leave.StaffID: true}
+		for _, l := range in.Leaves {
+			if l.StaffID != leave.StaffID && !d.Before(dayOf(l.StartDate)) && !d.After(dayOf(l.EndDate)) && !away[l.StaffID] {
+				away[l.StaffID] = true
+				day.OnLeave++
+			}
+		}
+		for _, s := range in.Staff {
+			if away[s.ID] {
+				continue
+			}
+			if s.Role == RoleAssistant {
+				day.AvailableAssistants++
+			} else {
+				day.AvailableNurses++
+			}
+		}
+
+		if day.Working && !day.Holiday {
+			day.RequiredNurses = in.RequiredNurses
+			day.RequiredAssistants = in.RequiredAssistants
+			day.Covered = day.AvailableNurses >= day.RequiredNurses && day.AvailableAssistants >= day.RequiredAssistants
+		}
+		if !day.Covered {
+			cov.Covered = false
+			cov.ShortDays = append(cov.ShortDays, day.Date)
+		}
+		cov.Days = append(cov.Days, day)
+	}
+	return cov
+}
+
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
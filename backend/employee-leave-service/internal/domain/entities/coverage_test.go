@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestProjectCoverage(t *testing.T) {
+	applicant, nurse, other, assistant := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	in := CoverageInputs{
+		RequiredNurses:     2,
+		RequiredAssistants: 1,
+		WorkingDays:        map[int]bool{0: false}, // Sundays off
+		Holidays:           []DateRange{{Start: date(2025, 6, 3), End: date(2025, 6, 3)}},
+		Staff: []CoverageStaff{
+			{ID: applicant, Role: RoleNurse},
+			{ID: nurse, Role: RoleNurse},
+			{ID: other, Role: RoleNurse},
+			{ID: assistant, Role: RoleAssistant},
+		},
+		Leaves: []LeaveRequest{{StaffID: other, StartDate: date(2025, 6, 2), EndDate: date(2025, 6, 4)}},
+	}
+	// Sunday 1 June to Thursday 5 June
+	leave := LeaveRequest{StaffID: applicant, StartDate: date(2025, 6, 1), EndDate: date(2025, 6, 5)}
+
+	cov := ProjectCoverage(leave, RoleNurse, in)
+	if len(cov.Days) != 5 {
+		t.Fatalf("got %d days, want 5", len(cov.Days))
+	}
+	want := []string{"2025-06-02", "2025-06-04"}
+	if cov.Covered || len(cov.ShortDays) != len(want) || cov.ShortDays[0] != want[0] || cov.ShortDays[1] != want[1] {
+		t.Errorf("short days: got %v, want %v", cov.ShortDays, want)
+	}
+	if d := cov.Days[1]; d.AvailableNurses != 1 || d.OnLeave != 1 || d.RequiredNurses != 2 {
+		t.Errorf("2 June: got %+v", d)
+	}
+	if d := cov.Days[0]; d.Working || !d.Covered {
+		t.Errorf("day off counted as short: %+v", d)
+	}
+	if d := cov.Days[2]; !d.Holiday || !d.Covered {
+		t.Errorf("holiday counted as short: %+v", d)
+	}
+	if d := cov.Days[4]; !d.Covered || d.AvailableNurses != 2 {
+		t.Errorf("5 June: got %+v", d)
+	}
+}
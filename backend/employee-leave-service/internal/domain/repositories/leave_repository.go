@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"time"
+
 	"nurseshift/employee-leave-service/internal/domain/entities"
 
 	"github.com/google/uuid"
@@ -38,4 +40,34 @@ type LeaveRepository interface {
 
 	// ToggleActive toggles the active status (for soft delete/restore)
 	ToggleActive(ctx context.Context, id uuid.UUID) error
+
+	// FindOverlapping returns the staff member's pending and approved leave sharing a day with
+	// start..end, leaving out excludeID when given
+	FindOverlapping(ctx context.Context, staffID uuid.UUID, start, end time.Time, excludeID *uuid.UUID) ([]entities.LeaveRequest, error)
+
+	// GetUsage returns the staff member's days of pending and approved leave in a year per leave type,
+	// leaving out excludeID when given
+	GetUsage(ctx context.Context, staffID uuid.UUID, year int, excludeID *uuid.UUID) ([]entities.LeaveUsage, error)
+
+	// GetStaff returns the staff member's department, role and start; nil when there is no such staff member
+	GetStaff(ctx context.Context, staffID uuid.UUID) (*entities.StaffInfo, error)
+
+	// GetQuota returns a department's quota of a leave type; nil when none is configured
+	GetQuota(ctx context.Context, departmentID uuid.UUID, leaveType entities.LeaveType) (*entities.LeaveQuota, error)
+
+	// GetQuotaByID returns a quota by ID; nil when it does not exist
+	GetQuotaByID(ctx context.Context, id uuid.UUID) (*entities.LeaveQuota, error)
+
+	// ListQuotas returns a department's quotas
+	ListQuotas(ctx context.Context, departmentID uuid.UUID) ([]entities.LeaveQuota, error)
+
+	// UpsertQuota creates or replaces the department's quota of the leave type, filling ID and times
+	UpsertQuota(ctx context.Context, q *entities.LeaveQuota) error
+
+	// DeleteQuota removes a quota; the leave type is then unlimited
+	DeleteQuota(ctx context.Context, id uuid.UUID) error
+
+	// GetCoverageInputs loads a department's daily shift requirements, working days, holidays, active
+	// staff and approved leave over start..end, leaving out excludeID when given
+	GetCoverageInputs(ctx context.Context, departmentID uuid.UUID, start, end time.Time, excludeID *uuid.UUID) (*entities.CoverageInputs, error)
 }
@@ -30,8 +30,16 @@ type LeaveUseCase interface {
 	// UpdateLeave updates an existing leave request
 	UpdateLeave(ctx context.Context, id uuid.UUID, update entities.LeaveRequestUpdate) error
 
-	// ApproveLeave approves a leave request
-	ApproveLeave(ctx context.Context, id uuid.UUID, approverID uuid.UUID) error
+	// ApproveLeave approves a leave request and returns the staffing it leaves behind. When the
+	// department would be understaffed and the policy blocks, it returns a CoverageError unless
+	// override is set.
+	ApproveLeave(ctx context.Context, id uuid.UUID, approverID uuid.UUID, override bool) (*entities.Coverage, error)
+
+	// GetCoverage projects the department's staffing over the days of a leave, were it approved
+	GetCoverage(ctx context.Context, id uuid.UUID) (*entities.Coverage, error)
+
+	// GetLeaveDetail retrieves a leave request with its projected coverage; nil when it does not exist
+	GetLeaveDetail(ctx context.Context, id uuid.UUID) (*entities.LeaveDetail, error)
 
 	// RejectLeave rejects a leave request
 	RejectLeave(ctx context.Context, id uuid.UUID, approverID uuid.UUID, reason string) error
@@ -41,17 +49,39 @@ type LeaveUseCase interface {
 
 	// ToggleLeaveStatus toggles the leave request status
 	ToggleLeaveStatus(ctx context.Context, id uuid.UUID) error
+
+	// GetBalances returns a staff member's balance of every leave type with a quota or with leave in
+	// the year, as of asOf
+	GetBalances(ctx context.Context, staffID uuid.UUID, year int, asOf time.Time) ([]entities.LeaveBalance, error)
+
+	// GetStaff returns a staff member's department; nil when there is no such staff member
+	GetStaff(ctx context.Context, staffID uuid.UUID) (*entities.StaffInfo, error)
+
+	// GetQuota returns a quota by ID; nil when it does not exist
+	GetQuota(ctx context.Context, id uuid.UUID) (*entities.LeaveQuota, error)
+
+	// ListQuotas returns a department's leave quotas
+	ListQuotas(ctx context.Context, departmentID uuid.UUID) ([]entities.LeaveQuota, error)
+
+	// SaveQuota creates or replaces a department's quota of a leave type
+	SaveQuota(ctx context.Context, q *entities.LeaveQuota) error
+
+	// DeleteQuota removes a quota
+	DeleteQuota(ctx context.Context, id uuid.UUID) error
 }
 
 // LeaveUseCaseImpl implements LeaveUseCase
 type LeaveUseCaseImpl struct {
-	repo repositories.LeaveRepository
+	repo     repositories.LeaveRepository
+	coverage entities.CoveragePolicy
 }
 
-// NewLeaveUseCase creates a new leave use case
-func NewLeaveUseCase(repo repositories.LeaveRepository) LeaveUseCase {
+// NewLeaveUseCase creates a new leave use case; coverage says whether approvals that leave the
+// department understaffed are blocked or only warned about
+func NewLeaveUseCase(repo repositories.LeaveRepository, coverage entities.CoveragePolicy) LeaveUseCase {
 	return &LeaveUseCaseImpl{
-		repo: repo,
+		repo:     repo,
+		coverage: coverage,
 	}
 }
 
@@ -61,6 +91,12 @@ func (uc *LeaveUseCaseImpl) CreateLeave(ctx context.Context, req entities.LeaveR
 	if req.EndDate.Before(req.StartDate) {
 		return uuid.Nil, entities.ErrInvalidDateRange
 	}
+	if !req.LeaveType.IsValid() {
+		return uuid.Nil, entities.ErrInvalidLeaveType
+	}
+	if err := uc.checkRules(ctx, req.StaffID, req.DepartmentID, req.LeaveType, req.StartDate, req.EndDate, nil); err != nil {
+		return uuid.Nil, err
+	}
 
 	// Create leave request
 	leave := entities.LeaveRequest{
@@ -100,19 +136,104 @@ func (uc *LeaveUseCaseImpl) GetLeavesByDepartment(ctx context.Context, departmen
 
 // UpdateLeave updates an existing leave request
 func (uc *LeaveUseCaseImpl) UpdateLeave(ctx context.Context, id uuid.UUID, update entities.LeaveRequestUpdate) error {
-	// Validate dates if both are provided
-	if update.StartDate != nil && update.EndDate != nil {
-		if update.EndDate.Before(*update.StartDate) {
-			return entities.ErrInvalidDateRange
+	current, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return entities.ErrLeaveNotFound
+	}
+
+	// Check the leave as it will be after the update
+	start, end, leaveType := current.StartDate, current.EndDate, current.LeaveType
+	if update.StartDate != nil {
+		start = *update.StartDate
+	}
+	if update.EndDate != nil {
+		end = *update.EndDate
+	}
+	if update.LeaveType != nil {
+		leaveType = *update.LeaveType
+	}
+	if end.Before(start) {
+		return entities.ErrInvalidDateRange
+	}
+	if !leaveType.IsValid() {
+		return entities.ErrInvalidLeaveType
+	}
+	status := current.Status
+	if update.Status != nil {
+		status = *update.Status
+	}
+	reshaped := !start.Equal(current.StartDate) || !end.Equal(current.EndDate) || leaveType != current.LeaveType
+	if counts(status) && (reshaped || !counts(current.Status)) {
+		if err := uc.checkRules(ctx, current.StaffID, current.DepartmentID, leaveType, start, end, &id); err != nil {
+			return err
 		}
 	}
 
 	return uc.repo.Update(ctx, id, update)
 }
 
-// ApproveLeave approves a leave request
-func (uc *LeaveUseCaseImpl) ApproveLeave(ctx context.Context, id uuid.UUID, approverID uuid.UUID) error {
-	return uc.repo.UpdateStatus(ctx, id, entities.LeaveStatusApproved, &approverID, nil)
+// ApproveLeave approves a leave request; the repository announces it to other services
+func (uc *LeaveUseCaseImpl) ApproveLeave(ctx context.Context, id uuid.UUID, approverID uuid.UUID, override bool) (*entities.Coverage, error) {
+	leave, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if leave == nil {
+		return nil, entities.ErrLeaveNotFound
+	}
+	cov, err := uc.projectCoverage(ctx, leave)
+	if err != nil {
+		return nil, err
+	}
+	if !cov.Covered && uc.coverage != entities.CoverageWarn && !override {
+		return cov, &entities.CoverageError{Coverage: cov}
+	}
+	return cov, uc.repo.UpdateStatus(ctx, id, entities.LeaveStatusApproved, &approverID, nil)
+}
+
+// GetCoverage projects the staffing over the days of a leave
+func (uc *LeaveUseCaseImpl) GetCoverage(ctx context.Context, id uuid.UUID) (*entities.Coverage, error) {
+	leave, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if leave == nil {
+		return nil, entities.ErrLeaveNotFound
+	}
+	return uc.projectCoverage(ctx, leave)
+}
+
+// GetLeaveDetail retrieves a leave request with its projected coverage
+func (uc *LeaveUseCaseImpl) GetLeaveDetail(ctx context.Context, id uuid.UUID) (*entities.LeaveDetail, error) {
+	leave, err := uc.repo.GetByIDWithDetails(ctx, id)
+	if err != nil || leave == nil {
+		return nil, err
+	}
+	cov, err := uc.projectCoverage(ctx, &leave.LeaveRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.LeaveDetail{LeaveRequestWithDetails: *leave, Coverage: cov}, nil
+}
+
+// projectCoverage counts who is left on duty each day of the leave against the department's shifts
+func (uc *LeaveUseCaseImpl) projectCoverage(ctx context.Context, leave *entities.LeaveRequest) (*entities.Coverage, error) {
+	role := entities.RoleNurse
+	staff, err := uc.repo.GetStaff(ctx, leave.StaffID)
+	if err != nil {
+		return nil, err
+	}
+	if staff != nil {
+		role = staff.Role
+	}
+	in, err := uc.repo.GetCoverageInputs(ctx, leave.DepartmentID, leave.StartDate, leave.EndDate, &leave.ID)
+	if err != nil {
+		return nil, err
+	}
+	return entities.ProjectCoverage(*leave, role, *in), nil
 }
 
 // RejectLeave rejects a leave request
@@ -125,7 +246,161 @@ func (uc *LeaveUseCaseImpl) DeleteLeave(ctx context.Context, id uuid.UUID) error
 	return uc.repo.Delete(ctx, id)
 }
 
-// ToggleLeaveStatus toggles the leave request status
+// ToggleLeaveStatus toggles the leave request status. A leave that comes back as pending is checked
+// against the rules again.
 func (uc *LeaveUseCaseImpl) ToggleLeaveStatus(ctx context.Context, id uuid.UUID) error {
+	leave, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if leave == nil {
+		return entities.ErrLeaveNotFound
+	}
+	if !counts(leave.Status) {
+		if err := uc.checkRules(ctx, leave.StaffID, leave.DepartmentID, leave.LeaveType, leave.StartDate, leave.EndDate, &id); err != nil {
+			return err
+		}
+	}
 	return uc.repo.ToggleActive(ctx, id)
 }
+
+// counts reports whether leave in this status holds its days: pending and approved leave do
+func counts(s entities.LeaveStatus) bool {
+	return s == entities.LeaveStatusPending || s == entities.LeaveStatusApproved
+}
+
+// checkRules refuses leave that overlaps the staff member's other pending or approved leave, or that
+// needs more days than the department's quota leaves in any year it falls in. excludeID is the leave
+// being changed, which is not counted against itself.
+func (uc *LeaveUseCaseImpl) checkRules(ctx context.Context, staffID, departmentID uuid.UUID, leaveType entities.LeaveType, start, end time.Time, excludeID *uuid.UUID) error {
+	overlapping, err := uc.repo.FindOverlapping(ctx, staffID, start, end, excludeID)
+	if err != nil {
+		return err
+	}
+	if len(overlapping) > 0 {
+		return &entities.OverlapError{Leaves: overlapping}
+	}
+
+	quota, err := uc.repo.GetQuota(ctx, departmentID, leaveType)
+	if err != nil || quota == nil {
+		return err
+	}
+	staff, err := uc.repo.GetStaff(ctx, staffID)
+	if err != nil {
+		return err
+	}
+	for year, days := range entities.DaysByYear(start, end) {
+		// monthly accrual: only what has accrued by the last day of the leave in that year counts
+		asOf := end
+		if asOf.Year() > year {
+			asOf = time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+		}
+		usage, previous, err := uc.usage(ctx, staffID, year, leaveType, excludeID)
+		if err != nil {
+			return err
+		}
+		b := entities.ComputeBalance(quota, staff, year, asOf, usage, previous)
+		if days > b.Available {
+			return &entities.BalanceError{LeaveType: leaveType, Year: year, Requested: days, Available: b.Available}
+		}
+	}
+	return nil
+}
+
+// usage returns the staff member's use of one leave type in year and in the year before
+func (uc *LeaveUseCaseImpl) usage(ctx context.Context, staffID uuid.UUID, year int, leaveType entities.LeaveType, excludeID *uuid.UUID) (entities.LeaveUsage, entities.LeaveUsage, error) {
+	pick := func(year int) (entities.LeaveUsage, error) {
+		all, err := uc.repo.GetUsage(ctx, staffID, year, excludeID)
+		if err != nil {
+			return entities.LeaveUsage{}, err
+		}
+		for _, u := range all {
+			if u.LeaveType == leaveType {
+				return u, nil
+			}
+		}
+		return entities.LeaveUsage{LeaveType: leaveType, Year: year}, nil
+	}
+	current, err := pick(year)
+	if err != nil {
+		return current, current, err
+	}
+	previous, err := pick(year - 1)
+	return current, previous, err
+}
+
+// GetBalances returns the staff member's balances for the year, quota-limited leave types first
+func (uc *LeaveUseCaseImpl) GetBalances(ctx context.Context, staffID uuid.UUID, year int, asOf time.Time) ([]entities.LeaveBalance, error) {
+	staff, err := uc.repo.GetStaff(ctx, staffID)
+	if err != nil {
+		return nil, err
+	}
+	if staff == nil {
+		return nil, entities.ErrStaffNotFound
+	}
+	quotas, err := uc.repo.ListQuotas(ctx, staff.DepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := uc.repo.GetUsage(ctx, staffID, year, nil)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := uc.repo.GetUsage(ctx, staffID, year-1, nil)
+	if err != nil {
+		return nil, err
+	}
+	usageOf := func(all []entities.LeaveUsage, t entities.LeaveType, year int) entities.LeaveUsage {
+		for _, u := range all {
+			if u.LeaveType == t {
+				return u
+			}
+		}
+		return entities.LeaveUsage{LeaveType: t, Year: year}
+	}
+
+	balances := []entities.LeaveBalance{}
+	limited := map[entities.LeaveType]bool{}
+	for i := range quotas {
+		q := &quotas[i]
+		limited[q.LeaveType] = true
+		balances = append(balances, entities.ComputeBalance(q, staff, year, asOf, usageOf(current, q.LeaveType, year), usageOf(previous, q.LeaveType, year-1)))
+	}
+	for _, u := range current {
+		if !limited[u.LeaveType] {
+			balances = append(balances, entities.ComputeBalance(nil, staff, year, asOf, u, entities.LeaveUsage{}))
+		}
+	}
+	return balances, nil
+}
+
+// GetStaff returns a staff member's department
+func (uc *LeaveUseCaseImpl) GetStaff(ctx context.Context, staffID uuid.UUID) (*entities.StaffInfo, error) {
+	return uc.repo.GetStaff(ctx, staffID)
+}
+
+// GetQuota returns a quota by ID
+func (uc *LeaveUseCaseImpl) GetQuota(ctx context.Context, id uuid.UUID) (*entities.LeaveQuota, error) {
+	return uc.repo.GetQuotaByID(ctx, id)
+}
+
+// ListQuotas returns a department's leave quotas
+func (uc *LeaveUseCaseImpl) ListQuotas(ctx context.Context, departmentID uuid.UUID) ([]entities.LeaveQuota, error) {
+	return uc.repo.ListQuotas(ctx, departmentID)
+}
+
+// SaveQuota validates and stores a quota
+func (uc *LeaveUseCaseImpl) SaveQuota(ctx context.Context, q *entities.LeaveQuota) error {
+	if q.Accrual == "" {
+		q.Accrual = entities.AccrualUpfront
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	return uc.repo.UpsertQuota(ctx, q)
+}
+
+// DeleteQuota removes a quota
+func (uc *LeaveUseCaseImpl) DeleteQuota(ctx context.Context, id uuid.UUID) error {
+	return uc.repo.DeleteQuota(ctx, id)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -12,6 +13,8 @@ type Config struct {
 	Database DatabaseConfig
 	Auth     AuthConfig
 	CORS     CORSConfig
+	Services ServicesConfig
+	Leave    LeaveConfig
 }
 
 // ServerConfig holds server configuration
@@ -38,6 +41,18 @@ type AuthConfig struct {
 	ExpireHours int
 }
 
+// ServicesConfig holds URLs of the services this one calls
+type ServicesConfig struct {
+	ScheduleURL     string
+	NotificationURL string
+	InternalToken   string
+}
+
+// LeaveConfig holds leave approval rules
+type LeaveConfig struct {
+	CoveragePolicy string // block or warn approvals that leave the department understaffed
+}
+
 // CORSConfig holds CORS configuration
 type CORSConfig struct {
 	Origins     []string
@@ -69,6 +84,17 @@ func Load() (*Config, error) {
 			Origins:     strings.Split(getEnv("CORS_ORIGINS", "*"), ","),
 			Credentials: getEnvAsBool("CORS_CREDENTIALS", true),
 		},
+		Services: ServicesConfig{
+			ScheduleURL:     getEnv("SCHEDULE_SERVICE_URL", "http://localhost:8084"),
+			NotificationURL: getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8087"),
+			InternalToken:   getEnv("INTERNAL_SERVICE_TOKEN", ""),
+		},
+		Leave: LeaveConfig{
+			CoveragePolicy: getEnv("LEAVE_COVERAGE_POLICY", "block"),
+		},
+	}
+	if cfg.Services.InternalToken == "" {
+		return nil, fmt.Errorf("INTERNAL_SERVICE_TOKEN is required for service-to-service calls")
 	}
 
 	return cfg, nil
@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nurseshift/employee-leave-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// FindOverlapping returns the staff member's pending and approved leave sharing a day with start..end
+func (r *PostgresLeaveRepository) FindOverlapping(ctx context.Context, staffID uuid.UUID, start, end time.Time, excludeID *uuid.UUID) ([]entities.LeaveRequest, error) {
+	query := fmt.Sprintf(`
+		SELECT id, staff_id, department_id, leave_type, start_date, end_date, status
+		FROM %s.leave_requests
+		WHERE staff_id = $1 AND start_date <= $3 AND end_date >= $2
+		  AND status IN ('pending', 'approved')
+		  AND ($4::uuid IS NULL OR id <> $4)
+		ORDER BY start_date
+	`, r.schema)
+
+	rows, err := r.db.QueryContext(ctx, query, staffID, start, end, excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find overlapping leave: %w", err)
+	}
+	defer rows.Close()
+
+	var leaves []entities.LeaveRequest
+	for rows.Next() {
+		var l entities.LeaveRequest
+		if err := rows.Scan(&l.ID, &l.StaffID, &l.DepartmentID, &l.LeaveType, &l.StartDate, &l.EndDate, &l.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan leave request: %w", err)
+		}
+		leaves = append(leaves, l)
+	}
+	return leaves, rows.Err()
+}
+
+// GetUsage sums, per leave type, the days of the staff member's pending and approved leave that fall
+// in the year; leave spanning new year counts only its days inside it
+func (r *PostgresLeaveRepository) GetUsage(ctx context.Context, staffID uuid.UUID, year int, excludeID *uuid.UUID) ([]entities.LeaveUsage, error) {
+	query := fmt.Sprintf(`
+		SELECT leave_type,
+			COALESCE(SUM(LEAST(end_date, $3::date) - GREATEST(start_date, $2::date) + 1) FILTER (WHERE status = 'approved'), 0),
+			COALESCE(SUM(LEAST(end_date, $3::date) - GREATEST(start_date, $2::date) + 1) FILTER (WHERE status = 'pending'), 0)
+		FROM %s.leave_requests
+		WHERE staff_id = $1 AND start_date <= $3 AND end_date >= $2
+		  AND status IN ('pending', 'approved')
+		  AND ($4::uuid IS NULL OR id <> $4)
+		GROUP BY leave_type
+	`, r.schema)
+
+	first := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+	rows, err := r.db.QueryContext(ctx, query, staffID, first, last, excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum leave usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []entities.LeaveUsage
+	for rows.Next() {
+		u := entities.LeaveUsage{Year: year}
+		if err := rows.Scan(&u.LeaveType, &u.Approved, &u.Pending); err != nil {
+			return nil, fmt.Errorf("failed to scan leave usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// GetStaff returns the staff member's department, role and when they were added
+func (r *PostgresLeaveRepository) GetStaff(ctx context.Context, staffID uuid.UUID) (*entities.StaffInfo, error) {
+	query := fmt.Sprintf(`
+		SELECT id, department_id, position, COALESCE(created_at, NOW())
+		FROM %s.department_staff
+		WHERE id = $1
+	`, r.schema)
+
+	var s entities.StaffInfo
+	var position string
+	err := r.db.QueryRowContext(ctx, query, staffID).Scan(&s.ID, &s.DepartmentID, &position, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staff: %w", err)
+	}
+	s.Role = entities.StaffRole(position)
+	return &s, nil
+}
+
+const quotaColumns = `id, department_id, leave_type, annual_days, accrual, carry_over_max_days, created_at, updated_at`
+
+func scanQuota(row interface{ Scan(...any) error }) (*entities.LeaveQuota, error) {
+	var q entities.LeaveQuota
+	if err := row.Scan(&q.ID, &q.DepartmentID, &q.LeaveType, &q.AnnualDays, &q.Accrual,
+		&q.CarryOverMaxDays, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// GetQuota returns a department's quota of a leave type
+func (r *PostgresLeaveRepository) GetQuota(ctx context.Context, departmentID uuid.UUID, leaveType entities.LeaveType) (*entities.LeaveQuota, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s.leave_quotas WHERE department_id = $1 AND leave_type = $2`, quotaColumns, r.schema)
+	q, err := scanQuota(r.db.QueryRowContext(ctx, query, departmentID, leaveType))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave quota: %w", err)
+	}
+	return q, nil
+}
+
+// GetQuotaByID returns a quota by ID
+func (r *PostgresLeaveRepository) GetQuotaByID(ctx context.Context, id uuid.UUID) (*entities.LeaveQuota, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s.leave_quotas WHERE id = $1`, quotaColumns, r.schema)
+	q, err := scanQuota(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leave quota: %w", err)
+	}
+	return q, nil
+}
+
+// ListQuotas returns a department's quotas by leave type
+func (r *PostgresLeaveRepository) ListQuotas(ctx context.Context, departmentID uuid.UUID) ([]entities.LeaveQuota, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s.leave_quotas WHERE department_id = $1 ORDER BY leave_type`, quotaColumns, r.schema)
+	rows, err := r.db.QueryContext(ctx, query, departmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leave quotas: %w", err)
+	}
+	defer rows.Close()
+
+	quotas := []entities.LeaveQuota{}
+	for rows.Next() {
+		q, err := scanQuota(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan leave quota: %w", err)
+		}
+		quotas = append(quotas, *q)
+	}
+	return quotas, rows.Err()
+}
+
+// UpsertQuota creates or replaces the department's quota of the leave type
+func (r *PostgresLeaveRepository) UpsertQuota(ctx context.Context, q *entities.LeaveQuota) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s.leave_quotas (department_id, leave_type, annual_days, accrual, carry_over_max_days)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (department_id, leave_type) DO UPDATE SET
+			annual_days = EXCLUDED.annual_days,
+			accrual = EXCLUDED.accrual,
+			carry_over_max_days = EXCLUDED.carry_over_max_days,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, r.schema)
+
+	err := r.db.QueryRowContext(ctx, query, q.DepartmentID, q.LeaveType, q.AnnualDays, q.Accrual, q.CarryOverMaxDays).
+		Scan(&q.ID, &q.CreatedAt, &q.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save leave quota: %w", err)
+	}
+	return nil
+}
+
+// DeleteQuota removes a quota
+func (r *PostgresLeaveRepository) DeleteQuota(ctx context.Context, id uuid.UUID) error {
+	query := fmt.Sprintf(`DELETE FROM %s.leave_quotas WHERE id = $1`, r.schema)
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete leave quota: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return entities.ErrQuotaNotFound
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nurseshift/employee-leave-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// GetCoverageInputs loads what projecting a department's staffing over start..end needs
+func (r *PostgresLeaveRepository) GetCoverageInputs(ctx context.Context, departmentID uuid.UUID, start, end time.Time, excludeID *uuid.UUID) (*entities.CoverageInputs, error) {
+	in := &entities.CoverageInputs{WorkingDays: map[int]bool{}}
+
+	query := fmt.Sprintf(`
+		SELECT COALESCE(SUM(required_nurses), 0), COALESCE(SUM(required_assistants), 0)
+		FROM %s.shifts
+		WHERE department_id = $1 AND is_active = true
+	`, r.schema)
+	if err := r.db.QueryRowContext(ctx, query, departmentID).Scan(&in.RequiredNurses, &in.RequiredAssistants); err != nil {
+		return nil, fmt.Errorf("failed to sum shift requirements: %w", err)
+	}
+
+	query = fmt.Sprintf(`SELECT day_of_week, is_working_day FROM %s.working_days WHERE department_id = $1`, r.schema)
+	rows, err := r.db.QueryContext(ctx, query, departmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working days: %w", err)
+	}
+	for rows.Next() {
+		var dow int
+		var working bool
+		if err := rows.Scan(&dow, &working); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan working day: %w", err)
+		}
+		in.WorkingDays[dow] = working
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`
+		SELECT start_date, end_date
+		FROM %s.holidays
+		WHERE department_id = $1 AND start_date <= $3 AND end_date >= $2
+	`, r.schema)
+	rows, err = r.db.QueryContext(ctx, query, departmentID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holidays: %w", err)
+	}
+	for rows.Next() {
+		var h entities.DateRange
+		if err := rows.Scan(&h.Start, &h.End); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan holiday: %w", err)
+		}
+		in.Holidays = append(in.Holidays, h)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`
+		SELECT id, position
+		FROM %s.department_staff
+		WHERE department_id = $1 AND COALESCE(is_active, true) = true
+	`, r.schema)
+	rows, err = r.db.QueryContext(ctx, query, departmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department staff: %w", err)
+	}
+	for rows.Next() {
+		var s entities.CoverageStaff
+		var position string
+		if err := rows.Scan(&s.ID, &position); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan staff: %w", err)
+		}
+		s.Role = entities.StaffRole(position)
+		in.Staff = append(in.Staff, s)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`
+		SELECT id, staff_id, department_id, leave_type, start_date, end_date, status
+		FROM %s.leave_requests
+		WHERE department_id = $1 AND status = 'approved'
+		  AND start_date <= $3 AND end_date >= $2
+		  AND ($4::uuid IS NULL OR id <> $4)
+	`, r.schema)
+	rows, err = r.db.QueryContext(ctx, query, departmentID, start, end, excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get approved leave: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l entities.LeaveRequest
+		if err := rows.Scan(&l.ID, &l.StaffID, &l.DepartmentID, &l.LeaveType, &l.StartDate, &l.EndDate, &l.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan leave request: %w", err)
+		}
+		in.Leaves = append(in.Leaves, l)
+	}
+	return in, rows.Err()
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"nurseshift/employee-leave-service/internal/domain/entities"
+	"nurseshift/employee-leave-service/internal/infrastructure/eventbus"
 
 	"github.com/google/uuid"
 )
@@ -16,13 +17,16 @@ import (
 type PostgresLeaveRepository struct {
 	db     *sql.DB
 	schema string
+	outbox *eventbus.Outbox
 }
 
-// NewPostgresLeaveRepository creates a new PostgreSQL leave repository
-func NewPostgresLeaveRepository(db *sql.DB, schema string) *PostgresLeaveRepository {
+// NewPostgresLeaveRepository creates a new PostgreSQL leave repository. Approvals and rejections are
+// announced through outbox, which may be nil.
+func NewPostgresLeaveRepository(db *sql.DB, schema string, outbox *eventbus.Outbox) *PostgresLeaveRepository {
 	return &PostgresLeaveRepository{
 		db:     db,
 		schema: schema,
+		outbox: outbox,
 	}
 }
 
@@ -263,14 +267,17 @@ func (r *PostgresLeaveRepository) Update(ctx context.Context, id uuid.UUID, upda
 	return nil
 }
 
-// UpdateStatus updates the status of a leave request
+// UpdateStatus updates the status of a leave request. An approval or rejection is enqueued as a
+// domain event in the same transaction.
 func (r *PostgresLeaveRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.LeaveStatus, approverID *uuid.UUID, rejectionReason *string) error {
 	query := fmt.Sprintf(`
-		UPDATE %s.leave_requests SET
+		UPDATE %s.leave_requests lr SET
 			status = $2, approved_by = $3, approved_at = $4, 
 			rejection_reason = $5, updated_at = $6
-		WHERE id = $1
-	`, r.schema)
+		FROM (SELECT status FROM %s.leave_requests WHERE id = $1 FOR UPDATE) prev
+		WHERE lr.id = $1
+		RETURNING lr.staff_id, lr.department_id, lr.leave_type, lr.start_date, lr.end_date, prev.status
+	`, r.schema, r.schema)
 
 	var approvedAt *time.Time
 	if approverID != nil {
@@ -278,12 +285,54 @@ func (r *PostgresLeaveRepository) UpdateStatus(ctx context.Context, id uuid.UUID
 		approvedAt = &now
 	}
 
-	_, err := r.db.ExecContext(ctx, query, id, status, approverID, approvedAt, rejectionReason, time.Now())
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to update leave request status: %w", err)
 	}
+	defer tx.Rollback()
 
-	return nil
+	var leave entities.LeaveRequest
+	var previous entities.LeaveStatus
+	err = tx.QueryRowContext(ctx, query, id, status, approverID, approvedAt, rejectionReason, time.Now()).
+		Scan(&leave.StaffID, &leave.DepartmentID, &leave.LeaveType, &leave.StartDate, &leave.EndDate, &previous)
+	if err == sql.ErrNoRows {
+		return entities.ErrLeaveNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update leave request status: %w", err)
+	}
+
+	// deciding again the way it was already decided changes nothing worth announcing
+	decided := status == entities.LeaveStatusApproved || status == entities.LeaveStatusRejected
+	if r.outbox != nil && decided && previous != status {
+		eventType := eventbus.LeaveApproved
+		if status == entities.LeaveStatusRejected {
+			eventType = eventbus.LeaveRejected
+		}
+		payload := eventbus.LeavePayload{
+			LeaveID:         id.String(),
+			StaffID:         leave.StaffID.String(),
+			DepartmentID:    leave.DepartmentID.String(),
+			LeaveType:       string(leave.LeaveType),
+			StartDate:       leave.StartDate.Format("2006-01-02"),
+			EndDate:         leave.EndDate.Format("2006-01-02"),
+			Status:          string(status),
+			RejectionReason: rejectionReason,
+		}
+		if approverID != nil {
+			decidedBy := approverID.String()
+			payload.DecidedBy = &decidedBy
+		}
+		event, err := eventbus.NewEvent(eventType, id.String(), payload.DepartmentID, payload)
+		if err != nil {
+			return err
+		}
+		if err := r.outbox.Enqueue(ctx, tx, event); err != nil {
+			return fmt.Errorf("failed to enqueue %s: %w", eventType, err)
+		}
+	}
+
+	return tx.Commit()
 }
 
 // Delete deletes a leave request
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"nurseshift/employee-leave-service/internal/domain/entities"
+	"nurseshift/employee-leave-service/internal/infrastructure/access"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// checkAccess runs the department policy for the caller against the leaves of a department
+func (h *LeaveHandler) checkAccess(c *fiber.Ctx, departmentID string, act access.Action) error {
+	userID, _ := c.Locals("userID").(string)
+	role, _ := c.Locals("userRole").(string)
+	return h.access.Check(c.Context(), userID, role, departmentID, access.ResourceLeave, act)
+}
+
+// authorize answers with a department access denial unless the caller's role in the department
+// allows act on its leaves
+func (h *LeaveHandler) authorize(c *fiber.Ctx, departmentID string, act access.Action) (bool, error) {
+	if err := h.checkAccess(c, departmentID, act); err != nil {
+		return false, accessError(c, err)
+	}
+	return true, nil
+}
+
+// authorizeLeave is authorize for the department of an existing leave request
+func (h *LeaveHandler) authorizeLeave(c *fiber.Ctx, leaveID uuid.UUID, act access.Action) (bool, error) {
+	leave, err := h.leaveUseCase.GetLeaveByID(context.Background(), leaveID)
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to fetch leave request",
+			"error":   err.Error(),
+		})
+	}
+	if leave == nil {
+		return false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบคำขอลา",
+		})
+	}
+	return h.authorize(c, leave.DepartmentID.String(), act)
+}
+
+// readableLeaves keeps the leaves of the departments the caller may read
+func (h *LeaveHandler) readableLeaves(c *fiber.Ctx, leaves []entities.LeaveRequestWithDetails) ([]entities.LeaveRequestWithDetails, error) {
+	allowed := map[uuid.UUID]bool{}
+	out := make([]entities.LeaveRequestWithDetails, 0, len(leaves))
+	for _, l := range leaves {
+		ok, seen := allowed[l.DepartmentID]
+		if !seen {
+			err := h.checkAccess(c, l.DepartmentID.String(), access.ActionRead)
+			var denial *access.Denial
+			if err != nil && !errors.As(err, &denial) {
+				return nil, err
+			}
+			ok = err == nil
+			allowed[l.DepartmentID] = ok
+		}
+		if ok {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// accessError renders a failed department access check: the denial, or a lookup failure
+func accessError(c *fiber.Ctx, err error) error {
+	var denial *access.Denial
+	if errors.As(err, &denial) {
+		return c.Status(denial.Status).JSON(denial.Body())
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"status":  "error",
+		"message": "ไม่สามารถตรวจสอบสิทธิ์ในแผนกได้",
+		"error":   err.Error(),
+	})
+}
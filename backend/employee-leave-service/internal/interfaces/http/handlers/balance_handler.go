@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"nurseshift/employee-leave-service/internal/domain/entities"
+	"nurseshift/employee-leave-service/internal/infrastructure/access"
+	"nurseshift/employee-leave-service/internal/infrastructure/audit"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ruleError renders a leave request the leave rules refuse; refused is false for any other error
+func ruleError(c *fiber.Ctx, err error) (refused bool, _ error) {
+	var overlap *entities.OverlapError
+	var balance *entities.BalanceError
+	switch {
+	case errors.As(err, &overlap):
+		return true, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ช่วงวันลาซ้อนกับคำขอลาอื่นที่รออนุมัติหรืออนุมัติแล้ว",
+			"data":    overlap.Leaves,
+		})
+	case errors.As(err, &balance):
+		return true, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"status":  "error",
+			"message": "วันลาคงเหลือไม่พอ",
+			"data":    balance,
+		})
+	case errors.Is(err, entities.ErrInvalidDateRange):
+		return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "วันสิ้นสุดต้องไม่ก่อนวันเริ่มต้น",
+		})
+	case errors.Is(err, entities.ErrInvalidLeaveType):
+		return true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ประเภทการลาไม่ถูกต้อง",
+		})
+	}
+	return false, nil
+}
+
+// GetBalances returns a staff member's leave balances of a year
+func (h *LeaveHandler) GetBalances(c *fiber.Ctx) error {
+	staffID, err := uuid.Parse(c.Query("staffId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid staff ID",
+		})
+	}
+
+	now := time.Now()
+	year := now.Year()
+	if y := c.Query("year"); y != "" {
+		if year, err = strconv.Atoi(y); err != nil || year < 2000 || year > 2100 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Invalid year",
+			})
+		}
+	}
+	asOf := now
+	if d := c.Query("asOf"); d != "" {
+		if asOf, err = time.Parse("2006-01-02", d); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Invalid date format. Use YYYY-MM-DD",
+			})
+		}
+	}
+
+	staff, err := h.leaveUseCase.GetStaff(context.Background(), staffID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลพนักงานได้",
+			"error":   err.Error(),
+		})
+	}
+	if staff == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบพนักงาน",
+		})
+	}
+	if ok, err := h.authorize(c, staff.DepartmentID.String(), access.ActionRead); !ok {
+		return err
+	}
+
+	balances, err := h.leaveUseCase.GetBalances(context.Background(), staffID, year, asOf)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถคำนวณวันลาคงเหลือได้",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ดึงวันลาคงเหลือสำเร็จ",
+		"data":    balances,
+	})
+}
+
+// GetQuotas returns a department's leave quotas
+func (h *LeaveHandler) GetQuotas(c *fiber.Ctx) error {
+	departmentID, err := uuid.Parse(c.Query("departmentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid department ID",
+		})
+	}
+	if ok, err := h.authorize(c, departmentID.String(), access.ActionRead); !ok {
+		return err
+	}
+
+	quotas, err := h.leaveUseCase.ListQuotas(context.Background(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงโควตาวันลาได้",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ดึงโควตาวันลาสำเร็จ",
+		"data":    quotas,
+	})
+}
+
+// SaveQuota creates or replaces a department's quota of a leave type
+func (h *LeaveHandler) SaveQuota(c *fiber.Ctx) error {
+	var req struct {
+		DepartmentID     string  `json:"departmentId"`
+		LeaveType        string  `json:"leaveType"`
+		AnnualDays       float64 `json:"annualDays"`
+		Accrual          string  `json:"accrual"`
+		CarryOverMaxDays float64 `json:"carryOverMaxDays"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid request body",
+		})
+	}
+	departmentID, err := uuid.Parse(req.DepartmentID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid department ID",
+		})
+	}
+	if ok, err := h.authorize(c, departmentID.String(), access.ActionMutate); !ok {
+		return err
+	}
+
+	q := &entities.LeaveQuota{
+		DepartmentID:     departmentID,
+		LeaveType:        entities.LeaveType(req.LeaveType),
+		AnnualDays:       req.AnnualDays,
+		Accrual:          entities.AccrualMethod(req.Accrual),
+		CarryOverMaxDays: req.CarryOverMaxDays,
+	}
+	if err := h.leaveUseCase.SaveQuota(context.Background(), q); err != nil {
+		if errors.Is(err, entities.ErrInvalidLeaveType) || errors.Is(err, entities.ErrInvalidQuota) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ข้อมูลโควตาวันลาไม่ถูกต้อง",
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถบันทึกโควตาวันลาได้",
+			"error":   err.Error(),
+		})
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("leave_quota.save")
+	rec.Resource("leave_quota", q.ID.String())
+	rec.Department(departmentID.String())
+	rec.After(q)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "บันทึกโควตาวันลาสำเร็จ",
+		"data":    q,
+	})
+}
+
+// DeleteQuota removes a leave quota; the leave type is no longer limited in the department
+func (h *LeaveHandler) DeleteQuota(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid quota ID",
+		})
+	}
+
+	q, err := h.leaveUseCase.GetQuota(context.Background(), id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงโควตาวันลาได้",
+			"error":   err.Error(),
+		})
+	}
+	if q == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบโควตาวันลา",
+		})
+	}
+	if ok, err := h.authorize(c, q.DepartmentID.String(), access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("leave_quota.delete")
+	rec.Resource("leave_quota", q.ID.String())
+	rec.Department(q.DepartmentID.String())
+	rec.Before(q)
+
+	if err := h.leaveUseCase.DeleteQuota(context.Background(), id); err != nil {
+		if errors.Is(err, entities.ErrQuotaNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ไม่พบโควตาวันลา",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถลบโควตาวันลาได้",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ลบโควตาวันลาสำเร็จ",
+	})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"nurseshift/employee-leave-service/internal/domain/entities"
+	"nurseshift/employee-leave-service/internal/infrastructure/access"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GetLeave returns a leave request with the staffing its days would be left with
+func (h *LeaveHandler) GetLeave(c *fiber.Ctx) error {
+	leaveID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid leave ID format",
+		})
+	}
+
+	if ok, err := h.authorizeLeave(c, leaveID, access.ActionRead); !ok {
+		return err
+	}
+
+	leave, err := h.leaveUseCase.GetLeaveDetail(context.Background(), leaveID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลคำขอลาได้",
+			"error":   err.Error(),
+		})
+	}
+	if leave == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบคำขอลา",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ดึงข้อมูลคำขอลาสำเร็จ",
+		"data":    leave,
+	})
+}
+
+// GetLeaveCoverage is the approval pre-check: for each day of the leave, whether the nurses and
+// assistants left can still fill the department's shifts
+func (h *LeaveHandler) GetLeaveCoverage(c *fiber.Ctx) error {
+	leaveID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid leave ID format",
+		})
+	}
+
+	if ok, err := h.authorizeLeave(c, leaveID, access.ActionRead); !ok {
+		return err
+	}
+
+	coverage, err := h.leaveUseCase.GetCoverage(context.Background(), leaveID)
+	if err != nil {
+		if errors.Is(err, entities.ErrLeaveNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ไม่พบคำขอลา",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถคำนวณจำนวนบุคลากรที่เหลือได้",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "คำนวณจำนวนบุคลากรที่เหลือสำเร็จ",
+		"data":    coverage,
+	})
+}
@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"nurseshift/employee-leave-service/internal/domain/entities"
 	"nurseshift/employee-leave-service/internal/domain/usecases"
+	"nurseshift/employee-leave-service/internal/infrastructure/access"
+	"nurseshift/employee-leave-service/internal/infrastructure/audit"
 	"nurseshift/employee-leave-service/internal/infrastructure/database"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,16 +20,29 @@ import (
 type LeaveHandler struct {
 	leaveUseCase usecases.LeaveUseCase
 	db           *database.Connection
+	access       *access.Guard
 }
 
 // NewLeaveHandler creates a new leave handler
-func NewLeaveHandler(leaveUseCase usecases.LeaveUseCase, db *database.Connection) *LeaveHandler {
+func NewLeaveHandler(leaveUseCase usecases.LeaveUseCase, db *database.Connection, guard *access.Guard) *LeaveHandler {
 	return &LeaveHandler{
 		leaveUseCase: leaveUseCase,
 		db:           db,
+		access:       guard,
 	}
 }
 
+// auditLeave starts the audit record of a change to a leave request, with the request as it is now
+func (h *LeaveHandler) auditLeave(c *fiber.Ctx, action string, leaveID uuid.UUID) *audit.Recorder {
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action(action)
+	if before, err := h.leaveUseCase.GetLeaveByID(context.Background(), leaveID); err == nil && before != nil {
+		rec.Department(before.DepartmentID.String())
+		rec.Before(before)
+	}
+	return rec
+}
+
 // GetLeaves returns all leave requests with filters
 func (h *LeaveHandler) GetLeaves(c *fiber.Ctx) error {
 	// Get query parameters
@@ -52,6 +69,19 @@ func (h *LeaveHandler) GetLeaves(c *fiber.Ctx) error {
 		}
 	}
 
+	// only admins list leaves across departments
+	if c.Locals("userRole") != "admin" {
+		if departmentId == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ต้องระบุ departmentId",
+			})
+		}
+		if ok, err := h.authorize(c, departmentId, access.ActionRead); !ok {
+			return err
+		}
+	}
+
 	// Get leaves from use case
 	leaves, err := h.leaveUseCase.GetLeavesByFilter(context.Background(), filter)
 	if err != nil {
@@ -76,7 +106,10 @@ func (h *LeaveHandler) CreateLeave(c *fiber.Ctx) error {
 		EmployeeName   string `json:"employeeName" validate:"required"`
 		DepartmentID   string `json:"departmentId" validate:"required"`
 		DepartmentName string `json:"departmentName" validate:"required"`
-		Date           string `json:"date" validate:"required"`
+		Date           string `json:"date"`      // a one-day leave
+		StartDate      string `json:"startDate"` // or a range, instead of date
+		EndDate        string `json:"endDate"`
+		LeaveType      string `json:"leaveType"` // personal when empty
 		Reason         string `json:"reason"`
 	}
 
@@ -105,28 +138,53 @@ func (h *LeaveHandler) CreateLeave(c *fiber.Ctx) error {
 		})
 	}
 
-	// Parse date
-	date, err := time.Parse("2006-01-02", req.Date)
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionRequest); !ok {
+		return err
+	}
+
+	// Parse dates: a single date, or a start and an end
+	if req.StartDate == "" {
+		req.StartDate = req.Date
+	}
+	if req.EndDate == "" {
+		req.EndDate = req.StartDate
+	}
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"status":  "error",
 			"message": "Invalid date format. Use YYYY-MM-DD",
 		})
 	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid date format. Use YYYY-MM-DD",
+		})
+	}
+
+	leaveType := entities.LeaveTypePersonal // Default to personal leave
+	if req.LeaveType != "" {
+		leaveType = entities.LeaveType(req.LeaveType)
+	}
 
 	// Create leave request
 	leaveReq := entities.LeaveRequestCreate{
 		StaffID:      userID,
 		DepartmentID: deptID,
-		LeaveType:    entities.LeaveTypePersonal, // Default to personal leave
-		StartDate:    date,
-		EndDate:      date,
+		LeaveType:    leaveType,
+		StartDate:    startDate,
+		EndDate:      endDate,
 		Reason:       &req.Reason,
 	}
 
 	// Create leave through use case
 	leaveID, err := h.leaveUseCase.CreateLeave(context.Background(), leaveReq)
 	if err != nil {
+		if refused, rerr := ruleError(c, err); refused {
+			return rerr
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
 			"message": "Failed to create leave request",
@@ -144,6 +202,11 @@ func (h *LeaveHandler) CreateLeave(c *fiber.Ctx) error {
 		})
 	}
 
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("leave.create")
+	rec.Resource("leave", leaveID.String())
+	rec.After(leave)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"status":  "success",
 		"message": "สร้างวันหยุดพนักงานสำเร็จ",
@@ -162,6 +225,9 @@ func (h *LeaveHandler) GetLeavesByDepartment(c *fiber.Ctx) error {
 			"message": "Invalid department ID format",
 		})
 	}
+	if ok, err := h.authorize(c, departmentId, access.ActionRead); !ok {
+		return err
+	}
 
 	leaves, err := h.leaveUseCase.GetLeavesByDepartment(context.Background(), deptID)
 	if err != nil {
@@ -199,6 +265,9 @@ func (h *LeaveHandler) GetLeavesByEmployee(c *fiber.Ctx) error {
 			"error":   err.Error(),
 		})
 	}
+	if leaves, err = h.readableLeaves(c, leaves); err != nil {
+		return accessError(c, err)
+	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
@@ -220,8 +289,11 @@ func (h *LeaveHandler) UpdateLeave(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Date   *string `json:"date"`
-		Reason *string `json:"reason"`
+		Date      *string `json:"date"`
+		StartDate *string `json:"startDate"`
+		EndDate   *string `json:"endDate"`
+		LeaveType *string `json:"leaveType"`
+		Reason    *string `json:"reason"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -241,13 +313,36 @@ func (h *LeaveHandler) UpdateLeave(c *fiber.Ctx) error {
 			update.EndDate = &date
 		}
 	}
+	if req.StartDate != nil {
+		if date, err := time.Parse("2006-01-02", *req.StartDate); err == nil {
+			update.StartDate = &date
+		}
+	}
+	if req.EndDate != nil {
+		if date, err := time.Parse("2006-01-02", *req.EndDate); err == nil {
+			update.EndDate = &date
+		}
+	}
+	if req.LeaveType != nil {
+		leaveType := entities.LeaveType(*req.LeaveType)
+		update.LeaveType = &leaveType
+	}
 
 	if req.Reason != nil {
 		update.Reason = req.Reason
 	}
 
+	if ok, err := h.authorizeLeave(c, leaveID, access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := h.auditLeave(c, "leave.update", leaveID)
+
 	// Update leave through use case
 	if err := h.leaveUseCase.UpdateLeave(context.Background(), leaveID, update); err != nil {
+		if refused, rerr := ruleError(c, err); refused {
+			return rerr
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
 			"message": "Failed to update leave request",
@@ -265,6 +360,8 @@ func (h *LeaveHandler) UpdateLeave(c *fiber.Ctx) error {
 		})
 	}
 
+	rec.After(leave)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "อัปเดตวันหยุดพนักงานสำเร็จ",
@@ -284,6 +381,12 @@ func (h *LeaveHandler) DeleteLeave(c *fiber.Ctx) error {
 		})
 	}
 
+	if ok, err := h.authorizeLeave(c, leaveID, access.ActionMutate); !ok {
+		return err
+	}
+
+	h.auditLeave(c, "leave.delete", leaveID)
+
 	if err := h.leaveUseCase.DeleteLeave(context.Background(), leaveID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
@@ -310,7 +413,16 @@ func (h *LeaveHandler) ToggleLeave(c *fiber.Ctx) error {
 		})
 	}
 
+	if ok, err := h.authorizeLeave(c, leaveID, access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := h.auditLeave(c, "leave.toggle", leaveID)
+
 	if err := h.leaveUseCase.ToggleLeaveStatus(context.Background(), leaveID); err != nil {
+		if refused, rerr := ruleError(c, err); refused {
+			return rerr
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
 			"message": "Failed to toggle leave status",
@@ -328,6 +440,8 @@ func (h *LeaveHandler) ToggleLeave(c *fiber.Ctx) error {
 		})
 	}
 
+	rec.After(leave)
+
 	status := "เปิดใช้งาน"
 	if leave.Status == entities.LeaveStatusCancelled {
 		status = "ปิดใช้งาน"
@@ -340,6 +454,133 @@ func (h *LeaveHandler) ToggleLeave(c *fiber.Ctx) error {
 	})
 }
 
+// ApproveLeave approves a leave request; schedule-service is notified to propose a roster repair
+func (h *LeaveHandler) ApproveLeave(c *fiber.Ctx) error {
+	leaveID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid leave ID format",
+		})
+	}
+	approverID, err := uuid.Parse(fmt.Sprint(c.Locals("userID")))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid user",
+		})
+	}
+
+	if ok, err := h.authorizeLeave(c, leaveID, access.ActionMutate); !ok {
+		return err
+	}
+
+	// override approves despite a staffing shortfall the coverage policy would block
+	var req struct {
+		Override bool `json:"override"`
+	}
+	_ = c.BodyParser(&req)
+
+	rec := h.auditLeave(c, "leave.approve", leaveID)
+
+	coverage, err := h.leaveUseCase.ApproveLeave(context.Background(), leaveID, approverID, req.Override)
+	if err != nil {
+		if errors.Is(err, entities.ErrLeaveNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ไม่พบคำขอลา",
+			})
+		}
+		if errors.Is(err, entities.ErrUnderstaffed) {
+			rec.Skip()
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"status":  "error",
+				"message": "อนุมัติไม่ได้ จำนวนบุคลากรที่เหลือไม่พอสำหรับเวรในบางวัน",
+				"data":    coverage,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to approve leave request",
+			"error":   err.Error(),
+		})
+	}
+
+	leave, err := h.leaveUseCase.GetLeaveByID(context.Background(), leaveID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Leave approved but failed to fetch details",
+			"error":   err.Error(),
+		})
+	}
+
+	rec.After(leave)
+
+	message := "อนุมัติวันหยุดพนักงานสำเร็จ"
+	if !coverage.Covered {
+		message = "อนุมัติวันหยุดพนักงานสำเร็จ แต่จำนวนบุคลากรที่เหลือไม่พอสำหรับเวรในบางวัน"
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": message,
+		"data":    entities.LeaveDetail{LeaveRequestWithDetails: *leave, Coverage: coverage},
+	})
+}
+
+// RejectLeave rejects a leave request with a reason
+func (h *LeaveHandler) RejectLeave(c *fiber.Ctx) error {
+	leaveID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid leave ID format",
+		})
+	}
+	approverID, err := uuid.Parse(fmt.Sprint(c.Locals("userID")))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid user",
+		})
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.BodyParser(&req)
+
+	if ok, err := h.authorizeLeave(c, leaveID, access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := h.auditLeave(c, "leave.reject", leaveID)
+
+	if err := h.leaveUseCase.RejectLeave(context.Background(), leaveID, approverID, req.Reason); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to reject leave request",
+			"error":   err.Error(),
+		})
+	}
+
+	leave, err := h.leaveUseCase.GetLeaveByID(context.Background(), leaveID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Leave rejected but failed to fetch details",
+			"error":   err.Error(),
+		})
+	}
+
+	rec.After(leave)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ปฏิเสธวันหยุดพนักงานสำเร็จ",
+		"data":    leave,
+	})
+}
+
 // Health returns service health status
 func (h *LeaveHandler) Health(c *fiber.Ctx) error {
 	// Check database connection
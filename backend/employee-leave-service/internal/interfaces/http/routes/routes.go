@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"nurseshift/employee-leave-service/internal/infrastructure/audit"
 	"nurseshift/employee-leave-service/internal/interfaces/http/handlers"
 	mw "nurseshift/employee-leave-service/internal/interfaces/http/middleware"
 
@@ -8,20 +9,29 @@ import (
 )
 
 // SetupRoutes configures all the routes for the application
-func SetupRoutes(app *fiber.App, h *handlers.LeaveHandler) {
+func SetupRoutes(app *fiber.App, h *handlers.LeaveHandler, auditRepo *audit.Repository) {
 	api := app.Group("/api/v1")
 
 	// Leave request routes with authentication
 	leaves := api.Group("/leaves")
 	leaves.Use(mw.AuthMiddleware())
+	leaves.Use(audit.Middleware(auditRepo, "leave"))
 	{
 		leaves.Get("/", h.GetLeaves)
 		leaves.Post("/", h.CreateLeave)
 		leaves.Get("/departments/:departmentId", h.GetLeavesByDepartment)
 		leaves.Get("/employees/:employeeId", h.GetLeavesByEmployee)
+		leaves.Get("/balances", h.GetBalances)
+		leaves.Get("/quotas", h.GetQuotas)
+		leaves.Put("/quotas", h.SaveQuota)
+		leaves.Delete("/quotas/:id", h.DeleteQuota)
+		leaves.Get("/:id", h.GetLeave)
+		leaves.Get("/:id/coverage", h.GetLeaveCoverage)
 		leaves.Put("/:id", h.UpdateLeave)
 		leaves.Delete("/:id", h.DeleteLeave)
 		leaves.Put("/:id/toggle", h.ToggleLeave)
+		leaves.Put("/:id/approve", h.ApproveLeave)
+		leaves.Put("/:id/reject", h.RejectLeave)
 	}
 
 	// Health check (no auth required)
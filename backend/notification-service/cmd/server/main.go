@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -10,7 +11,11 @@ import (
 	"time"
 
 	"nurseshift/notification-service/internal/infrastructure/config"
+	dbpkg "nurseshift/notification-service/internal/infrastructure/database"
+	"nurseshift/notification-service/internal/infrastructure/eventbus"
+	"nurseshift/notification-service/internal/infrastructure/repositories"
 	"nurseshift/notification-service/internal/interfaces/http/handlers"
+	"nurseshift/notification-service/internal/interfaces/http/middleware"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -41,7 +46,7 @@ func main() {
 	app.Use(helmet.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     strings.Join(cfg.CORS.Origins, ","),
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
 		AllowCredentials: true,
 	}))
@@ -50,12 +55,24 @@ func main() {
 		app.Use(logger.New())
 	}
 
-	// Initialize handlers
-	notificationHandler := handlers.NewNotificationHandler()
+	// Initialize DB + repository + handlers
+	conn, err := dbpkg.NewConnection(cfg)
+	if err != nil {
+		log.Fatalf("DB connect error: %v", err)
+	}
+	defer conn.Close()
+	repo := repositories.NewPostgresNotificationRepository(conn.DB, cfg.Database.Schema)
+	inbox := eventbus.NewInbox(conn.DB, cfg.Database.Schema)
+	notificationHandler := handlers.NewNotificationHandler(repo, inbox)
+
+	// Retention: purge old notifications in the background
+	stopRetention := make(chan struct{})
+	go runRetention(repo, cfg.Retention, stopRetention)
 
 	// Routes
 	api := app.Group("/api/v1")
 	notifications := api.Group("/notifications")
+	notifications.Use(middleware.AuthMiddleware())
 	{
 		notifications.Get("/", notificationHandler.GetNotifications)
 		notifications.Get("/stats", notificationHandler.GetNotificationStats)
@@ -65,6 +82,12 @@ func main() {
 		notifications.Delete("/:id", notificationHandler.DeleteNotification)
 	}
 
+	// Service-to-service notifications
+	internal := api.Group("/internal", middleware.InternalServiceMiddleware())
+	internal.Post("/notifications", notificationHandler.CreateInternalNotification)
+	// Domain events delivered by the other services' outbox dispatchers
+	internal.Post("/events", notificationHandler.ReceiveEvent)
+
 	// Health check
 	app.Get("/health", notificationHandler.Health)
 
@@ -82,8 +105,42 @@ func main() {
 	<-c
 
 	fmt.Println("\n🛑 Shutting down Notification Service...")
+	close(stopRetention)
 	app.Shutdown()
 	fmt.Println("✅ Notification Service stopped gracefully")
 }
 
+// runRetention deletes read notifications older than ReadDays and all notifications older than MaxDays,
+// once at startup and then every IntervalHours
+func runRetention(repo *repositories.PostgresNotificationRepository, cfg config.RetentionConfig, stop <-chan struct{}) {
+	if cfg.IntervalHours <= 0 {
+		return
+	}
+	purge := func() {
+		now := time.Now()
+		readBefore := now.AddDate(0, 0, -cfg.ReadDays)
+		allBefore := now.AddDate(0, 0, -cfg.MaxDays)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		n, err := repo.Purge(ctx, readBefore, allBefore)
+		if err != nil {
+			log.Printf("Notification retention failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("Notification retention purged %d notifications", n)
+		}
+	}
 
+	purge()
+	ticker := time.NewTicker(time.Duration(cfg.IntervalHours) * time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			purge()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationType mirrors the notification_type enum
+type NotificationType string
+
+const (
+	NotificationTypeSchedule NotificationType = "schedule"
+	NotificationTypeLeave    NotificationType = "leave"
+	NotificationTypeSystem   NotificationType = "system"
+	NotificationTypePayment  NotificationType = "payment"
+	NotificationTypeReminder NotificationType = "reminder"
+	NotificationTypeHoliday  NotificationType = "holiday"
+)
+
+// IsValid reports whether t is one of the notification_type values
+func (t NotificationType) IsValid() bool {
+	switch t {
+	case NotificationTypeSchedule, NotificationTypeLeave, NotificationTypeSystem,
+		NotificationTypePayment, NotificationTypeReminder, NotificationTypeHoliday:
+		return true
+	}
+	return false
+}
+
+// NotificationPriority mirrors the notification_priority enum
+type NotificationPriority string
+
+const (
+	NotificationPriorityLow    NotificationPriority = "low"
+	NotificationPriorityMedium NotificationPriority = "medium"
+	NotificationPriorityHigh   NotificationPriority = "high"
+)
+
+// IsValid reports whether p is one of the notification_priority values
+func (p NotificationPriority) IsValid() bool {
+	switch p {
+	case NotificationPriorityLow, NotificationPriorityMedium, NotificationPriorityHigh:
+		return true
+	}
+	return false
+}
+
+// Notification represents a row of the notifications table
+type Notification struct {
+	ID        uuid.UUID            `json:"id"`
+	UserID    uuid.UUID            `json:"userId"`
+	Type      NotificationType     `json:"type"`
+	Title     string               `json:"title"`
+	Message   string               `json:"message"`
+	Priority  NotificationPriority `json:"priority"`
+	IsRead    bool                 `json:"isRead"`
+	ReadAt    *time.Time           `json:"readAt"`
+	ActionURL *string              `json:"actionUrl"`
+	Data      json.RawMessage      `json:"data,omitempty"`
+	Timestamp time.Time            `json:"timestamp"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+}
+
+// NotificationFilter represents the filters of a notification listing
+type NotificationFilter struct {
+	UserID   uuid.UUID
+	Type     *NotificationType
+	Priority *NotificationPriority
+	IsRead   *bool
+	Page     int
+	Limit    int
+}
+
+// NotificationStats represents per-user notification counters
+type NotificationStats struct {
+	Total         int            `json:"total"`
+	Unread        int            `json:"unread"`
+	Read          int            `json:"read"`
+	HighPriority  int            `json:"highPriority"`
+	TypeBreakdown map[string]int `json:"typeBreakdown"`
+}
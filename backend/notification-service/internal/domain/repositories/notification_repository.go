@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nurseshift/notification-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotificationNotFound is returned when a notification does not exist or belongs to another user
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// NotificationRepository defines the interface for notification data access
+type NotificationRepository interface {
+	// Create stores a new notification
+	Create(ctx context.Context, n *entities.Notification) error
+
+	// CreateMany stores several notifications at once; none is stored when one fails
+	CreateMany(ctx context.Context, ns []*entities.Notification) error
+
+	// StaffUserID returns the account of a department staff member, matched by email; nil when the
+	// staff member has none
+	StaffUserID(ctx context.Context, staffID uuid.UUID) (*uuid.UUID, error)
+
+	// DepartmentUserIDs returns the members of a department together with its head
+	DepartmentUserIDs(ctx context.Context, departmentID uuid.UUID) ([]uuid.UUID, error)
+
+	// List returns one page of a user's notifications, newest first, and the total matching the filter
+	List(ctx context.Context, filter entities.NotificationFilter) ([]entities.Notification, int, error)
+
+	// MarkAsRead marks one of the user's notifications as read
+	MarkAsRead(ctx context.Context, id, userID uuid.UUID) (*entities.Notification, error)
+
+	// MarkAllAsRead marks every unread notification of the user as read
+	MarkAllAsRead(ctx context.Context, userID uuid.UUID) (int64, error)
+
+	// Delete removes one of the user's notifications
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+
+	// Stats returns the user's notification counters
+	Stats(ctx context.Context, userID uuid.UUID) (*entities.NotificationStats, error)
+
+	// Purge applies retention: read notifications older than readBefore and all notifications older
+	// than allBefore are deleted
+	Purge(ctx context.Context, readBefore, allBefore time.Time) (int64, error)
+}
@@ -11,12 +11,13 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Security SecurityConfig
-	CORS     CORSConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Security  SecurityConfig
+	CORS      CORSConfig
+	Retention RetentionConfig
 }
 
 // ServerConfig holds server-related configuration
@@ -65,6 +66,13 @@ type CORSConfig struct {
 	Credentials bool
 }
 
+// RetentionConfig holds notification retention configuration
+type RetentionConfig struct {
+	ReadDays      int // read notifications older than this are purged
+	MaxDays       int // every notification older than this is purged
+	IntervalHours int
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists
@@ -108,6 +116,11 @@ func Load() (*Config, error) {
 			Origins:     strings.Split(getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:3002"), ","),
 			Credentials: getEnvAsBool("CORS_CREDENTIALS", true),
 		},
+		Retention: RetentionConfig{
+			ReadDays:      getEnvAsInt("NOTIFICATION_RETENTION_READ_DAYS", 30),
+			MaxDays:       getEnvAsInt("NOTIFICATION_RETENTION_DAYS", 180),
+			IntervalHours: getEnvAsInt("NOTIFICATION_RETENTION_INTERVAL_HOURS", 6),
+		},
 	}
 
 	if err := config.validate(); err != nil {
@@ -0,0 +1,289 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"nurseshift/notification-service/internal/domain/entities"
+	domainrepos "nurseshift/notification-service/internal/domain/repositories"
+
+	"github.com/google/uuid"
+)
+
+// PostgresNotificationRepository implements NotificationRepository for PostgreSQL
+type PostgresNotificationRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewPostgresNotificationRepository creates a new PostgreSQL notification repository
+func NewPostgresNotificationRepository(db *sql.DB, schema string) *PostgresNotificationRepository {
+	return &PostgresNotificationRepository{
+		db:     db,
+		schema: schema,
+	}
+}
+
+const notificationColumns = `id, user_id, type::text, priority::text, title, message, action_url,
+	COALESCE(is_read, false), read_at, data, created_at`
+
+func scanNotification(row interface{ Scan(...any) error }) (*entities.Notification, error) {
+	var n entities.Notification
+	var data []byte
+	var priority sql.NullString
+	if err := row.Scan(&n.ID, &n.UserID, &n.Type, &priority, &n.Title, &n.Message, &n.ActionURL,
+		&n.IsRead, &n.ReadAt, &data, &n.CreatedAt); err != nil {
+		return nil, err
+	}
+	n.Priority = entities.NotificationPriorityMedium
+	if priority.Valid {
+		n.Priority = entities.NotificationPriority(priority.String)
+	}
+	if len(data) > 0 {
+		n.Data = data
+	}
+	n.Timestamp = n.CreatedAt
+	n.UpdatedAt = n.CreatedAt
+	if n.ReadAt != nil {
+		n.UpdatedAt = *n.ReadAt
+	}
+	return &n, nil
+}
+
+// Create stores a new notification
+func (r *PostgresNotificationRepository) Create(ctx context.Context, n *entities.Notification) error {
+	return r.create(ctx, r.db, n)
+}
+
+// CreateMany stores several notifications in one transaction
+func (r *PostgresNotificationRepository) CreateMany(ctx context.Context, ns []*entities.Notification) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create notifications: %w", err)
+	}
+	defer tx.Rollback()
+	for _, n := range ns {
+		if err := r.create(ctx, tx, n); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresNotificationRepository) create(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, n *entities.Notification) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	var data any
+	if len(n.Data) > 0 {
+		data = []byte(n.Data)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s.notifications (id, user_id, type, priority, title, message, action_url, is_read, data, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, false, $8, NOW())
+		RETURNING created_at
+	`, r.schema)
+	err := q.QueryRowContext(ctx, query,
+		n.ID, n.UserID, string(n.Type), string(n.Priority), n.Title, n.Message, n.ActionURL, data,
+	).Scan(&n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	n.IsRead = false
+	n.Timestamp = n.CreatedAt
+	n.UpdatedAt = n.CreatedAt
+	return nil
+}
+
+// StaffUserID returns the active account whose email is the staff member's
+func (r *PostgresNotificationRepository) StaffUserID(ctx context.Context, staffID uuid.UUID) (*uuid.UUID, error) {
+	query := fmt.Sprintf(`
+		SELECT u.id
+		FROM %s.department_staff ds
+		JOIN %s.users u ON u.email = ds.email
+		WHERE ds.id = $1 AND u.status = 'active'
+	`, r.schema, r.schema)
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, staffID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find staff account: %w", err)
+	}
+	return &id, nil
+}
+
+// DepartmentUserIDs returns the members of a department and its head, each once
+func (r *PostgresNotificationRepository) DepartmentUserIDs(ctx context.Context, departmentID uuid.UUID) ([]uuid.UUID, error) {
+	query := fmt.Sprintf(`
+		SELECT user_id FROM %s.department_users WHERE department_id = $1
+		UNION
+		SELECT head_user_id FROM %s.departments WHERE id = $1 AND head_user_id IS NOT NULL
+	`, r.schema, r.schema)
+	rows, err := r.db.QueryContext(ctx, query, departmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list department users: %w", err)
+	}
+	defer rows.Close()
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// List returns one page of a user's notifications, newest first, and the total matching the filter
+func (r *PostgresNotificationRepository) List(ctx context.Context, filter entities.NotificationFilter) ([]entities.Notification, int, error) {
+	where := []string{"user_id = $1"}
+	args := []any{filter.UserID}
+	if filter.Type != nil {
+		args = append(args, string(*filter.Type))
+		where = append(where, fmt.Sprintf("type::text = $%d", len(args)))
+	}
+	if filter.Priority != nil {
+		args = append(args, string(*filter.Priority))
+		where = append(where, fmt.Sprintf("priority::text = $%d", len(args)))
+	}
+	if filter.IsRead != nil {
+		args = append(args, *filter.IsRead)
+		where = append(where, fmt.Sprintf("COALESCE(is_read, false) = $%d", len(args)))
+	}
+	whereSQL := strings.Join(where, " AND ")
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s.notifications WHERE %s", r.schema, whereSQL)
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s.notifications WHERE %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d",
+		notificationColumns, r.schema, whereSQL, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []entities.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, *n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating notifications: %w", err)
+	}
+	return notifications, total, nil
+}
+
+// MarkAsRead marks one of the user's notifications as read; read_at keeps the first read time
+func (r *PostgresNotificationRepository) MarkAsRead(ctx context.Context, id, userID uuid.UUID) (*entities.Notification, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s.notifications
+		SET is_read = true, read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+		RETURNING %s
+	`, r.schema, notificationColumns)
+	n, err := scanNotification(r.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, domainrepos.ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark notification as read: %w", err)
+	}
+	return n, nil
+}
+
+// MarkAllAsRead marks every unread notification of the user as read
+func (r *PostgresNotificationRepository) MarkAllAsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s.notifications
+		SET is_read = true, read_at = NOW()
+		WHERE user_id = $1 AND COALESCE(is_read, false) = false
+	`, r.schema)
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Delete removes one of the user's notifications
+func (r *PostgresNotificationRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	query := fmt.Sprintf("DELETE FROM %s.notifications WHERE id = $1 AND user_id = $2", r.schema)
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return domainrepos.ErrNotificationNotFound
+	}
+	return nil
+}
+
+// Stats returns the user's notification counters
+func (r *PostgresNotificationRepository) Stats(ctx context.Context, userID uuid.UUID) (*entities.NotificationStats, error) {
+	query := fmt.Sprintf(`
+		SELECT type::text,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE COALESCE(is_read, false) = false),
+			COUNT(*) FILTER (WHERE priority::text = 'high')
+		FROM %s.notifications
+		WHERE user_id = $1
+		GROUP BY type
+	`, r.schema)
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &entities.NotificationStats{TypeBreakdown: map[string]int{}}
+	for rows.Next() {
+		var notificationType string
+		var total, unread, high int
+		if err := rows.Scan(&notificationType, &total, &unread, &high); err != nil {
+			return nil, fmt.Errorf("failed to scan notification stats: %w", err)
+		}
+		stats.Total += total
+		stats.Unread += unread
+		stats.HighPriority += high
+		stats.TypeBreakdown[notificationType] = total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification stats: %w", err)
+	}
+	stats.Read = stats.Total - stats.Unread
+	return stats, nil
+}
+
+// Purge applies retention: read notifications older than readBefore and all notifications older than
+// allBefore are deleted
+func (r *PostgresNotificationRepository) Purge(ctx context.Context, readBefore, allBefore time.Time) (int64, error) {
+	query := fmt.Sprintf(`
+		DELETE FROM %s.notifications
+		WHERE (COALESCE(is_read, false) = true AND created_at < $1) OR created_at < $2
+	`, r.schema)
+	result, err := r.db.ExecContext(ctx, query, readBefore, allBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"nurseshift/notification-service/internal/domain/entities"
+	domainrepos "nurseshift/notification-service/internal/domain/repositories"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// testRepository runs against TEST_DATABASE_URL in a throwaway schema holding only the notifications
+// table; without the variable the test is skipped
+func testRepository(t *testing.T) *PostgresNotificationRepository {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "notification_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	ddl := fmt.Sprintf(`
+		CREATE SCHEMA %[1]s;
+		CREATE TABLE %[1]s.notifications (
+			id UUID PRIMARY KEY,
+			user_id UUID,
+			type TEXT NOT NULL,
+			priority TEXT DEFAULT 'medium',
+			title VARCHAR(255) NOT NULL,
+			message TEXT NOT NULL,
+			action_url VARCHAR(500),
+			is_read BOOLEAN DEFAULT false,
+			read_at TIMESTAMP WITH TIME ZONE,
+			data JSONB,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		)`, schema)
+	if _, err := db.Exec(ddl); err != nil {
+		_ = db.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		_ = db.Close()
+	})
+	return NewPostgresNotificationRepository(db, schema)
+}
+
+func TestNotificationsAreScopedToTheirUser(t *testing.T) {
+	repo := testRepository(t)
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+	mine := &entities.Notification{UserID: alice, Type: entities.NotificationTypeSchedule, Priority: entities.NotificationPriorityHigh, Title: "a", Message: "a"}
+	theirs := &entities.Notification{UserID: bob, Type: entities.NotificationTypeLeave, Priority: entities.NotificationPriorityMedium, Title: "b", Message: "b"}
+	if err := repo.CreateMany(ctx, []*entities.Notification{mine, theirs}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, total, err := repo.List(ctx, entities.NotificationFilter{UserID: alice, Page: 1, Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(list) != 1 || list[0].ID != mine.ID {
+		t.Fatalf("alice lists %d of %d notifications, want only her own", len(list), total)
+	}
+
+	if _, err := repo.MarkAsRead(ctx, theirs.ID, alice); !errors.Is(err, domainrepos.ErrNotificationNotFound) {
+		t.Errorf("alice marking bob's notification read: %v, want not found", err)
+	}
+	if err := repo.Delete(ctx, theirs.ID, alice); !errors.Is(err, domainrepos.ErrNotificationNotFound) {
+		t.Errorf("alice deleting bob's notification: %v, want not found", err)
+	}
+	if n, err := repo.MarkAllAsRead(ctx, alice); err != nil || n != 1 {
+		t.Errorf("alice marking all read touched %d rows (%v), want 1", n, err)
+	}
+
+	stats, err := repo.Stats(ctx, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 1 || stats.Unread != 1 {
+		t.Errorf("bob's stats after alice's calls = %+v, want his one notification still unread", stats)
+	}
+	read, err := repo.MarkAsRead(ctx, theirs.ID, bob)
+	if err != nil || !read.IsRead || read.ReadAt == nil {
+		t.Errorf("bob marking his own notification read: %+v, %v", read, err)
+	}
+}
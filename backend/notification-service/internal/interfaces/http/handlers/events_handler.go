@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"nurseshift/notification-service/internal/domain/entities"
+	"nurseshift/notification-service/internal/infrastructure/eventbus"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// eventConsumer names this service in event_inbox
+const eventConsumer = "notification-service"
+
+// ReceiveEvent turns the domain events other services deliver from their outbox into notifications.
+// Delivery is at least once, so an event already handled is acknowledged without notifying again;
+// events this service does not react to are acknowledged as well.
+func (h *NotificationHandler) ReceiveEvent(c *fiber.Ctx) error {
+	var e eventbus.Event
+	if err := c.BodyParser(&e); err != nil || e.ID == uuid.Nil || e.Type == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ข้อมูลเหตุการณ์ไม่ถูกต้อง",
+		})
+	}
+
+	var build func(ctx context.Context) ([]*entities.Notification, error)
+	switch e.Type {
+	case eventbus.LeaveApproved, eventbus.LeaveRejected:
+		var p eventbus.LeavePayload
+		if err := e.Decode(&p); err != nil {
+			return badEvent(c, err)
+		}
+		build = func(ctx context.Context) ([]*entities.Notification, error) { return h.leaveNotifications(ctx, e, p) }
+	case eventbus.SchedulePublished:
+		var p eventbus.SchedulePublishedPayload
+		if err := e.Decode(&p); err != nil {
+			return badEvent(c, err)
+		}
+		build = func(ctx context.Context) ([]*entities.Notification, error) { return h.scheduleNotifications(ctx, e, p) }
+	case eventbus.PaymentApproved:
+		var p eventbus.PaymentApprovedPayload
+		if err := e.Decode(&p); err != nil {
+			return badEvent(c, err)
+		}
+		build = func(ctx context.Context) ([]*entities.Notification, error) { return paymentNotifications(e, p) }
+	default:
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "success",
+			"message": "ไม่มีการดำเนินการสำหรับเหตุการณ์นี้",
+		})
+	}
+
+	created := 0
+	handled, err := h.inbox.Handle(c.Context(), eventConsumer, e, func(ctx context.Context) error {
+		ns, err := build(ctx)
+		if err != nil || len(ns) == 0 {
+			return err
+		}
+		if err := h.repo.CreateMany(ctx, ns); err != nil {
+			return err
+		}
+		created = len(ns)
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถสร้างการแจ้งเตือนได้",
+			"error":   err.Error(),
+		})
+	}
+	if !handled {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "success",
+			"message": "เคยได้รับเหตุการณ์นี้แล้ว",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "สร้างการแจ้งเตือนสำเร็จ",
+		"data":    fiber.Map{"created": created},
+	})
+}
+
+func badEvent(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"status":  "error",
+		"message": "ข้อมูลเหตุการณ์ไม่ถูกต้อง",
+		"error":   err.Error(),
+	})
+}
+
+// leaveNotifications tells the staff member, when they have an account, how their leave was decided
+func (h *NotificationHandler) leaveNotifications(ctx context.Context, e eventbus.Event, p eventbus.LeavePayload) ([]*entities.Notification, error) {
+	staffID, err := uuid.Parse(p.StaffID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := h.repo.StaffUserID(ctx, staffID)
+	if err != nil || userID == nil {
+		return nil, err
+	}
+	n := &entities.Notification{
+		UserID:    *userID,
+		Type:      entities.NotificationTypeLeave,
+		Title:     "คำขอลาได้รับการอนุมัติ",
+		Message:   fmt.Sprintf("คำขอลาวันที่ %s ถึง %s ได้รับการอนุมัติแล้ว", p.StartDate, p.EndDate),
+		Priority:  entities.NotificationPriorityMedium,
+		ActionURL: strPtr("/dashboard/employee-leaves"),
+		Data:      e.Payload,
+	}
+	if e.Type == eventbus.LeaveRejected {
+		n.Title = "คำขอลาไม่ได้รับการอนุมัติ"
+		n.Message = fmt.Sprintf("คำขอลาวันที่ %s ถึง %s ไม่ได้รับการอนุมัติ", p.StartDate, p.EndDate)
+		if p.RejectionReason != nil && *p.RejectionReason != "" {
+			n.Message += " เหตุผล: " + *p.RejectionReason
+		}
+		n.Priority = entities.NotificationPriorityHigh
+	}
+	return []*entities.Notification{n}, nil
+}
+
+// scheduleNotifications tells every member of the department, except whoever published, that the
+// month's roster is out
+func (h *NotificationHandler) scheduleNotifications(ctx context.Context, e eventbus.Event, p eventbus.SchedulePublishedPayload) ([]*entities.Notification, error) {
+	departmentID, err := uuid.Parse(p.DepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs, err := h.repo.DepartmentUserIDs(ctx, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	var ns []*entities.Notification
+	for _, id := range userIDs {
+		if id.String() == p.PublishedBy {
+			continue
+		}
+		ns = append(ns, &entities.Notification{
+			UserID:    id,
+			Type:      entities.NotificationTypeSchedule,
+			Title:     fmt.Sprintf("ตารางเวรเดือน %s เผยแพร่แล้ว", p.Month),
+			Message:   fmt.Sprintf("ตารางเวรเดือน %s ฉบับที่ %d เผยแพร่แล้ว ตรวจสอบเวรของคุณได้ที่หน้าตารางเวร", p.Month, p.VersionNo),
+			Priority:  entities.NotificationPriorityMedium,
+			ActionURL: strPtr("/dashboard/schedule"),
+			Data:      e.Payload,
+		})
+	}
+	return ns, nil
+}
+
+// paymentNotifications tells the payer that their package is active
+func paymentNotifications(e eventbus.Event, p eventbus.PaymentApprovedPayload) ([]*entities.Notification, error) {
+	userID, err := uuid.Parse(p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return []*entities.Notification{{
+		UserID:    userID,
+		Type:      entities.NotificationTypePayment,
+		Title:     "การชำระเงินได้รับการอนุมัติ",
+		Message:   fmt.Sprintf("การชำระเงินสำหรับแพ็คเกจ %s ได้รับการอนุมัติแล้ว ขยายเวลาใช้งาน %d วัน", p.PackageName, p.ExtendedDays),
+		Priority:  entities.NotificationPriorityHigh,
+		ActionURL: strPtr("/dashboard/packages"),
+		Data:      e.Payload,
+	}}, nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -1,211 +1,170 @@
 package handlers
 
 import (
+	"encoding/json"
 	"strconv"
+	"strings"
 	"time"
 
+	"nurseshift/notification-service/internal/domain/entities"
+	"nurseshift/notification-service/internal/domain/repositories"
+	"nurseshift/notification-service/internal/infrastructure/eventbus"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
-// NotificationHandler handles notification-related HTTP requests
-type NotificationHandler struct{}
+const maxNotificationPageSize = 100
 
-// NewNotificationHandler creates a new notification handler
-func NewNotificationHandler() *NotificationHandler {
-	return &NotificationHandler{}
+// NotificationHandler handles notification-related HTTP requests
+type NotificationHandler struct {
+	repo  repositories.NotificationRepository
+	inbox *eventbus.Inbox
 }
 
-// Notification represents notification data structure
-type Notification struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"userId"`
-	Type      string    `json:"type"` // schedule, leave, system, payment, reminder, holiday
-	Title     string    `json:"title"`
-	Message   string    `json:"message"`
-	Priority  string    `json:"priority"` // high, medium, low
-	IsRead    bool      `json:"isRead"`
-	ActionURL *string   `json:"actionUrl"`
-	Timestamp time.Time `json:"timestamp"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+// NewNotificationHandler creates a new notification handler; inbox deduplicates delivered events
+func NewNotificationHandler(repo repositories.NotificationRepository, inbox *eventbus.Inbox) *NotificationHandler {
+	return &NotificationHandler{repo: repo, inbox: inbox}
 }
 
-// Mock data
-var mockNotifications = []Notification{
-	{
-		ID:        "1",
-		UserID:    "user-1",
-		Type:      "schedule",
-		Title:     "ตารางเวรใหม่ประจำเดือนมีนาคม",
-		Message:   "ตารางเวรประจำเดือนมีนาคม 2024 ได้รับการอนุมัติและเผยแพร่แล้ว กรุณาตรวจสอบตารางเวรของคุณ",
-		Priority:  "high",
-		IsRead:    false,
-		ActionURL: stringPtr("/dashboard/schedule"),
-		Timestamp: time.Now().Add(-time.Hour * 2),
-		CreatedAt: time.Now().Add(-time.Hour * 2),
-		UpdatedAt: time.Now().Add(-time.Hour * 2),
-	},
-	{
-		ID:        "2",
-		UserID:    "user-1",
-		Type:      "leave",
-		Title:     "คำขอลาป่วยได้รับการอนุมัติ",
-		Message:   "คำขอลาป่วยวันที่ 15 มีนาคม 2024 ได้รับการอนุมัติจากหัวหน้าแผนกแล้ว",
-		Priority:  "medium",
-		IsRead:    true,
-		ActionURL: stringPtr("/dashboard/employee-leaves"),
-		Timestamp: time.Now().Add(-time.Hour * 6),
-		CreatedAt: time.Now().Add(-time.Hour * 6),
-		UpdatedAt: time.Now().Add(-time.Hour * 6),
-	},
-	{
-		ID:        "3",
-		UserID:    "user-1",
-		Type:      "system",
-		Title:     "การอัปเดตระบบ",
-		Message:   "ระบบจะมีการปรับปรุงในวันที่ 20 มีนาคม 2024 เวลา 02:00-04:00 น. อาจมีการหยุดให้บริการชั่วคราว",
-		Priority:  "low",
-		IsRead:    false,
-		ActionURL: nil,
-		Timestamp: time.Now().Add(-time.Hour * 12),
-		CreatedAt: time.Now().Add(-time.Hour * 12),
-		UpdatedAt: time.Now().Add(-time.Hour * 12),
-	},
-	{
-		ID:        "4",
-		UserID:    "user-1",
-		Type:      "payment",
-		Title:     "การชำระเงินแพ็คเกจ",
-		Message:   "การชำระเงินแพ็คเกจมาตรฐานได้รับการอนุมัติแล้ว บัญชีของคุณได้รับการต่ออายุเป็น 30 วัน",
-		Priority:  "high",
-		IsRead:    true,
-		ActionURL: stringPtr("/dashboard/packages"),
-		Timestamp: time.Now().Add(-time.Hour * 24),
-		CreatedAt: time.Now().Add(-time.Hour * 24),
-		UpdatedAt: time.Now().Add(-time.Hour * 24),
-	},
-	{
-		ID:        "5",
-		UserID:    "user-1",
-		Type:      "reminder",
-		Title:     "แจ้งเตือนเปลี่ยนเวร",
-		Message:   "คุณมีเวรเช้าในวันพรุ่งนี้ (16 มีนาคม 2024) เวลา 07:00-15:00 น. ที่แผนกผู้ป่วยใน",
-		Priority:  "medium",
-		IsRead:    false,
-		ActionURL: stringPtr("/dashboard/schedule"),
-		Timestamp: time.Now().Add(-time.Hour * 4),
-		CreatedAt: time.Now().Add(-time.Hour * 4),
-		UpdatedAt: time.Now().Add(-time.Hour * 4),
-	},
-	{
-		ID:        "6",
-		UserID:    "user-1",
-		Type:      "holiday",
-		Title:     "วันหยุดประจำปี",
-		Message:   "วันสงกรานต์ (13-15 เมษายน 2024) ได้รับการอนุมัติเป็นวันหยุดประจำปีแล้ว",
-		Priority:  "low",
-		IsRead:    true,
-		ActionURL: stringPtr("/dashboard/department-settings"),
-		Timestamp: time.Now().Add(-time.Hour * 48),
-		CreatedAt: time.Now().Add(-time.Hour * 48),
-		UpdatedAt: time.Now().Add(-time.Hour * 48),
-	},
+// createNotificationRequest is the body of both the user-facing and the internal create endpoints
+type createNotificationRequest struct {
+	UserID    string          `json:"userId"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Message   string          `json:"message"`
+	Priority  string          `json:"priority"`
+	ActionURL *string         `json:"actionUrl"`
+	Data      json.RawMessage `json:"data"`
 }
 
-// GetNotifications returns notifications with optional filters
-func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
-	userID := c.Query("userId")
-	notificationType := c.Query("type")
-	isRead := c.Query("isRead")
-	priority := c.Query("priority")
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+// currentUser returns the authenticated user set by AuthMiddleware
+func currentUser(c *fiber.Ctx) (uuid.UUID, bool) {
+	s, _ := c.Locals("userID").(string)
+	id, err := uuid.Parse(s)
+	return id, err == nil
+}
 
-	filteredNotifications := mockNotifications
+func isAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals("userRole").(string)
+	return role == "admin"
+}
 
-	// Filter by user
-	if userID != "" {
-		var filtered []Notification
-		for _, notification := range filteredNotifications {
-			if notification.UserID == userID {
-				filtered = append(filtered, notification)
-			}
-		}
-		filteredNotifications = filtered
+// targetUser resolves whose notifications a request reads: the caller, or any user named by an admin
+func targetUser(c *fiber.Ctx, requested string) (uuid.UUID, error) {
+	self, ok := currentUser(c)
+	if !ok {
+		return uuid.Nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "กรุณาเข้าสู่ระบบ",
+		})
+	}
+	if requested == "" || requested == self.String() {
+		return self, nil
 	}
+	if !isAdmin(c) {
+		return uuid.Nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  "error",
+			"message": "คุณไม่มีสิทธิ์เข้าถึงการแจ้งเตือนของผู้ใช้อื่น",
+		})
+	}
+	id, err := uuid.Parse(requested)
+	if err != nil || id == uuid.Nil {
+		return uuid.Nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "userId ไม่ถูกต้อง",
+		})
+	}
+	return id, nil
+}
 
-	// Filter by type
-	if notificationType != "" {
-		var filtered []Notification
-		for _, notification := range filteredNotifications {
-			if notification.Type == notificationType {
-				filtered = append(filtered, notification)
-			}
-		}
-		filteredNotifications = filtered
+// GetNotifications returns the authenticated user's notifications with optional filters
+func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
+	userID, err := targetUser(c, c.Query("userId"))
+	if userID == uuid.Nil {
+		return err
 	}
 
-	// Filter by read status
-	if isRead != "" {
-		readStatus := isRead == "true"
-		var filtered []Notification
-		for _, notification := range filteredNotifications {
-			if notification.IsRead == readStatus {
-				filtered = append(filtered, notification)
-			}
-		}
-		filteredNotifications = filtered
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > maxNotificationPageSize {
+		limit = maxNotificationPageSize
 	}
 
-	// Filter by priority
-	if priority != "" {
-		var filtered []Notification
-		for _, notification := range filteredNotifications {
-			if notification.Priority == priority {
-				filtered = append(filtered, notification)
-			}
+	filter := entities.NotificationFilter{UserID: userID, Page: page, Limit: limit}
+	if v := c.Query("type"); v != "" {
+		t := entities.NotificationType(v)
+		if !t.IsValid() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ประเภทการแจ้งเตือนไม่ถูกต้อง",
+			})
 		}
-		filteredNotifications = filtered
+		filter.Type = &t
 	}
-
-	// Pagination
-	start := (page - 1) * limit
-	end := start + limit
-	if start > len(filteredNotifications) {
-		start = len(filteredNotifications)
+	if v := c.Query("priority"); v != "" {
+		p := entities.NotificationPriority(v)
+		if !p.IsValid() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ระดับความสำคัญไม่ถูกต้อง",
+			})
+		}
+		filter.Priority = &p
 	}
-	if end > len(filteredNotifications) {
-		end = len(filteredNotifications)
+	if v := c.Query("isRead"); v != "" {
+		read := v == "true"
+		filter.IsRead = &read
 	}
 
-	paginatedNotifications := filteredNotifications[start:end]
-	totalPages := (len(filteredNotifications) + limit - 1) / limit
+	notifications, total, err := h.repo.List(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลการแจ้งเตือนได้",
+			"error":   err.Error(),
+		})
+	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "ดึงข้อมูลการแจ้งเตือนสำเร็จ",
 		"data": fiber.Map{
-			"notifications": paginatedNotifications,
-			"total":         len(filteredNotifications),
+			"notifications": notifications,
+			"total":         total,
 			"page":          page,
 			"limit":         limit,
-			"totalPages":    totalPages,
+			"totalPages":    (total + limit - 1) / limit,
 		},
 	})
 }
 
-// CreateNotification creates a new notification
+// CreateNotification creates a notification for the authenticated user; admins may target any user
 func (h *NotificationHandler) CreateNotification(c *fiber.Ctx) error {
-	var req struct {
-		UserID    string  `json:"userId" validate:"required"`
-		Type      string  `json:"type" validate:"required"`
-		Title     string  `json:"title" validate:"required"`
-		Message   string  `json:"message" validate:"required"`
-		Priority  string  `json:"priority"`
-		ActionURL *string `json:"actionUrl"`
+	var req createNotificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+			"error":   err.Error(),
+		})
+	}
+	userID, err := targetUser(c, req.UserID)
+	if userID == uuid.Nil {
+		return err
 	}
+	return h.create(c, req, userID)
+}
 
+// CreateInternalNotification creates a notification on behalf of another service; userId is required
+func (h *NotificationHandler) CreateInternalNotification(c *fiber.Ctx) error {
+	var req createNotificationRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"status":  "error",
@@ -213,86 +172,126 @@ func (h *NotificationHandler) CreateNotification(c *fiber.Ctx) error {
 			"error":   err.Error(),
 		})
 	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "userId ไม่ถูกต้อง",
+		})
+	}
+	return h.create(c, req, userID)
+}
 
+func (h *NotificationHandler) create(c *fiber.Ctx, req createNotificationRequest, userID uuid.UUID) error {
 	// Set default priority if not provided
 	if req.Priority == "" {
-		req.Priority = "medium"
-	}
-
-	// Create new notification
-	newNotification := Notification{
-		ID:        uuid.New().String(),
-		UserID:    req.UserID,
-		Type:      req.Type,
-		Title:     req.Title,
-		Message:   req.Message,
-		Priority:  req.Priority,
-		IsRead:    false,
+		req.Priority = string(entities.NotificationPriorityMedium)
+	}
+	n := &entities.Notification{
+		UserID:    userID,
+		Type:      entities.NotificationType(req.Type),
+		Title:     strings.TrimSpace(req.Title),
+		Message:   strings.TrimSpace(req.Message),
+		Priority:  entities.NotificationPriority(req.Priority),
 		ActionURL: req.ActionURL,
-		Timestamp: time.Now(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Data:      req.Data,
+	}
+	switch {
+	case !n.Type.IsValid():
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ประเภทการแจ้งเตือนไม่ถูกต้อง",
+		})
+	case !n.Priority.IsValid():
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ระดับความสำคัญไม่ถูกต้อง",
+		})
+	case n.Title == "" || n.Message == "":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "กรุณาระบุหัวข้อและข้อความ",
+		})
+	case len(n.Data) > 0 && !json.Valid(n.Data):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "data ต้องเป็น JSON",
+		})
 	}
 
-	// Add to mock data
-	mockNotifications = append(mockNotifications, newNotification)
+	if err := h.repo.Create(c.Context(), n); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถสร้างการแจ้งเตือนได้",
+			"error":   err.Error(),
+		})
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"status":  "success",
 		"message": "สร้างการแจ้งเตือนสำเร็จ",
-		"data":    newNotification,
+		"data":    n,
 	})
 }
 
-// MarkAsRead marks a notification as read
+// MarkAsRead marks one of the authenticated user's notifications as read
 func (h *NotificationHandler) MarkAsRead(c *fiber.Ctx) error {
-	notificationID := c.Params("id")
-
-	// Find and mark notification as read
-	for i, notification := range mockNotifications {
-		if notification.ID == notificationID {
-			mockNotifications[i].IsRead = true
-			mockNotifications[i].UpdatedAt = time.Now()
-
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{
-				"status":  "success",
-				"message": "ทำเครื่องหมายอ่านแล้วสำเร็จ",
-				"data":    mockNotifications[i],
-			})
-		}
+	userID, ok := currentUser(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "กรุณาเข้าสู่ระบบ",
+		})
+	}
+	notificationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบการแจ้งเตือน",
+		})
+	}
+
+	n, err := h.repo.MarkAsRead(c.Context(), notificationID, userID)
+	if err == repositories.ErrNotificationNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบการแจ้งเตือน",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถทำเครื่องหมายอ่านแล้วได้",
+			"error":   err.Error(),
+		})
 	}
 
-	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-		"status":  "error",
-		"message": "ไม่พบการแจ้งเตือน",
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ทำเครื่องหมายอ่านแล้วสำเร็จ",
+		"data":    n,
 	})
 }
 
-// MarkAllAsRead marks all notifications as read for a user
+// MarkAllAsRead marks all notifications of the authenticated user as read
 func (h *NotificationHandler) MarkAllAsRead(c *fiber.Ctx) error {
-	var req struct {
-		UserID string `json:"userId" validate:"required"`
+	userID, ok := currentUser(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "กรุณาเข้าสู่ระบบ",
+		})
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	updatedCount, err := h.repo.MarkAllAsRead(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
-			"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+			"message": "ไม่สามารถทำเครื่องหมายอ่านแล้วได้",
 			"error":   err.Error(),
 		})
 	}
 
-	updatedCount := 0
-
-	// Mark all user's notifications as read
-	for i, notification := range mockNotifications {
-		if notification.UserID == req.UserID && !notification.IsRead {
-			mockNotifications[i].IsRead = true
-			mockNotifications[i].UpdatedAt = time.Now()
-			updatedCount++
-		}
-	}
-
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "ทำเครื่องหมายอ่านแล้วทั้งหมดสำเร็จ",
@@ -302,70 +301,58 @@ func (h *NotificationHandler) MarkAllAsRead(c *fiber.Ctx) error {
 	})
 }
 
-// DeleteNotification deletes a notification
+// DeleteNotification deletes one of the authenticated user's notifications
 func (h *NotificationHandler) DeleteNotification(c *fiber.Ctx) error {
-	notificationID := c.Params("id")
-
-	// Find and remove notification
-	for i, notification := range mockNotifications {
-		if notification.ID == notificationID {
-			mockNotifications = append(mockNotifications[:i], mockNotifications[i+1:]...)
+	userID, ok := currentUser(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  "error",
+			"message": "กรุณาเข้าสู่ระบบ",
+		})
+	}
+	notificationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบการแจ้งเตือน",
+		})
+	}
 
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{
-				"status":  "success",
-				"message": "ลบการแจ้งเตือนสำเร็จ",
-			})
-		}
+	err = h.repo.Delete(c.Context(), notificationID, userID)
+	if err == repositories.ErrNotificationNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบการแจ้งเตือน",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถลบการแจ้งเตือนได้",
+			"error":   err.Error(),
+		})
 	}
 
-	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-		"status":  "error",
-		"message": "ไม่พบการแจ้งเตือน",
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ลบการแจ้งเตือนสำเร็จ",
 	})
 }
 
-// GetNotificationStats returns notification statistics
+// GetNotificationStats returns notification statistics of the authenticated user
 func (h *NotificationHandler) GetNotificationStats(c *fiber.Ctx) error {
-	userID := c.Query("userId")
-
-	filteredNotifications := mockNotifications
-	if userID != "" {
-		var filtered []Notification
-		for _, notification := range filteredNotifications {
-			if notification.UserID == userID {
-				filtered = append(filtered, notification)
-			}
-		}
-		filteredNotifications = filtered
+	userID, err := targetUser(c, c.Query("userId"))
+	if userID == uuid.Nil {
+		return err
 	}
 
-	totalNotifications := len(filteredNotifications)
-	unreadCount := 0
-	readCount := 0
-	highPriorityCount := 0
-
-	typeStats := make(map[string]int)
-
-	for _, notification := range filteredNotifications {
-		if notification.IsRead {
-			readCount++
-		} else {
-			unreadCount++
-		}
-
-		if notification.Priority == "high" {
-			highPriorityCount++
-		}
-
-		typeStats[notification.Type]++
-	}
-
-	stats := fiber.Map{
-		"total":           totalNotifications,
-		"unread":          unreadCount,
-		"read":            readCount,
-		"highPriority":    highPriorityCount,
-		"typeBreakdown":   typeStats,
+	stats, err := h.repo.Stats(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงสถิติการแจ้งเตือนได้",
+			"error":   err.Error(),
+		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -383,8 +370,3 @@ func (h *NotificationHandler) Health(c *fiber.Ctx) error {
 		"timestamp": time.Now(),
 	})
 }
-
-// Helper function
-func stringPtr(s string) *string {
-	return &s
-}
\ No newline at end of file
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 	"os"
@@ -141,3 +142,18 @@ func RoleMiddleware(allowedRoles ...string) fiber.Handler {
 		})
 	}
 }
+
+// InternalServiceMiddleware accepts service-to-service calls carrying X-Internal-Token = INTERNAL_SERVICE_TOKEN;
+// with the variable unset every call is rejected
+func InternalServiceMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		expected := os.Getenv("INTERNAL_SERVICE_TOKEN")
+		if expected == "" || subtle.ConstantTimeCompare([]byte(c.Get("X-Internal-Token")), []byte(expected)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"status":  "error",
+				"message": "internal token ไม่ถูกต้อง",
+			})
+		}
+		return c.Next()
+	}
+}
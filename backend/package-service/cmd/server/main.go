@@ -9,8 +9,10 @@ import (
 	"syscall"
 	"time"
 
+	"nurseshift/package-service/internal/infrastructure/clients"
 	"nurseshift/package-service/internal/infrastructure/config"
 	"nurseshift/package-service/internal/interfaces/http/handlers"
+	"nurseshift/package-service/internal/interfaces/http/middleware"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -51,7 +53,9 @@ func main() {
 	}
 
 	// Initialize handlers
-	packageHandler := handlers.NewPackageHandler()
+	// Orders are opened in payment-service, which owns the payments ledger
+	paymentClient := clients.NewPaymentServiceClient(cfg.Services.PaymentURL, cfg.Services.InternalToken)
+	packageHandler := handlers.NewPackageHandler(paymentClient)
 
 	// Routes
 	api := app.Group("/api/v1")
@@ -61,7 +65,7 @@ func main() {
 		packages.Get("/current", packageHandler.GetCurrentUserPackage)
 		packages.Get("/stats", packageHandler.GetPackageStats)
 		packages.Get("/:id", packageHandler.GetPackage)
-		packages.Post("/order", packageHandler.CreatePackageOrder)
+		packages.Post("/order", middleware.AuthMiddleware(), packageHandler.CreatePackageOrder)
 		packages.Put("/settings", packageHandler.UpdatePackageSettings)
 	}
 
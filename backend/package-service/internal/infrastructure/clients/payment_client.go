@@ -0,0 +1,72 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PaymentServiceError carries a non-2xx answer of payment-service so it can be relayed to the caller
+type PaymentServiceError struct {
+	Status  int
+	Message string
+}
+
+func (e *PaymentServiceError) Error() string {
+	return fmt.Sprintf("payment-service responded %d: %s", e.Status, e.Message)
+}
+
+// PaymentServiceClient calls payment-service's internal endpoints
+type PaymentServiceClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewPaymentServiceClient creates a client for payment-service at baseURL using the shared internal token
+func NewPaymentServiceClient(baseURL, token string) *PaymentServiceClient {
+	return &PaymentServiceClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// CreateOrder opens a PromptPay order for the user and returns payment-service's data object:
+// the pending payment and its PromptPay QR payload
+func (c *PaymentServiceClient) CreateOrder(ctx context.Context, userID, packageID string) (json.RawMessage, error) {
+	body, err := json.Marshal(map[string]string{
+		"userId":    userID,
+		"packageId": packageID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/internal/orders", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid payment-service response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &PaymentServiceError{Status: resp.StatusCode, Message: out.Message}
+	}
+	return out.Data, nil
+}
@@ -17,6 +17,7 @@ type Config struct {
 	JWT      JWTConfig
 	Security SecurityConfig
 	CORS     CORSConfig
+	Services ServicesConfig
 }
 
 // ServerConfig holds server-related configuration
@@ -65,6 +66,12 @@ type CORSConfig struct {
 	Credentials bool
 }
 
+// ServicesConfig holds URLs of the services this one calls
+type ServicesConfig struct {
+	PaymentURL    string
+	InternalToken string
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists
@@ -108,6 +115,10 @@ func Load() (*Config, error) {
 			Origins:     strings.Split(getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:3002"), ","),
 			Credentials: getEnvAsBool("CORS_CREDENTIALS", true),
 		},
+		Services: ServicesConfig{
+			PaymentURL:    getEnv("PAYMENT_SERVICE_URL", "http://localhost:8089"),
+			InternalToken: getEnv("INTERNAL_SERVICE_TOKEN", ""),
+		},
 	}
 
 	if err := config.validate(); err != nil {
@@ -128,6 +139,9 @@ func (c *Config) validate() error {
 	if c.JWT.Secret == "" || c.JWT.Secret == "your-secret-key" {
 		return fmt.Errorf("JWT secret must be set and not be the default value")
 	}
+	if c.Services.InternalToken == "" {
+		return fmt.Errorf("INTERNAL_SERVICE_TOKEN is required for service-to-service calls")
+	}
 	return nil
 }
 
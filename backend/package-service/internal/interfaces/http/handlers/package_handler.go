@@ -1,81 +1,57 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
 	"time"
 
+	"nurseshift/package-service/internal/infrastructure/clients"
+	"nurseshift/package-service/internal/infrastructure/entitlements"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 )
 
 // PackageHandler handles package-related HTTP requests
-type PackageHandler struct{}
+type PackageHandler struct {
+	payments *clients.PaymentServiceClient
+}
 
 // NewPackageHandler creates a new package handler
-func NewPackageHandler() *PackageHandler {
-	return &PackageHandler{}
+func NewPackageHandler(payments *clients.PaymentServiceClient) *PackageHandler {
+	return &PackageHandler{payments: payments}
+}
+
+// packageJSON is a package as the frontend lists it; the limits lead the feature list so they
+// always match what the other services enforce
+func packageJSON(p entitlements.Plan) fiber.Map {
+	features := []string{
+		fmt.Sprintf("แผนกสูงสุด %d แผนก", p.MaxDepartments),
+		fmt.Sprintf("พนักงานสูงสุด %d คน", p.MaxStaff),
+	}
+	features = append(features, p.Highlights...)
+	return fiber.Map{
+		"id":             p.ID,
+		"name":           p.Name,
+		"type":           p.Type,
+		"price":          p.Price,
+		"duration":       p.DurationDays,
+		"description":    p.Description,
+		"features":       features,
+		"maxUsers":       p.MaxStaff,
+		"maxDepartments": p.MaxDepartments,
+		"isPopular":      p.Popular,
+		"isActive":       true,
+	}
 }
 
 // GetPackages returns available packages
 func (h *PackageHandler) GetPackages(c *fiber.Ctx) error {
-	// Mock packages data matching frontend
-	packages := []fiber.Map{
-		{
-			"id":          1,
-			"name":        "แพ็คเกจทดลองใช้",
-			"type":        "trial",
-			"price":       0.00,
-			"duration":    90,
-			"description": "ทดลองใช้ฟรี 90 วัน",
-			"features": []string{
-				"จัดการแผนกพื้นฐาน",
-				"พนักงานสูงสุด 5 คน",
-				"ตารางเวรแบบง่าย",
-			},
-			"maxUsers":       5,
-			"maxDepartments": 2,
-			"isPopular":      false,
-			"isActive":       true,
-		},
-		{
-			"id":          2,
-			"name":        "แพ็คเกจมาตรฐาน",
-			"type":        "standard",
-			"price":       990.00,
-			"duration":    30,
-			"description": "เหมาะสำหรับแผนกขนาดกลาง",
-			"features": []string{
-				"จัดการหลายแผนก",
-				"พนักงานไม่จำกัด",
-				"ตารางเวรอัตโนมัติ",
-				"การแจ้งเตือนแบบเรียลไทม์",
-				"รายงานและสถิติ",
-			},
-			"maxUsers":       25,
-			"maxDepartments": 5,
-			"isPopular":      true,
-			"isActive":       true,
-		},
-		{
-			"id":          3,
-			"name":        "แพ็คเกจระดับองค์กร",
-			"type":        "enterprise",
-			"price":       2990.00,
-			"duration":    90,
-			"description": "เหมาะสำหรับองค์กรขนาดใหญ่",
-			"features": []string{
-				"จัดการหลายแผนกไม่จำกัด",
-				"พนักงานไม่จำกัด",
-				"ตารางเวรอัตโนมัติด้วย AI",
-				"การแจ้งเตือนแบบเรียลไทม์",
-				"รายงานและสถิติขั้นสูง",
-				"การสำรองข้อมูล",
-				"การสนับสนุนลูกค้าแบบพิเศษ",
-			},
-			"maxUsers":       1000,
-			"maxDepartments": 50,
-			"isPopular":      false,
-			"isActive":       true,
-		},
+	plans := entitlements.Plans()
+	packages := make([]fiber.Map, 0, len(plans))
+	for _, p := range plans {
+		packages = append(packages, packageJSON(p))
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -85,67 +61,39 @@ func (h *PackageHandler) GetPackages(c *fiber.Ctx) error {
 	})
 }
 
-// GetPackage returns specific package details
+// GetPackage returns specific package details, looked up by id or type
 func (h *PackageHandler) GetPackage(c *fiber.Ctx) error {
 	packageID := c.Params("id")
 
-	// Mock package detail
-	pkg := fiber.Map{
-		"id":          packageID,
-		"name":        "แพ็คเกจมาตรฐาน",
-		"type":        "standard",
-		"price":       990.00,
-		"duration":    30,
-		"description": "เหมาะสำหรับแผนกขนาดกลาง",
-		"features": []string{
-			"จัดการหลายแผนก",
-			"พนักงานไม่จำกัด",
-			"ตารางเวรอัตโนมัติ",
-			"การแจ้งเตือนแบบเรียลไทม์",
-			"รายงานและสถิติ",
-		},
-		"maxUsers":       25,
-		"maxDepartments": 5,
-		"isPopular":      true,
-		"isActive":       true,
-		"createdAt":      time.Now().Add(-90 * 24 * time.Hour),
-		"updatedAt":      time.Now(),
+	for _, p := range entitlements.Plans() {
+		if strconv.Itoa(p.ID) == packageID || p.Type == packageID {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"status":  "success",
+				"message": "ดึงข้อมูลแพ็คเกจสำเร็จ",
+				"data":    packageJSON(p),
+			})
+		}
 	}
-
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": "ดึงข้อมูลแพ็คเกจสำเร็จ",
-		"data":    pkg,
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"status":  "error",
+		"message": "ไม่พบแพ็คเกจ",
 	})
 }
 
 // GetCurrentUserPackage returns user's current package
 func (h *PackageHandler) GetCurrentUserPackage(c *fiber.Ctx) error {
 	// Mock current package data
-	currentPackage := fiber.Map{
-		"id":         2,
-		"name":       "แพ็คเกจมาตรฐาน",
-		"type":       "standard",
-		"price":      990.00,
-		"duration":   30,
-		"isActive":   true,
-		"startDate":  "2025-08-01",
-		"expireDate": "2025-08-31",
-		"daysLeft":   22,
-		"autoRenew":  false,
-		"features": []string{
-			"จัดการหลายแผนก",
-			"พนักงานไม่จำกัด",
-			"ตารางเวรอัตโนมัติ",
-			"การแจ้งเตือนแบบเรียลไทม์",
-			"รายงานและสถิติ",
-		},
-		"usage": fiber.Map{
-			"users":          18,
-			"maxUsers":       25,
-			"departments":    3,
-			"maxDepartments": 5,
-		},
+	plan := entitlements.PlanFor("standard")
+	currentPackage := packageJSON(plan)
+	currentPackage["startDate"] = "2025-08-01"
+	currentPackage["expireDate"] = "2025-08-31"
+	currentPackage["daysLeft"] = 22
+	currentPackage["autoRenew"] = false
+	currentPackage["usage"] = fiber.Map{
+		"users":          18,
+		"maxUsers":       plan.MaxStaff,
+		"departments":    3,
+		"maxDepartments": plan.MaxDepartments,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -155,31 +103,35 @@ func (h *PackageHandler) GetCurrentUserPackage(c *fiber.Ctx) error {
 	})
 }
 
-// CreatePackageOrder creates a new package order
+// CreatePackageOrder creates a new package order. payment-service records it as a pending payment and
+// returns the PromptPay QR payload (amount and order reference included) to pay it with.
 func (h *PackageHandler) CreatePackageOrder(c *fiber.Ctx) error {
 	var req struct {
-		PackageID int `json:"packageId" validate:"required"`
+		PackageID string `json:"packageId" validate:"required"`
 	}
 
-	if err := c.BodyParser(&req); err != nil {
+	if err := c.BodyParser(&req); err != nil || req.PackageID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"status":  "error",
 			"message": "ข้อมูลไม่ถูกต้อง",
 		})
 	}
 
-	// Mock order response
-	order := fiber.Map{
-		"id":         uuid.New().String(),
-		"packageId":  req.PackageID,
-		"status":     "pending_payment",
-		"totalPrice": 990.00,
-		"createdAt":  time.Now(),
-		"bankInfo": fiber.Map{
-			"bankName":      "ธนาคารกสิกรไทย",
-			"accountName":   "บริษัท เนิร์สชิฟท์ จำกัด",
-			"accountNumber": "123-4-56789-0",
-		},
+	userID, _ := c.Locals("userID").(string)
+	order, err := h.payments.CreateOrder(c.Context(), userID, req.PackageID)
+	var serviceErr *clients.PaymentServiceError
+	if errors.As(err, &serviceErr) {
+		return c.Status(serviceErr.Status).JSON(fiber.Map{
+			"status":  "error",
+			"message": serviceErr.Message,
+		})
+	}
+	if err != nil {
+		log.Printf("create order for user %s, package %v: %v", userID, req.PackageID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถสร้างคำสั่งซื้อได้ กรุณาลองใหม่อีกครั้ง",
+		})
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -246,5 +198,3 @@ func (h *PackageHandler) Health(c *fiber.Ctx) error {
 		"timestamp": time.Now(),
 	})
 }
-
-
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nurseshift/package-service/internal/infrastructure/entitlements"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type listedPackage struct {
+	ID             int      `json:"id"`
+	Type           string   `json:"type"`
+	Price          float64  `json:"price"`
+	MaxUsers       int      `json:"maxUsers"`
+	MaxDepartments int      `json:"maxDepartments"`
+	Features       []string `json:"features"`
+}
+
+func get(t *testing.T, path string, out interface{}) int {
+	t.Helper()
+	h := NewPackageHandler(nil)
+	app := fiber.New()
+	app.Get("/packages", h.GetPackages)
+	app.Get("/packages/:id", h.GetPackage)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestPackagesFollowTheEnforcedPlans(t *testing.T) {
+	var list struct {
+		Data []listedPackage `json:"data"`
+	}
+	if status := get(t, "/packages", &list); status != http.StatusOK {
+		t.Fatalf("GET /packages = %d", status)
+	}
+	plans := entitlements.Plans()
+	if len(list.Data) != len(plans) {
+		t.Fatalf("listed %d packages, want %d", len(list.Data), len(plans))
+	}
+	for i, p := range plans {
+		got := list.Data[i]
+		if got.ID != p.ID || got.Type != p.Type || got.Price != p.Price || got.MaxUsers != p.MaxStaff || got.MaxDepartments != p.MaxDepartments {
+			t.Errorf("package %d = %+v, want the %s plan", i, got, p.Type)
+		}
+		if len(got.Features) != len(p.Highlights)+2 {
+			t.Errorf("%s lists %v", p.Type, got.Features)
+		}
+	}
+
+	for _, id := range []string{"2", "standard"} {
+		var one struct {
+			Data listedPackage `json:"data"`
+		}
+		if status := get(t, "/packages/"+id, &one); status != http.StatusOK || one.Data.Type != "standard" {
+			t.Errorf("GET /packages/%s = %d %+v, want the standard package", id, status, one.Data)
+		}
+	}
+	var missing map[string]interface{}
+	if status := get(t, "/packages/9", &missing); status != http.StatusNotFound {
+		t.Errorf("GET /packages/9 = %d, want 404", status)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -9,8 +10,15 @@ import (
 	"syscall"
 	"time"
 
+	"nurseshift/payment-service/internal/infrastructure/audit"
 	"nurseshift/payment-service/internal/infrastructure/config"
+	dbpkg "nurseshift/payment-service/internal/infrastructure/database"
+	"nurseshift/payment-service/internal/infrastructure/eventbus"
+	"nurseshift/payment-service/internal/infrastructure/promptpay"
+	"nurseshift/payment-service/internal/infrastructure/repositories"
+	"nurseshift/payment-service/internal/infrastructure/storage"
 	"nurseshift/payment-service/internal/interfaces/http/handlers"
+	"nurseshift/payment-service/internal/interfaces/http/middleware"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -34,6 +42,7 @@ func main() {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  30 * time.Second,
+		BodyLimit:    (cfg.Storage.MaxUploadMB + 1) << 20, // slip upload plus form fields
 	})
 
 	// Middleware
@@ -50,22 +59,70 @@ func main() {
 		app.Use(logger.New())
 	}
 
-	// Initialize handlers
-	paymentHandler := handlers.NewPaymentHandler()
+	// Initialize DB + repository + storage + handlers
+	conn, err := dbpkg.NewConnection(cfg)
+	if err != nil {
+		log.Fatalf("DB connect error: %v", err)
+	}
+	defer conn.Close()
+	// Approved payments are enqueued in the outbox with the approval and delivered to
+	// notification-service
+	outbox := eventbus.NewOutbox(conn.DB, cfg.Database.Schema, "payment-service")
+	dispatcher := eventbus.NewDispatcher(outbox, eventbus.DispatcherConfig{})
+	dispatcher.Subscribe(eventbus.NewWebhook("notification-service",
+		strings.TrimRight(cfg.Services.NotificationURL, "/")+"/api/v1/internal/events", cfg.Services.InternalToken),
+		eventbus.PaymentApproved)
+	dispatchCtx, stopDispatcher := context.WithCancel(context.Background())
+	go dispatcher.Run(dispatchCtx)
+
+	repo := repositories.NewPostgresPaymentRepository(conn.DB, cfg.Database.Schema, outbox)
+	auditRepo := audit.NewRepository(conn.DB, cfg.Database.Schema)
+	slips, err := storage.NewSlipStorage(cfg.Storage.Driver, cfg.Storage.Dir)
+	if err != nil {
+		log.Fatalf("Slip storage error: %v", err)
+	}
+
+	// PromptPay receiving account; orders are refused until it is configured
+	var promptPayTarget *promptpay.Target
+	if cfg.PromptPay.ID != "" {
+		target, err := promptpay.ParseTarget(cfg.PromptPay.ID)
+		if err != nil {
+			log.Fatalf("PROMPTPAY_ID: %v", err)
+		}
+		promptPayTarget = &target
+	} else {
+		log.Println("PROMPTPAY_ID is not set, PromptPay orders are disabled")
+	}
+	// Without a verification provider, slips are checked for a valid QR and left to manual review
+	var slipLookup promptpay.SlipLookup
+	if l := promptpay.NewHTTPSlipLookup(cfg.PromptPay.SlipVerifyURL, cfg.PromptPay.SlipVerifyToken); l != nil {
+		slipLookup = l
+	}
+	paymentHandler := handlers.NewPaymentHandler(repo, slips, int64(cfg.Storage.MaxUploadMB)<<20, promptPayTarget, slipLookup)
 
 	// Routes
 	api := app.Group("/api/v1")
 	payments := api.Group("/payments")
+	payments.Use(middleware.AuthMiddleware())
+	payments.Use(audit.Middleware(auditRepo, "payment"))
 	{
 		payments.Get("/", paymentHandler.GetPayments)
 		payments.Post("/", paymentHandler.CreatePayment)
-		payments.Get("/stats", paymentHandler.GetPaymentStats)
+		payments.Post("/orders", paymentHandler.CreateOrder)
+		payments.Get("/packages", paymentHandler.GetPackages)
+		payments.Get("/stats", middleware.RoleMiddleware("admin"), paymentHandler.GetPaymentStats)
 		payments.Get("/:id", paymentHandler.GetPayment)
+		payments.Get("/:id/evidence", paymentHandler.GetPaymentEvidence)
+		payments.Get("/:id/promptpay", paymentHandler.GetPromptPayQR)
 		payments.Put("/:id", paymentHandler.UpdatePayment)
-		payments.Put("/:id/approve", paymentHandler.ApprovePayment)
-		payments.Put("/:id/reject", paymentHandler.RejectPayment)
+		payments.Put("/:id/approve", middleware.RoleMiddleware("admin"), paymentHandler.ApprovePayment)
+		payments.Put("/:id/reject", middleware.RoleMiddleware("admin"), paymentHandler.RejectPayment)
 	}
 
+	// Service-to-service: package-service opens orders for its users
+	internal := api.Group("/internal", middleware.InternalServiceMiddleware())
+	internal.Post("/orders", paymentHandler.CreateInternalOrder)
+
 	// Health check
 	app.Get("/health", paymentHandler.Health)
 
@@ -83,8 +140,7 @@ func main() {
 	<-c
 
 	fmt.Println("\n🛑 Shutting down Payment Service...")
+	stopDispatcher()
 	app.Shutdown()
 	fmt.Println("✅ Payment Service stopped gracefully")
 }
-
-
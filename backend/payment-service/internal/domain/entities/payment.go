@@ -0,0 +1,116 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentStatus mirrors the payment_status enum
+type PaymentStatus string
+
+const (
+	PaymentStatusPending  PaymentStatus = "pending"
+	PaymentStatusApproved PaymentStatus = "approved"
+	PaymentStatusRejected PaymentStatus = "rejected"
+	PaymentStatusExpired  PaymentStatus = "expired"
+)
+
+// IsValid reports whether s is one of the payment_status values
+func (s PaymentStatus) IsValid() bool {
+	switch s {
+	case PaymentStatusPending, PaymentStatusApproved, PaymentStatusRejected, PaymentStatusExpired:
+		return true
+	}
+	return false
+}
+
+// Slip verification states of a payment
+const (
+	VerificationAwaitingSlip = "awaiting_slip" // order created, no slip uploaded yet
+	VerificationVerified     = "verified"      // slip QR decoded and amount/date/reference matched
+	VerificationManualReview = "manual_review" // slip QR decoded, amount and date left to the admin
+)
+
+// Package represents a row of the packages table
+type Package struct {
+	ID             uuid.UUID `json:"id"`
+	Name           string    `json:"name"`
+	Type           *string   `json:"type"` // package_type: standard, enterprise, trial
+	Price          float64   `json:"price"`
+	Duration       int       `json:"duration"` // days
+	MaxDepartments *int      `json:"maxDepartments"`
+	Features       []string  `json:"features"`
+	IsPopular      bool      `json:"isPopular"`
+	IsActive       bool      `json:"isActive"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Payment represents a row of the payments table
+type Payment struct {
+	ID           uuid.UUID     `json:"id"`
+	UserID       uuid.UUID     `json:"userId"`
+	PackageID    uuid.UUID     `json:"packageId"`
+	PackageName  string        `json:"packageName"`
+	Amount       float64       `json:"amount"`
+	Status       PaymentStatus `json:"status"`
+	Reference    *string       `json:"reference"` // order reference carried in the PromptPay QR
+	Evidence     *string       `json:"evidence"`  // storage key of the uploaded slip
+	PaymentDate  string        `json:"paymentDate"`
+	ApprovedBy   *uuid.UUID    `json:"approvedBy"`
+	ApprovedAt   *time.Time    `json:"approvedAt"`
+	ExtendedDays *int          `json:"extendedDays"`
+	RejectReason *string       `json:"rejectReason"`
+	Verification *string       `json:"verification"`
+	SlipTransRef *string       `json:"slipTransRef"`
+	SlipBank     *string       `json:"slipBank"`
+	SlipNote     *string       `json:"slipNote"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+}
+
+// SlipEvidence is an uploaded slip together with what verification found
+type SlipEvidence struct {
+	Evidence     string
+	TransRef     string
+	SendingBank  string
+	Verification string
+	Note         *string
+}
+
+// PaymentFilter represents the filters of a payment listing
+type PaymentFilter struct {
+	UserID       *uuid.UUID
+	Status       *PaymentStatus
+	Verification *string
+}
+
+// PaymentStats represents payment counters over the whole ledger
+type PaymentStats struct {
+	TotalPayments int     `json:"totalPayments"`
+	Approved      int     `json:"approved"`
+	Pending       int     `json:"pending"`
+	Rejected      int     `json:"rejected"`
+	TotalRevenue  float64 `json:"totalRevenue"`
+}
+
+// Subscription is the user's plan after an approved payment has been applied
+type Subscription struct {
+	UserID         uuid.UUID `json:"userId"`
+	PackageType    string    `json:"packageType"`
+	MaxDepartments int       `json:"maxDepartments"`
+	ExpiresAt      time.Time `json:"subscriptionExpiresAt"`
+	DaysRemaining  int       `json:"daysRemaining"`
+}
+
+// ExtendedExpiry is when a subscription ends after adding days: counted from the current expiry while
+// it is still in the future, otherwise from now, so renewing early never loses paid days and a lapsed
+// account does not get the days it went without
+func ExtendedExpiry(current *time.Time, now time.Time, days int) time.Time {
+	from := now
+	if current != nil && current.After(now) {
+		from = *current
+	}
+	return from.AddDate(0, 0, days)
+}
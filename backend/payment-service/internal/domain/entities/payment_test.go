@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtendedExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	later := time.Date(2026, 3, 25, 0, 0, 0, 0, time.UTC)
+	lapsed := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		current *time.Time
+		want    time.Time
+	}{
+		{"never subscribed starts now", nil, now.AddDate(0, 0, 30)},
+		{"still active adds to the current expiry", &later, later.AddDate(0, 0, 30)},
+		{"lapsed starts now", &lapsed, now.AddDate(0, 0, 30)},
+		{"expiring this instant starts now", &now, now.AddDate(0, 0, 30)},
+	}
+	for _, tc := range cases {
+		if got := ExtendedExpiry(tc.current, now, 30); !got.Equal(tc.want) {
+			t.Errorf("%s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"nurseshift/payment-service/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrPaymentNotFound is returned when a payment does not exist
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrPackageNotFound is returned when a package does not exist or is inactive
+	ErrPackageNotFound = errors.New("package not found")
+	// ErrPaymentNotPending is returned when approving or rejecting a payment that was already reviewed
+	ErrPaymentNotPending = errors.New("payment is not pending")
+	// ErrPaymentApproved is returned when resubmitting evidence for an approved payment
+	ErrPaymentApproved = errors.New("payment is already approved")
+	// ErrPaymentAwaitingSlip is returned when approving an order nobody has uploaded a slip for
+	ErrPaymentAwaitingSlip = errors.New("payment has no slip")
+	// ErrSlipUsed is returned when the slip's transaction already settles another payment
+	ErrSlipUsed = errors.New("slip already settles another payment")
+)
+
+// PaymentRepository defines the interface for payment ledger data access
+type PaymentRepository interface {
+	// ListPackages returns the packages, optionally only active ones
+	ListPackages(ctx context.Context, activeOnly bool) ([]entities.Package, error)
+
+	// GetPackage returns an active package
+	GetPackage(ctx context.Context, id uuid.UUID) (*entities.Package, error)
+
+	// CreatePayment stores a new pending payment; orders are created without evidence and wait for a slip
+	CreatePayment(ctx context.Context, p *entities.Payment) error
+
+	// GetPayment returns a payment with its package name
+	GetPayment(ctx context.Context, id uuid.UUID) (*entities.Payment, error)
+
+	// ListPayments returns payments matching the filter, newest first
+	ListPayments(ctx context.Context, filter entities.PaymentFilter) ([]entities.Payment, error)
+
+	// ResubmitPayment attaches a verified slip to a pending or rejected payment and sets it pending again
+	ResubmitPayment(ctx context.Context, id uuid.UUID, slip entities.SlipEvidence) (*entities.Payment, error)
+
+	// SlipUsed reports whether a slip transaction already settles a payment other than exceptID
+	SlipUsed(ctx context.Context, transRef string, exceptID uuid.UUID) (bool, error)
+
+	// ApprovePayment approves a pending payment and, in the same transaction, extends the payer's
+	// subscription by extendedDays (the package duration when nil) and applies the package's type
+	// and department limit
+	ApprovePayment(ctx context.Context, id, approvedBy uuid.UUID, extendedDays *int) (*entities.Payment, *entities.Subscription, error)
+
+	// RejectPayment rejects a pending payment
+	RejectPayment(ctx context.Context, id, rejectedBy uuid.UUID, reason string) (*entities.Payment, error)
+
+	// Stats returns ledger counters
+	Stats(ctx context.Context) (*entities.PaymentStats, error)
+}
@@ -11,12 +11,15 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Security SecurityConfig
-	CORS     CORSConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Security  SecurityConfig
+	CORS      CORSConfig
+	Storage   StorageConfig
+	PromptPay PromptPayConfig
+	Services  ServicesConfig
 }
 
 // ServerConfig holds server-related configuration
@@ -65,6 +68,26 @@ type CORSConfig struct {
 	Credentials bool
 }
 
+// StorageConfig holds payment slip storage configuration
+type StorageConfig struct {
+	Driver      string // local
+	Dir         string
+	MaxUploadMB int
+}
+
+// PromptPayConfig holds the receiving PromptPay account and the optional slip verification provider
+type PromptPayConfig struct {
+	ID              string // mobile number, national/tax ID or e-wallet ID
+	SlipVerifyURL   string
+	SlipVerifyToken string
+}
+
+// ServicesConfig holds URLs of the services this one delivers events to
+type ServicesConfig struct {
+	NotificationURL string
+	InternalToken   string
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists
@@ -108,6 +131,20 @@ func Load() (*Config, error) {
 			Origins:     strings.Split(getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:3002"), ","),
 			Credentials: getEnvAsBool("CORS_CREDENTIALS", true),
 		},
+		Storage: StorageConfig{
+			Driver:      getEnv("SLIP_STORAGE_DRIVER", "local"),
+			Dir:         getEnv("SLIP_STORAGE_DIR", "./uploads/slips"),
+			MaxUploadMB: getEnvAsInt("SLIP_MAX_UPLOAD_MB", 5),
+		},
+		PromptPay: PromptPayConfig{
+			ID:              getEnv("PROMPTPAY_ID", ""),
+			SlipVerifyURL:   getEnv("SLIP_VERIFY_URL", ""),
+			SlipVerifyToken: getEnv("SLIP_VERIFY_TOKEN", ""),
+		},
+		Services: ServicesConfig{
+			NotificationURL: getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8087"),
+			InternalToken:   getEnv("INTERNAL_SERVICE_TOKEN", ""),
+		},
 	}
 
 	if err := config.validate(); err != nil {
@@ -128,6 +165,9 @@ func (c *Config) validate() error {
 	if c.JWT.Secret == "" || c.JWT.Secret == "your-secret-key" {
 		return fmt.Errorf("JWT secret must be set and not be the default value")
 	}
+	if c.Services.InternalToken == "" {
+		return fmt.Errorf("INTERNAL_SERVICE_TOKEN is required for service-to-service calls")
+	}
 	return nil
 }
 
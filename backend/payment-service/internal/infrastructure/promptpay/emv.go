@@ -0,0 +1,88 @@
+package promptpay
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPayload is returned for strings that are not well-formed EMVCo TLV with a valid CRC
+var ErrInvalidPayload = errors.New("invalid EMVCo payload")
+
+// Field is one tag-length-value element of an EMVCo payload
+type Field struct {
+	Tag   string
+	Value string
+}
+
+// tlv encodes one field: two-digit tag, two-digit length, value
+func tlv(tag, value string) string {
+	return fmt.Sprintf("%s%02d%s", tag, len(value), value)
+}
+
+// CRC16 is CRC-16/CCITT-FALSE (poly 0x1021, init 0xFFFF), the checksum EMVCo QR payloads use
+func CRC16(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for b := 0; b < 8; b++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// withCRC appends the checksum field crcTag; the CRC covers everything up to and including the
+// checksum's own tag and length
+func withCRC(payload, crcTag string) string {
+	payload += crcTag + "04"
+	return payload + fmt.Sprintf("%04X", CRC16(payload))
+}
+
+// ParseFields splits a TLV string into its fields, in order
+func ParseFields(s string) ([]Field, error) {
+	var fields []Field
+	for i := 0; i < len(s); {
+		if i+4 > len(s) {
+			return nil, ErrInvalidPayload
+		}
+		n, err := strconv.Atoi(s[i+2 : i+4])
+		if err != nil || i+4+n > len(s) {
+			return nil, ErrInvalidPayload
+		}
+		fields = append(fields, Field{Tag: s[i : i+2], Value: s[i+4 : i+4+n]})
+		i += 4 + n
+	}
+	return fields, nil
+}
+
+// ParseWithCRC parses a payload whose last field is the checksum crcTag and verifies it
+func ParseWithCRC(s, crcTag string) ([]Field, error) {
+	s = strings.TrimSpace(s)
+	fields, err := ParseFields(s)
+	if err != nil || len(fields) < 2 {
+		return nil, ErrInvalidPayload
+	}
+	last := fields[len(fields)-1]
+	if last.Tag != crcTag || len(last.Value) != 4 {
+		return nil, ErrInvalidPayload
+	}
+	if !strings.EqualFold(last.Value, fmt.Sprintf("%04X", CRC16(s[:len(s)-4]))) {
+		return nil, fmt.Errorf("%w: CRC mismatch", ErrInvalidPayload)
+	}
+	return fields[:len(fields)-1], nil
+}
+
+func lookup(fields []Field, tag string) (string, bool) {
+	for _, f := range fields {
+		if f.Tag == tag {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,55 @@
+package promptpay
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCRC16(t *testing.T) {
+	// the CRC-16/CCITT-FALSE check value, and the checksums of payloads published with the
+	// promptpay-qr reference implementation (github.com/dtinth/promptpay-qr)
+	cases := map[string]uint16{
+		"123456789": 0x29B1,
+		"00020101021129370016A000000677010111011300660000000005802TH53037646304":         0x8956,
+		"00020101021129370016A000000677010111021312345678901235802TH53037646304":         0xEC40,
+		"00020101021229370016A000000677010111011300660000000005802TH530376454044.226304": 0xE469,
+	}
+	for data, want := range cases {
+		if got := CRC16(data); got != want {
+			t.Errorf("CRC16(%q) = %04X, want %04X", data, got, want)
+		}
+	}
+}
+
+func TestParseWithCRC(t *testing.T) {
+	published := "00020101021129370016A000000677010111011300660000000005802TH530376463048956"
+	fields, err := ParseWithCRC(published, "63")
+	if err != nil {
+		t.Fatalf("published payload: %v", err)
+	}
+	if v, _ := lookup(fields, "58"); v != "TH" {
+		t.Errorf("country = %q, want TH", v)
+	}
+	if _, ok := lookup(fields, "63"); ok {
+		t.Error("checksum field returned with the data fields")
+	}
+
+	bad := map[string]string{
+		"wrong checksum":       published[:len(published)-4] + "8957",
+		"truncated":            published[:len(published)-3],
+		"length past the end":  "000201" + "0199",
+		"non-numeric length":   "00AB01",
+		"no checksum field":    "000201010211",
+		"checksum under a tag": published[:len(published)-8] + "6404" + published[len(published)-4:],
+	}
+	for name, s := range bad {
+		if _, err := ParseWithCRC(s, "63"); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("%s: %v, want ErrInvalidPayload", name, err)
+		}
+	}
+	// scanners may hand back a lowercase checksum or a trailing newline
+	withAmount := "00020101021229370016A000000677010111011300660000000005802TH530376454044.226304e469\n"
+	if _, err := ParseWithCRC(withAmount, "63"); err != nil {
+		t.Errorf("lowercase checksum: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package promptpay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Application ID of PromptPay credit transfer (merchant account information, tag 29)
+const creditTransferAID = "A000000677010111"
+
+// Target types, the sub-tag of tag 29 that carries the receiving proxy
+const (
+	TargetMobile     = "01"
+	TargetNationalID = "02"
+	TargetEWallet    = "03"
+)
+
+// ErrInvalidTarget is returned for PromptPay IDs that are not a mobile number, a 13-digit
+// national/tax ID or a 15-digit e-wallet ID
+var ErrInvalidTarget = errors.New("invalid PromptPay ID")
+
+// Target is a PromptPay receiving proxy
+type Target struct {
+	Type  string
+	Value string // formatted for tag 29: 0066XXXXXXXXX for mobiles, digits otherwise
+}
+
+// ParseTarget recognises a PromptPay ID by its digit count; dashes and spaces are ignored
+func ParseTarget(id string) (Target, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return 'x'
+	}, id)
+	if strings.ContainsRune(digits, 'x') {
+		return Target{}, ErrInvalidTarget
+	}
+	switch {
+	case len(digits) == 10 && digits[0] == '0':
+		return Target{Type: TargetMobile, Value: "0066" + digits[1:]}, nil
+	case len(digits) == 13:
+		return Target{Type: TargetNationalID, Value: digits}, nil
+	case len(digits) == 15:
+		return Target{Type: TargetEWallet, Value: digits}, nil
+	}
+	return Target{}, ErrInvalidTarget
+}
+
+// Payload builds the EMVCo merchant-presented QR string for a PromptPay transfer. A positive amount
+// makes the QR dynamic (single use, amount fixed); reference, when set, goes into the additional data
+// field as the reference label (tag 62, sub-tag 05, at most 25 characters).
+func Payload(target Target, amount float64, reference string) (string, error) {
+	if len(reference) > 25 {
+		return "", fmt.Errorf("reference %q is longer than 25 characters", reference)
+	}
+	initiation := "11" // static
+	if amount > 0 {
+		initiation = "12" // dynamic
+	}
+
+	var b strings.Builder
+	b.WriteString(tlv("00", "01")) // payload format indicator
+	b.WriteString(tlv("01", initiation))
+	b.WriteString(tlv("29", tlv("00", creditTransferAID)+tlv(target.Type, target.Value)))
+	b.WriteString(tlv("53", "764")) // THB
+	if amount > 0 {
+		b.WriteString(tlv("54", fmt.Sprintf("%.2f", amount)))
+	}
+	b.WriteString(tlv("58", "TH"))
+	if reference != "" {
+		b.WriteString(tlv("62", tlv("05", reference)))
+	}
+	return withCRC(b.String(), "63"), nil
+}
@@ -0,0 +1,70 @@
+package promptpay
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	cases := []struct {
+		id   string
+		want Target
+	}{
+		{"081-234-5678", Target{TargetMobile, "0066812345678"}},
+		{"0812345678", Target{TargetMobile, "0066812345678"}},
+		{"1 2345 67890 12 3", Target{TargetNationalID, "1234567890123"}},
+		{"123456789012345", Target{TargetEWallet, "123456789012345"}},
+	}
+	for _, tc := range cases {
+		if got, err := ParseTarget(tc.id); err != nil || got != tc.want {
+			t.Errorf("ParseTarget(%q) = %+v, %v, want %+v", tc.id, got, err, tc.want)
+		}
+	}
+	for _, id := range []string{"", "812345678", "1812345678", "08123456789", "08x2345678", "+66812345678"} {
+		if _, err := ParseTarget(id); !errors.Is(err, ErrInvalidTarget) {
+			t.Errorf("ParseTarget(%q): %v, want ErrInvalidTarget", id, err)
+		}
+	}
+}
+
+func TestPayload(t *testing.T) {
+	mobile, _ := ParseTarget("081-234-5678")
+	taxID, _ := ParseTarget("1234567890123")
+	cases := []struct {
+		name      string
+		target    Target
+		amount    float64
+		reference string
+		want      string
+	}{
+		{"static, no amount", mobile, 0, "",
+			"00020101021129370016A0000006770101110113006681234567853037645802TH6304823E"},
+		{"dynamic with a reference", taxID, 1500, "NS2603100001",
+			"00020101021229370016A00000067701011102131234567890123530376454071500.005802TH62160512NS260310000163044EF9"},
+	}
+	for _, tc := range cases {
+		got, err := Payload(tc.target, tc.amount, tc.reference)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s:\n got %s\nwant %s", tc.name, got, tc.want)
+		}
+		fields, err := ParseWithCRC(got, "63")
+		if err != nil {
+			t.Errorf("%s: own payload does not verify: %v", tc.name, err)
+			continue
+		}
+		account, _ := lookup(fields, "29")
+		sub, _ := ParseFields(account)
+		if aid, _ := lookup(sub, "00"); aid != creditTransferAID {
+			t.Errorf("%s: AID %q", tc.name, aid)
+		}
+		if v, _ := lookup(sub, tc.target.Type); v != tc.target.Value {
+			t.Errorf("%s: target %q, want %q", tc.name, v, tc.target.Value)
+		}
+	}
+	if _, err := Payload(mobile, 100, "THIS-REFERENCE-IS-TOO-LONG"); err == nil {
+		t.Error("a 26-character reference was accepted")
+	}
+}
@@ -0,0 +1,75 @@
+package promptpay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// ErrNoSlipQR is returned when no slip verification QR can be read from an image
+var ErrNoSlipQR = errors.New("no slip verification QR found")
+
+// SlipQR is the mini QR Thai banks print on transfer slips. It identifies the transaction only;
+// amount and date have to be looked up with the sending bank (see SlipLookup).
+type SlipQR struct {
+	APIID       string `json:"apiId"`
+	SendingBank string `json:"sendingBank"` // BOT bank code, e.g. 004 = KBank
+	TransRef    string `json:"transRef"`
+	Country     string `json:"country"`
+}
+
+// ParseSlipQR parses a slip mini QR payload: tag 00 holds sub-tags 00 (API ID), 01 (sending bank)
+// and 02 (transaction reference), tag 51 the country and tag 91 the CRC16
+func ParseSlipQR(s string) (*SlipQR, error) {
+	fields, err := ParseWithCRC(s, "91")
+	if err != nil {
+		return nil, err
+	}
+	inner, ok := lookup(fields, "00")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing tag 00", ErrInvalidPayload)
+	}
+	sub, err := ParseFields(inner)
+	if err != nil {
+		return nil, err
+	}
+	slip := &SlipQR{}
+	slip.APIID, _ = lookup(sub, "00")
+	slip.SendingBank, _ = lookup(sub, "01")
+	slip.TransRef, _ = lookup(sub, "02")
+	slip.Country, _ = lookup(fields, "51")
+	if slip.TransRef == "" {
+		return nil, fmt.Errorf("%w: missing transaction reference", ErrInvalidPayload)
+	}
+	return slip, nil
+}
+
+// DecodeSlipImage finds and parses the slip mini QR in a JPEG or PNG slip image
+func DecodeSlipImage(data []byte) (*SlipQR, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read slip image: %w", err)
+	}
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read slip image: %w", err)
+	}
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	result, err := qrcode.NewQRCodeReader().Decode(bmp, hints)
+	if err != nil {
+		return nil, ErrNoSlipQR
+	}
+	slip, err := ParseSlipQR(result.GetText())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoSlipQR, err)
+	}
+	return slip, nil
+}
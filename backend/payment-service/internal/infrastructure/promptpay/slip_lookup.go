@@ -0,0 +1,124 @@
+package promptpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrSlipNotFound is returned by a SlipLookup when the bank does not know the transaction
+var ErrSlipNotFound = errors.New("slip transaction not found")
+
+// Bangkok is the zone slip dates are compared in
+var Bangkok = time.FixedZone("ICT", 7*60*60)
+
+// SlipDetails is what a bank-side lookup reports for a slip transaction
+type SlipDetails struct {
+	TransRef      string    `json:"transRef"`
+	Amount        float64   `json:"amount"`
+	TransferredAt time.Time `json:"transferredAt"`
+	Reference     string    `json:"reference"` // reference label carried from the PromptPay QR, if the bank keeps it
+}
+
+// SlipLookup resolves a slip mini QR to the transfer's amount and date. Implementations wrap a bank
+// or slip-verification provider API.
+type SlipLookup interface {
+	Lookup(ctx context.Context, slip *SlipQR) (*SlipDetails, error)
+}
+
+// HTTPSlipLookup calls a slip-verification provider:
+// GET {baseURL}?sendingBank=..&transRef=.. with a bearer token, answering SlipDetails as JSON
+type HTTPSlipLookup struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewHTTPSlipLookup creates a lookup against baseURL; it returns nil when baseURL is empty so callers
+// can fall back to manual review
+func NewHTTPSlipLookup(baseURL, token string) *HTTPSlipLookup {
+	if baseURL == "" {
+		return nil
+	}
+	return &HTTPSlipLookup{
+		baseURL: baseURL,
+		token:   token,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Lookup asks the provider about the slip's transaction
+func (l *HTTPSlipLookup) Lookup(ctx context.Context, slip *SlipQR) (*SlipDetails, error) {
+	q := url.Values{}
+	q.Set("sendingBank", slip.SendingBank)
+	q.Set("transRef", slip.TransRef)
+	sep := "?"
+	if strings.Contains(l.baseURL, "?") {
+		sep = "&"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.baseURL+sep+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if l.token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.token)
+	}
+	resp, err := l.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSlipNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("slip verification responded %d", resp.StatusCode)
+	}
+	var details SlipDetails
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		return nil, fmt.Errorf("invalid slip verification response: %w", err)
+	}
+	if details.TransRef != "" && details.TransRef != slip.TransRef {
+		return nil, fmt.Errorf("slip verification answered for another transaction")
+	}
+	return &details, nil
+}
+
+// SlipExpectation is what a slip must show to settle a payment
+type SlipExpectation struct {
+	Reference string // the order's reference label
+	TransRef  string // the transaction read from the uploaded slip's QR
+	Amount    float64
+	From      time.Time // the transfer cannot predate the order
+	To        time.Time
+}
+
+// MatchSlip compares looked-up slip details against a payment and returns the mismatches, in Thai,
+// for display; an empty result means the slip settles the payment. The transfer has to be tied to
+// the payment by the order's reference label or, when the bank does not keep it, by the bank
+// confirming the very transaction the uploaded slip shows.
+func MatchSlip(want SlipExpectation, got *SlipDetails) []string {
+	var problems []string
+	if math.Abs(got.Amount-want.Amount) >= 0.005 {
+		problems = append(problems, fmt.Sprintf("ยอดเงินในสลิป %.2f บาท ไม่ตรงกับยอดที่ต้องชำระ %.2f บาท", got.Amount, want.Amount))
+	}
+	day := func(t time.Time) string { return t.In(Bangkok).Format("2006-01-02") }
+	if got.TransferredAt.IsZero() {
+		problems = append(problems, "ไม่พบวันที่โอนเงินในสลิป")
+	} else if day(got.TransferredAt) < day(want.From) || day(got.TransferredAt) > day(want.To) {
+		problems = append(problems, fmt.Sprintf("วันที่โอนเงินในสลิป (%s) ไม่อยู่ในช่วงของคำสั่งซื้อ", day(got.TransferredAt)))
+	}
+	switch {
+	case got.Reference != "" && !strings.EqualFold(got.Reference, want.Reference):
+		problems = append(problems, fmt.Sprintf("เลขอ้างอิงในสลิป (%s) ไม่ตรงกับคำสั่งซื้อ (%s)", got.Reference, want.Reference))
+	case got.Reference == "" && (want.TransRef == "" || got.TransRef != want.TransRef):
+		problems = append(problems, "ไม่สามารถยืนยันได้ว่าสลิปนี้เป็นการชำระของคำสั่งซื้อนี้")
+	}
+	return problems
+}
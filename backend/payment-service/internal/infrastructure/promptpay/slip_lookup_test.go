@@ -0,0 +1,40 @@
+package promptpay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchSlip(t *testing.T) {
+	ordered := time.Date(2026, 3, 10, 9, 0, 0, 0, Bangkok)
+	want := SlipExpectation{Reference: "NS2603100001", TransRef: "0154113215BTF06542", Amount: 1500, From: ordered, To: ordered.Add(48 * time.Hour)}
+	good := SlipDetails{TransRef: want.TransRef, Amount: 1500, TransferredAt: ordered.Add(time.Hour), Reference: want.Reference}
+
+	cases := []struct {
+		name   string
+		edit   func(*SlipDetails, *SlipExpectation)
+		issues int
+	}{
+		{"reference and transaction match", func(*SlipDetails, *SlipExpectation) {}, 0},
+		{"bank keeps no reference, same transaction", func(d *SlipDetails, _ *SlipExpectation) { d.Reference = "" }, 0},
+		{"reference in another case", func(d *SlipDetails, _ *SlipExpectation) { d.Reference = "ns2603100001" }, 0},
+		{"another order's reference", func(d *SlipDetails, _ *SlipExpectation) { d.Reference = "NS2603100002" }, 1},
+		{"reference on a slip for an order without one", func(_ *SlipDetails, w *SlipExpectation) { w.Reference = "" }, 1},
+		{"no reference, bank reports no transaction", func(d *SlipDetails, _ *SlipExpectation) { d.Reference, d.TransRef = "", "" }, 1},
+		{"no reference, another transaction", func(d *SlipDetails, _ *SlipExpectation) { d.Reference, d.TransRef = "", "0154113215BTF00000" }, 1},
+		{"a satang short", func(d *SlipDetails, _ *SlipExpectation) { d.Amount = 1499.99 }, 1},
+		{"transferred the day before the order", func(d *SlipDetails, _ *SlipExpectation) { d.TransferredAt = ordered.AddDate(0, 0, -1) }, 1},
+		{"late evening UTC is the next day in Bangkok", func(d *SlipDetails, _ *SlipExpectation) {
+			d.TransferredAt = time.Date(2026, 3, 12, 18, 0, 0, 0, time.UTC)
+		}, 1},
+		{"no transfer date", func(d *SlipDetails, _ *SlipExpectation) { d.TransferredAt = time.Time{} }, 1},
+		{"wrong amount and reference", func(d *SlipDetails, _ *SlipExpectation) { d.Amount, d.Reference = 15, "X" }, 2},
+	}
+	for _, tc := range cases {
+		got, w := good, want
+		tc.edit(&got, &w)
+		if problems := MatchSlip(w, &got); len(problems) != tc.issues {
+			t.Errorf("%s: %d problems %q, want %d", tc.name, len(problems), problems, tc.issues)
+		}
+	}
+}
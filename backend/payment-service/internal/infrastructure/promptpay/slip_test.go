@@ -0,0 +1,42 @@
+package promptpay
+
+import (
+	"errors"
+	"testing"
+)
+
+// slipPayload builds a slip mini QR the way banks print it
+func slipPayload(bank, transRef string) string {
+	return withCRC(tlv("00", tlv("00", "000001")+tlv("01", bank)+tlv("02", transRef))+tlv("51", "TH"), "91")
+}
+
+func TestParseSlipQR(t *testing.T) {
+	valid := slipPayload("004", "0154113215BTF06542")
+	if valid != "00390006000001010300402180154113215BTF065425102TH9104F405" {
+		t.Fatalf("slipPayload = %s", valid)
+	}
+	slip, err := ParseSlipQR(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SlipQR{APIID: "000001", SendingBank: "004", TransRef: "0154113215BTF06542", Country: "TH"}
+	if *slip != want {
+		t.Errorf("ParseSlipQR = %+v, want %+v", *slip, want)
+	}
+
+	malformed := map[string]string{
+		"empty":                    "",
+		"truncated":                valid[:len(valid)-6],
+		"cut inside tag 00":        valid[:20],
+		"flipped character":        valid[:10] + "2" + valid[11:],
+		"PromptPay payment QR":     "00020101021129370016A000000677010111011300660000000005802TH530376463048956",
+		"no transaction":           withCRC(tlv("00", tlv("00", "000001")+tlv("01", "004"))+tlv("51", "TH"), "91"),
+		"no tag 00":                withCRC(tlv("51", "TH"), "91"),
+		"sub-tags run off the end": withCRC(tlv("00", "0006000001019"), "91"),
+	}
+	for name, s := range malformed {
+		if _, err := ParseSlipQR(s); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("%s: %v, want ErrInvalidPayload", name, err)
+		}
+	}
+}
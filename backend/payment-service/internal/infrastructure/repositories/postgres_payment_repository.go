@@ -0,0 +1,424 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nurseshift/payment-service/internal/domain/entities"
+	domainrepos "nurseshift/payment-service/internal/domain/repositories"
+	"nurseshift/payment-service/internal/infrastructure/eventbus"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PostgresPaymentRepository implements PaymentRepository for PostgreSQL
+type PostgresPaymentRepository struct {
+	db     *sql.DB
+	schema string
+	outbox *eventbus.Outbox
+}
+
+// NewPostgresPaymentRepository creates a new PostgreSQL payment repository. Approvals are announced
+// through outbox, which may be nil.
+func NewPostgresPaymentRepository(db *sql.DB, schema string, outbox *eventbus.Outbox) *PostgresPaymentRepository {
+	return &PostgresPaymentRepository{
+		db:     db,
+		schema: schema,
+		outbox: outbox,
+	}
+}
+
+const packageColumns = `id, name, type::text, price, duration_days, max_departments, features,
+	COALESCE(is_popular, false), COALESCE(is_active, true), created_at, updated_at`
+
+func scanPackage(row interface{ Scan(...any) error }) (*entities.Package, error) {
+	var p entities.Package
+	var pkgType sql.NullString
+	var maxDepartments sql.NullInt64
+	var features []byte
+	if err := row.Scan(&p.ID, &p.Name, &pkgType, &p.Price, &p.Duration, &maxDepartments, &features,
+		&p.IsPopular, &p.IsActive, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if pkgType.Valid {
+		p.Type = &pkgType.String
+	}
+	if maxDepartments.Valid {
+		n := int(maxDepartments.Int64)
+		p.MaxDepartments = &n
+	}
+	p.Features = []string{}
+	if len(features) > 0 {
+		_ = json.Unmarshal(features, &p.Features)
+	}
+	return &p, nil
+}
+
+// paymentSelect reads payments with the package name; the payments table is aliased p
+func (r *PostgresPaymentRepository) paymentSelect() string {
+	return fmt.Sprintf(`
+		SELECT p.id, p.user_id, p.package_id, COALESCE(pk.name, ''), p.amount, p.status::text,
+			p.reference, p.evidence_url, p.payment_date, p.approved_by, p.approved_at, p.extended_days,
+			p.rejection_reason, p.verification_status, p.slip_trans_ref, p.slip_sending_bank, p.slip_note,
+			p.created_at, p.updated_at
+		FROM %s.payments p
+		LEFT JOIN %s.packages pk ON pk.id = p.package_id`, r.schema, r.schema)
+}
+
+func scanPayment(row interface{ Scan(...any) error }) (*entities.Payment, error) {
+	var p entities.Payment
+	var packageID uuid.NullUUID
+	var approvedBy uuid.NullUUID
+	var extendedDays sql.NullInt64
+	var paymentDate sql.NullTime
+	if err := row.Scan(&p.ID, &p.UserID, &packageID, &p.PackageName, &p.Amount, &p.Status,
+		&p.Reference, &p.Evidence, &paymentDate, &approvedBy, &p.ApprovedAt, &extendedDays,
+		&p.RejectReason, &p.Verification, &p.SlipTransRef, &p.SlipBank, &p.SlipNote,
+		&p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.PackageID = packageID.UUID
+	if approvedBy.Valid {
+		p.ApprovedBy = &approvedBy.UUID
+	}
+	if extendedDays.Valid {
+		n := int(extendedDays.Int64)
+		p.ExtendedDays = &n
+	}
+	if paymentDate.Valid {
+		p.PaymentDate = paymentDate.Time.Format("2006-01-02")
+	}
+	return &p, nil
+}
+
+// ListPackages returns the packages, optionally only active ones
+func (r *PostgresPaymentRepository) ListPackages(ctx context.Context, activeOnly bool) ([]entities.Package, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s.packages", packageColumns, r.schema)
+	if activeOnly {
+		query += " WHERE COALESCE(is_active, true) = true"
+	}
+	query += " ORDER BY price ASC"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query packages: %w", err)
+	}
+	defer rows.Close()
+
+	packages := []entities.Package{}
+	for rows.Next() {
+		p, err := scanPackage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan package: %w", err)
+		}
+		packages = append(packages, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating packages: %w", err)
+	}
+	return packages, nil
+}
+
+// GetPackage returns an active package
+func (r *PostgresPaymentRepository) GetPackage(ctx context.Context, id uuid.UUID) (*entities.Package, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s.packages WHERE id = $1 AND COALESCE(is_active, true) = true",
+		packageColumns, r.schema)
+	p, err := scanPackage(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domainrepos.ErrPackageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get package: %w", err)
+	}
+	return p, nil
+}
+
+// CreatePayment stores a new pending payment; orders are created without evidence and wait for a slip
+func (r *PostgresPaymentRepository) CreatePayment(ctx context.Context, p *entities.Payment) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s.payments (id, user_id, package_id, amount, status, payment_date, reference, evidence_url,
+			verification_status, slip_trans_ref, slip_sending_bank, slip_note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'pending', $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+	`, r.schema)
+	if _, err := r.db.ExecContext(ctx, query, p.ID, p.UserID, p.PackageID, p.Amount, p.PaymentDate, p.Reference,
+		p.Evidence, p.Verification, p.SlipTransRef, p.SlipBank, p.SlipNote); err != nil {
+		if slipTaken(err) {
+			return domainrepos.ErrSlipUsed
+		}
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+	created, err := r.GetPayment(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	*p = *created
+	return nil
+}
+
+// GetPayment returns a payment with its package name
+func (r *PostgresPaymentRepository) GetPayment(ctx context.Context, id uuid.UUID) (*entities.Payment, error) {
+	p, err := scanPayment(r.db.QueryRowContext(ctx, r.paymentSelect()+" WHERE p.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, domainrepos.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return p, nil
+}
+
+// ListPayments returns payments matching the filter, newest first
+func (r *PostgresPaymentRepository) ListPayments(ctx context.Context, filter entities.PaymentFilter) ([]entities.Payment, error) {
+	var where []string
+	var args []any
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		where = append(where, fmt.Sprintf("p.user_id = $%d", len(args)))
+	}
+	if filter.Status != nil {
+		args = append(args, string(*filter.Status))
+		where = append(where, fmt.Sprintf("p.status::text = $%d", len(args)))
+	}
+	if filter.Verification != nil {
+		args = append(args, *filter.Verification)
+		where = append(where, fmt.Sprintf("p.verification_status = $%d", len(args)))
+	}
+	query := r.paymentSelect()
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY p.created_at DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := []entities.Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		payments = append(payments, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payments: %w", err)
+	}
+	return payments, nil
+}
+
+// ResubmitPayment attaches a verified slip to a pending or rejected payment and sets it pending again
+func (r *PostgresPaymentRepository) ResubmitPayment(ctx context.Context, id uuid.UUID, slip entities.SlipEvidence) (*entities.Payment, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s.payments
+		SET evidence_url = $2, status = 'pending', payment_date = CURRENT_DATE,
+			verification_status = $3, slip_trans_ref = NULLIF($4, ''), slip_sending_bank = NULLIF($5, ''), slip_note = $6,
+			rejection_reason = NULL, approved_by = NULL, approved_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status::text IN ('pending', 'rejected')
+	`, r.schema)
+	result, err := r.db.ExecContext(ctx, query, id, slip.Evidence, slip.Verification, slip.TransRef, slip.SendingBank, slip.Note)
+	if slipTaken(err) {
+		return nil, domainrepos.ErrSlipUsed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resubmit payment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := r.GetPayment(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, domainrepos.ErrPaymentApproved
+	}
+	return r.GetPayment(ctx, id)
+}
+
+// slipTaken reports whether err is the unique index on payments.slip_trans_ref refusing a slip that
+// another payment consumed after SlipUsed was checked
+func slipTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_payments_slip_trans_ref"
+}
+
+// SlipUsed reports whether a slip transaction already settles a payment other than exceptID
+func (r *PostgresPaymentRepository) SlipUsed(ctx context.Context, transRef string, exceptID uuid.UUID) (bool, error) {
+	query := fmt.Sprintf(`
+		SELECT EXISTS (SELECT 1 FROM %s.payments WHERE slip_trans_ref = $1 AND id <> $2)
+	`, r.schema)
+	var used bool
+	if err := r.db.QueryRowContext(ctx, query, transRef, exceptID).Scan(&used); err != nil {
+		return false, fmt.Errorf("failed to check slip: %w", err)
+	}
+	return used, nil
+}
+
+// ApprovePayment approves a pending payment and extends the payer's subscription in one transaction.
+// The new expiry follows entities.ExtendedExpiry, and a user suspended for expiry is reactivated so
+// cron-service keeps counting their days down.
+func (r *PostgresPaymentRepository) ApprovePayment(ctx context.Context, id, approvedBy uuid.UUID, extendedDays *int) (*entities.Payment, *entities.Subscription, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var userID, packageID uuid.UUID
+	var status, packageName string
+	var amount float64
+	var hasSlip bool
+	var duration int
+	var pkgType sql.NullString
+	var maxDepartments sql.NullInt64
+	lockQuery := fmt.Sprintf(`
+		SELECT p.user_id, p.status::text, p.evidence_url IS NOT NULL, pk.duration_days, pk.type::text, pk.max_departments,
+			p.package_id, pk.name, p.amount
+		FROM %s.payments p
+		JOIN %s.packages pk ON pk.id = p.package_id
+		WHERE p.id = $1
+		FOR UPDATE OF p
+	`, r.schema, r.schema)
+	err = tx.QueryRowContext(ctx, lockQuery, id).Scan(&userID, &status, &hasSlip, &duration, &pkgType, &maxDepartments,
+		&packageID, &packageName, &amount)
+	if err == sql.ErrNoRows {
+		return nil, nil, domainrepos.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock payment: %w", err)
+	}
+	if status != string(entities.PaymentStatusPending) {
+		return nil, nil, domainrepos.ErrPaymentNotPending
+	}
+	if !hasSlip {
+		return nil, nil, domainrepos.ErrPaymentAwaitingSlip
+	}
+
+	days := duration
+	if extendedDays != nil {
+		days = *extendedDays
+	}
+
+	approveQuery := fmt.Sprintf(`
+		UPDATE %s.payments
+		SET status = 'approved', approved_by = $2, approved_at = NOW(), extended_days = $3,
+			rejection_reason = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, r.schema)
+	if _, err := tx.ExecContext(ctx, approveQuery, id, approvedBy, days); err != nil {
+		return nil, nil, fmt.Errorf("failed to approve payment: %w", err)
+	}
+
+	var pkgTypeArg, maxDepartmentsArg any
+	if pkgType.Valid {
+		pkgTypeArg = pkgType.String
+	}
+	if maxDepartments.Valid {
+		maxDepartmentsArg = maxDepartments.Int64
+	}
+	var current sql.NullTime
+	lockUserQuery := fmt.Sprintf("SELECT subscription_expires_at FROM %s.users WHERE id = $1 FOR UPDATE", r.schema)
+	err = tx.QueryRowContext(ctx, lockUserQuery, userID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("payer %s no longer exists", userID)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock payer: %w", err)
+	}
+	var currentExpiry *time.Time
+	if current.Valid {
+		currentExpiry = &current.Time
+	}
+	sub := &entities.Subscription{UserID: userID}
+	extendQuery := fmt.Sprintf(`
+		UPDATE %s.users u
+		SET subscription_expires_at = $2,
+			days_remaining = GREATEST(0, EXTRACT(EPOCH FROM ($2 - NOW())) / 86400),
+			package_type = COALESCE($3, u.package_type),
+			max_departments = COALESCE($4, u.max_departments),
+			status = CASE WHEN u.status = 'suspended' THEN 'active' ELSE u.status END,
+			updated_at = NOW()
+		WHERE u.id = $1
+		RETURNING u.subscription_expires_at, COALESCE(u.package_type::text, ''), COALESCE(u.max_departments, 0), u.days_remaining
+	`, r.schema)
+	err = tx.QueryRowContext(ctx, extendQuery, userID, entities.ExtendedExpiry(currentExpiry, time.Now(), days), pkgTypeArg, maxDepartmentsArg).
+		Scan(&sub.ExpiresAt, &sub.PackageType, &sub.MaxDepartments, &sub.DaysRemaining)
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("payer %s no longer exists", userID)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extend subscription: %w", err)
+	}
+
+	if r.outbox != nil {
+		e, err := eventbus.NewEvent(eventbus.PaymentApproved, id.String(), "", eventbus.PaymentApprovedPayload{
+			PaymentID:    id.String(),
+			UserID:       userID.String(),
+			PackageID:    packageID.String(),
+			PackageName:  packageName,
+			Amount:       amount,
+			ExtendedDays: days,
+			ApprovedBy:   approvedBy.String(),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := r.outbox.Enqueue(ctx, tx, e); err != nil {
+			return nil, nil, fmt.Errorf("failed to enqueue %s: %w", eventbus.PaymentApproved, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit approval: %w", err)
+	}
+
+	p, err := r.GetPayment(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, sub, nil
+}
+
+// RejectPayment rejects a pending payment
+func (r *PostgresPaymentRepository) RejectPayment(ctx context.Context, id, rejectedBy uuid.UUID, reason string) (*entities.Payment, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s.payments
+		SET status = 'rejected', rejection_reason = $3, approved_by = $2, approved_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status::text = 'pending'
+	`, r.schema)
+	result, err := r.db.ExecContext(ctx, query, id, rejectedBy, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject payment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := r.GetPayment(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, domainrepos.ErrPaymentNotPending
+	}
+	return r.GetPayment(ctx, id)
+}
+
+// Stats returns ledger counters
+func (r *PostgresPaymentRepository) Stats(ctx context.Context) (*entities.PaymentStats, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE status::text = 'approved'),
+			COUNT(*) FILTER (WHERE status::text = 'pending'),
+			COUNT(*) FILTER (WHERE status::text = 'rejected'),
+			COALESCE(SUM(amount) FILTER (WHERE status::text = 'approved'), 0)
+		FROM %s.payments
+	`, r.schema)
+	var s entities.PaymentStats
+	if err := r.db.QueryRowContext(ctx, query).Scan(&s.TotalPayments, &s.Approved, &s.Pending, &s.Rejected, &s.TotalRevenue); err != nil {
+		return nil, fmt.Errorf("failed to query payment stats: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSlipNotFound is returned when a stored slip does not exist
+var ErrSlipNotFound = errors.New("slip not found")
+
+// SlipStorage stores payment evidence slips. Keys are opaque to callers and are what the payments table
+// keeps in evidence_url, so a different backend (S3, GCS, ...) only needs its own implementation.
+type SlipStorage interface {
+	// Save stores the slip under a key derived from name and returns the key
+	Save(ctx context.Context, name string, data []byte) (string, error)
+	// Open returns the slip stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// LocalSlipStorage keeps slips on the local filesystem under dir
+type LocalSlipStorage struct {
+	dir string
+}
+
+// NewLocalSlipStorage creates the storage directory if needed
+func NewLocalSlipStorage(dir string) (*LocalSlipStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create slip directory: %w", err)
+	}
+	return &LocalSlipStorage{dir: dir}, nil
+}
+
+// NewSlipStorage returns the storage backend selected by driver
+func NewSlipStorage(driver, dir string) (SlipStorage, error) {
+	switch driver {
+	case "", "local":
+		return NewLocalSlipStorage(dir)
+	default:
+		return nil, fmt.Errorf("unsupported slip storage driver %q", driver)
+	}
+}
+
+// Save writes the slip to dir/<name>; name must be a plain file name
+func (s *LocalSlipStorage) Save(ctx context.Context, name string, data []byte) (string, error) {
+	key, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	tmp := key + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return "", fmt.Errorf("failed to write slip: %w", err)
+	}
+	if err := os.Rename(tmp, key); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("failed to store slip: %w", err)
+	}
+	return name, nil
+}
+
+// Open opens the slip stored under key
+func (s *LocalSlipStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, ErrSlipNotFound
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSlipNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open slip: %w", err)
+	}
+	return f, nil
+}
+
+func (s *LocalSlipStorage) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid slip key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
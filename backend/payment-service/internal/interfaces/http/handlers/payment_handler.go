@@ -1,320 +1,516 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
 	"time"
 
+	"nurseshift/payment-service/internal/domain/entities"
+	"nurseshift/payment-service/internal/domain/repositories"
+	"nurseshift/payment-service/internal/infrastructure/audit"
+	"nurseshift/payment-service/internal/infrastructure/promptpay"
+	"nurseshift/payment-service/internal/infrastructure/storage"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
+// maxExtendedDays caps a manual extension on approval (10 years)
+const maxExtendedDays = 3650
+
+// slipExtensions lists the accepted slip content types, sniffed from the file bytes; slips must be
+// images so their verification QR can be read
+var slipExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
 // PaymentHandler handles payment-related HTTP requests
-type PaymentHandler struct{}
+type PaymentHandler struct {
+	repo           repositories.PaymentRepository
+	slips          storage.SlipStorage
+	maxUploadBytes int64
+	promptPay      *promptpay.Target    // nil when no PromptPay ID is configured
+	slipLookup     promptpay.SlipLookup // nil leaves amount and date to manual review
+}
 
 // NewPaymentHandler creates a new payment handler
-func NewPaymentHandler() *PaymentHandler {
-	return &PaymentHandler{}
+func NewPaymentHandler(repo repositories.PaymentRepository, slips storage.SlipStorage, maxUploadBytes int64, promptPay *promptpay.Target, slipLookup promptpay.SlipLookup) *PaymentHandler {
+	return &PaymentHandler{
+		repo:           repo,
+		slips:          slips,
+		maxUploadBytes: maxUploadBytes,
+		promptPay:      promptPay,
+		slipLookup:     slipLookup,
+	}
 }
 
-// Package represents a package data structure
-type Package struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Price        int       `json:"price"`
-	Duration     int       `json:"duration"`
-	Description  string    `json:"description"`
-	Features     []string  `json:"features"`
-	IsPopular    bool      `json:"isPopular"`
-	IsActive     bool      `json:"isActive"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+// currentUser returns the authenticated user set by AuthMiddleware
+func currentUser(c *fiber.Ctx) (uuid.UUID, bool) {
+	s, _ := c.Locals("userID").(string)
+	id, err := uuid.Parse(s)
+	return id, err == nil
 }
 
-// Payment represents a payment data structure
-type Payment struct {
-	ID           string    `json:"id"`
-	UserID       string    `json:"userId"`
-	PackageID    string    `json:"packageId"`
-	PackageName  string    `json:"packageName"`
-	Amount       int       `json:"amount"`
-	Status       string    `json:"status"` // pending, approved, rejected
-	Evidence     *string   `json:"evidence"`
-	PaymentDate  string    `json:"paymentDate"`
-	ApprovedDate *string   `json:"approvedDate"`
-	ExtendedDays *int      `json:"extendedDays"`
-	RejectReason *string   `json:"rejectReason"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+func isAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals("userRole").(string)
+	return role == "admin"
 }
 
-// Mock data
-var mockPackages = []Package{
-	{
-		ID:          "1",
-		Name:        "แพ็คเกจมาตรฐาน",
-		Price:       990,
-		Duration:    30,
-		Description: "เหมาะสำหรับแผนกขนาดกลาง",
-		Features: []string{
-			"จัดการหลายแผนก",
-			"พนักงานไม่จำกัด",
-			"ตารางเวรอัตโนมัติ",
-			"การแจ้งเตือนแบบเรียลไทม์",
-			"รายงานและสถิติ",
-		},
-		IsPopular: true,
-		IsActive:  true,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	},
-	{
-		ID:          "2",
-		Name:        "แพ็คเกจระดับองค์กร",
-		Price:       2990,
-		Duration:    90,
-		Description: "เหมาะสำหรับองค์กรขนาดใหญ่",
-		Features: []string{
-			"จัดการหลายแผนกไม่จำกัด",
-			"พนักงานไม่จำกัด",
-			"ตารางเวรอัตโนมัติด้วย AI",
-			"การแจ้งเตือนแบบเรียลไทม์",
-			"รายงานและสถิติขั้นสูง",
-			"การสำรองข้อมูล",
-			"การสนับสนุนลูกค้าแบบพิเศษ",
-		},
-		IsPopular: false,
-		IsActive:  true,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	},
+func unauthorized(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"status":  "error",
+		"message": "กรุณาเข้าสู่ระบบ",
+	})
+}
+
+func paymentNotFound(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"status":  "error",
+		"message": "ไม่พบข้อมูลการชำระเงิน",
+	})
 }
 
-var mockPayments = []Payment{
-	{
-		ID:           "1",
-		UserID:       "user-1",
-		PackageID:    "1",
-		PackageName:  "แพ็คเกจมาตรฐาน",
-		Amount:       990,
-		Status:       "approved",
-		Evidence:     stringPtr("payment_evidence_1.jpg"),
-		PaymentDate:  "2024-03-01",
-		ApprovedDate: stringPtr("2024-03-02"),
-		ExtendedDays: intPtr(30),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	},
-	{
-		ID:          "2",
-		UserID:      "user-1",
-		PackageID:   "2",
-		PackageName: "แพ็คเกจระดับองค์กร",
-		Amount:      2990,
-		Status:      "pending",
-		Evidence:    stringPtr("payment_evidence_2.jpg"),
-		PaymentDate: "2024-03-15",
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	},
-	{
-		ID:           "3",
-		UserID:       "user-1",
-		PackageID:    "1",
-		PackageName:  "แพ็คเกจมาตรฐาน",
-		Amount:       990,
-		Status:       "rejected",
-		Evidence:     stringPtr("payment_evidence_3.jpg"),
-		PaymentDate:  "2024-02-15",
-		RejectReason: stringPtr("หลักฐานการโอนเงินไม่ชัดเจน"),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	},
+// loadOwnPayment returns the payment named by :id when the caller paid it or is an admin
+func (h *PaymentHandler) loadOwnPayment(c *fiber.Ctx) (*entities.Payment, error) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return nil, unauthorized(c)
+	}
+	paymentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, paymentNotFound(c)
+	}
+	payment, err := h.repo.GetPayment(c.Context(), paymentID)
+	if errors.Is(err, repositories.ErrPaymentNotFound) || (err == nil && payment.UserID != userID && !isAdmin(c)) {
+		return nil, paymentNotFound(c)
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลการชำระเงินได้",
+			"error":   err.Error(),
+		})
+	}
+	return payment, nil
 }
 
-// GetPayments returns all payments with optional filters
-func (h *PaymentHandler) GetPayments(c *fiber.Ctx) error {
-	userID := c.Query("userId")
-	status := c.Query("status")
+// readSlip reads and checks the "evidence" file of a multipart request
+func (h *PaymentHandler) readSlip(c *fiber.Ctx) ([]byte, string, error) {
+	fileHeader, err := c.FormFile("evidence")
+	if err != nil {
+		return nil, "", c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "กรุณาแนบหลักฐานการโอนเงิน (evidence)",
+		})
+	}
+	if fileHeader.Size > h.maxUploadBytes {
+		return nil, "", c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"status":  "error",
+			"message": fmt.Sprintf("ไฟล์หลักฐานต้องมีขนาดไม่เกิน %d MB", h.maxUploadBytes>>20),
+		})
+	}
+	data, err := readFormFile(fileHeader)
+	if err != nil {
+		return nil, "", c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถอ่านไฟล์หลักฐานได้",
+			"error":   err.Error(),
+		})
+	}
+	ext, ok := slipExtensions[http.DetectContentType(data)]
+	if !ok {
+		return nil, "", c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "หลักฐานต้องเป็นรูปภาพสลิป JPG หรือ PNG",
+		})
+	}
+	return data, ext, nil
+}
+
+func readFormFile(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
 
-	filteredPayments := mockPayments
+// storeSlip saves the slip of a payment under a fresh key
+func (h *PaymentHandler) storeSlip(c *fiber.Ctx, paymentID uuid.UUID, data []byte, ext string) (string, error) {
+	name := fmt.Sprintf("%s-%d%s", paymentID, time.Now().UnixNano(), ext)
+	key, err := h.slips.Save(c.Context(), name, data)
+	if err != nil {
+		return "", c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถบันทึกหลักฐานการโอนเงินได้",
+			"error":   err.Error(),
+		})
+	}
+	return key, nil
+}
 
-	// Filter by user
-	if userID != "" {
-		var filtered []Payment
-		for _, payment := range filteredPayments {
-			if payment.UserID == userID {
-				filtered = append(filtered, payment)
-			}
-		}
-		filteredPayments = filtered
+// GetPayments returns the caller's payments; admins see every payment and may filter by userId
+func (h *PaymentHandler) GetPayments(c *fiber.Ctx) error {
+	userID, ok := currentUser(c)
+	if !ok {
+		return unauthorized(c)
 	}
 
-	// Filter by status
-	if status != "" {
-		var filtered []Payment
-		for _, payment := range filteredPayments {
-			if payment.Status == status {
-				filtered = append(filtered, payment)
+	var filter entities.PaymentFilter
+	if isAdmin(c) {
+		if v := c.Query("userId"); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"status":  "error",
+					"message": "userId ไม่ถูกต้อง",
+				})
 			}
+			filter.UserID = &id
+		}
+	} else {
+		filter.UserID = &userID
+	}
+	if v := c.Query("status"); v != "" {
+		status := entities.PaymentStatus(v)
+		if !status.IsValid() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "สถานะการชำระเงินไม่ถูกต้อง",
+			})
 		}
-		filteredPayments = filtered
+		filter.Status = &status
+	}
+	if v := c.Query("verification"); v != "" {
+		filter.Verification = &v
+	}
+
+	payments, err := h.repo.ListPayments(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลการชำระเงินได้",
+			"error":   err.Error(),
+		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "ดึงข้อมูลการชำระเงินสำเร็จ",
-		"data":    filteredPayments,
+		"data":    payments,
+	})
+}
+
+// GetPackages returns the active packages that can be paid for
+func (h *PaymentHandler) GetPackages(c *fiber.Ctx) error {
+	packages, err := h.repo.ListPackages(c.Context(), true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลแพ็คเกจได้",
+			"error":   err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ดึงข้อมูลแพ็คเกจสำเร็จ",
+		"data":    packages,
 	})
 }
 
-// CreatePayment creates a new payment record
+// CreatePayment records a pending payment for a package with its transfer slip.
+// Multipart form: packageId, paymentDate (YYYY-MM-DD, optional) and the evidence file.
+// The amount is the package price.
 func (h *PaymentHandler) CreatePayment(c *fiber.Ctx) error {
-	var req struct {
-		UserID      string  `json:"userId" validate:"required"`
-		PackageID   string  `json:"packageId" validate:"required"`
-		PackageName string  `json:"packageName" validate:"required"`
-		Amount      int     `json:"amount" validate:"required"`
-		Evidence    *string `json:"evidence"`
+	userID, ok := currentUser(c)
+	if !ok {
+		return unauthorized(c)
 	}
 
-	if err := c.BodyParser(&req); err != nil {
+	packageID, err := uuid.Parse(c.FormValue("packageId"))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"status":  "error",
-			"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
-			"error":   err.Error(),
+			"message": "packageId ไม่ถูกต้อง",
+		})
+	}
+	paymentDate := c.FormValue("paymentDate", time.Now().Format("2006-01-02"))
+	if d, err := time.Parse("2006-01-02", paymentDate); err != nil || d.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "วันที่ชำระเงินไม่ถูกต้อง",
 		})
 	}
 
-	// Create new payment
-	newPayment := Payment{
-		ID:          uuid.New().String(),
-		UserID:      req.UserID,
-		PackageID:   req.PackageID,
-		PackageName: req.PackageName,
-		Amount:      req.Amount,
-		Status:      "pending",
-		Evidence:    req.Evidence,
-		PaymentDate: time.Now().Format("2006-01-02"),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	pkg, err := h.repo.GetPackage(c.Context(), packageID)
+	if errors.Is(err, repositories.ErrPackageNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบแพ็คเกจ",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลแพ็คเกจได้",
+			"error":   err.Error(),
+		})
 	}
 
-	// Add to mock data
-	mockPayments = append(mockPayments, newPayment)
+	data, ext, err := h.readSlip(c)
+	if data == nil {
+		return err
+	}
+	payment := &entities.Payment{
+		ID:          uuid.New(),
+		UserID:      userID,
+		PackageID:   pkg.ID,
+		Amount:      pkg.Price,
+		PaymentDate: paymentDate,
+	}
+	slip, err := h.verifySlip(c, payment, data)
+	if slip == nil {
+		return err
+	}
+	key, err := h.storeSlip(c, payment.ID, data, ext)
+	if key == "" {
+		return err
+	}
+	payment.Evidence = &key
+	payment.Verification = &slip.Verification
+	payment.SlipTransRef = &slip.TransRef
+	payment.SlipBank = &slip.SendingBank
+	payment.SlipNote = slip.Note
+
+	err = h.repo.CreatePayment(c.Context(), payment)
+	if errors.Is(err, repositories.ErrSlipUsed) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "สลิปนี้ถูกใช้ชำระเงินรายการอื่นแล้ว",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถสร้างรายการชำระเงินได้",
+			"error":   err.Error(),
+		})
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"status":  "success",
 		"message": "สร้างรายการชำระเงินสำเร็จ",
-		"data":    newPayment,
+		"data":    payment,
 	})
 }
 
 // GetPayment returns specific payment details
 func (h *PaymentHandler) GetPayment(c *fiber.Ctx) error {
-	paymentID := c.Params("id")
-
-	for _, payment := range mockPayments {
-		if payment.ID == paymentID {
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{
-				"status":  "success",
-				"message": "ดึงข้อมูลการชำระเงินสำเร็จ",
-				"data":    payment,
-			})
-		}
+	payment, err := h.loadOwnPayment(c)
+	if payment == nil {
+		return err
 	}
 
-	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-		"status":  "error",
-		"message": "ไม่พบข้อมูลการชำระเงิน",
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ดึงข้อมูลการชำระเงินสำเร็จ",
+		"data":    payment,
 	})
 }
 
-// UpdatePayment updates payment information
-func (h *PaymentHandler) UpdatePayment(c *fiber.Ctx) error {
-	paymentID := c.Params("id")
-
-	var req struct {
-		Evidence *string `json:"evidence"`
+// GetPaymentEvidence streams the transfer slip of a payment
+func (h *PaymentHandler) GetPaymentEvidence(c *fiber.Ctx) error {
+	payment, err := h.loadOwnPayment(c)
+	if payment == nil {
+		return err
+	}
+	if payment.Evidence == nil || *payment.Evidence == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบหลักฐานการโอนเงิน",
+		})
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	rc, err := h.slips.Open(c.Context(), *payment.Evidence)
+	if errors.Is(err, storage.ErrSlipNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"status":  "error",
-			"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+			"message": "ไม่พบหลักฐานการโอนเงิน",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถเปิดหลักฐานการโอนเงินได้",
 			"error":   err.Error(),
 		})
 	}
-
-	// Find and update payment
-	for i, payment := range mockPayments {
-		if payment.ID == paymentID {
-			if req.Evidence != nil {
-				mockPayments[i].Evidence = req.Evidence
-			}
-			mockPayments[i].Status = "pending" // Reset to pending when updated
-			mockPayments[i].UpdatedAt = time.Now()
-			mockPayments[i].PaymentDate = time.Now().Format("2006-01-02")
-			mockPayments[i].RejectReason = nil // Clear previous reject reason
-
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{
-				"status":  "success",
-				"message": "อัปเดตการชำระเงินสำเร็จ",
-				"data":    mockPayments[i],
-			})
-		}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถเปิดหลักฐานการโอนเงินได้",
+			"error":   err.Error(),
+		})
 	}
 
-	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-		"status":  "error",
-		"message": "ไม่พบข้อมูลการชำระเงิน",
-	})
+	c.Set(fiber.HeaderContentType, http.DetectContentType(data))
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	return c.Send(data)
 }
 
-// ApprovePayment approves a payment
-func (h *PaymentHandler) ApprovePayment(c *fiber.Ctx) error {
-	paymentID := c.Params("id")
+// UpdatePayment uploads the slip of an order, or resubmits a pending or rejected payment with a new
+// slip (multipart field evidence). The slip is verified before the payment is queued for approval.
+func (h *PaymentHandler) UpdatePayment(c *fiber.Ctx) error {
+	payment, err := h.loadOwnPayment(c)
+	if payment == nil {
+		return err
+	}
+	if payment.Status == entities.PaymentStatusApproved {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "การชำระเงินนี้ได้รับการอนุมัติแล้ว",
+		})
+	}
 
-	var req struct {
-		ExtendedDays int `json:"extendedDays" validate:"required"`
+	data, ext, err := h.readSlip(c)
+	if data == nil {
+		return err
 	}
+	slip, err := h.verifySlip(c, payment, data)
+	if slip == nil {
+		return err
+	}
+	key, err := h.storeSlip(c, payment.ID, data, ext)
+	if key == "" {
+		return err
+	}
+	slip.Evidence = key
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	updated, err := h.repo.ResubmitPayment(c.Context(), payment.ID, *slip)
+	if errors.Is(err, repositories.ErrPaymentApproved) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"status":  "error",
-			"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+			"message": "การชำระเงินนี้ได้รับการอนุมัติแล้ว",
+		})
+	}
+	if errors.Is(err, repositories.ErrSlipUsed) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "สลิปนี้ถูกใช้ชำระเงินรายการอื่นแล้ว",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถอัปเดตการชำระเงินได้",
 			"error":   err.Error(),
 		})
 	}
 
-	// Find and approve payment
-	for i, payment := range mockPayments {
-		if payment.ID == paymentID {
-			mockPayments[i].Status = "approved"
-			mockPayments[i].ApprovedDate = stringPtr(time.Now().Format("2006-01-02"))
-			mockPayments[i].ExtendedDays = &req.ExtendedDays
-			mockPayments[i].UpdatedAt = time.Now()
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "อัปเดตการชำระเงินสำเร็จ",
+		"data":    updated,
+	})
+}
+
+// ApprovePayment approves a pending payment and extends the payer's subscription.
+// extendedDays is optional and defaults to the package duration.
+func (h *PaymentHandler) ApprovePayment(c *fiber.Ctx) error {
+	adminID, ok := currentUser(c)
+	if !ok {
+		return unauthorized(c)
+	}
+	paymentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return paymentNotFound(c)
+	}
 
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{
-				"status":  "success",
-				"message": "อนุมัติการชำระเงินสำเร็จ",
-				"data":    mockPayments[i],
+	var req struct {
+		ExtendedDays *int `json:"extendedDays"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+				"error":   err.Error(),
 			})
 		}
 	}
+	if req.ExtendedDays != nil && (*req.ExtendedDays < 1 || *req.ExtendedDays > maxExtendedDays) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": fmt.Sprintf("จำนวนวันที่ต่ออายุต้องอยู่ระหว่าง 1-%d วัน", maxExtendedDays),
+		})
+	}
 
-	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-		"status":  "error",
-		"message": "ไม่พบข้อมูลการชำระเงิน",
+	rec := h.auditPayment(c, "payment.approve", paymentID)
+	payment, subscription, err := h.repo.ApprovePayment(c.Context(), paymentID, adminID, req.ExtendedDays)
+	switch {
+	case errors.Is(err, repositories.ErrPaymentNotFound):
+		return paymentNotFound(c)
+	case errors.Is(err, repositories.ErrPaymentNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "การชำระเงินนี้ได้รับการตรวจสอบแล้ว",
+		})
+	case errors.Is(err, repositories.ErrPaymentAwaitingSlip):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "คำสั่งซื้อนี้ยังไม่มีสลิปการโอนเงิน",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถอนุมัติการชำระเงินได้",
+			"error":   err.Error(),
+		})
+	}
+
+	rec.After(fiber.Map{"payment": payment, "subscription": subscription})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "อนุมัติการชำระเงินสำเร็จ",
+		"data": fiber.Map{
+			"payment":      payment,
+			"subscription": subscription,
+		},
 	})
 }
 
-// RejectPayment rejects a payment
+// auditPayment starts the audit record of a review of a payment, with the payment as it is now
+func (h *PaymentHandler) auditPayment(c *fiber.Ctx, action string, paymentID uuid.UUID) *audit.Recorder {
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action(action)
+	if before, err := h.repo.GetPayment(c.Context(), paymentID); err == nil {
+		rec.Before(before)
+	}
+	return rec
+}
+
+// RejectPayment rejects a pending payment with a reason
 func (h *PaymentHandler) RejectPayment(c *fiber.Ctx) error {
-	paymentID := c.Params("id")
+	adminID, ok := currentUser(c)
+	if !ok {
+		return unauthorized(c)
+	}
+	paymentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return paymentNotFound(c)
+	}
 
 	var req struct {
 		Reason string `json:"reason" validate:"required"`
 	}
-
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"status":  "error",
@@ -322,61 +518,70 @@ func (h *PaymentHandler) RejectPayment(c *fiber.Ctx) error {
 			"error":   err.Error(),
 		})
 	}
+	if req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "กรุณาระบุเหตุผลที่ปฏิเสธ",
+		})
+	}
 
-	// Find and reject payment
-	for i, payment := range mockPayments {
-		if payment.ID == paymentID {
-			mockPayments[i].Status = "rejected"
-			mockPayments[i].RejectReason = &req.Reason
-			mockPayments[i].UpdatedAt = time.Now()
-
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{
-				"status":  "success",
-				"message": "ปฏิเสธการชำระเงินสำเร็จ",
-				"data":    mockPayments[i],
-			})
-		}
+	rec := h.auditPayment(c, "payment.reject", paymentID)
+	payment, err := h.repo.RejectPayment(c.Context(), paymentID, adminID, req.Reason)
+	switch {
+	case errors.Is(err, repositories.ErrPaymentNotFound):
+		return paymentNotFound(c)
+	case errors.Is(err, repositories.ErrPaymentNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "การชำระเงินนี้ได้รับการตรวจสอบแล้ว",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถปฏิเสธการชำระเงินได้",
+			"error":   err.Error(),
+		})
 	}
 
-	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-		"status":  "error",
-		"message": "ไม่พบข้อมูลการชำระเงิน",
+	rec.After(payment)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ปฏิเสธการชำระเงินสำเร็จ",
+		"data":    payment,
 	})
 }
 
 // GetPaymentStats returns payment statistics
 func (h *PaymentHandler) GetPaymentStats(c *fiber.Ctx) error {
-	totalPayments := len(mockPayments)
-	approved := 0
-	pending := 0
-	rejected := 0
-	totalRevenue := 0
-
-	for _, payment := range mockPayments {
-		switch payment.Status {
-		case "approved":
-			approved++
-			totalRevenue += payment.Amount
-		case "pending":
-			pending++
-		case "rejected":
-			rejected++
-		}
+	stats, err := h.repo.Stats(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงสถิติการชำระเงินได้",
+			"error":   err.Error(),
+		})
 	}
-
-	stats := fiber.Map{
-		"totalPayments": totalPayments,
-		"approved":      approved,
-		"pending":       pending,
-		"rejected":      rejected,
-		"totalRevenue":  totalRevenue,
-		"packages":      mockPackages,
+	packages, err := h.repo.ListPackages(c.Context(), false)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลแพ็คเกจได้",
+			"error":   err.Error(),
+		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "ดึงสถิติการชำระเงินสำเร็จ",
-		"data":    stats,
+		"data": fiber.Map{
+			"totalPayments": stats.TotalPayments,
+			"approved":      stats.Approved,
+			"pending":       stats.Pending,
+			"rejected":      stats.Rejected,
+			"totalRevenue":  stats.TotalRevenue,
+			"packages":      packages,
+		},
 	})
 }
 
@@ -388,12 +593,3 @@ func (h *PaymentHandler) Health(c *fiber.Ctx) error {
 		"timestamp": time.Now(),
 	})
 }
-
-// Helper functions
-func stringPtr(s string) *string {
-	return &s
-}
-
-func intPtr(i int) *int {
-	return &i
-}
\ No newline at end of file
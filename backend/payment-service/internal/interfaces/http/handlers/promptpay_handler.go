@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"time"
+
+	"nurseshift/payment-service/internal/domain/entities"
+	"nurseshift/payment-service/internal/domain/repositories"
+	"nurseshift/payment-service/internal/infrastructure/promptpay"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// newOrderReference returns a short unique-enough reference for the PromptPay QR, e.g. NS4QZ7K2MXA9
+func newOrderReference() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "NS" + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)[:10], nil
+}
+
+// promptPayData is the QR section of an order response
+func (h *PaymentHandler) promptPayData(p *entities.Payment) (fiber.Map, error) {
+	reference := ""
+	if p.Reference != nil {
+		reference = *p.Reference
+	}
+	payload, err := promptpay.Payload(*h.promptPay, p.Amount, reference)
+	if err != nil {
+		return nil, err
+	}
+	return fiber.Map{
+		"payload":   payload,
+		"amount":    p.Amount,
+		"reference": reference,
+	}, nil
+}
+
+// CreateOrder opens a PromptPay order for a package: a pending payment without a slip and the QR
+// payload to pay it with. Body: packageId.
+func (h *PaymentHandler) CreateOrder(c *fiber.Ctx) error {
+	userID, ok := currentUser(c)
+	if !ok {
+		return unauthorized(c)
+	}
+	var req struct {
+		PackageID string `json:"packageId"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+			"error":   err.Error(),
+		})
+	}
+	return h.createOrder(c, userID, req.PackageID)
+}
+
+// CreateInternalOrder opens a PromptPay order on behalf of package-service. Body: userId, packageId.
+func (h *PaymentHandler) CreateInternalOrder(c *fiber.Ctx) error {
+	var req struct {
+		UserID    string `json:"userId"`
+		PackageID string `json:"packageId"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ข้อมูลที่ส่งมาไม่ถูกต้อง",
+			"error":   err.Error(),
+		})
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "userId ไม่ถูกต้อง",
+		})
+	}
+	return h.createOrder(c, userID, req.PackageID)
+}
+
+func (h *PaymentHandler) createOrder(c *fiber.Ctx, userID uuid.UUID, rawPackageID string) error {
+	if h.promptPay == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ยังไม่ได้ตั้งค่าบัญชีพร้อมเพย์สำหรับรับชำระเงิน",
+		})
+	}
+	packageID, err := uuid.Parse(rawPackageID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "packageId ไม่ถูกต้อง",
+		})
+	}
+	pkg, err := h.repo.GetPackage(c.Context(), packageID)
+	if errors.Is(err, repositories.ErrPackageNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบแพ็คเกจ",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถดึงข้อมูลแพ็คเกจได้",
+			"error":   err.Error(),
+		})
+	}
+	if pkg.Price <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "แพ็คเกจนี้ไม่ต้องชำระเงิน",
+		})
+	}
+
+	reference, err := newOrderReference()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถสร้างคำสั่งซื้อได้",
+			"error":   err.Error(),
+		})
+	}
+	verification := entities.VerificationAwaitingSlip
+	payment := &entities.Payment{
+		ID:           uuid.New(),
+		UserID:       userID,
+		PackageID:    pkg.ID,
+		Amount:       pkg.Price,
+		PaymentDate:  time.Now().In(promptpay.Bangkok).Format("2006-01-02"),
+		Reference:    &reference,
+		Verification: &verification,
+	}
+	if err := h.repo.CreatePayment(c.Context(), payment); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถสร้างคำสั่งซื้อได้",
+			"error":   err.Error(),
+		})
+	}
+	qr, err := h.promptPayData(payment)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถสร้าง QR พร้อมเพย์ได้",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"message": "สร้างคำสั่งซื้อสำเร็จ",
+		"data": fiber.Map{
+			"payment":   payment,
+			"promptpay": qr,
+		},
+	})
+}
+
+// GetPromptPayQR returns the PromptPay QR payload of an order that still waits for its slip
+func (h *PaymentHandler) GetPromptPayQR(c *fiber.Ctx) error {
+	payment, err := h.loadOwnPayment(c)
+	if payment == nil {
+		return err
+	}
+	if h.promptPay == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ยังไม่ได้ตั้งค่าบัญชีพร้อมเพย์สำหรับรับชำระเงิน",
+		})
+	}
+	if payment.Status != entities.PaymentStatusPending && payment.Status != entities.PaymentStatusRejected {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "คำสั่งซื้อนี้ไม่ต้องชำระเงินแล้ว",
+		})
+	}
+	qr, err := h.promptPayData(payment)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถสร้าง QR พร้อมเพย์ได้",
+			"error":   err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "ดึงข้อมูล QR พร้อมเพย์สำเร็จ",
+		"data":    qr,
+	})
+}
+
+// verifySlip reads the slip's verification QR and matches it against the payment. A slip already
+// used for another payment is refused; without a SlipLookup, or when the lookup is unavailable, the
+// slip is accepted for manual review of amount and date.
+func (h *PaymentHandler) verifySlip(c *fiber.Ctx, payment *entities.Payment, data []byte) (*entities.SlipEvidence, error) {
+	qr, err := promptpay.DecodeSlipImage(data)
+	if err != nil {
+		return nil, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบ QR ตรวจสอบสลิปในรูปภาพ กรุณาอัปโหลดสลิปฉบับเต็มจากแอปธนาคาร",
+			"error":   err.Error(),
+		})
+	}
+
+	used, err := h.repo.SlipUsed(c.Context(), qr.TransRef, payment.ID)
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถตรวจสอบสลิปได้",
+			"error":   err.Error(),
+		})
+	}
+	if used {
+		return nil, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "สลิปนี้ถูกใช้ชำระเงินรายการอื่นแล้ว",
+		})
+	}
+
+	slip := &entities.SlipEvidence{
+		TransRef:     qr.TransRef,
+		SendingBank:  qr.SendingBank,
+		Verification: entities.VerificationManualReview,
+	}
+	if h.slipLookup == nil {
+		return slip, nil
+	}
+
+	details, err := h.slipLookup.Lookup(c.Context(), qr)
+	if errors.Is(err, promptpay.ErrSlipNotFound) {
+		return nil, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่พบรายการโอนเงินตามสลิปนี้ที่ธนาคาร",
+		})
+	}
+	if err != nil {
+		note := "ตรวจสอบสลิปอัตโนมัติไม่สำเร็จ: " + err.Error()
+		slip.Note = &note
+		return slip, nil
+	}
+
+	want := promptpay.SlipExpectation{
+		TransRef: qr.TransRef,
+		Amount:   payment.Amount,
+		From:     payment.CreatedAt,
+		To:       time.Now(),
+	}
+	if payment.Reference != nil {
+		want.Reference = *payment.Reference
+	}
+	if payment.CreatedAt.IsZero() {
+		// a direct payment being created: the transfer happened on the declared payment date
+		want.From, _ = time.ParseInLocation("2006-01-02", payment.PaymentDate, promptpay.Bangkok)
+	}
+	if problems := promptpay.MatchSlip(want, details); len(problems) > 0 {
+		return nil, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ข้อมูลในสลิปไม่ตรงกับรายการชำระเงิน",
+			"errors":  problems,
+		})
+	}
+	slip.Verification = entities.VerificationVerified
+	return slip, nil
+}
@@ -9,6 +9,8 @@ import (
 	"syscall"
 	"time"
 
+	"nurseshift/priority-service/internal/infrastructure/access"
+	"nurseshift/priority-service/internal/infrastructure/audit"
 	"nurseshift/priority-service/internal/infrastructure/config"
 	"nurseshift/priority-service/internal/infrastructure/database"
 	"nurseshift/priority-service/internal/interfaces/http/handlers"
@@ -59,9 +61,11 @@ func main() {
 	}
 	defer conn.Close()
 	priorityRepo := database.NewPriorityRepository(conn)
+	auditRepo := audit.NewRepository(conn.DB, cfg.Database.Schema)
 
 	// Initialize handlers
-	priorityHandler := handlers.NewPriorityHandler(priorityRepo)
+	guard := access.NewGuard(access.NewRepository(conn.DB, cfg.Database.Schema))
+	priorityHandler := handlers.NewPriorityHandler(priorityRepo, guard)
 
 	// Routes
 	api := app.Group("/api/v1")
@@ -69,6 +73,7 @@ func main() {
 	// Protected routes (authentication required)
 	priorities := api.Group("/priorities")
 	priorities.Use(middleware.AuthMiddleware(""))
+	priorities.Use(audit.Middleware(auditRepo, "priority"))
 	{
 		priorities.Get("/", priorityHandler.GetPriorities)
 		priorities.Put("/:id", priorityHandler.UpdatePriority)
@@ -170,7 +170,7 @@ func (r *PriorityRepository) FindOrCreateDefaults(ctx context.Context, departmen
 		{DepartmentID: departmentID, Name: "จำนวนเวรดึกติดต่อกัน", Description: sql.NullString{String: "จำกัดจำนวนเวรดึกที่พนักงานคนหนึ่งทำติดกันไม่เกิน X วัน", Valid: true}, PriorityOrder: 3, IsActive: true},
 		{DepartmentID: departmentID, Name: "จำนวนเวรติดต่อกัน", Description: sql.NullString{String: "จำกัดจำนวนเวรทุกประเภทที่พนักงานคนหนึ่งทำติดกันไม่เกิน X วัน", Valid: true}, PriorityOrder: 4, IsActive: true},
 		{DepartmentID: departmentID, Name: "จำนวนชั่วโมงทำงานสูงสุดติดต่อกันโดยไม่พัก", Description: sql.NullString{String: "จำกัดจำนวนชั่วโมงการทำงานต่อเนื่องโดยไม่พักไม่เกิน X ชั่วโมง", Valid: true}, PriorityOrder: 5, IsActive: true},
-		{DepartmentID: departmentID, Name: "จำนวนชั่วโมงการทำงานทั้งหมด", Description: sql.NullString{String: "จำกัดจำนวนชั่วโมงทำงานรวมในช่วงเวลาที่กำหนดไม่เกิน X ชั่วโมง", Valid: true}, PriorityOrder: 6, IsActive: true},
+		{DepartmentID: departmentID, Name: "จำนวนชั่วโมงการทำงานทั้งหมด", Description: sql.NullString{String: "จำกัดจำนวนชั่วโมงทำงานรวมในแต่ละเดือนไม่เกิน X ชั่วโมง", Valid: true}, PriorityOrder: 6, IsActive: true},
 	}
 
 	for i := range defaults {
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"nurseshift/priority-service/internal/infrastructure/access"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// authorize answers with a department access denial unless the caller's role in the department
+// allows act on its priorities
+func (h *PriorityHandler) authorize(c *fiber.Ctx, departmentID string, act access.Action) (bool, error) {
+	userID, _ := c.Locals("userID").(string)
+	role, _ := c.Locals("role").(string)
+	if err := h.access.Check(c.Context(), userID, role, departmentID, access.ResourcePriority, act); err != nil {
+		return false, accessError(c, err)
+	}
+	return true, nil
+}
+
+// authorizePriority is authorize for the department of an existing priority
+func (h *PriorityHandler) authorizePriority(ctx context.Context, c *fiber.Ctx, id string, act access.Action) (bool, error) {
+	departmentID, _, err := h.repo.GetMeta(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบความสำคัญที่ระบุ"})
+	}
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return h.authorize(c, departmentID, act)
+}
+
+// accessError renders a failed department access check: the denial, or a lookup failure
+func accessError(c *fiber.Ctx, err error) error {
+	var denial *access.Denial
+	if errors.As(err, &denial) {
+		return c.Status(denial.Status).JSON(denial.Body())
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": "ไม่สามารถตรวจสอบสิทธิ์ในแผนกได้", "error": err.Error()})
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"time"
 
+	"nurseshift/priority-service/internal/infrastructure/access"
+	"nurseshift/priority-service/internal/infrastructure/audit"
 	"nurseshift/priority-service/internal/infrastructure/database"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,12 +14,44 @@ import (
 
 // PriorityHandler handles priority-related HTTP requests (DB-backed)
 type PriorityHandler struct {
-	repo *database.PriorityRepository
+	repo   *database.PriorityRepository
+	access *access.Guard
 }
 
 // NewPriorityHandler creates a new priority handler
-func NewPriorityHandler(repo *database.PriorityRepository) *PriorityHandler {
-	return &PriorityHandler{repo: repo}
+func NewPriorityHandler(repo *database.PriorityRepository, guard *access.Guard) *PriorityHandler {
+	return &PriorityHandler{repo: repo, access: guard}
+}
+
+// auditPriorities starts the audit record of a change to priorities, with their current state
+func (h *PriorityHandler) auditPriorities(ctx context.Context, c *fiber.Ctx, action string, ids ...string) *audit.Recorder {
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action(action)
+	if before := h.prioritySnapshots(ctx, ids...); len(before) > 0 {
+		rec.Department(before[0]["departmentId"].(string))
+		rec.Before(before)
+	}
+	return rec
+}
+
+// prioritySnapshots is the audit view of the given priorities; missing ones are left out
+func (h *PriorityHandler) prioritySnapshots(ctx context.Context, ids ...string) []fiber.Map {
+	var out []fiber.Map
+	for _, id := range ids {
+		rec, err := h.repo.GetByID(ctx, id)
+		if err != nil {
+			continue
+		}
+		out = append(out, fiber.Map{
+			"id":           rec.ID,
+			"departmentId": rec.DepartmentID,
+			"name":         rec.Name,
+			"order":        rec.PriorityOrder,
+			"isActive":     rec.IsActive,
+			"config":       rec.Config.String,
+		})
+	}
+	return out
 }
 
 // GetPriorities returns all priorities for a department
@@ -30,6 +64,9 @@ func (h *PriorityHandler) GetPriorities(c *fiber.Ctx) error {
 			"message": "กรุณาระบุ departmentId",
 		})
 	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
 
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
@@ -75,11 +112,11 @@ func (h *PriorityHandler) GetPriorities(c *fiber.Ctx) error {
 			settingLabel = "จำนวนชั่วโมงทำงานติดต่อกันสูงสุด"
 			defaultVal = 48
 		case "จำนวนชั่วโมงการทำงานทั้งหมด":
-			// ตีความเป็นความแตกต่างชั่วโมงทำงานรวมระหว่างบุคคล
-			settingType = "maxTotalWorkHoursDifference"
+			// เพดานชั่วโมงทำงานรวมต่อคนในเดือนที่จัดเวร
+			settingType = "maxTotalWorkHours"
 			settingUnit = "ชั่วโมง"
-			settingLabel = "ความแตกต่างชั่วโมงการทำงานรวมสูงสุดระหว่างบุคคล"
-			defaultVal = 16
+			settingLabel = "จำนวนชั่วโมงการทำงานรวมสูงสุดต่อเดือน"
+			defaultVal = 192
 		}
 
 		// Extract setting value from config JSON if exists
@@ -141,6 +178,12 @@ func (h *PriorityHandler) UpdatePriority(c *fiber.Ctx) error {
 
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
+	if ok, err := h.authorizePriority(ctx, c, id, access.ActionMutate); !ok {
+		return err
+	}
+
+	// reordering shifts the other priorities of the department too; the record keeps the one moved
+	audited := h.auditPriorities(ctx, c, "priority.update", id)
 
 	if req.IsActive != nil {
 		if err := h.repo.UpdateActive(ctx, id, *req.IsActive); err != nil {
@@ -157,6 +200,7 @@ func (h *PriorityHandler) UpdatePriority(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
+	audited.After(h.prioritySnapshots(ctx, id))
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "อัปเดตความสำคัญสำเร็จ",
@@ -184,6 +228,10 @@ func (h *PriorityHandler) UpdatePrioritySetting(c *fiber.Ctx) error {
 	}
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
+	if ok, err := h.authorizePriority(ctx, c, id, access.ActionMutate); !ok {
+		return err
+	}
+	audited := h.auditPriorities(ctx, c, "priority.update_setting", id)
 	if err := h.repo.UpdateSetting(ctx, id, req.SettingValue); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
@@ -191,6 +239,7 @@ func (h *PriorityHandler) UpdatePrioritySetting(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
+	audited.After(h.prioritySnapshots(ctx, id))
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "data": fiber.Map{
 		"id": rec.ID, "name": rec.Name, "description": rec.Description.String, "order": rec.PriorityOrder, "isActive": rec.IsActive,
 	}})
@@ -207,9 +256,16 @@ func (h *PriorityHandler) SwapPriorityOrder(c *fiber.Ctx) error {
 	}
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
+	for _, id := range []string{req.PriorityID1, req.PriorityID2} {
+		if ok, err := h.authorizePriority(ctx, c, id, access.ActionMutate); !ok {
+			return err
+		}
+	}
+	audited := h.auditPriorities(ctx, c, "priority.swap_order", req.PriorityID1, req.PriorityID2)
 	if err := h.repo.SwapOrder(ctx, req.PriorityID1, req.PriorityID2); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
+	audited.After(h.prioritySnapshots(ctx, req.PriorityID1, req.PriorityID2))
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สลับลำดับความสำคัญสำเร็จ"})
 }
 
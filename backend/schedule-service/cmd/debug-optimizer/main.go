@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
 	"time"
@@ -10,6 +12,9 @@ import (
 )
 
 func main() {
+	solverName := flag.String("solver", "greedy", "greedy | local-search")
+	budgetMs := flag.Int("ms", 2000, "time budget for anytime solvers (ms)")
+	flag.Parse()
 	os.Setenv("SCHEDULE_DEBUG", "1")
 
 	month := time.Now().Format("2006-01")
@@ -40,7 +45,11 @@ func main() {
 	// Working days: จันทร์-ศุกร์
 	working := map[int]bool{0: false, 1: true, 2: true, 3: true, 4: true, 5: true, 6: false}
 
-	out, err := optimizer.SolveMonth(optimizer.Input{
+	solver, err := optimizer.NewSolver(*solverName, time.Duration(*budgetMs)*time.Millisecond)
+	if err != nil {
+		panic(err)
+	}
+	res, err := solver.Solve(context.Background(), optimizer.Input{
 		DepartmentID:   "D1",
 		Month:          month,
 		Shifts:         shifts,
@@ -59,10 +68,11 @@ func main() {
 	for _, s := range staff {
 		name[s.ID] = s.Name
 	}
-	for _, a := range out {
+	for _, a := range res.Assignments {
 		cnt[a.StaffID]++
 	}
-	fmt.Println("=== RESULT ===")
+	fmt.Printf("=== RESULT (%s) objective=%.1f lowerBound=%.1f gap=%.3f unmet=%d iterations=%d ===\n",
+		res.Solver, res.Objective, res.LowerBound, res.Gap, res.UnmetTotal, res.Iterations)
 	for _, s := range staff {
 		fmt.Printf("%-15s -> %d\n", name[s.ID], cnt[s.ID])
 	}
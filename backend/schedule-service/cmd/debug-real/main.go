@@ -90,12 +90,11 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	maxDiff := 1
-	if v, err := repo.GetPriorityValue(context.Background(), departmentID, "จำนวนเวรเท่าเทียมในแต่ละประเภท"); err == nil && v.Valid {
-		if v.Int64 >= 0 && v.Int64 <= 5 {
-			maxDiff = int(v.Int64)
-		}
+	prios, err := repo.ListSchedulingPriorities(context.Background(), departmentID)
+	if err != nil {
+		log.Printf("load priorities: %v (using defaults)", err)
 	}
+	rules := optimizer.BuildRules(prios)
 
 	out, err := optimizer.SolveMonth(optimizer.Input{
 		DepartmentID:   departmentID,
@@ -105,7 +104,8 @@ func main() {
 		WorkingDays:    working,
 		Holidays:       holidays,
 		Leaves:         leaves,
-		MaxDiffAllowed: maxDiff,
+		MaxDiffAllowed: rules.MaxDiffAllowed(1),
+		Rules:          rules,
 	})
 	if err != nil {
 		log.Fatal(err)
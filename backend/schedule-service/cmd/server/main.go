@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -9,8 +10,13 @@ import (
 	"syscall"
 	"time"
 
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/audit"
 	"nurseshift/schedule-service/internal/infrastructure/config"
 	dbpkg "nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/infrastructure/entitlements"
+	"nurseshift/schedule-service/internal/infrastructure/eventbus"
+	"nurseshift/schedule-service/internal/infrastructure/realtime"
 	"nurseshift/schedule-service/internal/interfaces/http/handlers"
 	"nurseshift/schedule-service/internal/interfaces/http/middleware"
 
@@ -43,8 +49,8 @@ func main() {
 	app.Use(helmet.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     strings.Join(cfg.CORS.Origins, ","),
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,Last-Event-ID",
 		AllowCredentials: true,
 	}))
 
@@ -59,14 +65,40 @@ func main() {
 	}
 	defer conn.Close()
 	repo := dbpkg.NewScheduleRepository(conn)
-	scheduleHandler := handlers.NewScheduleHandler(repo)
+	checker := entitlements.NewChecker(conn.DB, "nurse_shift")
+	auditRepo := audit.NewRepository(conn.DB, "nurse_shift")
+	guard := access.NewGuard(access.NewRepository(conn.DB, "nurse_shift"))
+	eventRepo := realtime.NewRepository(conn.DB, "nurse_shift")
+	hub := realtime.NewHub(eventRepo)
+	pruneCtx, stopPruner := context.WithCancel(context.Background())
+	go hub.RunPruner(pruneCtx, 7*24*time.Hour, time.Hour)
+
+	// Published schedules are enqueued in the outbox with the publish and delivered to
+	// notification-service; events from other services are deduplicated through the inbox
+	outbox := eventbus.NewOutbox(conn.DB, "nurse_shift", "schedule-service")
+	repo.SetOutbox(outbox)
+	dispatcher := eventbus.NewDispatcher(outbox, eventbus.DispatcherConfig{})
+	dispatcher.Subscribe(eventbus.NewWebhook("notification-service",
+		strings.TrimRight(cfg.Services.NotificationURL, "/")+"/api/v1/internal/events", cfg.Services.InternalToken),
+		eventbus.SchedulePublished)
+	dispatchCtx, stopDispatcher := context.WithCancel(context.Background())
+	go dispatcher.Run(dispatchCtx)
+	inbox := eventbus.NewInbox(conn.DB, "nurse_shift")
+
+	scheduleHandler := handlers.NewScheduleHandler(repo, checker, guard, auditRepo, hub, inbox)
 
 	// Routes
 	api := app.Group("/api/v1")
 
+	// Live roster events (Server-Sent Events); registered ahead of the /schedules group so that
+	// EventSource clients can pass the token in the query
+	api.Get("/schedules/events", middleware.QueryTokenMiddleware(), middleware.AuthMiddleware(""), scheduleHandler.StreamScheduleEvents)
+
 	// Protected routes (authentication required)
 	schedules := api.Group("/schedules")
 	schedules.Use(middleware.AuthMiddleware(""))
+	schedules.Use(middleware.SubscriptionMiddleware(checker))
+	schedules.Use(audit.Middleware(auditRepo, "schedule"))
 	{
 		schedules.Get("/", scheduleHandler.GetSchedules)
 		schedules.Post("/", scheduleHandler.CreateSchedule)
@@ -76,6 +108,46 @@ func main() {
 		schedules.Post("/edit-shift", scheduleHandler.EditShift)
 		schedules.Post("/check-overlap", scheduleHandler.CheckShiftOverlap)
 		schedules.Post("/optimize-generate", scheduleHandler.OptimizeGenerate)
+		schedules.Get("/generation-runs", scheduleHandler.ListGenerationRuns)
+		schedules.Get("/generation-runs/:runId", scheduleHandler.GetGenerationRun)
+		schedules.Get("/rules", scheduleHandler.GetSchedulingRules)
+		schedules.Get("/rules/sequence", scheduleHandler.GetSequenceRules)
+		schedules.Get("/fairness", scheduleHandler.GetFairnessLedger)
+		schedules.Post("/fairness/refresh", scheduleHandler.RefreshFairnessLedger)
+		schedules.Put("/rules/sequence", scheduleHandler.SaveSequenceRules)
+		schedules.Get("/preferences", scheduleHandler.GetPreferences)
+		schedules.Put("/preferences", scheduleHandler.SavePreference)
+		schedules.Put("/preferences/policy", scheduleHandler.SavePreferencePolicy)
+		schedules.Get("/skills", scheduleHandler.GetSkills)
+		schedules.Post("/skills", scheduleHandler.CreateSkill)
+		schedules.Get("/skills/lapsed", scheduleHandler.GetLapsedCertifications)
+		schedules.Delete("/skills/:skillId", scheduleHandler.DeleteSkill)
+		schedules.Put("/skills/:skillId/staff/:staffId", scheduleHandler.SetStaffSkill)
+		schedules.Delete("/skills/:skillId/staff/:staffId", scheduleHandler.DeleteStaffSkill)
+		schedules.Put("/skills/:skillId/shifts/:shiftId", scheduleHandler.SetShiftSkillRequirement)
+		schedules.Get("/versions", scheduleHandler.ListScheduleVersions)
+		schedules.Post("/versions", scheduleHandler.CreateScheduleVersion)
+		schedules.Get("/versions/diff", scheduleHandler.DiffScheduleVersions)
+		schedules.Get("/versions/:versionId", scheduleHandler.GetScheduleVersion)
+		schedules.Patch("/versions/:versionId/status", scheduleHandler.UpdateScheduleVersionStatus)
+		schedules.Post("/versions/:versionId/publish", scheduleHandler.PublishScheduleVersion)
+		schedules.Post("/versions/:versionId/restore", scheduleHandler.RestoreScheduleVersion)
+		schedules.Get("/swaps", scheduleHandler.ListSwaps)
+		schedules.Post("/swaps", scheduleHandler.CreateSwap)
+		schedules.Get("/swaps/:swapId", scheduleHandler.GetSwap)
+		schedules.Post("/swaps/:swapId/propose", scheduleHandler.ProposeSwap)
+		schedules.Post("/swaps/:swapId/approve", scheduleHandler.ApproveSwap)
+		schedules.Post("/swaps/:swapId/reject", scheduleHandler.RejectSwap)
+		schedules.Post("/swaps/:swapId/cancel", scheduleHandler.CancelSwap)
+		schedules.Get("/repairs", scheduleHandler.ListRepairs)
+		schedules.Post("/repairs", scheduleHandler.CreateRepair)
+		schedules.Get("/repairs/:repairId", scheduleHandler.GetRepair)
+		schedules.Post("/repairs/:repairId/apply", scheduleHandler.ApplyRepair)
+		schedules.Post("/repairs/:repairId/discard", scheduleHandler.DiscardRepair)
+		schedules.Get("/export", scheduleHandler.ExportSchedule)
+		schedules.Get("/calendar-tokens", scheduleHandler.ListCalendarTokens)
+		schedules.Post("/calendar-tokens", scheduleHandler.CreateCalendarToken)
+		schedules.Delete("/calendar-tokens/:tokenId", scheduleHandler.RevokeCalendarToken)
 		schedules.Get("/:id", scheduleHandler.GetSchedule)
 		schedules.Put("/:id", scheduleHandler.UpdateSchedule)
 		schedules.Delete("/:id", scheduleHandler.DeleteSchedule)
@@ -84,11 +156,22 @@ func main() {
 	}
 
 	// Auto-generate routes
-	apiAuth := api.Group("/schedules").Use(middleware.AuthMiddleware(""))
+	apiAuth := api.Group("/schedules").Use(middleware.AuthMiddleware(""), middleware.SubscriptionMiddleware(checker), audit.Middleware(auditRepo, "schedule"))
 	// Route compatibility: point auto-generate to the new optimizer logic
 	apiAuth.Post("/auto-generate", scheduleHandler.AutoGenerate) // Enhanced Dynamic Priority Algorithm
 	apiAuth.Post("/ai-generate", scheduleHandler.AIGenerate)
 
+	// Audit trail of every service writing audit_logs
+	api.Get("/audit-logs", middleware.AuthMiddleware(""), scheduleHandler.ListAuditLogs)
+
+	// Service-to-service callbacks
+	internal := api.Group("/internal", middleware.InternalServiceMiddleware())
+	internal.Post("/leave-approved", scheduleHandler.LeaveApproved)
+	internal.Post("/events", scheduleHandler.ReceiveEvent)
+
+	// iCalendar subscriptions: the unguessable token in the URL is the credential
+	api.Get("/calendar/:token", scheduleHandler.CalendarFeed)
+
 	// Calendar meta (working/holiday)
 	api.Get("/calendar-meta", scheduleHandler.CalendarMeta)
 
@@ -109,6 +192,9 @@ func main() {
 	<-c
 
 	fmt.Println("\n🛑 Shutting down Schedule Service...")
+	stopPruner()
+	stopDispatcher()
+	hub.Close() // ends open event streams, which Shutdown would otherwise wait for
 	app.Shutdown()
 	fmt.Println("✅ Schedule Service stopped gracefully")
 }
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Calendar feed scopes
+const (
+	CalendarScopeStaff      = "staff"
+	CalendarScopeDepartment = "department"
+)
+
+// CalendarToken grants read access to one iCalendar feed; only the sha256 of the secret is stored
+type CalendarToken struct {
+	ID           string
+	Scope        string
+	DepartmentID string
+	StaffID      sql.NullString
+	CreatedBy    sql.NullString
+	CreatedAt    time.Time
+	LastUsedAt   sql.NullTime
+	RevokedAt    sql.NullTime
+}
+
+// NewCalendarSecret returns a random URL-safe feed secret and the hash to store for it
+func NewCalendarSecret() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return secret, HashCalendarSecret(secret), nil
+}
+
+// HashCalendarSecret is the lookup key of a feed secret
+func HashCalendarSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *ScheduleRepository) calendarTokensTable() string {
+	return fmt.Sprintf("%s.schedule_calendar_tokens", r.schema)
+}
+
+const calendarTokenColumns = "id, scope, department_id, staff_id, created_by, created_at, last_used_at, revoked_at"
+
+func scanCalendarToken(row interface{ Scan(...any) error }) (*CalendarToken, error) {
+	var t CalendarToken
+	if err := row.Scan(&t.ID, &t.Scope, &t.DepartmentID, &t.StaffID, &t.CreatedBy, &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateCalendarToken stores a new feed token under tokenHash
+func (r *ScheduleRepository) CreateCalendarToken(ctx context.Context, t *CalendarToken, tokenHash string) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	q := fmt.Sprintf(`INSERT INTO %s (id, token_hash, scope, department_id, staff_id, created_by, created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,NOW()) RETURNING created_at`, r.calendarTokensTable())
+	return r.conn.DB.QueryRowContext(ctx, q, t.ID, tokenHash, t.Scope, t.DepartmentID, t.StaffID, t.CreatedBy).Scan(&t.CreatedAt)
+}
+
+// ListCalendarTokens returns the feed tokens of a department, newest first
+func (r *ScheduleRepository) ListCalendarTokens(ctx context.Context, departmentID string) ([]CalendarToken, error) {
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE department_id = $1 ORDER BY created_at DESC", calendarTokenColumns, r.calendarTokensTable())
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []CalendarToken
+	for rows.Next() {
+		t, err := scanCalendarToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+// GetCalendarToken returns one feed token by id
+func (r *ScheduleRepository) GetCalendarToken(ctx context.Context, id string) (*CalendarToken, error) {
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", calendarTokenColumns, r.calendarTokensTable())
+	return scanCalendarToken(r.conn.DB.QueryRowContext(ctx, q, id))
+}
+
+// ResolveCalendarToken returns the active token for a secret hash and records the access;
+// revoked or unknown tokens return sql.ErrNoRows
+func (r *ScheduleRepository) ResolveCalendarToken(ctx context.Context, tokenHash string) (*CalendarToken, error) {
+	q := fmt.Sprintf(`UPDATE %s SET last_used_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL
+        RETURNING %s`, r.calendarTokensTable(), calendarTokenColumns)
+	return scanCalendarToken(r.conn.DB.QueryRowContext(ctx, q, tokenHash))
+}
+
+// RevokeCalendarToken disables a feed token; revoking twice is a no-op
+func (r *ScheduleRepository) RevokeCalendarToken(ctx context.Context, id string) error {
+	q := fmt.Sprintf("UPDATE %s SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1", r.calendarTokensTable())
+	res, err := r.conn.DB.ExecContext(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDepartmentName returns the display name of a department
+func (r *ScheduleRepository) GetDepartmentName(ctx context.Context, departmentID string) (string, error) {
+	q := fmt.Sprintf("SELECT name FROM %s.departments WHERE id = $1", r.schema)
+	var name string
+	err := r.conn.DB.QueryRowContext(ctx, q, departmentID).Scan(&name)
+	return name, err
+}
+
+// IsOwnStaff reports whether staffID is the caller's own staff record: the active account whose
+// email is the staff member's, as notification-service matches them
+func (r *ScheduleRepository) IsOwnStaff(ctx context.Context, userID, staffID string) (bool, error) {
+	q := fmt.Sprintf(`SELECT EXISTS (
+        SELECT 1 FROM %[1]s.department_staff ds
+        JOIN %[1]s.users u ON u.email = ds.email
+        WHERE ds.id = $1 AND u.id = $2 AND u.status = 'active')`, r.schema)
+	var ok bool
+	err := r.conn.DB.QueryRowContext(ctx, q, staffID, userID).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GenerationRun is one persisted schedule generation with its JSON report
+type GenerationRun struct {
+	ID            string
+	DepartmentID  string
+	Month         string
+	Generator     string
+	RequiredSlots int
+	FilledSlots   int
+	UnmetTotal    int
+	Report        json.RawMessage
+	CreatedBy     sql.NullString
+	CreatedAt     time.Time
+}
+
+func (r *ScheduleRepository) generationRunsTable() string {
+	return fmt.Sprintf("%s.schedule_generation_runs", r.schema)
+}
+
+// InsertGenerationRun stores a generation report
+func (r *ScheduleRepository) InsertGenerationRun(ctx context.Context, run *GenerationRun) error {
+	q := fmt.Sprintf(`INSERT INTO %s (id, department_id, month, generator, required_slots, filled_slots, unmet_total, report, created_by, created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,NOW()) RETURNING created_at`, r.generationRunsTable())
+	return r.conn.DB.QueryRowContext(ctx, q, run.ID, run.DepartmentID, run.Month, run.Generator, run.RequiredSlots, run.FilledSlots, run.UnmetTotal, []byte(run.Report), run.CreatedBy).Scan(&run.CreatedAt)
+}
+
+// ListGenerationRuns returns runs for a department (optionally one month), newest first, without the report body
+func (r *ScheduleRepository) ListGenerationRuns(ctx context.Context, departmentID, month string) ([]GenerationRun, error) {
+	q := fmt.Sprintf("SELECT id, department_id, month, generator, required_slots, filled_slots, unmet_total, created_by, created_at FROM %s WHERE department_id = $1", r.generationRunsTable())
+	args := []any{departmentID}
+	if month != "" {
+		q += " AND month = $2"
+		args = append(args, month)
+	}
+	q += " ORDER BY created_at DESC LIMIT 100"
+	rows, err := r.conn.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []GenerationRun
+	for rows.Next() {
+		var run GenerationRun
+		if err := rows.Scan(&run.ID, &run.DepartmentID, &run.Month, &run.Generator, &run.RequiredSlots, &run.FilledSlots, &run.UnmetTotal, &run.CreatedBy, &run.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
+
+// GetGenerationRun returns a run with its full report
+func (r *ScheduleRepository) GetGenerationRun(ctx context.Context, id string) (*GenerationRun, error) {
+	q := fmt.Sprintf("SELECT id, department_id, month, generator, required_slots, filled_slots, unmet_total, report, created_by, created_at FROM %s WHERE id = $1", r.generationRunsTable())
+	run := &GenerationRun{}
+	var report []byte
+	err := r.conn.DB.QueryRowContext(ctx, q, id).Scan(&run.ID, &run.DepartmentID, &run.Month, &run.Generator, &run.RequiredSlots, &run.FilledSlots, &run.UnmetTotal, &report, &run.CreatedBy, &run.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	run.Report = report
+	return run, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FairnessEntry is what one staff member worked in one month, kept so fairness can be balanced
+// over several months: shifts by shift type, weekend and public-holiday days worked and hours
+type FairnessEntry struct {
+	DepartmentID string
+	StaffID      string
+	Month        string         // YYYY-MM
+	ShiftCounts  map[string]int // shift type -> shifts
+	WeekendDays  int
+	HolidayDays  int
+	Hours        float64
+	UpdatedAt    time.Time
+}
+
+// ReplaceLedgerMonth replaces the department's ledger rows of month with entries
+func (r *ScheduleRepository) ReplaceLedgerMonth(ctx context.Context, departmentID, month string, entries []FairnessEntry) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.staff_fairness_ledger WHERE department_id = $1 AND month = $2", r.schema), departmentID, month); err != nil {
+		return err
+	}
+	q := fmt.Sprintf(`
+        INSERT INTO %s.staff_fairness_ledger (staff_id, department_id, month, shift_counts, weekend_days, holiday_days, hours, updated_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,NOW())`, r.schema)
+	for _, e := range entries {
+		counts, err := json.Marshal(e.ShiftCounts)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, q, e.StaffID, departmentID, month, string(counts), e.WeekendDays, e.HolidayDays, e.Hours); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListLedger returns the department's ledger rows from month from to month to (YYYY-MM, inclusive)
+func (r *ScheduleRepository) ListLedger(ctx context.Context, departmentID, from, to string) ([]FairnessEntry, error) {
+	q := fmt.Sprintf(`
+        SELECT staff_id, department_id, month, shift_counts, weekend_days, holiday_days, hours, updated_at
+        FROM %s.staff_fairness_ledger
+        WHERE department_id = $1 AND month >= $2 AND month <= $3
+        ORDER BY month, staff_id`, r.schema)
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []FairnessEntry
+	for rows.Next() {
+		var e FairnessEntry
+		var counts []byte
+		if err := rows.Scan(&e.StaffID, &e.DepartmentID, &e.Month, &counts, &e.WeekendDays, &e.HolidayDays, &e.Hours, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(counts, &e.ShiftCounts); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Defaults of a department's preference policy until its head nurse sets one
+const (
+	DefaultMaxRequestsOff       = 4
+	DefaultPreferenceCutoffDays = 7
+)
+
+// StaffPreference is what a staff member asks of the roster: shift types to get or to avoid, the
+// weekdays they would rather work and, for one month, the days they ask to have off
+type StaffPreference struct {
+	DepartmentID        string
+	StaffID             string
+	StaffName           string
+	PreferredShiftTypes []string
+	AvoidedShiftTypes   []string
+	PreferredWeekdays   []int // 0=Sun..6=Sat
+	RequestsOff         []string
+	UpdatedBy           sql.NullString
+	UpdatedAt           sql.NullTime
+}
+
+// PreferencePolicy caps request-off days per staff member per month and closes submissions
+// CutoffDays before the month starts
+type PreferencePolicy struct {
+	DepartmentID   string
+	MaxRequestsOff int
+	CutoffDays     int
+}
+
+// Cutoff is when submissions for month (YYYY-MM) close
+func (p PreferencePolicy) Cutoff(month string) (time.Time, error) {
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return first.AddDate(0, 0, -p.CutoffDays), nil
+}
+
+// ListPreferencesForMonth returns the preferences of the department's active staff with their
+// request-off days in month (YYYY-MM); staff who asked for nothing have empty lists
+func (r *ScheduleRepository) ListPreferencesForMonth(ctx context.Context, departmentID, month string) ([]StaffPreference, error) {
+	q := fmt.Sprintf(`
+        SELECT ds.id, ds.department_id, ds.name,
+               COALESCE(p.preferred_shift_types, '{}'), COALESCE(p.avoided_shift_types, '{}'), COALESCE(p.preferred_weekdays, '{}'),
+               ARRAY(
+                 SELECT to_char(o.request_date,'YYYY-MM-DD') FROM %[1]s.staff_request_off_days o
+                 WHERE o.staff_id = ds.id AND to_char(o.request_date,'YYYY-MM') = $2
+                 ORDER BY o.request_date
+               ),
+               p.updated_by, p.updated_at
+        FROM %[1]s.department_staff ds
+        LEFT JOIN %[1]s.staff_preferences p ON p.staff_id = ds.id
+        WHERE ds.department_id = $1 AND ds.is_active = true
+        ORDER BY ds.name`, r.schema)
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []StaffPreference
+	for rows.Next() {
+		var p StaffPreference
+		var weekdays pq.Int64Array
+		if err := rows.Scan(&p.StaffID, &p.DepartmentID, &p.StaffName, pq.Array(&p.PreferredShiftTypes), pq.Array(&p.AvoidedShiftTypes), &weekdays, pq.Array(&p.RequestsOff), &p.UpdatedBy, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		for _, d := range weekdays {
+			p.PreferredWeekdays = append(p.PreferredWeekdays, int(d))
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// SavePreference stores a staff member's shift-type and weekday preferences and replaces their
+// request-off days in month with p.RequestsOff
+func (r *ScheduleRepository) SavePreference(ctx context.Context, p *StaffPreference, month string) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	weekdays := make(pq.Int64Array, 0, len(p.PreferredWeekdays))
+	for _, d := range p.PreferredWeekdays {
+		weekdays = append(weekdays, int64(d))
+	}
+	q := fmt.Sprintf(`
+        INSERT INTO %s.staff_preferences (staff_id, department_id, preferred_shift_types, avoided_shift_types, preferred_weekdays, updated_by, updated_at)
+        VALUES ($1,$2,$3,$4,$5,$6,NOW())
+        ON CONFLICT (staff_id) DO UPDATE SET department_id = EXCLUDED.department_id,
+            preferred_shift_types = EXCLUDED.preferred_shift_types, avoided_shift_types = EXCLUDED.avoided_shift_types,
+            preferred_weekdays = EXCLUDED.preferred_weekdays, updated_by = EXCLUDED.updated_by, updated_at = NOW()`, r.schema)
+	if _, err := tx.ExecContext(ctx, q, p.StaffID, p.DepartmentID, pq.Array(p.PreferredShiftTypes), pq.Array(p.AvoidedShiftTypes), weekdays, p.UpdatedBy); err != nil {
+		return err
+	}
+	q = fmt.Sprintf("DELETE FROM %s.staff_request_off_days WHERE staff_id = $1 AND to_char(request_date,'YYYY-MM') = $2", r.schema)
+	if _, err := tx.ExecContext(ctx, q, p.StaffID, month); err != nil {
+		return err
+	}
+	q = fmt.Sprintf("INSERT INTO %s.staff_request_off_days (staff_id, department_id, request_date) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING", r.schema)
+	for _, d := range p.RequestsOff {
+		if _, err := tx.ExecContext(ctx, q, p.StaffID, p.DepartmentID, d); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetPreferencePolicy returns the department's policy, or the defaults when it has none
+func (r *ScheduleRepository) GetPreferencePolicy(ctx context.Context, departmentID string) (PreferencePolicy, error) {
+	p := PreferencePolicy{DepartmentID: departmentID, MaxRequestsOff: DefaultMaxRequestsOff, CutoffDays: DefaultPreferenceCutoffDays}
+	q := fmt.Sprintf("SELECT max_requests_off, cutoff_days FROM %s.schedule_preference_policies WHERE department_id = $1", r.schema)
+	err := r.conn.DB.QueryRowContext(ctx, q, departmentID).Scan(&p.MaxRequestsOff, &p.CutoffDays)
+	if err == sql.ErrNoRows {
+		return p, nil
+	}
+	return p, err
+}
+
+// SavePreferencePolicy creates or replaces the department's policy
+func (r *ScheduleRepository) SavePreferencePolicy(ctx context.Context, p PreferencePolicy) error {
+	q := fmt.Sprintf(`
+        INSERT INTO %s.schedule_preference_policies (department_id, max_requests_off, cutoff_days, updated_at)
+        VALUES ($1,$2,$3,NOW())
+        ON CONFLICT (department_id) DO UPDATE SET max_requests_off = EXCLUDED.max_requests_off, cutoff_days = EXCLUDED.cutoff_days, updated_at = NOW()`, r.schema)
+	_, err := r.conn.DB.ExecContext(ctx, q, p.DepartmentID, p.MaxRequestsOff, p.CutoffDays)
+	return err
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repair proposal states
+const (
+	RepairPending   = "pending"
+	RepairApplied   = "applied"
+	RepairDiscarded = "discarded"
+)
+
+var (
+	// ErrRepairStale is returned when the roster changed after the repair was computed
+	ErrRepairStale = errors.New("roster changed since the repair was proposed")
+	// ErrRepairClosed is returned when the repair was already applied or discarded
+	ErrRepairClosed = errors.New("repair proposal is no longer pending")
+)
+
+// ScheduleRepair is a proposed change set that reacts to an approved leave
+type ScheduleRepair struct {
+	ID           string
+	DepartmentID string
+	Month        string
+	LeaveID      sql.NullString
+	StaffID      string
+	StartDate    string
+	EndDate      string
+	Status       string
+	Changes      json.RawMessage // []optimizer.RepairChange
+	Unfilled     json.RawMessage
+	Violations   json.RawMessage // []optimizer.Violation
+	Moved        int
+	// RosterVersion fingerprints the month's schedules the repair was computed from
+	RosterVersion sql.NullString
+	CreatedBy     sql.NullString
+	CreatedAt     time.Time
+	DecidedBy     sql.NullString
+	DecidedAt     sql.NullTime
+}
+
+// RepairEdit is one assignment a repair or an approved swap removes from or adds to a roster
+type RepairEdit struct {
+	Action  string `json:"action"`
+	StaffID string `json:"staffId"`
+	ShiftID string `json:"shiftId"`
+	Date    string `json:"date"`
+}
+
+func (r *ScheduleRepository) repairsTable() string {
+	return fmt.Sprintf("%s.schedule_repairs", r.schema)
+}
+
+const repairColumns = `id, department_id, month, leave_id, staff_id, to_char(start_date,'YYYY-MM-DD'), to_char(end_date,'YYYY-MM-DD'),
+        status, changes, unfilled, violations, moved, roster_version, created_by, created_at, decided_by, decided_at`
+
+func scanRepair(row interface{ Scan(...any) error }) (*ScheduleRepair, error) {
+	var rp ScheduleRepair
+	var changes, unfilled, violations []byte
+	if err := row.Scan(&rp.ID, &rp.DepartmentID, &rp.Month, &rp.LeaveID, &rp.StaffID, &rp.StartDate, &rp.EndDate,
+		&rp.Status, &changes, &unfilled, &violations, &rp.Moved, &rp.RosterVersion, &rp.CreatedBy, &rp.CreatedAt, &rp.DecidedBy, &rp.DecidedAt); err != nil {
+		return nil, err
+	}
+	rp.Changes, rp.Unfilled, rp.Violations = changes, unfilled, violations
+	return &rp, nil
+}
+
+// InsertRepair stores a pending repair proposal
+func (r *ScheduleRepository) InsertRepair(ctx context.Context, rp *ScheduleRepair) error {
+	if rp.ID == "" {
+		rp.ID = uuid.New().String()
+	}
+	rp.Status = RepairPending
+	if rp.Violations == nil {
+		rp.Violations = json.RawMessage("[]")
+	}
+	q := fmt.Sprintf(`INSERT INTO %s (id, department_id, month, leave_id, staff_id, start_date, end_date, status, changes, unfilled, violations, moved, roster_version, created_by, created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,NOW()) RETURNING created_at`, r.repairsTable())
+	return r.conn.DB.QueryRowContext(ctx, q, rp.ID, rp.DepartmentID, rp.Month, rp.LeaveID, rp.StaffID, rp.StartDate, rp.EndDate,
+		rp.Status, []byte(rp.Changes), []byte(rp.Unfilled), []byte(rp.Violations), rp.Moved, rp.RosterVersion, rp.CreatedBy).Scan(&rp.CreatedAt)
+}
+
+// rosterVersionQuery hashes every assignment of the published roster of a department+month (see
+// publishedRosterSelect), so any add, removal, move or newly published version changes it
+func (r *ScheduleRepository) rosterVersionQuery() string {
+	return `SELECT md5(COALESCE(string_agg(staff_id::text || '|' || shift_id::text || '|' || schedule_date::text, ',' ORDER BY schedule_date, shift_id, staff_id), ''))
+        FROM (` + r.publishedRosterSelect() + `) roster`
+}
+
+// RosterVersion returns the current fingerprint of a department+month roster; read it before the
+// assignments a repair is computed from
+func (r *ScheduleRepository) RosterVersion(ctx context.Context, departmentID, month string) (string, error) {
+	var v string
+	err := r.conn.DB.QueryRowContext(ctx, r.rosterVersionQuery(), departmentID, month).Scan(&v)
+	return v, err
+}
+
+// ListRepairs returns repair proposals of a department, optionally filtered by status, newest first
+func (r *ScheduleRepository) ListRepairs(ctx context.Context, departmentID, status string) ([]ScheduleRepair, error) {
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE department_id = $1", repairColumns, r.repairsTable())
+	args := []any{departmentID}
+	if status != "" {
+		q += " AND status = $2"
+		args = append(args, status)
+	}
+	q += " ORDER BY created_at DESC LIMIT 100"
+	rows, err := r.conn.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ScheduleRepair
+	for rows.Next() {
+		rp, err := scanRepair(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rp)
+	}
+	return out, rows.Err()
+}
+
+// GetRepair returns one repair proposal
+func (r *ScheduleRepository) GetRepair(ctx context.Context, id string) (*ScheduleRepair, error) {
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", repairColumns, r.repairsTable())
+	return scanRepair(r.conn.DB.QueryRowContext(ctx, q, id))
+}
+
+// ApplyRepair applies a pending repair to the month's published roster (see editPublishedRoster) in
+// one transaction. That roster must still be the one the repair was computed from, so its adds
+// cannot break rules against assignments made since; otherwise ErrRepairStale.
+func (r *ScheduleRepository) ApplyRepair(ctx context.Context, id, decidedBy string) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var departmentID, month, status string
+	var proposed sql.NullString
+	var changes []byte
+	qLock := fmt.Sprintf("SELECT department_id, month, status, changes, roster_version FROM %s WHERE id = $1 FOR UPDATE", r.repairsTable())
+	if err := tx.QueryRowContext(ctx, qLock, id).Scan(&departmentID, &month, &status, &changes, &proposed); err != nil {
+		return err
+	}
+	if status != RepairPending {
+		return ErrRepairClosed
+	}
+	// same lock as version saves, then compare the roster under it
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", departmentID+"|"+month); err != nil {
+		return err
+	}
+	var current string
+	if err := tx.QueryRowContext(ctx, r.rosterVersionQuery(), departmentID, month).Scan(&current); err != nil {
+		return err
+	}
+	if !proposed.Valid || proposed.String != current {
+		return ErrRepairStale
+	}
+	var edits []RepairEdit
+	if err := json.Unmarshal(changes, &edits); err != nil {
+		return err
+	}
+	if err := r.editPublishedRoster(ctx, tx, departmentID, month, "repair", decidedBy, edits); err != nil {
+		if err == ErrRosterChanged {
+			return ErrRepairStale
+		}
+		return err
+	}
+	return r.decideRepair(ctx, tx, id, RepairApplied, decidedBy)
+}
+
+// DiscardRepair marks a pending repair as discarded
+func (r *ScheduleRepository) DiscardRepair(ctx context.Context, id, decidedBy string) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var status string
+	qLock := fmt.Sprintf("SELECT status FROM %s WHERE id = $1 FOR UPDATE", r.repairsTable())
+	if err := tx.QueryRowContext(ctx, qLock, id).Scan(&status); err != nil {
+		return err
+	}
+	if status != RepairPending {
+		return ErrRepairClosed
+	}
+	return r.decideRepair(ctx, tx, id, RepairDiscarded, decidedBy)
+}
+
+func (r *ScheduleRepository) decideRepair(ctx context.Context, tx *sql.Tx, id, status, decidedBy string) error {
+	q := fmt.Sprintf("UPDATE %s SET status = $1, decided_by = $2, decided_at = NOW() WHERE id = $3", r.repairsTable())
+	if _, err := tx.ExecContext(ctx, q, status, sql.NullString{String: decidedBy, Valid: decidedBy != ""}, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
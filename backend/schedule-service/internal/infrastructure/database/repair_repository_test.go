@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+)
+
+// proposeRepair stores a pending repair that moves a's morning of the 2nd to c, fingerprinted
+// against the roster as it is now
+func proposeRepair(t *testing.T, r *ScheduleRepository, w ward) *ScheduleRepair {
+	t.Helper()
+	ctx := context.Background()
+	version, err := r.RosterVersion(ctx, w.id, "2026-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, _ := json.Marshal([]RepairEdit{
+		{Action: "remove", StaffID: w.a, ShiftID: w.m, Date: "2026-02-02"},
+		{Action: "add", StaffID: w.c, ShiftID: w.m, Date: "2026-02-02"},
+	})
+	rp := &ScheduleRepair{
+		DepartmentID:  w.id,
+		Month:         "2026-02",
+		StaffID:       w.a,
+		StartDate:     "2026-02-02",
+		EndDate:       "2026-02-02",
+		Changes:       changes,
+		Unfilled:      json.RawMessage("[]"),
+		RosterVersion: sql.NullString{String: version, Valid: true},
+	}
+	if err := r.InsertRepair(ctx, rp); err != nil {
+		t.Fatal(err)
+	}
+	return rp
+}
+
+func TestApplyRepairRefusesAChangedRoster(t *testing.T) {
+	r := testRepository(t)
+	w := seedWard(t, r)
+	ctx := context.Background()
+	first := publish(t, r, w, staffRow(w.a, w.m, "2026-02-02"), staffRow(w.b, w.n, "2026-02-05"))
+	stale := proposeRepair(t, r, w)
+
+	// a draft in the working copy does not change what the repair was computed from
+	if err := r.CreateVersion(ctx, &ScheduleVersion{DepartmentID: w.id, Month: "2026-02", Source: "enhanced"}, []VersionAssignment{staffRow(w.b, w.m, "2026-02-09")}, true); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.RosterVersion(ctx, w.id, "2026-02"); err != nil || v != stale.RosterVersion.String {
+		t.Fatalf("RosterVersion after loading a draft = %q (%v), want the published roster's %q", v, err, stale.RosterVersion.String)
+	}
+
+	// the published roster changes: c takes b's night of the 5th
+	id := offer(t, r, w, w.b, w.n, "2026-02-05")
+	if err := r.ProposeSwap(ctx, id, w.c, "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ApproveSwap(ctx, id, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ApplyRepair(ctx, stale.ID, ""); err != ErrRepairStale {
+		t.Fatalf("applying a repair computed from an older roster: %v, want ErrRepairStale", err)
+	}
+	if rp, err := r.GetRepair(ctx, stale.ID); err != nil || rp.Status != RepairPending {
+		t.Errorf("stale repair: %+v, %v, want still pending", rp, err)
+	}
+	_, items := published(t, r, w)
+	if !SameSnapshot(items, []VersionAssignment{staffRow(w.a, w.m, "2026-02-02"), staffRow(w.c, w.n, "2026-02-05")}) {
+		t.Errorf("published roster = %+v, want only the swap applied", items)
+	}
+
+	fresh := proposeRepair(t, r, w)
+	if err := r.ApplyRepair(ctx, fresh.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	v, items := published(t, r, w)
+	if v.ID == first.ID || v.Source != "repair" || !SameSnapshot(items, []VersionAssignment{staffRow(w.c, w.m, "2026-02-02"), staffRow(w.c, w.n, "2026-02-05")}) {
+		t.Errorf("published version %d (%s) holds %+v, want a repair version with a's morning given to c", v.VersionNo, v.Source, items)
+	}
+	if err := r.ApplyRepair(ctx, fresh.ID, ""); err != ErrRepairClosed {
+		t.Errorf("applying a repair twice: %v, want ErrRepairClosed", err)
+	}
+}
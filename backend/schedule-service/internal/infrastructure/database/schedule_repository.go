@@ -9,6 +9,9 @@ import (
 	"net/url"
 	"os"
 	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/eventbus"
+	"nurseshift/schedule-service/internal/infrastructure/holiday"
 )
 
 type ScheduleRecord struct {
@@ -33,6 +36,7 @@ type ScheduleWithRole struct {
 type ScheduleRepository struct {
 	conn   *Connection
 	schema string
+	outbox *eventbus.Outbox
 }
 
 func NewScheduleRepository(conn *Connection) *ScheduleRepository {
@@ -43,6 +47,12 @@ func NewScheduleRepository(conn *Connection) *ScheduleRepository {
 	return &ScheduleRepository{conn: conn, schema: schema}
 }
 
+// SetOutbox makes the repository enqueue domain events, such as a published schedule, in the
+// transaction that makes the change
+func (r *ScheduleRepository) SetOutbox(o *eventbus.Outbox) {
+	r.outbox = o
+}
+
 func (r *ScheduleRepository) table() string {
 	return fmt.Sprintf("%s.schedules", r.schema)
 }
@@ -252,34 +262,58 @@ type Holiday struct {
 	End   string
 }
 
-// ListHolidaysForMonth returns holidays overlapping the given YYYY-MM month
+// ListHolidaysForMonth returns the holidays overlapping the given YYYY-MM month: the department's own
+// and those it inherits from subscribed holiday calendars and has not opted out of, with recurring
+// holidays dated in the month
 func (r *ScheduleRepository) ListHolidaysForMonth(ctx context.Context, departmentID string, month string) ([]Holiday, error) {
-	// select where range overlaps month
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, err
+	}
+	last := first.AddDate(0, 1, -1)
+
 	q := fmt.Sprintf(`
-        SELECT to_char(start_date,'YYYY-MM-DD'), to_char(end_date,'YYYY-MM-DD')
-        FROM %s.holidays 
+        SELECT start_date, end_date, COALESCE(is_recurring, false), recurrence_pattern
+        FROM %s.holidays
         WHERE department_id = $1
-          AND (
-            to_char(start_date,'YYYY-MM') = $2 
-            OR to_char(end_date,'YYYY-MM') = $2
-            OR (
-              start_date <= to_date($2 || '-01','YYYY-MM-DD') 
-              AND end_date >= to_date($2 || '-28','YYYY-MM-DD')
-            )
+          AND (COALESCE(is_recurring, false) OR (start_date <= $3 AND end_date >= $2))
+    `, r.schema)
+	var inherited bool
+	check := fmt.Sprintf(`SELECT to_regclass('%s.department_holiday_calendars') IS NOT NULL`, r.schema)
+	if err := r.conn.DB.QueryRowContext(ctx, check).Scan(&inherited); err != nil {
+		return nil, err
+	}
+	if inherited {
+		q += fmt.Sprintf(`
+        UNION ALL
+        SELECT ch.start_date, ch.end_date, ch.is_recurring, ch.recurrence_pattern
+        FROM %[1]s.calendar_holidays ch
+        JOIN %[1]s.department_holiday_calendars dc ON dc.calendar_id = ch.calendar_id
+        WHERE dc.department_id = $1
+          AND (ch.is_recurring OR (ch.start_date <= $3 AND ch.end_date >= $2))
+          AND NOT EXISTS (
+            SELECT 1 FROM %[1]s.department_holiday_opt_outs o
+            WHERE o.department_id = $1 AND o.calendar_holiday_id = ch.id
           )
     `, r.schema)
-	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID, month)
+	}
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID, first, last)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var out []Holiday
 	for rows.Next() {
-		var h Holiday
-		if err := rows.Scan(&h.Start, &h.End); err != nil {
+		var rule holiday.Rule
+		var pattern []byte
+		if err := rows.Scan(&rule.Start, &rule.End, &rule.Recurring, &pattern); err != nil {
 			return nil, err
 		}
-		out = append(out, h)
+		// a pattern that no longer parses falls back to the holiday's own date
+		rule.Pattern, _ = holiday.ParsePattern(pattern)
+		for _, o := range rule.Occurrences(first, last) {
+			out = append(out, Holiday{Start: o.Start.Format("2006-01-02"), End: o.End.Format("2006-01-02")})
+		}
 	}
 	return out, rows.Err()
 }
@@ -339,6 +373,38 @@ func (r *ScheduleRepository) GetPriorityValue(ctx context.Context, departmentID
 	return out, err
 }
 
+// SchedulingPriority is one scheduling_priorities row as managed by priority-service
+type SchedulingPriority struct {
+	Name     string
+	Order    int
+	IsActive bool
+	Config   sql.NullString
+}
+
+// ListSchedulingPriorities returns all priorities of a department ordered by priority_order
+func (r *ScheduleRepository) ListSchedulingPriorities(ctx context.Context, departmentID string) ([]SchedulingPriority, error) {
+	q := fmt.Sprintf(`
+        SELECT name, priority_order, is_active, config::text
+        FROM %s.scheduling_priorities
+        WHERE department_id = $1
+        ORDER BY priority_order ASC
+    `, r.schema)
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SchedulingPriority
+	for rows.Next() {
+		var p SchedulingPriority
+		if err := rows.Scan(&p.Name, &p.Order, &p.IsActive, &p.Config); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
 type Assignment struct {
 	ID           string
 	DepartmentID string
@@ -0,0 +1,634 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/eventbus"
+
+	"github.com/google/uuid"
+)
+
+// Schedule version states (draft → under_review → published → archived)
+const (
+	VersionDraft       = "draft"
+	VersionUnderReview = "under_review"
+	VersionPublished   = "published"
+	VersionArchived    = "archived"
+)
+
+// ErrVersionTransition is returned when a status change is not allowed from the current state
+var ErrVersionTransition = errors.New("invalid schedule version transition")
+
+// ErrRosterChanged is returned when a swap or repair removes an assignment the published roster no longer holds
+var ErrRosterChanged = errors.New("assignment is not in the published roster")
+
+// versionTransitions lists allowed manual status changes; publishing goes through PublishVersion
+var versionTransitions = map[string][]string{
+	VersionDraft:       {VersionUnderReview, VersionArchived},
+	VersionUnderReview: {VersionDraft, VersionArchived},
+	VersionPublished:   {VersionArchived},
+}
+
+// CanTransitionVersion reports whether a version may move from one status to another
+func CanTransitionVersion(from, to string) bool {
+	if to == VersionPublished {
+		return from == VersionDraft || from == VersionUnderReview
+	}
+	for _, s := range versionTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduleVersion is one saved roster of a department+month
+type ScheduleVersion struct {
+	ID              string
+	DepartmentID    string
+	Month           string
+	VersionNo       int
+	Status          string
+	Source          string // generator name, "manual", "restore"
+	GenerationRunID sql.NullString
+	RestoredFrom    sql.NullString
+	Notes           sql.NullString
+	AssignmentCount int
+	CreatedBy       sql.NullString
+	CreatedAt       time.Time
+	PublishedBy     sql.NullString
+	PublishedAt     sql.NullTime
+}
+
+// VersionAssignment is one assignment inside a version snapshot
+type VersionAssignment struct {
+	DepartmentID string // only set by ListPublishedForUser
+	StaffID      sql.NullString
+	UserID       sql.NullString
+	ShiftID      string
+	ScheduleDate string
+	Status       string
+	Notes        sql.NullString
+	StaffName    string
+	StaffRole    string
+}
+
+// Key identifies the assignment independent of the row id (who, when, which shift)
+func (a VersionAssignment) Key() string {
+	who := a.StaffID.String
+	if !a.StaffID.Valid {
+		who = "u:" + a.UserID.String
+	}
+	return a.ScheduleDate + "|" + a.ShiftID + "|" + who
+}
+
+func (r *ScheduleRepository) versionsTable() string {
+	return fmt.Sprintf("%s.schedule_versions", r.schema)
+}
+
+func (r *ScheduleRepository) versionAssignmentsTable() string {
+	return fmt.Sprintf("%s.schedule_version_assignments", r.schema)
+}
+
+const versionColumns = "v.id, v.department_id, v.month, v.version_no, v.status, v.source, v.generation_run_id, v.restored_from, v.notes, v.created_by, v.created_at, v.published_by, v.published_at"
+
+func scanVersion(row interface{ Scan(...any) error }, v *ScheduleVersion, extra ...any) error {
+	dest := []any{&v.ID, &v.DepartmentID, &v.Month, &v.VersionNo, &v.Status, &v.Source, &v.GenerationRunID, &v.RestoredFrom, &v.Notes, &v.CreatedBy, &v.CreatedAt, &v.PublishedBy, &v.PublishedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// ListVersions returns versions of a department+month, newest first, with assignment counts
+func (r *ScheduleRepository) ListVersions(ctx context.Context, departmentID, month string) ([]ScheduleVersion, error) {
+	q := fmt.Sprintf(`SELECT %s, (SELECT COUNT(*) FROM %s a WHERE a.version_id = v.id)
+        FROM %s v WHERE v.department_id = $1 AND v.month = $2 ORDER BY v.version_no DESC`, versionColumns, r.versionAssignmentsTable(), r.versionsTable())
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ScheduleVersion
+	for rows.Next() {
+		var v ScheduleVersion
+		if err := scanVersion(rows, &v, &v.AssignmentCount); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// GetVersion returns one version (sql.ErrNoRows if missing)
+func (r *ScheduleRepository) GetVersion(ctx context.Context, id string) (*ScheduleVersion, error) {
+	q := fmt.Sprintf(`SELECT %s, (SELECT COUNT(*) FROM %s a WHERE a.version_id = v.id) FROM %s v WHERE v.id = $1`, versionColumns, r.versionAssignmentsTable(), r.versionsTable())
+	v := &ScheduleVersion{}
+	if err := scanVersion(r.conn.DB.QueryRowContext(ctx, q, id), v, &v.AssignmentCount); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetPublishedVersion returns the published version of a department+month (sql.ErrNoRows if none)
+func (r *ScheduleRepository) GetPublishedVersion(ctx context.Context, departmentID, month string) (*ScheduleVersion, error) {
+	q := fmt.Sprintf(`SELECT %s, 0 FROM %s v WHERE v.department_id = $1 AND v.month = $2 AND v.status = 'published'`, versionColumns, r.versionsTable())
+	v := &ScheduleVersion{}
+	if err := scanVersion(r.conn.DB.QueryRowContext(ctx, q, departmentID, month), v, &v.AssignmentCount); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// HasVersions reports whether a department+month has any saved version (months without are legacy data)
+func (r *ScheduleRepository) HasVersions(ctx context.Context, departmentID, month string) (bool, error) {
+	var ok bool
+	q := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE department_id = $1 AND month = $2)", r.versionsTable())
+	err := r.conn.DB.QueryRowContext(ctx, q, departmentID, month).Scan(&ok)
+	return ok, err
+}
+
+// ListVersionAssignments returns a version's snapshot with staff names
+func (r *ScheduleRepository) ListVersionAssignments(ctx context.Context, versionID string) ([]VersionAssignment, error) {
+	q := fmt.Sprintf(`
+        SELECT a.staff_id, a.user_id, a.shift_id, to_char(a.schedule_date,'YYYY-MM-DD'), COALESCE(a.status,'assigned'), a.notes,
+               COALESCE(ds.name, TRIM(COALESCE(u.first_name,'') || ' ' || COALESCE(u.last_name,'')), ''), COALESCE(ds.position, '')
+        FROM %s a
+        LEFT JOIN %s.department_staff ds ON ds.id = a.staff_id
+        LEFT JOIN %s.users u ON u.id = a.user_id
+        WHERE a.version_id = $1
+        ORDER BY a.schedule_date ASC`, r.versionAssignmentsTable(), r.schema, r.schema)
+	rows, err := r.conn.DB.QueryContext(ctx, q, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VersionAssignment
+	for rows.Next() {
+		var a VersionAssignment
+		if err := rows.Scan(&a.StaffID, &a.UserID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes, &a.StaffName, &a.StaffRole); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ListWorkingCopy returns the live schedules of a department+month in snapshot form
+func (r *ScheduleRepository) ListWorkingCopy(ctx context.Context, departmentID, month string) ([]VersionAssignment, error) {
+	q := fmt.Sprintf(`
+        SELECT s.staff_id, s.user_id, s.shift_id, to_char(s.schedule_date,'YYYY-MM-DD'), COALESCE(s.status,'assigned'), s.notes,
+               COALESCE(ds.name, TRIM(COALESCE(u.first_name,'') || ' ' || COALESCE(u.last_name,'')), ''), COALESCE(ds.position, '')
+        FROM %s s
+        LEFT JOIN %s.department_staff ds ON ds.id = s.staff_id
+        LEFT JOIN %s.users u ON u.id = s.user_id
+        WHERE s.department_id = $1 AND to_char(s.schedule_date,'YYYY-MM') = $2
+        ORDER BY s.schedule_date ASC`, r.table(), r.schema, r.schema)
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VersionAssignment
+	for rows.Next() {
+		var a VersionAssignment
+		if err := rows.Scan(&a.StaffID, &a.UserID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes, &a.StaffName, &a.StaffRole); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ListPublishedForUser returns a user's own assignments across departments as staff see them: the
+// published version of months that have versions, the live schedules of months that predate them
+func (r *ScheduleRepository) ListPublishedForUser(ctx context.Context, userID string) ([]VersionAssignment, error) {
+	q := fmt.Sprintf(`
+        SELECT s.department_id, s.staff_id, s.user_id, s.shift_id, to_char(s.schedule_date,'YYYY-MM-DD'), COALESCE(s.status,'assigned'), s.notes,
+               TRIM(COALESCE(u.first_name,'') || ' ' || COALESCE(u.last_name,'')), COALESCE(du.department_role, '')
+        FROM %[1]s s
+        JOIN %[3]s.users u ON u.id = s.user_id
+        LEFT JOIN %[3]s.department_users du ON du.department_id = s.department_id AND du.user_id = s.user_id
+        WHERE s.user_id = $1
+          AND NOT EXISTS (SELECT 1 FROM %[2]s v WHERE v.department_id = s.department_id AND v.month = to_char(s.schedule_date,'YYYY-MM'))
+        UNION ALL
+        SELECT v.department_id, a.staff_id, a.user_id, a.shift_id, to_char(a.schedule_date,'YYYY-MM-DD'), COALESCE(a.status,'assigned'), a.notes,
+               TRIM(COALESCE(u.first_name,'') || ' ' || COALESCE(u.last_name,'')), COALESCE(du.department_role, '')
+        FROM %[4]s a
+        JOIN %[2]s v ON v.id = a.version_id AND v.status = 'published'
+        JOIN %[3]s.users u ON u.id = a.user_id
+        LEFT JOIN %[3]s.department_users du ON du.department_id = v.department_id AND du.user_id = a.user_id
+        WHERE a.user_id = $1
+        ORDER BY 5 ASC`, r.table(), r.versionsTable(), r.schema, r.versionAssignmentsTable())
+	rows, err := r.conn.DB.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VersionAssignment
+	for rows.Next() {
+		var a VersionAssignment
+		if err := rows.Scan(&a.DepartmentID, &a.StaffID, &a.UserID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes, &a.StaffName, &a.StaffRole); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// AssignmentsToSnapshot converts generated assignments into snapshot rows
+func AssignmentsToSnapshot(items []Assignment) []VersionAssignment {
+	out := make([]VersionAssignment, 0, len(items))
+	for _, a := range items {
+		out = append(out, VersionAssignment{
+			StaffID:      sql.NullString{String: a.StaffID, Valid: a.StaffID != ""},
+			UserID:       sql.NullString{String: a.UserID, Valid: a.UserID != ""},
+			ShiftID:      a.ShiftID,
+			ScheduleDate: a.ScheduleDate,
+			Status:       a.Status,
+			Notes:        a.Notes,
+		})
+	}
+	return out
+}
+
+// CreateVersion saves a snapshot as a new version (next version_no for the department+month).
+// When loadWorkingCopy is set the live schedules of that month are replaced by the snapshot in the same transaction.
+func (r *ScheduleRepository) CreateVersion(ctx context.Context, v *ScheduleVersion, items []VersionAssignment, loadWorkingCopy bool) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// serialize version numbering per department+month
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", v.DepartmentID+"|"+v.Month); err != nil {
+		return err
+	}
+	qNo := fmt.Sprintf("SELECT COALESCE(MAX(version_no),0)+1 FROM %s WHERE department_id = $1 AND month = $2", r.versionsTable())
+	if err := tx.QueryRowContext(ctx, qNo, v.DepartmentID, v.Month).Scan(&v.VersionNo); err != nil {
+		return err
+	}
+	if v.ID == "" {
+		v.ID = uuid.New().String()
+	}
+	if v.Status == "" {
+		v.Status = VersionDraft
+	}
+	qIns := fmt.Sprintf(`INSERT INTO %s (id, department_id, month, version_no, status, source, generation_run_id, restored_from, notes, created_by, created_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,NOW()) RETURNING created_at`, r.versionsTable())
+	if err := tx.QueryRowContext(ctx, qIns, v.ID, v.DepartmentID, v.Month, v.VersionNo, v.Status, v.Source, v.GenerationRunID, v.RestoredFrom, v.Notes, v.CreatedBy).Scan(&v.CreatedAt); err != nil {
+		return err
+	}
+	if err := r.insertSnapshot(ctx, tx, v.ID, items); err != nil {
+		return err
+	}
+	if loadWorkingCopy {
+		if err := r.replaceWorkingCopy(ctx, tx, v.DepartmentID, v.Month, items); err != nil {
+			return err
+		}
+	}
+	v.AssignmentCount = len(items)
+	return tx.Commit()
+}
+
+func (r *ScheduleRepository) insertSnapshot(ctx context.Context, tx *sql.Tx, versionID string, items []VersionAssignment) error {
+	const batch = 500
+	for startIdx := 0; startIdx < len(items); startIdx += batch {
+		end := startIdx + batch
+		if end > len(items) {
+			end = len(items)
+		}
+		q := fmt.Sprintf("INSERT INTO %s (version_id, staff_id, user_id, shift_id, schedule_date, status, notes) VALUES ", r.versionAssignmentsTable())
+		args := []any{}
+		for i, a := range items[startIdx:end] {
+			if i > 0 {
+				q += ","
+			}
+			base := i*7 + 1
+			q += fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,COALESCE($%d,'assigned'),$%d)", base, base+1, base+2, base+3, base+4, base+5, base+6)
+			status := sql.NullString{String: a.Status, Valid: a.Status != ""}
+			args = append(args, versionID, a.StaffID, a.UserID, a.ShiftID, a.ScheduleDate, status, a.Notes)
+		}
+		if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceWorkingCopy swaps the live schedules of a month for a snapshot
+func (r *ScheduleRepository) replaceWorkingCopy(ctx context.Context, tx *sql.Tx, departmentID, month string, items []VersionAssignment) error {
+	qDel := fmt.Sprintf("DELETE FROM %s WHERE department_id=$1 AND to_char(schedule_date,'YYYY-MM')=$2", r.table())
+	if _, err := tx.ExecContext(ctx, qDel, departmentID, month); err != nil {
+		return err
+	}
+	const batch = 500
+	for startIdx := 0; startIdx < len(items); startIdx += batch {
+		end := startIdx + batch
+		if end > len(items) {
+			end = len(items)
+		}
+		q := fmt.Sprintf("INSERT INTO %s (id, department_id, staff_id, user_id, shift_id, schedule_date, status, notes, created_at, updated_at) VALUES ", r.table())
+		args := []any{}
+		for i, a := range items[startIdx:end] {
+			if i > 0 {
+				q += ","
+			}
+			base := i*8 + 1
+			q += fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,COALESCE($%d,'assigned'),$%d,NOW(),NOW())", base, base+1, base+2, base+3, base+4, base+5, base+6, base+7)
+			status := sql.NullString{String: a.Status, Valid: a.Status != ""}
+			args = append(args, uuid.New().String(), departmentID, a.StaffID, a.UserID, a.ShiftID, a.ScheduleDate, status, a.Notes)
+		}
+		q += " ON CONFLICT DO NOTHING"
+		if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateVersionStatus moves a version along the lifecycle (not to published; see PublishVersion)
+func (r *ScheduleRepository) UpdateVersionStatus(ctx context.Context, id, status string) error {
+	v, err := r.GetVersion(ctx, id)
+	if err != nil {
+		return err
+	}
+	if status == VersionPublished || !CanTransitionVersion(v.Status, status) {
+		return ErrVersionTransition
+	}
+	q := fmt.Sprintf("UPDATE %s SET status = $1 WHERE id = $2 AND status = $3", r.versionsTable())
+	res, err := r.conn.DB.ExecContext(ctx, q, status, id, v.Status)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrVersionTransition
+	}
+	return nil
+}
+
+// PublishVersion makes a draft/under-review version the published one: the previous published version is
+// archived and the live schedules of the month are replaced by the snapshot, all in one transaction
+func (r *ScheduleRepository) PublishVersion(ctx context.Context, id, publishedBy string) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var departmentID, month, status string
+	var versionNo int
+	qGet := fmt.Sprintf("SELECT department_id, month, status, version_no FROM %s WHERE id = $1 FOR UPDATE", r.versionsTable())
+	if err := tx.QueryRowContext(ctx, qGet, id).Scan(&departmentID, &month, &status, &versionNo); err != nil {
+		return err
+	}
+	if !CanTransitionVersion(status, VersionPublished) {
+		return ErrVersionTransition
+	}
+	qArchive := fmt.Sprintf("UPDATE %s SET status = 'archived' WHERE department_id = $1 AND month = $2 AND status = 'published'", r.versionsTable())
+	if _, err := tx.ExecContext(ctx, qArchive, departmentID, month); err != nil {
+		return err
+	}
+	qPub := fmt.Sprintf("UPDATE %s SET status = 'published', published_by = $2, published_at = NOW() WHERE id = $1", r.versionsTable())
+	if _, err := tx.ExecContext(ctx, qPub, id, sql.NullString{String: publishedBy, Valid: publishedBy != ""}); err != nil {
+		return err
+	}
+
+	// load snapshot inside the transaction
+	items, err := r.snapshotTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := r.replaceWorkingCopy(ctx, tx, departmentID, month, items); err != nil {
+		return err
+	}
+	if err := r.enqueuePublished(ctx, tx, id, versionNo, departmentID, month, publishedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// snapshotTx reads a version's snapshot inside tx
+func (r *ScheduleRepository) snapshotTx(ctx context.Context, tx *sql.Tx, versionID string) ([]VersionAssignment, error) {
+	qItems := fmt.Sprintf("SELECT staff_id, user_id, shift_id, to_char(schedule_date,'YYYY-MM-DD'), COALESCE(status,'assigned'), notes FROM %s WHERE version_id = $1", r.versionAssignmentsTable())
+	rows, err := tx.QueryContext(ctx, qItems, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []VersionAssignment
+	for rows.Next() {
+		var a VersionAssignment
+		if err := rows.Scan(&a.StaffID, &a.UserID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes); err != nil {
+			return nil, err
+		}
+		items = append(items, a)
+	}
+	return items, rows.Err()
+}
+
+// workingCopyTx reads the live schedules of a month inside tx
+func (r *ScheduleRepository) workingCopyTx(ctx context.Context, tx *sql.Tx, departmentID, month string) ([]VersionAssignment, error) {
+	q := fmt.Sprintf("SELECT staff_id, user_id, shift_id, to_char(schedule_date,'YYYY-MM-DD'), COALESCE(status,'assigned'), notes FROM %s WHERE department_id = $1 AND to_char(schedule_date,'YYYY-MM') = $2", r.table())
+	rows, err := tx.QueryContext(ctx, q, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []VersionAssignment
+	for rows.Next() {
+		var a VersionAssignment
+		if err := rows.Scan(&a.StaffID, &a.UserID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes); err != nil {
+			return nil, err
+		}
+		items = append(items, a)
+	}
+	return items, rows.Err()
+}
+
+func (r *ScheduleRepository) enqueuePublished(ctx context.Context, tx *sql.Tx, versionID string, versionNo int, departmentID, month, publishedBy string) error {
+	if r.outbox == nil {
+		return nil
+	}
+	e, err := eventbus.NewEvent(eventbus.SchedulePublished, versionID, departmentID, eventbus.SchedulePublishedPayload{
+		VersionID:    versionID,
+		VersionNo:    versionNo,
+		DepartmentID: departmentID,
+		Month:        month,
+		PublishedBy:  publishedBy,
+	})
+	if err != nil {
+		return err
+	}
+	return r.outbox.Enqueue(ctx, tx, e)
+}
+
+// publishedRosterSelect selects the staff-based roster staff see for department $1 and month $2: the
+// published version's snapshot, or the live schedules when the month predates versioning
+func (r *ScheduleRepository) publishedRosterSelect() string {
+	return fmt.Sprintf(`
+        SELECT s.staff_id, s.shift_id, s.schedule_date, COALESCE(s.status,'assigned') AS status, s.notes
+        FROM %[1]s s
+        WHERE s.department_id = $1 AND to_char(s.schedule_date,'YYYY-MM') = $2 AND s.staff_id IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM %[2]s v WHERE v.department_id = $1 AND v.month = $2)
+        UNION ALL
+        SELECT a.staff_id, a.shift_id, a.schedule_date, COALESCE(a.status,'assigned'), a.notes
+        FROM %[3]s a
+        JOIN %[2]s v ON v.id = a.version_id AND v.status = 'published'
+        WHERE v.department_id = $1 AND v.month = $2 AND a.staff_id IS NOT NULL`, r.table(), r.versionsTable(), r.versionAssignmentsTable())
+}
+
+// ListPublishedStaffAssignments returns the staff-based roster of a department+month as staff see it.
+// Swaps and repairs are validated against and applied to this roster, not to a draft in the working copy.
+func (r *ScheduleRepository) ListPublishedStaffAssignments(ctx context.Context, departmentID, month string) ([]Assignment, error) {
+	q := "SELECT staff_id, shift_id, to_char(schedule_date,'YYYY-MM-DD'), status, notes FROM (" + r.publishedRosterSelect() + ") roster ORDER BY schedule_date"
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Assignment
+	for rows.Next() {
+		a := Assignment{DepartmentID: departmentID}
+		if err := rows.Scan(&a.StaffID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// editPublishedRoster applies edits to the roster staff see for a month. With a published version the
+// edited snapshot becomes a new published version (source names the change) and the old one is archived;
+// the working copy follows when it mirrored the old version, while a draft loaded there is left alone.
+// Months that predate versioning are edited in the live schedules. A removal the roster does not hold
+// returns ErrRosterChanged. The caller holds the department+month advisory lock.
+func (r *ScheduleRepository) editPublishedRoster(ctx context.Context, tx *sql.Tx, departmentID, month, source, actorID string, edits []RepairEdit) error {
+	var versioned bool
+	qHas := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE department_id = $1 AND month = $2)", r.versionsTable())
+	if err := tx.QueryRowContext(ctx, qHas, departmentID, month).Scan(&versioned); err != nil {
+		return err
+	}
+	if !versioned {
+		return r.editWorkingCopy(ctx, tx, departmentID, edits)
+	}
+
+	var publishedID string
+	var publishedNo int
+	qPub := fmt.Sprintf("SELECT id, version_no FROM %s WHERE department_id = $1 AND month = $2 AND status = 'published' FOR UPDATE", r.versionsTable())
+	if err := tx.QueryRowContext(ctx, qPub, departmentID, month).Scan(&publishedID, &publishedNo); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRosterChanged
+		}
+		return err
+	}
+	before, err := r.snapshotTx(ctx, tx, publishedID)
+	if err != nil {
+		return err
+	}
+	after, err := applyRosterEdits(before, edits)
+	if err != nil {
+		return err
+	}
+	live, err := r.workingCopyTx(ctx, tx, departmentID, month)
+	if err != nil {
+		return err
+	}
+
+	v := &ScheduleVersion{
+		ID:           uuid.New().String(),
+		DepartmentID: departmentID,
+		Month:        month,
+		Source:       source,
+		Notes:        sql.NullString{String: fmt.Sprintf("แก้ไขจากเวอร์ชัน %d ที่เผยแพร่", publishedNo), Valid: true},
+		CreatedBy:    sql.NullString{String: actorID, Valid: actorID != ""},
+	}
+	qNo := fmt.Sprintf("SELECT COALESCE(MAX(version_no),0)+1 FROM %s WHERE department_id = $1 AND month = $2", r.versionsTable())
+	if err := tx.QueryRowContext(ctx, qNo, departmentID, month).Scan(&v.VersionNo); err != nil {
+		return err
+	}
+	qArchive := fmt.Sprintf("UPDATE %s SET status = 'archived' WHERE id = $1", r.versionsTable())
+	if _, err := tx.ExecContext(ctx, qArchive, publishedID); err != nil {
+		return err
+	}
+	qIns := fmt.Sprintf(`INSERT INTO %s (id, department_id, month, version_no, status, source, notes, created_by, created_at, published_by, published_at)
+        VALUES ($1,$2,$3,$4,'published',$5,$6,$7,NOW(),$7,NOW())`, r.versionsTable())
+	if _, err := tx.ExecContext(ctx, qIns, v.ID, departmentID, month, v.VersionNo, source, v.Notes, v.CreatedBy); err != nil {
+		return err
+	}
+	if err := r.insertSnapshot(ctx, tx, v.ID, after); err != nil {
+		return err
+	}
+	if SameSnapshot(live, before) {
+		if err := r.replaceWorkingCopy(ctx, tx, departmentID, month, after); err != nil {
+			return err
+		}
+	}
+	return r.enqueuePublished(ctx, tx, v.ID, v.VersionNo, departmentID, month, actorID)
+}
+
+// editWorkingCopy applies edits to the live schedules rows
+func (r *ScheduleRepository) editWorkingCopy(ctx context.Context, tx *sql.Tx, departmentID string, edits []RepairEdit) error {
+	qDel := fmt.Sprintf("DELETE FROM %s WHERE department_id = $1 AND staff_id = $2 AND shift_id = $3 AND schedule_date = $4", r.table())
+	qIns := fmt.Sprintf(`INSERT INTO %s (id, department_id, staff_id, shift_id, schedule_date, status, created_at, updated_at)
+        VALUES ($1,$2,$3,$4,$5,'assigned',NOW(),NOW())`, r.table())
+	for _, e := range edits {
+		switch e.Action {
+		case "remove":
+			res, err := tx.ExecContext(ctx, qDel, departmentID, e.StaffID, e.ShiftID, e.Date)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				return ErrRosterChanged
+			}
+		case "add":
+			if _, err := tx.ExecContext(ctx, qIns, uuid.New().String(), departmentID, e.StaffID, e.ShiftID, e.Date); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyRosterEdits returns the snapshot with edits applied in order
+func applyRosterEdits(items []VersionAssignment, edits []RepairEdit) ([]VersionAssignment, error) {
+	out := append([]VersionAssignment(nil), items...)
+	for _, e := range edits {
+		a := VersionAssignment{StaffID: sql.NullString{String: e.StaffID, Valid: true}, ShiftID: e.ShiftID, ScheduleDate: e.Date, Status: "assigned"}
+		switch e.Action {
+		case "remove":
+			i := 0
+			for i < len(out) && out[i].Key() != a.Key() {
+				i++
+			}
+			if i == len(out) {
+				return nil, ErrRosterChanged
+			}
+			out = append(out[:i], out[i+1:]...)
+		case "add":
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// SameSnapshot reports whether two snapshots hold the same assignments, ignoring order
+func SameSnapshot(a, b []VersionAssignment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keys := map[string]int{}
+	for _, x := range a {
+		keys[x.Key()]++
+	}
+	for _, x := range b {
+		keys[x.Key()]--
+		if keys[x.Key()] < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestCanTransitionVersion(t *testing.T) {
+	states := []string{VersionDraft, VersionUnderReview, VersionPublished, VersionArchived}
+	allowed := map[string]map[string]bool{
+		VersionDraft:       {VersionUnderReview: true, VersionPublished: true, VersionArchived: true},
+		VersionUnderReview: {VersionDraft: true, VersionPublished: true, VersionArchived: true},
+		VersionPublished:   {VersionArchived: true},
+		VersionArchived:    {},
+	}
+	for _, from := range states {
+		for _, to := range states {
+			if got := CanTransitionVersion(from, to); got != allowed[from][to] {
+				t.Errorf("%s -> %s: %v, want %v", from, to, got, allowed[from][to])
+			}
+		}
+	}
+	if CanTransitionVersion("", VersionPublished) || CanTransitionVersion(VersionDraft, "deleted") {
+		t.Error("unknown states allowed")
+	}
+}
+
+func TestVersionAssignmentKey(t *testing.T) {
+	staff := VersionAssignment{StaffID: sql.NullString{String: "s1", Valid: true}, ShiftID: "m", ScheduleDate: "2026-02-01", Status: "assigned"}
+	moved := staff
+	moved.Status, moved.StaffName = "confirmed", "renamed"
+	if staff.Key() != moved.Key() {
+		t.Errorf("status and name changed the key: %q vs %q", staff.Key(), moved.Key())
+	}
+	user := VersionAssignment{UserID: sql.NullString{String: "s1", Valid: true}, ShiftID: "m", ScheduleDate: "2026-02-01"}
+	if staff.Key() == user.Key() {
+		t.Errorf("a staff row and a user with the same id share key %q", staff.Key())
+	}
+	other := staff
+	other.ShiftID = "n"
+	if staff.Key() == other.Key() {
+		t.Error("different shifts share a key")
+	}
+}
+
+func versionRow(staffID, shiftID, date string) VersionAssignment {
+	return VersionAssignment{StaffID: sql.NullString{String: staffID, Valid: true}, ShiftID: shiftID, ScheduleDate: date, StaffName: staffID}
+}
+
+func TestSameSnapshot(t *testing.T) {
+	a := []VersionAssignment{versionRow("s1", "m", "2026-02-01"), versionRow("s2", "m", "2026-02-01")}
+	cases := []struct {
+		name string
+		b    []VersionAssignment
+		want bool
+	}{
+		{"same rows in another order", []VersionAssignment{a[1], a[0]}, true},
+		{"one row fewer", a[:1], false},
+		{"one person swapped", []VersionAssignment{a[0], versionRow("s3", "m", "2026-02-01")}, false},
+		{"duplicate instead of a second person", []VersionAssignment{a[0], a[0]}, false},
+	}
+	for _, tc := range cases {
+		if got := SameSnapshot(a, tc.b); got != tc.want {
+			t.Errorf("%s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestApplyRosterEdits(t *testing.T) {
+	before := []VersionAssignment{versionRow("s1", "m", "2026-02-01"), versionRow("s2", "n", "2026-02-03")}
+	after, err := applyRosterEdits(before, []RepairEdit{
+		{Action: "remove", StaffID: "s1", ShiftID: "m", Date: "2026-02-01"},
+		{Action: "add", StaffID: "s3", ShiftID: "m", Date: "2026-02-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []VersionAssignment{versionRow("s2", "n", "2026-02-03"), versionRow("s3", "m", "2026-02-01")}
+	if !SameSnapshot(after, want) {
+		t.Errorf("after the edits: %+v, want %+v", after, want)
+	}
+	if !SameSnapshot(before, []VersionAssignment{versionRow("s1", "m", "2026-02-01"), versionRow("s2", "n", "2026-02-03")}) {
+		t.Errorf("the edits changed the original snapshot: %+v", before)
+	}
+	if _, err := applyRosterEdits(before, []RepairEdit{{Action: "remove", StaffID: "s3", ShiftID: "m", Date: "2026-02-01"}}); err != ErrRosterChanged {
+		t.Errorf("removing an assignment the roster does not hold: %v, want ErrRosterChanged", err)
+	}
+}
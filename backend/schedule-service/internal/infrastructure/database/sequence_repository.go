@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ShiftTransition is a shift type followed by another, e.g. night -> morning
+type ShiftTransition struct {
+	From string
+	To   string
+}
+
+// SequenceRules are how a department's shifts may follow each other: at least MinRestHours off
+// between the end of one shift and the start of the next, and none of ForbiddenTransitions with less
+// than a day in between. The zero value allows any sequence.
+type SequenceRules struct {
+	DepartmentID         string
+	MinRestHours         int
+	ForbiddenTransitions []ShiftTransition
+	UpdatedBy            sql.NullString
+}
+
+// GetSequenceRules returns the department's sequence rules; a department without a row allows any sequence
+func (r *ScheduleRepository) GetSequenceRules(ctx context.Context, departmentID string) (SequenceRules, error) {
+	s := SequenceRules{DepartmentID: departmentID}
+	var pairs []string
+	q := fmt.Sprintf("SELECT min_rest_hours, forbidden_transitions, updated_by FROM %s.shift_sequence_rules WHERE department_id = $1", r.schema)
+	err := r.conn.DB.QueryRowContext(ctx, q, departmentID).Scan(&s.MinRestHours, pq.Array(&pairs), &s.UpdatedBy)
+	if err == sql.ErrNoRows {
+		return s, nil
+	}
+	// stored as "from>to"
+	for _, p := range pairs {
+		if from, to, ok := strings.Cut(p, ">"); ok {
+			s.ForbiddenTransitions = append(s.ForbiddenTransitions, ShiftTransition{From: from, To: to})
+		}
+	}
+	return s, err
+}
+
+// SaveSequenceRules creates or replaces the department's sequence rules
+func (r *ScheduleRepository) SaveSequenceRules(ctx context.Context, s SequenceRules) error {
+	pairs := make([]string, 0, len(s.ForbiddenTransitions))
+	for _, t := range s.ForbiddenTransitions {
+		pairs = append(pairs, t.From+">"+t.To)
+	}
+	q := fmt.Sprintf(`
+        INSERT INTO %s.shift_sequence_rules (department_id, min_rest_hours, forbidden_transitions, updated_by, updated_at)
+        VALUES ($1,$2,$3,$4,NOW())
+        ON CONFLICT (department_id) DO UPDATE SET min_rest_hours = EXCLUDED.min_rest_hours, forbidden_transitions = EXCLUDED.forbidden_transitions,
+            updated_by = EXCLUDED.updated_by, updated_at = NOW()`, r.schema)
+	_, err := r.conn.DB.ExecContext(ctx, q, s.DepartmentID, s.MinRestHours, pq.Array(pairs), s.UpdatedBy)
+	return err
+}
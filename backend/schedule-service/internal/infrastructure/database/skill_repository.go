@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Skill is a competency staff can hold, such as ICU or ACLS certification
+type Skill struct {
+	ID           string
+	DepartmentID string
+	Code         string
+	Name         string
+	CreatedAt    time.Time
+}
+
+// StaffSkill is a staff member's certification in a skill; it counts up to and including ExpiresOn
+type StaffSkill struct {
+	StaffID     string
+	SkillID     string
+	CertifiedOn sql.NullString // YYYY-MM-DD
+	ExpiresOn   sql.NullString // YYYY-MM-DD; NULL = does not expire
+}
+
+// ShiftSkillRequirement is how many staff holding a skill every occurrence of a shift needs
+type ShiftSkillRequirement struct {
+	ShiftID  string
+	SkillID  string
+	MinCount int
+}
+
+// Competencies is a department's skill matrix: its skills, who holds them and what its shifts require
+type Competencies struct {
+	Skills       []Skill
+	StaffSkills  []StaffSkill
+	Requirements []ShiftSkillRequirement
+}
+
+// DefaultSkills are created for a department that has none yet
+var DefaultSkills = []Skill{
+	{Code: "icu", Name: "ดูแลผู้ป่วยวิกฤต (ICU)"},
+	{Code: "charge", Name: "หัวหน้าเวร (Charge nurse)"},
+	{Code: "acls", Name: "ACLS"},
+	{Code: "neonatal", Name: "ดูแลทารกแรกเกิด (Neonatal)"},
+}
+
+// FindOrCreateDefaultSkills gives a department without skills the DefaultSkills
+func (r *ScheduleRepository) FindOrCreateDefaultSkills(ctx context.Context, departmentID string) error {
+	var n int
+	if err := r.conn.DB.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s.skills WHERE department_id = $1", r.schema), departmentID).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	q := fmt.Sprintf("INSERT INTO %s.skills (id, department_id, code, name) VALUES ($1,$2,$3,$4) ON CONFLICT (department_id, code) DO NOTHING", r.schema)
+	for _, s := range DefaultSkills {
+		if _, err := r.conn.DB.ExecContext(ctx, q, uuid.New().String(), departmentID, s.Code, s.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListCompetencies returns the department's skill matrix; staff and shifts that are no longer active are left out
+func (r *ScheduleRepository) ListCompetencies(ctx context.Context, departmentID string) (Competencies, error) {
+	var out Competencies
+	rows, err := r.conn.DB.QueryContext(ctx, fmt.Sprintf("SELECT id, department_id, code, name, created_at FROM %s.skills WHERE department_id = $1 ORDER BY created_at, code", r.schema), departmentID)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var s Skill
+		if err := rows.Scan(&s.ID, &s.DepartmentID, &s.Code, &s.Name, &s.CreatedAt); err != nil {
+			rows.Close()
+			return out, err
+		}
+		out.Skills = append(out.Skills, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+
+	rows, err = r.conn.DB.QueryContext(ctx, fmt.Sprintf(`
+        SELECT ss.staff_id, ss.skill_id, to_char(ss.certified_on,'YYYY-MM-DD'), to_char(ss.expires_on,'YYYY-MM-DD')
+        FROM %[1]s.staff_skills ss
+        JOIN %[1]s.skills k ON k.id = ss.skill_id
+        JOIN %[1]s.department_staff ds ON ds.id = ss.staff_id AND ds.is_active = true
+        WHERE k.department_id = $1`, r.schema), departmentID)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var s StaffSkill
+		if err := rows.Scan(&s.StaffID, &s.SkillID, &s.CertifiedOn, &s.ExpiresOn); err != nil {
+			rows.Close()
+			return out, err
+		}
+		out.StaffSkills = append(out.StaffSkills, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+
+	rows, err = r.conn.DB.QueryContext(ctx, fmt.Sprintf(`
+        SELECT q.shift_id, q.skill_id, q.min_count
+        FROM %[1]s.shift_skill_requirements q
+        JOIN %[1]s.skills k ON k.id = q.skill_id
+        JOIN %[1]s.shifts s ON s.id = q.shift_id AND s.is_active = true
+        WHERE k.department_id = $1`, r.schema), departmentID)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var q ShiftSkillRequirement
+		if err := rows.Scan(&q.ShiftID, &q.SkillID, &q.MinCount); err != nil {
+			return out, err
+		}
+		out.Requirements = append(out.Requirements, q)
+	}
+	return out, rows.Err()
+}
+
+// CreateSkill adds a skill to a department
+func (r *ScheduleRepository) CreateSkill(ctx context.Context, s *Skill) error {
+	s.ID = uuid.New().String()
+	q := fmt.Sprintf("INSERT INTO %s.skills (id, department_id, code, name) VALUES ($1,$2,$3,$4) RETURNING created_at", r.schema)
+	return r.conn.DB.QueryRowContext(ctx, q, s.ID, s.DepartmentID, s.Code, s.Name).Scan(&s.CreatedAt)
+}
+
+// GetSkill returns one skill
+func (r *ScheduleRepository) GetSkill(ctx context.Context, id string) (*Skill, error) {
+	var s Skill
+	q := fmt.Sprintf("SELECT id, department_id, code, name, created_at FROM %s.skills WHERE id = $1", r.schema)
+	if err := r.conn.DB.QueryRowContext(ctx, q, id).Scan(&s.ID, &s.DepartmentID, &s.Code, &s.Name, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteSkill removes a skill with its certifications and shift requirements
+func (r *ScheduleRepository) DeleteSkill(ctx context.Context, id string) error {
+	_, err := r.conn.DB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.skills WHERE id = $1", r.schema), id)
+	return err
+}
+
+// SetStaffSkill records or renews a staff member's certification
+func (r *ScheduleRepository) SetStaffSkill(ctx context.Context, s StaffSkill) error {
+	q := fmt.Sprintf(`
+        INSERT INTO %s.staff_skills (staff_id, skill_id, certified_on, expires_on, updated_at)
+        VALUES ($1,$2,$3,$4,NOW())
+        ON CONFLICT (staff_id, skill_id) DO UPDATE SET certified_on = EXCLUDED.certified_on, expires_on = EXCLUDED.expires_on, updated_at = NOW()`, r.schema)
+	_, err := r.conn.DB.ExecContext(ctx, q, s.StaffID, s.SkillID, s.CertifiedOn, s.ExpiresOn)
+	return err
+}
+
+// DeleteStaffSkill removes a staff member's certification
+func (r *ScheduleRepository) DeleteStaffSkill(ctx context.Context, staffID, skillID string) error {
+	_, err := r.conn.DB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.staff_skills WHERE staff_id = $1 AND skill_id = $2", r.schema), staffID, skillID)
+	return err
+}
+
+// SetShiftSkillRequirement sets how many holders of a skill a shift needs; 0 removes the requirement
+func (r *ScheduleRepository) SetShiftSkillRequirement(ctx context.Context, q ShiftSkillRequirement) error {
+	if q.MinCount <= 0 {
+		_, err := r.conn.DB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.shift_skill_requirements WHERE shift_id = $1 AND skill_id = $2", r.schema), q.ShiftID, q.SkillID)
+		return err
+	}
+	stmt := fmt.Sprintf(`
+        INSERT INTO %s.shift_skill_requirements (shift_id, skill_id, min_count) VALUES ($1,$2,$3)
+        ON CONFLICT (shift_id, skill_id) DO UPDATE SET min_count = EXCLUDED.min_count`, r.schema)
+	_, err := r.conn.DB.ExecContext(ctx, stmt, q.ShiftID, q.SkillID, q.MinCount)
+	return err
+}
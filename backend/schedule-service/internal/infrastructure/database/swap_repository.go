@@ -0,0 +1,381 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Swap request states: open (offered) → proposed (a colleague takes it or offers a counter shift) → approved / rejected;
+// open and proposed requests can also be cancelled
+const (
+	SwapOpen      = "open"
+	SwapProposed  = "proposed"
+	SwapApproved  = "approved"
+	SwapRejected  = "rejected"
+	SwapCancelled = "cancelled"
+)
+
+var (
+	// ErrSwapState is returned when the swap is not in a state that allows the action
+	ErrSwapState = errors.New("swap request is not in a valid state for this action")
+	// ErrSwapStale is returned when an assignment of the swap is no longer in the published roster
+	ErrSwapStale = errors.New("swap assignment no longer exists in the schedule")
+)
+
+// SwapRequest is a shift trade between two staff of the same department and role
+type SwapRequest struct {
+	ID             string
+	DepartmentID   string
+	FromStaffID    string
+	ShiftID        string
+	ScheduleDate   string
+	ToStaffID      sql.NullString
+	CounterShiftID sql.NullString
+	CounterDate    sql.NullString // empty when the colleague takes the shift outright
+	Status         string
+	Notes          sql.NullString
+	RequestedBy    sql.NullString
+	DecidedBy      sql.NullString
+	DecidedAt      sql.NullTime
+	DecisionNote   sql.NullString
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FromStaffName  string
+	ToStaffName    sql.NullString
+}
+
+// SwapEvent is one entry in the history of a swap request
+type SwapEvent struct {
+	ID        string
+	SwapID    string
+	Action    string
+	ActorID   sql.NullString
+	Details   json.RawMessage
+	CreatedAt time.Time
+}
+
+func (r *ScheduleRepository) swapsTable() string {
+	return fmt.Sprintf("%s.schedule_swaps", r.schema)
+}
+
+func (r *ScheduleRepository) swapEventsTable() string {
+	return fmt.Sprintf("%s.schedule_swap_events", r.schema)
+}
+
+const swapColumns = `sw.id, sw.department_id, sw.from_staff_id, sw.shift_id, to_char(sw.schedule_date,'YYYY-MM-DD'),
+               sw.to_staff_id, sw.counter_shift_id, to_char(sw.counter_date,'YYYY-MM-DD'), sw.status, sw.notes,
+               sw.requested_by, sw.decided_by, sw.decided_at, sw.decision_note, sw.created_at, sw.updated_at,
+               COALESCE(fs.name,''), ts.name`
+
+func (r *ScheduleRepository) swapSelect() string {
+	return fmt.Sprintf(`
+        SELECT %s
+        FROM %s sw
+        LEFT JOIN %s.department_staff fs ON fs.id = sw.from_staff_id
+        LEFT JOIN %s.department_staff ts ON ts.id = sw.to_staff_id`, swapColumns, r.swapsTable(), r.schema, r.schema)
+}
+
+func scanSwap(row interface{ Scan(...any) error }) (*SwapRequest, error) {
+	var s SwapRequest
+	err := row.Scan(&s.ID, &s.DepartmentID, &s.FromStaffID, &s.ShiftID, &s.ScheduleDate,
+		&s.ToStaffID, &s.CounterShiftID, &s.CounterDate, &s.Status, &s.Notes,
+		&s.RequestedBy, &s.DecidedBy, &s.DecidedAt, &s.DecisionNote, &s.CreatedAt, &s.UpdatedAt,
+		&s.FromStaffName, &s.ToStaffName)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSwaps returns swap requests of a department, optionally filtered by status, newest first
+func (r *ScheduleRepository) ListSwaps(ctx context.Context, departmentID, status string) ([]SwapRequest, error) {
+	q := r.swapSelect() + " WHERE sw.department_id = $1"
+	args := []any{departmentID}
+	if status != "" {
+		q += " AND sw.status = $2"
+		args = append(args, status)
+	}
+	q += " ORDER BY sw.created_at DESC LIMIT 200"
+	rows, err := r.conn.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SwapRequest
+	for rows.Next() {
+		s, err := scanSwap(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// GetSwap returns one swap request
+func (r *ScheduleRepository) GetSwap(ctx context.Context, id string) (*SwapRequest, error) {
+	return scanSwap(r.conn.DB.QueryRowContext(ctx, r.swapSelect()+" WHERE sw.id = $1", id))
+}
+
+// ListSwapEvents returns the history of a swap request, oldest first
+func (r *ScheduleRepository) ListSwapEvents(ctx context.Context, swapID string) ([]SwapEvent, error) {
+	q := fmt.Sprintf("SELECT id, swap_id, action, actor_id, COALESCE(details,'{}'::jsonb), created_at FROM %s WHERE swap_id = $1 ORDER BY created_at ASC", r.swapEventsTable())
+	rows, err := r.conn.DB.QueryContext(ctx, q, swapID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SwapEvent
+	for rows.Next() {
+		var e SwapEvent
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.SwapID, &e.Action, &e.ActorID, &details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Details = details
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *ScheduleRepository) insertSwapEvent(ctx context.Context, tx *sql.Tx, swapID, action, actorID string, details any) error {
+	body, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	q := fmt.Sprintf("INSERT INTO %s (id, swap_id, action, actor_id, details, created_at) VALUES ($1,$2,$3,$4,$5,NOW())", r.swapEventsTable())
+	_, err = tx.ExecContext(ctx, q, uuid.New().String(), swapID, action, sql.NullString{String: actorID, Valid: actorID != ""}, body)
+	return err
+}
+
+// lockSwap locks the swap row and checks it is in one of the allowed states
+func (r *ScheduleRepository) lockSwap(ctx context.Context, tx *sql.Tx, id string, allowed ...string) error {
+	var status string
+	q := fmt.Sprintf("SELECT status FROM %s WHERE id = $1 FOR UPDATE", r.swapsTable())
+	if err := tx.QueryRowContext(ctx, q, id).Scan(&status); err != nil {
+		return err
+	}
+	for _, s := range allowed {
+		if s == status {
+			return nil
+		}
+	}
+	return ErrSwapState
+}
+
+// CreateSwap stores a new open offer and its first history entry
+func (r *ScheduleRepository) CreateSwap(ctx context.Context, s *SwapRequest) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	s.Status = SwapOpen
+	q := fmt.Sprintf(`INSERT INTO %s (id, department_id, from_staff_id, shift_id, schedule_date, status, notes, requested_by, created_at, updated_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NOW(),NOW()) RETURNING created_at, updated_at`, r.swapsTable())
+	if err := tx.QueryRowContext(ctx, q, s.ID, s.DepartmentID, s.FromStaffID, s.ShiftID, s.ScheduleDate, s.Status, s.Notes, s.RequestedBy).Scan(&s.CreatedAt, &s.UpdatedAt); err != nil {
+		return err
+	}
+	if err := r.insertSwapEvent(ctx, tx, s.ID, "offered", s.RequestedBy.String, map[string]any{
+		"fromStaffId": s.FromStaffID, "shiftId": s.ShiftID, "date": s.ScheduleDate,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ProposeSwap records the colleague taking the offer; counterShiftID/counterDate empty means taking it outright.
+// A new proposal on a proposed request replaces the previous one.
+func (r *ScheduleRepository) ProposeSwap(ctx context.Context, id, toStaffID, counterShiftID, counterDate, actorID string) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := r.lockSwap(ctx, tx, id, SwapOpen, SwapProposed); err != nil {
+		return err
+	}
+	q := fmt.Sprintf(`UPDATE %s SET to_staff_id = $1, counter_shift_id = $2, counter_date = $3, status = $4, updated_at = NOW() WHERE id = $5`, r.swapsTable())
+	cShift := sql.NullString{String: counterShiftID, Valid: counterShiftID != ""}
+	cDate := sql.NullString{String: counterDate, Valid: counterDate != ""}
+	if _, err := tx.ExecContext(ctx, q, toStaffID, cShift, cDate, SwapProposed, id); err != nil {
+		return err
+	}
+	details := map[string]any{"toStaffId": toStaffID}
+	if cShift.Valid {
+		details["counterShiftId"] = counterShiftID
+		details["counterDate"] = counterDate
+	}
+	if err := r.insertSwapEvent(ctx, tx, id, "proposed", actorID, details); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CloseSwap rejects or cancels a swap request without touching schedules
+func (r *ScheduleRepository) CloseSwap(ctx context.Context, id, status, actorID, note string) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	allowed := []string{SwapOpen, SwapProposed}
+	if status == SwapRejected {
+		allowed = []string{SwapProposed}
+	}
+	if err := r.lockSwap(ctx, tx, id, allowed...); err != nil {
+		return err
+	}
+	q := fmt.Sprintf(`UPDATE %s SET status = $1, decided_by = $2, decided_at = NOW(), decision_note = $3, updated_at = NOW() WHERE id = $4`, r.swapsTable())
+	if _, err := tx.ExecContext(ctx, q, status, sql.NullString{String: actorID, Valid: actorID != ""}, sql.NullString{String: note, Valid: note != ""}, id); err != nil {
+		return err
+	}
+	if err := r.insertSwapEvent(ctx, tx, id, status, actorID, map[string]any{"note": note}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// swapEdits returns the roster edits of a swap grouped by month, removals before additions
+func swapEdits(s *SwapRequest) map[string][]RepairEdit {
+	type slot struct{ from, to, shiftID, date string }
+	slots := []slot{{s.FromStaffID, s.ToStaffID.String, s.ShiftID, s.ScheduleDate}}
+	if s.CounterShiftID.Valid {
+		slots = append(slots, slot{s.ToStaffID.String, s.FromStaffID, s.CounterShiftID.String, s.CounterDate.String})
+	}
+	out := map[string][]RepairEdit{}
+	for _, sl := range slots {
+		month := sl.date[:7]
+		out[month] = append([]RepairEdit{{Action: "remove", StaffID: sl.from, ShiftID: sl.shiftID, Date: sl.date}}, out[month]...)
+		out[month] = append(out[month], RepairEdit{Action: "add", StaffID: sl.to, ShiftID: sl.shiftID, Date: sl.date})
+	}
+	return out
+}
+
+// ApproveSwap applies a proposed swap to the published roster of every month it touches (see
+// editPublishedRoster) and marks it approved, all in one transaction
+func (r *ScheduleRepository) ApproveSwap(ctx context.Context, id, actorID, note string) error {
+	tx, err := r.conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := r.lockSwap(ctx, tx, id, SwapProposed); err != nil {
+		return err
+	}
+	s, err := scanSwap(tx.QueryRowContext(ctx, r.swapSelect()+" WHERE sw.id = $1", id))
+	if err != nil {
+		return err
+	}
+	edits := swapEdits(s)
+	months := make([]string, 0, len(edits))
+	for m := range edits {
+		months = append(months, m)
+	}
+	sort.Strings(months)
+	for _, month := range months {
+		// same lock as version saves, taken in month order
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", s.DepartmentID+"|"+month); err != nil {
+			return err
+		}
+		if err := r.editPublishedRoster(ctx, tx, s.DepartmentID, month, "swap", actorID, edits[month]); err != nil {
+			if err == ErrRosterChanged {
+				return ErrSwapStale
+			}
+			return err
+		}
+	}
+	q := fmt.Sprintf(`UPDATE %s SET status = $1, decided_by = $2, decided_at = NOW(), decision_note = $3, updated_at = NOW() WHERE id = $4`, r.swapsTable())
+	if _, err := tx.ExecContext(ctx, q, SwapApproved, sql.NullString{String: actorID, Valid: actorID != ""}, sql.NullString{String: note, Valid: note != ""}, id); err != nil {
+		return err
+	}
+	details := map[string]any{"fromStaffId": s.FromStaffID, "toStaffId": s.ToStaffID.String, "note": note}
+	if s.CounterShiftID.Valid {
+		details["counterShiftId"] = s.CounterShiftID.String
+		details["counterDate"] = s.CounterDate.String
+	}
+	if err := r.insertSwapEvent(ctx, tx, id, SwapApproved, actorID, details); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetScheduleRecord returns one schedules row
+func (r *ScheduleRepository) GetScheduleRecord(ctx context.Context, id string) (*Assignment, error) {
+	q := fmt.Sprintf("SELECT id, department_id, COALESCE(staff_id::text,''), COALESCE(user_id::text,''), shift_id, to_char(schedule_date,'YYYY-MM-DD'), COALESCE(status,'assigned'), notes FROM %s WHERE id = $1", r.table())
+	var a Assignment
+	if err := r.conn.DB.QueryRowContext(ctx, q, id).Scan(&a.ID, &a.DepartmentID, &a.StaffID, &a.UserID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListStaffAssignmentsForMonth returns the staff-based schedules rows of a department+month
+func (r *ScheduleRepository) ListStaffAssignmentsForMonth(ctx context.Context, departmentID, month string) ([]Assignment, error) {
+	q := fmt.Sprintf(`SELECT id, department_id, staff_id, shift_id, to_char(schedule_date,'YYYY-MM-DD'), COALESCE(status,'assigned'), notes
+        FROM %s WHERE department_id = $1 AND to_char(schedule_date,'YYYY-MM') = $2 AND staff_id IS NOT NULL`, r.table())
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Assignment
+	for rows.Next() {
+		var a Assignment
+		if err := rows.Scan(&a.ID, &a.DepartmentID, &a.StaffID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ListStaffAssignmentsAround returns the staff-based schedules rows of a department within days
+// days before and after month (YYYY-MM), leaving out the month itself
+func (r *ScheduleRepository) ListStaffAssignmentsAround(ctx context.Context, departmentID, month string, days int) ([]Assignment, error) {
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, err
+	}
+	next := first.AddDate(0, 1, 0)
+	q := fmt.Sprintf(`SELECT id, department_id, staff_id, shift_id, to_char(schedule_date,'YYYY-MM-DD'), COALESCE(status,'assigned'), notes
+        FROM %s WHERE department_id = $1 AND staff_id IS NOT NULL
+          AND ((schedule_date >= $2 AND schedule_date < $3) OR (schedule_date >= $4 AND schedule_date < $5))
+        ORDER BY schedule_date`, r.table())
+	rows, err := r.conn.DB.QueryContext(ctx, q, departmentID,
+		first.AddDate(0, 0, -days).Format("2006-01-02"), first.Format("2006-01-02"),
+		next.Format("2006-01-02"), next.AddDate(0, 0, days).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Assignment
+	for rows.Next() {
+		var a Assignment
+		if err := rows.Scan(&a.ID, &a.DepartmentID, &a.StaffID, &a.ShiftID, &a.ScheduleDate, &a.Status, &a.Notes); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// GetDepartmentStaff returns one staff member
+func (r *ScheduleRepository) GetDepartmentStaff(ctx context.Context, id string) (*DepartmentStaff, error) {
+	q := fmt.Sprintf("SELECT id, department_id, name, position FROM %s.department_staff WHERE id = $1", r.schema)
+	var s DepartmentStaff
+	if err := r.conn.DB.QueryRowContext(ctx, q, id).Scan(&s.ID, &s.DepartmentID, &s.Name, &s.Position); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
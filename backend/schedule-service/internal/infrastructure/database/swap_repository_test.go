@@ -0,0 +1,339 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// testRepository runs against TEST_DATABASE_URL in a throwaway schema holding the few base tables
+// swaps and repairs touch plus their migrations; without the variable the test is skipped
+func testRepository(t *testing.T) *ScheduleRepository {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "schedule_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	ddl := fmt.Sprintf(`
+		CREATE SCHEMA %[1]s;
+		CREATE TABLE %[1]s.users (id UUID PRIMARY KEY, first_name TEXT, last_name TEXT);
+		CREATE TABLE %[1]s.departments (id UUID PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE %[1]s.department_staff (
+			id UUID PRIMARY KEY,
+			department_id UUID NOT NULL REFERENCES %[1]s.departments(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			position VARCHAR(100) NOT NULL
+		);
+		CREATE TABLE %[1]s.schedules (
+			id UUID PRIMARY KEY,
+			department_id UUID NOT NULL REFERENCES %[1]s.departments(id) ON DELETE CASCADE,
+			user_id UUID NOT NULL,
+			shift_id UUID NOT NULL,
+			schedule_date DATE NOT NULL,
+			status VARCHAR(20) DEFAULT 'assigned',
+			notes TEXT,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);`, schema)
+	for _, name := range []string{"migration_schedule_versions.sql", "migration_schedule_swaps.sql", "migration_schedule_repairs.sql"} {
+		migration, err := os.ReadFile("../../../../../database/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ddl += "\n" + strings.ReplaceAll(string(migration), "nurse_shift.", schema+".")
+	}
+	if _, err := db.Exec(ddl); err != nil {
+		_ = db.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		_ = db.Close()
+	})
+	return &ScheduleRepository{conn: &Connection{DB: db}, schema: schema}
+}
+
+// ward is a department with nurses a, b and c and a morning and a night shift
+type ward struct {
+	id      string
+	a, b, c string
+	m, n    string
+}
+
+func seedWard(t *testing.T, r *ScheduleRepository) ward {
+	t.Helper()
+	w := ward{id: uuid.NewString(), a: uuid.NewString(), b: uuid.NewString(), c: uuid.NewString(), m: uuid.NewString(), n: uuid.NewString()}
+	if _, err := r.conn.DB.Exec(fmt.Sprintf("INSERT INTO %s.departments (id, name) VALUES ($1, 'ward')", r.schema), w.id); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{w.a, w.b, w.c} {
+		if _, err := r.conn.DB.Exec(fmt.Sprintf("INSERT INTO %s.department_staff (id, department_id, name, position) VALUES ($1, $2, $3, 'nurse')", r.schema), id, w.id, "nurse "+id[:8]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w
+}
+
+func staffRow(staffID, shiftID, date string) VersionAssignment {
+	return VersionAssignment{StaffID: sql.NullString{String: staffID, Valid: true}, ShiftID: shiftID, ScheduleDate: date, Status: "assigned"}
+}
+
+// publish saves items as a version of February 2026 and publishes it, which also loads the working copy
+func publish(t *testing.T, r *ScheduleRepository, w ward, items ...VersionAssignment) *ScheduleVersion {
+	t.Helper()
+	ctx := context.Background()
+	v := &ScheduleVersion{DepartmentID: w.id, Month: "2026-02", Source: "manual"}
+	if err := r.CreateVersion(ctx, v, items, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PublishVersion(ctx, v.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func published(t *testing.T, r *ScheduleRepository, w ward) (*ScheduleVersion, []VersionAssignment) {
+	t.Helper()
+	v, err := r.GetPublishedVersion(context.Background(), w.id, "2026-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := r.ListVersionAssignments(context.Background(), v.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, items
+}
+
+func workingCopy(t *testing.T, r *ScheduleRepository, w ward) []VersionAssignment {
+	t.Helper()
+	items, err := r.ListWorkingCopy(context.Background(), w.id, "2026-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+func offer(t *testing.T, r *ScheduleRepository, w ward, staffID, shiftID, date string) string {
+	t.Helper()
+	s := &SwapRequest{DepartmentID: w.id, FromStaffID: staffID, ShiftID: shiftID, ScheduleDate: date}
+	if err := r.CreateSwap(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	return s.ID
+}
+
+func swapStatus(t *testing.T, r *ScheduleRepository, id string) string {
+	t.Helper()
+	s, err := r.GetSwap(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Status
+}
+
+func TestSwapStatusMachine(t *testing.T) {
+	r := testRepository(t)
+	w := seedWard(t, r)
+	ctx := context.Background()
+
+	id := offer(t, r, w, w.a, w.m, "2026-02-02")
+	if got := swapStatus(t, r, id); got != SwapOpen {
+		t.Fatalf("new swap is %s, want open", got)
+	}
+	if err := r.CloseSwap(ctx, id, SwapRejected, "", ""); err != ErrSwapState {
+		t.Errorf("rejecting an open swap: %v, want ErrSwapState", err)
+	}
+	if err := r.ApproveSwap(ctx, id, "", ""); err != ErrSwapState {
+		t.Errorf("approving an open swap: %v, want ErrSwapState", err)
+	}
+	if err := r.ProposeSwap(ctx, id, w.b, "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	// a new proposal replaces the previous one
+	if err := r.ProposeSwap(ctx, id, w.c, w.n, "2026-02-05", ""); err != nil {
+		t.Fatal(err)
+	}
+	s, err := r.GetSwap(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != SwapProposed || s.ToStaffID.String != w.c || s.CounterShiftID.String != w.n || s.CounterDate.String != "2026-02-05" {
+		t.Errorf("after the second proposal: %+v, want proposed by c with the night of the 5th", s)
+	}
+	if err := r.CloseSwap(ctx, id, SwapRejected, "", "short-staffed"); err != nil {
+		t.Fatal(err)
+	}
+	if got := swapStatus(t, r, id); got != SwapRejected {
+		t.Errorf("rejected swap is %s", got)
+	}
+	for name, err := range map[string]error{
+		"proposing":  r.ProposeSwap(ctx, id, w.b, "", "", ""),
+		"approving":  r.ApproveSwap(ctx, id, "", ""),
+		"cancelling": r.CloseSwap(ctx, id, SwapCancelled, "", ""),
+	} {
+		if err != ErrSwapState {
+			t.Errorf("%s a rejected swap: %v, want ErrSwapState", name, err)
+		}
+	}
+
+	cancelled := offer(t, r, w, w.a, w.n, "2026-02-03")
+	if err := r.CloseSwap(ctx, cancelled, SwapCancelled, "", ""); err != nil {
+		t.Fatalf("cancelling an open swap: %v", err)
+	}
+	if err := r.ProposeSwap(ctx, cancelled, w.b, "", "", ""); err != ErrSwapState {
+		t.Errorf("proposing on a cancelled swap: %v, want ErrSwapState", err)
+	}
+
+	events, err := r.ListSwapEvents(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action)
+	}
+	if strings.Join(actions, ",") != "offered,proposed,proposed,rejected" {
+		t.Errorf("history = %v, want offered, proposed twice and rejected", actions)
+	}
+}
+
+func TestApproveSwapRewritesThePublishedRoster(t *testing.T) {
+	r := testRepository(t)
+	w := seedWard(t, r)
+	ctx := context.Background()
+	first := publish(t, r, w,
+		staffRow(w.a, w.m, "2026-02-02"),
+		staffRow(w.b, w.n, "2026-02-05"),
+		staffRow(w.c, w.m, "2026-02-05"))
+
+	id := offer(t, r, w, w.a, w.m, "2026-02-02")
+	if err := r.ProposeSwap(ctx, id, w.b, w.n, "2026-02-05", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ApproveSwap(ctx, id, "", "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if got := swapStatus(t, r, id); got != SwapApproved {
+		t.Errorf("approved swap is %s", got)
+	}
+	v, items := published(t, r, w)
+	want := []VersionAssignment{staffRow(w.b, w.m, "2026-02-02"), staffRow(w.a, w.n, "2026-02-05"), staffRow(w.c, w.m, "2026-02-05")}
+	if v.ID == first.ID || v.Source != "swap" || !SameSnapshot(items, want) {
+		t.Errorf("published version %d (%s) holds %+v, want a new swap version with both rows traded", v.VersionNo, v.Source, items)
+	}
+	if old, err := r.GetVersion(ctx, first.ID); err != nil || old.Status != VersionArchived {
+		t.Errorf("the previously published version: %+v, %v, want archived", old, err)
+	}
+	if live := workingCopy(t, r, w); !SameSnapshot(live, want) {
+		t.Errorf("working copy = %+v, want it to follow the published roster it mirrored", live)
+	}
+
+	// a draft loaded in the working copy is neither validated against nor edited
+	draft := []VersionAssignment{staffRow(w.c, w.m, "2026-02-02"), staffRow(w.c, w.n, "2026-02-09")}
+	if err := r.CreateVersion(ctx, &ScheduleVersion{DepartmentID: w.id, Month: "2026-02", Source: "enhanced"}, draft, true); err != nil {
+		t.Fatal(err)
+	}
+	id = offer(t, r, w, w.c, w.m, "2026-02-05")
+	if err := r.ProposeSwap(ctx, id, w.b, "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ApproveSwap(ctx, id, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	_, items = published(t, r, w)
+	want = []VersionAssignment{staffRow(w.b, w.m, "2026-02-02"), staffRow(w.a, w.n, "2026-02-05"), staffRow(w.b, w.m, "2026-02-05")}
+	if !SameSnapshot(items, want) {
+		t.Errorf("published roster = %+v, want %+v", items, want)
+	}
+	if live := workingCopy(t, r, w); !SameSnapshot(live, draft) {
+		t.Errorf("working copy = %+v, want the draft left as it was", live)
+	}
+}
+
+func TestApproveSwapIsAtomic(t *testing.T) {
+	r := testRepository(t)
+	w := seedWard(t, r)
+	ctx := context.Background()
+	first := publish(t, r, w, staffRow(w.a, w.m, "2026-02-02"), staffRow(w.b, w.n, "2026-02-05"))
+
+	// the counter shift is not b's in the published roster
+	id := offer(t, r, w, w.a, w.m, "2026-02-02")
+	if err := r.ProposeSwap(ctx, id, w.b, w.n, "2026-02-06", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ApproveSwap(ctx, id, "", ""); err != ErrSwapStale {
+		t.Fatalf("approving a swap whose counter shift is gone: %v, want ErrSwapStale", err)
+	}
+	if got := swapStatus(t, r, id); got != SwapProposed {
+		t.Errorf("swap is %s after the failed approval, want still proposed", got)
+	}
+	v, items := published(t, r, w)
+	if v.ID != first.ID || !SameSnapshot(items, []VersionAssignment{staffRow(w.a, w.m, "2026-02-02"), staffRow(w.b, w.n, "2026-02-05")}) {
+		t.Errorf("published version %d holds %+v, want the original untouched, including the offered row", v.VersionNo, items)
+	}
+	if live := workingCopy(t, r, w); !SameSnapshot(live, items) {
+		t.Errorf("working copy = %+v, want it untouched", live)
+	}
+}
+
+func TestApproveSwapBeforeVersioning(t *testing.T) {
+	r := testRepository(t)
+	w := seedWard(t, r)
+	ctx := context.Background()
+	q := fmt.Sprintf("INSERT INTO %s.schedules (id, department_id, user_id, staff_id, shift_id, schedule_date) VALUES ($1,$2,$3,$4,$5,$6)", r.schema)
+	for _, row := range [][3]string{{w.a, w.m, "2026-02-02"}, {w.b, w.n, "2026-02-05"}} {
+		if _, err := r.conn.DB.Exec(q, uuid.NewString(), w.id, uuid.NewString(), row[0], row[1], row[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	id := offer(t, r, w, w.a, w.m, "2026-02-02")
+	if err := r.ProposeSwap(ctx, id, w.b, w.n, "2026-02-05", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ApproveSwap(ctx, id, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	want := []VersionAssignment{staffRow(w.b, w.m, "2026-02-02"), staffRow(w.a, w.n, "2026-02-05")}
+	if live := workingCopy(t, r, w); !SameSnapshot(live, want) {
+		t.Errorf("schedules = %+v, want both rows traded", live)
+	}
+	if has, err := r.HasVersions(ctx, w.id, "2026-02"); err != nil || has {
+		t.Errorf("HasVersions = %v, %v: a month without versions must not get one from a swap", has, err)
+	}
+}
+
+func TestSwapEdits(t *testing.T) {
+	s := &SwapRequest{
+		FromStaffID: "a", ShiftID: "m", ScheduleDate: "2026-02-27",
+		ToStaffID:      sql.NullString{String: "b", Valid: true},
+		CounterShiftID: sql.NullString{String: "n", Valid: true},
+		CounterDate:    sql.NullString{String: "2026-03-02", Valid: true},
+	}
+	edits := swapEdits(s)
+	want := map[string][]RepairEdit{
+		"2026-02": {{"remove", "a", "m", "2026-02-27"}, {"add", "b", "m", "2026-02-27"}},
+		"2026-03": {{"remove", "b", "n", "2026-03-02"}, {"add", "a", "n", "2026-03-02"}},
+	}
+	if fmt.Sprint(edits) != fmt.Sprint(want) {
+		t.Errorf("swapEdits = %v, want %v", edits, want)
+	}
+
+	s.CounterDate.String = "2026-02-28"
+	edits = swapEdits(s)
+	if len(edits) != 1 || len(edits["2026-02"]) != 4 || edits["2026-02"][0].Action != "remove" || edits["2026-02"][1].Action != "remove" {
+		t.Errorf("same-month swap = %v, want both removals before the additions", edits)
+	}
+}
@@ -0,0 +1,154 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// ErrNoThaiFont is returned when no TrueType font with Thai glyphs could be loaded; the PDF core
+// fonts cannot render Thai
+var ErrNoThaiFont = errors.New("no Thai TrueType font found; set ROSTER_PDF_FONT")
+
+// DefaultFontPaths are tried in order when ROSTER_PDF_FONT is not set (Alpine font-noto-thai, then
+// Debian fonts-thai-tlwg)
+var DefaultFontPaths = []string{
+	"/usr/share/fonts/noto/NotoSansThai-Regular.ttf",
+	"/usr/share/fonts/truetype/noto/NotoSansThai-Regular.ttf",
+	"/usr/share/fonts/truetype/tlwg/Garuda.ttf",
+}
+
+// LoadThaiFont reads the PDF font: the ROSTER_PDF_FONT file if set, otherwise the first default found
+func LoadThaiFont() ([]byte, error) {
+	paths := DefaultFontPaths
+	if p := os.Getenv("ROSTER_PDF_FONT"); p != "" {
+		paths = []string{p}
+	}
+	for _, p := range paths {
+		if b, err := os.ReadFile(p); err == nil {
+			return b, nil
+		}
+	}
+	return nil, ErrNoThaiFont
+}
+
+const (
+	pdfFamily = "thai"
+	pdfMargin = 8.0
+	pdfRowH   = 5.5
+	pdfNoW    = 7.0
+	pdfNameW  = 40.0
+	pdfTotalW = 9.0
+)
+
+func setFill(pdf *fpdf.Fpdf, hex string) bool {
+	r, g, b, ok := rgb(hex)
+	if ok {
+		pdf.SetFillColor(r, g, b)
+	}
+	return ok
+}
+
+// WritePDF renders the roster on A4 landscape pages, repeating the header row on every page.
+// font must be a TrueType font with Thai glyphs (see LoadThaiFont).
+func (r *Roster) WritePDF(w io.Writer, font []byte) error {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFamily, "", font)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.SetDrawColor(156, 163, 175)
+	pageW, pageH := pdf.GetPageSize()
+
+	totalsN := len(r.Shifts) + 2
+	dayW := 7.0
+	if len(r.Days) > 0 {
+		dayW = (pageW - 2*pdfMargin - pdfNoW - pdfNameW - float64(totalsN)*pdfTotalW) / float64(len(r.Days))
+	}
+
+	header := func() {
+		pdf.AddPage()
+		pdf.SetFont(pdfFamily, "", 12)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(0, 7, r.Title(), "", 1, "L", false, 0, "")
+		pdf.SetFont(pdfFamily, "", 7)
+		setFill(pdf, headerFill)
+		pdf.CellFormat(pdfNoW, pdfRowH*2, "ลำดับ", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(pdfNameW, pdfRowH*2, "ชื่อ-สกุล", "1", 0, "C", true, 0, "")
+		x, y := pdf.GetXY()
+		for i, d := range r.Days {
+			fill := dayFill(d)
+			if fill == "" {
+				fill = headerFill
+			}
+			setFill(pdf, fill)
+			pdf.SetXY(x+float64(i)*dayW, y)
+			pdf.CellFormat(dayW, pdfRowH, strconv.Itoa(d.Date.Day()), "1", 0, "C", true, 0, "")
+			pdf.SetXY(x+float64(i)*dayW, y+pdfRowH)
+			pdf.CellFormat(dayW, pdfRowH, ThaiWeekday(d.Date), "1", 0, "C", true, 0, "")
+		}
+		pdf.SetXY(x+float64(len(r.Days))*dayW, y)
+		setFill(pdf, headerFill)
+		for _, sh := range r.Shifts {
+			pdf.CellFormat(pdfTotalW, pdfRowH*2, sh.Code, "1", 0, "C", true, 0, "")
+		}
+		pdf.CellFormat(pdfTotalW, pdfRowH*2, "รวม", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(pdfTotalW, pdfRowH*2, "ชม.", "1", 1, "C", true, 0, "")
+	}
+
+	header()
+	for n, row := range r.Rows {
+		if _, y := pdf.GetXY(); y+pdfRowH > pageH-pdfMargin {
+			header()
+		}
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(pdfNoW, pdfRowH, strconv.Itoa(n+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(pdfNameW, pdfRowH, row.Name, "1", 0, "L", false, 0, "")
+		for i, cell := range row.Cells {
+			fill, color := r.cellFill(cell, r.Days[i])
+			filled := fill != "" && setFill(pdf, "#"+fill)
+			if color == "FFFFFF" {
+				pdf.SetTextColor(255, 255, 255)
+			} else {
+				pdf.SetTextColor(0, 0, 0)
+			}
+			pdf.CellFormat(dayW, pdfRowH, r.CellText(cell), "1", 0, "C", filled, 0, "")
+		}
+		pdf.SetTextColor(0, 0, 0)
+		for s := range r.Shifts {
+			pdf.CellFormat(pdfTotalW, pdfRowH, strconv.Itoa(row.Count(s)), "1", 0, "C", false, 0, "")
+		}
+		pdf.CellFormat(pdfTotalW, pdfRowH, strconv.Itoa(row.Total()), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(pdfTotalW, pdfRowH, formatHours(row.Hours(r.Shifts)), "1", 1, "C", false, 0, "")
+	}
+
+	// Legend
+	legendH := float64(len(r.Shifts)+3) * pdfRowH
+	if _, y := pdf.GetXY(); y+legendH > pageH-pdfMargin {
+		header()
+	}
+	pdf.Ln(2)
+	pdf.CellFormat(0, pdfRowH, "คำอธิบาย", "", 1, "L", false, 0, "")
+	for i, sh := range r.Shifts {
+		fill, color := r.cellFill([]int{i}, Day{Working: true})
+		filled := fill != "" && setFill(pdf, "#"+fill)
+		if color == "FFFFFF" {
+			pdf.SetTextColor(255, 255, 255)
+		}
+		pdf.CellFormat(pdfNoW, pdfRowH, sh.Code, "1", 0, "C", filled, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(0, pdfRowH, " "+sh.Name+" "+sh.Start+"-"+sh.End+" ("+formatHours(sh.Hours)+" ชม.)", "", 1, "L", false, 0, "")
+	}
+	setFill(pdf, holidayFill)
+	pdf.CellFormat(pdfNoW, pdfRowH, "", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(40, pdfRowH, " วันหยุดนักขัตฤกษ์", "", 0, "L", false, 0, "")
+	setFill(pdf, nonWorkingFill)
+	pdf.CellFormat(pdfNoW, pdfRowH, "", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(40, pdfRowH, " วันไม่ทำการ", "", 1, "L", false, 0, "")
+	if !r.PrintedAt.IsZero() {
+		pdf.CellFormat(0, pdfRowH, "พิมพ์เมื่อ "+r.PrintedAt.Format("02/01/")+strconv.Itoa(r.PrintedAt.Year()+543)+r.PrintedAt.Format(" 15:04"), "", 1, "R", false, 0, "")
+	}
+	return pdf.Output(w)
+}
@@ -0,0 +1,173 @@
+// Package export renders the monthly roster grid ("ตารางเวร") as XLSX and PDF
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Roster is one department-month laid out as staff rows × day columns
+type Roster struct {
+	Department string
+	Month      time.Time // first day of the month
+	Days       []Day
+	Shifts     []Shift // legend and totals columns, in display order
+	Rows       []Row
+	PrintedAt  time.Time
+}
+
+// Day is one column of the grid
+type Day struct {
+	Date    time.Time
+	Working bool
+	Holiday bool
+}
+
+// Shaded reports whether the column is a holiday or a non-working day
+func (d Day) Shaded() bool { return d.Holiday || !d.Working }
+
+// Shift is one shift definition as printed in the legend
+type Shift struct {
+	ID    string
+	Code  string
+	Name  string
+	Start string // HH:MM
+	End   string
+	Color string // #RRGGBB from shifts.color
+	Hours float64
+}
+
+// Row is one staff member; Cells[i] holds indexes into Roster.Shifts for Days[i]
+type Row struct {
+	Name  string
+	Role  string // display label (พยาบาล / ผู้ช่วยพยาบาล)
+	Cells [][]int
+}
+
+// Count returns how many times the row works shift index s
+func (r Row) Count(s int) int {
+	n := 0
+	for _, cell := range r.Cells {
+		for _, i := range cell {
+			if i == s {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Total returns the number of shifts in the row
+func (r Row) Total() int {
+	n := 0
+	for _, cell := range r.Cells {
+		n += len(cell)
+	}
+	return n
+}
+
+// Hours returns the worked hours of the row
+func (r Row) Hours(shifts []Shift) float64 {
+	h := 0.0
+	for _, cell := range r.Cells {
+		for _, i := range cell {
+			h += shifts[i].Hours
+		}
+	}
+	return h
+}
+
+// CellText joins the shift codes of one cell, e.g. "ช/บ"
+func (r *Roster) CellText(cell []int) string {
+	codes := make([]string, 0, len(cell))
+	for _, i := range cell {
+		codes = append(codes, r.Shifts[i].Code)
+	}
+	return strings.Join(codes, "/")
+}
+
+var thaiMonths = [...]string{"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน",
+	"กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม"}
+
+var thaiWeekdays = [...]string{"อา", "จ", "อ", "พ", "พฤ", "ศ", "ส"}
+
+// ThaiMonth formats t as "ตุลาคม 2569" (Buddhist era)
+func ThaiMonth(t time.Time) string {
+	return fmt.Sprintf("%s %d", thaiMonths[t.Month()-1], t.Year()+543)
+}
+
+// ThaiWeekday is the short weekday label printed under each day number
+func ThaiWeekday(t time.Time) string {
+	return thaiWeekdays[t.Weekday()]
+}
+
+// Title is the heading printed on both formats
+func (r *Roster) Title() string {
+	return fmt.Sprintf("ตารางเวร %s ประจำเดือน %s", r.Department, ThaiMonth(r.Month))
+}
+
+// ShiftCode picks the short code printed in a cell: the usual ward letters for the standard
+// shift types, otherwise the first consonant of the shift name
+func ShiftCode(shiftType, name string) string {
+	switch strings.ToLower(shiftType) {
+	case "morning":
+		return "ช"
+	case "afternoon":
+		return "บ"
+	case "night":
+		return "ด"
+	case "overtime":
+		return "OT"
+	}
+	n := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "เวร"))
+	for _, r := range n {
+		// skip Thai leading vowels (เ แ โ ใ ไ)
+		if r >= 'เ' && r <= 'ไ' {
+			continue
+		}
+		return string(r)
+	}
+	return "?"
+}
+
+// formatHours prints 8 as "8" and 7.5 as "7.5"
+func formatHours(h float64) string {
+	return strconv.FormatFloat(h, 'f', -1, 64)
+}
+
+// rgb parses #RRGGBB; ok is false for anything else
+func rgb(hex string) (int, int, int, bool) {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), true
+}
+
+// darkBackground reports whether white text reads better than black on the colour
+func darkBackground(r, g, b int) bool {
+	return 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) < 150
+}
+
+const (
+	holidayFill    = "#FECACA"
+	nonWorkingFill = "#E5E7EB"
+	headerFill     = "#F3F4F6"
+)
+
+// dayFill is the shading of a day column (empty string when unshaded)
+func dayFill(d Day) string {
+	switch {
+	case d.Holiday:
+		return holidayFill
+	case !d.Working:
+		return nonWorkingFill
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"testing"
+	"time"
+)
+
+// testRoster is three days of February 2026: a working Sunday, a holiday and a closed day
+func testRoster() *Roster {
+	return &Roster{
+		Department: "ICU",
+		Month:      time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		Days: []Day{
+			{Date: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), Working: true},
+			{Date: time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC), Working: true, Holiday: true},
+			{Date: time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC), Working: false},
+		},
+		Shifts: []Shift{
+			{ID: "m", Code: "ช", Name: "เช้า", Start: "08:00", End: "16:00", Color: "#FDE68A", Hours: 8},
+			{ID: "n", Code: "ด", Name: "ดึก", Start: "22:00", End: "06:00", Color: "#1E3A8A", Hours: 8},
+			{ID: "x", Code: "ส", Name: "เวรเสริม", Start: "10:00", End: "13:30", Color: "", Hours: 3.5},
+		},
+		Rows: []Row{
+			{Name: "ก", Role: "พยาบาล", Cells: [][]int{{0, 1}, nil, {2}}},
+			{Name: "ข", Role: "ผู้ช่วยพยาบาล", Cells: [][]int{nil, {0}, nil}},
+		},
+	}
+}
+
+func TestRowTotals(t *testing.T) {
+	r := testRoster()
+	row := r.Rows[0]
+	if row.Count(0) != 1 || row.Count(1) != 1 || row.Count(2) != 1 {
+		t.Errorf("counts = %d/%d/%d, want one of each shift", row.Count(0), row.Count(1), row.Count(2))
+	}
+	if row.Total() != 3 {
+		t.Errorf("Total = %d, want 3", row.Total())
+	}
+	if h := row.Hours(r.Shifts); h != 19.5 {
+		t.Errorf("Hours = %v, want 19.5", h)
+	}
+	if got := r.CellText(row.Cells[0]); got != "ช/ด" {
+		t.Errorf("CellText = %q, want ช/ด", got)
+	}
+	if got := r.CellText(row.Cells[1]); got != "" {
+		t.Errorf("CellText of a day off = %q, want empty", got)
+	}
+	if empty := r.Rows[1]; empty.Count(1) != 0 || empty.Total() != 1 || empty.Hours(r.Shifts) != 8 {
+		t.Errorf("second row totals = %d/%d/%v", empty.Count(1), empty.Total(), empty.Hours(r.Shifts))
+	}
+}
+
+func TestShiftCode(t *testing.T) {
+	tests := []struct {
+		shiftType, name, want string
+	}{
+		{"morning", "เวรเช้า", "ช"},
+		{"Afternoon", "บ่าย", "บ"},
+		{"night", "ดึก", "ด"},
+		{"overtime", "OT", "OT"},
+		{"custom", "เวรเสริม", "ส"},
+		{"custom", "ไอซียู", "อ"},
+		{"custom", "  ", "?"},
+	}
+	for _, tt := range tests {
+		if got := ShiftCode(tt.shiftType, tt.name); got != tt.want {
+			t.Errorf("ShiftCode(%q, %q) = %q, want %q", tt.shiftType, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCellFill(t *testing.T) {
+	r := testRoster()
+	working, holiday, closed := r.Days[0], r.Days[1], r.Days[2]
+	tests := []struct {
+		name        string
+		cell        []int
+		day         Day
+		fill, color string
+	}{
+		{"light shift colour", []int{0}, working, "FDE68A", "000000"},
+		{"dark shift colour", []int{1}, working, "1E3A8A", "FFFFFF"},
+		{"first shift of a double", []int{1, 0}, working, "1E3A8A", "FFFFFF"},
+		{"shift colour over a holiday", []int{0}, holiday, "FDE68A", "000000"},
+		{"shift without a colour", []int{2}, closed, "E5E7EB", ""},
+		{"holiday off", nil, holiday, "FECACA", ""},
+		{"closed day off", nil, closed, "E5E7EB", ""},
+		{"working day off", nil, working, "", ""},
+	}
+	for _, tt := range tests {
+		fill, color := r.cellFill(tt.cell, tt.day)
+		if fill != tt.fill || color != tt.color {
+			t.Errorf("%s: cellFill = %q, %q, want %q, %q", tt.name, fill, color, tt.fill, tt.color)
+		}
+	}
+	for i, want := range []bool{false, true, true} {
+		if r.Days[i].Shaded() != want {
+			t.Errorf("day %d Shaded = %v, want %v", i+1, !want, want)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	if got := testRoster().Title(); got != "ตารางเวร ICU ประจำเดือน กุมภาพันธ์ 2569" {
+		t.Errorf("Title = %q", got)
+	}
+	if got := ThaiWeekday(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)); got != "อา" {
+		t.Errorf("ThaiWeekday(Sunday) = %q, want อา", got)
+	}
+}
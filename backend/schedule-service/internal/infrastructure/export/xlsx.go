@@ -0,0 +1,250 @@
+package export
+
+import (
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxFont has Thai glyphs on Windows, macOS and LibreOffice installs
+const xlsxFont = "Tahoma"
+
+type xlsxStyleKey struct {
+	fill   string
+	color  string
+	bold   bool
+	align  string
+	border bool
+}
+
+// xlsxStyles caches excelize style ids so each combination is registered once
+type xlsxStyles struct {
+	f     *excelize.File
+	cache map[xlsxStyleKey]int
+}
+
+func (s *xlsxStyles) get(k xlsxStyleKey) (int, error) {
+	if id, ok := s.cache[k]; ok {
+		return id, nil
+	}
+	st := &excelize.Style{
+		Font:      &excelize.Font{Family: xlsxFont, Size: 10, Bold: k.bold, Color: k.color},
+		Alignment: &excelize.Alignment{Horizontal: k.align, Vertical: "center"},
+	}
+	if k.fill != "" {
+		st.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{k.fill}}
+	}
+	if k.border {
+		for _, side := range []string{"left", "right", "top", "bottom"} {
+			st.Border = append(st.Border, excelize.Border{Type: side, Color: "9CA3AF", Style: 1})
+		}
+	}
+	id, err := s.f.NewStyle(st)
+	if err != nil {
+		return 0, err
+	}
+	s.cache[k] = id
+	return id, nil
+}
+
+// set writes a value with a style in one call
+func (s *xlsxStyles) set(sheet string, col, row int, v any, k xlsxStyleKey) error {
+	cell, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return err
+	}
+	if err := s.f.SetCellValue(sheet, cell, v); err != nil {
+		return err
+	}
+	id, err := s.get(k)
+	if err != nil {
+		return err
+	}
+	return s.f.SetCellStyle(sheet, cell, cell, id)
+}
+
+// cellFill returns the fill and font colour of a grid cell: the shift colour when worked, the day
+// shading otherwise
+func (r *Roster) cellFill(cell []int, d Day) (fill, color string) {
+	if len(cell) > 0 {
+		if cr, cg, cb, ok := rgb(r.Shifts[cell[0]].Color); ok {
+			color = "000000"
+			if darkBackground(cr, cg, cb) {
+				color = "FFFFFF"
+			}
+			return trimHash(r.Shifts[cell[0]].Color), color
+		}
+	}
+	return trimHash(dayFill(d)), ""
+}
+
+func trimHash(s string) string {
+	if len(s) > 0 && s[0] == '#' {
+		return s[1:]
+	}
+	return s
+}
+
+// WriteXLSX renders the roster as a single-sheet workbook laid out for A4 landscape printing
+func (r *Roster) WriteXLSX(w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "ตารางเวร"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+	st := &xlsxStyles{f: f, cache: map[xlsxStyleKey]int{}}
+	header := xlsxStyleKey{fill: trimHash(headerFill), bold: true, align: "center", border: true}
+	plain := xlsxStyleKey{align: "center", border: true}
+
+	const firstDayCol = 4 // A=ลำดับ B=ชื่อ C=ตำแหน่ง
+	lastDayCol := firstDayCol + len(r.Days) - 1
+	totalsCol := lastDayCol + 1
+	lastCol := totalsCol + len(r.Shifts) + 1
+
+	// Title
+	if err := st.set(sheet, 1, 1, r.Title(), xlsxStyleKey{bold: true, align: "left"}); err != nil {
+		return err
+	}
+	lastCell, _ := excelize.CoordinatesToCellName(lastCol, 1)
+	if err := f.MergeCell(sheet, "A1", lastCell); err != nil {
+		return err
+	}
+
+	// Header rows: day number and weekday
+	for i, label := range []string{"ลำดับ", "ชื่อ-สกุล", "ตำแหน่ง"} {
+		if err := st.set(sheet, i+1, 2, label, header); err != nil {
+			return err
+		}
+		if err := st.set(sheet, i+1, 3, "", header); err != nil {
+			return err
+		}
+		top, _ := excelize.CoordinatesToCellName(i+1, 2)
+		bottom, _ := excelize.CoordinatesToCellName(i+1, 3)
+		if err := f.MergeCell(sheet, top, bottom); err != nil {
+			return err
+		}
+	}
+	for i, d := range r.Days {
+		k := header
+		if fill := dayFill(d); fill != "" {
+			k.fill = trimHash(fill)
+		}
+		if err := st.set(sheet, firstDayCol+i, 2, d.Date.Day(), k); err != nil {
+			return err
+		}
+		if err := st.set(sheet, firstDayCol+i, 3, ThaiWeekday(d.Date), k); err != nil {
+			return err
+		}
+	}
+	totalsLabels := make([]string, 0, len(r.Shifts)+2)
+	for _, sh := range r.Shifts {
+		totalsLabels = append(totalsLabels, sh.Code)
+	}
+	totalsLabels = append(totalsLabels, "รวมเวร", "ชั่วโมง")
+	for i, label := range totalsLabels {
+		if err := st.set(sheet, totalsCol+i, 2, label, header); err != nil {
+			return err
+		}
+		if err := st.set(sheet, totalsCol+i, 3, "", header); err != nil {
+			return err
+		}
+		top, _ := excelize.CoordinatesToCellName(totalsCol+i, 2)
+		bottom, _ := excelize.CoordinatesToCellName(totalsCol+i, 3)
+		if err := f.MergeCell(sheet, top, bottom); err != nil {
+			return err
+		}
+	}
+
+	// Staff rows
+	for n, row := range r.Rows {
+		y := 4 + n
+		if err := st.set(sheet, 1, y, n+1, plain); err != nil {
+			return err
+		}
+		if err := st.set(sheet, 2, y, row.Name, xlsxStyleKey{align: "left", border: true}); err != nil {
+			return err
+		}
+		if err := st.set(sheet, 3, y, row.Role, xlsxStyleKey{align: "left", border: true}); err != nil {
+			return err
+		}
+		for i, cell := range row.Cells {
+			fill, color := r.cellFill(cell, r.Days[i])
+			if err := st.set(sheet, firstDayCol+i, y, r.CellText(cell), xlsxStyleKey{fill: fill, color: color, align: "center", border: true}); err != nil {
+				return err
+			}
+		}
+		for s := range r.Shifts {
+			if err := st.set(sheet, totalsCol+s, y, row.Count(s), plain); err != nil {
+				return err
+			}
+		}
+		if err := st.set(sheet, totalsCol+len(r.Shifts), y, row.Total(), xlsxStyleKey{bold: true, align: "center", border: true}); err != nil {
+			return err
+		}
+		if err := st.set(sheet, totalsCol+len(r.Shifts)+1, y, row.Hours(r.Shifts), plain); err != nil {
+			return err
+		}
+	}
+
+	// Legend
+	y := 4 + len(r.Rows) + 1
+	if err := st.set(sheet, 2, y, "คำอธิบาย", xlsxStyleKey{bold: true, align: "left"}); err != nil {
+		return err
+	}
+	for i, sh := range r.Shifts {
+		fill, color := r.cellFill([]int{i}, Day{Working: true})
+		if err := st.set(sheet, 1, y+1+i, sh.Code, xlsxStyleKey{fill: fill, color: color, align: "center", border: true}); err != nil {
+			return err
+		}
+		text := sh.Name + " " + sh.Start + "-" + sh.End + " (" + formatHours(sh.Hours) + " ชม.)"
+		if err := st.set(sheet, 2, y+1+i, text, xlsxStyleKey{align: "left"}); err != nil {
+			return err
+		}
+	}
+	ly := y + 1 + len(r.Shifts)
+	if err := st.set(sheet, 1, ly, "", xlsxStyleKey{fill: trimHash(holidayFill), border: true}); err != nil {
+		return err
+	}
+	if err := st.set(sheet, 2, ly, "วันหยุดนักขัตฤกษ์", xlsxStyleKey{align: "left"}); err != nil {
+		return err
+	}
+	if err := st.set(sheet, 1, ly+1, "", xlsxStyleKey{fill: trimHash(nonWorkingFill), border: true}); err != nil {
+		return err
+	}
+	if err := st.set(sheet, 2, ly+1, "วันไม่ทำการ", xlsxStyleKey{align: "left"}); err != nil {
+		return err
+	}
+
+	// Layout
+	colName := func(c int) string { n, _ := excelize.ColumnNumberToName(c); return n }
+	if err := f.SetColWidth(sheet, "A", "A", 5); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "B", "B", 24); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "C", "C", 12); err != nil {
+		return err
+	}
+	if len(r.Days) > 0 {
+		if err := f.SetColWidth(sheet, colName(firstDayCol), colName(lastDayCol), 4.5); err != nil {
+			return err
+		}
+	}
+	if err := f.SetColWidth(sheet, colName(totalsCol), colName(lastCol), 7); err != nil {
+		return err
+	}
+	if err := f.SetPanes(sheet, &excelize.Panes{Freeze: true, XSplit: firstDayCol - 1, YSplit: 3, TopLeftCell: colName(firstDayCol) + "4", ActivePane: "bottomRight"}); err != nil {
+		return err
+	}
+	a4, landscape, one, zero, fit := 9, "landscape", 1, 0, true
+	if err := f.SetPageLayout(sheet, &excelize.PageLayoutOptions{Size: &a4, Orientation: &landscape, FitToWidth: &one, FitToHeight: &zero}); err != nil {
+		return err
+	}
+	if err := f.SetSheetProps(sheet, &excelize.SheetPropsOptions{FitToPage: &fit}); err != nil {
+		return err
+	}
+	_, err := f.WriteTo(w)
+	return err
+}
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testRoster().WriteXLSX(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("the workbook does not open: %v", err)
+	}
+	defer f.Close()
+	const sheet = "ตารางเวร"
+	if sheets := f.GetSheetList(); len(sheets) != 1 || sheets[0] != sheet {
+		t.Fatalf("sheets = %v, want [%s]", sheets, sheet)
+	}
+
+	// D-F are the three days, G-I the per-shift counts, J the total and K the hours
+	for cell, want := range map[string]string{
+		"A1": "ตารางเวร ICU ประจำเดือน กุมภาพันธ์ 2569",
+		"B2": "ชื่อ-สกุล",
+		"D2": "1", "E2": "2", "F2": "3",
+		"D3": "อา", "E3": "จ", "F3": "อ",
+		"G2": "ช", "H2": "ด", "I2": "ส", "J2": "รวมเวร", "K2": "ชั่วโมง",
+		"A4": "1", "B4": "ก", "C4": "พยาบาล",
+		"D4": "ช/ด", "E4": "", "F4": "ส",
+		"G4": "1", "H4": "1", "I4": "1", "J4": "3", "K4": "19.5",
+		"B5": "ข", "C5": "ผู้ช่วยพยาบาล", "E5": "ช",
+		"G5": "1", "H5": "0", "J5": "1", "K5": "8",
+		"B7": "คำอธิบาย", "A8": "ช", "B8": "เช้า 08:00-16:00 (8 ชม.)", "B10": "เวรเสริม 10:00-13:30 (3.5 ชม.)",
+		"B11": "วันหยุดนักขัตฤกษ์", "B12": "วันไม่ทำการ",
+	} {
+		got, err := f.GetCellValue(sheet, cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s = %q, want %q", cell, got, want)
+		}
+	}
+
+	fill := func(cell string) string {
+		t.Helper()
+		id, err := f.GetCellStyle(sheet, cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st, err := f.GetStyle(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(st.Fill.Color) == 0 {
+			return ""
+		}
+		return st.Fill.Color[0]
+	}
+	for cell, want := range map[string]string{
+		"D2":  "F3F4F6", // working day header
+		"E2":  "FECACA", // holiday header
+		"F3":  "E5E7EB", // closed day header
+		"D4":  "FDE68A", // a double takes the colour of its first shift
+		"E4":  "FECACA", // off on the holiday
+		"F4":  "E5E7EB", // a shift without a colour keeps the day shading
+		"E5":  "FDE68A", // the morning colour wins over the holiday
+		"D5":  "",       // off on a working day
+		"A8":  "FDE68A", // legend swatch
+		"A11": "FECACA",
+		"A12": "E5E7EB",
+	} {
+		if got := fill(cell); got != want {
+			t.Errorf("fill of %s = %q, want %q", cell, got, want)
+		}
+	}
+}
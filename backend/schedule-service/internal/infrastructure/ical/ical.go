@@ -0,0 +1,110 @@
+// Package ical writes RFC 5545 iCalendar feeds
+package ical
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TZID is the timezone every NurseShift feed is written in
+const TZID = "Asia/Bangkok"
+
+// Bangkok has no daylight saving time, so a fixed +07:00 zone is exact
+var Bangkok = time.FixedZone("ICT", 7*60*60)
+
+// Event is one VEVENT; Start/End are wall-clock times in Bangkok
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Categories  string
+}
+
+// Calendar is a VCALENDAR with its events
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+const (
+	localLayout = "20060102T150405"
+	utcLayout   = "20060102T150405Z"
+)
+
+// escapeText escapes a TEXT value (RFC 5545 3.3.11)
+func escapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// foldLine splits a content line into 75-octet pieces without cutting a UTF-8 character (RFC 5545 3.1)
+func foldLine(line string) string {
+	if len(line) <= 75 {
+		return line + "\r\n"
+	}
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// Render serialises the calendar; now is used for DTSTAMP
+func (c Calendar) Render(now time.Time) []byte {
+	var b strings.Builder
+	w := func(line string) { b.WriteString(foldLine(line)) }
+	w("BEGIN:VCALENDAR")
+	w("VERSION:2.0")
+	w("PRODID:-//NurseShift//Schedule Service//TH")
+	w("CALSCALE:GREGORIAN")
+	w("METHOD:PUBLISH")
+	if c.Name != "" {
+		w("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+	w("X-WR-TIMEZONE:" + TZID)
+	w("BEGIN:VTIMEZONE")
+	w("TZID:" + TZID)
+	w("BEGIN:STANDARD")
+	w("DTSTART:19700101T000000")
+	w("TZOFFSETFROM:+0700")
+	w("TZOFFSETTO:+0700")
+	w("TZNAME:ICT")
+	w("END:STANDARD")
+	w("END:VTIMEZONE")
+	stamp := now.UTC().Format(utcLayout)
+	for _, e := range c.Events {
+		w("BEGIN:VEVENT")
+		w("UID:" + e.UID)
+		w("DTSTAMP:" + stamp)
+		w("DTSTART;TZID=" + TZID + ":" + e.Start.Format(localLayout))
+		w("DTEND;TZID=" + TZID + ":" + e.End.Format(localLayout))
+		w("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			w("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Location != "" {
+			w("LOCATION:" + escapeText(e.Location))
+		}
+		if e.Categories != "" {
+			w("CATEGORIES:" + escapeText(e.Categories))
+		}
+		w("TRANSP:OPAQUE")
+		w("END:VEVENT")
+	}
+	w("END:VCALENDAR")
+	return []byte(b.String())
+}
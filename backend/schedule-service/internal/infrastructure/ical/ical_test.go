@@ -0,0 +1,99 @@
+package ical
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestEscapeText(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"เวรเช้า", "เวรเช้า"},
+		{`a,b;c\d`, `a\,b\;c\\d`},
+		{"line one\nline two\r\nline three", `line one\nline two\nline three`},
+		{`\,`, `\\\,`},
+	}
+	for _, tc := range cases {
+		if got := escapeText(tc.in); got != tc.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFoldLineKeepsThaiCharactersWhole(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("เวรดึก ", 20)
+	folded := foldLine(line)
+	if !strings.HasSuffix(folded, "\r\n") {
+		t.Fatalf("folded line does not end in CRLF: %q", folded)
+	}
+	parts := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+	if len(parts) < 2 {
+		t.Fatalf("a %d-octet line was not folded", len(line))
+	}
+	var unfolded strings.Builder
+	for i, p := range parts {
+		if len(p) > 75 {
+			t.Errorf("line %d is %d octets, want at most 75", i, len(p))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(p, " ") {
+				t.Errorf("continuation line %d does not start with a space: %q", i, p)
+			}
+			p = p[1:]
+		}
+		if !utf8.ValidString(p) {
+			t.Errorf("line %d cuts a character: %q", i, p)
+		}
+		unfolded.WriteString(p)
+	}
+	if unfolded.String() != line {
+		t.Errorf("unfolding gives %q, want %q", unfolded.String(), line)
+	}
+	if got := foldLine("UID:short"); got != "UID:short\r\n" {
+		t.Errorf("short line = %q", got)
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	day := time.Date(2026, 2, 2, 0, 0, 0, 0, Bangkok)
+	cal := Calendar{
+		Name: "ตารางเวร หอผู้ป่วยอายุรกรรมหญิง, ชั้น 5; อาคารเฉลิมพระเกียรติ",
+		Events: []Event{
+			{
+				UID:        "a1@nurseshift",
+				Start:      day.Add(8 * time.Hour),
+				End:        day.Add(16 * time.Hour),
+				Summary:    "เช้า - สมใจ ใจดี",
+				Location:   "หอผู้ป่วยอายุรกรรมหญิง",
+				Categories: "morning",
+			},
+			{
+				UID:         "b2@nurseshift",
+				Start:       day.Add(24 * time.Hour),
+				End:         day.Add(32 * time.Hour),
+				Summary:     "ดึก",
+				Description: "รับเวรต่อจากเวรบ่าย\nตรวจเช็ครถฉุกเฉิน; นับยา, ลงบันทึก",
+				Categories:  "night",
+			},
+		},
+	}
+	got := cal.Render(time.Date(2026, 1, 25, 3, 4, 5, 0, time.UTC))
+	golden := "testdata/calendar.ics"
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Render differs from %s (run go test -update to accept):\n%s", golden, got)
+	}
+}
@@ -0,0 +1,192 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Event types published on a department's roster channel
+const (
+	EventAssignmentAdded   = "assignment.added"
+	EventAssignmentRemoved = "assignment.removed"
+	EventAssignmentMoved   = "assignment.moved"
+	EventMonthRegenerated  = "month.regenerated" // the working copy of a month was replaced wholesale
+	EventVersionPublished  = "version.published"
+
+	// EventResync tells a resuming client that events it missed are no longer kept: refetch the month
+	EventResync = "resync"
+)
+
+// Event is one change of a department's roster. IDs grow across all departments, so a client
+// resumes by sending back the last id it saw.
+type Event struct {
+	ID           int64           `json:"id"`
+	DepartmentID string          `json:"departmentId"`
+	Type         string          `json:"type"`
+	Month        string          `json:"month,omitempty"` // YYYY-MM the change falls in
+	Data         json.RawMessage `json:"data"`
+	ActorID      *string         `json:"actorId"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// Store keeps published events so that reconnecting clients can catch up
+type Store interface {
+	Append(ctx context.Context, e *Event) error
+	Since(ctx context.Context, departmentID string, afterID int64, limit int) ([]Event, error)
+	OldestID(ctx context.Context) (int64, error)
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// subscriberBuffer is how many events a slow subscriber may fall behind before it is dropped;
+// the client then reconnects and catches up from the store
+const subscriberBuffer = 64
+
+// replayPage is how many stored events Replay reads per query
+const replayPage = 500
+
+// Subscription receives the live events of one department
+type Subscription struct {
+	C            <-chan Event
+	ch           chan Event
+	departmentID string
+	hub          *Hub
+	once         sync.Once
+}
+
+// Close unsubscribes; C is closed
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub stores roster events and fans them out to the subscribers of their department
+type Hub struct {
+	store Store
+	pubMu sync.Mutex // publishes one at a time, so subscribers see ids in order
+	mu    sync.Mutex
+	subs  map[string]map[*Subscription]struct{}
+}
+
+// NewHub creates a hub over store
+func NewHub(store Store) *Hub {
+	return &Hub{store: store, subs: map[string]map[*Subscription]struct{}{}}
+}
+
+// Publish stores an event and delivers it to the department's subscribers. data is marshalled to JSON.
+func (h *Hub) Publish(ctx context.Context, departmentID, eventType, month string, data any, actorID string) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{DepartmentID: departmentID, Type: eventType, Month: month, Data: raw}
+	if actorID != "" {
+		e.ActorID = &actorID
+	}
+	h.pubMu.Lock()
+	defer h.pubMu.Unlock()
+	if err := h.store.Append(ctx, e); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs[departmentID] {
+		select {
+		case s.ch <- *e:
+		default:
+			// too far behind: drop it rather than block the publisher
+			h.removeLocked(s)
+		}
+	}
+	return e, nil
+}
+
+// Subscribe starts receiving the live events of a department. Subscribe before Replay so that
+// nothing published in between is lost; the caller skips ids it already replayed.
+func (h *Hub) Subscribe(departmentID string) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	s := &Subscription{C: ch, ch: ch, departmentID: departmentID, hub: h}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[departmentID] == nil {
+		h.subs[departmentID] = map[*Subscription]struct{}{}
+	}
+	h.subs[departmentID][s] = struct{}{}
+	return s
+}
+
+// Replay calls fn with the stored events of a department after afterID, oldest first. complete is
+// false when events after afterID have already been pruned, so the client must resync.
+func (h *Hub) Replay(ctx context.Context, departmentID string, afterID int64, fn func(Event) error) (complete bool, err error) {
+	complete = true
+	if afterID > 0 {
+		oldest, err := h.store.OldestID(ctx)
+		if err != nil {
+			return false, err
+		}
+		// an empty store after prunes (or a reset database) cannot vouch for the gap either
+		complete = oldest != 0 && afterID >= oldest-1
+	}
+	for {
+		events, err := h.store.Since(ctx, departmentID, afterID, replayPage)
+		if err != nil {
+			return complete, err
+		}
+		for _, e := range events {
+			if err := fn(e); err != nil {
+				return complete, err
+			}
+			afterID = e.ID
+		}
+		if len(events) < replayPage {
+			return complete, nil
+		}
+	}
+}
+
+// Close ends every subscription, letting open streams finish before the server shuts down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subs {
+		for s := range subs {
+			h.removeLocked(s)
+		}
+	}
+}
+
+// RunPruner deletes events older than retention every interval until ctx is done
+func (h *Hub) RunPruner(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := h.store.Prune(ctx, time.Now().Add(-retention)); err != nil {
+			log.Printf("Failed to prune schedule events: %v", err)
+		} else if n > 0 {
+			log.Printf("Pruned %d schedule events", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(s)
+}
+
+func (h *Hub) removeLocked(s *Subscription) {
+	s.once.Do(func() {
+		delete(h.subs[s.departmentID], s)
+		if len(h.subs[s.departmentID]) == 0 {
+			delete(h.subs, s.departmentID)
+		}
+		close(s.ch)
+	})
+}
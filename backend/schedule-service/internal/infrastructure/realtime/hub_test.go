@@ -0,0 +1,282 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore is an in-memory Store
+type memStore struct {
+	mu     sync.Mutex
+	nextID int64
+	events []Event
+}
+
+func (s *memStore) Append(_ context.Context, e *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	e.ID = s.nextID
+	e.CreatedAt = time.Now()
+	s.events = append(s.events, *e)
+	return nil
+}
+
+func (s *memStore) Since(_ context.Context, departmentID string, afterID int64, limit int) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Event
+	for _, e := range s.events {
+		if e.DepartmentID == departmentID && e.ID > afterID && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) OldestID(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return 0, nil
+	}
+	return s.events[0].ID, nil
+}
+
+func (s *memStore) Prune(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.events[:0]
+	for _, e := range s.events {
+		if !e.CreatedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	n := int64(len(s.events) - len(kept))
+	s.events = kept
+	return n, nil
+}
+
+// pruneFirst drops the n oldest events, as Prune would once they are past retention
+func (s *memStore) pruneFirst(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append([]Event(nil), s.events[n:]...)
+}
+
+const (
+	wardA = "dept-a"
+	wardB = "dept-b"
+)
+
+func publish(t *testing.T, h *Hub, departmentID string) Event {
+	t.Helper()
+	e, err := h.Publish(context.Background(), departmentID, EventAssignmentAdded, "2026-11", map[string]string{"date": "2026-11-02"}, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *e
+}
+
+func replay(t *testing.T, h *Hub, departmentID string, afterID int64) ([]int64, bool) {
+	t.Helper()
+	var ids []int64
+	complete, err := h.Replay(context.Background(), departmentID, afterID, func(e Event) error {
+		if e.DepartmentID != departmentID {
+			t.Errorf("replay of %s returned event %d of %s", departmentID, e.ID, e.DepartmentID)
+		}
+		ids = append(ids, e.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids, complete
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReplayAfterLastEventID(t *testing.T) {
+	h := NewHub(&memStore{})
+	var mine []int64
+	for i := 0; i < 6; i++ {
+		dept := wardA
+		if i%2 == 1 {
+			dept = wardB
+		}
+		e := publish(t, h, dept)
+		if dept == wardA {
+			mine = append(mine, e.ID)
+		}
+	}
+
+	ids, complete := replay(t, h, wardA, 0)
+	if !complete || !equalIDs(ids, mine) {
+		t.Errorf("replay from the start = %v (complete %v), want %v", ids, complete, mine)
+	}
+	ids, complete = replay(t, h, wardA, mine[0])
+	if !complete || !equalIDs(ids, mine[1:]) {
+		t.Errorf("replay after %d = %v (complete %v), want %v", mine[0], ids, complete, mine[1:])
+	}
+	// the last id a client saw may belong to another department
+	ids, complete = replay(t, h, wardA, mine[1]+1)
+	if !complete || !equalIDs(ids, mine[2:]) {
+		t.Errorf("replay after %d = %v (complete %v), want %v", mine[1]+1, ids, complete, mine[2:])
+	}
+	if ids, complete = replay(t, h, wardA, mine[2]); !complete || len(ids) != 0 {
+		t.Errorf("replay after the latest event = %v (complete %v), want nothing", ids, complete)
+	}
+}
+
+func TestReplayPages(t *testing.T) {
+	h := NewHub(&memStore{})
+	for i := 0; i < replayPage*2+3; i++ {
+		publish(t, h, wardA)
+	}
+	ids, complete := replay(t, h, wardA, 1)
+	if !complete || len(ids) != replayPage*2+2 {
+		t.Fatalf("replayed %d events (complete %v), want %d", len(ids), complete, replayPage*2+2)
+	}
+	for i, id := range ids {
+		if id != int64(i+2) {
+			t.Fatalf("event %d of the replay has id %d, want %d", i, id, i+2)
+		}
+	}
+}
+
+func TestReplayAfterPrunedEvents(t *testing.T) {
+	store := &memStore{}
+	h := NewHub(store)
+	for i := 0; i < 5; i++ {
+		publish(t, h, wardA)
+	}
+	store.pruneFirst(3) // ids 1-3 are gone, 4 is the oldest kept
+
+	if ids, complete := replay(t, h, wardA, 3); !complete || !equalIDs(ids, []int64{4, 5}) {
+		t.Errorf("replay after 3 = %v (complete %v), want [4 5] and complete", ids, complete)
+	}
+	ids, complete := replay(t, h, wardA, 1)
+	if complete {
+		t.Errorf("replay after 1 claims to be complete though 2 and 3 were pruned")
+	}
+	if !equalIDs(ids, []int64{4, 5}) {
+		t.Errorf("replay after 1 = %v, want what is still kept", ids)
+	}
+	if _, complete := replay(t, h, wardA, 0); !complete {
+		t.Errorf("a client without a last id has nothing to miss")
+	}
+
+	store.pruneFirst(2)
+	if _, complete := replay(t, h, wardA, 5); complete {
+		t.Errorf("an empty store cannot vouch for the events after 5")
+	}
+}
+
+func TestReplayStopsOnError(t *testing.T) {
+	h := NewHub(&memStore{})
+	publish(t, h, wardA)
+	publish(t, h, wardA)
+	stop := errors.New("client gone")
+	calls := 0
+	_, err := h.Replay(context.Background(), wardA, 0, func(Event) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Replay = %v after %d calls, want the callback's error after one", err, calls)
+	}
+}
+
+func TestSubscribersOnlySeeTheirDepartment(t *testing.T) {
+	h := NewHub(&memStore{})
+	a, b := h.Subscribe(wardA), h.Subscribe(wardB)
+	defer a.Close()
+	defer b.Close()
+
+	ea := publish(t, h, wardA)
+	eb := publish(t, h, wardB)
+	if got := <-a.C; got.ID != ea.ID || got.DepartmentID != wardA {
+		t.Errorf("department A received %+v, want event %d", got, ea.ID)
+	}
+	if got := <-b.C; got.ID != eb.ID || got.DepartmentID != wardB {
+		t.Errorf("department B received %+v, want event %d", got, eb.ID)
+	}
+	select {
+	case e := <-a.C:
+		t.Errorf("department A also received %+v", e)
+	case e := <-b.C:
+		t.Errorf("department B also received %+v", e)
+	default:
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	h := NewHub(&memStore{})
+	slow := h.Subscribe(wardA)
+	other := h.Subscribe(wardB)
+	defer other.Close()
+
+	for i := 0; i < subscriberBuffer; i++ {
+		publish(t, h, wardA)
+	}
+	overflow := publish(t, h, wardA)
+
+	var got int
+	for e := range slow.C {
+		if e.ID == overflow.ID {
+			t.Errorf("the event that overflowed the buffer was delivered")
+		}
+		got++
+	}
+	if got != subscriberBuffer {
+		t.Errorf("slow subscriber received %d events before being dropped, want %d", got, subscriberBuffer)
+	}
+	slow.Close() // closing a dropped subscription again is harmless
+
+	h.mu.Lock()
+	_, kept := h.subs[wardA]
+	h.mu.Unlock()
+	if kept {
+		t.Errorf("the dropped subscriber is still registered")
+	}
+
+	// the other department and new subscribers are unaffected
+	e := publish(t, h, wardB)
+	if got := <-other.C; got.ID != e.ID {
+		t.Errorf("department B received %+v, want event %d", got, e.ID)
+	}
+	fresh := h.Subscribe(wardA)
+	defer fresh.Close()
+	e = publish(t, h, wardA)
+	if got := <-fresh.C; got.ID != e.ID {
+		t.Errorf("resubscribed client received %+v, want event %d", got, e.ID)
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	h := NewHub(&memStore{})
+	a, b := h.Subscribe(wardA), h.Subscribe(wardB)
+	h.Close()
+	for _, s := range []*Subscription{a, b} {
+		if _, ok := <-s.C; ok {
+			t.Errorf("subscription of %s is still open after Close", s.departmentID)
+		}
+		s.Close()
+	}
+	if len(h.subs) != 0 {
+		t.Errorf("%d departments still have subscribers", len(h.subs))
+	}
+}
@@ -0,0 +1,78 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Repository keeps roster events in schedule_events
+type Repository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewRepository creates an event repository over schema
+func NewRepository(db *sql.DB, schema string) *Repository {
+	return &Repository{db: db, schema: schema}
+}
+
+// Append stores an event, filling its id and creation time
+func (r *Repository) Append(ctx context.Context, e *Event) error {
+	q := fmt.Sprintf(`
+		INSERT INTO %s.schedule_events (department_id, event_type, month, payload, actor_id)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		RETURNING id, created_at`, r.schema)
+	return r.db.QueryRowContext(ctx, q, e.DepartmentID, e.Type, e.Month, string(e.Data), e.ActorID).Scan(&e.ID, &e.CreatedAt)
+}
+
+// Since returns up to limit events of a department with ids above afterID, oldest first
+func (r *Repository) Since(ctx context.Context, departmentID string, afterID int64, limit int) ([]Event, error) {
+	q := fmt.Sprintf(`
+		SELECT id, department_id, event_type, COALESCE(month, ''), payload, actor_id, created_at
+		FROM %s.schedule_events
+		WHERE department_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3`, r.schema)
+	rows, err := r.db.QueryContext(ctx, q, departmentID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Event
+	for rows.Next() {
+		var e Event
+		var payload []byte
+		var actor sql.NullString
+		if err := rows.Scan(&e.ID, &e.DepartmentID, &e.Type, &e.Month, &payload, &actor, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Data = payload
+		if actor.Valid {
+			e.ActorID = &actor.String
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// OldestID returns the smallest id still kept, 0 when there are none
+func (r *Repository) OldestID(ctx context.Context) (int64, error) {
+	var id sql.NullInt64
+	q := fmt.Sprintf(`SELECT MIN(id) FROM %s.schedule_events`, r.schema)
+	if err := r.db.QueryRowContext(ctx, q).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id.Int64, nil
+}
+
+// Prune deletes events created before the given time
+func (r *Repository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	q := fmt.Sprintf(`DELETE FROM %s.schedule_events WHERE created_at < $1`, r.schema)
+	res, err := r.db.ExecContext(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// authorize answers with a department access denial unless the caller's role in the department
+// allows act on its schedules
+func (h *ScheduleHandler) authorize(c *fiber.Ctx, departmentID string, act access.Action) (bool, error) {
+	userID, _ := c.Locals("userID").(string)
+	role, _ := c.Locals("role").(string)
+	if err := h.access.Check(c.Context(), userID, role, departmentID, access.ResourceSchedule, act); err != nil {
+		return false, accessError(c, err)
+	}
+	return true, nil
+}
+
+// canManage reports whether the caller may see drafts and change the schedules of a department
+func (h *ScheduleHandler) canManage(c *fiber.Ctx, departmentID string) bool {
+	userID, _ := c.Locals("userID").(string)
+	role, _ := c.Locals("role").(string)
+	err := h.access.Check(c.Context(), userID, role, departmentID, access.ResourceSchedule, access.ActionMutate)
+	var denial *access.Denial
+	if err != nil && !errors.As(err, &denial) {
+		log.Printf("department access check error: %v", err)
+	}
+	return err == nil
+}
+
+// accessError renders a failed department access check: the denial, or a lookup failure
+func accessError(c *fiber.Ctx, err error) error {
+	var denial *access.Denial
+	if errors.As(err, &denial) {
+		return c.Status(denial.Status).JSON(denial.Body())
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"status":  "error",
+		"message": "ไม่สามารถตรวจสอบสิทธิ์ในแผนกได้",
+		"error":   err.Error(),
+	})
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/infrastructure/ical"
+	"nurseshift/schedule-service/internal/optimizer"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func calendarTokenJSON(t *database.CalendarToken) fiber.Map {
+	out := fiber.Map{
+		"id":           t.ID,
+		"scope":        t.Scope,
+		"departmentId": t.DepartmentID,
+		"staffId":      nullStr(t.StaffID),
+		"createdBy":    nullStr(t.CreatedBy),
+		"createdAt":    t.CreatedAt,
+		"lastUsedAt":   nil,
+		"revokedAt":    nil,
+		"active":       !t.RevokedAt.Valid,
+	}
+	if t.LastUsedAt.Valid {
+		out["lastUsedAt"] = t.LastUsedAt.Time
+	}
+	if t.RevokedAt.Valid {
+		out["revokedAt"] = t.RevokedAt.Time
+	}
+	return out
+}
+
+// ownsStaff reports whether the caller is the staff member staffID
+func (h *ScheduleHandler) ownsStaff(c *fiber.Ctx, staffID string) (bool, error) {
+	userID, _ := c.Locals("userID").(string)
+	if staffID == "" || userID == "" {
+		return false, nil
+	}
+	return h.repo.IsOwnStaff(c.Context(), userID, staffID)
+}
+
+// CreateCalendarToken issues a feed token for a staff member or a whole department; the secret is only returned here.
+// Staff may create a token for their own staff record, department feeds are for managers only.
+func (h *ScheduleHandler) CreateCalendarToken(c *fiber.Ctx) error {
+	var req struct {
+		Scope        string `json:"scope"`
+		DepartmentID string `json:"departmentId"`
+		StaffID      string `json:"staffId"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId"})
+	}
+	if req.Scope == "" {
+		req.Scope = database.CalendarScopeStaff
+		if req.StaffID == "" {
+			req.Scope = database.CalendarScopeDepartment
+		}
+	}
+	switch req.Scope {
+	case database.CalendarScopeStaff:
+		if req.StaffID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ staffId สำหรับปฏิทินรายบุคคล"})
+		}
+	case database.CalendarScopeDepartment:
+		req.StaffID = ""
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "scope ต้องเป็น staff หรือ department"})
+	}
+	if !h.canManage(c, req.DepartmentID) {
+		own, err := h.ownsStaff(c, req.StaffID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		if !own {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์สร้างลิงก์ปฏิทินนี้"})
+		}
+	}
+	if req.StaffID != "" {
+		st, err := h.repo.GetDepartmentStaff(c.Context(), req.StaffID)
+		if err == sql.ErrNoRows || (err == nil && st.DepartmentID != req.DepartmentID) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบบุคลากรในแผนกนี้"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+	}
+	secret, hash, err := database.NewCalendarSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	userID, _ := c.Locals("userID").(string)
+	t := &database.CalendarToken{
+		Scope:        req.Scope,
+		DepartmentID: req.DepartmentID,
+		StaffID:      sql.NullString{String: req.StaffID, Valid: req.StaffID != ""},
+		CreatedBy:    sql.NullString{String: userID, Valid: userID != ""},
+	}
+	if err := h.repo.CreateCalendarToken(c.Context(), t, hash); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	data := calendarTokenJSON(t)
+	data["token"] = secret
+	data["feedPath"] = "/api/v1/calendar/" + secret + ".ics"
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "message": "สร้างลิงก์ปฏิทินสำเร็จ", "data": data})
+}
+
+// ListCalendarTokens lists the feed tokens of a department (without secrets); staff only see the
+// tokens of their own staff record
+func (h *ScheduleHandler) ListCalendarTokens(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	manage := h.canManage(c, departmentID)
+	if !manage {
+		if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+			return err
+		}
+	}
+	tokens, err := h.repo.ListCalendarTokens(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	out := make([]fiber.Map, 0, len(tokens))
+	own := map[string]bool{}
+	for i := range tokens {
+		if !manage {
+			if tokens[i].Scope != database.CalendarScopeStaff {
+				continue
+			}
+			staffID := tokens[i].StaffID.String
+			if _, checked := own[staffID]; !checked {
+				ok, err := h.ownsStaff(c, staffID)
+				if err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+				}
+				own[staffID] = ok
+			}
+			if !own[staffID] {
+				continue
+			}
+		}
+		out = append(out, calendarTokenJSON(&tokens[i]))
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงรายการลิงก์ปฏิทินสำเร็จ", "data": out})
+}
+
+// RevokeCalendarToken disables a feed token so calendar apps stop receiving updates; staff may revoke
+// the tokens of their own staff record
+func (h *ScheduleHandler) RevokeCalendarToken(c *fiber.Ctx) error {
+	t, err := h.repo.GetCalendarToken(c.Context(), c.Params("tokenId"))
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบลิงก์ปฏิทิน"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if !h.canManage(c, t.DepartmentID) {
+		own := false
+		if t.Scope == database.CalendarScopeStaff {
+			if own, err = h.ownsStaff(c, t.StaffID.String); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+			}
+		}
+		if !own {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์ยกเลิกลิงก์ปฏิทินนี้"})
+		}
+	}
+	if err := h.repo.RevokeCalendarToken(c.Context(), t.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ยกเลิกลิงก์ปฏิทินสำเร็จ"})
+}
+
+// CalendarFeed serves the iCalendar feed behind a token; it is public, the token itself is the credential
+func (h *ScheduleHandler) CalendarFeed(c *fiber.Ctx) error {
+	secret := strings.TrimSuffix(c.Params("token"), ".ics")
+	if secret == "" {
+		return c.Status(fiber.StatusNotFound).SendString("not found")
+	}
+	t, err := h.repo.ResolveCalendarToken(c.Context(), database.HashCalendarSecret(secret))
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).SendString("not found")
+	}
+	if err != nil {
+		log.Printf("calendar feed token error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("internal error")
+	}
+
+	now := time.Now().In(ical.Bangkok)
+	var months []string
+	if t.Scope == database.CalendarScopeStaff {
+		// last month through two months ahead keeps the feed small but covers what staff look at
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, ical.Bangkok)
+		for i := -1; i <= 2; i++ {
+			months = append(months, first.AddDate(0, i, 0).Format("2006-01"))
+		}
+	} else {
+		month := c.Query("month")
+		if month == "" {
+			month = now.Format("2006-01")
+		}
+		if _, err := time.Parse("2006-01", month); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("month must be YYYY-MM")
+		}
+		months = []string{month}
+	}
+
+	cal, err := h.buildCalendar(c.Context(), t, months)
+	if err != nil {
+		log.Printf("calendar feed build error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("internal error")
+	}
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderCacheControl, "private, max-age=900")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="nurseshift.ics"`)
+	return c.Status(fiber.StatusOK).Send(cal.Render(time.Now()))
+}
+
+// buildCalendar collects the visible assignments of a token's scope into VEVENTs
+func (h *ScheduleHandler) buildCalendar(ctx context.Context, t *database.CalendarToken, months []string) (*ical.Calendar, error) {
+	shifts, err := h.repo.ListShifts(ctx, t.DepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]database.ShiftRecord, len(shifts))
+	for _, sh := range shifts {
+		byID[sh.ID] = sh
+	}
+	deptName, err := h.repo.GetDepartmentName(ctx, t.DepartmentID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	cal := &ical.Calendar{Name: "ตารางเวร " + deptName}
+	if t.Scope == database.CalendarScopeStaff {
+		st, err := h.repo.GetDepartmentStaff(ctx, t.StaffID.String)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if st != nil {
+			cal.Name = fmt.Sprintf("ตารางเวร %s (%s)", st.Name, deptName)
+		}
+	}
+	for _, month := range months {
+		items, err := h.feedAssignments(ctx, t.DepartmentID, month)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range items {
+			if t.Scope == database.CalendarScopeStaff && a.StaffID.String != t.StaffID.String {
+				continue
+			}
+			sh, ok := byID[a.ShiftID]
+			if !ok {
+				continue
+			}
+			if e, ok := calendarEvent(t, deptName, sh, a); ok {
+				cal.Events = append(cal.Events, e)
+			}
+		}
+	}
+	return cal, nil
+}
+
+// calendarEvent is the VEVENT of one assignment; shifts ending at or before their start end the next
+// day, and the UID depends only on the department and the assignment, so calendar apps update the
+// event in place when the feed is regenerated
+func calendarEvent(t *database.CalendarToken, deptName string, sh database.ShiftRecord, a database.VersionAssignment) (ical.Event, bool) {
+	day, err := time.ParseInLocation("2006-01-02", a.ScheduleDate, ical.Bangkok)
+	if err != nil {
+		return ical.Event{}, false
+	}
+	start, end, ok := optimizer.ShiftWindow(sh, day)
+	if !ok {
+		return ical.Event{}, false
+	}
+	summary := sh.Name
+	if t.Scope == database.CalendarScopeDepartment && a.StaffName != "" {
+		summary = sh.Name + " - " + a.StaffName
+	}
+	sum := sha1.Sum([]byte(t.DepartmentID + "|" + a.Key()))
+	return ical.Event{
+		UID:        hex.EncodeToString(sum[:]) + "@nurseshift",
+		Start:      start,
+		End:        end,
+		Summary:    summary,
+		Location:   deptName,
+		Categories: sh.Type,
+	}, true
+}
+
+// feedAssignments returns what a feed may show for a month: the published version when the month is
+// versioned, otherwise the live schedules
+func (h *ScheduleHandler) feedAssignments(ctx context.Context, departmentID, month string) ([]database.VersionAssignment, error) {
+	has, err := h.repo.HasVersions(ctx, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	if has {
+		v, err := h.repo.GetPublishedVersion(ctx, departmentID, month)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return h.repo.ListVersionAssignments(ctx, v.ID)
+	}
+	rows, err := h.repo.ListWithStaff(ctx, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]database.VersionAssignment, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, database.VersionAssignment{
+			StaffID:      sql.NullString{String: r.StaffID, Valid: r.StaffID != ""},
+			ShiftID:      r.ShiftID,
+			ScheduleDate: r.ScheduleDate,
+			Status:       r.Status,
+			Notes:        r.Notes,
+			StaffName:    r.StaffName,
+			StaffRole:    r.StaffRole,
+		})
+	}
+	return out, nil
+}
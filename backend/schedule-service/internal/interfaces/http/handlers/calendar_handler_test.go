@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/infrastructure/ical"
+)
+
+func TestCalendarEvent(t *testing.T) {
+	staffToken := &database.CalendarToken{Scope: database.CalendarScopeStaff, DepartmentID: "dept", StaffID: sql.NullString{String: "s1", Valid: true}}
+	deptToken := &database.CalendarToken{Scope: database.CalendarScopeDepartment, DepartmentID: "dept"}
+	night := database.ShiftRecord{ID: "n", Name: "ดึก", Type: "night", StartTime: "22:00", EndTime: "06:00"}
+	morning := database.ShiftRecord{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00"}
+	a := versionRow("s1", "n", "2026-02-28")
+
+	e, ok := calendarEvent(staffToken, "ICU", night, a)
+	if !ok {
+		t.Fatal("no event for a valid assignment")
+	}
+	wantStart := time.Date(2026, 2, 28, 22, 0, 0, 0, ical.Bangkok)
+	wantEnd := time.Date(2026, 3, 1, 6, 0, 0, 0, ical.Bangkok)
+	if !e.Start.Equal(wantStart) || !e.End.Equal(wantEnd) {
+		t.Errorf("overnight shift runs %v - %v, want %v - %v", e.Start, e.End, wantStart, wantEnd)
+	}
+	if e.Summary != "ดึก" || e.Location != "ICU" || e.Categories != "night" {
+		t.Errorf("staff event = %+v", e)
+	}
+
+	// regenerating the feed, even after the status or name changed, keeps the UID
+	again := a
+	again.Status, again.StaffName = "confirmed", "renamed"
+	if e2, _ := calendarEvent(staffToken, "ICU", night, again); e2.UID != e.UID {
+		t.Errorf("UID changed between regenerations: %q vs %q", e.UID, e2.UID)
+	}
+	other := a
+	other.ShiftID = "m"
+	if e3, _ := calendarEvent(staffToken, "ICU", morning, other); e3.UID == e.UID {
+		t.Errorf("two assignments share UID %q", e.UID)
+	}
+	if e4, _ := calendarEvent(&database.CalendarToken{Scope: database.CalendarScopeStaff, DepartmentID: "other"}, "ICU", night, a); e4.UID == e.UID {
+		t.Errorf("the same assignment in two departments shares UID %q", e.UID)
+	}
+
+	if e5, _ := calendarEvent(deptToken, "ICU", morning, other); e5.Summary != "เช้า - s1" || !e5.End.Equal(time.Date(2026, 2, 28, 16, 0, 0, 0, ical.Bangkok)) {
+		t.Errorf("department event = %+v, want the staff name in the summary and a same-day end", e5)
+	}
+	if _, ok := calendarEvent(staffToken, "ICU", night, versionRow("s1", "n", "28/02/2026")); ok {
+		t.Error("an unparsable date produced an event")
+	}
+}
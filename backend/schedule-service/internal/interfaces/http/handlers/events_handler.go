@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/infrastructure/realtime"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	eventStreamRetry     = 3 * time.Second  // reconnect delay suggested to EventSource
+	eventStreamHeartbeat = 25 * time.Second // keeps proxies from closing an idle stream
+	eventStreamWriteWait = 10 * time.Second
+)
+
+// rosterSlot is one staff member on one shift of one day, as carried by assignment events
+type rosterSlot struct {
+	ID      string `json:"id,omitempty"`
+	Date    string `json:"date"`
+	ShiftID string `json:"shiftId"`
+	StaffID string `json:"staffId,omitempty"`
+	UserID  string `json:"userId,omitempty"`
+}
+
+func slotOf(a *database.Assignment) rosterSlot {
+	return rosterSlot{ID: a.ID, Date: a.ScheduleDate, ShiftID: a.ShiftID, StaffID: a.StaffID, UserID: a.UserID}
+}
+
+// publishRoster tells the department's open calendars about a change. The change is already saved,
+// so a failure only costs the live update and is logged.
+func (h *ScheduleHandler) publishRoster(c *fiber.Ctx, departmentID, eventType, month string, data any) {
+	if h.events == nil {
+		return
+	}
+	userID, _ := c.Locals("userID").(string)
+	if _, err := h.events.Publish(c.Context(), departmentID, eventType, month, data, userID); err != nil {
+		log.Printf("Failed to publish %s for department %s: %v", eventType, departmentID, err)
+	}
+}
+
+// publishSlots publishes one assignment event per slot
+func (h *ScheduleHandler) publishSlots(c *fiber.Ctx, departmentID, eventType, source string, slots []rosterSlot) {
+	for _, s := range slots {
+		h.publishRoster(c, departmentID, eventType, monthOf(s.Date), fiber.Map{"slot": s, "source": source})
+	}
+}
+
+// publishMove publishes an assignment moving from one slot to another
+func (h *ScheduleHandler) publishMove(c *fiber.Ctx, departmentID, source string, from, to rosterSlot) {
+	h.publishRoster(c, departmentID, realtime.EventAssignmentMoved, monthOf(to.Date), fiber.Map{"from": from, "to": to, "source": source})
+}
+
+// publishShiftEdit publishes who left and who joined a shift whose staff was replaced
+func (h *ScheduleHandler) publishShiftEdit(c *fiber.Ctx, departmentID, date, shiftID string, before, after []string) {
+	was := map[string]bool{}
+	for _, id := range before {
+		was[id] = true
+	}
+	is := map[string]bool{}
+	var added, removed []rosterSlot
+	for _, id := range after {
+		if !was[id] && !is[id] {
+			added = append(added, rosterSlot{Date: date, ShiftID: shiftID, StaffID: id})
+		}
+		is[id] = true
+	}
+	for id := range was {
+		if !is[id] {
+			removed = append(removed, rosterSlot{Date: date, ShiftID: shiftID, StaffID: id})
+		}
+	}
+	h.publishSlots(c, departmentID, realtime.EventAssignmentRemoved, "edit_shift", removed)
+	h.publishSlots(c, departmentID, realtime.EventAssignmentAdded, "edit_shift", added)
+}
+
+// publishRegenerated announces a version that replaced the working copy of its month
+func (h *ScheduleHandler) publishRegenerated(c *fiber.Ctx, v *database.ScheduleVersion) {
+	h.publishRoster(c, v.DepartmentID, realtime.EventMonthRegenerated, v.Month, fiber.Map{
+		"versionId":       v.ID,
+		"versionNo":       v.VersionNo,
+		"source":          v.Source,
+		"generationRunId": nullStr(v.GenerationRunID),
+	})
+}
+
+// publishRepair publishes the rows an applied repair removed and added
+func (h *ScheduleHandler) publishRepair(c *fiber.Ctx, rp *database.ScheduleRepair) {
+	var edits []database.RepairEdit
+	if err := json.Unmarshal(rp.Changes, &edits); err != nil {
+		log.Printf("Failed to read the changes of repair %s: %v", rp.ID, err)
+		return
+	}
+	for _, e := range edits {
+		slot := []rosterSlot{{Date: e.Date, ShiftID: e.ShiftID, StaffID: e.StaffID}}
+		switch e.Action {
+		case "remove":
+			h.publishSlots(c, rp.DepartmentID, realtime.EventAssignmentRemoved, "repair", slot)
+		case "add":
+			h.publishSlots(c, rp.DepartmentID, realtime.EventAssignmentAdded, "repair", slot)
+		}
+	}
+}
+
+// StreamScheduleEvents streams the roster events of a department as Server-Sent Events
+// (?departmentId=). A reconnecting client sends the last id it saw in Last-Event-ID, or in
+// ?lastEventId=, and first receives what it missed; a resync event means some of it is gone.
+func (h *ScheduleHandler) StreamScheduleEvents(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	if h.events == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "error", "message": "ไม่รองรับการติดตามตารางเวรแบบเรียลไทม์"})
+	}
+	lastID := int64(0)
+	if v := c.Get("Last-Event-ID", c.Query("lastEventId")); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "Last-Event-ID ไม่ถูกต้อง"})
+		}
+		lastID = id
+	}
+
+	// subscribe before replaying so that nothing published in between is missed
+	sub := h.events.Subscribe(departmentID)
+	conn := c.Context().Conn()
+	hub := h.events
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+		flush := func() error {
+			_ = conn.SetWriteDeadline(time.Now().Add(eventStreamWriteWait))
+			return w.Flush()
+		}
+		send := func(e realtime.Event) error {
+			body, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, body)
+			return flush()
+		}
+
+		fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry.Milliseconds())
+		if err := flush(); err != nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		replayed := lastID
+		complete, err := hub.Replay(ctx, departmentID, lastID, func(e realtime.Event) error {
+			if err := send(e); err != nil {
+				return err
+			}
+			replayed = e.ID
+			return nil
+		})
+		cancel()
+		if err != nil {
+			log.Printf("Failed to replay schedule events of department %s: %v", departmentID, err)
+			return
+		}
+		if !complete {
+			fmt.Fprintf(w, "event: %s\ndata: {\"departmentId\":%q}\n\n", realtime.EventResync, departmentID)
+			if err := flush(); err != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(eventStreamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case e, ok := <-sub.C:
+				if !ok {
+					return // dropped for falling behind, or shutting down; the client resumes
+				}
+				if e.ID <= replayed {
+					continue
+				}
+				if err := send(e); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				if err := flush(); err != nil {
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
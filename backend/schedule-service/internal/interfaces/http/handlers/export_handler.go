@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/infrastructure/export"
+	"nurseshift/schedule-service/internal/infrastructure/ical"
+	"nurseshift/schedule-service/internal/optimizer"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// shiftTypeOrder sorts the legend and totals columns in the order of the working day
+var shiftTypeOrder = map[string]int{"morning": 0, "afternoon": 1, "night": 2, "overtime": 3}
+
+// buildRoster lays out a department+month as a staff × day grid; working selects the live
+// schedules instead of what staff see (the published version)
+func (h *ScheduleHandler) buildRoster(ctx context.Context, departmentID, month string, working bool) (*export.Roster, error) {
+	days, err := h.monthCalendar(ctx, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	shifts, err := h.repo.ListShifts(ctx, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	deptName, err := h.repo.GetDepartmentName(ctx, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	staff, err := h.repo.ListDepartmentStaff(ctx, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	var items []database.VersionAssignment
+	if working {
+		items, err = h.repo.ListWorkingCopy(ctx, departmentID, month)
+	} else {
+		items, err = h.feedAssignments(ctx, departmentID, month)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return assembleRoster(deptName, days, shifts, staff, items, time.Now().In(ical.Bangkok)), nil
+}
+
+// assembleRoster builds the grid from the loaded month: shifts in working-day order, nurses
+// before assistants, one cell per day holding every shift the person works that day
+func assembleRoster(deptName string, days []calendarDay, shifts []database.ShiftRecord, staff []database.DepartmentStaff, items []database.VersionAssignment, printedAt time.Time) *export.Roster {
+	sort.SliceStable(shifts, func(i, j int) bool {
+		oi, ok := shiftTypeOrder[shifts[i].Type]
+		if !ok {
+			oi = len(shiftTypeOrder)
+		}
+		oj, ok := shiftTypeOrder[shifts[j].Type]
+		if !ok {
+			oj = len(shiftTypeOrder)
+		}
+		if oi != oj {
+			return oi < oj
+		}
+		return shifts[i].StartTime < shifts[j].StartTime
+	})
+
+	first := days[0].Date
+	roster := &export.Roster{Department: deptName, Month: first, PrintedAt: printedAt}
+	for _, d := range days {
+		roster.Days = append(roster.Days, export.Day{Date: d.Date, Working: d.Working, Holiday: d.Holiday})
+	}
+	shiftIdx := map[string]int{}
+	for _, sh := range shifts {
+		hours := 0.0
+		if s, e, ok := optimizer.ShiftWindow(sh, first); ok {
+			hours = e.Sub(s).Hours()
+		}
+		shiftIdx[sh.ID] = len(roster.Shifts)
+		roster.Shifts = append(roster.Shifts, export.Shift{
+			ID: sh.ID, Code: export.ShiftCode(sh.Type, sh.Name), Name: sh.Name,
+			Start: sh.StartTime, End: sh.EndTime, Color: sh.Color, Hours: hours,
+		})
+	}
+
+	// Active staff always get a row; staff who left mid-month keep theirs while they have shifts
+	type person struct {
+		name, position string
+		cells          [][]int
+	}
+	people := map[string]*person{}
+	order := []string{}
+	add := func(id, name, position string) *person {
+		if p, ok := people[id]; ok {
+			return p
+		}
+		p := &person{name: name, position: position, cells: make([][]int, len(days))}
+		people[id] = p
+		order = append(order, id)
+		return p
+	}
+	for _, s := range staff {
+		add(s.ID, s.Name, s.Position)
+	}
+	for _, a := range items {
+		si, ok := shiftIdx[a.ShiftID]
+		if !ok || !a.StaffID.Valid {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if err != nil || d.Month() != first.Month() {
+			continue
+		}
+		p := add(a.StaffID.String, a.StaffName, a.StaffRole)
+		p.cells[d.Day()-1] = append(p.cells[d.Day()-1], si)
+	}
+	for _, p := range people {
+		for _, cell := range p.cells {
+			sort.Ints(cell)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		pi, pj := people[order[i]], people[order[j]]
+		ri, rj := staffRole(pi.position), staffRole(pj.position)
+		if ri != rj {
+			return ri == "nurse"
+		}
+		return pi.name < pj.name
+	})
+	for _, id := range order {
+		p := people[id]
+		role := "พยาบาล"
+		if staffRole(p.position) == "assistant" {
+			role = "ผู้ช่วยพยาบาล"
+		}
+		roster.Rows = append(roster.Rows, export.Row{Name: p.name, Role: role, Cells: p.cells})
+	}
+	return roster
+}
+
+// ExportSchedule renders the monthly roster of a department as XLSX (default) or PDF. Managers may
+// pass source=working to print the unpublished working copy.
+func (h *ScheduleHandler) ExportSchedule(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	month := c.Query("month")
+	format := c.Query("format", "xlsx")
+	if departmentID == "" || month == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId และ month"})
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบเดือนไม่ถูกต้อง"})
+	}
+	if format != "xlsx" && format != "pdf" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "format ต้องเป็น xlsx หรือ pdf"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	working := c.Query("source") == "working"
+	if working && !h.canManage(c, departmentID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์จัดการตารางเวรของแผนกนี้"})
+	}
+	roster, err := h.buildRoster(c.Context(), departmentID, month, working)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	var buf bytes.Buffer
+	filename := fmt.Sprintf("roster-%s.%s", month, format)
+	switch format {
+	case "pdf":
+		font, err := export.LoadThaiFont()
+		if err != nil {
+			log.Printf("roster pdf font error: %v", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "error", "message": "ไม่พบฟอนต์ภาษาไทยสำหรับสร้าง PDF"})
+		}
+		if err := roster.WritePDF(&buf, font); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		c.Set(fiber.HeaderContentType, "application/pdf")
+	default:
+		if err := roster.WriteXLSX(&buf); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	}
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
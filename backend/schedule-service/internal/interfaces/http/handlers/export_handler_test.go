@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func TestAssembleRoster(t *testing.T) {
+	var days []calendarDay
+	for d := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC); d.Month() == time.February; d = d.AddDate(0, 0, 1) {
+		days = append(days, calendarDay{Date: d, Working: d.Weekday() != time.Sunday, Holiday: d.Day() == 3})
+	}
+	shifts := []database.ShiftRecord{
+		{ID: "x", Name: "เวรเสริม", Type: "extra", StartTime: "10:00", EndTime: "14:00", Color: "#10B981"},
+		{ID: "n", Name: "ดึก", Type: "night", StartTime: "22:00", EndTime: "06:00", Color: "#1E3A8A"},
+		{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00", Color: "#FDE68A"},
+	}
+	staff := []database.DepartmentStaff{
+		{ID: "s-asst", Name: "ข", Position: "ผู้ช่วยพยาบาล"},
+		{ID: "s-idle", Name: "ง", Position: "nurse"},
+		{ID: "s-nurse", Name: "ก", Position: "nurse"},
+	}
+	left := versionRow("s-left", "n", "2026-02-10")
+	left.StaffName, left.StaffRole = "ค", "nurse"
+	items := []database.VersionAssignment{
+		versionRow("s-nurse", "n", "2026-02-01"),
+		versionRow("s-nurse", "m", "2026-02-01"),
+		versionRow("s-nurse", "m", "2026-02-03"),
+		versionRow("s-nurse", "x", "2026-02-28"),
+		versionRow("s-asst", "m", "2026-02-03"),
+		left,
+		versionRow("s-nurse", "gone", "2026-02-04"), // shift no longer active
+		versionRow("s-nurse", "m", "2026-03-01"),    // outside the month
+	}
+	printed := time.Date(2026, 1, 25, 9, 0, 0, 0, time.UTC)
+
+	r := assembleRoster("ICU", days, shifts, staff, items, printed)
+	if r.Department != "ICU" || !r.PrintedAt.Equal(printed) || !r.Month.Equal(days[0].Date) {
+		t.Errorf("header = %q %v %v", r.Department, r.Month, r.PrintedAt)
+	}
+	if len(r.Days) != 28 || !r.Days[2].Holiday || r.Days[0].Working || !r.Days[1].Working {
+		t.Errorf("days do not carry the calendar flags: %+v", r.Days[:3])
+	}
+
+	// shifts follow the working day, unknown types last; colours come from the shift record
+	wantShifts := []struct{ id, code, color string }{{"m", "ช", "#FDE68A"}, {"n", "ด", "#1E3A8A"}, {"x", "ส", "#10B981"}}
+	if len(r.Shifts) != len(wantShifts) {
+		t.Fatalf("%d shifts, want %d", len(r.Shifts), len(wantShifts))
+	}
+	for i, w := range wantShifts {
+		if sh := r.Shifts[i]; sh.ID != w.id || sh.Code != w.code || sh.Color != w.color {
+			t.Errorf("shift %d = %+v, want %s %s %s", i, sh, w.id, w.code, w.color)
+		}
+	}
+	if r.Shifts[1].Hours != 8 || r.Shifts[2].Hours != 4 {
+		t.Errorf("hours = %v/%v, want the overnight shift at 8 and the extra at 4", r.Shifts[1].Hours, r.Shifts[2].Hours)
+	}
+
+	// nurses by name, then assistants; a leaver keeps a row for the shifts they worked
+	wantRows := []struct {
+		name, role            string
+		morning, night, total int
+		hours                 float64
+	}{
+		{"ก", "พยาบาล", 2, 1, 4, 28},
+		{"ค", "พยาบาล", 0, 1, 1, 8},
+		{"ง", "พยาบาล", 0, 0, 0, 0},
+		{"ข", "ผู้ช่วยพยาบาล", 1, 0, 1, 8},
+	}
+	if len(r.Rows) != len(wantRows) {
+		t.Fatalf("%d rows, want %d", len(r.Rows), len(wantRows))
+	}
+	for i, w := range wantRows {
+		row := r.Rows[i]
+		if row.Name != w.name || row.Role != w.role {
+			t.Errorf("row %d = %s %s, want %s %s", i, row.Name, row.Role, w.name, w.role)
+			continue
+		}
+		if row.Count(0) != w.morning || row.Count(1) != w.night || row.Total() != w.total || row.Hours(r.Shifts) != w.hours {
+			t.Errorf("%s: morning %d night %d total %d hours %v, want %d %d %d %v", w.name,
+				row.Count(0), row.Count(1), row.Total(), row.Hours(r.Shifts), w.morning, w.night, w.total, w.hours)
+		}
+	}
+	if got := r.CellText(r.Rows[0].Cells[0]); got != "ช/ด" {
+		t.Errorf("the double on the 1st reads %q, want ช/ด", got)
+	}
+}
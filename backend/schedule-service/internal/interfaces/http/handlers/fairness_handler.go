@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/optimizer"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rolling window, in months, the fairness ledger is balanced over unless a request says otherwise
+const (
+	DefaultFairnessWindowMonths = 6
+	MaxFairnessWindowMonths     = 24
+)
+
+// addMonths shifts a YYYY-MM month by n months
+func addMonths(month string, n int) string {
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return month
+	}
+	return t.AddDate(0, n, 0).Format("2006-01")
+}
+
+// refreshLedger rebuilds the department's fairness ledger for month from its live roster
+func (h *ScheduleHandler) refreshLedger(c *fiber.Ctx, departmentID, month string) ([]database.FairnessEntry, error) {
+	in, err := h.monthInput(c, departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	current, err := h.repo.ListStaffAssignmentsForMonth(c.Context(), departmentID, month)
+	if err != nil {
+		return nil, err
+	}
+	entries := optimizer.BuildLedger(in, current)
+	return entries, h.repo.ReplaceLedgerMonth(c.Context(), departmentID, month, entries)
+}
+
+// loadLedger returns the department's ledger from month from to month to (inclusive), first
+// building the months that have no rows yet from their rosters
+func (h *ScheduleHandler) loadLedger(c *fiber.Ctx, departmentID, from, to string) ([]database.FairnessEntry, error) {
+	entries, err := h.repo.ListLedger(c.Context(), departmentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	have := map[string]bool{}
+	for _, e := range entries {
+		have[e.Month] = true
+	}
+	for m := from; m <= to; m = addMonths(m, 1) {
+		if have[m] {
+			continue
+		}
+		built, err := h.refreshLedger(c, departmentID, m)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, built...)
+	}
+	return entries, nil
+}
+
+// fairnessWindow reads ?windowMonths=, 1..MaxFairnessWindowMonths; ok is false when out of range
+func fairnessWindow(raw string) (int, bool) {
+	if raw == "" {
+		return DefaultFairnessWindowMonths, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > MaxFairnessWindowMonths {
+		return 0, false
+	}
+	return n, true
+}
+
+// GetFairnessLedger shows each staff member's running totals over a rolling window ending with
+// month (shifts by type, weekend and holiday days, hours) and their balance against the average
+// of their role in the department (?departmentId=&month=&windowMonths=)
+func (h *ScheduleHandler) GetFairnessLedger(c *fiber.Ctx) error {
+	departmentID, month := c.Query("departmentId"), c.Query("month", time.Now().Format("2006-01"))
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "month ต้องอยู่ในรูปแบบ YYYY-MM"})
+	}
+	window, ok := fairnessWindow(c.Query("windowMonths"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "windowMonths ต้องอยู่ระหว่าง 1-" + fmtInt(MaxFairnessWindowMonths)})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	from := addMonths(month, -(window - 1))
+	entries, err := h.loadLedger(c, departmentID, from, month)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	staff, err := h.repo.ListDepartmentStaff(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	type running struct {
+		role   string
+		months map[string]bool
+		totals map[string]float64 // shift type | weekend | holiday | hours
+	}
+	byStaff := map[string]*running{}
+	roleCount := map[string]float64{}
+	for _, s := range staff {
+		role := staffRole(s.Position)
+		byStaff[s.ID] = &running{role: role, months: map[string]bool{}, totals: map[string]float64{}}
+		roleCount[role]++
+	}
+	keys := map[string]bool{optimizer.LedgerWeekend: true, optimizer.LedgerHoliday: true, optimizer.LedgerHours: true}
+	for _, e := range entries {
+		r := byStaff[e.StaffID]
+		if r == nil {
+			continue
+		}
+		r.months[e.Month] = true
+		for t, n := range e.ShiftCounts {
+			r.totals[t] += float64(n)
+			keys[t] = true
+		}
+		r.totals[optimizer.LedgerWeekend] += float64(e.WeekendDays)
+		r.totals[optimizer.LedgerHoliday] += float64(e.HolidayDays)
+		r.totals[optimizer.LedgerHours] += e.Hours
+	}
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	averages := map[string]map[string]float64{}
+	for _, r := range byStaff {
+		if averages[r.role] == nil {
+			averages[r.role] = map[string]float64{}
+		}
+		for k := range keys {
+			averages[r.role][k] += r.totals[k] / roleCount[r.role]
+		}
+	}
+	out := make([]fiber.Map, 0, len(staff))
+	for _, s := range staff {
+		r := byStaff[s.ID]
+		totals, balance := fiber.Map{}, fiber.Map{}
+		for k := range keys {
+			totals[k] = round(r.totals[k])
+			balance[k] = round(r.totals[k] - averages[r.role][k])
+		}
+		out = append(out, fiber.Map{"staffId": s.ID, "staffName": s.Name, "role": r.role, "months": len(r.months), "totals": totals, "balance": balance})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i]["staffName"].(string) < out[j]["staffName"].(string) })
+	avgJSON := fiber.Map{}
+	for role, avg := range averages {
+		m := fiber.Map{}
+		for k, v := range avg {
+			m[k] = round(v)
+		}
+		avgJSON[role] = m
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงสมดุลการจัดเวรสะสมสำเร็จ", "data": fiber.Map{
+		"departmentId": departmentID,
+		"from":         from,
+		"to":           month,
+		"windowMonths": window,
+		"averages":     avgJSON,
+		"staff":        out,
+	}})
+}
+
+// RefreshFairnessLedger rebuilds a month of the ledger from its current roster, e.g. after edits
+// made outside a published version
+func (h *ScheduleHandler) RefreshFairnessLedger(c *fiber.Ctx) error {
+	var req struct {
+		DepartmentID string `json:"departmentId"`
+		Month        string `json:"month"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" || req.Month == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId และ month"})
+	}
+	if _, err := time.Parse("2006-01", req.Month); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "month ต้องอยู่ในรูปแบบ YYYY-MM"})
+	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	entries, err := h.refreshLedger(c, req.DepartmentID, req.Month)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.fairness_ledger.refresh")
+	rec.Resource("department", req.DepartmentID)
+	rec.After(fiber.Map{"month": req.Month, "staff": len(entries)})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "คำนวณสมดุลการจัดเวรของเดือนใหม่สำเร็จ", "data": fiber.Map{"departmentId": req.DepartmentID, "month": req.Month, "staff": len(entries)}})
+}
+
+// generationWindow is the fairnessWindowMonths of a generate request, DefaultFairnessWindowMonths when
+// omitted; false when it is out of range
+func generationWindow(months *int) (int, bool) {
+	window := DefaultFairnessWindowMonths
+	if months != nil {
+		window = *months
+	}
+	return window, window >= 0 && window <= MaxFairnessWindowMonths
+}
+
+// ledgerForGeneration loads the window of months before month the optimizer balances against;
+// on a read error the month is balanced on its own
+func (h *ScheduleHandler) ledgerForGeneration(c *fiber.Ctx, departmentID, month string, window int) []database.FairnessEntry {
+	if window <= 0 {
+		return nil
+	}
+	entries, err := h.loadLedger(c, departmentID, addMonths(month, -window), addMonths(month, -1))
+	if err != nil {
+		log.Printf("load fairness ledger error: %v", err)
+		return nil
+	}
+	return entries
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/optimizer"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// saveGenerationRun persists a generation report; failures are logged so a generated roster is never lost over it
+func (h *ScheduleHandler) saveGenerationRun(c *fiber.Ctx, report optimizer.Report) string {
+	body, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("generation run marshal error: %v", err)
+		return ""
+	}
+	run := &database.GenerationRun{
+		ID:            uuid.New().String(),
+		DepartmentID:  report.DepartmentID,
+		Month:         report.Month,
+		Generator:     report.Generator,
+		RequiredSlots: report.RequiredSlots,
+		FilledSlots:   report.FilledSlots,
+		UnmetTotal:    report.UnmetTotal,
+		Report:        body,
+	}
+	if uid, ok := c.Locals("userID").(string); ok && uid != "" {
+		run.CreatedBy = sql.NullString{String: uid, Valid: true}
+	}
+	if err := h.repo.InsertGenerationRun(c.Context(), run); err != nil {
+		log.Printf("generation run insert error: %v", err)
+		return ""
+	}
+	return run.ID
+}
+
+// ListGenerationRuns returns generation run summaries for a department (and optional month)
+func (h *ScheduleHandler) ListGenerationRuns(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	month := c.Query("month")
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	runs, err := h.repo.ListGenerationRuns(c.Context(), departmentID, month)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	out := make([]fiber.Map, 0, len(runs))
+	for _, run := range runs {
+		out = append(out, fiber.Map{
+			"id":            run.ID,
+			"departmentId":  run.DepartmentID,
+			"month":         run.Month,
+			"generator":     run.Generator,
+			"requiredSlots": run.RequiredSlots,
+			"filledSlots":   run.FilledSlots,
+			"unmetTotal":    run.UnmetTotal,
+			"createdBy":     run.CreatedBy.String,
+			"createdAt":     run.CreatedAt,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงประวัติการสร้างตารางเวรสำเร็จ", "data": out})
+}
+
+// GetGenerationRun returns one generation run with its full report
+func (h *ScheduleHandler) GetGenerationRun(c *fiber.Ctx) error {
+	run, err := h.repo.GetGenerationRun(c.Context(), c.Params("runId"))
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบรายงานการสร้างตารางเวร"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if ok, err := h.authorize(c, run.DepartmentID, access.ActionRead); !ok {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงรายงานการสร้างตารางเวรสำเร็จ", "data": fiber.Map{
+		"id":        run.ID,
+		"createdBy": run.CreatedBy.String,
+		"createdAt": run.CreatedAt,
+		"report":    run.Report,
+	}})
+}
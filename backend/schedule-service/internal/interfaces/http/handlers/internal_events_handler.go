@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+
+	"nurseshift/schedule-service/internal/infrastructure/eventbus"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// eventConsumer names this service in event_inbox
+const eventConsumer = "schedule-service"
+
+// ReceiveEvent takes the domain events other services deliver from their outbox. Delivery is at
+// least once, so an event already handled is acknowledged without being handled again; events this
+// service does not react to are acknowledged as well.
+func (h *ScheduleHandler) ReceiveEvent(c *fiber.Ctx) error {
+	var e eventbus.Event
+	if err := c.BodyParser(&e); err != nil || e.ID == uuid.Nil || e.Type == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลเหตุการณ์ไม่ถูกต้อง"})
+	}
+
+	switch e.Type {
+	case eventbus.LeaveApproved:
+		var p eventbus.LeavePayload
+		if err := e.Decode(&p); err != nil || p.StaffID == "" || p.DepartmentID == "" || p.StartDate == "" || p.EndDate < p.StartDate {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลการลาไม่ถูกต้อง"})
+		}
+		ev := leaveEvent{LeaveID: p.LeaveID, StaffID: p.StaffID, DepartmentID: p.DepartmentID, StartDate: p.StartDate, EndDate: p.EndDate}
+		var repairs []fiber.Map
+		handled, err := h.inbox.Handle(c.Context(), eventConsumer, e, func(ctx context.Context) error {
+			var err error
+			repairs, err = h.proposeLeaveRepairs(c, ev, "")
+			return err
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		if !handled {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "เคยได้รับเหตุการณ์นี้แล้ว"})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สร้างข้อเสนอปรับตารางเวรสำเร็จ", "data": repairs})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ไม่มีการดำเนินการสำหรับเหตุการณ์นี้"})
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// shiftTypes are the values of the shift_type enum staff can state preferences for
+var shiftTypes = map[string]bool{"morning": true, "afternoon": true, "night": true, "overtime": true}
+
+func preferenceJSON(p *database.StaffPreference) fiber.Map {
+	out := fiber.Map{
+		"staffId":             p.StaffID,
+		"staffName":           p.StaffName,
+		"departmentId":        p.DepartmentID,
+		"preferredShiftTypes": nonNil(p.PreferredShiftTypes),
+		"avoidedShiftTypes":   nonNil(p.AvoidedShiftTypes),
+		"preferredWeekdays":   p.PreferredWeekdays,
+		"requestOffDates":     nonNil(p.RequestsOff),
+		"updatedBy":           nullStr(p.UpdatedBy),
+		"updatedAt":           nil,
+	}
+	if p.PreferredWeekdays == nil {
+		out["preferredWeekdays"] = []int{}
+	}
+	if p.UpdatedAt.Valid {
+		out["updatedAt"] = p.UpdatedAt.Time
+	}
+	return out
+}
+
+func nonNil(xs []string) []string {
+	if xs == nil {
+		return []string{}
+	}
+	return xs
+}
+
+func policyJSON(p database.PreferencePolicy, month string) fiber.Map {
+	out := fiber.Map{"departmentId": p.DepartmentID, "maxRequestsOff": p.MaxRequestsOff, "cutoffDays": p.CutoffDays}
+	if cutoff, err := p.Cutoff(month); err == nil {
+		out["month"] = month
+		out["cutoff"] = cutoff
+		out["open"] = time.Now().Before(cutoff)
+	}
+	return out
+}
+
+// cleanShiftTypes lower-cases and de-duplicates shift types; false when one is not a shift type
+func cleanShiftTypes(xs []string) ([]string, bool) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, x := range xs {
+		x = strings.ToLower(strings.TrimSpace(x))
+		if !shiftTypes[x] {
+			return nil, false
+		}
+		if !seen[x] {
+			seen[x] = true
+			out = append(out, x)
+		}
+	}
+	return out, true
+}
+
+// GetPreferences lists the preferences and request-off days of a department's staff for a month,
+// with the department's cap and cut-off (?departmentId=&month=)
+func (h *ScheduleHandler) GetPreferences(c *fiber.Ctx) error {
+	departmentID, month := c.Query("departmentId"), c.Query("month")
+	if departmentID == "" || month == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId และ month"})
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "month ต้องอยู่ในรูปแบบ YYYY-MM"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	policy, err := h.repo.GetPreferencePolicy(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	prefs, err := h.repo.ListPreferencesForMonth(c.Context(), departmentID, month)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	staff := make([]fiber.Map, 0, len(prefs))
+	for i := range prefs {
+		staff = append(staff, preferenceJSON(&prefs[i]))
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงความต้องการของบุคลากรสำเร็จ", "data": fiber.Map{"policy": policyJSON(policy, month), "staff": staff}})
+}
+
+// SavePreference stores a staff member's shift-type and weekday preferences and their request-off
+// days for a month. Staff can submit until the department's cut-off; after it only those who manage
+// the department's schedules can change them.
+func (h *ScheduleHandler) SavePreference(c *fiber.Ctx) error {
+	var req struct {
+		DepartmentID        string   `json:"departmentId"`
+		StaffID             string   `json:"staffId"`
+		Month               string   `json:"month"`
+		PreferredShiftTypes []string `json:"preferredShiftTypes"`
+		AvoidedShiftTypes   []string `json:"avoidedShiftTypes"`
+		PreferredWeekdays   []int    `json:"preferredWeekdays"`
+		RequestOffDates     []string `json:"requestOffDates"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" || req.StaffID == "" || req.Month == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId, staffId และ month"})
+	}
+	if _, err := time.Parse("2006-01", req.Month); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "month ต้องอยู่ในรูปแบบ YYYY-MM"})
+	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionRequest); !ok {
+		return err
+	}
+
+	preferred, ok1 := cleanShiftTypes(req.PreferredShiftTypes)
+	avoided, ok2 := cleanShiftTypes(req.AvoidedShiftTypes)
+	if !ok1 || !ok2 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ประเภทเวรต้องเป็น morning, afternoon, night หรือ overtime"})
+	}
+	for _, t := range preferred {
+		for _, a := range avoided {
+			if t == a {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ประเภทเวรเดียวกันเป็นทั้งเวรที่ต้องการและเวรที่ไม่ต้องการไม่ได้"})
+			}
+		}
+	}
+	weekdays := []int{}
+	seenDay := map[int]bool{}
+	for _, d := range req.PreferredWeekdays {
+		if d < 0 || d > 6 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "วันในสัปดาห์ต้องอยู่ระหว่าง 0 (อาทิตย์) ถึง 6 (เสาร์)"})
+		}
+		if !seenDay[d] {
+			seenDay[d] = true
+			weekdays = append(weekdays, d)
+		}
+	}
+	sort.Ints(weekdays)
+	offDates := []string{}
+	seenDate := map[string]bool{}
+	for _, d := range req.RequestOffDates {
+		if _, err := time.Parse("2006-01-02", d); err != nil || monthOf(d) != req.Month {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "วันที่ขอหยุดต้องเป็นวันที่ YYYY-MM-DD ในเดือนที่ระบุ"})
+		}
+		if !seenDate[d] {
+			seenDate[d] = true
+			offDates = append(offDates, d)
+		}
+	}
+	sort.Strings(offDates)
+
+	st, err := h.repo.GetDepartmentStaff(c.Context(), req.StaffID)
+	if err == sql.ErrNoRows || (err == nil && st.DepartmentID != req.DepartmentID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบบุคลากรในแผนกนี้"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	policy, err := h.repo.GetPreferencePolicy(c.Context(), req.DepartmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if len(offDates) > policy.MaxRequestsOff {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"status": "error", "message": "ขอหยุดได้ไม่เกิน " + fmtInt(policy.MaxRequestsOff) + " วันต่อเดือน", "data": policyJSON(policy, req.Month)})
+	}
+	if cutoff, _ := policy.Cutoff(req.Month); !time.Now().Before(cutoff) && !h.canManage(c, req.DepartmentID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ปิดรับความต้องการสำหรับเดือนนี้แล้ว", "data": policyJSON(policy, req.Month)})
+	}
+
+	var before *database.StaffPreference
+	if prefs, err := h.repo.ListPreferencesForMonth(c.Context(), req.DepartmentID, req.Month); err == nil {
+		for i := range prefs {
+			if prefs[i].StaffID == req.StaffID {
+				before = &prefs[i]
+			}
+		}
+	}
+	userID, _ := c.Locals("userID").(string)
+	p := &database.StaffPreference{
+		DepartmentID:        req.DepartmentID,
+		StaffID:             req.StaffID,
+		StaffName:           st.Name,
+		PreferredShiftTypes: preferred,
+		AvoidedShiftTypes:   avoided,
+		PreferredWeekdays:   weekdays,
+		RequestsOff:         offDates,
+		UpdatedBy:           sql.NullString{String: userID, Valid: userID != ""},
+	}
+	if err := h.repo.SavePreference(c.Context(), p, req.Month); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.preference.save")
+	rec.Resource("staff", req.StaffID)
+	if before != nil {
+		rec.Before(preferenceJSON(before))
+	}
+	rec.After(preferenceJSON(p))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "บันทึกความต้องการสำเร็จ", "data": preferenceJSON(p)})
+}
+
+// SavePreferencePolicy sets how many days off each staff member may request per month and how many
+// days before the month submissions close
+func (h *ScheduleHandler) SavePreferencePolicy(c *fiber.Ctx) error {
+	var req struct {
+		DepartmentID   string `json:"departmentId"`
+		MaxRequestsOff *int   `json:"maxRequestsOff"`
+		CutoffDays     *int   `json:"cutoffDays"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId"})
+	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	before, err := h.repo.GetPreferencePolicy(c.Context(), req.DepartmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	policy := before
+	if req.MaxRequestsOff != nil {
+		policy.MaxRequestsOff = *req.MaxRequestsOff
+	}
+	if req.CutoffDays != nil {
+		policy.CutoffDays = *req.CutoffDays
+	}
+	if policy.MaxRequestsOff < 0 || policy.MaxRequestsOff > 31 || policy.CutoffDays < 0 || policy.CutoffDays > 60 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "maxRequestsOff ต้องอยู่ระหว่าง 0-31 และ cutoffDays ระหว่าง 0-60"})
+	}
+	if err := h.repo.SavePreferencePolicy(c.Context(), policy); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.preference_policy.save")
+	rec.Resource("department", req.DepartmentID)
+	rec.Before(fiber.Map{"maxRequestsOff": before.MaxRequestsOff, "cutoffDays": before.CutoffDays})
+	rec.After(fiber.Map{"maxRequestsOff": policy.MaxRequestsOff, "cutoffDays": policy.CutoffDays})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "บันทึกเงื่อนไขการขอหยุดสำเร็จ", "data": fiber.Map{"departmentId": policy.DepartmentID, "maxRequestsOff": policy.MaxRequestsOff, "cutoffDays": policy.CutoffDays}})
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/optimizer"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// leaveEvent is the approved leave sent by employee-leave-service (or entered by a head nurse)
+type leaveEvent struct {
+	LeaveID      string `json:"leaveId"`
+	StaffID      string `json:"staffId"`
+	DepartmentID string `json:"departmentId"`
+	StartDate    string `json:"startDate"` // YYYY-MM-DD
+	EndDate      string `json:"endDate"`
+}
+
+// rawViolations decodes stored repair violations; repairs stored before they were recorded have none
+func rawViolations(raw json.RawMessage) []optimizer.Violation {
+	var vs []optimizer.Violation
+	_ = json.Unmarshal(raw, &vs)
+	return vs
+}
+
+func repairJSON(rp *database.ScheduleRepair) fiber.Map {
+	out := fiber.Map{
+		"id":           rp.ID,
+		"departmentId": rp.DepartmentID,
+		"month":        rp.Month,
+		"leaveId":      nullStr(rp.LeaveID),
+		"staffId":      rp.StaffID,
+		"startDate":    rp.StartDate,
+		"endDate":      rp.EndDate,
+		"status":       rp.Status,
+		"changes":      json.RawMessage(rp.Changes),
+		"unfilled":     json.RawMessage(rp.Unfilled),
+		"violations":   violationsJSON(rawViolations(rp.Violations)),
+		"moved":        rp.Moved,
+		"createdBy":    nullStr(rp.CreatedBy),
+		"createdAt":    rp.CreatedAt,
+		"decidedBy":    nullStr(rp.DecidedBy),
+		"decidedAt":    nil,
+	}
+	if rp.DecidedAt.Valid {
+		out["decidedAt"] = rp.DecidedAt.Time
+	}
+	return out
+}
+
+// proposeLeaveRepairs computes and stores one repair per month the leave touches that already has a
+// published roster; repairs are computed from and applied to that roster, not a draft
+func (h *ScheduleHandler) proposeLeaveRepairs(c *fiber.Ctx, ev leaveEvent, createdBy string) ([]fiber.Map, error) {
+	start, err := time.Parse("2006-01-02", ev.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse("2006-01-02", ev.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	out := []fiber.Map{}
+	for m := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(end); m = m.AddDate(0, 1, 0) {
+		month := m.Format("2006-01")
+		version, err := h.repo.RosterVersion(c.Context(), ev.DepartmentID, month)
+		if err != nil {
+			return nil, err
+		}
+		current, err := h.repo.ListPublishedStaffAssignments(c.Context(), ev.DepartmentID, month)
+		if err != nil {
+			return nil, err
+		}
+		affected := false
+		for _, a := range current {
+			if a.StaffID == ev.StaffID && a.ScheduleDate >= ev.StartDate && a.ScheduleDate <= ev.EndDate {
+				affected = true
+				break
+			}
+		}
+		if !affected {
+			continue
+		}
+		in, err := h.monthInput(c, ev.DepartmentID, month)
+		if err != nil {
+			return nil, err
+		}
+		res, err := optimizer.RepairLeave(in, current, ev.StaffID, ev.StartDate, ev.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		changes, _ := json.Marshal(res.Changes)
+		unfilled, _ := json.Marshal(res.Unfilled)
+		violations, _ := json.Marshal(res.Violations)
+		rp := &database.ScheduleRepair{
+			DepartmentID:  ev.DepartmentID,
+			Month:         month,
+			LeaveID:       sql.NullString{String: ev.LeaveID, Valid: ev.LeaveID != ""},
+			StaffID:       ev.StaffID,
+			StartDate:     ev.StartDate,
+			EndDate:       ev.EndDate,
+			Changes:       changes,
+			Unfilled:      unfilled,
+			Violations:    violations,
+			Moved:         res.Moved,
+			RosterVersion: sql.NullString{String: version, Valid: true},
+			CreatedBy:     sql.NullString{String: createdBy, Valid: createdBy != ""},
+		}
+		if err := h.repo.InsertRepair(c.Context(), rp); err != nil {
+			return nil, err
+		}
+		out = append(out, repairJSON(rp))
+	}
+	return out, nil
+}
+
+func parseLeaveEvent(c *fiber.Ctx) (leaveEvent, bool) {
+	var ev leaveEvent
+	if err := c.BodyParser(&ev); err != nil || ev.StaffID == "" || ev.DepartmentID == "" || ev.StartDate == "" || ev.EndDate == "" {
+		return ev, false
+	}
+	return ev, ev.EndDate >= ev.StartDate
+}
+
+// LeaveApproved receives approved leave posted directly and proposes roster repairs; employee-leave-service
+// now delivers leave.approved to ReceiveEvent instead
+func (h *ScheduleHandler) LeaveApproved(c *fiber.Ctx) error {
+	ev, ok := parseLeaveEvent(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลการลาไม่ถูกต้อง"})
+	}
+	repairs, err := h.proposeLeaveRepairs(c, ev, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สร้างข้อเสนอปรับตารางเวรสำเร็จ", "data": repairs})
+}
+
+// CreateRepair lets a head nurse request a repair proposal for a leave by hand
+func (h *ScheduleHandler) CreateRepair(c *fiber.Ctx) error {
+	ev, ok := parseLeaveEvent(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId, staffId, startDate และ endDate"})
+	}
+	if !h.canManage(c, ev.DepartmentID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์จัดการตารางเวรของแผนกนี้"})
+	}
+	repairs, err := h.proposeLeaveRepairs(c, ev, c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "message": "สร้างข้อเสนอปรับตารางเวรสำเร็จ", "data": repairs})
+}
+
+// ListRepairs lists repair proposals of a department (?departmentId=&status=)
+func (h *ScheduleHandler) ListRepairs(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	repairs, err := h.repo.ListRepairs(c.Context(), departmentID, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	out := make([]fiber.Map, 0, len(repairs))
+	for i := range repairs {
+		out = append(out, repairJSON(&repairs[i]))
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงข้อเสนอปรับตารางเวรสำเร็จ", "data": out})
+}
+
+// loadRepair fetches a repair proposal, writing the error response when it cannot
+func (h *ScheduleHandler) loadRepair(c *fiber.Ctx) (*database.ScheduleRepair, error) {
+	rp, err := h.repo.GetRepair(c.Context(), c.Params("repairId"))
+	if err == sql.ErrNoRows {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบข้อเสนอปรับตารางเวร"})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if ok, err := h.authorize(c, rp.DepartmentID, access.ActionRead); !ok {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// GetRepair returns one repair proposal
+func (h *ScheduleHandler) GetRepair(c *fiber.Ctx) error {
+	rp, resp := h.loadRepair(c)
+	if rp == nil {
+		return resp
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงข้อเสนอปรับตารางเวรสำเร็จ", "data": repairJSON(rp)})
+}
+
+// ApplyRepair writes a reviewed repair to the published roster
+func (h *ScheduleHandler) ApplyRepair(c *fiber.Ctx) error {
+	return h.decideRepair(c, true)
+}
+
+// DiscardRepair drops a repair proposal without changing the schedules
+func (h *ScheduleHandler) DiscardRepair(c *fiber.Ctx) error {
+	return h.decideRepair(c, false)
+}
+
+func (h *ScheduleHandler) decideRepair(c *fiber.Ctx, apply bool) error {
+	rp, resp := h.loadRepair(c)
+	if rp == nil {
+		return resp
+	}
+	if !h.canManage(c, rp.DepartmentID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์จัดการตารางเวรของแผนกนี้"})
+	}
+	userID := c.Locals("userID").(string)
+	var err error
+	if apply {
+		err = h.repo.ApplyRepair(c.Context(), rp.ID, userID)
+	} else {
+		err = h.repo.DiscardRepair(c.Context(), rp.ID, userID)
+	}
+	switch err {
+	case nil:
+	case database.ErrRepairClosed:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "ข้อเสนอนี้ถูกดำเนินการไปแล้ว"})
+	case database.ErrRepairStale:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "ตารางเวรถูกแก้ไขหลังสร้างข้อเสนอ กรุณาสร้างข้อเสนอใหม่"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if apply {
+		h.publishRepair(c, rp)
+	}
+	rp, _ = h.repo.GetRepair(c.Context(), rp.ID)
+	message := "ปรับตารางเวรตามข้อเสนอสำเร็จ"
+	if !apply {
+		message = "ยกเลิกข้อเสนอปรับตารางเวรสำเร็จ"
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": message, "data": repairJSON(rp)})
+}
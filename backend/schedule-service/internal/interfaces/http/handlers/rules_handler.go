@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/optimizer"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// loadRules builds the department's rule set from scheduling_priorities and its shift sequence
+// rules; on a read error the priority-service defaults are used so generation still follows the
+// standard rules
+func (h *ScheduleHandler) loadRules(c *fiber.Ctx, departmentID string) optimizer.RuleSet {
+	prios, err := h.repo.ListSchedulingPriorities(c.Context(), departmentID)
+	if err != nil {
+		log.Printf("load scheduling priorities error: %v", err)
+		prios = nil
+	}
+	rules := optimizer.BuildRules(prios)
+	seq, err := h.repo.GetSequenceRules(c.Context(), departmentID)
+	if err != nil {
+		log.Printf("load sequence rules error: %v", err)
+		return rules
+	}
+	return rules.WithSequence(seq)
+}
+
+// boundaryContext loads the neighbouring months' roster the generators and checks take as fixed
+// context; on a read error the month is handled on its own
+func (h *ScheduleHandler) boundaryContext(c *fiber.Ctx, departmentID, month string) []database.Assignment {
+	around, err := h.repo.ListStaffAssignmentsAround(c.Context(), departmentID, month, optimizer.BoundaryContextDays)
+	if err != nil {
+		log.Printf("load boundary context error: %v", err)
+		return nil
+	}
+	return around
+}
+
+// GetSchedulingRules returns the rules the generators will apply for a department
+func (h *ScheduleHandler) GetSchedulingRules(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	rules := h.loadRules(c, departmentID)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงกฎการจัดเวรสำเร็จ", "data": rules.List()})
+}
+
+// violationMessages explains optimizer.Violation reasons to users
+var violationMessages = map[string]string{
+	"leave":                   "ติดวันลา",
+	"consecutive-day":         "ทำงานติดต่อกันเกินจำนวนวันที่กำหนด",
+	"consecutive-night":       "เวรดึกติดต่อกันเกินจำนวนที่กำหนด",
+	"invalid-shift":           "ไม่พบเวรหรือเวลาเวรไม่ถูกต้อง",
+	"overlap":                 "เวลาเวรซ้อนกับเวรอื่น",
+	"exceed-contiguous-hours": "ชั่วโมงทำงานต่อเนื่องเกินกำหนด",
+	"exceed-total-hours":      "ชั่วโมงทำงานรวมในเดือนเกินกำหนด",
+	"insufficient-rest":       "เวลาพักระหว่างเวรน้อยกว่าที่กำหนด",
+	"forbidden-transition":    "ลำดับเวรนี้ไม่อนุญาต (เช่น ดึกต่อเช้า)",
+	"missing-skill":           "เวรจะขาดผู้มีทักษะที่เวรนี้กำหนด",
+	"lapsed-certification":    "ใบรับรองทักษะที่เวรนี้กำหนดหมดอายุแล้ว",
+}
+
+func violationsJSON(vs []optimizer.Violation) []fiber.Map {
+	out := make([]fiber.Map, 0, len(vs))
+	for _, v := range vs {
+		out = append(out, fiber.Map{"staffId": v.StaffID, "shiftId": v.ShiftID, "date": v.Date, "reason": v.Reason, "message": violationMessages[v.Reason]})
+	}
+	return out
+}
+
+// validateChanges checks manual edits (remove then add) against the department's hard rules,
+// month by month, using the same tracker as the generators
+func (h *ScheduleHandler) validateChanges(c *fiber.Ctx, departmentID string, remove, add []database.Assignment) ([]optimizer.Violation, error) {
+	return h.validateAgainst(c, departmentID, h.repo.ListStaffAssignmentsForMonth, remove, add)
+}
+
+// validatePublishedChanges checks swap edits against the published roster, which is what they are
+// applied to, rather than a draft loaded in the working copy
+func (h *ScheduleHandler) validatePublishedChanges(c *fiber.Ctx, departmentID string, remove, add []database.Assignment) ([]optimizer.Violation, error) {
+	return h.validateAgainst(c, departmentID, h.repo.ListPublishedStaffAssignments, remove, add)
+}
+
+func (h *ScheduleHandler) validateAgainst(c *fiber.Ctx, departmentID string, roster func(ctx context.Context, departmentID, month string) ([]database.Assignment, error), remove, add []database.Assignment) ([]optimizer.Violation, error) {
+	byMonth := map[string][2][]database.Assignment{}
+	for _, a := range remove {
+		m := byMonth[monthOf(a.ScheduleDate)]
+		m[0] = append(m[0], a)
+		byMonth[monthOf(a.ScheduleDate)] = m
+	}
+	for _, a := range add {
+		m := byMonth[monthOf(a.ScheduleDate)]
+		m[1] = append(m[1], a)
+		byMonth[monthOf(a.ScheduleDate)] = m
+	}
+	var out []optimizer.Violation
+	for month, ch := range byMonth {
+		in, err := h.monthInput(c, departmentID, month)
+		if err != nil {
+			return nil, err
+		}
+		current, err := roster(c.Context(), departmentID, month)
+		if err != nil {
+			return nil, err
+		}
+		vs, err := optimizer.ValidateChanges(in, current, ch[0], ch[1])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vs...)
+	}
+	return out, nil
+}
+
+// monthInput loads what the rule checks need for a department+month
+func (h *ScheduleHandler) monthInput(c *fiber.Ctx, departmentID, month string) (optimizer.Input, error) {
+	shifts, err := h.repo.ListShifts(c.Context(), departmentID)
+	if err != nil {
+		return optimizer.Input{}, err
+	}
+	staff, err := h.repo.ListDepartmentStaff(c.Context(), departmentID)
+	if err != nil {
+		return optimizer.Input{}, err
+	}
+	working, _ := h.repo.ListWorkingDays(c.Context(), departmentID)
+	holidays, _ := h.repo.ListHolidaysForMonth(c.Context(), departmentID, month)
+	leaves, _ := h.repo.ListLeavesForMonth(c.Context(), departmentID, month)
+	skills, _ := h.repo.ListCompetencies(c.Context(), departmentID)
+	return optimizer.Input{
+		DepartmentID: departmentID,
+		Month:        month,
+		Shifts:       shifts,
+		Staff:        staff,
+		WorkingDays:  working,
+		Holidays:     holidays,
+		Leaves:       leaves,
+		Rules:        h.loadRules(c, departmentID),
+		Competencies: skills,
+		Context:      h.boundaryContext(c, departmentID, month),
+	}, nil
+}
+
+func monthOf(date string) string {
+	if len(date) >= 7 {
+		return date[:7]
+	}
+	return date
+}
+
+func sequenceRulesJSON(s database.SequenceRules) fiber.Map {
+	pairs := make([]fiber.Map, 0, len(s.ForbiddenTransitions))
+	for _, t := range s.ForbiddenTransitions {
+		pairs = append(pairs, fiber.Map{"from": t.From, "to": t.To})
+	}
+	return fiber.Map{"departmentId": s.DepartmentID, "minRestHours": s.MinRestHours, "forbiddenTransitions": pairs}
+}
+
+// GetSequenceRules returns the department's minimum rest between shifts and forbidden shift transitions
+func (h *ScheduleHandler) GetSequenceRules(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	seq, err := h.repo.GetSequenceRules(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงกฎลำดับเวรสำเร็จ", "data": sequenceRulesJSON(seq)})
+}
+
+// SaveSequenceRules sets the minimum rest hours between the end of one shift and the start of the
+// next and the shift-type transitions (e.g. night -> morning) not allowed within a day. Every
+// generator and the manual edit checks enforce them as hard rules.
+func (h *ScheduleHandler) SaveSequenceRules(c *fiber.Ctx) error {
+	var req struct {
+		DepartmentID         string `json:"departmentId"`
+		MinRestHours         int    `json:"minRestHours"`
+		ForbiddenTransitions []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"forbiddenTransitions"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId"})
+	}
+	if req.MinRestHours < 0 || req.MinRestHours > 24 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "minRestHours ต้องอยู่ระหว่าง 0-24"})
+	}
+	seq := database.SequenceRules{DepartmentID: req.DepartmentID, MinRestHours: req.MinRestHours, ForbiddenTransitions: []database.ShiftTransition{}}
+	seen := map[database.ShiftTransition]bool{}
+	for _, t := range req.ForbiddenTransitions {
+		tr := database.ShiftTransition{From: strings.ToLower(strings.TrimSpace(t.From)), To: strings.ToLower(strings.TrimSpace(t.To))}
+		if !shiftTypes[tr.From] || !shiftTypes[tr.To] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ประเภทเวรต้องเป็น morning, afternoon, night หรือ overtime"})
+		}
+		if !seen[tr] {
+			seen[tr] = true
+			seq.ForbiddenTransitions = append(seq.ForbiddenTransitions, tr)
+		}
+	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	before, err := h.repo.GetSequenceRules(c.Context(), req.DepartmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	userID, _ := c.Locals("userID").(string)
+	seq.UpdatedBy = sql.NullString{String: userID, Valid: userID != ""}
+	if err := h.repo.SaveSequenceRules(c.Context(), seq); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.sequence_rules.save")
+	rec.Resource("department", req.DepartmentID)
+	rec.Before(sequenceRulesJSON(before))
+	rec.After(sequenceRulesJSON(seq))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "บันทึกกฎลำดับเวรสำเร็จ", "data": sequenceRulesJSON(seq)})
+}
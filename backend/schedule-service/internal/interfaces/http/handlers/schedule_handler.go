@@ -1,16 +1,23 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
-	"sort"
 	"strings"
 	"time"
 
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/audit"
 	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/infrastructure/entitlements"
+	"nurseshift/schedule-service/internal/infrastructure/eventbus"
+	"nurseshift/schedule-service/internal/infrastructure/realtime"
 	"nurseshift/schedule-service/internal/optimizer"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,11 +25,30 @@ import (
 )
 
 // ScheduleHandler handles schedule-related HTTP requests
-type ScheduleHandler struct{ repo *database.ScheduleRepository }
+type ScheduleHandler struct {
+	repo         *database.ScheduleRepository
+	entitlements *entitlements.Checker
+	access       *access.Guard
+	audit        *audit.Repository
+	events       *realtime.Hub
+	inbox        *eventbus.Inbox
+}
 
 // NewScheduleHandler creates a new schedule handler
-func NewScheduleHandler(repo *database.ScheduleRepository) *ScheduleHandler {
-	return &ScheduleHandler{repo: repo}
+func NewScheduleHandler(repo *database.ScheduleRepository, checker *entitlements.Checker, guard *access.Guard, auditRepo *audit.Repository, hub *realtime.Hub, inbox *eventbus.Inbox) *ScheduleHandler {
+	return &ScheduleHandler{repo: repo, entitlements: checker, access: guard, audit: auditRepo, events: hub, inbox: inbox}
+}
+
+// requireFeature answers with a package denial unless the caller's package includes f
+func (h *ScheduleHandler) requireFeature(c *fiber.Ctx, f entitlements.Feature) (bool, error) {
+	if isAdmin(c) {
+		return true, nil
+	}
+	userID, _ := c.Locals("userID").(string)
+	if err := h.entitlements.CheckFeature(c.Context(), userID, f); err != nil {
+		return false, entitlementError(c, err)
+	}
+	return true, nil
 }
 
 // GetAvailableStaff returns staff who are not assigned to the given date/shift and have no time overlap
@@ -34,6 +60,9 @@ func (h *ScheduleHandler) GetAvailableStaff(c *fiber.Ctx) error {
 	if departmentID == "" || date == "" || shiftID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId, date และ shiftId"})
 	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
 
 	// load shift to get interval
 	shifts, err := h.repo.ListShifts(c.Context(), departmentID)
@@ -116,12 +145,41 @@ func (h *ScheduleHandler) GetAvailableStaff(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงรายชื่อที่พร้อมขึ้นเวร", "data": out})
 }
 
-// GetSchedules returns schedules for authenticated user's departments
+// GetSchedules returns schedules for authenticated user's departments; staff without a
+// departmentId and month get their own published assignments
 func (h *ScheduleHandler) GetSchedules(c *fiber.Ctx) error {
-	_ = c.Locals("userID").(string)
+	userID := c.Locals("userID").(string)
 	departmentId := c.Query("departmentId")
 	month := c.Query("month")
 
+	// staff only see the published version; drafts stay with the department's managers
+	if !h.canManage(c, departmentId) {
+		if departmentId == "" && month == "" {
+			own, err := h.repo.ListPublishedForUser(c.Context(), userID)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+			}
+			out := make([]fiber.Map, 0, len(own))
+			for _, a := range own {
+				out = append(out, snapshotJSON(a.DepartmentID, []database.VersionAssignment{a})...)
+			}
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงข้อมูลตารางเวรสำเร็จ", "data": out})
+		}
+		if departmentId == "" || month == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId และ month"})
+		}
+		if ok, err := h.authorize(c, departmentId, access.ActionRead); !ok {
+			return err
+		}
+		published, versioned, err := h.publishedSchedules(c, departmentId, month)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		if versioned {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงข้อมูลตารางเวรสำเร็จ", "data": published})
+		}
+	}
+
 	// 1) พยายามแบบ staff-based ก่อน
 	itemsStaff, err := h.repo.ListWithStaff(c.Context(), departmentId, month)
 	if err != nil {
@@ -182,37 +240,27 @@ func (h *ScheduleHandler) GetSchedules(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงข้อมูลตารางเวรสำเร็จ", "data": out})
 }
 
-// CalendarMeta returns working/holiday flags for given month
-func (h *ScheduleHandler) CalendarMeta(c *fiber.Ctx) error {
-	// ไม่ต้องบังคับมี userID เพราะเส้นนี้ไม่ได้ติด middleware เสมอไป
-	if v := c.Locals("userID"); v != nil {
-		if _, ok := v.(string); !ok {
-			// ignore silently; this endpoint does not require auth
-		}
-	}
-	departmentId := c.Query("departmentId")
-	month := c.Query("month")
-	if departmentId == "" || month == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId และ month"})
-	}
+// calendarDay is one day of a month with the department's working/holiday flags
+type calendarDay struct {
+	Date    time.Time
+	Working bool
+	Holiday bool
+}
 
-	working, err := h.repo.ListWorkingDays(c.Context(), departmentId)
+// monthCalendar returns every day of a YYYY-MM month flagged from working_days and holidays
+func (h *ScheduleHandler) monthCalendar(ctx context.Context, departmentID, month string) ([]calendarDay, error) {
+	t, err := time.Parse("2006-01", month)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		return nil, err
 	}
-	holidays, err := h.repo.ListHolidaysForMonth(c.Context(), departmentId, month)
+	working, err := h.repo.ListWorkingDays(ctx, departmentID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		return nil, err
 	}
-
-	t, err := time.Parse("2006-01", month)
+	holidays, err := h.repo.ListHolidaysForMonth(ctx, departmentID, month)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบเดือนไม่ถูกต้อง"})
+		return nil, err
 	}
-	year, m, _ := t.Date()
-	first := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
-	next := first.AddDate(0, 1, 0)
-	days := int(next.Sub(first).Hours() / 24)
 	isHoliday := func(d time.Time) bool {
 		ds := d.Format("2006-01-02")
 		for _, h := range holidays {
@@ -222,18 +270,44 @@ func (h *ScheduleHandler) CalendarMeta(c *fiber.Ctx) error {
 		}
 		return false
 	}
-	data := []fiber.Map{}
-	for day := 1; day <= days; day++ {
-		d := time.Date(year, m, day, 0, 0, 0, 0, time.UTC)
-		wd := int(d.Weekday())
+	var out []calendarDay
+	for d := t; d.Month() == t.Month(); d = d.AddDate(0, 0, 1) {
 		w := true
-		if v, ok := working[wd]; ok {
+		if v, ok := working[int(d.Weekday())]; ok {
 			w = v
 		}
+		out = append(out, calendarDay{Date: d, Working: w, Holiday: isHoliday(d)})
+	}
+	return out, nil
+}
+
+// CalendarMeta returns working/holiday flags for given month
+func (h *ScheduleHandler) CalendarMeta(c *fiber.Ctx) error {
+	// ไม่ต้องบังคับมี userID เพราะเส้นนี้ไม่ได้ติด middleware เสมอไป
+	if v := c.Locals("userID"); v != nil {
+		if _, ok := v.(string); !ok {
+			// ignore silently; this endpoint does not require auth
+		}
+	}
+	departmentId := c.Query("departmentId")
+	month := c.Query("month")
+	if departmentId == "" || month == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId และ month"})
+	}
+
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบเดือนไม่ถูกต้อง"})
+	}
+	days, err := h.monthCalendar(c.Context(), departmentId, month)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	data := []fiber.Map{}
+	for _, d := range days {
 		data = append(data, fiber.Map{
-			"date":      d.Format("2006-01-02"),
-			"isWorking": w,
-			"isHoliday": isHoliday(d),
+			"date":      d.Date.Format("2006-01-02"),
+			"isWorking": d.Working,
+			"isHoliday": d.Holiday,
 		})
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ปฏิทินการทำงาน", "data": data})
@@ -265,11 +339,18 @@ func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
 		})
 	}
 
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+
 	id := uuid.New().String()
 	rec := &database.ScheduleRecord{ID: id, DepartmentID: req.DepartmentID, UserID: userID, ShiftID: "", ScheduleDate: req.Date, Status: "assigned"}
 	if err := h.repo.Create(c.Context(), rec); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
+	auditOf(c).Action("schedule.create")
+	auditOf(c).Resource("schedule", id)
+	h.publishSlots(c, req.DepartmentID, realtime.EventAssignmentAdded, "create", []rosterSlot{{ID: id, Date: req.Date, UserID: userID}})
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "message": "สร้างตารางเวรสำเร็จ", "data": rec})
 }
 
@@ -279,6 +360,10 @@ func (h *ScheduleHandler) GetScheduleStats(c *fiber.Ctx) error {
 	departmentId := c.Query("departmentId")
 	month := c.Query("month")
 
+	if ok, err := h.authorize(c, departmentId, access.ActionRead); !ok {
+		return err
+	}
+
 	withRole, err := h.repo.ListWithRole(c.Context(), departmentId, month)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
@@ -315,6 +400,21 @@ func (h *ScheduleHandler) GetSchedule(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบข้อมูลตารางเวรที่ระบุ"})
 }
 
+// loadScheduleRecord fetches a schedules row, writing the error response when it cannot
+func (h *ScheduleHandler) loadScheduleRecord(c *fiber.Ctx, id string) (*database.Assignment, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบข้อมูลตารางเวรที่ระบุ"})
+	}
+	a, err := h.repo.GetScheduleRecord(c.Context(), id)
+	if err == sql.ErrNoRows {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบข้อมูลตารางเวรที่ระบุ"})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return a, nil
+}
+
 // UpdateSchedule updates schedule information
 func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
@@ -342,9 +442,26 @@ func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
 	if v, ok := req["shiftId"].(string); ok {
 		shiftPtr = &v
 	}
+	before, resp := h.loadScheduleRecord(c, scheduleID)
+	if before == nil {
+		return resp
+	}
+	if ok, err := h.authorize(c, before.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	rec := auditOf(c)
+	rec.Action("schedule.update")
+	rec.Department(before.DepartmentID)
+	rec.Before(assignmentSnapshot(before))
 	if err := h.repo.Update(c.Context(), scheduleID, statusPtr, notesPtr, shiftPtr); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
+	if after, err := h.repo.GetScheduleRecord(c.Context(), scheduleID); err == nil {
+		rec.After(assignmentSnapshot(after))
+		if after.ShiftID != before.ShiftID {
+			h.publishMove(c, before.DepartmentID, "update", slotOf(before), slotOf(after))
+		}
+	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "อัปเดตตารางเวรสำเร็จ", "data": fiber.Map{"id": scheduleID, "updatedAt": time.Now()}})
 }
 
@@ -354,9 +471,21 @@ func (h *ScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
 	scheduleID := c.Params("id")
 
 	_ = userID
+	before, resp := h.loadScheduleRecord(c, scheduleID)
+	if before == nil {
+		return resp
+	}
+	if ok, err := h.authorize(c, before.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	rec := auditOf(c)
+	rec.Action("schedule.delete")
+	rec.Department(before.DepartmentID)
+	rec.Before(assignmentSnapshot(before))
 	if err := h.repo.Delete(c.Context(), scheduleID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
+	h.publishSlots(c, before.DepartmentID, realtime.EventAssignmentRemoved, "delete", []rosterSlot{slotOf(before)})
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ลบตารางเวรสำเร็จ"})
 }
 
@@ -373,6 +502,9 @@ func (h *ScheduleHandler) Health(c *fiber.Ctx) error {
 func (h *ScheduleHandler) ListShifts(c *fiber.Ctx) error {
 	_ = c.Locals("userID").(string)
 	departmentId := c.Query("departmentId")
+	if ok, err := h.authorize(c, departmentId, access.ActionRead); !ok {
+		return err
+	}
 	items, err := h.repo.ListShifts(c.Context(), departmentId)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
@@ -394,15 +526,28 @@ func (h *ScheduleHandler) ListShifts(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงข้อมูลกะสำเร็จ", "data": out})
 }
 
-// AutoGenerate creates schedules using simple backend logic
+// AutoGenerate creates schedules using simple backend logic.
+// Body field "fairnessWindowMonths" sets how many earlier months of the fairness ledger to balance
+// against (default 6, 0 = this month only), as for OptimizeGenerate.
 func (h *ScheduleHandler) AutoGenerate(c *fiber.Ctx) error {
 	var req struct {
-		DepartmentID string `json:"departmentId"`
-		Month        string `json:"month"` // YYYY-MM
+		DepartmentID         string `json:"departmentId"`
+		Month                string `json:"month"` // YYYY-MM
+		FairnessWindowMonths *int   `json:"fairnessWindowMonths"`
 	}
 	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" || req.Month == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId และ month"})
 	}
+	window, ok := generationWindow(req.FairnessWindowMonths)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "fairnessWindowMonths ต้องอยู่ระหว่าง 0-" + fmtInt(MaxFairnessWindowMonths)})
+	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	if ok, err := h.requireFeature(c, entitlements.FeatureAutoSchedule); !ok {
+		return err
+	}
 
 	shifts, err := h.repo.ListShifts(c.Context(), req.DepartmentID)
 	if err != nil {
@@ -445,117 +590,23 @@ func (h *ScheduleHandler) AutoGenerate(c *fiber.Ctx) error {
 
 	log.Printf("=== PARSED: year=%d, month=%d, days=%d ===", year, month, days)
 
-	// If there are existing schedules for this month and department, clear them before re-generate
-	if err := h.repo.DeleteByDepartmentAndMonth(c.Context(), req.DepartmentID, req.Month); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
-	}
-
 	// Use Enhanced Dynamic Priority Algorithm instead of old algorithm
-	return h.runEnhancedAlgorithm(c, req, nurses, assistants, shifts, staffList, year, month, days)
+	return h.runEnhancedAlgorithm(c, req, window, nurses, assistants, shifts, staffList, year, month, days)
 }
 
-// runEnhancedAlgorithm implements the Enhanced Dynamic Priority Algorithm
+// runEnhancedAlgorithm implements the Enhanced Dynamic Priority Algorithm; window is how many earlier
+// months of the fairness ledger it balances against
 func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
-	DepartmentID string `json:"departmentId"`
-	Month        string `json:"month"`
-}, nurses, assistants []string, shifts []database.ShiftRecord, staffList []database.DepartmentStaff, year int, month time.Month, days int) error {
+	DepartmentID         string `json:"departmentId"`
+	Month                string `json:"month"` // YYYY-MM
+	FairnessWindowMonths *int   `json:"fairnessWindowMonths"`
+}, window int, nurses, assistants []string, shifts []database.ShiftRecord, staffList []database.DepartmentStaff, year int, month time.Month, days int) error {
 
 	log.Printf("=== START ENHANCED ALGORITHM: %s-%s ===", req.DepartmentID, req.Month)
 
 	// Enhanced Dynamic Priority Algorithm with Progressive Relaxation
 	var items []database.Assignment
 	assignmentCount := map[string]int{}
-	lastAssignedDate := map[string]time.Time{}
-
-	// Track assigned time intervals per staff per date to allow multiple non-overlapping shifts
-	// intervals are in minutes from 00:00 of the day; end may exceed 1440 for overnight shifts
-	assignedIntervals := map[string]map[string][][2]int{}
-
-	// helpers for time/intervals
-	parseHM := func(hm string) (int, bool) {
-		// hm format HH:MM
-		if len(hm) < 4 {
-			return 0, false
-		}
-		var hh, mm int
-		if _, err := fmt.Sscanf(hm, "%d:%d", &hh, &mm); err != nil {
-			return 0, false
-		}
-		return hh*60 + mm, true
-	}
-	shiftInterval := func(sh database.ShiftRecord) (int, int, bool) {
-		start, ok1 := parseHM(sh.StartTime)
-		end, ok2 := parseHM(sh.EndTime)
-		if !ok1 || !ok2 {
-			return 0, 0, false
-		}
-		if end <= start { // overnight or 00:00 next day
-			end += 24 * 60
-		}
-		return start, end, true
-	}
-	overlaps := func(aStart, aEnd, bStart, bEnd int) bool {
-		return aStart < bEnd && bStart < aEnd
-	}
-	mergeAndMaxContiguous := func(ivals [][2]int) int {
-		if len(ivals) == 0 {
-			return 0
-		}
-		sort.Slice(ivals, func(i, j int) bool { return ivals[i][0] < ivals[j][0] })
-		curS, curE := ivals[0][0], ivals[0][1]
-		maxDur := curE - curS
-		for i := 1; i < len(ivals); i++ {
-			s, e := ivals[i][0], ivals[i][1]
-			if s <= curE { // overlap or touch → contiguous
-				if e > curE {
-					curE = e
-				}
-			} else { // gap → new block
-				if curE-curS > maxDur {
-					maxDur = curE - curS
-				}
-				curS, curE = s, e
-			}
-		}
-		if curE-curS > maxDur {
-			maxDur = curE - curS
-		}
-		return maxDur
-	}
-	// read max contiguous-hours policy (default 16h)
-	maxContiguousHours := 16
-	if v, err := h.repo.GetPriorityValue(c.Context(), req.DepartmentID, "ชั่วโมงติดต่อกันสูงสุด"); err == nil && v.Valid {
-		if v.Int64 > 0 && v.Int64 <= 24 {
-			maxContiguousHours = int(v.Int64)
-		}
-	}
-	maxContiguousMinutes := maxContiguousHours * 60
-
-	log.Printf("=== SETUP COMPLETE: maxContiguousHours=%d ===", maxContiguousHours)
-
-	canAssignShift := func(staffID string, d time.Time, sh database.ShiftRecord) bool {
-		start, end, ok := shiftInterval(sh)
-		if !ok {
-			return false
-		}
-		dateStr := d.Format("2006-01-02")
-		if assignedIntervals[staffID] == nil {
-			assignedIntervals[staffID] = map[string][][2]int{}
-		}
-		existing := assignedIntervals[staffID][dateStr]
-		for _, iv := range existing {
-			if overlaps(iv[0], iv[1], start, end) {
-				return false // overlap not allowed
-			}
-		}
-		// check contiguous hours after adding this shift
-		merged := append(append([][2]int{}, existing...), [2]int{start, end})
-		if mergeAndMaxContiguous(merged) > maxContiguousMinutes {
-			return false
-		}
-		return true
-	}
-
 	log.Printf("=== START BACKEND ALGORITHM: %s-%s ===", req.DepartmentID, req.Month)
 
 	// pull working days & holidays & leaves first
@@ -587,135 +638,66 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 		return false
 	}
 
-	// Get priorities from database to create dynamic algorithm
-	type Priority struct {
-		Name   string
-		Order  int
-		Value  interface{}
-		Active bool
+	// Rule engine: scheduling_priorities from priority-service become hard/soft constraints weighted by priority_order
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range shifts {
+		shiftByID[sh.ID] = sh
+	}
+	rules := h.loadRules(c, req.DepartmentID)
+	prefs, _ := h.repo.ListPreferencesForMonth(c.Context(), req.DepartmentID, req.Month)
+	skills, _ := h.repo.ListCompetencies(c.Context(), req.DepartmentID)
+	in := optimizer.Input{
+		DepartmentID: req.DepartmentID,
+		Month:        req.Month,
+		Shifts:       shifts,
+		Staff:        staffList,
+		WorkingDays:  workingDays,
+		Holidays:     holidays,
+		Leaves:       leaves,
+		Rules:        rules,
+		Preferences:  prefs,
+		Competencies: skills,
+		Context:      h.boundaryContext(c, req.DepartmentID, req.Month),
+		Ledger:       h.ledgerForGeneration(c, req.DepartmentID, req.Month, window),
+	}
+	tracker, err := optimizer.NewRuleTracker(in)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบเดือนไม่ถูกต้อง"})
+	}
+	for _, r := range tracker.Rules() {
+		log.Printf("=== RULE %d: %s (%s) value=%d hard=%t weight=%.0f ===", r.Order, r.Name, r.Kind, r.Value, r.Hard, r.Weight)
 	}
 
-	priorities := []Priority{}
-	// This should be replaced with actual API call to priority service
-	// For now, hardcode based on the current setup
-	priorities = append(priorities, Priority{"วันที่ขอหยุด", 1, nil, true})
-	priorities = append(priorities, Priority{"จำนวนเวรเท่ากันในแต่ละประเภท", 2, 1, true})
-	priorities = append(priorities, Priority{"จำนวนชั่วโมงการทำงานทั้งหมด", 3, 20, true})
-	priorities = append(priorities, Priority{"จำนวนเวรติดต่อกัน", 4, 5, true})
-
-	log.Printf("=== DYNAMIC PRIORITIES LOADED: %d priorities ===", len(priorities))
-
-	// Dynamic pick function that respects priority order
+	// Pick the cheapest eligible candidate, first among those holding a skill the shift is still short of.
+	// relaxLevel 1 enforces every rule; each further level turns the next lowest-priority hard rule into a
+	// weighted penalty. Leave and overlapping shifts are never relaxed.
 	pickWithPriorities := func(cands []string, date time.Time, sh database.ShiftRecord, relaxLevel int) (string, bool) {
+		tracker.Relax(relaxLevel - 1)
+		defer tracker.Relax(0)
+
 		best := ""
-		bestScore := int(^uint(0) >> 1)
+		bestCovers := false
+		bestScore := math.MaxFloat64
 
 		log.Printf("=== PICK: candidates=%d, relaxLevel=%d ===", len(cands), relaxLevel)
 
 		for _, uid := range cands {
-			score := 0
-			canAssign := true
-			skipReasons := []string{}
-
-			// Apply priority constraints based on relaxLevel
-			switch {
-			case relaxLevel <= 1: // Strictest - respect all priorities
-				// Priority 1: วันที่ขอหยุด (NEVER relax this)
-				if isOnLeave(uid, date) {
-					skipReasons = append(skipReasons, "on-leave")
-					canAssign = false
-					break
-				}
-
-				// Priority 2: จำนวนเวรเท่ากันในแต่ละประเภท
-				score += assignmentCount[uid] * 100
-
-				// Priority 3: จำนวนชั่วโมงการทำงานทั้งหมด (time overlap)
-				if !canAssignShift(uid, date, sh) {
-					skipReasons = append(skipReasons, "time-overlap")
-					canAssign = false
-					break
-				}
-
-				// Priority 4: จำนวนเวรติดต่อกัน
-				if d, exists := lastAssignedDate[uid]; exists {
-					if d.AddDate(0, 0, 1).Equal(date) {
-						skipReasons = append(skipReasons, "consecutive-day")
-						score += 1000 // High penalty
-					}
-				}
-
-			case relaxLevel <= 3: // Relax consecutive days
-				// Priority 1: วันที่ขอหยุด (NEVER relax)
-				if isOnLeave(uid, date) {
-					skipReasons = append(skipReasons, "on-leave")
-					canAssign = false
-					break
-				}
-
-				// Priority 2: จำนวนเวรเท่ากันในแต่ละประเภท
-				score += assignmentCount[uid] * 100
-
-				// Priority 3: จำนวนชั่วโมงการทำงานทั้งหมด (time overlap)
-				if !canAssignShift(uid, date, sh) {
-					skipReasons = append(skipReasons, "time-overlap")
-					canAssign = false
-					break
-				}
-
-				// Priority 4: จำนวนเวรติดต่อกัน (RELAXED - just add penalty)
-				if d, exists := lastAssignedDate[uid]; exists {
-					if d.AddDate(0, 0, 1).Equal(date) {
-						score += 500 // Lower penalty
-					}
-				}
-
-			case relaxLevel <= 6: // Relax time overlap
-				// Priority 1: วันที่ขอหยุด (NEVER relax)
-				if isOnLeave(uid, date) {
-					skipReasons = append(skipReasons, "on-leave")
-					canAssign = false
-					break
-				}
-
-				// Priority 2: จำนวนเวรเท่ากันในแต่ละประเภท
-				score += assignmentCount[uid] * 50
-
-				// Priority 3: จำนวนชั่วโมงการทำงานทั้งหมด (RELAXED - just penalty)
-				if !canAssignShift(uid, date, sh) {
-					score += 200 // Add penalty but don't block
-				}
-
-				// Priority 4: จำนวนเวรติดต่อกัน (RELAXED)
-				if d, exists := lastAssignedDate[uid]; exists {
-					if d.AddDate(0, 0, 1).Equal(date) {
-						score += 100 // Small penalty
-					}
-				}
-
-			default: // relaxLevel > 6 - Emergency mode, only respect leaves
-				// Priority 1: วันที่ขอหยุด (NEVER relax)
-				if isOnLeave(uid, date) {
-					skipReasons = append(skipReasons, "on-leave")
-					canAssign = false
-					break
-				}
-
-				// All other priorities ignored - just try to balance assignments
-				score += assignmentCount[uid] * 10
+			if reason := tracker.Reason(uid, date, sh); reason != "" {
+				log.Printf("=== PICK: Skipped %s (reason: %s) ===", uid, reason)
+				continue
 			}
-
-			if canAssign && score < bestScore {
+			covers := tracker.CoversSkill(uid, date, sh)
+			score := float64(assignmentCount[uid])*100 + tracker.Cost(uid, date, sh)
+			if (covers && !bestCovers) || (covers == bestCovers && score < bestScore) {
+				bestCovers = covers
 				bestScore = score
 				best = uid
-				log.Printf("=== PICK: New best candidate %s (score=%d) ===", uid, score)
-			} else if !canAssign {
-				log.Printf("=== PICK: Skipped %s (reasons: %v) ===", uid, skipReasons)
+				log.Printf("=== PICK: New best candidate %s (score=%.0f) ===", uid, score)
 			}
 		}
 
 		if best != "" {
-			log.Printf("=== PICK: Selected %s (score=%d, relaxLevel=%d) ===", best, bestScore, relaxLevel)
+			log.Printf("=== PICK: Selected %s (score=%.0f, relaxLevel=%d) ===", best, bestScore, relaxLevel)
 		} else {
 			log.Printf("=== PICK: No candidates available at relaxLevel %d ===", relaxLevel)
 		}
@@ -725,6 +707,55 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 
 	// (duplicate code removed - already defined above)
 
+	// Generation report: unmet slots with each candidate and the rule that blocked them
+	staffName := map[string]string{}
+	for _, s := range staffList {
+		staffName[s.ID] = s.Name
+	}
+	report := optimizer.Report{Generator: "enhanced", DepartmentID: req.DepartmentID, Month: req.Month, Unmet: []optimizer.UnmetSlot{}, Rules: rules.List()}
+	blockReason := func(uid string, d time.Time, sh database.ShiftRecord) string {
+		if r := tracker.Reason(uid, d, sh); r != "" {
+			return r
+		}
+		return "unknown"
+	}
+	// shortfalls are reported once the rebalance pass has settled who works where
+	type shortfall struct {
+		dateStr string
+		d       time.Time
+		sh      database.ShiftRecord
+		role    string
+		missing int
+		cands   []string
+	}
+	var shortfalls []shortfall
+	addUnmet := func(dateStr string, d time.Time, sh database.ShiftRecord, role string, missing int, cands []string) {
+		inSlot := map[string]bool{}
+		for _, it := range items {
+			if it.ScheduleDate == dateStr && it.ShiftID == sh.ID {
+				inSlot[it.StaffID] = true
+			}
+		}
+		u := optimizer.UnmetSlot{Date: dateStr, ShiftID: sh.ID, ShiftName: sh.Name}
+		if n := len(report.Unmet); n > 0 && report.Unmet[n-1].Date == dateStr && report.Unmet[n-1].ShiftID == sh.ID {
+			u = report.Unmet[n-1]
+			report.Unmet = report.Unmet[:n-1]
+		}
+		if role == "assistant" {
+			u.MissingAssistants += missing
+		} else {
+			u.MissingNurses += missing
+		}
+		for _, uid := range cands {
+			if inSlot[uid] {
+				continue
+			}
+			u.Candidates = append(u.Candidates, optimizer.BlockedCandidate{StaffID: uid, StaffName: staffName[uid], Role: role, Reason: blockReason(uid, d, sh)})
+		}
+		report.Unmet = append(report.Unmet, u)
+		report.UnmetTotal += missing
+	}
+
 	log.Printf("=== STARTING MAIN ASSIGNMENT LOOP: %d days ===", days)
 
 	for day := 1; day <= days; day++ {
@@ -774,21 +805,16 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 					log.Printf("=== NURSES: Assigned %s at relax level %d ===", sid, relaxLevel)
 					items = append(items, database.Assignment{ID: uuid.New().String(), DepartmentID: req.DepartmentID, StaffID: sid, ShiftID: sh.ID, ScheduleDate: dateStr, Status: "assigned"})
 					assignmentCount[sid]++
-					lastAssignedDate[sid] = d
-					// record interval for this day
-					if assignedIntervals[sid] == nil {
-						assignedIntervals[sid] = map[string][][2]int{}
-					}
-					if s, e, ok := shiftInterval(sh); ok {
-						assignedIntervals[sid][dateStr] = append(assignedIntervals[sid][dateStr], [2]int{s, e})
-					}
+					tracker.Place(sid, d, sh)
 					nurseAssigned++
 				}
 			}
 
+			report.RequiredSlots += sh.RequiredNurse + sh.RequiredAsst
 			if nurseAssigned < sh.RequiredNurse {
 				log.Printf("=== WARNING: %s %s still needs %d nurses ===",
 					dateStr, sh.Name, sh.RequiredNurse-nurseAssigned)
+				shortfalls = append(shortfalls, shortfall{dateStr, d, sh, "nurse", sh.RequiredNurse - nurseAssigned, nurses})
 			}
 
 			// assistants - try with progressive relaxation of priorities
@@ -811,13 +837,7 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 					log.Printf("=== ASSISTANTS: Assigned %s at relax level %d ===", sid, relaxLevel)
 					items = append(items, database.Assignment{ID: uuid.New().String(), DepartmentID: req.DepartmentID, StaffID: sid, ShiftID: sh.ID, ScheduleDate: dateStr, Status: "assigned"})
 					assignmentCount[sid]++
-					lastAssignedDate[sid] = d
-					if assignedIntervals[sid] == nil {
-						assignedIntervals[sid] = map[string][][2]int{}
-					}
-					if s, e, ok := shiftInterval(sh); ok {
-						assignedIntervals[sid][dateStr] = append(assignedIntervals[sid][dateStr], [2]int{s, e})
-					}
+					tracker.Place(sid, d, sh)
 					assistantAssigned++
 				}
 			}
@@ -825,18 +845,14 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 			if assistantAssigned < sh.RequiredAsst {
 				log.Printf("=== WARNING: %s %s still needs %d assistants ===",
 					dateStr, sh.Name, sh.RequiredAsst-assistantAssigned)
+				shortfalls = append(shortfalls, shortfall{dateStr, d, sh, "assistant", sh.RequiredAsst - assistantAssigned, assistants})
 			}
 		}
 	}
 
 	// Post-balance pass: ลดความต่างจำนวนเวรต่อคน (ตามตำแหน่ง) ให้ใกล้กันมากที่สุดภายใต้กฏ
-	// อ่านค่าจาก scheduling_priorities ถ้ามี (priority ชื่อ: "จำนวนเวรเท่าเทียมในแต่ละประเภท")
-	maxDiffAllowed := 1
-	if v, err := h.repo.GetPriorityValue(c.Context(), req.DepartmentID, "จำนวนเวรเท่าเทียมในแต่ละประเภท"); err == nil && v.Valid {
-		if v.Int64 >= 0 && v.Int64 <= 5 {
-			maxDiffAllowed = int(v.Int64)
-		}
-	}
+	// อ่านค่าจาก scheduling_priorities (priority: "จำนวนเวรเท่ากันในแต่ละประเภท")
+	maxDiffAllowed := rules.MaxDiffAllowed(1)
 
 	// สร้าง map ช่วยเหลือ
 	staffRole := map[string]string{}
@@ -860,17 +876,8 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 		return d
 	}
 	canAssignOn := func(staffID string, dateStr string) bool {
-		// ห้ามชนวันเดียวกัน และห้ามติดกันวันก่อน/วันถัดไป
-		if assignedDates[staffID] != nil && assignedDates[staffID][dateStr] {
-			return false
-		}
-		d := parseDate(dateStr)
-		prev := d.AddDate(0, 0, -1).Format("2006-01-02")
-		next := d.AddDate(0, 0, 1).Format("2006-01-02")
-		if (assignedDates[staffID] != nil && assignedDates[staffID][prev]) || (assignedDates[staffID] != nil && assignedDates[staffID][next]) {
-			return false
-		}
-		return true
+		// ห้ามชนวันเดียวกัน (วันติดกัน/ชั่วโมงต่อเนื่องตรวจโดย rule tracker ต่อเวร)
+		return assignedDates[staffID] == nil || !assignedDates[staffID][dateStr]
 	}
 	// index ของ items ต่อวัน เพื่อหาความขัดแย้ง
 	dayToIndices := map[string][]int{}
@@ -942,7 +949,18 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 					if isOnLeave(lowID, parseDate(dateStr)) {
 						continue
 					}
+					// ตรวจกฏจาก scheduling_priorities
+					sh := shiftByID[items[idx].ShiftID]
+					if tracker.Reason(lowID, parseDate(dateStr), sh) != "" {
+						continue
+					}
+					// ห้ามย้ายจนเวรขาดผู้มีทักษะที่กำหนด
+					if !tracker.KeepsSkills(highID, lowID, parseDate(dateStr), sh) {
+						continue
+					}
 					// ย้าย
+					tracker.Unplace(highID, parseDate(dateStr), sh)
+					tracker.Place(lowID, parseDate(dateStr), sh)
 					items[idx].StaffID = lowID
 					// ปรับชุดข้อมูลช่วย
 					assignmentCount[highID]--
@@ -969,6 +987,11 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 	// Rebalance สำหรับ nurse และ assistant แยกกัน
 	rebalanceRole("nurse")
 	rebalanceRole("assistant")
+	for _, sf := range shortfalls {
+		addUnmet(sf.dateStr, sf.d, sf.sh, sf.role, sf.missing, sf.cands)
+	}
+	report.AddSkillGaps(tracker.SkillGaps())
+	report.Lapsed = optimizer.LapsedAssignments(in, items)
 
 	// Enhanced Algorithm complete - save results directly
 	log.Printf("=== ENHANCED ALGORITHM COMPLETE: Total items=%d ===", len(items))
@@ -1040,10 +1063,15 @@ func (h *ScheduleHandler) runEnhancedAlgorithm(c *fiber.Ctx, req struct {
 		tryFill("nurse", nurses)
 		tryFill("assistant", assistants)
 	*/ // End of old algorithm comment
-	if err := h.repo.BulkInsertAssignmentsStaff(c.Context(), items); err != nil {
+	report.FilledSlots = report.RequiredSlots - report.UnmetTotal
+	report.Satisfaction = tracker.Satisfaction(staffList, shifts, items)
+	runID := h.saveGenerationRun(c, report)
+	// generated schedules become a new draft; manual edits are kept as their own version
+	version, err := h.saveDraft(c, req.DepartmentID, req.Month, "enhanced", runID, items)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สร้างตารางเวรอัตโนมัติสำเร็จ", "data": fiber.Map{"inserted": len(items)}})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สร้างตารางเวรอัตโนมัติสำเร็จ", "data": fiber.Map{"inserted": len(items), "runId": runID, "versionId": version.ID, "versionNo": version.VersionNo, "report": report}})
 }
 
 // AIGenerate delegates schedule generation to Gemini Flash
@@ -1055,6 +1083,12 @@ func (h *ScheduleHandler) AIGenerate(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" || req.Month == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId และ month"})
 	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	if ok, err := h.requireFeature(c, entitlements.FeatureAISchedule); !ok {
+		return err
+	}
 	apiKey := os.Getenv("GEMINI_API_KEY")
 	if apiKey == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ไม่พบ GEMINI_API_KEY ใน environment"})
@@ -1064,7 +1098,14 @@ func (h *ScheduleHandler) AIGenerate(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
-	users, err := h.repo.ListDepartmentUsers(c.Context(), req.DepartmentID)
+	if err := h.repo.EnsureStaffSchedulingSchema(c.Context()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	staffList, err := h.repo.ListDepartmentStaff(c.Context(), req.DepartmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	skills, err := h.repo.ListCompetencies(c.Context(), req.DepartmentID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
@@ -1072,17 +1113,27 @@ func (h *ScheduleHandler) AIGenerate(c *fiber.Ctx) error {
 	// Build prompt with strict JSON instruction
 	prompt := strings.Builder{}
 	prompt.WriteString("You are a scheduling assistant for hospital nurse shifts.\n")
-	prompt.WriteString("Return ONLY valid JSON with this schema: {\"assignments\":[{\"userId\":string,\"shiftId\":string,\"date\":\"YYYY-MM-DD\"}]}\n")
-	prompt.WriteString("Users (id, role):\n")
-	for _, u := range users {
-		prompt.WriteString(u.UserID + "," + u.DepartmentRole + "\n")
+	prompt.WriteString("Return ONLY valid JSON with this schema: {\"assignments\":[{\"staffId\":string,\"shiftId\":string,\"date\":\"YYYY-MM-DD\"}]}\n")
+	prompt.WriteString("Staff (id, position):\n")
+	for _, s := range staffList {
+		prompt.WriteString(s.ID + "," + s.Position + "\n")
 	}
 	prompt.WriteString("Shifts (id,name,type,start,end,needNurse,needAssistant):\n")
 	for _, s := range shifts {
 		prompt.WriteString(s.ID + "," + s.Name + "," + s.Type + "," + s.StartTime + "," + s.EndTime + "," + fmtInt(s.RequiredNurse) + "," + fmtInt(s.RequiredAsst) + "\n")
 	}
+	if len(skills.Requirements) > 0 {
+		prompt.WriteString("Shift skill requirements (shiftId,skillId,minimum holders per day):\n")
+		for _, q := range skills.Requirements {
+			prompt.WriteString(q.ShiftID + "," + q.SkillID + "," + fmtInt(q.MinCount) + "\n")
+		}
+		prompt.WriteString("Staff skills (staffId,skillId,valid until YYYY-MM-DD or empty when it does not expire):\n")
+		for _, ss := range skills.StaffSkills {
+			prompt.WriteString(ss.StaffID + "," + ss.SkillID + "," + ss.ExpiresOn.String + "\n")
+		}
+	}
 	prompt.WriteString("Target month: " + req.Month + "\n")
-	prompt.WriteString("Constraints: balance total hours and contiguous days, respect staff role requirements, fill all required positions per shift per day.\n")
+	prompt.WriteString("Constraints: balance total hours and contiguous days, respect staff role requirements, fill all required positions per shift per day, and staff every shift with enough holders of each required skill whose certification is valid on the date.\n")
 
 	payload := map[string]any{
 		"contents": []map[string]any{{
@@ -1119,7 +1170,7 @@ func (h *ScheduleHandler) AIGenerate(c *fiber.Ctx) error {
 	jsonStr := extractJSON(textOut)
 	var parsed struct {
 		Assignments []struct {
-			UserID  string `json:"userId"`
+			StaffID string `json:"staffId"`
 			ShiftID string `json:"shiftId"`
 			Date    string `json:"date"`
 		} `json:"assignments"`
@@ -1129,23 +1180,64 @@ func (h *ScheduleHandler) AIGenerate(c *fiber.Ctx) error {
 	}
 	var items []database.Assignment
 	for _, a := range parsed.Assignments {
-		items = append(items, database.Assignment{ID: uuid.New().String(), DepartmentID: req.DepartmentID, UserID: a.UserID, ShiftID: a.ShiftID, ScheduleDate: a.Date, Status: "assigned"})
+		items = append(items, database.Assignment{ID: uuid.New().String(), DepartmentID: req.DepartmentID, StaffID: a.StaffID, ShiftID: a.ShiftID, ScheduleDate: a.Date, Status: "assigned"})
+	}
+	// the model is not bound by the skill matrix: a roster it leaves short of a required skill, or staffs on a
+	// lapsed certification, is refused rather than saved as a draft
+	workingDays, _ := h.repo.ListWorkingDays(c.Context(), req.DepartmentID)
+	holidays, _ := h.repo.ListHolidaysForMonth(c.Context(), req.DepartmentID, req.Month)
+	report, err := checkSkills(optimizer.Input{
+		DepartmentID: req.DepartmentID,
+		Month:        req.Month,
+		Shifts:       shifts,
+		Staff:        staffList,
+		WorkingDays:  workingDays,
+		Holidays:     holidays,
+		Competencies: skills,
+	}, items)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบเดือนไม่ถูกต้อง"})
+	}
+	report.Generator = "ai"
+	if report.SkillShortTotal > 0 || len(report.Lapsed) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"status": "error", "message": "ตารางเวรจาก AI ไม่ครอบคลุมทักษะที่เวรกำหนด จึงไม่ได้บันทึก", "data": fiber.Map{"report": report}})
 	}
-	if err := h.repo.BulkInsertAssignments(c.Context(), items); err != nil {
+	version, err := h.saveDraft(c, req.DepartmentID, req.Month, "ai", "", items)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สร้างตารางเวรด้วย AI สำเร็จ", "data": fiber.Map{"inserted": len(items)}})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สร้างตารางเวรด้วย AI สำเร็จ", "data": fiber.Map{"inserted": len(items), "versionId": version.ID, "versionNo": version.VersionNo}})
 }
 
-// OptimizeGenerate creates schedules using internal Go optimizer (fairness-weighted greedy)
+// OptimizeGenerate creates schedules using internal Go optimizer.
+// Body field "solver" selects the backend ("greedy" default, or "local-search"),
+// "timeLimitMs" sets the time budget for anytime solvers and "fairnessWindowMonths" how many
+// earlier months of the fairness ledger to balance against (default 6, 0 = this month only).
 func (h *ScheduleHandler) OptimizeGenerate(c *fiber.Ctx) error {
 	var req struct {
-		DepartmentID string `json:"departmentId"`
-		Month        string `json:"month"`
+		DepartmentID         string `json:"departmentId"`
+		Month                string `json:"month"`
+		Solver               string `json:"solver"`
+		TimeLimitMs          int    `json:"timeLimitMs"`
+		FairnessWindowMonths *int   `json:"fairnessWindowMonths"`
 	}
 	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" || req.Month == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId และ month"})
 	}
+	window, ok := generationWindow(req.FairnessWindowMonths)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "fairnessWindowMonths ต้องอยู่ระหว่าง 0-" + fmtInt(MaxFairnessWindowMonths)})
+	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	if ok, err := h.requireFeature(c, entitlements.FeatureAutoSchedule); !ok {
+		return err
+	}
+	solver, err := optimizer.NewSolver(req.Solver, time.Duration(req.TimeLimitMs)*time.Millisecond)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ไม่รู้จัก solver ที่ระบุ", "error": err.Error()})
+	}
 
 	shifts, err := h.repo.ListShifts(c.Context(), req.DepartmentID)
 	if err != nil {
@@ -1161,14 +1253,11 @@ func (h *ScheduleHandler) OptimizeGenerate(c *fiber.Ctx) error {
 	working, _ := h.repo.ListWorkingDays(c.Context(), req.DepartmentID)
 	holidays, _ := h.repo.ListHolidaysForMonth(c.Context(), req.DepartmentID, req.Month)
 	leaves, _ := h.repo.ListLeavesForMonth(c.Context(), req.DepartmentID, req.Month)
-	maxDiffAllowed := 1
-	if v, err := h.repo.GetPriorityValue(c.Context(), req.DepartmentID, "จำนวนเวรเท่าเทียมในแต่ละประเภท"); err == nil && v.Valid {
-		if v.Int64 >= 0 && v.Int64 <= 5 {
-			maxDiffAllowed = int(v.Int64)
-		}
-	}
+	rules := h.loadRules(c, req.DepartmentID)
+	prefs, _ := h.repo.ListPreferencesForMonth(c.Context(), req.DepartmentID, req.Month)
+	skills, _ := h.repo.ListCompetencies(c.Context(), req.DepartmentID)
 
-	out, err := optimizer.SolveMonth(optimizer.Input{
+	res, err := solver.Solve(c.Context(), optimizer.Input{
 		DepartmentID:   req.DepartmentID,
 		Month:          req.Month,
 		Shifts:         shifts,
@@ -1176,19 +1265,40 @@ func (h *ScheduleHandler) OptimizeGenerate(c *fiber.Ctx) error {
 		WorkingDays:    working,
 		Holidays:       holidays,
 		Leaves:         leaves,
-		MaxDiffAllowed: maxDiffAllowed,
+		MaxDiffAllowed: rules.MaxDiffAllowed(1),
+		Rules:          rules,
+		Preferences:    prefs,
+		Competencies:   skills,
+		Context:        h.boundaryContext(c, req.DepartmentID, req.Month),
+		Ledger:         h.ledgerForGeneration(c, req.DepartmentID, req.Month, window),
 	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
 
-	if err := h.repo.DeleteByDepartmentAndMonth(c.Context(), req.DepartmentID, req.Month); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
-	}
-	if err := h.repo.BulkInsertAssignmentsStaff(c.Context(), out); err != nil {
+	report := res.Report(req.DepartmentID, req.Month)
+	report.Rules = rules.List()
+	runID := h.saveGenerationRun(c, report)
+	version, err := h.saveDraft(c, req.DepartmentID, req.Month, "optimizer:"+res.Solver, runID, res.Assignments)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สร้างตารางเวรด้วย Optimizer (Go) สำเร็จ", "data": fiber.Map{"inserted": len(out)}})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "สร้างตารางเวรด้วย Optimizer (Go) สำเร็จ", "data": fiber.Map{
+		"inserted":   len(res.Assignments),
+		"solver":     res.Solver,
+		"objective":  res.Objective,
+		"lowerBound": res.LowerBound,
+		"gap":        res.Gap,
+		"optimal":    res.Optimal,
+		"unmetTotal": res.UnmetTotal,
+		"lapsed":     len(res.Lapsed),
+		"iterations": res.Iterations,
+		"elapsedMs":  res.Elapsed.Milliseconds(),
+		"runId":      runID,
+		"versionId":  version.ID,
+		"versionNo":  version.VersionNo,
+		"report":     report,
+	}})
 }
 
 func fmtInt(v int) string { return fmt.Sprintf("%d", v) }
@@ -1227,6 +1337,9 @@ func (h *ScheduleHandler) EditShift(c *fiber.Ctx) error {
 			"message": "ต้องระบุ date, shiftId และ departmentId",
 		})
 	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
 
 	// Replace all staff for this shift (like CreateSchedule)
 	// First, remove all existing assignments for this shift
@@ -1238,6 +1351,19 @@ func (h *ScheduleHandler) EditShift(c *fiber.Ctx) error {
 		})
 	}
 
+	var previous []string
+	for _, assignment := range existingAssignments {
+		if assignment.ShiftID == req.ShiftID {
+			previous = append(previous, assignment.StaffID)
+		}
+	}
+	rec := auditOf(c)
+	rec.Action("schedule.edit_shift")
+	rec.Resource("shift", req.ShiftID)
+	rec.Department(req.DepartmentID)
+	rec.Before(fiber.Map{"date": req.Date, "shiftId": req.ShiftID, "staff": previous})
+	rec.After(fiber.Map{"date": req.Date, "shiftId": req.ShiftID, "nurses": req.Nurses, "assistants": req.Assistants})
+
 	// Remove existing assignments for this shift
 	for _, assignment := range existingAssignments {
 		if assignment.ShiftID == req.ShiftID {
@@ -1285,6 +1411,10 @@ func (h *ScheduleHandler) EditShift(c *fiber.Ctx) error {
 		}
 	}
 
+	staff := make([]string, 0, len(req.Nurses)+len(req.Assistants))
+	staff = append(append(staff, req.Nurses...), req.Assistants...)
+	h.publishShiftEdit(c, req.DepartmentID, req.Date, req.ShiftID, previous, staff)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "แก้ไขเวรสำเร็จ",
@@ -1317,6 +1447,9 @@ func (h *ScheduleHandler) CheckShiftOverlap(c *fiber.Ctx) error {
 			"message": "ต้องระบุ departmentId, date, shiftId และ staffId",
 		})
 	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionRead); !ok {
+		return err
+	}
 
 	// Check if staff is already assigned to this shift
 	existingAssignments, err := h.repo.ListAssignmentsWithShiftForDate(c.Context(), req.DepartmentID, req.Date)
@@ -1338,8 +1471,28 @@ func (h *ScheduleHandler) CheckShiftOverlap(c *fiber.Ctx) error {
 		}
 	}
 
-	// For now, assume staff can be assigned (no time overlap check implemented)
-	// TODO: Implement time overlap check with other shifts
+	// Check the department's hard rules (leave, overlap, contiguous hours, consecutive days/nights)
+	violations, err := h.validateChanges(c, req.DepartmentID, nil, []database.Assignment{{
+		DepartmentID: req.DepartmentID,
+		StaffID:      req.StaffID,
+		ShiftID:      req.ShiftID,
+		ScheduleDate: req.Date,
+	}})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถตรวจสอบกฎการจัดเวรได้: " + err.Error(),
+		})
+	}
+	if len(violations) > 0 {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":     "success",
+			"canAssign":  false,
+			"message":    violationMessages[violations[0].Reason],
+			"violations": violationsJSON(violations),
+		})
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":    "success",
 		"canAssign": true,
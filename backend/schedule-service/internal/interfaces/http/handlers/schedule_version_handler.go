@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+	"strings"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/infrastructure/realtime"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// backupWorkingCopy saves unsaved manual edits of a month as a draft before the live schedules are replaced
+func (h *ScheduleHandler) backupWorkingCopy(c *fiber.Ctx, departmentID, month string) error {
+	live, err := h.repo.ListWorkingCopy(c.Context(), departmentID, month)
+	if err != nil || len(live) == 0 {
+		return err
+	}
+	versions, err := h.repo.ListVersions(c.Context(), departmentID, month)
+	if err != nil {
+		return err
+	}
+	for i, v := range versions {
+		// the newest version and the published one are what the working copy normally mirrors
+		if i > 0 && v.Status != database.VersionPublished {
+			continue
+		}
+		snap, err := h.repo.ListVersionAssignments(c.Context(), v.ID)
+		if err != nil {
+			return err
+		}
+		if database.SameSnapshot(live, snap) {
+			return nil
+		}
+	}
+	v := &database.ScheduleVersion{
+		DepartmentID: departmentID,
+		Month:        month,
+		Source:       "manual",
+		Notes:        sql.NullString{String: "บันทึกอัตโนมัติจากตารางเวรที่แก้ไขก่อนถูกแทนที่", Valid: true},
+	}
+	if uid, ok := c.Locals("userID").(string); ok && uid != "" {
+		v.CreatedBy = sql.NullString{String: uid, Valid: true}
+	}
+	return h.repo.CreateVersion(c.Context(), v, live, false)
+}
+
+// saveDraft stores generated assignments as a new draft version and loads it into the working copy
+func (h *ScheduleHandler) saveDraft(c *fiber.Ctx, departmentID, month, source, runID string, items []database.Assignment) (*database.ScheduleVersion, error) {
+	if err := h.backupWorkingCopy(c, departmentID, month); err != nil {
+		return nil, err
+	}
+	v := &database.ScheduleVersion{
+		DepartmentID:    departmentID,
+		Month:           month,
+		Source:          source,
+		GenerationRunID: sql.NullString{String: runID, Valid: runID != ""},
+	}
+	if uid, ok := c.Locals("userID").(string); ok && uid != "" {
+		v.CreatedBy = sql.NullString{String: uid, Valid: true}
+	}
+	if err := h.repo.CreateVersion(c.Context(), v, database.AssignmentsToSnapshot(items), true); err != nil {
+		return nil, err
+	}
+	h.publishRegenerated(c, v)
+	return v, nil
+}
+
+func versionJSON(v *database.ScheduleVersion) fiber.Map {
+	out := fiber.Map{
+		"id":              v.ID,
+		"departmentId":    v.DepartmentID,
+		"month":           v.Month,
+		"versionNo":       v.VersionNo,
+		"status":          v.Status,
+		"source":          v.Source,
+		"generationRunId": nullStr(v.GenerationRunID),
+		"restoredFrom":    nullStr(v.RestoredFrom),
+		"notes":           nullStr(v.Notes),
+		"assignmentCount": v.AssignmentCount,
+		"createdBy":       nullStr(v.CreatedBy),
+		"createdAt":       v.CreatedAt,
+		"publishedBy":     nullStr(v.PublishedBy),
+		"publishedAt":     nil,
+	}
+	if v.PublishedAt.Valid {
+		out["publishedAt"] = v.PublishedAt.Time
+	}
+	return out
+}
+
+func nullStr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	v := s.String
+	return &v
+}
+
+// snapshotJSON renders snapshot rows in the same shape GetSchedules returns
+func snapshotJSON(departmentID string, items []database.VersionAssignment) []fiber.Map {
+	out := make([]fiber.Map, 0, len(items))
+	for _, a := range items {
+		role := "nurse"
+		if strings.Contains(strings.ToLower(a.StaffRole), "assist") || strings.Contains(a.StaffRole, "ผู้ช่วย") {
+			role = "assistant"
+		}
+		out = append(out, fiber.Map{
+			"departmentId":   departmentID,
+			"staffId":        nullStr(a.StaffID),
+			"userId":         nullStr(a.UserID),
+			"shiftId":        a.ShiftID,
+			"scheduleDate":   a.ScheduleDate,
+			"status":         a.Status,
+			"notes":          nullStr(a.Notes),
+			"departmentRole": role,
+			"userName":       a.StaffName,
+		})
+	}
+	return out
+}
+
+// publishedSchedules returns what staff may see for a month: the published version, or the live
+// schedules when the month predates versioning
+func (h *ScheduleHandler) publishedSchedules(c *fiber.Ctx, departmentID, month string) ([]fiber.Map, bool, error) {
+	has, err := h.repo.HasVersions(c.Context(), departmentID, month)
+	if err != nil || !has {
+		return nil, false, err
+	}
+	v, err := h.repo.GetPublishedVersion(c.Context(), departmentID, month)
+	if err == sql.ErrNoRows {
+		return []fiber.Map{}, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	items, err := h.repo.ListVersionAssignments(c.Context(), v.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return snapshotJSON(departmentID, items), true, nil
+}
+
+// ListScheduleVersions lists versions of a department+month
+func (h *ScheduleHandler) ListScheduleVersions(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	month := c.Query("month")
+	if departmentID == "" || month == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId และ month"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	versions, err := h.repo.ListVersions(c.Context(), departmentID, month)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	manage := h.canManage(c, departmentID)
+	out := make([]fiber.Map, 0, len(versions))
+	for i := range versions {
+		if !manage && versions[i].Status != database.VersionPublished {
+			continue
+		}
+		out = append(out, versionJSON(&versions[i]))
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงรายการเวอร์ชันตารางเวรสำเร็จ", "data": out})
+}
+
+// CreateScheduleVersion saves the current working copy of a month as a new draft
+func (h *ScheduleHandler) CreateScheduleVersion(c *fiber.Ctx) error {
+	var req struct {
+		DepartmentID string `json:"departmentId"`
+		Month        string `json:"month"`
+		Notes        string `json:"notes"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" || req.Month == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId และ month"})
+	}
+	if !h.canManage(c, req.DepartmentID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์จัดการตารางเวรของแผนกนี้"})
+	}
+	live, err := h.repo.ListWorkingCopy(c.Context(), req.DepartmentID, req.Month)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	v := &database.ScheduleVersion{
+		DepartmentID: req.DepartmentID,
+		Month:        req.Month,
+		Source:       "manual",
+		Notes:        sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+		CreatedBy:    sql.NullString{String: c.Locals("userID").(string), Valid: true},
+	}
+	if err := h.repo.CreateVersion(c.Context(), v, live, false); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "message": "บันทึกร่างตารางเวรสำเร็จ", "data": versionJSON(v)})
+}
+
+// loadVersion fetches a version and checks the caller may see it
+func (h *ScheduleHandler) loadVersion(c *fiber.Ctx, id string, manageOnly bool) (*database.ScheduleVersion, error) {
+	v, err := h.repo.GetVersion(c.Context(), id)
+	if err == sql.ErrNoRows {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบเวอร์ชันตารางเวร"})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if (manageOnly || v.Status != database.VersionPublished) && !h.canManage(c, v.DepartmentID) {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์เข้าถึงเวอร์ชันตารางเวรนี้"})
+	}
+	if ok, err := h.authorize(c, v.DepartmentID, access.ActionRead); !ok {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetScheduleVersion returns a version with its assignments
+func (h *ScheduleHandler) GetScheduleVersion(c *fiber.Ctx) error {
+	v, resp := h.loadVersion(c, c.Params("versionId"), false)
+	if v == nil {
+		return resp
+	}
+	items, err := h.repo.ListVersionAssignments(c.Context(), v.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	data := versionJSON(v)
+	data["assignments"] = snapshotJSON(v.DepartmentID, items)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงเวอร์ชันตารางเวรสำเร็จ", "data": data})
+}
+
+// UpdateScheduleVersionStatus moves a version between draft, under_review and archived
+func (h *ScheduleHandler) UpdateScheduleVersionStatus(c *fiber.Ctx) error {
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Status == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ status"})
+	}
+	v, resp := h.loadVersion(c, c.Params("versionId"), true)
+	if v == nil {
+		return resp
+	}
+	if req.Status == database.VersionPublished {
+		return h.PublishScheduleVersion(c)
+	}
+	if err := h.repo.UpdateVersionStatus(c.Context(), v.ID, req.Status); err != nil {
+		if err == database.ErrVersionTransition {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "ไม่สามารถเปลี่ยนสถานะจาก " + v.Status + " เป็น " + req.Status + " ได้"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	v.Status = req.Status
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "เปลี่ยนสถานะเวอร์ชันสำเร็จ", "data": versionJSON(v)})
+}
+
+// PublishScheduleVersion publishes a version: staff see it and the working copy is replaced by it
+func (h *ScheduleHandler) PublishScheduleVersion(c *fiber.Ctx) error {
+	v, resp := h.loadVersion(c, c.Params("versionId"), true)
+	if v == nil {
+		return resp
+	}
+	if !database.CanTransitionVersion(v.Status, database.VersionPublished) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "เผยแพร่ได้เฉพาะเวอร์ชันร่างหรือรอตรวจสอบ"})
+	}
+	if err := h.backupWorkingCopy(c, v.DepartmentID, v.Month); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if err := h.repo.PublishVersion(c.Context(), v.ID, c.Locals("userID").(string)); err != nil {
+		if err == database.ErrVersionTransition {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "เผยแพร่ได้เฉพาะเวอร์ชันร่างหรือรอตรวจสอบ"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if published, err := h.repo.GetVersion(c.Context(), v.ID); err == nil {
+		v = published
+	}
+	h.publishRoster(c, v.DepartmentID, realtime.EventVersionPublished, v.Month, versionJSON(v))
+	if _, err := h.refreshLedger(c, v.DepartmentID, v.Month); err != nil {
+		log.Printf("refresh fairness ledger error: %v", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "เผยแพร่ตารางเวรสำเร็จ", "data": versionJSON(v)})
+}
+
+// RestoreScheduleVersion copies an older version into a new draft and loads it into the working copy
+func (h *ScheduleHandler) RestoreScheduleVersion(c *fiber.Ctx) error {
+	src, resp := h.loadVersion(c, c.Params("versionId"), true)
+	if src == nil {
+		return resp
+	}
+	items, err := h.repo.ListVersionAssignments(c.Context(), src.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if err := h.backupWorkingCopy(c, src.DepartmentID, src.Month); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	v := &database.ScheduleVersion{
+		DepartmentID: src.DepartmentID,
+		Month:        src.Month,
+		Source:       "restore",
+		RestoredFrom: sql.NullString{String: src.ID, Valid: true},
+		CreatedBy:    sql.NullString{String: c.Locals("userID").(string), Valid: true},
+	}
+	if err := h.repo.CreateVersion(c.Context(), v, items, true); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	h.publishRegenerated(c, v)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "message": "กู้คืนเวอร์ชันเป็นร่างใหม่สำเร็จ", "data": versionJSON(v)})
+}
+
+// DiffScheduleVersions compares two versions (?from=&to=); to=working compares against the live schedules
+func (h *ScheduleHandler) DiffScheduleVersions(c *fiber.Ctx) error {
+	fromID, toID := c.Query("from"), c.Query("to")
+	if fromID == "" || toID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ from และ to"})
+	}
+	from, resp := h.loadVersion(c, fromID, true)
+	if from == nil {
+		return resp
+	}
+	fromItems, err := h.repo.ListVersionAssignments(c.Context(), from.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	var toInfo fiber.Map
+	var toItems []database.VersionAssignment
+	if toID == "working" {
+		toInfo = fiber.Map{"id": "working", "departmentId": from.DepartmentID, "month": from.Month}
+		toItems, err = h.repo.ListWorkingCopy(c.Context(), from.DepartmentID, from.Month)
+	} else {
+		to, resp := h.loadVersion(c, toID, true)
+		if to == nil {
+			return resp
+		}
+		if to.DepartmentID != from.DepartmentID || to.Month != from.Month {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "เปรียบเทียบได้เฉพาะเวอร์ชันของแผนกและเดือนเดียวกัน"})
+		}
+		toInfo = versionJSON(to)
+		toItems, err = h.repo.ListVersionAssignments(c.Context(), to.ID)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	changes, added, removed, unchanged := diffSnapshots(fromItems, toItems)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "เปรียบเทียบเวอร์ชันสำเร็จ", "data": fiber.Map{
+		"from":    versionJSON(from),
+		"to":      toInfo,
+		"summary": fiber.Map{"added": added, "removed": removed, "unchanged": unchanged},
+		"changes": changes,
+	}})
+}
+
+// diffSnapshots groups added/removed people per date+shift
+func diffSnapshots(from, to []database.VersionAssignment) ([]fiber.Map, int, int, int) {
+	type slotDiff struct {
+		date, shiftID  string
+		added, removed []fiber.Map
+	}
+	person := func(a database.VersionAssignment) fiber.Map {
+		return fiber.Map{"staffId": nullStr(a.StaffID), "userId": nullStr(a.UserID), "name": a.StaffName}
+	}
+	inFrom := map[string]bool{}
+	for _, a := range from {
+		inFrom[a.Key()] = true
+	}
+	inTo := map[string]bool{}
+	for _, a := range to {
+		inTo[a.Key()] = true
+	}
+	slots := map[string]*slotDiff{}
+	get := func(a database.VersionAssignment) *slotDiff {
+		k := a.ScheduleDate + "|" + a.ShiftID
+		if slots[k] == nil {
+			slots[k] = &slotDiff{date: a.ScheduleDate, shiftID: a.ShiftID, added: []fiber.Map{}, removed: []fiber.Map{}}
+		}
+		return slots[k]
+	}
+	added, removed, unchanged := 0, 0, 0
+	for _, a := range to {
+		if inFrom[a.Key()] {
+			unchanged++
+			continue
+		}
+		get(a).added = append(get(a).added, person(a))
+		added++
+	}
+	for _, a := range from {
+		if !inTo[a.Key()] {
+			get(a).removed = append(get(a).removed, person(a))
+			removed++
+		}
+	}
+	keys := make([]string, 0, len(slots))
+	for k := range slots {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]fiber.Map, 0, len(keys))
+	for _, k := range keys {
+		s := slots[k]
+		out = append(out, fiber.Map{"date": s.date, "shiftId": s.shiftID, "added": s.added, "removed": s.removed})
+	}
+	return out, added, removed, unchanged
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func versionRow(staffID, shiftID, date string) database.VersionAssignment {
+	return database.VersionAssignment{StaffID: sql.NullString{String: staffID, Valid: true}, ShiftID: shiftID, ScheduleDate: date, StaffName: staffID}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	from := []database.VersionAssignment{
+		versionRow("s1", "m", "2026-02-01"),
+		versionRow("s2", "m", "2026-02-01"),
+		versionRow("s1", "n", "2026-02-02"),
+	}
+	to := []database.VersionAssignment{
+		versionRow("s1", "m", "2026-02-01"),
+		versionRow("s3", "m", "2026-02-01"),
+		versionRow("s2", "n", "2026-02-03"),
+	}
+	changes, added, removed, unchanged := diffSnapshots(from, to)
+	if added != 2 || removed != 2 || unchanged != 1 {
+		t.Errorf("added %d removed %d unchanged %d, want 2 2 1", added, removed, unchanged)
+	}
+	want := []struct {
+		date, shift    string
+		added, removed int
+	}{
+		{"2026-02-01", "m", 1, 1},
+		{"2026-02-02", "n", 0, 1},
+		{"2026-02-03", "n", 1, 0},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v", changes)
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c["date"] != w.date || c["shiftId"] != w.shift || len(c["added"].([]fiber.Map)) != w.added || len(c["removed"].([]fiber.Map)) != w.removed {
+			t.Errorf("change %d = %+v, want %+v", i, c, w)
+		}
+	}
+	if _, a, r, u := diffSnapshots(from, from); a != 0 || r != 0 || u != len(from) {
+		t.Errorf("identical snapshots: added %d removed %d unchanged %d", a, r, u)
+	}
+}
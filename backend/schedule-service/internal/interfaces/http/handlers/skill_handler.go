@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/optimizer"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var skillCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+func skillJSON(s database.Skill) fiber.Map {
+	return fiber.Map{"id": s.ID, "departmentId": s.DepartmentID, "code": s.Code, "name": s.Name, "createdAt": s.CreatedAt}
+}
+
+func staffSkillJSON(s database.StaffSkill) fiber.Map {
+	return fiber.Map{"staffId": s.StaffID, "skillId": s.SkillID, "certifiedOn": nullStr(s.CertifiedOn), "expiresOn": nullStr(s.ExpiresOn)}
+}
+
+// optionalDate turns an optional YYYY-MM-DD into a NullString; false when it is not a date
+func optionalDate(s string) (sql.NullString, bool) {
+	if s == "" {
+		return sql.NullString{}, true
+	}
+	if _, err := time.Parse("2006-01-02", s); err != nil {
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: s, Valid: true}, true
+}
+
+// skillFor loads the :skillId of the request and checks the caller may act on its department
+func (h *ScheduleHandler) skillFor(c *fiber.Ctx, act access.Action) (*database.Skill, bool, error) {
+	skill, err := h.repo.GetSkill(c.Context(), c.Params("skillId"))
+	if err == sql.ErrNoRows {
+		return nil, false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบทักษะ"})
+	}
+	if err != nil {
+		return nil, false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if ok, err := h.authorize(c, skill.DepartmentID, act); !ok {
+		return nil, false, err
+	}
+	return skill, true, nil
+}
+
+// GetSkills returns a department's skill matrix: its skills, the certifications its staff hold and
+// how many holders each shift requires (?departmentId=)
+func (h *ScheduleHandler) GetSkills(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	if err := h.repo.FindOrCreateDefaultSkills(c.Context(), departmentID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	comp, err := h.repo.ListCompetencies(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	skills := make([]fiber.Map, 0, len(comp.Skills))
+	for _, s := range comp.Skills {
+		skills = append(skills, skillJSON(s))
+	}
+	staff := make([]fiber.Map, 0, len(comp.StaffSkills))
+	for _, s := range comp.StaffSkills {
+		staff = append(staff, staffSkillJSON(s))
+	}
+	reqs := make([]fiber.Map, 0, len(comp.Requirements))
+	for _, q := range comp.Requirements {
+		reqs = append(reqs, fiber.Map{"shiftId": q.ShiftID, "skillId": q.SkillID, "minCount": q.MinCount})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงข้อมูลทักษะสำเร็จ", "data": fiber.Map{"skills": skills, "staffSkills": staff, "requirements": reqs}})
+}
+
+// CreateSkill adds a skill to a department
+func (h *ScheduleHandler) CreateSkill(c *fiber.Ctx) error {
+	var req struct {
+		DepartmentID string `json:"departmentId"`
+		Code         string `json:"code"`
+		Name         string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.DepartmentID == "" || strings.TrimSpace(req.Name) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง ต้องระบุ departmentId, code และ name"})
+	}
+	req.Code = strings.ToLower(strings.TrimSpace(req.Code))
+	if !skillCodePattern.MatchString(req.Code) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "code ต้องเป็นตัวอักษรภาษาอังกฤษพิมพ์เล็ก ตัวเลข - หรือ _ ไม่เกิน 50 ตัว"})
+	}
+	if ok, err := h.authorize(c, req.DepartmentID, access.ActionMutate); !ok {
+		return err
+	}
+	comp, err := h.repo.ListCompetencies(c.Context(), req.DepartmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	for _, existing := range comp.Skills {
+		if existing.Code == req.Code {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "มีทักษะรหัสนี้ในแผนกแล้ว"})
+		}
+	}
+	s := &database.Skill{DepartmentID: req.DepartmentID, Code: req.Code, Name: strings.TrimSpace(req.Name)}
+	if err := h.repo.CreateSkill(c.Context(), s); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.skill.create")
+	rec.Resource("skill", s.ID)
+	rec.After(skillJSON(*s))
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "message": "เพิ่มทักษะสำเร็จ", "data": skillJSON(*s)})
+}
+
+// DeleteSkill removes a skill together with its certifications and shift requirements
+func (h *ScheduleHandler) DeleteSkill(c *fiber.Ctx) error {
+	skill, ok, err := h.skillFor(c, access.ActionMutate)
+	if !ok {
+		return err
+	}
+	if err := h.repo.DeleteSkill(c.Context(), skill.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.skill.delete")
+	rec.Resource("skill", skill.ID)
+	rec.Before(skillJSON(*skill))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ลบทักษะสำเร็จ"})
+}
+
+// SetStaffSkill records or renews a staff member's certification in a skill; expiresOn is the last
+// day it counts, left out when it does not expire
+func (h *ScheduleHandler) SetStaffSkill(c *fiber.Ctx) error {
+	var req struct {
+		CertifiedOn string `json:"certifiedOn"`
+		ExpiresOn   string `json:"expiresOn"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง"})
+	}
+	certified, ok1 := optionalDate(req.CertifiedOn)
+	expires, ok2 := optionalDate(req.ExpiresOn)
+	if !ok1 || !ok2 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "certifiedOn และ expiresOn ต้องอยู่ในรูปแบบ YYYY-MM-DD"})
+	}
+	if certified.Valid && expires.Valid && expires.String < certified.String {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "วันหมดอายุต้องไม่ก่อนวันที่ได้รับการรับรอง"})
+	}
+	skill, ok, err := h.skillFor(c, access.ActionMutate)
+	if !ok {
+		return err
+	}
+	st, err := h.repo.GetDepartmentStaff(c.Context(), c.Params("staffId"))
+	if err == sql.ErrNoRows || (err == nil && st.DepartmentID != skill.DepartmentID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบบุคลากรในแผนกนี้"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	ss := database.StaffSkill{StaffID: st.ID, SkillID: skill.ID, CertifiedOn: certified, ExpiresOn: expires}
+	if err := h.repo.SetStaffSkill(c.Context(), ss); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.staff_skill.set")
+	rec.Resource("staff", st.ID)
+	rec.After(staffSkillJSON(ss))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "บันทึกการรับรองทักษะสำเร็จ", "data": staffSkillJSON(ss)})
+}
+
+// DeleteStaffSkill removes a staff member's certification in a skill
+func (h *ScheduleHandler) DeleteStaffSkill(c *fiber.Ctx) error {
+	skill, ok, err := h.skillFor(c, access.ActionMutate)
+	if !ok {
+		return err
+	}
+	staffID := c.Params("staffId")
+	if err := h.repo.DeleteStaffSkill(c.Context(), staffID, skill.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.staff_skill.delete")
+	rec.Resource("staff", staffID)
+	rec.Before(fiber.Map{"staffId": staffID, "skillId": skill.ID})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ลบการรับรองทักษะสำเร็จ"})
+}
+
+// SetShiftSkillRequirement sets how many holders of a skill every occurrence of a shift needs;
+// minCount 0 removes the requirement. The generators treat requirements as hard coverage constraints.
+func (h *ScheduleHandler) SetShiftSkillRequirement(c *fiber.Ctx) error {
+	var req struct {
+		MinCount *int `json:"minCount"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.MinCount == nil || *req.MinCount < 0 || *req.MinCount > 50 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ minCount ระหว่าง 0-50"})
+	}
+	skill, ok, err := h.skillFor(c, access.ActionMutate)
+	if !ok {
+		return err
+	}
+	shifts, err := h.repo.ListShifts(c.Context(), skill.DepartmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	var shift *database.ShiftRecord
+	for i := range shifts {
+		if shifts[i].ID == c.Params("shiftId") {
+			shift = &shifts[i]
+		}
+	}
+	if shift == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบเวรในแผนกนี้"})
+	}
+	if *req.MinCount > shift.RequiredNurse+shift.RequiredAsst {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"status": "error", "message": "จำนวนผู้มีทักษะที่ต้องการมากกว่าจำนวนบุคลากรของเวร"})
+	}
+	q := database.ShiftSkillRequirement{ShiftID: shift.ID, SkillID: skill.ID, MinCount: *req.MinCount}
+	if err := h.repo.SetShiftSkillRequirement(c.Context(), q); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+
+	rec := auditOf(c)
+	rec.Action("schedule.shift_skill.set")
+	rec.Resource("shift", shift.ID)
+	rec.After(fiber.Map{"skillId": skill.ID, "minCount": q.MinCount})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "บันทึกทักษะที่เวรต้องการสำเร็จ", "data": fiber.Map{"shiftId": shift.ID, "skillId": skill.ID, "minCount": q.MinCount}})
+}
+
+// GetLapsedCertifications flags the month's assignments to shifts requiring a skill whose
+// certification had expired by the date (?departmentId=&month=)
+func (h *ScheduleHandler) GetLapsedCertifications(c *fiber.Ctx) error {
+	departmentID, month := c.Query("departmentId"), c.Query("month")
+	if departmentID == "" || month == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId และ month"})
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "month ต้องอยู่ในรูปแบบ YYYY-MM"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	comp, err := h.repo.ListCompetencies(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	shifts, err := h.repo.ListShifts(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	staff, err := h.repo.ListDepartmentStaff(c.Context(), departmentID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	current, err := h.repo.ListStaffAssignmentsForMonth(c.Context(), departmentID, month)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	lapsed := optimizer.LapsedAssignments(optimizer.Input{Shifts: shifts, Staff: staff, Competencies: comp}, current)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ตรวจสอบการรับรองที่หมดอายุสำเร็จ", "data": fiber.Map{"departmentId": departmentID, "month": month, "lapsed": lapsed}})
+}
+
+// checkSkills runs the skill coverage check over a finished roster of in.Month: the working days' shifts
+// short of a required skill, and the assignments on lapsed certifications
+func checkSkills(in optimizer.Input, items []database.Assignment) (optimizer.Report, error) {
+	report := optimizer.Report{DepartmentID: in.DepartmentID, Month: in.Month, Unmet: []optimizer.UnmetSlot{}}
+	tracker, err := optimizer.NewRuleTracker(in)
+	if err != nil {
+		return report, err
+	}
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range in.Shifts {
+		shiftByID[sh.ID] = sh
+	}
+	for _, a := range items {
+		sh, ok := shiftByID[a.ShiftID]
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if !ok || err != nil || a.StaffID == "" {
+			continue
+		}
+		tracker.Place(a.StaffID, d, sh)
+	}
+	report.AddSkillGaps(tracker.SkillGaps())
+	report.Lapsed = optimizer.LapsedAssignments(in, items)
+	return report, nil
+}
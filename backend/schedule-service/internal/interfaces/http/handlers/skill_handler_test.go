@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/optimizer"
+)
+
+func TestCheckSkills(t *testing.T) {
+	in := optimizer.Input{
+		DepartmentID: "dept",
+		Month:        "2026-02",
+		Shifts:       []database.ShiftRecord{{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00", RequiredNurse: 1}},
+		Staff:        []database.DepartmentStaff{{ID: "a", Name: "A", Position: "nurse"}, {ID: "b", Name: "B", Position: "nurse"}},
+		WorkingDays:  map[int]bool{0: false, 1: true, 2: true, 3: true, 4: true, 5: true, 6: false},
+		Competencies: database.Competencies{
+			Skills:       []database.Skill{{ID: "acls", Name: "ACLS"}},
+			StaffSkills:  []database.StaffSkill{{StaffID: "a", SkillID: "acls"}, {StaffID: "b", SkillID: "acls", ExpiresOn: sql.NullString{String: "2026-02-10", Valid: true}}},
+			Requirements: []database.ShiftSkillRequirement{{ShiftID: "m", SkillID: "acls", MinCount: 1}},
+		},
+	}
+	// every weekday of February 2026 (the 1st is a Sunday) staffed by a, except b on the 11th after their ACLS lapsed and nobody on the 12th
+	var items []database.Assignment
+	for day := 2; day <= 27; day++ {
+		date := fmt.Sprintf("2026-02-%02d", day)
+		switch {
+		case day%7 == 0 || day%7 == 1:
+		case day == 11:
+			items = append(items, database.Assignment{StaffID: "b", ShiftID: "m", ScheduleDate: date})
+		case day != 12:
+			items = append(items, database.Assignment{StaffID: "a", ShiftID: "m", ScheduleDate: date})
+		}
+	}
+	report, err := checkSkills(in, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SkillShortTotal != 2 || len(report.Unmet) != 2 || report.Unmet[0].Date != "2026-02-11" || report.Unmet[1].Date != "2026-02-12" {
+		t.Errorf("unmet = %+v (%d short), want the 11th and 12th short of ACLS", report.Unmet, report.SkillShortTotal)
+	}
+	if len(report.Lapsed) != 1 || report.Lapsed[0].StaffID != "b" || report.Lapsed[0].ExpiredOn != "2026-02-10" {
+		t.Errorf("lapsed = %+v, want b on the 11th", report.Lapsed)
+	}
+
+	if report, _ := checkSkills(in, nil); report.SkillShortTotal != 20 {
+		t.Errorf("an empty roster is %d short, want one ACLS holder on each of the 20 weekdays", report.SkillShortTotal)
+	}
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"nurseshift/schedule-service/internal/infrastructure/access"
+	"nurseshift/schedule-service/internal/infrastructure/database"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// staffRole maps a department_staff position to nurse/assistant the same way GetSchedules does
+func staffRole(position string) string {
+	if strings.Contains(strings.ToLower(position), "assist") || strings.Contains(position, "ผู้ช่วย") {
+		return "assistant"
+	}
+	return "nurse"
+}
+
+func swapJSON(s *database.SwapRequest) fiber.Map {
+	out := fiber.Map{
+		"id":             s.ID,
+		"departmentId":   s.DepartmentID,
+		"fromStaffId":    s.FromStaffID,
+		"fromStaffName":  s.FromStaffName,
+		"shiftId":        s.ShiftID,
+		"scheduleDate":   s.ScheduleDate,
+		"toStaffId":      nullStr(s.ToStaffID),
+		"toStaffName":    nullStr(s.ToStaffName),
+		"counterShiftId": nullStr(s.CounterShiftID),
+		"counterDate":    nullStr(s.CounterDate),
+		"status":         s.Status,
+		"notes":          nullStr(s.Notes),
+		"requestedBy":    nullStr(s.RequestedBy),
+		"decidedBy":      nullStr(s.DecidedBy),
+		"decidedAt":      nil,
+		"decisionNote":   nullStr(s.DecisionNote),
+		"createdAt":      s.CreatedAt,
+		"updatedAt":      s.UpdatedAt,
+	}
+	if s.DecidedAt.Valid {
+		out["decidedAt"] = s.DecidedAt.Time
+	}
+	return out
+}
+
+// swapChanges returns the schedules rows a swap removes and adds
+func swapChanges(s *database.SwapRequest) (remove, add []database.Assignment) {
+	remove = []database.Assignment{{DepartmentID: s.DepartmentID, StaffID: s.FromStaffID, ShiftID: s.ShiftID, ScheduleDate: s.ScheduleDate}}
+	add = []database.Assignment{{DepartmentID: s.DepartmentID, StaffID: s.ToStaffID.String, ShiftID: s.ShiftID, ScheduleDate: s.ScheduleDate}}
+	if s.CounterShiftID.Valid {
+		remove = append(remove, database.Assignment{DepartmentID: s.DepartmentID, StaffID: s.ToStaffID.String, ShiftID: s.CounterShiftID.String, ScheduleDate: s.CounterDate.String})
+		add = append(add, database.Assignment{DepartmentID: s.DepartmentID, StaffID: s.FromStaffID, ShiftID: s.CounterShiftID.String, ScheduleDate: s.CounterDate.String})
+	}
+	return remove, add
+}
+
+// loadSwap fetches a swap request, writing the error response when it cannot
+func (h *ScheduleHandler) loadSwap(c *fiber.Ctx) (*database.SwapRequest, error) {
+	s, err := h.repo.GetSwap(c.Context(), c.Params("swapId"))
+	if err == sql.ErrNoRows {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบคำขอแลกเวร"})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if ok, err := h.authorize(c, s.DepartmentID, access.ActionRead); !ok {
+		return nil, err
+	}
+	return s, nil
+}
+
+// inPublishedRoster reports whether staffID works shiftID on date in the roster staff see; only
+// those assignments can be traded
+func (h *ScheduleHandler) inPublishedRoster(c *fiber.Ctx, departmentID, staffID, shiftID, date string) (bool, error) {
+	roster, err := h.repo.ListPublishedStaffAssignments(c.Context(), departmentID, monthOf(date))
+	if err != nil {
+		return false, err
+	}
+	for _, a := range roster {
+		if a.StaffID == staffID && a.ShiftID == shiftID && a.ScheduleDate == date {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func swapErrorResponse(c *fiber.Ctx, err error) error {
+	switch err {
+	case database.ErrSwapState:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "สถานะคำขอแลกเวรไม่อนุญาตให้ดำเนินการนี้"})
+	case database.ErrSwapStale:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "เวรที่ขอแลกถูกเปลี่ยนแปลงไปแล้ว กรุณาสร้างคำขอใหม่"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+}
+
+// ListSwaps lists swap requests of a department (?departmentId=&status=)
+func (h *ScheduleHandler) ListSwaps(c *fiber.Ctx) error {
+	departmentID := c.Query("departmentId")
+	if departmentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ departmentId"})
+	}
+	if ok, err := h.authorize(c, departmentID, access.ActionRead); !ok {
+		return err
+	}
+	swaps, err := h.repo.ListSwaps(c.Context(), departmentID, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	out := make([]fiber.Map, 0, len(swaps))
+	for i := range swaps {
+		out = append(out, swapJSON(&swaps[i]))
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงรายการคำขอแลกเวรสำเร็จ", "data": out})
+}
+
+// CreateSwap offers an assignment of the published roster for trade, given as a schedules row
+// (scheduleId) or as departmentId+staffId+shiftId+scheduleDate, the shape staff see it in
+func (h *ScheduleHandler) CreateSwap(c *fiber.Ctx) error {
+	var req struct {
+		ScheduleID   string `json:"scheduleId"`
+		DepartmentID string `json:"departmentId"`
+		StaffID      string `json:"staffId"`
+		ShiftID      string `json:"shiftId"`
+		ScheduleDate string `json:"scheduleDate"`
+		Notes        string `json:"notes"`
+	}
+	if err := c.BodyParser(&req); err != nil || (req.ScheduleID == "" && (req.DepartmentID == "" || req.StaffID == "" || req.ShiftID == "" || req.ScheduleDate == "")) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ scheduleId หรือ departmentId, staffId, shiftId และ scheduleDate"})
+	}
+	a := &database.Assignment{DepartmentID: req.DepartmentID, StaffID: req.StaffID, ShiftID: req.ShiftID, ScheduleDate: req.ScheduleDate}
+	if req.ScheduleID != "" {
+		var err error
+		a, err = h.repo.GetScheduleRecord(c.Context(), req.ScheduleID)
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบเวรที่ต้องการแลก"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+	}
+	if ok, err := h.authorize(c, a.DepartmentID, access.ActionRequest); !ok {
+		return err
+	}
+	if a.StaffID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "แลกได้เฉพาะเวรที่จัดให้บุคลากรในแผนก"})
+	}
+	published, err := h.inPublishedRoster(c, a.DepartmentID, a.StaffID, a.ShiftID, a.ScheduleDate)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if !published {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "แลกได้เฉพาะเวรในตารางเวรที่เผยแพร่แล้ว"})
+	}
+	s := &database.SwapRequest{
+		DepartmentID: a.DepartmentID,
+		FromStaffID:  a.StaffID,
+		ShiftID:      a.ShiftID,
+		ScheduleDate: a.ScheduleDate,
+		Notes:        sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+		RequestedBy:  sql.NullString{String: c.Locals("userID").(string), Valid: true},
+	}
+	if err := h.repo.CreateSwap(c.Context(), s); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	s, _ = h.repo.GetSwap(c.Context(), s.ID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "message": "สร้างคำขอแลกเวรสำเร็จ", "data": swapJSON(s)})
+}
+
+// GetSwap returns a swap request with its history and, while proposed, the current rule check
+func (h *ScheduleHandler) GetSwap(c *fiber.Ctx) error {
+	s, resp := h.loadSwap(c)
+	if s == nil {
+		return resp
+	}
+	events, err := h.repo.ListSwapEvents(c.Context(), s.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	history := make([]fiber.Map, 0, len(events))
+	for _, e := range events {
+		history = append(history, fiber.Map{"id": e.ID, "action": e.Action, "actorId": nullStr(e.ActorID), "details": json.RawMessage(e.Details), "createdAt": e.CreatedAt})
+	}
+	data := swapJSON(s)
+	data["history"] = history
+	if s.Status == database.SwapProposed {
+		remove, add := swapChanges(s)
+		violations, err := h.validatePublishedChanges(c, s.DepartmentID, remove, add)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		data["violations"] = violationsJSON(violations)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงคำขอแลกเวรสำเร็จ", "data": data})
+}
+
+// ProposeSwap lets a colleague with the same role take the offer, optionally giving one of their own
+// published assignments (counterScheduleId, or counterShiftId+counterDate) in return; the resulting
+// roster must pass the hard rules
+func (h *ScheduleHandler) ProposeSwap(c *fiber.Ctx) error {
+	var req struct {
+		StaffID           string `json:"staffId"`
+		CounterScheduleID string `json:"counterScheduleId"`
+		CounterShiftID    string `json:"counterShiftId"`
+		CounterDate       string `json:"counterDate"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.StaffID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ต้องระบุ staffId"})
+	}
+	s, resp := h.loadSwap(c)
+	if s == nil {
+		return resp
+	}
+	if ok, err := h.authorize(c, s.DepartmentID, access.ActionRequest); !ok {
+		return err
+	}
+	if s.Status != database.SwapOpen && s.Status != database.SwapProposed {
+		return swapErrorResponse(c, database.ErrSwapState)
+	}
+	if req.StaffID == s.FromStaffID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ไม่สามารถแลกเวรกับตนเองได้"})
+	}
+	from, err := h.repo.GetDepartmentStaff(c.Context(), s.FromStaffID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	to, err := h.repo.GetDepartmentStaff(c.Context(), req.StaffID)
+	if err == sql.ErrNoRows || (err == nil && to.DepartmentID != s.DepartmentID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ไม่พบบุคลากรนี้ในแผนก"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if staffRole(from.Position) != staffRole(to.Position) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "แลกเวรได้เฉพาะบุคลากรตำแหน่งเดียวกัน"})
+	}
+
+	proposal := *s
+	proposal.ToStaffID = sql.NullString{String: to.ID, Valid: true}
+	proposal.CounterShiftID, proposal.CounterDate = sql.NullString{}, sql.NullString{}
+	if req.CounterScheduleID != "" {
+		counter, err := h.repo.GetScheduleRecord(c.Context(), req.CounterScheduleID)
+		if err == sql.ErrNoRows || (err == nil && (counter.StaffID != to.ID || counter.DepartmentID != s.DepartmentID)) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "เวรที่เสนอแลกต้องเป็นเวรของผู้รับแลกในแผนกเดียวกัน"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		req.CounterShiftID, req.CounterDate = counter.ShiftID, counter.ScheduleDate
+	}
+	if req.CounterShiftID != "" {
+		published, err := h.inPublishedRoster(c, s.DepartmentID, to.ID, req.CounterShiftID, req.CounterDate)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		if !published {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "เวรที่เสนอแลกต้องเป็นเวรของผู้รับแลกในตารางเวรที่เผยแพร่แล้ว"})
+		}
+		proposal.CounterShiftID = sql.NullString{String: req.CounterShiftID, Valid: true}
+		proposal.CounterDate = sql.NullString{String: req.CounterDate, Valid: true}
+	}
+
+	remove, add := swapChanges(&proposal)
+	violations, err := h.validatePublishedChanges(c, s.DepartmentID, remove, add)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if len(violations) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "การแลกเวรนี้ขัดกับกฎการจัดเวร", "violations": violationsJSON(violations)})
+	}
+	if err := h.repo.ProposeSwap(c.Context(), s.ID, to.ID, proposal.CounterShiftID.String, proposal.CounterDate.String, c.Locals("userID").(string)); err != nil {
+		return swapErrorResponse(c, err)
+	}
+	s, _ = h.repo.GetSwap(c.Context(), s.ID)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "เสนอรับแลกเวรสำเร็จ รอหัวหน้าพยาบาลอนุมัติ", "data": swapJSON(s)})
+}
+
+type swapDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+// ApproveSwap re-validates a proposed swap against the published roster and publishes it with the swap applied
+func (h *ScheduleHandler) ApproveSwap(c *fiber.Ctx) error {
+	var req swapDecisionRequest
+	_ = c.BodyParser(&req)
+	s, resp := h.loadSwap(c)
+	if s == nil {
+		return resp
+	}
+	if !h.canManage(c, s.DepartmentID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "เฉพาะหัวหน้าแผนกเท่านั้นที่อนุมัติการแลกเวรได้"})
+	}
+	if s.Status != database.SwapProposed {
+		return swapErrorResponse(c, database.ErrSwapState)
+	}
+	remove, add := swapChanges(s)
+	violations, err := h.validatePublishedChanges(c, s.DepartmentID, remove, add)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if len(violations) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"status": "error", "message": "การแลกเวรนี้ขัดกับกฎการจัดเวร", "violations": violationsJSON(violations)})
+	}
+	if err := h.repo.ApproveSwap(c.Context(), s.ID, c.Locals("userID").(string), req.Note); err != nil {
+		return swapErrorResponse(c, err)
+	}
+	for i := range remove {
+		h.publishMove(c, s.DepartmentID, "swap", slotOf(&remove[i]), slotOf(&add[i]))
+	}
+	s, _ = h.repo.GetSwap(c.Context(), s.ID)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "อนุมัติการแลกเวรสำเร็จ", "data": swapJSON(s)})
+}
+
+// RejectSwap rejects a proposed swap
+func (h *ScheduleHandler) RejectSwap(c *fiber.Ctx) error {
+	return h.closeSwap(c, database.SwapRejected, "ปฏิเสธการแลกเวรสำเร็จ")
+}
+
+// CancelSwap withdraws an open or proposed swap
+func (h *ScheduleHandler) CancelSwap(c *fiber.Ctx) error {
+	return h.closeSwap(c, database.SwapCancelled, "ยกเลิกคำขอแลกเวรสำเร็จ")
+}
+
+func (h *ScheduleHandler) closeSwap(c *fiber.Ctx, status, message string) error {
+	var req swapDecisionRequest
+	_ = c.BodyParser(&req)
+	s, resp := h.loadSwap(c)
+	if s == nil {
+		return resp
+	}
+	userID := c.Locals("userID").(string)
+	if status == database.SwapRejected || s.RequestedBy.String != userID {
+		if !h.canManage(c, s.DepartmentID) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์ดำเนินการกับคำขอแลกเวรนี้"})
+		}
+	}
+	if err := h.repo.CloseSwap(c.Context(), s.ID, status, userID, req.Note); err != nil {
+		return swapErrorResponse(c, err)
+	}
+	s, _ = h.repo.GetSwap(c.Context(), s.ID)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": message, "data": swapJSON(s)})
+}
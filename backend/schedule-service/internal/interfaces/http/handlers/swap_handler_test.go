@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+	"nurseshift/schedule-service/internal/optimizer"
+)
+
+func TestSwapChangesAreValidated(t *testing.T) {
+	in := optimizer.Input{
+		DepartmentID: "dept",
+		Month:        "2026-02",
+		Shifts: []database.ShiftRecord{
+			{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00"},
+			{ID: "n", Name: "ดึก", Type: "night", StartTime: "00:00", EndTime: "08:00"},
+		},
+		Staff: []database.DepartmentStaff{{ID: "a", Position: "nurse"}, {ID: "b", Position: "nurse"}},
+	}
+	current := []database.Assignment{
+		{StaffID: "a", ShiftID: "m", ScheduleDate: "2026-02-02"},
+		{StaffID: "b", ShiftID: "n", ScheduleDate: "2026-02-05"},
+	}
+	swap := &database.SwapRequest{DepartmentID: "dept", FromStaffID: "a", ShiftID: "m", ScheduleDate: "2026-02-02",
+		ToStaffID: sql.NullString{String: "b", Valid: true}}
+
+	cases := []struct {
+		name    string
+		counter string // counter date of b's night, empty to take the shift outright
+		leave   string // b's leave day
+		want    string
+	}{
+		{"taken outright", "", "", ""},
+		{"traded for the counter shift", "2026-02-05", "", ""},
+		{"taker on leave that day", "", "2026-02-02", "leave"},
+		{"giver on leave on the counter day", "2026-02-05", "", "leave"},
+	}
+	for _, tc := range cases {
+		s := *swap
+		if tc.counter != "" {
+			s.CounterShiftID = sql.NullString{String: "n", Valid: true}
+			s.CounterDate = sql.NullString{String: tc.counter, Valid: true}
+		}
+		vin := in
+		switch {
+		case tc.leave != "":
+			vin.Leaves = []database.LeaveRange{{StaffID: "b", Start: tc.leave, End: tc.leave}}
+		case tc.want != "":
+			vin.Leaves = []database.LeaveRange{{StaffID: "a", Start: tc.counter, End: tc.counter}}
+		}
+		remove, add := swapChanges(&s)
+		violations, err := optimizer.ValidateChanges(vin, current, remove, add)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if len(violations) > 0 {
+			got = violations[0].Reason
+		}
+		if got != tc.want || len(violations) > 1 {
+			t.Errorf("%s: violations %+v, want %q", tc.name, violations, tc.want)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"nurseshift/schedule-service/internal/infrastructure/entitlements"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -70,6 +74,19 @@ func AuthMiddleware(_ string) fiber.Handler {
 	}
 }
 
+// QueryTokenMiddleware lets a route take its bearer token from ?access_token= when the request has
+// no Authorization header; EventSource cannot send headers. Put it before AuthMiddleware.
+func QueryTokenMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request().Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		return c.Next()
+	}
+}
+
 // AdminOnlyMiddleware ensures only admin users can access
 func AdminOnlyMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -83,3 +100,50 @@ func AdminOnlyMiddleware() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// InternalServiceMiddleware accepts service-to-service calls carrying X-Internal-Token = INTERNAL_SERVICE_TOKEN;
+// with the variable unset every call is rejected
+func InternalServiceMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		expected := os.Getenv("INTERNAL_SERVICE_TOKEN")
+		if expected == "" || subtle.ConstantTimeCompare([]byte(c.Get("X-Internal-Token")), []byte(expected)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"status":  "error",
+				"message": "internal token ไม่ถูกต้อง",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// SubscriptionMiddleware blocks write requests of suspended or expired accounts; reads and admins pass
+func SubscriptionMiddleware(checker *entitlements.Checker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if c.Locals("role") == "admin" {
+			return c.Next()
+		}
+		userID, _ := c.Locals("userID").(string)
+		err := checker.CheckWritable(c.Context(), userID)
+		var denial *entitlements.Denial
+		switch {
+		case err == nil:
+			return c.Next()
+		case errors.As(err, &denial):
+			return c.Status(denial.Status).JSON(denial.Body())
+		case errors.Is(err, entitlements.ErrUnknownAccount):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"status":  "error",
+				"message": "ไม่พบบัญชีผู้ใช้",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "ไม่สามารถตรวจสอบสถานะแพ็คเกจได้",
+			"error":   err.Error(),
+		})
+	}
+}
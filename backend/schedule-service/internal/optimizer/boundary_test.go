@@ -0,0 +1,109 @@
+package optimizer
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func TestGeneratorsRespectPreviousMonth(t *testing.T) {
+	in := Input{
+		DepartmentID: "dept",
+		Month:        "2026-02",
+		Shifts: []database.ShiftRecord{
+			{ID: "n", Name: "ดึก", Type: "night", StartTime: "00:00", EndTime: "08:00", RequiredNurse: 1},
+			{ID: "e", Name: "บ่าย", Type: "afternoon", StartTime: "16:00", EndTime: "00:00"},
+			{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00", RequiredNurse: 1},
+		},
+		Staff: []database.DepartmentStaff{
+			{ID: "a", Name: "A", Position: "nurse"},
+			{ID: "b", Name: "B", Position: "nurse"},
+			{ID: "c", Name: "C", Position: "nurse"},
+			{ID: "d", Name: "D", Position: "nurse"},
+		},
+		Rules: BuildRules(nil).WithSequence(database.SequenceRules{MinRestHours: 11}),
+		Context: []database.Assignment{
+			{StaffID: "a", ShiftID: "n", ScheduleDate: "2026-01-30"},
+			{StaffID: "a", ShiftID: "n", ScheduleDate: "2026-01-31"},
+			{StaffID: "b", ShiftID: "e", ScheduleDate: "2026-01-31"}, // ends at midnight
+		},
+	}
+	for _, name := range []string{SolverGreedy, SolverLocalSearch} {
+		solver, err := NewSolver(name, 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := solver.Solve(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range res.Assignments {
+			if a.ScheduleDate != "2026-02-01" {
+				continue
+			}
+			if a.StaffID == "a" && a.ShiftID == "n" {
+				t.Errorf("%s: a gets a third night in a row on the 1st", name)
+			}
+			if a.StaffID == "b" && a.ShiftID == "m" {
+				t.Errorf("%s: b works the morning of the 1st without 11h rest after the 31st", name)
+			}
+		}
+	}
+}
+
+func TestContiguousHoursRunInFromPreviousMonth(t *testing.T) {
+	// a twelve-hour night on 31 January ends at 08:00 on the 1st, where the morning shift starts
+	night := database.ShiftRecord{ID: "n", Name: "ดึก", Type: "night", StartTime: "20:00", EndTime: "08:00"}
+	cfg := func(s string) RuleSet {
+		return BuildRules([]database.SchedulingPriority{{Name: PriorityMaxContiguousHours, Order: 1, IsActive: true, Config: sql.NullString{String: s, Valid: true}}})
+	}
+	in := fixture("2026-02", 2, night, needs(morningShift, 1))
+	in.Rules = cfg(`{"value":16}`)
+	in.Context = []database.Assignment{{StaffID: "a", ShiftID: "n", ScheduleDate: "2026-01-31"}}
+	first := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := tr.Reason("a", first, morningShift); r != "exceed-contiguous-hours" {
+		t.Errorf("tracker: a's morning on the 1st = %q, want exceed-contiguous-hours", r)
+	}
+	if r := tr.Reason("b", first, morningShift); r != "" {
+		t.Errorf("tracker: b's morning on the 1st = %q, want none", r)
+	}
+	m, err := newModel(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := m.newState()
+	m1, _ := m.slotFor("2026-02-01", "m", "nurse")
+	if r := st.blockReason(0, m1); r != "exceed-contiguous-hours" {
+		t.Errorf("model: a's morning on the 1st = %q, want exceed-contiguous-hours", r)
+	}
+	for _, s := range solvers() {
+		for _, a := range solve(t, s, in).Assignments {
+			if a.StaffID == "a" && a.ScheduleDate == "2026-02-01" {
+				t.Errorf("%s: a works the morning of the 1st straight after the night of 31 January", s.Name())
+			}
+		}
+	}
+
+	// soft, the block that starts in January is priced once it reaches into February
+	in.Rules = cfg(`{"value":16,"hard":false}`)
+	m, err = newModel(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st = m.newState()
+	if c := st.softRuleCost(0); c != 0 {
+		t.Errorf("model: January's night alone costs %v, want 0", c)
+	}
+	st.add(m1, 0)
+	if c, want := st.softRuleCost(0), rulePenalty*m.lim.contigWeight*4; c != want {
+		t.Errorf("model: soft cost of the morning after January's night = %v, want %v", c, want)
+	}
+}
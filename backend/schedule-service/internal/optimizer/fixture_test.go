@@ -0,0 +1,46 @@
+package optimizer
+
+import (
+	"context"
+	"testing"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// The shifts the tests roster: a day split into three eight-hour blocks
+var (
+	morningShift = database.ShiftRecord{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00"}
+	eveningShift = database.ShiftRecord{ID: "e", Name: "บ่าย", Type: "afternoon", StartTime: "16:00", EndTime: "00:00"}
+	nightShift   = database.ShiftRecord{ID: "n", Name: "ดึก", Type: "night", StartTime: "00:00", EndTime: "08:00"}
+)
+
+// needs returns sh requiring nurses nurses
+func needs(sh database.ShiftRecord, nurses int) database.ShiftRecord {
+	sh.RequiredNurse = nurses
+	return sh
+}
+
+// fixture is a department of staff nurses named a, b, c, ... rostering shifts over month
+func fixture(month string, staff int, shifts ...database.ShiftRecord) Input {
+	in := Input{DepartmentID: "dept", Month: month, Shifts: shifts}
+	for i := 0; i < staff; i++ {
+		id := string(rune('a' + i))
+		in.Staff = append(in.Staff, database.DepartmentStaff{ID: id, Name: id, Position: "nurse"})
+	}
+	return in
+}
+
+// solvers are the generators every constraint must hold in; local search stops on an iteration count
+// rather than the clock so runs are repeatable
+func solvers() []Solver {
+	return []Solver{GreedySolver{}, &LocalSearchSolver{TimeBudget: MaxTimeBudget, Seed: 1, MaxIterations: 20000}}
+}
+
+func solve(t *testing.T, s Solver, in Input) *Result {
+	t.Helper()
+	res, err := s.Solve(context.Background(), in)
+	if err != nil {
+		t.Fatalf("%s: %v", s.Name(), err)
+	}
+	return res
+}
@@ -0,0 +1,312 @@
+package optimizer
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// ledgerWeight prices the squared distance of a staff member's running weekend days, holiday days and
+// hours (in 8-hour units) over the fairness window from their role's average. Shift counts are balanced by
+// moving the month's fairness targets instead (see shiftLead).
+const ledgerWeight = 1.0
+
+// Ledger keys besides the shift types
+const (
+	LedgerWeekend = "weekend"
+	LedgerHoliday = "holiday"
+	LedgerHours   = "hours"
+)
+
+func isWeekend(d time.Time) bool {
+	return d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+}
+
+func ledgerShiftType(sh database.ShiftRecord) string {
+	if t := strings.ToLower(strings.TrimSpace(sh.Type)); t != "" {
+		return t
+	}
+	return "other"
+}
+
+// BuildLedger summarises a month's roster into one ledger entry per staff member who worked:
+// shifts by type, weekend and holiday days (a day counts once however many shifts) and hours
+func BuildLedger(in Input, assignments []database.Assignment) []database.FairnessEntry {
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range in.Shifts {
+		shiftByID[sh.ID] = sh
+	}
+	isHoliday := func(ds string) bool {
+		for _, h := range in.Holidays {
+			if ds >= h.Start && ds <= h.End {
+				return true
+			}
+		}
+		return false
+	}
+	byStaff := map[string]*database.FairnessEntry{}
+	daysWorked := map[string]map[string]bool{}
+	for _, a := range assignments {
+		sh, ok := shiftByID[a.ShiftID]
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if !ok || err != nil || a.StaffID == "" {
+			continue
+		}
+		e := byStaff[a.StaffID]
+		if e == nil {
+			e = &database.FairnessEntry{DepartmentID: in.DepartmentID, StaffID: a.StaffID, Month: in.Month, ShiftCounts: map[string]int{}}
+			byStaff[a.StaffID] = e
+			daysWorked[a.StaffID] = map[string]bool{}
+		}
+		e.ShiftCounts[ledgerShiftType(sh)]++
+		e.Hours += shiftHours(sh)
+		if daysWorked[a.StaffID][a.ScheduleDate] {
+			continue
+		}
+		daysWorked[a.StaffID][a.ScheduleDate] = true
+		if isWeekend(d) {
+			e.WeekendDays++
+		}
+		if isHoliday(a.ScheduleDate) {
+			e.HolidayDays++
+		}
+	}
+	out := make([]database.FairnessEntry, 0, len(byStaff))
+	for _, e := range byStaff {
+		e.Hours = math.Round(e.Hours*100) / 100
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StaffID < out[j].StaffID })
+	return out
+}
+
+// ledger is Input.Ledger as each staff member's lead over their role's average, which the month's
+// roster should work off. Leads compare monthly rates, so staff with fewer months in the window are
+// not pushed to catch up on months they were not there.
+type ledger struct {
+	keys     []string             // shift types of in.Shifts, then LedgerWeekend, LedgerHoliday and LedgerHours
+	keyOf    map[string]int       // shiftID -> index of its type in keys
+	carry    map[string][]float64 // staffID -> lead per key
+	target   map[string][]float64 // role -> this month's even share of weekend days, holiday days and hours
+	role     map[string]string    // staffID -> role
+	holidays map[string]bool      // YYYY-MM-DD of the month's holidays
+}
+
+func newLedger(in Input) ledger {
+	l := ledger{keyOf: map[string]int{}, carry: map[string][]float64{}, target: map[string][]float64{}, role: map[string]string{}, holidays: map[string]bool{}}
+	if len(in.Ledger) == 0 {
+		return l
+	}
+	index := map[string]int{}
+	for _, sh := range in.Shifts {
+		t := ledgerShiftType(sh)
+		if _, ok := index[t]; !ok {
+			index[t] = len(l.keys)
+			l.keys = append(l.keys, t)
+		}
+		l.keyOf[sh.ID] = index[t]
+	}
+	l.keys = append(l.keys, LedgerWeekend, LedgerHoliday, LedgerHours)
+	weekend, holiday, hours := l.weekend(), l.holiday(), l.hours()
+
+	headcount := map[string]float64{}
+	for _, s := range in.Staff {
+		l.role[s.ID] = staffRoleOf(s.Position)
+		headcount[l.role[s.ID]]++
+	}
+
+	// monthly rates over the window, then each staff member's lead over their role's mean rate
+	totals := map[string][]float64{}
+	months := map[string]map[string]bool{}
+	for _, e := range in.Ledger {
+		if _, ok := l.role[e.StaffID]; !ok {
+			continue
+		}
+		if totals[e.StaffID] == nil {
+			totals[e.StaffID] = make([]float64, len(l.keys))
+			months[e.StaffID] = map[string]bool{}
+		}
+		months[e.StaffID][e.Month] = true
+		for t, n := range e.ShiftCounts {
+			if i, ok := index[t]; ok {
+				totals[e.StaffID][i] += float64(n)
+			}
+		}
+		totals[e.StaffID][weekend] += float64(e.WeekendDays)
+		totals[e.StaffID][holiday] += float64(e.HolidayDays)
+		totals[e.StaffID][hours] += e.Hours / 8
+	}
+	meanRate := map[string][]float64{}
+	ratedStaff := map[string]float64{}
+	for id, tot := range totals {
+		r := l.role[id]
+		if meanRate[r] == nil {
+			meanRate[r] = make([]float64, len(l.keys))
+		}
+		for i, v := range tot {
+			meanRate[r][i] += v / float64(len(months[id]))
+		}
+		ratedStaff[r]++
+	}
+	for id, tot := range totals {
+		r, n := l.role[id], float64(len(months[id]))
+		lead := make([]float64, len(l.keys))
+		for i, v := range tot {
+			lead[i] = (v/n - meanRate[r][i]/ratedStaff[r]) * n
+		}
+		l.carry[id] = lead
+	}
+
+	// this month's even share of hours per role, over working non-holiday days
+	for _, r := range []string{"nurse", "assistant"} {
+		l.target[r] = make([]float64, len(l.keys))
+	}
+	t, err := time.Parse("2006-01", in.Month)
+	if err != nil {
+		return l
+	}
+	isHoliday := func(ds string) bool {
+		for _, h := range in.Holidays {
+			if ds >= h.Start && ds <= h.End {
+				return true
+			}
+		}
+		return false
+	}
+	for d := t; d.Month() == t.Month(); d = d.AddDate(0, 0, 1) {
+		if isHoliday(d.Format("2006-01-02")) {
+			l.holidays[d.Format("2006-01-02")] = true
+			continue
+		}
+		if w, ok := in.WorkingDays[int(d.Weekday())]; ok && !w {
+			continue
+		}
+		for _, sh := range in.Shifts {
+			for r, need := range map[string]int{"nurse": sh.RequiredNurse, "assistant": sh.RequiredAsst} {
+				if need <= 0 || headcount[r] == 0 {
+					continue
+				}
+				share := float64(need) / headcount[r]
+				l.target[r][hours] += share * shiftHours(sh) / 8
+			}
+		}
+	}
+	// weekend days: at most one per weekend day with demand, shared across the role
+	for d := t; d.Month() == t.Month(); d = d.AddDate(0, 0, 1) {
+		if !isWeekend(d) {
+			continue
+		}
+		if w, ok := in.WorkingDays[int(d.Weekday())]; (ok && !w) || isHoliday(d.Format("2006-01-02")) {
+			continue
+		}
+		for _, r := range []string{"nurse", "assistant"} {
+			need := 0
+			for _, sh := range in.Shifts {
+				if r == "assistant" {
+					need += sh.RequiredAsst
+				} else {
+					need += sh.RequiredNurse
+				}
+			}
+			if need > 0 && headcount[r] > 0 {
+				l.target[r][weekend] += math.Min(float64(need)/headcount[r], 1)
+			}
+		}
+	}
+	// holidays carry no demand, so the holiday target stays 0: whoever is put on one by hand adds to
+	// their lead, and those already ahead cost the most to put there
+	return l
+}
+
+// weekend, holiday and hours are the indices of the keys that follow the shift types
+func (l ledger) weekend() int { return len(l.keys) - 3 }
+func (l ledger) holiday() int { return len(l.keys) - 2 }
+func (l ledger) hours() int   { return len(l.keys) - 1 }
+
+// active reports whether there is history to balance against
+func (l ledger) active() bool { return len(l.carry) > 0 }
+
+// newCounts is an empty tally of the month's weekend days, holiday days and hours
+func (l ledger) newCounts() []float64 { return make([]float64, len(l.keys)) }
+
+// count adds (sign 1) or removes (sign -1) shift sh on d to cur; firstOfDay is whether it is the
+// staff member's only shift that day, so weekend and holiday days count once
+func (l ledger) count(cur []float64, d time.Time, sh database.ShiftRecord, firstOfDay bool, sign float64) {
+	if !l.active() {
+		return
+	}
+	cur[l.hours()] += sign * shiftHours(sh) / 8
+	if firstOfDay && isWeekend(d) {
+		cur[l.weekend()] += sign
+	}
+	if firstOfDay && l.holidays[d.Format("2006-01-02")] {
+		cur[l.holiday()] += sign
+	}
+}
+
+// shiftLead is how many more shifts of shiftID's type than their role's average staffID worked over
+// the window, split across the month's shifts of that type; the month's fairness target for them
+// drops by as much
+func (l ledger) shiftLead(staffID, shiftID string) float64 {
+	i, ok := l.keyOf[shiftID]
+	if !ok || l.carry[staffID] == nil {
+		return 0
+	}
+	same := 0
+	for _, j := range l.keyOf {
+		if j == i {
+			same++
+		}
+	}
+	return l.carry[staffID][i] / float64(same)
+}
+
+// totalLead is shiftLead summed over the shift types
+func (l ledger) totalLead(staffID string) float64 {
+	lead := 0.0
+	if carry := l.carry[staffID]; carry != nil {
+		for i := 0; i < l.weekend(); i++ {
+			lead += carry[i]
+		}
+	}
+	return lead
+}
+
+// hoursLead is how many hours more than their role's average staffID worked over the window
+func (l ledger) hoursLead(staffID string) float64 {
+	if carry := l.carry[staffID]; carry != nil {
+		return carry[l.hours()] * 8
+	}
+	return 0
+}
+
+// total is ledgerWeight times the squared distance of staffID's running weekend days, holiday days
+// and hours, cur included, from their role's average
+func (l ledger) total(staffID string, cur []float64) float64 {
+	if !l.active() {
+		return 0
+	}
+	lead, target := l.carry[staffID], l.target[l.role[staffID]]
+	c := 0.0
+	for i := l.weekend(); i < len(l.keys); i++ {
+		x := cur[i] - target[i]
+		if lead != nil {
+			x += lead[i]
+		}
+		c += ledgerWeight * x * x
+	}
+	return c
+}
+
+// cost is the change of total from staffID working sh on d on top of cur
+func (l ledger) cost(staffID string, cur []float64, d time.Time, sh database.ShiftRecord, firstOfDay bool) float64 {
+	if !l.active() {
+		return 0
+	}
+	next := append([]float64(nil), cur...)
+	l.count(next, d, sh, firstOfDay, 1)
+	return l.total(staffID, next) - l.total(staffID, cur)
+}
@@ -0,0 +1,143 @@
+package optimizer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func TestBuildLedger(t *testing.T) {
+	in := Input{
+		DepartmentID: "dept",
+		Month:        "2026-03",
+		Shifts: []database.ShiftRecord{
+			{ID: "m", Type: "morning", StartTime: "08:00", EndTime: "16:00"},
+			{ID: "e", Type: "afternoon", StartTime: "16:00", EndTime: "00:00"},
+		},
+		Holidays: []database.Holiday{{Start: "2026-03-09", End: "2026-03-09"}},
+	}
+	out := BuildLedger(in, []database.Assignment{
+		{StaffID: "a", ShiftID: "m", ScheduleDate: "2026-03-07"}, // Saturday
+		{StaffID: "a", ShiftID: "e", ScheduleDate: "2026-03-07"},
+		{StaffID: "a", ShiftID: "m", ScheduleDate: "2026-03-09"}, // holiday
+	})
+	if len(out) != 1 {
+		t.Fatalf("entries = %+v, want one for a", out)
+	}
+	e := out[0]
+	if e.ShiftCounts["morning"] != 2 || e.ShiftCounts["afternoon"] != 1 || e.WeekendDays != 1 || e.HolidayDays != 1 || e.Hours != 24 {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestLedgerBalancesNightsAcrossMonths(t *testing.T) {
+	in := Input{
+		DepartmentID: "dept",
+		Month:        "2026-04",
+		Shifts: []database.ShiftRecord{
+			{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00", RequiredNurse: 1},
+			{ID: "n", Name: "ดึก", Type: "night", StartTime: "00:00", EndTime: "08:00", RequiredNurse: 1},
+		},
+		Staff: []database.DepartmentStaff{
+			{ID: "a", Name: "A", Position: "nurse"},
+			{ID: "b", Name: "B", Position: "nurse"},
+			{ID: "c", Name: "C", Position: "nurse"},
+			{ID: "d", Name: "D", Position: "nurse"},
+		},
+		Rules: BuildRules(nil),
+	}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		nights := 4
+		if id == "a" {
+			nights = 12 // most nights in March
+		}
+		in.Ledger = append(in.Ledger, database.FairnessEntry{StaffID: id, Month: "2026-03", ShiftCounts: map[string]int{"night": nights, "morning": 16 - nights}})
+	}
+	for _, name := range []string{SolverGreedy, SolverLocalSearch} {
+		solver, err := NewSolver(name, 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := solver.Solve(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nights := map[string]int{}
+		for _, a := range res.Assignments {
+			if a.ShiftID == "n" {
+				nights[a.StaffID]++
+			}
+		}
+		for _, id := range []string{"b", "c", "d"} {
+			if nights["a"] >= nights[id] {
+				t.Errorf("%s: a works %d nights in April, %s %d; a should work fewer after March", name, nights["a"], id, nights[id])
+			}
+		}
+	}
+}
+
+func TestLedgerBalancesHolidays(t *testing.T) {
+	in := Input{
+		DepartmentID: "dept",
+		Month:        "2026-04",
+		Shifts:       []database.ShiftRecord{{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00", RequiredNurse: 1}},
+		Staff: []database.DepartmentStaff{
+			{ID: "a", Name: "A", Position: "nurse"},
+			{ID: "b", Name: "B", Position: "nurse"},
+		},
+		Holidays: []database.Holiday{{Start: "2026-04-13", End: "2026-04-15"}},
+		Ledger: []database.FairnessEntry{
+			{StaffID: "a", Month: "2026-03", ShiftCounts: map[string]int{"morning": 20}, WeekendDays: 4, HolidayDays: 3, Hours: 160},
+			{StaffID: "b", Month: "2026-03", ShiftCounts: map[string]int{"morning": 20}, WeekendDays: 4, Hours: 160},
+		},
+	}
+	l := newLedger(in)
+	if l.keys[l.holiday()] != LedgerHoliday {
+		t.Fatalf("keys = %v, want %s among them", l.keys, LedgerHoliday)
+	}
+	if a, b := l.carry["a"][l.holiday()], l.carry["b"][l.holiday()]; a != 1.5 || b != -1.5 {
+		t.Errorf("holiday leads = %v, %v, want 1.5 and -1.5", a, b)
+	}
+
+	// on a holiday the one who worked more of them last month costs more, weighted like a weekend day
+	holiday := time.Date(2026, 4, 14, 0, 0, 0, 0, time.UTC)
+	costA := l.cost("a", l.newCounts(), holiday, in.Shifts[0], true)
+	costB := l.cost("b", l.newCounts(), holiday, in.Shifts[0], true)
+	if diff := costA - costB; diff != 6*ledgerWeight {
+		t.Errorf("holiday cost a - b = %v, want %v", diff, 6*ledgerWeight)
+	}
+	workday := time.Date(2026, 4, 16, 0, 0, 0, 0, time.UTC)
+	if a, b := l.cost("a", l.newCounts(), workday, in.Shifts[0], true), l.cost("b", l.newCounts(), workday, in.Shifts[0], true); a != b {
+		t.Errorf("working-day costs = %v, %v, want equal", a, b)
+	}
+
+	// a second shift the same day does not count the holiday again
+	cur := l.newCounts()
+	l.count(cur, holiday, in.Shifts[0], true, 1)
+	l.count(cur, holiday, in.Shifts[0], false, 1)
+	if cur[l.holiday()] != 1 || cur[l.weekend()] != 0 || cur[l.hours()] != 2 {
+		t.Errorf("counts = %v, want one holiday day and two 8-hour units", cur)
+	}
+}
+
+func TestRuleTrackerPricesTheLedger(t *testing.T) {
+	in := fixture("2026-04", 2, needs(morningShift, 1), needs(nightShift, 1))
+	in.Rules = BuildRules(nil)
+	in.Ledger = []database.FairnessEntry{
+		{StaffID: "a", Month: "2026-03", ShiftCounts: map[string]int{"night": 12, "morning": 4}},
+		{StaffID: "b", Month: "2026-03", ShiftCounts: map[string]int{"night": 4, "morning": 12}},
+	}
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	if a, b := tr.Cost("a", d, nightShift), tr.Cost("b", d, nightShift); a <= b {
+		t.Errorf("night cost a %.1f, b %.1f; want a, ahead on nights over the ledger, to cost more", a, b)
+	}
+	if a, b := tr.Cost("a", d, morningShift), tr.Cost("b", d, morningShift); a >= b {
+		t.Errorf("morning cost a %.1f, b %.1f; want a, behind on mornings, to cost less", a, b)
+	}
+}
@@ -0,0 +1,266 @@
+package optimizer
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// LocalSearchSolver is an anytime simulated-annealing solver. It warm-starts from SolveMonth,
+// then explores fill / move / swap / eject moves under the same hard constraints until the
+// time budget runs out, the context is cancelled, the lower bound is reached or MaxIterations is hit.
+type LocalSearchSolver struct {
+	TimeBudget    time.Duration
+	Seed          int64 // 0 = seed from clock
+	MaxIterations int   // 0 = run until the time budget; tests use it to stop deterministically
+}
+
+func (s *LocalSearchSolver) Name() string { return SolverLocalSearch }
+
+func (s *LocalSearchSolver) Solve(ctx context.Context, in Input) (*Result, error) {
+	started := time.Now()
+	budget := s.TimeBudget
+	if budget <= 0 {
+		budget = DefaultTimeBudget
+	}
+	deadline := started.Add(budget)
+	seed := s.Seed
+	if seed == 0 {
+		seed = started.UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	mdl, err := newModel(in)
+	if err != nil {
+		return nil, err
+	}
+	st := mdl.newState()
+
+	// warm start: keep every greedy assignment that is valid under the model
+	greedy, err := SolveMonth(in)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range greedy {
+		staff, ok := mdl.staffIndex[a.StaffID]
+		if !ok {
+			continue
+		}
+		if si, ok := mdl.slotFor(a.ScheduleDate, a.ShiftID, mdl.roleOf[a.StaffID]); ok && st.canAssign(staff, si) {
+			st.add(si, staff)
+		}
+	}
+
+	lb := mdl.lowerBound()
+	cur := st.objective()
+	best, bestObj := st.snapshot(), cur
+	ls := &search{st: st, rng: rng}
+
+	const t0, tEnd = 50.0, 0.05
+	temp := t0
+	iter := 0
+	for ; ; iter++ {
+		if iter%256 == 0 {
+			if ctx.Err() != nil || !time.Now().Before(deadline) {
+				break
+			}
+			frac := float64(time.Since(started)) / float64(budget)
+			temp = t0 * math.Pow(tEnd/t0, frac)
+		}
+		if bestObj-lb < 1e-6 || len(mdl.slots) == 0 || (s.MaxIterations > 0 && iter >= s.MaxIterations) {
+			break
+		}
+
+		var delta float64
+		var undo func()
+		shortBefore := st.skillShort
+		switch r := rng.Float64(); {
+		case r < 0.35 && st.unmet > 0:
+			delta, undo = ls.fill()
+		case r < 0.45 && st.unmet > 0:
+			delta, undo = ls.eject()
+		case r < 0.75:
+			delta, undo = ls.move()
+		default:
+			delta, undo = ls.swap()
+		}
+		if undo == nil {
+			continue
+		}
+		// skill requirements are hard: a move may seat a required holder but never give one up
+		if st.skillShort > shortBefore {
+			undo()
+			continue
+		}
+		delta += unmetWeight * float64(st.skillShort-shortBefore)
+		if delta > 0 && rng.Float64() >= math.Exp(-delta/temp) {
+			undo()
+			continue
+		}
+		cur += delta
+		if cur < bestObj-1e-9 {
+			best, bestObj = st.snapshot(), cur
+		}
+	}
+
+	res := mdl.result(mdl.restore(best), SolverLocalSearch)
+	res.Iterations = iter
+	res.Elapsed = time.Since(started)
+	return res, nil
+}
+
+// search holds the neighbourhood moves; each returns the objective delta and an undo func (nil = no move)
+type search struct {
+	st  *state
+	rng *rand.Rand
+}
+
+func (ls *search) randomSlot(want func(si int) bool) (int, bool) {
+	n := len(ls.st.m.slots)
+	for tries := 0; tries < 32; tries++ {
+		si := ls.rng.Intn(n)
+		if want(si) {
+			return si, true
+		}
+	}
+	return 0, false
+}
+
+func (ls *search) open(si int) bool   { return len(ls.st.slotStaff[si]) < ls.st.m.slots[si].need }
+func (ls *search) filled(si int) bool { return len(ls.st.slotStaff[si]) > 0 }
+
+// fill puts an eligible staff member into an under-filled slot
+func (ls *search) fill() (float64, func()) {
+	st := ls.st
+	si, ok := ls.randomSlot(ls.open)
+	if !ok {
+		return 0, nil
+	}
+	ids := st.m.byRole[st.m.slots[si].role]
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	off := ls.rng.Intn(len(ids))
+	for i := range ids {
+		s := ids[(i+off)%len(ids)]
+		if !st.canAssign(s, si) {
+			continue
+		}
+		before := st.staffCost(s)
+		st.add(si, s)
+		return st.staffCost(s) - before - unmetWeight, func() { st.remove(si, s) }
+	}
+	return 0, nil
+}
+
+// move hands one assignment to another staff member of the same role
+func (ls *search) move() (float64, func()) {
+	st := ls.st
+	si, ok := ls.randomSlot(ls.filled)
+	if !ok {
+		return 0, nil
+	}
+	from := st.slotStaff[si][ls.rng.Intn(len(st.slotStaff[si]))]
+	ids := st.m.byRole[st.m.slots[si].role]
+	to := ids[ls.rng.Intn(len(ids))]
+	if to == from {
+		return 0, nil
+	}
+	before := st.staffCost(from) + st.staffCost(to)
+	st.remove(si, from)
+	if !st.canAssign(to, si) {
+		st.add(si, from)
+		return 0, nil
+	}
+	st.add(si, to)
+	return st.staffCost(from) + st.staffCost(to) - before, func() {
+		st.remove(si, to)
+		st.add(si, from)
+	}
+}
+
+// swap exchanges the staff of two assignments with the same role
+func (ls *search) swap() (float64, func()) {
+	st := ls.st
+	si, ok1 := ls.randomSlot(ls.filled)
+	sj, ok2 := ls.randomSlot(func(x int) bool { return ls.filled(x) && x != si && st.m.slots[x].role == st.m.slots[si].role })
+	if !ok1 || !ok2 {
+		return 0, nil
+	}
+	a := st.slotStaff[si][ls.rng.Intn(len(st.slotStaff[si]))]
+	b := st.slotStaff[sj][ls.rng.Intn(len(st.slotStaff[sj]))]
+	if a == b || st.has(sj, a) || st.has(si, b) {
+		return 0, nil
+	}
+	before := st.staffCost(a) + st.staffCost(b)
+	st.remove(si, a)
+	st.remove(sj, b)
+	if !st.canAssign(b, si) {
+		st.add(si, a)
+		st.add(sj, b)
+		return 0, nil
+	}
+	st.add(si, b)
+	if !st.canAssign(a, sj) {
+		st.remove(si, b)
+		st.add(si, a)
+		st.add(sj, b)
+		return 0, nil
+	}
+	st.add(sj, a)
+	return st.staffCost(a) + st.staffCost(b) - before, func() {
+		st.remove(si, b)
+		st.remove(sj, a)
+		st.add(si, a)
+		st.add(sj, b)
+	}
+}
+
+// eject frees a staff member for an under-filled slot by dropping their assignments on the
+// surrounding days, letting the search re-fill those elsewhere (escapes local minima)
+func (ls *search) eject() (float64, func()) {
+	st := ls.st
+	si, ok := ls.randomSlot(ls.open)
+	if !ok {
+		return 0, nil
+	}
+	sl := st.m.slots[si]
+	ids := st.m.byRole[sl.role]
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	s := ids[ls.rng.Intn(len(ids))]
+	if st.m.onLeave[s][sl.day] || st.has(si, s) {
+		return 0, nil
+	}
+	before := st.staffCost(s)
+	unmetBefore := st.unmet
+	var dropped []int
+	for d := sl.day - 1; d <= sl.day+1; d++ {
+		if d < 0 || d >= len(st.m.days) {
+			continue
+		}
+		for _, k := range append([]int(nil), st.dayShifts[s][d]...) {
+			if x, ok := st.m.slotFor(st.m.date(d), st.m.shifts[k].ID, sl.role); ok {
+				st.remove(x, s)
+				dropped = append(dropped, x)
+			}
+		}
+	}
+	restore := func() {
+		for _, x := range dropped {
+			st.add(x, s)
+		}
+	}
+	if !st.canAssign(s, si) {
+		restore()
+		return 0, nil
+	}
+	st.add(si, s)
+	delta := st.staffCost(s) - before + unmetWeight*float64(st.unmet-unmetBefore)
+	return delta, func() {
+		st.remove(si, s)
+		restore()
+	}
+}
@@ -0,0 +1,1009 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// Objective weights shared by every solver so results are comparable
+const (
+	unmetWeight         = 1000.0 // per missing person in a slot
+	fairnessWeight      = 1.0    // squared deviation of total shifts from role target
+	shiftFairnessWeight = 3.0    // squared deviation per shift type (same 1:3 ratio as SolveMonth.cost)
+)
+
+// slot is one (date, shift, role) demand cell of the month
+type slot struct {
+	day   int // 0-based day of month
+	shift int // index into model.shifts
+	role  string
+	need  int
+}
+
+// model is the solver-independent view of an Input: slots, eligibility and objective
+type model struct {
+	in          Input
+	days        []time.Time
+	shifts      []database.ShiftRecord
+	shiftIndex  map[string]int
+	intervals   [][2]int // per shift, minutes from 00:00; end may exceed 1440
+	validShift  []bool
+	staff       []database.DepartmentStaff
+	staffIndex  map[string]int
+	staffRole   []string
+	roleOf      map[string]string
+	byRole      map[string][]int
+	onLeave     [][]bool        // staff -> day
+	fixed       []map[int][]int // staff -> day outside the month (negative = before) -> Context shift indices
+	slots       []slot
+	slotIndex   map[string]int
+	target      map[string]float64
+	shiftTarget map[string][]float64
+	hoursTarget map[string]float64
+	shiftHours  []float64
+	isNight     []bool
+	shiftTypes  []string // lower case
+	lim         limits
+	prefs       preferences
+	ledger      ledger
+	lead        [][]float64 // staff -> shift -> ledger lead the month's shift target drops by; nil without a ledger
+	leadTotal   []float64   // staff -> lead summed over the shifts
+	prefCost    [][]float64 // staff -> day*len(shifts)+shift -> preference cost; nil without preferences
+	skills      skillIndex
+	skillUntil  []map[string]int // staff -> skillID -> last day index the certification is valid
+	demandCell  []bool           // day*len(shifts)+shift -> the shift runs that day
+}
+
+// staffRoleOf classifies a department_staff position the same way SolveMonth does
+func staffRoleOf(position string) string {
+	if position == "assistant" || position == "ผู้ช่วยพยาบาล" || position == "ผู้ช่วย" {
+		return "assistant"
+	}
+	return "nurse"
+}
+
+func parseHM(hm string) (int, bool) {
+	var hh, mm int
+	if _, err := fmt.Sscanf(hm, "%d:%d", &hh, &mm); err != nil {
+		return 0, false
+	}
+	return hh*60 + mm, true
+}
+
+// shiftMinutes returns the shift window in minutes; overnight shifts end after 1440
+func shiftMinutes(sh database.ShiftRecord) (int, int, bool) {
+	s, ok1 := parseHM(sh.StartTime)
+	e, ok2 := parseHM(sh.EndTime)
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	if e <= s {
+		e += 24 * 60
+	}
+	return s, e, true
+}
+
+// ShiftWindow returns when sh starts and ends on date (wall clock in date's location); an end at or
+// before the start means the shift finishes the next day
+func ShiftWindow(sh database.ShiftRecord, date time.Time) (time.Time, time.Time, bool) {
+	s, e, ok := shiftMinutes(sh)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return day.Add(time.Duration(s) * time.Minute), day.Add(time.Duration(e) * time.Minute), true
+}
+
+// maxContiguousMinutes merges touching/overlapping intervals and returns the longest block
+func maxContiguousMinutes(ivals [][2]int) int {
+	if len(ivals) == 0 {
+		return 0
+	}
+	sort.Slice(ivals, func(i, j int) bool { return ivals[i][0] < ivals[j][0] })
+	curS, curE := ivals[0][0], ivals[0][1]
+	maxDur := curE - curS
+	for i := 1; i < len(ivals); i++ {
+		s, e := ivals[i][0], ivals[i][1]
+		if s <= curE {
+			if e > curE {
+				curE = e
+			}
+		} else {
+			if curE-curS > maxDur {
+				maxDur = curE - curS
+			}
+			curS, curE = s, e
+		}
+	}
+	if curE-curS > maxDur {
+		maxDur = curE - curS
+	}
+	return maxDur
+}
+
+// blockMinutes merges touching/overlapping intervals and returns the length of the block holding iv,
+// which must be one of ivals
+func blockMinutes(ivals [][2]int, iv [2]int) int {
+	sort.Slice(ivals, func(i, j int) bool { return ivals[i][0] < ivals[j][0] })
+	curS, curE := ivals[0][0], ivals[0][1]
+	for _, o := range ivals[1:] {
+		if o[0] > curE {
+			if curS <= iv[0] && iv[1] <= curE {
+				return curE - curS
+			}
+			curS, curE = o[0], o[1]
+		} else if o[1] > curE {
+			curE = o[1]
+		}
+	}
+	return curE - curS
+}
+
+func newModel(in Input) (*model, error) {
+	t, err := time.Parse("2006-01", in.Month)
+	if err != nil {
+		return nil, err
+	}
+	year, mo, _ := t.Date()
+	first := time.Date(year, mo, 1, 0, 0, 0, 0, time.UTC)
+	m := &model{
+		in:          in,
+		shifts:      in.Shifts,
+		shiftIndex:  map[string]int{},
+		staff:       in.Staff,
+		staffIndex:  map[string]int{},
+		roleOf:      map[string]string{},
+		byRole:      map[string][]int{"nurse": {}, "assistant": {}},
+		slotIndex:   map[string]int{},
+		target:      map[string]float64{},
+		shiftTarget: map[string][]float64{},
+		hoursTarget: map[string]float64{},
+		lim:         in.Rules.limits(),
+		prefs:       newPreferences(in),
+		ledger:      newLedger(in),
+		skills:      newSkillIndex(in.Competencies),
+	}
+	for d := first; d.Before(first.AddDate(0, 1, 0)); d = d.AddDate(0, 0, 1) {
+		m.days = append(m.days, d)
+	}
+
+	for i, sh := range m.shifts {
+		m.shiftIndex[sh.ID] = i
+		s, e, ok := shiftMinutes(sh)
+		m.intervals = append(m.intervals, [2]int{s, e})
+		m.validShift = append(m.validShift, ok)
+		m.shiftHours = append(m.shiftHours, shiftHours(sh))
+		m.isNight = append(m.isNight, IsNightShift(sh))
+		m.shiftTypes = append(m.shiftTypes, strings.ToLower(sh.Type))
+	}
+	for i, s := range m.staff {
+		role := staffRoleOf(s.Position)
+		m.staffIndex[s.ID] = i
+		m.roleOf[s.ID] = role
+		m.staffRole = append(m.staffRole, role)
+		m.byRole[role] = append(m.byRole[role], i)
+	}
+
+	m.prefCost = make([][]float64, len(m.staff))
+	for i, s := range m.staff {
+		if m.prefs.byStaff[s.ID] == nil || m.prefs.weight == 0 {
+			continue
+		}
+		m.prefCost[i] = make([]float64, len(m.days)*len(m.shifts))
+		for di, d := range m.days {
+			for k, sh := range m.shifts {
+				m.prefCost[i][di*len(m.shifts)+k] = m.prefs.cost(s.ID, d, sh)
+			}
+		}
+	}
+
+	m.skillUntil = make([]map[string]int, len(m.staff))
+	for i, s := range m.staff {
+		m.skillUntil[i] = map[string]int{}
+		for skill := range m.skills.expires[s.ID] {
+			last := -1
+			for di := range m.days {
+				if m.skills.qualified(s.ID, skill, m.date(di)) {
+					last = di
+				}
+			}
+			m.skillUntil[i][skill] = last
+		}
+	}
+
+	// neighbouring months' roster, fixed
+	m.fixed = make([]map[int][]int, len(m.staff))
+	for i := range m.fixed {
+		m.fixed[i] = map[int][]int{}
+	}
+	for _, a := range in.Context {
+		si, ok1 := m.staffIndex[a.StaffID]
+		k, ok2 := m.shiftIndex[a.ShiftID]
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if !ok1 || !ok2 || err != nil || !m.validShift[k] {
+			continue
+		}
+		if day := int(d.Sub(first).Hours() / 24); day < 0 || day >= len(m.days) {
+			m.fixed[si][day] = append(m.fixed[si][day], k)
+		}
+	}
+
+	// leave days per staff
+	m.onLeave = make([][]bool, len(m.staff))
+	for i := range m.onLeave {
+		m.onLeave[i] = make([]bool, len(m.days))
+	}
+	for _, lv := range in.Leaves {
+		si, ok := m.staffIndex[lv.StaffID]
+		if !ok {
+			continue
+		}
+		start, err1 := time.Parse("2006-01-02", lv.Start)
+		end, err2 := time.Parse("2006-01-02", lv.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		for di, d := range m.days {
+			if !d.Before(start) && !d.After(end) {
+				m.onLeave[si][di] = true
+			}
+		}
+	}
+
+	// demand slots on working, non-holiday days
+	isHoliday := func(d time.Time) bool {
+		ds := d.Format("2006-01-02")
+		for _, h := range in.Holidays {
+			if ds >= h.Start && ds <= h.End {
+				return true
+			}
+		}
+		return false
+	}
+	m.demandCell = make([]bool, len(m.days)*len(m.shifts))
+	demand := map[string]float64{}
+	shiftDemand := map[string][]float64{"nurse": make([]float64, len(m.shifts)), "assistant": make([]float64, len(m.shifts))}
+	for di, d := range m.days {
+		if w, ok := in.WorkingDays[int(d.Weekday())]; ok && !w {
+			continue
+		}
+		if isHoliday(d) {
+			continue
+		}
+		for shi, sh := range m.shifts {
+			for _, rn := range []struct {
+				role string
+				need int
+			}{{"nurse", sh.RequiredNurse}, {"assistant", sh.RequiredAsst}} {
+				if rn.need <= 0 {
+					continue
+				}
+				m.demandCell[di*len(m.shifts)+shi] = true
+				m.slotIndex[slotKey(d.Format("2006-01-02"), sh.ID, rn.role)] = len(m.slots)
+				m.slots = append(m.slots, slot{day: di, shift: shi, role: rn.role, need: rn.need})
+				demand[rn.role] += float64(rn.need)
+				shiftDemand[rn.role][shi] += float64(rn.need)
+			}
+		}
+	}
+	for _, role := range []string{"nurse", "assistant"} {
+		n := float64(len(m.byRole[role]))
+		if n < 1 {
+			n = 1
+		}
+		m.target[role] = demand[role] / n
+		m.shiftTarget[role] = make([]float64, len(m.shifts))
+		for shi := range m.shifts {
+			m.shiftTarget[role][shi] = shiftDemand[role][shi] / n
+			m.hoursTarget[role] += m.shiftHours[shi] * shiftDemand[role][shi] / n
+		}
+	}
+	if m.ledger.active() {
+		m.lead, m.leadTotal = make([][]float64, len(m.staff)), make([]float64, len(m.staff))
+		for s, st := range m.staff {
+			m.lead[s] = make([]float64, len(m.shifts))
+			for k, sh := range m.shifts {
+				m.lead[s][k] = m.ledger.shiftLead(st.ID, sh.ID)
+				m.leadTotal[s] += m.lead[s][k]
+			}
+		}
+	}
+	return m, nil
+}
+
+// targetOf is staff s's fair share of the month's shifts, less what they are ahead over the fairness window
+func (m *model) targetOf(s int) float64 {
+	t := m.target[m.staffRole[s]]
+	if m.leadTotal != nil {
+		t -= m.leadTotal[s]
+	}
+	return t
+}
+
+// shiftTargetOf is targetOf for shift k alone
+func (m *model) shiftTargetOf(s, k int) float64 {
+	t := m.shiftTarget[m.staffRole[s]][k]
+	if m.lead != nil {
+		t -= m.lead[s][k]
+	}
+	return t
+}
+
+func slotKey(date, shiftID, role string) string { return date + "|" + shiftID + "|" + role }
+
+func (m *model) slotFor(date, shiftID, role string) (int, bool) {
+	si, ok := m.slotIndex[slotKey(date, shiftID, role)]
+	return si, ok
+}
+
+func (m *model) date(day int) string { return m.days[day].Format("2006-01-02") }
+
+// qualified reports whether staff s holds skill with a valid certification on day
+func (m *model) qualified(s int, skill string, day int) bool {
+	last, ok := m.skillUntil[s][skill]
+	return ok && day <= last
+}
+
+// lowerBound returns a valid lower bound on the objective. Per role, unmet demand can never drop
+// below the slots that lack enough non-leave staff, and each fairness term can never beat an even
+// split; the unmet and per-shift terms are bounded together, the total-count term on its own.
+func (m *model) lowerBound() float64 {
+	total := 0.0
+	for _, role := range []string{"nurse", "assistant"} {
+		ids := m.byRole[role]
+		demand := make([]int, len(m.shifts))
+		forced := make([]int, len(m.shifts))
+		for _, sl := range m.slots {
+			if sl.role != role {
+				continue
+			}
+			demand[sl.shift] += sl.need
+			avail := 0
+			for _, s := range ids {
+				if !m.onLeave[s][sl.day] {
+					avail++
+				}
+			}
+			if avail < sl.need {
+				forced[sl.shift] += sl.need - avail
+			}
+		}
+		sumDemand, sumForced := 0, 0
+		for k := range m.shifts {
+			sumDemand += demand[k]
+			sumForced += forced[k]
+		}
+		if len(ids) == 0 {
+			total += unmetWeight * float64(sumDemand)
+			continue
+		}
+		targets, shiftTargets := make([]float64, len(ids)), make([][]float64, len(m.shifts))
+		for i, s := range ids {
+			targets[i] = m.targetOf(s)
+		}
+		for k := range m.shifts {
+			shiftTargets[k] = make([]float64, len(ids))
+			for i, s := range ids {
+				shiftTargets[k][i] = m.shiftTargetOf(s, k)
+			}
+		}
+		perShift := 0.0
+		for k := range m.shifts {
+			best := math.Inf(1)
+			for u := forced[k]; u <= demand[k]; u++ {
+				v := unmetWeight*float64(u) + shiftFairnessWeight*splitDeviation(demand[k]-u, shiftTargets[k])
+				if v < best {
+					best = v
+				}
+			}
+			perShift += best
+		}
+		// capacity bound: nobody works more consecutive days than allowed nor more shifts a day than fit
+		capacity := 0
+		perDay := m.maxShiftsPerDay(role)
+		for _, s := range ids {
+			capacity += m.maxWorkDays(s, role) * perDay
+		}
+		if short := sumDemand - capacity; short > sumForced {
+			sumForced = short
+		}
+		total += math.Max(perShift, unmetWeight*float64(sumForced))
+		best := math.Inf(1)
+		for u := sumForced; u <= sumDemand; u++ {
+			if v := fairnessWeight * splitDeviation(sumDemand-u, targets); v < best {
+				best = v
+			}
+		}
+		total += best
+	}
+	return total
+}
+
+// maxWorkDays is the most days with demand for role that staff s could work under the hard consecutive-day cap
+func (m *model) maxWorkDays(s int, role string) int {
+	hasDemand := make([]bool, len(m.days))
+	for _, sl := range m.slots {
+		if sl.role == role {
+			hasDemand[sl.day] = true
+		}
+	}
+	n, run := 0, 0
+	for d := range m.days {
+		if !hasDemand[d] || m.onLeave[s][d] {
+			run = 0
+			continue
+		}
+		if m.lim.consecHard && m.lim.consecCap > 0 && run >= m.lim.consecCap {
+			run = 0 // forced day off
+			continue
+		}
+		n++
+		run++
+	}
+	return n
+}
+
+// maxShiftsPerDay is the largest set of role shifts one person can hold on a single day
+func (m *model) maxShiftsPerDay(role string) int {
+	used := map[int]bool{}
+	for _, sl := range m.slots {
+		if sl.role == role && m.validShift[sl.shift] {
+			used[sl.shift] = true
+		}
+	}
+	var ks []int
+	for k := range used {
+		ks = append(ks, k)
+	}
+	if len(ks) > 12 {
+		return len(ks)
+	}
+	best := 0
+	for mask := 1; mask < 1<<len(ks); mask++ {
+		var ivals [][2]int
+		ok := true
+		for i, k := range ks {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			iv := m.intervals[k]
+			for _, o := range ivals {
+				if o[0] < iv[1] && iv[0] < o[1] {
+					ok = false
+				}
+			}
+			ivals = append(ivals, iv)
+		}
+		if ok && len(ivals) > best && (!m.lim.contigHard || maxContiguousMinutes(ivals) <= m.lim.contigMinutes) {
+			best = len(ivals)
+		}
+	}
+	return best
+}
+
+// splitDeviation is min Σ(c_i - t_i)^2 over non-negative integers c_i summing to a. The sum is
+// separable and convex, so moving one unit at a time to the cheapest c_i is optimal.
+func splitDeviation(a int, t []float64) float64 {
+	c := make([]int, len(t))
+	sum := 0
+	for i, ti := range t {
+		c[i] = int(math.Max(0, math.Round(ti)))
+		sum += c[i]
+	}
+	for sum != a {
+		step := 1
+		if sum > a {
+			step = -1
+		}
+		best, bi := math.Inf(1), -1
+		for i, ti := range t {
+			if c[i]+step < 0 {
+				continue
+			}
+			x := float64(c[i]) - ti
+			if v := 2*float64(step)*x + 1; v < best {
+				best, bi = v, i
+			}
+		}
+		c[bi] += step
+		sum += step
+	}
+	dev := 0.0
+	for i, ti := range t {
+		x := float64(c[i]) - ti
+		dev += x * x
+	}
+	return dev
+}
+
+// state is a mutable assignment of staff to slots with incremental bookkeeping
+type state struct {
+	m            *model
+	slotStaff    [][]int
+	dayShifts    [][][]int // staff -> day -> shift indices
+	count        []int
+	countByShift [][]int
+	hours        []float64
+	unmet        int
+	holders      [][]int // day*len(shifts)+shift -> qualified staff per skill requirement of the shift
+	skillShort   int     // required skill holders missing over the month
+}
+
+func (m *model) newState() *state {
+	st := &state{
+		m:            m,
+		slotStaff:    make([][]int, len(m.slots)),
+		dayShifts:    make([][][]int, len(m.staff)),
+		count:        make([]int, len(m.staff)),
+		countByShift: make([][]int, len(m.staff)),
+		hours:        make([]float64, len(m.staff)),
+	}
+	for i := range m.staff {
+		st.dayShifts[i] = make([][]int, len(m.days))
+		st.countByShift[i] = make([]int, len(m.shifts))
+	}
+	for _, sl := range m.slots {
+		st.unmet += sl.need
+	}
+	st.holders = make([][]int, len(m.demandCell))
+	for cell, ok := range m.demandCell {
+		reqs := m.skills.reqs[m.shifts[cell%len(m.shifts)].ID]
+		if !ok || len(reqs) == 0 {
+			continue
+		}
+		st.holders[cell] = make([]int, len(reqs))
+		for _, r := range reqs {
+			st.skillShort += r.min
+		}
+	}
+	return st
+}
+
+// countSkills updates the skill coverage of slot si's shift for staff s joining (+1) or leaving (-1)
+func (st *state) countSkills(si, s, delta int) {
+	sl := st.m.slots[si]
+	h := st.holders[sl.day*len(st.m.shifts)+sl.shift]
+	if h == nil {
+		return
+	}
+	for ri, r := range st.m.skills.reqs[st.m.shifts[sl.shift].ID] {
+		if !st.m.qualified(s, r.skill, sl.day) {
+			continue
+		}
+		if delta < 0 && h[ri] <= r.min {
+			st.skillShort++
+		}
+		h[ri] += delta
+		if delta > 0 && h[ri] <= r.min {
+			st.skillShort--
+		}
+	}
+}
+
+func (st *state) add(si, s int) {
+	sl := st.m.slots[si]
+	if len(st.slotStaff[si]) < sl.need {
+		st.unmet--
+	}
+	st.slotStaff[si] = append(st.slotStaff[si], s)
+	st.dayShifts[s][sl.day] = append(st.dayShifts[s][sl.day], sl.shift)
+	st.count[s]++
+	st.countByShift[s][sl.shift]++
+	st.hours[s] += st.m.shiftHours[sl.shift]
+	st.countSkills(si, s, 1)
+}
+
+func (st *state) remove(si, s int) {
+	sl := st.m.slots[si]
+	st.slotStaff[si] = removeInt(st.slotStaff[si], s)
+	st.dayShifts[s][sl.day] = removeInt(st.dayShifts[s][sl.day], sl.shift)
+	if len(st.slotStaff[si]) < sl.need {
+		st.unmet++
+	}
+	st.count[s]--
+	st.countByShift[s][sl.shift]--
+	st.hours[s] -= st.m.shiftHours[sl.shift]
+	st.countSkills(si, s, -1)
+}
+
+func removeInt(xs []int, v int) []int {
+	for i, x := range xs {
+		if x == v {
+			return append(xs[:i], xs[i+1:]...)
+		}
+	}
+	return xs
+}
+
+func (st *state) has(si, s int) bool {
+	for _, x := range st.slotStaff[si] {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// blockReason reports why staff s cannot take slot si right now; "" means eligible.
+// Reasons mirror the reason() closure in SolveMonth.
+func (st *state) blockReason(s, si int) string {
+	m := st.m
+	sl := m.slots[si]
+	if m.staffRole[s] != sl.role {
+		return "role"
+	}
+	if st.has(si, s) {
+		return "already-assigned"
+	}
+	if len(st.slotStaff[si]) >= sl.need {
+		return "full"
+	}
+	if m.onLeave[s][sl.day] {
+		return "leave"
+	}
+	if m.lim.consecHard && m.lim.consecCap > 0 && len(st.dayShifts[s][sl.day]) == 0 && st.runLen(s, sl.day, false) > m.lim.consecCap {
+		return "consecutive-day"
+	}
+	if m.lim.nightHard && m.lim.nightCap > 0 && m.isNight[sl.shift] && !st.workedNight(s, sl.day) && st.runLen(s, sl.day, true) > m.lim.nightCap {
+		return "consecutive-night"
+	}
+	if !m.validShift[sl.shift] {
+		return "invalid-shift"
+	}
+	iv := m.intervals[sl.shift]
+	for _, k := range st.dayShifts[s][sl.day] {
+		o := m.intervals[k]
+		if o[0] < iv[1] && iv[0] < o[1] {
+			return "overlap"
+		}
+	}
+	w := m.window(sl.day, sl.shift)
+	if m.lim.contigHard && st.contiguousWith(s, sl.day, w) > m.lim.contigMinutes {
+		return "exceed-contiguous-hours"
+	}
+	if m.lim.hoursCapHard && m.lim.hoursCap > 0 && st.hours[s]+m.shiftHours[sl.shift] > m.lim.hoursCap {
+		return "exceed-total-hours"
+	}
+	// rest, transitions and overlaps across days, on real timestamps
+	for d := sl.day - sequenceReach; d <= sl.day+sequenceReach; d++ {
+		for _, k := range st.shiftsOn(s, d) {
+			if r := m.lim.seq.between(m.window(d, k), w); r != "" {
+				return r
+			}
+		}
+	}
+	return ""
+}
+
+// window places shift k of day on the month's timeline
+func (m *model) window(day, k int) window {
+	iv := m.intervals[k]
+	return window{start: day*24*60 + iv[0], end: day*24*60 + iv[1], typ: m.shiftTypes[k]}
+}
+
+// contiguousWith is the block of touching or overlapping shifts w (on day) would join for staff s,
+// in minutes, on real timestamps so that a night shift runs on into the next morning. Shifts last
+// at most a day, so a block longer than the limit already shows within the days scanned.
+func (st *state) contiguousWith(s, day int, w window) int {
+	reach := st.m.lim.contigMinutes/(24*60) + 2
+	ivals := [][2]int{{w.start, w.end}}
+	for d := day - reach; d <= day+reach; d++ {
+		for _, k := range st.shiftsOn(s, d) {
+			o := st.m.window(d, k)
+			ivals = append(ivals, [2]int{o.start, o.end})
+		}
+	}
+	return blockMinutes(ivals, [2]int{w.start, w.end})
+}
+
+// contiguousExcess sums, over the blocks of touching shifts of staff s that include a day of the
+// month, the minutes beyond the contiguous-hours limit. Blocks may start in the previous month's
+// Context and run into the next.
+func (st *state) contiguousExcess(s int) int {
+	m := st.m
+	type span struct {
+		start, end int
+		inMonth    bool
+	}
+	var ws []span
+	for d := range m.fixed[s] {
+		for _, k := range m.fixed[s][d] {
+			w := m.window(d, k)
+			ws = append(ws, span{w.start, w.end, false})
+		}
+	}
+	for d, ks := range st.dayShifts[s] {
+		for _, k := range ks {
+			w := m.window(d, k)
+			ws = append(ws, span{w.start, w.end, true})
+		}
+	}
+	if len(ws) == 0 {
+		return 0
+	}
+	sort.Slice(ws, func(i, j int) bool { return ws[i].start < ws[j].start })
+	excess := 0
+	cur := ws[0]
+	settle := func() {
+		if over := cur.end - cur.start - m.lim.contigMinutes; cur.inMonth && over > 0 {
+			excess += over
+		}
+	}
+	for _, w := range ws[1:] {
+		if w.start > cur.end {
+			settle()
+			cur = w
+			continue
+		}
+		if w.end > cur.end {
+			cur.end = w.end
+		}
+		cur.inMonth = cur.inMonth || w.inMonth
+	}
+	settle()
+	return excess
+}
+
+func (st *state) canAssign(s, si int) bool { return st.blockReason(s, si) == "" }
+
+// shiftsOn is what staff s works on day, including the fixed Context outside the month
+func (st *state) shiftsOn(s, day int) []int {
+	if day < 0 || day >= len(st.m.days) {
+		return st.m.fixed[s][day]
+	}
+	return st.dayShifts[s][day]
+}
+
+func (st *state) workedNight(s, day int) bool {
+	for _, k := range st.shiftsOn(s, day) {
+		if st.m.isNight[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// runLen is the length of the run of worked (or night) days that day would belong to
+func (st *state) runLen(s, day int, night bool) int {
+	worked := func(d int) bool {
+		if night {
+			return st.workedNight(s, d)
+		}
+		return len(st.shiftsOn(s, d)) > 0
+	}
+	n := 1
+	for d := day - 1; worked(d); d-- {
+		n++
+	}
+	for d := day + 1; worked(d); d++ {
+		n++
+	}
+	return n
+}
+
+// staffCost is the fairness and soft-rule part of the objective contributed by one staff member
+func (st *state) staffCost(s int) float64 {
+	m := st.m
+	role := m.staffRole[s]
+	d := float64(st.count[s]) - m.targetOf(s)
+	c := fairnessWeight * d * d
+	for k, n := range st.countByShift[s] {
+		ds := float64(n) - m.shiftTargetOf(s, k)
+		c += shiftFairnessWeight * ds * ds
+		if m.lim.shiftBalance >= 0 {
+			x := excess(ds, m.lim.shiftBalance)
+			c += rulePenalty * m.lim.shiftWeight * x * x
+		}
+	}
+	if m.lim.hoursBalance >= 0 {
+		x := excess(st.hours[s]-m.hoursTarget[role]+m.ledger.hoursLead(m.staff[s].ID), m.lim.hoursBalance) / 8
+		c += rulePenalty * m.lim.hoursWeight * x * x
+	}
+	if !m.lim.hoursCapHard && m.lim.hoursCap > 0 {
+		if over := st.hours[s] - m.lim.hoursCap; over > 0 {
+			c += rulePenalty * m.lim.hoursCapWeight * over / 8
+		}
+	}
+	return c + st.softRuleCost(s) + st.preferenceCost(s) + st.ledgerCost(s)
+}
+
+// ledgerCost prices how far staff s ends the fairness window from their role's average
+func (st *state) ledgerCost(s int) float64 {
+	m := st.m
+	if !m.ledger.active() {
+		return 0
+	}
+	cur := m.ledger.newCounts()
+	for day, ks := range st.dayShifts[s] {
+		for i, k := range ks {
+			m.ledger.count(cur, m.days[day], m.shifts[k], i == 0, 1)
+		}
+	}
+	return m.ledger.total(m.staff[s].ID, cur)
+}
+
+// preferenceCost prices the shifts of staff s that miss their preferences or fall on a requested day off
+func (st *state) preferenceCost(s int) float64 {
+	costs := st.m.prefCost[s]
+	if costs == nil {
+		return 0
+	}
+	c := 0.0
+	for day, ks := range st.dayShifts[s] {
+		for _, k := range ks {
+			c += costs[day*len(st.m.shifts)+k]
+		}
+	}
+	return c
+}
+
+// softRuleCost prices consecutive-day/night and contiguous-hour rules configured as soft
+func (st *state) softRuleCost(s int) float64 {
+	m := st.m
+	lim := m.lim
+	if lim.consecHard && lim.nightHard && lim.contigHard {
+		return 0
+	}
+	c := 0.0
+	// runs carry in from the previous month and out into the next one
+	run, nightRun := 0, 0
+	for d := -1; len(st.shiftsOn(s, d)) > 0; d-- {
+		run++
+	}
+	for d := -1; st.workedNight(s, d); d-- {
+		nightRun++
+	}
+	for day := 0; day < len(m.days) || len(st.shiftsOn(s, day)) > 0; day++ {
+		ks := st.shiftsOn(s, day)
+		if len(ks) == 0 {
+			run, nightRun = 0, 0
+			continue
+		}
+		run++
+		if !lim.consecHard && lim.consecCap > 0 && run > lim.consecCap {
+			c += rulePenalty * lim.consecWeight
+		}
+		if st.workedNight(s, day) {
+			nightRun++
+		} else {
+			nightRun = 0
+		}
+		if !lim.nightHard && lim.nightCap > 0 && nightRun > lim.nightCap {
+			c += rulePenalty * lim.nightWeight
+		}
+	}
+	if !lim.contigHard {
+		c += rulePenalty * lim.contigWeight * float64(st.contiguousExcess(s)) / 60
+	}
+	return c
+}
+
+func (st *state) objective() float64 {
+	total := unmetWeight * float64(st.unmet+st.skillShort)
+	for s := range st.m.staff {
+		total += st.staffCost(s)
+	}
+	return total
+}
+
+func (st *state) snapshot() [][]int {
+	out := make([][]int, len(st.slotStaff))
+	for i, xs := range st.slotStaff {
+		out[i] = append([]int(nil), xs...)
+	}
+	return out
+}
+
+func (m *model) restore(snap [][]int) *state {
+	st := m.newState()
+	for si, xs := range snap {
+		for _, s := range xs {
+			st.add(si, s)
+		}
+	}
+	return st
+}
+
+// result converts a state into a Result with assignments, unmet coverage and gap
+func (m *model) result(st *state, solver string) *Result {
+	res := &Result{Solver: solver}
+	for si, sl := range m.slots {
+		for _, s := range st.slotStaff[si] {
+			res.Assignments = append(res.Assignments, database.Assignment{
+				ID:           RandID(),
+				DepartmentID: m.in.DepartmentID,
+				StaffID:      m.staff[s].ID,
+				ShiftID:      m.shifts[sl.shift].ID,
+				ScheduleDate: m.date(sl.day),
+				Status:       "assigned",
+			})
+		}
+	}
+	sort.SliceStable(res.Assignments, func(i, j int) bool {
+		return res.Assignments[i].ScheduleDate < res.Assignments[j].ScheduleDate
+	})
+
+	unmetIdx := map[string]int{}
+	for si, sl := range m.slots {
+		res.RequiredSlots += sl.need
+		missing := sl.need - len(st.slotStaff[si])
+		if missing <= 0 {
+			continue
+		}
+		key := m.date(sl.day) + "|" + m.shifts[sl.shift].ID
+		i, ok := unmetIdx[key]
+		if !ok {
+			i = len(res.Unmet)
+			unmetIdx[key] = i
+			res.Unmet = append(res.Unmet, UnmetSlot{Date: m.date(sl.day), ShiftID: m.shifts[sl.shift].ID, ShiftName: m.shifts[sl.shift].Name})
+		}
+		if sl.role == "assistant" {
+			res.Unmet[i].MissingAssistants += missing
+		} else {
+			res.Unmet[i].MissingNurses += missing
+		}
+		res.UnmetTotal += missing
+		for _, s := range m.byRole[sl.role] {
+			reason := st.blockReason(s, si)
+			if reason == "already-assigned" {
+				continue
+			}
+			if reason == "" {
+				// eligible now but left out by the solver (e.g. time budget ran out)
+				reason = "unknown"
+			}
+			res.Unmet[i].Candidates = append(res.Unmet[i].Candidates, BlockedCandidate{
+				StaffID: m.staff[s].ID, StaffName: m.staff[s].Name, Role: sl.role, Reason: reason,
+			})
+		}
+	}
+
+	// skill requirements the staff of each shift occurrence leave uncovered
+	for cell, h := range st.holders {
+		if h == nil {
+			continue
+		}
+		day, k := cell/len(m.shifts), cell%len(m.shifts)
+		var ids []string
+		for _, role := range []string{"nurse", "assistant"} {
+			if si, ok := m.slotFor(m.date(day), m.shifts[k].ID, role); ok {
+				for _, s := range st.slotStaff[si] {
+					ids = append(ids, m.staff[s].ID)
+				}
+			}
+		}
+		short := m.skills.shortfalls(m.shifts[k].ID, m.date(day), ids)
+		if len(short) == 0 {
+			continue
+		}
+		key := m.date(day) + "|" + m.shifts[k].ID
+		i, ok := unmetIdx[key]
+		if !ok {
+			i = len(res.Unmet)
+			unmetIdx[key] = i
+			res.Unmet = append(res.Unmet, UnmetSlot{Date: m.date(day), ShiftID: m.shifts[k].ID, ShiftName: m.shifts[k].Name})
+		}
+		res.Unmet[i].MissingSkills = short
+		for _, x := range short {
+			res.SkillShortTotal += x.Required - x.Assigned
+		}
+	}
+	sort.SliceStable(res.Unmet, func(i, j int) bool { return res.Unmet[i].Date < res.Unmet[j].Date })
+	res.Lapsed = LapsedAssignments(m.in, res.Assignments)
+	res.Satisfaction = m.prefs.satisfaction(m.staff, m.shifts, res.Assignments)
+	res.Objective = st.objective()
+	res.LowerBound = m.lowerBound()
+	if res.Objective > 0 {
+		res.Gap = (res.Objective - res.LowerBound) / res.Objective
+		if res.Gap < 0 {
+			res.Gap = 0
+		}
+	}
+	res.Optimal = res.Objective-res.LowerBound < 1e-6
+	return res
+}
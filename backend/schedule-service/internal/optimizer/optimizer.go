@@ -2,8 +2,8 @@ package optimizer
 
 import (
 	"fmt"
+	"math"
 	"os"
-	"sort"
 	"time"
 
 	"nurseshift/schedule-service/internal/infrastructure/database"
@@ -20,9 +20,20 @@ type Input struct {
 	Holidays       []database.Holiday    // Start/End = YYYY-MM-DD
 	Leaves         []database.LeaveRange // StaffID, Start/End
 	MaxDiffAllowed int
+	Rules          RuleSet                    // from scheduling_priorities; zero value keeps legacy constraints
+	Preferences    []database.StaffPreference // soft: shift types, weekdays and request-off days
+	Competencies   database.Competencies      // hard: skills each shift requires and who holds them
+	Context        []database.Assignment      // fixed roster around the month (see BoundaryContextDays)
+	Ledger         []database.FairnessEntry   // earlier months of the fairness window, balanced cumulatively
 }
 
-// SolveMonth builds assignments using fairness-weighted greedy with hard constraints (no same-day, no consecutive-day, no leave/holiday/non-working).
+// BoundaryContextDays is how many days of the neighbouring months' rosters the generators take as
+// fixed context, so runs, rest and transitions carry across the month boundary
+const BoundaryContextDays = 14
+
+// SolveMonth builds assignments using fairness-weighted greedy with hard constraints (no overlap, no holiday/non-working)
+// plus the department's scheduling rules (leave, consecutive days/nights, contiguous hours) as hard or soft constraints
+// and staff preferences as soft constraints. Shifts' skill requirements are seated first and never given up.
 func SolveMonth(in Input) ([]database.Assignment, error) {
 	debug := os.Getenv("SCHEDULE_DEBUG") == "1"
 	dlog := func(format string, a ...any) {
@@ -40,7 +51,7 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 	next := first.AddDate(0, 1, 0)
 	days := int(next.Sub(first).Hours() / 24)
 
-	// Build holiday and leave maps
+	// Build holiday map (leave is checked by the rule tracker)
 	isHoliday := func(d time.Time) bool {
 		ds := d.Format("2006-01-02")
 		for _, h := range in.Holidays {
@@ -50,18 +61,6 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 		}
 		return false
 	}
-	leave := map[string]map[string]bool{}
-	for _, lv := range in.Leaves {
-		if leave[lv.StaffID] == nil {
-			leave[lv.StaffID] = map[string]bool{}
-		}
-		// mark all days in range
-		start, _ := time.Parse("2006-01-02", lv.Start)
-		end, _ := time.Parse("2006-01-02", lv.End)
-		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
-			leave[lv.StaffID][d.Format("2006-01-02")] = true
-		}
-	}
 
 	// Split roles
 	nurseIDs := []string{}
@@ -150,109 +149,21 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 	assignments := []database.Assignment{}
 	count := map[string]int{}
 	countByShift := map[string]map[string]int{} // staffID -> shiftID -> count
-	lastDay := map[string]int{}
-	// allow multiple non-overlapping shifts/day with max contiguous-hour limit
-	assignedIntervals := map[string]map[string][][2]int{} // staffID -> date -> list of [start,end] minutes
-
-	// helpers for time
-	parseHM := func(hm string) (int, bool) {
-		var hh, mm int
-		if _, err := fmt.Sscanf(hm, "%d:%d", &hh, &mm); err != nil {
-			return 0, false
-		}
-		return hh*60 + mm, true
-	}
-	shiftInterval := func(sh database.ShiftRecord) (int, int, bool) {
-		s, ok1 := parseHM(sh.StartTime)
-		e, ok2 := parseHM(sh.EndTime)
-		if !ok1 || !ok2 {
-			return 0, 0, false
-		}
-		if e <= s {
-			e += 24 * 60
-		}
-		return s, e, true
-	}
-	overlaps := func(aS, aE, bS, bE int) bool { return aS < bE && bS < aE }
-	mergeAndMaxContiguous := func(ivals [][2]int) int {
-		if len(ivals) == 0 {
-			return 0
-		}
-		sort.Slice(ivals, func(i, j int) bool { return ivals[i][0] < ivals[j][0] })
-		curS, curE := ivals[0][0], ivals[0][1]
-		maxDur := curE - curS
-		for i := 1; i < len(ivals); i++ {
-			s, e := ivals[i][0], ivals[i][1]
-			if s <= curE {
-				if e > curE {
-					curE = e
-				}
-			} else {
-				if curE-curS > maxDur {
-					maxDur = curE - curS
-				}
-				curS, curE = s, e
-			}
-		}
-		if curE-curS > maxDur {
-			maxDur = curE - curS
-		}
-		return maxDur
+	// leave, consecutive days/nights, overlap and contiguous hours come from the rule tracker
+	rules, err := NewRuleTracker(in)
+	if err != nil {
+		return nil, err
 	}
-	maxContiguousMinutes := 16 * 60
 
-	// helper
+	skills := newSkillIndex(in.Competencies)
+	slotStaff := map[string][]string{} // date|shiftID -> staff on it
+
 	isEligible := func(staffID, date string, d time.Time, sh database.ShiftRecord) bool {
-		if leave[staffID][date] {
-			return false
-		}
-		if prev, ok := lastDay[staffID]; ok {
-			pd := time.Date(year, m, prev, 0, 0, 0, 0, time.UTC)
-			if pd.AddDate(0, 0, 1).Equal(d) {
-				return false
-			}
-		}
-		s, e, ok := shiftInterval(sh)
-		if !ok {
-			return false
-		}
-		if assignedIntervals[staffID] == nil {
-			assignedIntervals[staffID] = map[string][][2]int{}
-		}
-		cur := assignedIntervals[staffID][date]
-		for _, iv := range cur {
-			if overlaps(iv[0], iv[1], s, e) {
-				return false
-			}
-		}
-		merged := append(append([][2]int{}, cur...), [2]int{s, e})
-		if mergeAndMaxContiguous(merged) > maxContiguousMinutes {
-			return false
-		}
-		return true
+		return rules.Reason(staffID, d, sh) == ""
 	}
 	reason := func(staffID, date string, d time.Time, sh database.ShiftRecord) string {
-		if leave[staffID][date] {
-			return "leave"
-		}
-		if prev, ok := lastDay[staffID]; ok {
-			pd := time.Date(year, m, prev, 0, 0, 0, 0, time.UTC)
-			if pd.AddDate(0, 0, 1).Equal(d) {
-				return "consecutive-day"
-			}
-		}
-		s, e, ok := shiftInterval(sh)
-		if !ok {
-			return "invalid-shift"
-		}
-		for _, iv := range assignedIntervals[staffID][date] {
-			if overlaps(iv[0], iv[1], s, e) {
-				return "overlap"
-			}
-		}
-		merged := append(append([][2]int{}, assignedIntervals[staffID][date]...), [2]int{s, e})
-		if mergeAndMaxContiguous(merged) > maxContiguousMinutes {
-			return "exceed-contiguous-hours"
+		if r := rules.Reason(staffID, d, sh); r != "" {
+			return r
 		}
 		return "unknown"
 	}
@@ -262,17 +173,63 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 		}
 		return diff * diff * 10
 	}
-	cost := func(role, staffID, shiftID string) int {
-		totalTarget := roleTarget[role]
+	cost := func(role, staffID string, d time.Time, sh database.ShiftRecord) int {
+		// targets drop by what the staff member is ahead over the fairness ledger's window
+		totalTarget := roleTarget[role] - int(math.Round(rules.ledger.totalLead(staffID)))
 		totalDiff := count[staffID] - totalTarget
 		// per-shift target/diff
-		st := roleShiftTarget[role][shiftID]
+		st := roleShiftTarget[role][sh.ID] - int(math.Round(rules.ledger.shiftLead(staffID, sh.ID)))
+		perShiftDiff := countByShift[staffID][sh.ID] - st
+		// weight per-shift balancing a bit stronger, plus soft scheduling rules
+		return penalty(totalDiff) + 3*penalty(perShiftDiff) + int(rules.Cost(staffID, d, sh))
+	}
+
+	// place/unplace keep every tracker in sync when a staff member gains or loses a shift
+	place := func(staffID string, d time.Time, sh database.ShiftRecord) {
+		count[staffID]++
 		if countByShift[staffID] == nil {
 			countByShift[staffID] = map[string]int{}
 		}
-		perShiftDiff := countByShift[staffID][shiftID] - st
-		// weight per-shift balancing a bit stronger
-		return penalty(totalDiff) + 3*penalty(perShiftDiff)
+		countByShift[staffID][sh.ID]++
+		rules.Place(staffID, d, sh)
+		key := d.Format("2006-01-02") + "|" + sh.ID
+		slotStaff[key] = append(slotStaff[key], staffID)
+	}
+	unplace := func(staffID string, d time.Time, sh database.ShiftRecord) {
+		count[staffID]--
+		countByShift[staffID][sh.ID]--
+		rules.Unplace(staffID, d, sh)
+		key := d.Format("2006-01-02") + "|" + sh.ID
+		for i, id := range slotStaff[key] {
+			if id == staffID {
+				slotStaff[key] = append(slotStaff[key][:i:i], slotStaff[key][i+1:]...)
+				break
+			}
+		}
+	}
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range in.Shifts {
+		shiftByID[sh.ID] = sh
+	}
+	// keepsSkills reports whether assignments[i] can go to toID without leaving its shift short of a required skill
+	keepsSkills := func(i int, toID string) bool {
+		a := assignments[i]
+		key := a.ScheduleDate + "|" + a.ShiftID
+		for _, r := range skills.reqs[a.ShiftID] {
+			if skills.qualified(a.StaffID, r.skill, a.ScheduleDate) && !skills.qualified(toID, r.skill, a.ScheduleDate) && skills.holders(slotStaff[key], r.skill, a.ScheduleDate) <= r.min {
+				return false
+			}
+		}
+		return true
+	}
+	// reassign moves assignments[i] to another staff member
+	reassign := func(i int, toID string) {
+		a := assignments[i]
+		d, _ := time.Parse("2006-01-02", a.ScheduleDate)
+		sh := shiftByID[a.ShiftID]
+		unplace(a.StaffID, d, sh)
+		place(toID, d, sh)
+		assignments[i].StaffID = toID
 	}
 
 	// Seed pass: assure at least 1 shift for everyone if capacity allows
@@ -293,6 +250,58 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 		}
 	}
 
+	// Build helper maps for the skill pass and rebalancing
+	staffRole := map[string]string{}
+	for _, s := range in.Staff {
+		r := s.Position
+		if r == "assistant" || r == "ผู้ช่วยพยาบาล" || r == "ผู้ช่วย" {
+			staffRole[s.ID] = "assistant"
+		} else {
+			staffRole[s.ID] = "nurse"
+		}
+	}
+
+	// Skill pass: seat the qualified staff each shift requires before anyone else takes the places
+	for day := 1; day <= days; day++ {
+		d := time.Date(year, m, day, 0, 0, 0, 0, time.UTC)
+		dateStr := d.Format("2006-01-02")
+		if capacity[dateStr] == nil {
+			continue
+		}
+		for _, sh := range in.Shifts {
+			key := dateStr + "|" + sh.ID
+			for _, r := range skills.reqs[sh.ID] {
+				for skills.holders(slotStaff[key], r.skill, dateStr) < r.min {
+					best, bestCost := "", 1<<30
+					for _, s := range in.Staff {
+						role := staffRole[s.ID]
+						rem := capacity[dateStr][sh.ID].n
+						if role == "assistant" {
+							rem = capacity[dateStr][sh.ID].a
+						}
+						if rem <= 0 || !skills.qualified(s.ID, r.skill, dateStr) || !isEligible(s.ID, dateStr, d, sh) {
+							continue
+						}
+						if c := cost(role, s.ID, d, sh); c < bestCost {
+							best, bestCost = s.ID, c
+						}
+					}
+					if best == "" {
+						dlog("skill: %s %s short of %s", dateStr, sh.Name, skills.names[r.skill])
+						break
+					}
+					assignments = append(assignments, database.Assignment{ID: RandID(), DepartmentID: in.DepartmentID, StaffID: best, ShiftID: sh.ID, ScheduleDate: dateStr, Status: "assigned"})
+					place(best, d, sh)
+					if staffRole[best] == "assistant" {
+						capacity[dateStr][sh.ID].a--
+					} else {
+						capacity[dateStr][sh.ID].n--
+					}
+				}
+			}
+		}
+	}
+
 	seedOnce := func(ids []string, role string) {
 		for _, id := range ids {
 			if count[id] > 0 {
@@ -324,18 +333,7 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 						continue
 					}
 					assignments = append(assignments, database.Assignment{ID: RandID(), DepartmentID: in.DepartmentID, StaffID: id, ShiftID: sh.ID, ScheduleDate: dateStr, Status: "assigned"})
-					count[id]++
-					if countByShift[id] == nil {
-						countByShift[id] = map[string]int{}
-					}
-					countByShift[id][sh.ID]++
-					lastDay[id] = day
-					if assignedIntervals[id] == nil {
-						assignedIntervals[id] = map[string][][2]int{}
-					}
-					if s, e, ok := shiftInterval(sh); ok {
-						assignedIntervals[id][dateStr] = append(assignedIntervals[id][dateStr], [2]int{s, e})
-					}
+					place(id, d, sh)
 					if role == "assistant" {
 						capacity[dateStr][sh.ID].a--
 					} else {
@@ -377,7 +375,7 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 					if !isEligible(id, dateStr, d, sh) {
 						continue
 					}
-					c := cost("nurse", id, sh.ID)
+					c := cost("nurse", id, d, sh)
 					if c < bestCost {
 						bestCost = c
 						best = id
@@ -387,18 +385,7 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 					break
 				}
 				assignments = append(assignments, database.Assignment{ID: RandID(), DepartmentID: in.DepartmentID, StaffID: best, ShiftID: sh.ID, ScheduleDate: dateStr, Status: "assigned"})
-				count[best]++
-				if countByShift[best] == nil {
-					countByShift[best] = map[string]int{}
-				}
-				countByShift[best][sh.ID]++
-				lastDay[best] = day
-				if assignedIntervals[best] == nil {
-					assignedIntervals[best] = map[string][][2]int{}
-				}
-				if s, e, ok := shiftInterval(sh); ok {
-					assignedIntervals[best][dateStr] = append(assignedIntervals[best][dateStr], [2]int{s, e})
-				}
+				place(best, d, sh)
 				if capacity[dateStr][sh.ID] != nil {
 					capacity[dateStr][sh.ID].n--
 				}
@@ -416,7 +403,7 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 					if !isEligible(id, dateStr, d, sh) {
 						continue
 					}
-					c := cost("assistant", id, sh.ID)
+					c := cost("assistant", id, d, sh)
 					if c < bestCost {
 						bestCost = c
 						best = id
@@ -426,18 +413,7 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 					break
 				}
 				assignments = append(assignments, database.Assignment{ID: RandID(), DepartmentID: in.DepartmentID, StaffID: best, ShiftID: sh.ID, ScheduleDate: dateStr, Status: "assigned"})
-				count[best]++
-				if countByShift[best] == nil {
-					countByShift[best] = map[string]int{}
-				}
-				countByShift[best][sh.ID]++
-				lastDay[best] = day
-				if assignedIntervals[best] == nil {
-					assignedIntervals[best] = map[string][][2]int{}
-				}
-				if s, e, ok := shiftInterval(sh); ok {
-					assignedIntervals[best][dateStr] = append(assignedIntervals[best][dateStr], [2]int{s, e})
-				}
+				place(best, d, sh)
 				if capacity[dateStr][sh.ID] != nil {
 					capacity[dateStr][sh.ID].a--
 				}
@@ -446,21 +422,6 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 		}
 	}
 
-	// Build helper maps for rebalancing
-	shiftByID := map[string]database.ShiftRecord{}
-	for _, sh := range in.Shifts {
-		shiftByID[sh.ID] = sh
-	}
-	staffRole := map[string]string{}
-	for _, s := range in.Staff {
-		r := s.Position
-		if r == "assistant" || r == "ผู้ช่วยพยาบาล" || r == "ผู้ช่วย" {
-			staffRole[s.ID] = "assistant"
-		} else {
-			staffRole[s.ID] = "nurse"
-		}
-	}
-
 	// Ensure everyone gets at least one shift if possible by swapping
 	ensureMinimumOne := func(roleIDs []string) {
 		for _, lowID := range roleIDs {
@@ -479,26 +440,10 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 				// check eligibility
 				d, _ := time.Parse("2006-01-02", a.ScheduleDate)
 				sh := shiftByID[a.ShiftID]
-				if !isEligible(lowID, a.ScheduleDate, d, sh) {
+				if !isEligible(lowID, a.ScheduleDate, d, sh) || !keepsSkills(i, lowID) {
 					continue
 				}
-				// update intervals maps
-				if s, e, ok := shiftInterval(sh); ok {
-					// remove from holder
-					old := assignedIntervals[a.StaffID][a.ScheduleDate]
-					nxt := make([][2]int, 0, len(old))
-					for _, iv := range old {
-						if !(iv[0] == s && iv[1] == e) {
-							nxt = append(nxt, iv)
-						}
-					}
-					assignedIntervals[a.StaffID][a.ScheduleDate] = nxt
-					// add to lowID
-					assignedIntervals[lowID][a.ScheduleDate] = append(assignedIntervals[lowID][a.ScheduleDate], [2]int{s, e})
-				}
-				assignments[i].StaffID = lowID
-				count[lowID]++
-				count[a.StaffID]--
+				reassign(i, lowID)
 				break
 			}
 		}
@@ -550,26 +495,10 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 				a := assignments[i]
 				d, _ := time.Parse("2006-01-02", a.ScheduleDate)
 				sh := shiftByID[a.ShiftID]
-				if !isEligible(lowID, a.ScheduleDate, d, sh) {
+				if !isEligible(lowID, a.ScheduleDate, d, sh) || !keepsSkills(i, lowID) {
 					continue
 				}
-				// swap
-				if s, e, ok := shiftInterval(sh); ok {
-					// remove from high
-					old := assignedIntervals[highID][a.ScheduleDate]
-					nxt := make([][2]int, 0, len(old))
-					for _, iv := range old {
-						if !(iv[0] == s && iv[1] == e) {
-							nxt = append(nxt, iv)
-						}
-					}
-					assignedIntervals[highID][a.ScheduleDate] = nxt
-					// add to low
-					assignedIntervals[lowID][a.ScheduleDate] = append(assignedIntervals[lowID][a.ScheduleDate], [2]int{s, e})
-				}
-				assignments[i].StaffID = lowID
-				count[lowID]++
-				count[highID]--
+				reassign(i, lowID)
 				moved = true
 				break
 			}
@@ -624,7 +553,7 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 				bestID := ""
 				bestCnt := 1<<31 - 1
 				for _, cid := range cands {
-					if !isEligible(cid, a.ScheduleDate, d, sh) {
+					if !isEligible(cid, a.ScheduleDate, d, sh) || !keepsSkills(i, cid) {
 						continue
 					}
 					if count[cid] < bestCnt {
@@ -634,21 +563,7 @@ func SolveMonth(in Input) ([]database.Assignment, error) {
 				if bestID == "" {
 					continue
 				}
-				// perform reassignment and update interval maps
-				if s, e, ok := shiftInterval(sh); ok {
-					old := assignedIntervals[highID][a.ScheduleDate]
-					nxt := make([][2]int, 0, len(old))
-					for _, iv := range old {
-						if !(iv[0] == s && iv[1] == e) {
-							nxt = append(nxt, iv)
-						}
-					}
-					assignedIntervals[highID][a.ScheduleDate] = nxt
-					assignedIntervals[bestID][a.ScheduleDate] = append(assignedIntervals[bestID][a.ScheduleDate], [2]int{s, e})
-				}
-				assignments[i].StaffID = bestID
-				count[bestID]++
-				count[highID]--
+				reassign(i, bestID)
 				moved = true
 				break
 			}
@@ -0,0 +1,177 @@
+package optimizer
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// Preference penalties at weight 1, in units of rulePenalty: working a requested day off costs the
+// most, an avoided shift type half of it, and a shift outside the preferred types or weekdays a quarter
+const (
+	requestOffPenalty   = 1.0
+	avoidedShiftPenalty = 0.5
+	unpreferredPenalty  = 0.25
+)
+
+// Satisfaction is how well a roster meets one staff member's preferences. Score is the share of
+// their requested days off kept and of their shifts matching every stated preference, 0-100; staff
+// who asked for nothing score 100.
+type Satisfaction struct {
+	StaffID             string  `json:"staffId"`
+	StaffName           string  `json:"staffName"`
+	Score               float64 `json:"score"`
+	RequestsOff         int     `json:"requestsOff"`
+	RequestsOffHonoured int     `json:"requestsOffHonoured"`
+	Shifts              int     `json:"shifts"`
+	MatchingShifts      int     `json:"matchingShifts"`
+	AvoidedShifts       int     `json:"avoidedShifts"`
+}
+
+// staffPrefs is one staff member's preferences indexed for lookups
+type staffPrefs struct {
+	preferred map[string]bool // shift types, lower case
+	avoided   map[string]bool
+	weekdays  map[int]bool
+	off       map[string]bool // YYYY-MM-DD
+}
+
+func (p *staffPrefs) hasShiftPrefs() bool {
+	return len(p.preferred) > 0 || len(p.avoided) > 0 || len(p.weekdays) > 0
+}
+
+// preferences are Input.Preferences as soft constraints, priced at the weight of the
+// requested-days-off rule
+type preferences struct {
+	byStaff map[string]*staffPrefs
+	weight  float64
+}
+
+func newPreferences(in Input) preferences {
+	p := preferences{byStaff: map[string]*staffPrefs{}, weight: in.Rules.limits().prefWeight}
+	lower := func(xs []string) map[string]bool {
+		out := map[string]bool{}
+		for _, x := range xs {
+			if x = strings.ToLower(strings.TrimSpace(x)); x != "" {
+				out[x] = true
+			}
+		}
+		return out
+	}
+	for _, pref := range in.Preferences {
+		sp := &staffPrefs{preferred: lower(pref.PreferredShiftTypes), avoided: lower(pref.AvoidedShiftTypes), weekdays: map[int]bool{}, off: map[string]bool{}}
+		for _, d := range pref.PreferredWeekdays {
+			sp.weekdays[d] = true
+		}
+		for _, d := range pref.RequestsOff {
+			sp.off[d] = true
+		}
+		if sp.hasShiftPrefs() || len(sp.off) > 0 {
+			p.byStaff[pref.StaffID] = sp
+		}
+	}
+	return p
+}
+
+// mismatch is the cost, at weight 1, of sh on d against the shift-type and weekday preferences
+func (sp *staffPrefs) mismatch(d time.Time, sh database.ShiftRecord) float64 {
+	c := 0.0
+	t := strings.ToLower(sh.Type)
+	if sp.avoided[t] {
+		c += avoidedShiftPenalty
+	}
+	if len(sp.preferred) > 0 && !sp.preferred[t] {
+		c += unpreferredPenalty
+	}
+	if len(sp.weekdays) > 0 && !sp.weekdays[int(d.Weekday())] {
+		c += unpreferredPenalty
+	}
+	return c
+}
+
+// shiftPenalty is the preference cost, at weight 1, of staffID working sh on d
+func (p preferences) shiftPenalty(staffID string, d time.Time, sh database.ShiftRecord) float64 {
+	sp := p.byStaff[staffID]
+	if sp == nil {
+		return 0
+	}
+	c := sp.mismatch(d, sh)
+	if sp.off[d.Format("2006-01-02")] {
+		c += requestOffPenalty
+	}
+	return c
+}
+
+// cost prices staffID working sh on d
+func (p preferences) cost(staffID string, d time.Time, sh database.ShiftRecord) float64 {
+	if p.weight == 0 {
+		return 0
+	}
+	return rulePenalty * p.weight * p.shiftPenalty(staffID, d, sh)
+}
+
+// satisfaction scores assignments against every staff member's preferences, in staff order
+func (p preferences) satisfaction(staff []database.DepartmentStaff, shifts []database.ShiftRecord, assignments []database.Assignment) []Satisfaction {
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range shifts {
+		shiftByID[sh.ID] = sh
+	}
+	worked := map[string]map[string]bool{}
+	out := make([]Satisfaction, 0, len(staff))
+	index := map[string]int{}
+	for _, s := range staff {
+		index[s.ID] = len(out)
+		out = append(out, Satisfaction{StaffID: s.ID, StaffName: s.Name})
+		worked[s.ID] = map[string]bool{}
+	}
+	for _, a := range assignments {
+		i, ok := index[a.StaffID]
+		if !ok {
+			continue
+		}
+		worked[a.StaffID][a.ScheduleDate] = true
+		out[i].Shifts++
+		sp := p.byStaff[a.StaffID]
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if sp == nil || err != nil {
+			continue
+		}
+		sh := shiftByID[a.ShiftID]
+		if sp.avoided[strings.ToLower(sh.Type)] {
+			out[i].AvoidedShifts++
+		}
+		// a requested day off is counted on its own, not as a mismatched shift
+		if sp.mismatch(d, sh) == 0 {
+			out[i].MatchingShifts++
+		}
+	}
+	for i := range out {
+		s := &out[i]
+		sp := p.byStaff[s.StaffID]
+		if sp == nil {
+			s.MatchingShifts = s.Shifts
+			s.Score = 100
+			continue
+		}
+		s.RequestsOff = len(sp.off)
+		for d := range sp.off {
+			if !worked[s.StaffID][d] {
+				s.RequestsOffHonoured++
+			}
+		}
+		asked, met := s.RequestsOff, s.RequestsOffHonoured
+		if sp.hasShiftPrefs() {
+			asked += s.Shifts
+			met += s.MatchingShifts
+		} else {
+			s.MatchingShifts = s.Shifts
+		}
+		s.Score = 100
+		if asked > 0 {
+			s.Score = math.Round(float64(met)/float64(asked)*1000) / 10
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package optimizer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func preferenceInput() Input {
+	return Input{
+		DepartmentID: "dept",
+		Month:        "2026-02",
+		Shifts: []database.ShiftRecord{
+			{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00", RequiredNurse: 1},
+			{ID: "n", Name: "ดึก", Type: "night", StartTime: "00:00", EndTime: "08:00", RequiredNurse: 1},
+		},
+		Staff: []database.DepartmentStaff{
+			{ID: "a", Name: "A", Position: "nurse"},
+			{ID: "b", Name: "B", Position: "nurse"},
+			{ID: "c", Name: "C", Position: "nurse"},
+			{ID: "d", Name: "D", Position: "nurse"},
+		},
+		Rules: BuildRules(nil),
+		Preferences: []database.StaffPreference{
+			{StaffID: "a", RequestsOff: []string{"2026-02-02", "2026-02-03"}},
+			{StaffID: "b", AvoidedShiftTypes: []string{"night"}},
+		},
+	}
+}
+
+func TestSolveMonthHonoursPreferences(t *testing.T) {
+	in := preferenceInput()
+	out, err := SolveMonth(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range out {
+		if a.StaffID == "a" && (a.ScheduleDate == "2026-02-02" || a.ScheduleDate == "2026-02-03") {
+			t.Errorf("a works %s %s, a requested day off", a.ScheduleDate, a.ShiftID)
+		}
+	}
+
+	sat := newPreferences(in).satisfaction(in.Staff, in.Shifts, out)
+	byID := map[string]Satisfaction{}
+	for _, s := range sat {
+		byID[s.StaffID] = s
+	}
+	if a := byID["a"]; a.RequestsOff != 2 || a.RequestsOffHonoured != 2 {
+		t.Errorf("a = %+v, want both days off honoured", a)
+	}
+	if c := byID["c"]; c.Score != 100 {
+		t.Errorf("c asked for nothing, score = %v", c.Score)
+	}
+	if b := byID["b"]; b.Shifts == 0 || b.MatchingShifts != b.Shifts-b.AvoidedShifts || b.Score != math.Round(float64(b.MatchingShifts)/float64(b.Shifts)*1000)/10 {
+		t.Errorf("b = %+v, score should be the share of non-night shifts", b)
+	}
+}
+
+func TestPreferencesIgnoredWithoutRequestedDaysOffRule(t *testing.T) {
+	in := preferenceInput()
+	in.Rules = BuildRules([]database.SchedulingPriority{{Name: PriorityMaxConsecutiveShifts, Order: 1, IsActive: true}})
+	p := newPreferences(in)
+	d := mustDay(t, "2026-02-02")
+	if c := p.cost("a", d, in.Shifts[0]); c != 0 {
+		t.Errorf("cost = %v, want 0 when the requested-days-off priority is off", c)
+	}
+	in.Rules = BuildRules(nil)
+	if c := newPreferences(in).cost("a", d, in.Shifts[0]); c <= 0 {
+		t.Errorf("cost = %v, want a penalty for working a requested day off", c)
+	}
+}
+
+func mustDay(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestRuleTrackerPricesPreferences(t *testing.T) {
+	in := preferenceInput()
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	morning, night := in.Shifts[0], in.Shifts[1]
+	off := mustDay(t, "2026-02-02")
+	if a, c := tr.Cost("a", off, morning), tr.Cost("c", off, morning); a <= c {
+		t.Errorf("a's requested day off costs %v, no more than c's ordinary day %v", a, c)
+	}
+	if b, c := tr.Cost("b", off, night), tr.Cost("c", off, night); b <= c {
+		t.Errorf("b's avoided night costs %v, no more than c's %v", b, c)
+	}
+
+	sat := tr.Satisfaction(in.Staff, in.Shifts, []database.Assignment{{StaffID: "b", ShiftID: "n", ScheduleDate: "2026-02-02"}})
+	for _, s := range sat {
+		if s.StaffID == "b" && (s.Shifts != 1 || s.AvoidedShifts != 1 || s.Score != 0) {
+			t.Errorf("b = %+v, want one avoided shift and a score of 0", s)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package optimizer
+
+import (
+	"sort"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// handOverWindowDays bounds how far from a vacated date a colleague's shift may be handed over
+const handOverWindowDays = 7
+
+// RepairChange is one edit of a repair change set
+type RepairChange struct {
+	Action  string `json:"action"` // remove | add
+	StaffID string `json:"staffId,omitempty"`
+	ShiftID string `json:"shiftId"`
+	Date    string `json:"date"`
+	Reason  string `json:"reason"` // leave | refill | hand-over
+}
+
+// RepairResult is a minimal-change repair of a roster after a leave is approved
+type RepairResult struct {
+	Changes    []RepairChange `json:"changes"`
+	Unfilled   []RepairChange `json:"unfilled"`   // vacated slots nobody can take without breaking a hard rule
+	Moved      int            `json:"moved"`      // existing assignments of other staff that change hands
+	Violations []Violation    `json:"violations"` // required skills the repair cannot keep covered, adds on lapsed certifications
+}
+
+// heldShift is one assignment on the roster being repaired
+type heldShift struct {
+	d  time.Time
+	sh database.ShiftRecord
+}
+
+func (h heldShift) change(action, staffID, reason string) RepairChange {
+	return RepairChange{Action: action, StaffID: staffID, ShiftID: h.sh.ID, Date: h.d.Format("2006-01-02"), Reason: reason}
+}
+
+// RepairLeave unassigns staffID from current between start and end (YYYY-MM-DD, inclusive) and refills the
+// vacated slots. A direct substitute is preferred; otherwise a colleague takes the slot and hands one of
+// their nearby shifts to a third person, so as few existing assignments as possible change. Staff who
+// hold a skill a slot is left short of are preferred, and a shift that ends up short of a required skill
+// it was covered for is reported as a violation.
+func RepairLeave(in Input, current []database.Assignment, staffID, start, end string) (*RepairResult, error) {
+	in.Leaves = append(append([]database.LeaveRange{}, in.Leaves...), database.LeaveRange{StaffID: staffID, Start: start, End: end})
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		return nil, err
+	}
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range in.Shifts {
+		shiftByID[sh.ID] = sh
+	}
+	byRole := map[string][]string{}
+	for _, s := range in.Staff {
+		r := staffRoleOf(s.Position)
+		byRole[r] = append(byRole[r], s.ID)
+	}
+	role := "nurse"
+	for _, s := range in.Staff {
+		if s.ID == staffID {
+			role = staffRoleOf(s.Position)
+		}
+	}
+
+	holds := map[string][]heldShift{} // staffID -> assignments still on the roster
+	before := map[string][]string{}   // date|shiftID -> staff before the repair
+	var vacated []heldShift
+	for _, a := range current {
+		sh, ok := shiftByID[a.ShiftID]
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if !ok || err != nil || a.StaffID == "" {
+			continue
+		}
+		before[a.ScheduleDate+"|"+a.ShiftID] = append(before[a.ScheduleDate+"|"+a.ShiftID], a.StaffID)
+		if a.StaffID == staffID && a.ScheduleDate >= start && a.ScheduleDate <= end {
+			vacated = append(vacated, heldShift{d, sh})
+			continue
+		}
+		tr.Place(a.StaffID, d, sh)
+		holds[a.StaffID] = append(holds[a.StaffID], heldShift{d, sh})
+	}
+	sort.Slice(vacated, func(i, j int) bool {
+		if !vacated[i].d.Equal(vacated[j].d) {
+			return vacated[i].d.Before(vacated[j].d)
+		}
+		return vacated[i].sh.StartTime < vacated[j].sh.StartTime
+	})
+
+	// best returns the eligible colleague with the lowest soft-rule cost and load, never the leaver or skip;
+	// one who holds a skill the shift is short of comes first and one whose certification for it lapsed last
+	best := func(h heldShift, skip string) string {
+		pick, pickRank, pickScore := "", 0, 0.0
+		date := h.d.Format("2006-01-02")
+		for _, id := range byRole[role] {
+			if id == staffID || id == skip || tr.Reason(id, h.d, h.sh) != "" {
+				continue
+			}
+			rank := 1
+			if tr.CoversSkill(id, h.d, h.sh) {
+				rank = 0
+			} else if len(tr.skills.lapsed(id, h.sh.ID, date)) > 0 {
+				rank = 2
+			}
+			score := tr.Cost(id, h.d, h.sh) + float64(len(holds[id]))
+			if pick == "" || rank < pickRank || (rank == pickRank && score < pickScore) {
+				pick, pickRank, pickScore = id, rank, score
+			}
+		}
+		return pick
+	}
+	assign := func(id string, h heldShift) {
+		tr.Place(id, h.d, h.sh)
+		holds[id] = append(holds[id], h)
+	}
+	// handOver lets colleague x take v after giving one of their nearby shifts to a third person
+	handOver := func(v heldShift) []RepairChange {
+		for _, x := range byRole[role] {
+			if x == staffID {
+				continue
+			}
+			for i, h := range holds[x] {
+				if gap := h.d.Sub(v.d).Hours() / 24; gap > handOverWindowDays || gap < -handOverWindowDays {
+					continue
+				}
+				tr.Unplace(x, h.d, h.sh)
+				if tr.Reason(x, v.d, v.sh) == "" {
+					tr.Place(x, v.d, v.sh)
+					if y := best(h, x); y != "" && tr.KeepsSkills(x, y, h.d, h.sh) {
+						holds[x] = append(holds[x][:i:i], holds[x][i+1:]...)
+						holds[x] = append(holds[x], v)
+						assign(y, h)
+						return []RepairChange{h.change("remove", x, "hand-over"), h.change("add", y, "hand-over"), v.change("add", x, "refill")}
+					}
+					tr.Unplace(x, v.d, v.sh)
+				}
+				tr.Place(x, h.d, h.sh)
+			}
+		}
+		return nil
+	}
+
+	res := &RepairResult{Changes: []RepairChange{}, Unfilled: []RepairChange{}, Violations: []Violation{}}
+	for _, v := range vacated {
+		res.Changes = append(res.Changes, v.change("remove", staffID, "leave"))
+		if id := best(v, ""); id != "" {
+			assign(id, v)
+			res.Changes = append(res.Changes, v.change("add", id, "refill"))
+			continue
+		}
+		if chain := handOver(v); chain != nil {
+			res.Changes = append(res.Changes, chain...)
+			res.Moved++
+			continue
+		}
+		res.Unfilled = append(res.Unfilled, v.change("add", "", "refill"))
+	}
+
+	seen := map[string]bool{}
+	for _, c := range res.Changes {
+		key := c.Date + "|" + c.ShiftID
+		if c.Action == "add" && len(tr.skills.lapsed(c.StaffID, c.ShiftID, c.Date)) > 0 {
+			res.Violations = append(res.Violations, Violation{StaffID: c.StaffID, ShiftID: c.ShiftID, Date: c.Date, Reason: "lapsed-certification"})
+		}
+		if !seen[key] {
+			seen[key] = true
+			res.Violations = append(res.Violations, tr.skills.lostSkills(c.ShiftID, c.Date, before[key], tr.slots[key])...)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,79 @@
+package optimizer
+
+import (
+	"fmt"
+	"testing"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func held(staffID, shiftID, date string) database.Assignment {
+	return database.Assignment{StaffID: staffID, ShiftID: shiftID, ScheduleDate: date}
+}
+
+func changeList(cs []RepairChange) []string {
+	out := make([]string, 0, len(cs))
+	for _, c := range cs {
+		out = append(out, fmt.Sprintf("%s %s %s %s %s", c.Action, c.StaffID, c.ShiftID, c.Date, c.Reason))
+	}
+	return out
+}
+
+func TestRepairLeaveDirectReplacement(t *testing.T) {
+	in := fixture("2026-02", 3, morningShift)
+	// b is the less loaded colleague; c already works the 3rd
+	current := []database.Assignment{held("a", "m", "2026-02-02"), held("c", "m", "2026-02-03")}
+	res, err := RepairLeave(in, current, "a", "2026-02-02", "2026-02-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"remove a m 2026-02-02 leave", "add b m 2026-02-02 refill"}
+	if fmt.Sprint(changeList(res.Changes)) != fmt.Sprint(want) {
+		t.Errorf("changes = %v, want %v", changeList(res.Changes), want)
+	}
+	if res.Moved != 0 || len(res.Unfilled) != 0 {
+		t.Errorf("moved %d, unfilled %v; want a substitute and nothing else", res.Moved, res.Unfilled)
+	}
+}
+
+func TestRepairLeaveHandOverChain(t *testing.T) {
+	// b works a day shift and c an early shift on the 2nd, both overlapping a's morning: nobody can
+	// take the morning directly, but b can once c takes b's day shift
+	day := database.ShiftRecord{ID: "d", Name: "กลางวัน", Type: "morning", StartTime: "10:00", EndTime: "18:00"}
+	early := database.ShiftRecord{ID: "x", Name: "เช้ามืด", Type: "morning", StartTime: "06:00", EndTime: "09:00"}
+	in := fixture("2026-02", 3, morningShift, day, early)
+	current := []database.Assignment{held("a", "m", "2026-02-02"), held("b", "d", "2026-02-02"), held("c", "x", "2026-02-02")}
+	res, err := RepairLeave(in, current, "a", "2026-02-02", "2026-02-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"remove a m 2026-02-02 leave",
+		"remove b d 2026-02-02 hand-over",
+		"add c d 2026-02-02 hand-over",
+		"add b m 2026-02-02 refill",
+	}
+	if fmt.Sprint(changeList(res.Changes)) != fmt.Sprint(want) {
+		t.Errorf("changes = %v, want %v", changeList(res.Changes), want)
+	}
+	if res.Moved != 1 || len(res.Unfilled) != 0 {
+		t.Errorf("moved %d, unfilled %v; want one handed-over shift", res.Moved, res.Unfilled)
+	}
+}
+
+func TestRepairLeaveReportsUncoveredSlots(t *testing.T) {
+	in := fixture("2026-02", 2, morningShift)
+	in.Leaves = []database.LeaveRange{{StaffID: "b", Start: "2026-02-03", End: "2026-02-03"}}
+	current := []database.Assignment{held("a", "m", "2026-02-02"), held("a", "m", "2026-02-03"), held("b", "m", "2026-02-05")}
+	res, err := RepairLeave(in, current, "a", "2026-02-02", "2026-02-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"remove a m 2026-02-02 leave", "add b m 2026-02-02 refill", "remove a m 2026-02-03 leave"}
+	if fmt.Sprint(changeList(res.Changes)) != fmt.Sprint(want) {
+		t.Errorf("changes = %v, want %v", changeList(res.Changes), want)
+	}
+	if got := changeList(res.Unfilled); len(got) != 1 || got[0] != "add  m 2026-02-03 refill" {
+		t.Errorf("unfilled = %v, want the 3rd reported as uncovered", got)
+	}
+}
@@ -0,0 +1,42 @@
+package optimizer
+
+import (
+	"testing"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func TestReportExplainsUnmetSlots(t *testing.T) {
+	// two nurses for a Monday shift needing two; b is on leave on Monday the 2nd
+	in := fixture("2026-02", 2, needs(morningShift, 2))
+	in.WorkingDays = map[int]bool{0: false, 1: true, 2: false, 3: false, 4: false, 5: false, 6: false}
+	in.Leaves = []database.LeaveRange{{StaffID: "b", Start: "2026-02-02", End: "2026-02-02"}}
+	for _, s := range solvers() {
+		rep := solve(t, s, in).Report(in.DepartmentID, in.Month)
+		if rep.Generator != "optimizer:"+s.Name() || rep.Month != in.Month || rep.Solver == nil {
+			t.Errorf("%s: header = %+v", s.Name(), rep)
+		}
+		if rep.RequiredSlots != 8 || rep.UnmetTotal != 1 || rep.FilledSlots != 7 {
+			t.Errorf("%s: required %d, unmet %d, filled %d; want 8, 1, 7", s.Name(), rep.RequiredSlots, rep.UnmetTotal, rep.FilledSlots)
+		}
+		if len(rep.Unmet) != 1 {
+			t.Fatalf("%s: unmet = %+v, want the 2nd only", s.Name(), rep.Unmet)
+		}
+		u := rep.Unmet[0]
+		if u.Date != "2026-02-02" || u.ShiftID != "m" || u.MissingNurses != 1 || u.MissingAssistants != 0 {
+			t.Errorf("%s: unmet slot = %+v", s.Name(), u)
+		}
+		// a already works the slot and is not a candidate; b is blocked by leave
+		if len(u.Candidates) != 1 || u.Candidates[0].StaffID != "b" || u.Candidates[0].Reason != "leave" || u.Candidates[0].Role != "nurse" {
+			t.Errorf("%s: candidates = %+v, want b blocked by leave", s.Name(), u.Candidates)
+		}
+	}
+}
+
+func TestReportOfFullRosterHasEmptyUnmet(t *testing.T) {
+	in := fixture("2026-02", 4, needs(morningShift, 1))
+	rep := solve(t, GreedySolver{}, in).Report(in.DepartmentID, in.Month)
+	if rep.Unmet == nil || len(rep.Unmet) != 0 || rep.FilledSlots != rep.RequiredSlots {
+		t.Errorf("report = %+v, want every slot filled and an empty (not null) unmet list", rep)
+	}
+}
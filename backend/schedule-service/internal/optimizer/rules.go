@@ -0,0 +1,257 @@
+package optimizer
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// Priority names seeded by priority-service (PriorityRepository.FindOrCreateDefaults)
+const (
+	PriorityRequestedDaysOff     = "วันที่ขอหยุด"
+	PriorityShiftTypeBalance     = "จำนวนเวรเท่ากันในแต่ละประเภท"
+	PriorityMaxConsecutiveNights = "จำนวนเวรดึกติดต่อกัน"
+	PriorityMaxConsecutiveShifts = "จำนวนเวรติดต่อกัน"
+	PriorityMaxContiguousHours   = "จำนวนชั่วโมงทำงานสูงสุดติดต่อกันโดยไม่พัก"
+	PriorityMaxTotalHours        = "จำนวนชั่วโมงการทำงานทั้งหมด"
+)
+
+// RuleKind identifies what a scheduling priority means to the generators
+type RuleKind string
+
+const (
+	RuleRequestedDaysOff     RuleKind = "requested-days-off"
+	RuleShiftTypeBalance     RuleKind = "shift-type-balance"
+	RuleMaxConsecutiveNights RuleKind = "max-consecutive-nights"
+	RuleMaxConsecutiveShifts RuleKind = "max-consecutive-shifts"
+	RuleMaxContiguousHours   RuleKind = "max-contiguous-hours"
+	RuleMaxTotalHours        RuleKind = "max-total-hours"
+	RuleTotalHoursBalance    RuleKind = "total-hours-balance"
+)
+
+// rulePenalty is the objective cost of one unit of soft-rule violation at weight 1
+const rulePenalty = 50.0
+
+// defaultHoursSpread is the total-hours spread balanced alongside the total-hours cap when its
+// config carries no maxDifference
+const defaultHoursSpread = 16
+
+// ruleSpec maps a priority name to its kind, default value (same defaults the priority UI shows)
+// and whether it is enforced as a hard constraint unless config says otherwise
+var ruleSpecs = map[string]struct {
+	kind  RuleKind
+	value int
+	hard  bool
+}{
+	PriorityRequestedDaysOff:     {RuleRequestedDaysOff, 0, true},
+	PriorityShiftTypeBalance:     {RuleShiftTypeBalance, 2, false},
+	PriorityMaxConsecutiveNights: {RuleMaxConsecutiveNights, 2, true},
+	PriorityMaxConsecutiveShifts: {RuleMaxConsecutiveShifts, 4, true},
+	PriorityMaxContiguousHours:   {RuleMaxContiguousHours, 48, true},
+	PriorityMaxTotalHours:        {RuleMaxTotalHours, 192, true},
+	// legacy names read by older handler code
+	"จำนวนเวรเท่าเทียมในแต่ละประเภท": {RuleShiftTypeBalance, 2, false},
+	"ชั่วโมงติดต่อกันสูงสุด":         {RuleMaxContiguousHours, 16, true},
+}
+
+// Rule is one active scheduling priority turned into a constraint.
+// Hard rules block assignments; soft rules add Weight-scaled penalties. Weight doubles with every
+// rank a rule climbs in priority_order, so reordering priorities changes the roster.
+type Rule struct {
+	Kind   RuleKind `json:"kind"`
+	Name   string   `json:"name"`
+	Order  int      `json:"order"`
+	Value  int      `json:"value"`
+	Hard   bool     `json:"hard"`
+	Weight float64  `json:"weight"`
+}
+
+// RuleSet is the set of active rules for a department. The zero value means "not configured" and
+// keeps the generators' legacy behaviour (no consecutive days, 16h contiguous limit).
+type RuleSet struct {
+	configured bool
+	rules      map[RuleKind]Rule
+	seq        sequence
+}
+
+// BuildRules converts scheduling_priorities rows into a RuleSet. A department without rows gets
+// the same defaults priority-service would seed for it.
+func BuildRules(records []database.SchedulingPriority) RuleSet {
+	if len(records) == 0 {
+		records = DefaultPriorities()
+	}
+	sorted := append([]database.SchedulingPriority(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+
+	var active []Rule
+	for _, rec := range sorted {
+		spec, ok := ruleSpecs[strings.TrimSpace(rec.Name)]
+		if !ok || !rec.IsActive {
+			continue
+		}
+		rule := Rule{Kind: spec.kind, Name: rec.Name, Order: rec.Order, Value: spec.value, Hard: spec.hard}
+		var cfg struct {
+			Value         *float64 `json:"value"`
+			Hard          *bool    `json:"hard"`
+			MaxDifference *float64 `json:"maxDifference"`
+		}
+		if rec.Config.Valid && rec.Config.String != "" {
+			if err := json.Unmarshal([]byte(rec.Config.String), &cfg); err == nil {
+				if cfg.Value != nil && *cfg.Value >= 0 {
+					rule.Value = int(*cfg.Value)
+				}
+				if cfg.Hard != nil {
+					rule.Hard = *cfg.Hard
+				}
+			}
+		}
+		active = append(active, rule)
+		if rule.Kind == RuleMaxTotalHours {
+			// the cap's row also balances total hours between staff, one rank below the cap
+			spread := defaultHoursSpread
+			if cfg.MaxDifference != nil && *cfg.MaxDifference >= 0 {
+				spread = int(*cfg.MaxDifference)
+			}
+			active = append(active, Rule{Kind: RuleTotalHoursBalance, Name: rec.Name, Order: rec.Order, Value: spread})
+		}
+	}
+
+	rs := RuleSet{configured: true, rules: map[RuleKind]Rule{}}
+	for rank, rule := range active {
+		if _, dup := rs.rules[rule.Kind]; dup {
+			continue // first (highest priority) row of a kind wins
+		}
+		rule.Weight = math.Pow(2, float64(len(active)-1-rank))
+		rs.rules[rule.Kind] = rule
+	}
+	return rs
+}
+
+// DefaultPriorities mirrors PriorityRepository.FindOrCreateDefaults in priority-service
+func DefaultPriorities() []database.SchedulingPriority {
+	names := []string{PriorityRequestedDaysOff, PriorityShiftTypeBalance, PriorityMaxConsecutiveNights, PriorityMaxConsecutiveShifts, PriorityMaxContiguousHours, PriorityMaxTotalHours}
+	out := make([]database.SchedulingPriority, 0, len(names))
+	for i, n := range names {
+		out = append(out, database.SchedulingPriority{Name: n, Order: i + 1, IsActive: true})
+	}
+	return out
+}
+
+// WithSequence adds the department's minimum rest and forbidden transitions, enforced as hard rules
+// by every generator whether or not priorities are configured
+func (rs RuleSet) WithSequence(s database.SequenceRules) RuleSet {
+	rs.seq = newSequence(s)
+	return rs
+}
+
+// Configured reports whether the set was built from priorities (false = legacy behaviour)
+func (rs RuleSet) Configured() bool { return rs.configured }
+
+// Active returns the active rule of a kind
+func (rs RuleSet) Active(kind RuleKind) (Rule, bool) {
+	r, ok := rs.rules[kind]
+	return r, ok
+}
+
+// List returns active rules in priority order
+func (rs RuleSet) List() []Rule {
+	out := make([]Rule, 0, len(rs.rules))
+	for _, r := range rs.rules {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Order < out[j].Order })
+	return out
+}
+
+// MaxDiffAllowed is the per-shift-type spread SolveMonth's rebalance aims for
+func (rs RuleSet) MaxDiffAllowed(fallback int) int {
+	if r, ok := rs.Active(RuleShiftTypeBalance); ok {
+		return r.Value
+	}
+	return fallback
+}
+
+// limits are the numeric constraints derived from a RuleSet, shared by every generator
+type limits struct {
+	consecCap      int // max consecutive working days; 0 = unlimited
+	consecHard     bool
+	consecWeight   float64
+	nightCap       int // max consecutive nights; 0 = unlimited
+	nightHard      bool
+	nightWeight    float64
+	contigMinutes  int
+	contigHard     bool
+	contigWeight   float64
+	shiftBalance   float64 // allowed per-shift spread (shifts); <0 = rule inactive
+	shiftWeight    float64
+	hoursCap       float64 // max hours per staff member in the month; 0 = unlimited
+	hoursCapHard   bool
+	hoursCapWeight float64
+	hoursBalance   float64 // allowed total-hours spread (hours); <0 = rule inactive
+	hoursWeight    float64
+	prefWeight     float64 // staff preferences and request-off days; 0 = ignored
+	seq            sequence
+}
+
+func (rs RuleSet) limits() limits {
+	l := limits{shiftBalance: -1, hoursBalance: -1, prefWeight: 1, seq: rs.seq}
+	if !rs.configured {
+		// legacy behaviour: never on consecutive days, 16h contiguous
+		l.consecCap, l.consecHard = 1, true
+		l.contigMinutes, l.contigHard = 16*60, true
+		return l
+	}
+	// approved leave is always hard; the rule only decides whether request-off days and preferences,
+	// which are wishes, count and at what weight
+	l.prefWeight = 0
+	if r, ok := rs.Active(RuleRequestedDaysOff); ok {
+		l.prefWeight = r.Weight
+	}
+	if r, ok := rs.Active(RuleMaxConsecutiveShifts); ok && r.Value > 0 {
+		l.consecCap, l.consecHard, l.consecWeight = r.Value, r.Hard, r.Weight
+	}
+	if r, ok := rs.Active(RuleMaxConsecutiveNights); ok && r.Value > 0 {
+		l.nightCap, l.nightHard, l.nightWeight = r.Value, r.Hard, r.Weight
+	}
+	l.contigMinutes, l.contigHard = 24*60*2, true
+	if r, ok := rs.Active(RuleMaxContiguousHours); ok && r.Value > 0 {
+		l.contigMinutes, l.contigHard, l.contigWeight = r.Value*60, r.Hard, r.Weight
+	}
+	if r, ok := rs.Active(RuleShiftTypeBalance); ok {
+		l.shiftBalance, l.shiftWeight = float64(r.Value), r.Weight
+	}
+	if r, ok := rs.Active(RuleMaxTotalHours); ok && r.Value > 0 {
+		l.hoursCap, l.hoursCapHard, l.hoursCapWeight = float64(r.Value), r.Hard, r.Weight
+	}
+	if r, ok := rs.Active(RuleTotalHoursBalance); ok {
+		l.hoursBalance, l.hoursWeight = float64(r.Value), r.Weight
+	}
+	return l
+}
+
+// IsNightShift reports whether a shift counts toward the consecutive-night rule
+func IsNightShift(sh database.ShiftRecord) bool {
+	t := strings.ToLower(sh.Type)
+	return t == "night" || strings.Contains(sh.Type, "ดึก") || strings.Contains(sh.Name, "ดึก")
+}
+
+// shiftHours is the length of a shift in hours (0 when times are invalid)
+func shiftHours(sh database.ShiftRecord) float64 {
+	s, e, ok := shiftMinutes(sh)
+	if !ok {
+		return 0
+	}
+	return float64(e-s) / 60
+}
+
+// excess returns how far |d| goes beyond half of an allowed spread
+func excess(d, spread float64) float64 {
+	x := math.Abs(d) - spread/2
+	if x < 0 {
+		return 0
+	}
+	return x
+}
@@ -0,0 +1,164 @@
+package optimizer
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func TestApprovedLeaveIsAlwaysHard(t *testing.T) {
+	cases := map[string][]database.SchedulingPriority{
+		"defaults":         nil,
+		"rule inactive":    {{Name: PriorityRequestedDaysOff, Order: 1, IsActive: false}, {Name: PriorityMaxConsecutiveShifts, Order: 2, IsActive: true}},
+		"rule marked soft": {{Name: PriorityRequestedDaysOff, Order: 1, IsActive: true, Config: sql.NullString{String: `{"hard":false}`, Valid: true}}},
+	}
+	for name, priorities := range cases {
+		// one nurse, on leave for the first week: those days must stay unmet
+		in := fixture("2026-02", 1, needs(morningShift, 1))
+		in.Rules = BuildRules(priorities)
+		in.Leaves = []database.LeaveRange{{StaffID: "a", Start: "2026-02-01", End: "2026-02-07"}}
+		tr, err := NewRuleTracker(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.Relax(10)
+		if r := tr.Reason("a", time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC), in.Shifts[0]); r != "leave" {
+			t.Errorf("%s: reason %q with every rule relaxed, want leave", name, r)
+		}
+		for _, s := range solvers() {
+			for _, a := range solve(t, s, in).Assignments {
+				if a.ScheduleDate <= "2026-02-07" {
+					t.Errorf("%s/%s: a rostered on %s during approved leave", name, s.Name(), a.ScheduleDate)
+				}
+			}
+		}
+	}
+}
+
+func TestTotalHoursCap(t *testing.T) {
+	cfg := func(s string) []database.SchedulingPriority {
+		return []database.SchedulingPriority{{Name: PriorityMaxTotalHours, Order: 1, IsActive: true, Config: sql.NullString{String: s, Valid: true}}}
+	}
+	rs := BuildRules(nil)
+	if r, ok := rs.Active(RuleMaxTotalHours); !ok || r.Value != 192 || !r.Hard {
+		t.Errorf("default cap = %+v, want 192 hours, hard", r)
+	}
+	if r, ok := rs.Active(RuleTotalHoursBalance); !ok || r.Value != defaultHoursSpread || r.Hard {
+		t.Errorf("default balance = %+v, want soft spread of %d", r, defaultHoursSpread)
+	}
+	if r, _ := BuildRules(cfg(`{"value":40,"maxDifference":8}`)).Active(RuleTotalHoursBalance); r.Value != 8 {
+		t.Errorf("balance spread = %d, want maxDifference 8", r.Value)
+	}
+
+	// one nurse, one eight-hour shift a day, capped at 40 hours: five shifts and no more
+	in := fixture("2026-02", 1, needs(morningShift, 1))
+	in.Rules = BuildRules(cfg(`{"value":40}`))
+	for _, s := range solvers() {
+		if n := len(solve(t, s, in).Assignments); n != 5 {
+			t.Errorf("%s: %d shifts under a hard 40h cap, want 5", s.Name(), n)
+		}
+	}
+	// a spread too wide to bind leaves the cap as the only hours term the tracker prices
+	in.Rules = BuildRules(cfg(`{"value":40,"maxDifference":1000}`))
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for d := 1; d <= 5; d++ {
+		tr.Place("a", time.Date(2026, 2, d, 0, 0, 0, 0, time.UTC), in.Shifts[0])
+	}
+	sixth := time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC)
+	if r := tr.Reason("a", sixth, in.Shifts[0]); r != "exceed-total-hours" {
+		t.Errorf("reason past the cap = %q, want exceed-total-hours", r)
+	}
+	tr.Relax(1)
+	if r := tr.Reason("a", sixth, in.Shifts[0]); r != "" {
+		t.Errorf("reason with the cap relaxed = %q, want none", r)
+	}
+	if c, want := tr.Cost("a", sixth, in.Shifts[0]), rulePenalty*tr.lim.hoursCapWeight; c != want {
+		t.Errorf("cost past a relaxed cap = %v, want one shift's penalty %v", c, want)
+	}
+
+	// a soft cap is priced, not enforced: covering the month beats staying under it
+	in.Rules = BuildRules(cfg(`{"value":40,"hard":false}`))
+	for _, s := range solvers() {
+		if n := len(solve(t, s, in).Assignments); n <= 5 {
+			t.Errorf("%s: %d shifts under a soft 40h cap, want the cap exceeded", s.Name(), n)
+		}
+	}
+}
+
+func TestContiguousHoursAcrossMidnight(t *testing.T) {
+	// a twelve-hour night from 20:00 runs into the next day's morning: 20 hours without a break
+	night := database.ShiftRecord{ID: "n", Name: "ดึก", Type: "night", StartTime: "20:00", EndTime: "08:00", RequiredNurse: 1}
+	cfg := func(s string) RuleSet {
+		return BuildRules([]database.SchedulingPriority{{Name: PriorityMaxContiguousHours, Order: 1, IsActive: true, Config: sql.NullString{String: s, Valid: true}}})
+	}
+	in := fixture("2026-02", 1, night, needs(morningShift, 1))
+	in.Rules = cfg(`{"value":16}`)
+	feb := func(d int) time.Time { return time.Date(2026, 2, d, 0, 0, 0, 0, time.UTC) }
+
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Place("a", feb(1), night)
+	if r := tr.Reason("a", feb(2), morningShift); r != "exceed-contiguous-hours" {
+		t.Errorf("tracker: morning after a night = %q, want exceed-contiguous-hours", r)
+	}
+	if r := tr.Reason("a", feb(1), morningShift); r != "" {
+		t.Errorf("tracker: morning before the night = %q, want none", r)
+	}
+
+	m, err := newModel(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := m.newState()
+	n1, _ := m.slotFor("2026-02-01", "n", "nurse")
+	m2, _ := m.slotFor("2026-02-02", "m", "nurse")
+	st.add(n1, 0)
+	if r := st.blockReason(0, m2); r != "exceed-contiguous-hours" {
+		t.Errorf("model: morning after a night = %q, want exceed-contiguous-hours", r)
+	}
+
+	for _, s := range solvers() {
+		nights := map[string]bool{}
+		res := solve(t, s, in)
+		for _, a := range res.Assignments {
+			if a.ShiftID == "n" {
+				nights[a.ScheduleDate] = true
+			}
+		}
+		for _, a := range res.Assignments {
+			d, _ := time.Parse("2006-01-02", a.ScheduleDate)
+			if a.ShiftID == "m" && nights[d.AddDate(0, 0, -1).Format("2006-01-02")] {
+				t.Errorf("%s: a works the morning of %s straight after the night before", s.Name(), a.ScheduleDate)
+			}
+		}
+	}
+
+	// soft, the four hours past the limit are priced by both
+	in.Rules = cfg(`{"value":16,"hard":false}`)
+	tr, err = NewRuleTracker(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Place("a", feb(1), night)
+	want := rulePenalty * tr.lim.contigWeight * 4
+	if c := tr.Cost("a", feb(2), morningShift); c != want {
+		t.Errorf("tracker: cost of the morning after a night = %v, want %v", c, want)
+	}
+	m, err = newModel(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st = m.newState()
+	st.add(n1, 0)
+	st.add(m2, 0)
+	if c := st.softRuleCost(0); c != want {
+		t.Errorf("model: soft cost of a night and the next morning = %v, want %v", c, want)
+	}
+}
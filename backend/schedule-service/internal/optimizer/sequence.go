@@ -0,0 +1,66 @@
+package optimizer
+
+import (
+	"strings"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// transitionWindow is how soon, in minutes, the next shift must start after one ends for a
+// forbidden transition between them to apply; a full day off breaks the sequence
+const transitionWindow = 24 * 60
+
+// sequenceReach is how many days apart two shifts can start and still be checked against each other:
+// a shift lasts at most a day and the rules look less than a day past its end
+const sequenceReach = 3
+
+// window is a shift on the month's timeline, in minutes from 00:00 on the first of the month
+type window struct {
+	start, end int
+	typ        string // shift type, lower case
+}
+
+func shiftWindowAt(day int, sh database.ShiftRecord) (window, bool) {
+	s, e, ok := shiftMinutes(sh)
+	if !ok {
+		return window{}, false
+	}
+	return window{start: day*24*60 + s, end: day*24*60 + e, typ: strings.ToLower(sh.Type)}, true
+}
+
+// sequence is database.SequenceRules in the form the generators check
+type sequence struct {
+	restMinutes int
+	forbidden   map[string]map[string]bool // from type -> to type
+}
+
+func newSequence(s database.SequenceRules) sequence {
+	q := sequence{restMinutes: s.MinRestHours * 60, forbidden: map[string]map[string]bool{}}
+	for _, t := range s.ForbiddenTransitions {
+		from, to := strings.ToLower(t.From), strings.ToLower(t.To)
+		if q.forbidden[from] == nil {
+			q.forbidden[from] = map[string]bool{}
+		}
+		q.forbidden[from][to] = true
+	}
+	return q
+}
+
+// between returns why two shifts of the same person cannot both stand; "" means they can. Shifts
+// that touch form one block left to the contiguous-hours rule, so only a gap counts as rest.
+func (q sequence) between(a, b window) string {
+	if b.start < a.start {
+		a, b = b, a
+	}
+	gap := b.start - a.end
+	if gap < 0 {
+		return "overlap"
+	}
+	if gap > 0 && gap < q.restMinutes {
+		return "insufficient-rest"
+	}
+	if gap < transitionWindow && q.forbidden[a.typ][b.typ] {
+		return "forbidden-transition"
+	}
+	return ""
+}
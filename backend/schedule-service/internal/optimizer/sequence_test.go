@@ -0,0 +1,88 @@
+package optimizer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func sequenceInput() Input {
+	return Input{
+		DepartmentID: "dept",
+		Month:        "2026-03",
+		Shifts: []database.ShiftRecord{
+			{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00", RequiredNurse: 1},
+			{ID: "e", Name: "บ่าย", Type: "afternoon", StartTime: "16:00", EndTime: "00:00", RequiredNurse: 1},
+			{ID: "n", Name: "ดึก", Type: "night", StartTime: "00:00", EndTime: "08:00", RequiredNurse: 1},
+		},
+		Staff: []database.DepartmentStaff{
+			{ID: "a", Name: "A", Position: "nurse"},
+			{ID: "b", Name: "B", Position: "nurse"},
+			{ID: "c", Name: "C", Position: "nurse"},
+			{ID: "d", Name: "D", Position: "nurse"},
+			{ID: "f", Name: "F", Position: "nurse"},
+		},
+		Rules: BuildRules(nil).WithSequence(database.SequenceRules{
+			MinRestHours:         11,
+			ForbiddenTransitions: []database.ShiftTransition{{From: "night", To: "morning"}},
+		}),
+	}
+}
+
+func TestRuleTrackerSequence(t *testing.T) {
+	in := sequenceInput()
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Place("a", mustDay(t, "2026-03-02"), in.Shifts[1]) // 16:00-00:00
+	tr.Place("b", mustDay(t, "2026-03-02"), in.Shifts[2]) // 00:00-08:00
+	cases := []struct {
+		staff, date string
+		shift       int
+		want        string
+	}{
+		{"a", "2026-03-03", 0, "insufficient-rest"}, // 8h after midnight
+		{"a", "2026-03-04", 0, ""},
+		{"b", "2026-03-02", 0, "forbidden-transition"}, // straight after the night
+		{"b", "2026-03-03", 0, ""},                     // a full day later
+		{"b", "2026-03-02", 1, "insufficient-rest"},    // 16:00, 8h after the night
+	}
+	for _, tc := range cases {
+		if got := tr.Reason(tc.staff, mustDay(t, tc.date), in.Shifts[tc.shift]); got != tc.want {
+			t.Errorf("%s %s %s: reason %q, want %q", tc.staff, tc.date, in.Shifts[tc.shift].ID, got, tc.want)
+		}
+	}
+}
+
+func TestSolversKeepSequenceRules(t *testing.T) {
+	in := sequenceInput()
+	seq := in.Rules.limits().seq
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range in.Shifts {
+		shiftByID[sh.ID] = sh
+	}
+	for _, name := range []string{SolverGreedy, SolverLocalSearch} {
+		solver, err := NewSolver(name, 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := solver.Solve(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byStaff := map[string][]window{}
+		for _, a := range res.Assignments {
+			d := mustDay(t, a.ScheduleDate)
+			w, _ := shiftWindowAt(d.Day()-1, shiftByID[a.ShiftID])
+			for _, o := range byStaff[a.StaffID] {
+				if r := seq.between(o, w); r != "" {
+					t.Errorf("%s: %s on %s %s breaks %s", name, a.StaffID, a.ScheduleDate, a.ShiftID, r)
+				}
+			}
+			byStaff[a.StaffID] = append(byStaff[a.StaffID], w)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package optimizer
+
+import (
+	"sort"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// SkillShortfall is a shift occurrence with fewer holders of a skill than the shift requires
+type SkillShortfall struct {
+	SkillID   string `json:"skillId"`
+	SkillName string `json:"skillName"`
+	Required  int    `json:"required"`
+	Assigned  int    `json:"assigned"`
+}
+
+// LapsedCertification flags an assignment to a shift requiring a skill whose certification the
+// staff member held but had expired by the date
+type LapsedCertification struct {
+	StaffID   string `json:"staffId"`
+	StaffName string `json:"staffName"`
+	Date      string `json:"date"`
+	ShiftID   string `json:"shiftId"`
+	ShiftName string `json:"shiftName"`
+	SkillID   string `json:"skillId"`
+	SkillName string `json:"skillName"`
+	ExpiredOn string `json:"expiredOn"`
+}
+
+// skillReq is one skill a shift requires
+type skillReq struct {
+	skill string
+	min   int
+}
+
+// skillIndex is Input.Competencies indexed for the generators
+type skillIndex struct {
+	names   map[string]string
+	reqs    map[string][]skillReq        // shiftID -> requirements
+	expires map[string]map[string]string // staffID -> skillID -> last valid date, "" = no expiry
+}
+
+func newSkillIndex(c database.Competencies) skillIndex {
+	x := skillIndex{names: map[string]string{}, reqs: map[string][]skillReq{}, expires: map[string]map[string]string{}}
+	for _, s := range c.Skills {
+		x.names[s.ID] = s.Name
+	}
+	for _, q := range c.Requirements {
+		if q.MinCount > 0 {
+			x.reqs[q.ShiftID] = append(x.reqs[q.ShiftID], skillReq{skill: q.SkillID, min: q.MinCount})
+		}
+	}
+	for _, reqs := range x.reqs {
+		sort.Slice(reqs, func(i, j int) bool { return reqs[i].skill < reqs[j].skill })
+	}
+	for _, s := range c.StaffSkills {
+		if x.expires[s.StaffID] == nil {
+			x.expires[s.StaffID] = map[string]string{}
+		}
+		x.expires[s.StaffID][s.SkillID] = s.ExpiresOn.String
+	}
+	return x
+}
+
+// qualified reports whether staffID holds skillID with a certification valid on date (YYYY-MM-DD)
+func (x skillIndex) qualified(staffID, skillID, date string) bool {
+	until, ok := x.expires[staffID][skillID]
+	return ok && (until == "" || date <= until)
+}
+
+// holders counts the staff among ids qualified for skillID on date
+func (x skillIndex) holders(ids []string, skillID, date string) int {
+	n := 0
+	for _, id := range ids {
+		if x.qualified(id, skillID, date) {
+			n++
+		}
+	}
+	return n
+}
+
+// shortfalls lists the requirements of shiftID that the staff in ids do not cover on date
+func (x skillIndex) shortfalls(shiftID, date string, ids []string) []SkillShortfall {
+	var out []SkillShortfall
+	for _, r := range x.reqs[shiftID] {
+		if n := x.holders(ids, r.skill, date); n < r.min {
+			out = append(out, SkillShortfall{SkillID: r.skill, SkillName: x.names[r.skill], Required: r.min, Assigned: n})
+		}
+	}
+	return out
+}
+
+// lapsed lists the skills shiftID requires that staffID held but whose certification had expired by date
+func (x skillIndex) lapsed(staffID, shiftID, date string) []string {
+	var out []string
+	for _, r := range x.reqs[shiftID] {
+		if until, held := x.expires[staffID][r.skill]; held && until != "" && date > until {
+			out = append(out, r.skill)
+		}
+	}
+	return out
+}
+
+// lostSkills compares who works shiftID on date before and after an edit and reports each required skill
+// the edit leaves short that was better covered before, against a holder who left the shift
+func (x skillIndex) lostSkills(shiftID, date string, before, after []string) []Violation {
+	var out []Violation
+	for _, r := range x.reqs[shiftID] {
+		n := x.holders(after, r.skill, date)
+		if n >= r.min || n >= x.holders(before, r.skill, date) {
+			continue
+		}
+		v := Violation{ShiftID: shiftID, Date: date, Reason: "missing-skill"}
+		for _, id := range before {
+			if x.qualified(id, r.skill, date) && !contains(after, id) {
+				v.StaffID = id
+				break
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func contains(ids []string, id string) bool {
+	for _, s := range ids {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+// LapsedAssignments flags the assignments that rely on a certification which had expired by their
+// date: the shift requires the skill and the staff member's certification ran out before it
+func LapsedAssignments(in Input, assignments []database.Assignment) []LapsedCertification {
+	x := newSkillIndex(in.Competencies)
+	names := map[string]string{}
+	for _, s := range in.Staff {
+		names[s.ID] = s.Name
+	}
+	shiftNames := map[string]string{}
+	for _, sh := range in.Shifts {
+		shiftNames[sh.ID] = sh.Name
+	}
+	out := []LapsedCertification{}
+	for _, a := range assignments {
+		for _, skill := range x.lapsed(a.StaffID, a.ShiftID, a.ScheduleDate) {
+			out = append(out, LapsedCertification{
+				StaffID: a.StaffID, StaffName: names[a.StaffID], Date: a.ScheduleDate,
+				ShiftID: a.ShiftID, ShiftName: shiftNames[a.ShiftID],
+				SkillID: skill, SkillName: x.names[skill], ExpiredOn: x.expires[a.StaffID][skill],
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out
+}
+
+// AddSkillGaps merges shifts short of required skills (RuleTracker.SkillGaps) into the report's unmet
+// slots and SkillShortTotal
+func (r *Report) AddSkillGaps(gaps []UnmetSlot) {
+	at := map[string]int{}
+	for i, u := range r.Unmet {
+		at[u.Date+"|"+u.ShiftID] = i
+	}
+	for _, g := range gaps {
+		for _, x := range g.MissingSkills {
+			r.SkillShortTotal += x.Required - x.Assigned
+		}
+		if i, ok := at[g.Date+"|"+g.ShiftID]; ok {
+			r.Unmet[i].MissingSkills = g.MissingSkills
+			continue
+		}
+		r.Unmet = append(r.Unmet, g)
+	}
+	sort.SliceStable(r.Unmet, func(i, j int) bool { return r.Unmet[i].Date < r.Unmet[j].Date })
+}
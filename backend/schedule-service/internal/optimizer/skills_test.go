@@ -0,0 +1,171 @@
+package optimizer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+func skillInput() Input {
+	return Input{
+		DepartmentID: "dept",
+		Month:        "2026-02",
+		Shifts: []database.ShiftRecord{
+			{ID: "m", Name: "เช้า", Type: "morning", StartTime: "08:00", EndTime: "16:00", RequiredNurse: 2},
+		},
+		Staff: []database.DepartmentStaff{
+			{ID: "a", Name: "A", Position: "nurse"},
+			{ID: "b", Name: "B", Position: "nurse"},
+			{ID: "c", Name: "C", Position: "nurse"},
+			{ID: "d", Name: "D", Position: "nurse"},
+		},
+		Rules: BuildRules(nil),
+		Competencies: database.Competencies{
+			Skills: []database.Skill{{ID: "charge", Name: "Charge nurse"}},
+			StaffSkills: []database.StaffSkill{
+				{StaffID: "a", SkillID: "charge"},
+				{StaffID: "d", SkillID: "charge"},
+				{StaffID: "b", SkillID: "charge", ExpiresOn: sql.NullString{String: "2026-02-14", Valid: true}},
+			},
+			Requirements: []database.ShiftSkillRequirement{{ShiftID: "m", SkillID: "charge", MinCount: 1}},
+		},
+	}
+}
+
+func TestSolversCoverSkillRequirements(t *testing.T) {
+	in := skillInput()
+	for _, name := range []string{SolverGreedy, SolverLocalSearch} {
+		solver, err := NewSolver(name, 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := solver.Solve(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		charge := map[string]bool{}
+		for _, a := range res.Assignments {
+			if a.StaffID == "a" || a.StaffID == "d" || (a.StaffID == "b" && a.ScheduleDate <= "2026-02-14") {
+				charge[a.ScheduleDate] = true
+			}
+		}
+		if len(charge) != 28 || res.SkillShortTotal != 0 {
+			t.Errorf("%s: charge nurse on %d of 28 days, %d short", name, len(charge), res.SkillShortTotal)
+		}
+	}
+}
+
+func TestLapsedAssignments(t *testing.T) {
+	in := skillInput()
+	out := LapsedAssignments(in, []database.Assignment{
+		{StaffID: "b", ShiftID: "m", ScheduleDate: "2026-02-14"},
+		{StaffID: "b", ShiftID: "m", ScheduleDate: "2026-02-15"},
+		{StaffID: "c", ShiftID: "m", ScheduleDate: "2026-02-15"},
+	})
+	if len(out) != 1 || out[0].StaffID != "b" || out[0].Date != "2026-02-15" || out[0].ExpiredOn != "2026-02-14" {
+		t.Errorf("lapsed = %+v, want only b on 2026-02-15", out)
+	}
+}
+
+func TestRuleTrackerSkillGaps(t *testing.T) {
+	in := skillInput()
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feb := func(day int) time.Time { return time.Date(2026, 2, day, 0, 0, 0, 0, time.UTC) }
+	sh := in.Shifts[0]
+	for day := 1; day <= 28; day++ {
+		tr.Place("c", feb(day), sh)
+		if day > 2 {
+			tr.Place("a", feb(day), sh)
+		}
+	}
+	if !tr.CoversSkill("d", feb(1), sh) || tr.CoversSkill("c", feb(1), sh) || tr.CoversSkill("d", feb(3), sh) {
+		t.Error("CoversSkill should hold only for a valid holder on a shift short of the skill")
+	}
+	if tr.KeepsSkills("a", "c", feb(3), sh) || !tr.KeepsSkills("a", "d", feb(3), sh) || !tr.KeepsSkills("c", "", feb(3), sh) {
+		t.Error("KeepsSkills should refuse only handing the last charge nurse's place to someone without the skill")
+	}
+
+	gaps := tr.SkillGaps()
+	if len(gaps) != 2 || gaps[0].Date != "2026-02-01" || gaps[1].Date != "2026-02-02" || gaps[0].MissingSkills[0].SkillID != "charge" {
+		t.Fatalf("gaps = %+v, want the 1st and 2nd short of a charge nurse", gaps)
+	}
+	report := Report{Unmet: []UnmetSlot{{Date: "2026-02-02", ShiftID: "m", MissingNurses: 1}}, UnmetTotal: 1}
+	report.AddSkillGaps(gaps)
+	if len(report.Unmet) != 2 || report.Unmet[0].Date != "2026-02-01" || report.Unmet[1].MissingNurses != 1 || len(report.Unmet[1].MissingSkills) != 1 || report.SkillShortTotal != 2 {
+		t.Errorf("report = %+v, want the gaps merged into the unmet slots", report)
+	}
+}
+
+func TestValidateChangesChecksSkills(t *testing.T) {
+	in := skillInput()
+	current := []database.Assignment{
+		held("a", "m", "2026-02-02"), held("c", "m", "2026-02-02"),
+		held("a", "m", "2026-02-20"), held("c", "m", "2026-02-20"),
+		held("c", "m", "2026-02-21"),
+	}
+	tests := []struct {
+		name        string
+		remove, add []database.Assignment
+		want        []string
+	}{
+		{"another charge nurse takes over", []database.Assignment{held("a", "m", "2026-02-02")}, []database.Assignment{held("b", "m", "2026-02-02")}, nil},
+		{"the charge nurse leaves the shift", []database.Assignment{held("a", "m", "2026-02-02")}, nil, []string{"a m 2026-02-02 missing-skill"}},
+		{"the cover's certification lapsed", []database.Assignment{held("a", "m", "2026-02-20")}, []database.Assignment{held("b", "m", "2026-02-20")},
+			[]string{"b m 2026-02-20 lapsed-certification", "a m 2026-02-20 missing-skill"}},
+		{"a shift already short is not made worse", nil, []database.Assignment{held("d", "m", "2026-02-21")}, nil},
+	}
+	for _, tt := range tests {
+		vs, err := ValidateChanges(in, current, tt.remove, tt.add)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, v := range vs {
+			got = append(got, v.StaffID+" "+v.ShiftID+" "+v.Date+" "+v.Reason)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: violations = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRepairLeaveKeepsSkillCoverage(t *testing.T) {
+	in := skillInput()
+	in.Staff = append(in.Staff, database.DepartmentStaff{ID: "e", Name: "E", Position: "nurse"})
+	// e carries the lightest load but holds no charge certification
+	current := []database.Assignment{
+		held("a", "m", "2026-02-02"), held("c", "m", "2026-02-02"),
+		held("b", "m", "2026-02-09"), held("d", "m", "2026-02-10"),
+	}
+	res, err := RepairLeave(in, current, "a", "2026-02-02", "2026-02-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := changeList(res.Changes)
+	if len(got) != 2 || (got[1] != "add b m 2026-02-02 refill" && got[1] != "add d m 2026-02-02 refill") || len(res.Violations) != 0 {
+		t.Errorf("changes = %v, violations %+v; want a charge nurse to cover", got, res.Violations)
+	}
+
+	// on the 20th d is on leave and b's certification has lapsed: the shift loses its charge nurse
+	in.Leaves = []database.LeaveRange{{StaffID: "d", Start: "2026-02-20", End: "2026-02-20"}}
+	current = []database.Assignment{held("a", "m", "2026-02-20"), held("c", "m", "2026-02-20")}
+	res, err = RepairLeave(in, current, "a", "2026-02-20", "2026-02-20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vs []string
+	for _, v := range res.Violations {
+		vs = append(vs, v.StaffID+" "+v.Date+" "+v.Reason)
+	}
+	want := []string{"remove a m 2026-02-20 leave", "add e m 2026-02-20 refill"}
+	if fmt.Sprint(changeList(res.Changes)) != fmt.Sprint(want) || fmt.Sprint(vs) != "[a 2026-02-20 missing-skill]" {
+		t.Errorf("changes = %v, violations %v; want e to refill rather than lapsed b, and the lost charge nurse reported", changeList(res.Changes), vs)
+	}
+}
@@ -0,0 +1,174 @@
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// Solver names accepted by NewSolver (and by /schedules/optimize-generate)
+const (
+	SolverGreedy      = "greedy"
+	SolverLocalSearch = "local-search"
+)
+
+// DefaultTimeBudget is used by anytime solvers when the caller does not specify one
+const DefaultTimeBudget = 5 * time.Second
+
+// MaxTimeBudget caps the time budget accepted from API callers
+const MaxTimeBudget = 30 * time.Second
+
+// Solver builds a monthly roster from an Input
+type Solver interface {
+	Name() string
+	Solve(ctx context.Context, in Input) (*Result, error)
+}
+
+// BlockedCandidate is a staff member of the right role who could not take an unmet slot, and why
+// (leave, consecutive-day, consecutive-night, overlap, exceed-contiguous-hours, exceed-total-hours, ...)
+type BlockedCandidate struct {
+	StaffID   string `json:"staffId"`
+	StaffName string `json:"staffName"`
+	Role      string `json:"role"`
+	Reason    string `json:"reason"`
+}
+
+// UnmetSlot is a date/shift whose required nurses or assistants could not all be filled
+type UnmetSlot struct {
+	Date              string             `json:"date"`
+	ShiftID           string             `json:"shiftId"`
+	ShiftName         string             `json:"shiftName"`
+	MissingNurses     int                `json:"missingNurses"`
+	MissingAssistants int                `json:"missingAssistants"`
+	MissingSkills     []SkillShortfall   `json:"missingSkills,omitempty"`
+	Candidates        []BlockedCandidate `json:"candidates"`
+}
+
+// SolverSummary carries solver quality figures inside a Report
+type SolverSummary struct {
+	Objective  float64 `json:"objective"`
+	LowerBound float64 `json:"lowerBound"`
+	Gap        float64 `json:"gap"`
+	Optimal    bool    `json:"optimal"`
+	Iterations int     `json:"iterations"`
+	ElapsedMs  int64   `json:"elapsedMs"`
+}
+
+// Report is the machine-readable outcome of one generation run (returned by the API and persisted)
+type Report struct {
+	Generator     string         `json:"generator"`
+	DepartmentID  string         `json:"departmentId"`
+	Month         string         `json:"month"`
+	RequiredSlots int            `json:"requiredSlots"`
+	FilledSlots   int            `json:"filledSlots"`
+	UnmetTotal    int            `json:"unmetTotal"`
+	Unmet         []UnmetSlot    `json:"unmet"`
+	Rules         []Rule         `json:"rules,omitempty"`
+	Solver        *SolverSummary `json:"solver,omitempty"`
+	Satisfaction  []Satisfaction `json:"satisfaction,omitempty"`
+	// SkillShortTotal counts required skill holders missing; Lapsed flags assignments on expired certifications
+	SkillShortTotal int                   `json:"skillShortTotal"`
+	Lapsed          []LapsedCertification `json:"lapsed,omitempty"`
+}
+
+// Result is the outcome of a solver run
+type Result struct {
+	Solver      string
+	Assignments []database.Assignment
+	// Objective = unmet penalty + fairness penalty (lower is better)
+	Objective float64
+	// LowerBound is a proven lower bound on Objective for this input
+	LowerBound float64
+	// Gap = (Objective - LowerBound) / Objective; 0 means proven optimal
+	Gap           float64
+	Optimal       bool
+	Unmet         []UnmetSlot
+	UnmetTotal    int
+	RequiredSlots int
+	Iterations    int
+	Elapsed       time.Duration
+	// Satisfaction scores how well the roster meets each staff member's preferences
+	Satisfaction    []Satisfaction
+	SkillShortTotal int
+	Lapsed          []LapsedCertification
+}
+
+// Report converts a solver result into a generation report
+func (r *Result) Report(departmentID, month string) Report {
+	unmet := r.Unmet
+	if unmet == nil {
+		unmet = []UnmetSlot{}
+	}
+	return Report{
+		Generator:       "optimizer:" + r.Solver,
+		DepartmentID:    departmentID,
+		Month:           month,
+		RequiredSlots:   r.RequiredSlots,
+		FilledSlots:     r.RequiredSlots - r.UnmetTotal,
+		UnmetTotal:      r.UnmetTotal,
+		Unmet:           unmet,
+		Satisfaction:    r.Satisfaction,
+		SkillShortTotal: r.SkillShortTotal,
+		Lapsed:          r.Lapsed,
+		Solver: &SolverSummary{
+			Objective:  r.Objective,
+			LowerBound: r.LowerBound,
+			Gap:        r.Gap,
+			Optimal:    r.Optimal,
+			Iterations: r.Iterations,
+			ElapsedMs:  r.Elapsed.Milliseconds(),
+		},
+	}
+}
+
+// NewSolver returns the solver registered under name; empty name selects the greedy solver
+func NewSolver(name string, budget time.Duration) (Solver, error) {
+	if budget <= 0 {
+		budget = DefaultTimeBudget
+	}
+	if budget > MaxTimeBudget {
+		budget = MaxTimeBudget
+	}
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", SolverGreedy:
+		return GreedySolver{}, nil
+	case SolverLocalSearch, "localsearch", "anytime":
+		return &LocalSearchSolver{TimeBudget: budget}, nil
+	default:
+		return nil, fmt.Errorf("unknown solver %q", name)
+	}
+}
+
+// GreedySolver wraps SolveMonth and scores its output with the shared model
+type GreedySolver struct{}
+
+func (GreedySolver) Name() string { return SolverGreedy }
+
+func (GreedySolver) Solve(_ context.Context, in Input) (*Result, error) {
+	started := time.Now()
+	out, err := SolveMonth(in)
+	if err != nil {
+		return nil, err
+	}
+	mdl, err := newModel(in)
+	if err != nil {
+		return nil, err
+	}
+	st := mdl.newState()
+	// score what greedy produced as-is; assignments outside the model (should not happen) are kept but not scored
+	for _, a := range out {
+		if si, ok := mdl.slotFor(a.ScheduleDate, a.ShiftID, mdl.roleOf[a.StaffID]); ok {
+			st.add(si, mdl.staffIndex[a.StaffID])
+		}
+	}
+	res := mdl.result(st, SolverGreedy)
+	res.Assignments = out
+	res.Satisfaction = mdl.prefs.satisfaction(in.Staff, in.Shifts, out)
+	res.Lapsed = LapsedAssignments(in, out)
+	res.Iterations = 1
+	res.Elapsed = time.Since(started)
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package optimizer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// solverCases are months with slack, with a shortage and with leave
+func solverCases() map[string]Input {
+	short := fixture("2026-02", 3, needs(morningShift, 2), needs(nightShift, 1))
+	leave := fixture("2026-02", 5, needs(morningShift, 2), needs(nightShift, 1))
+	leave.Leaves = []database.LeaveRange{{StaffID: "a", Start: "2026-02-01", End: "2026-02-14"}}
+	return map[string]Input{
+		"slack":    fixture("2026-02", 6, needs(morningShift, 1), needs(nightShift, 1)),
+		"shortage": short,
+		"leave":    leave,
+	}
+}
+
+func TestLocalSearchNeverWorseThanGreedy(t *testing.T) {
+	for name, in := range solverCases() {
+		greedy := solve(t, GreedySolver{}, in)
+		ls := solve(t, &LocalSearchSolver{TimeBudget: MaxTimeBudget, Seed: 1, MaxIterations: 5000}, in)
+		if ls.Objective > greedy.Objective+1e-9 {
+			t.Errorf("%s: local search %.2f, worse than its greedy warm start %.2f", name, ls.Objective, greedy.Objective)
+		}
+		if ls.UnmetTotal > greedy.UnmetTotal {
+			t.Errorf("%s: local search leaves %d slots unmet, greedy %d", name, ls.UnmetTotal, greedy.UnmetTotal)
+		}
+	}
+}
+
+func TestLowerBoundAndGap(t *testing.T) {
+	for name, in := range solverCases() {
+		for _, s := range solvers() {
+			res := solve(t, s, in)
+			if res.LowerBound > res.Objective+1e-6 {
+				t.Errorf("%s/%s: lower bound %.4f above objective %.4f", name, s.Name(), res.LowerBound, res.Objective)
+			}
+			if res.Gap < 0 || res.Gap > 1 {
+				t.Errorf("%s/%s: gap %.4f outside [0, 1]", name, s.Name(), res.Gap)
+			}
+			if res.Optimal != (res.Objective-res.LowerBound < 1e-6) {
+				t.Errorf("%s/%s: optimal = %v with objective %.4f and bound %.4f", name, s.Name(), res.Optimal, res.Objective, res.LowerBound)
+			}
+		}
+	}
+}
+
+func TestLocalSearchStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	in := solverCases()["shortage"]
+	res, err := (&LocalSearchSolver{TimeBudget: MaxTimeBudget, Seed: 1}).Solve(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Iterations != 0 {
+		t.Errorf("iterations = %d after cancel, want 0", res.Iterations)
+	}
+	// the greedy warm start is still returned
+	if len(res.Assignments) == 0 {
+		t.Error("no assignments returned after cancel")
+	}
+}
+
+func TestLocalSearchKeepsTimeBudget(t *testing.T) {
+	// a is on leave for the first half, so b has to carry it; the lower bound splits the work
+	// evenly whoever is on leave, so only the budget ends the search
+	in := fixture("2026-02", 2, needs(morningShift, 1))
+	in.Leaves = []database.LeaveRange{{StaffID: "a", Start: "2026-02-01", End: "2026-02-14"}}
+	const budget = 50 * time.Millisecond
+	started := time.Now()
+	res := solve(t, &LocalSearchSolver{TimeBudget: budget, Seed: 1}, in)
+	if res.Optimal {
+		t.Fatal("reached the lower bound; the test month no longer exercises the budget")
+	}
+	if elapsed := time.Since(started); elapsed < budget || elapsed > budget+500*time.Millisecond {
+		t.Errorf("ran %v on a %v budget", elapsed, budget)
+	}
+}
+
+func TestNewSolverClampsBudget(t *testing.T) {
+	s, err := NewSolver(SolverLocalSearch, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.(*LocalSearchSolver).TimeBudget; got != MaxTimeBudget {
+		t.Errorf("budget = %v, want %v", got, MaxTimeBudget)
+	}
+	if _, err := NewSolver("simplex", 0); err == nil {
+		t.Error("unknown solver accepted")
+	}
+}
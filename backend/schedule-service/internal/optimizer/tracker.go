@@ -0,0 +1,410 @@
+package optimizer
+
+import (
+	"math"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// RuleTracker follows assignments made one at a time (greedy generators) and answers whether the
+// department's rules allow the next one and what soft-rule cost it carries.
+type RuleTracker struct {
+	first        time.Time
+	rules        RuleSet
+	base         limits
+	lim          limits
+	days         int
+	leave        map[string]map[string]bool
+	role         map[string]string
+	worked       map[string]map[int]int // staffID -> day of month -> shifts that day; <1 and >days are Context
+	nights       map[string]map[int]int // staffID -> day of month -> night shifts that day
+	hours        map[string]float64
+	countByShift map[string]map[string]int
+	intervals    map[string]map[string][][2]int // staffID -> date -> [start,end] minutes
+	windows      map[string][]window            // staffID -> shifts on the month's timeline
+	shiftTarget  map[string]map[string]float64  // role -> shiftID -> even share
+	hoursTarget  map[string]float64             // role -> even share of hours
+	prefs        preferences
+	ledger       ledger
+	ledgerCount  map[string][]float64 // staffID -> this month's tally per ledger key
+	skills       skillIndex
+	shifts       []database.ShiftRecord
+	open         []time.Time         // the month's working days that are not holidays
+	slots        map[string][]string // date|shiftID -> staff placed this month
+}
+
+// NewRuleTracker prepares a tracker for in.Month using in.Rules, leaves and demand
+func NewRuleTracker(in Input) (*RuleTracker, error) {
+	t, err := time.Parse("2006-01", in.Month)
+	if err != nil {
+		return nil, err
+	}
+	year, mo, _ := t.Date()
+	first := time.Date(year, mo, 1, 0, 0, 0, 0, time.UTC)
+	tr := &RuleTracker{
+		first:        first,
+		rules:        in.Rules,
+		base:         in.Rules.limits(),
+		days:         first.AddDate(0, 1, -1).Day(),
+		leave:        map[string]map[string]bool{},
+		role:         map[string]string{},
+		worked:       map[string]map[int]int{},
+		nights:       map[string]map[int]int{},
+		hours:        map[string]float64{},
+		countByShift: map[string]map[string]int{},
+		intervals:    map[string]map[string][][2]int{},
+		windows:      map[string][]window{},
+		shiftTarget:  map[string]map[string]float64{"nurse": {}, "assistant": {}},
+		hoursTarget:  map[string]float64{},
+		prefs:        newPreferences(in),
+		ledger:       newLedger(in),
+		ledgerCount:  map[string][]float64{},
+		skills:       newSkillIndex(in.Competencies),
+		shifts:       in.Shifts,
+		slots:        map[string][]string{},
+	}
+	tr.lim = tr.base
+	for _, lv := range in.Leaves {
+		start, err1 := time.Parse("2006-01-02", lv.Start)
+		end, err2 := time.Parse("2006-01-02", lv.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if tr.leave[lv.StaffID] == nil {
+			tr.leave[lv.StaffID] = map[string]bool{}
+		}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			tr.leave[lv.StaffID][d.Format("2006-01-02")] = true
+		}
+	}
+	// the neighbouring months' roster only extends runs and the timeline; it never counts toward balance
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range in.Shifts {
+		shiftByID[sh.ID] = sh
+	}
+	for _, a := range in.Context {
+		sh, ok := shiftByID[a.ShiftID]
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if !ok || err != nil || a.StaffID == "" || (d.Year() == year && d.Month() == mo) {
+			continue
+		}
+		tr.fix(a.StaffID, d, sh)
+	}
+	headcount := map[string]float64{}
+	for _, s := range in.Staff {
+		r := staffRoleOf(s.Position)
+		tr.role[s.ID] = r
+		headcount[r]++
+	}
+	isHoliday := func(ds string) bool {
+		for _, h := range in.Holidays {
+			if ds >= h.Start && ds <= h.End {
+				return true
+			}
+		}
+		return false
+	}
+	for d := first; d.Month() == mo; d = d.AddDate(0, 0, 1) {
+		if w, ok := in.WorkingDays[int(d.Weekday())]; ok && !w {
+			continue
+		}
+		if isHoliday(d.Format("2006-01-02")) {
+			continue
+		}
+		tr.open = append(tr.open, d)
+		for _, sh := range in.Shifts {
+			for r, need := range map[string]int{"nurse": sh.RequiredNurse, "assistant": sh.RequiredAsst} {
+				if need <= 0 || headcount[r] == 0 {
+					continue
+				}
+				share := float64(need) / headcount[r]
+				tr.shiftTarget[r][sh.ID] += share
+				tr.hoursTarget[r] += share * shiftHours(sh)
+			}
+		}
+	}
+	return tr, nil
+}
+
+// Relax treats the n lowest-priority hard rules as soft (approved leave is never relaxed);
+// Relax(0) restores the configured rules.
+func (t *RuleTracker) Relax(n int) {
+	t.lim = t.base
+	if !t.rules.Configured() {
+		// legacy: the only relaxable rule is the consecutive-day ban
+		if n > 0 {
+			t.lim.consecHard, t.lim.consecWeight = false, 1
+		}
+		return
+	}
+	list := t.rules.List()
+	for i := len(list) - 1; i >= 0 && n > 0; i-- {
+		r := list[i]
+		if !r.Hard {
+			continue
+		}
+		switch r.Kind {
+		case RuleMaxConsecutiveShifts:
+			t.lim.consecHard = false
+		case RuleMaxConsecutiveNights:
+			t.lim.nightHard = false
+		case RuleMaxContiguousHours:
+			t.lim.contigHard = false
+		case RuleMaxTotalHours:
+			t.lim.hoursCapHard = false
+		default:
+			continue
+		}
+		n--
+	}
+}
+
+// Rules returns the active rules in priority order
+func (t *RuleTracker) Rules() []Rule { return t.rules.List() }
+
+// dayOf is d's day of the month; days of the neighbouring months fall below 1 and above t.days
+func (t *RuleTracker) dayOf(d time.Time) int {
+	return int(d.Sub(t.first).Hours()/24) + 1
+}
+
+func (t *RuleTracker) runLen(byDay map[int]int, day int) int {
+	n := 1
+	for d := day - 1; byDay[d] > 0; d-- {
+		n++
+	}
+	for d := day + 1; byDay[d] > 0; d++ {
+		n++
+	}
+	return n
+}
+
+// contiguousWith is the block of touching or overlapping shifts w would join for staffID, in minutes,
+// on the month's timeline so that it runs across midnight and into the neighbouring months' Context
+func (t *RuleTracker) contiguousWith(staffID string, w window) int {
+	ivals := [][2]int{{w.start, w.end}}
+	for _, o := range t.windows[staffID] {
+		ivals = append(ivals, [2]int{o.start, o.end})
+	}
+	return blockMinutes(ivals, [2]int{w.start, w.end})
+}
+
+// OnLeave reports whether staffID has leave on d
+func (t *RuleTracker) OnLeave(staffID string, d time.Time) bool {
+	return t.leave[staffID][d.Format("2006-01-02")]
+}
+
+// Reason returns why staffID cannot take sh on d under the enforced rules; "" means eligible
+func (t *RuleTracker) Reason(staffID string, d time.Time, sh database.ShiftRecord) string {
+	date := d.Format("2006-01-02")
+	day := t.dayOf(d)
+	if t.leave[staffID][date] {
+		return "leave"
+	}
+	if t.lim.consecHard && t.lim.consecCap > 0 && t.worked[staffID][day] == 0 && t.runLen(t.worked[staffID], day) > t.lim.consecCap {
+		return "consecutive-day"
+	}
+	if t.lim.nightHard && t.lim.nightCap > 0 && IsNightShift(sh) && t.nights[staffID][day] == 0 && t.runLen(t.nights[staffID], day) > t.lim.nightCap {
+		return "consecutive-night"
+	}
+	s, e, ok := shiftMinutes(sh)
+	if !ok {
+		return "invalid-shift"
+	}
+	for _, iv := range t.intervals[staffID][date] {
+		if iv[0] < e && s < iv[1] {
+			return "overlap"
+		}
+	}
+	w, _ := shiftWindowAt(day-1, sh)
+	if t.lim.contigHard && t.contiguousWith(staffID, w) > t.lim.contigMinutes {
+		return "exceed-contiguous-hours"
+	}
+	if t.lim.hoursCapHard && t.lim.hoursCap > 0 && t.hours[staffID]+shiftHours(sh) > t.lim.hoursCap {
+		return "exceed-total-hours"
+	}
+	// rest, transitions and overlaps across days, on real timestamps
+	for _, o := range t.windows[staffID] {
+		if r := t.lim.seq.between(o, w); r != "" {
+			return r
+		}
+	}
+	return ""
+}
+
+// Cost prices the soft rules (and relaxed hard rules) and the staff preferences that assigning sh on d to staffID would touch
+func (t *RuleTracker) Cost(staffID string, d time.Time, sh database.ShiftRecord) float64 {
+	lim := t.lim
+	day := t.dayOf(d)
+	role := t.role[staffID]
+	c := 0.0
+	if !lim.consecHard && lim.consecCap > 0 && t.worked[staffID][day] == 0 {
+		if over := t.runLen(t.worked[staffID], day) - lim.consecCap; over > 0 {
+			c += rulePenalty * lim.consecWeight * float64(over)
+		}
+	}
+	if !lim.nightHard && lim.nightCap > 0 && IsNightShift(sh) && t.nights[staffID][day] == 0 {
+		if over := t.runLen(t.nights[staffID], day) - lim.nightCap; over > 0 {
+			c += rulePenalty * lim.nightWeight * float64(over)
+		}
+	}
+	if w, ok := shiftWindowAt(day-1, sh); ok && !lim.contigHard {
+		if over := t.contiguousWith(staffID, w) - lim.contigMinutes; over > 0 {
+			c += rulePenalty * lim.contigWeight * float64(over) / 60
+		}
+	}
+	if !lim.hoursCapHard && lim.hoursCap > 0 {
+		h := t.hours[staffID]
+		over := math.Max(h+shiftHours(sh)-lim.hoursCap, 0) - math.Max(h-lim.hoursCap, 0)
+		c += rulePenalty * lim.hoursCapWeight * over / 8
+	}
+	c += t.prefs.cost(staffID, d, sh)
+	if t.ledger.active() {
+		if t.ledgerCount[staffID] == nil {
+			t.ledgerCount[staffID] = t.ledger.newCounts()
+		}
+		c += t.ledger.cost(staffID, t.ledgerCount[staffID], d, sh, t.worked[staffID][day] == 0)
+	}
+	// balance rules: marginal change of the squared excess, negative while below target; targets
+	// drop by what the staff member is ahead over the fairness ledger's window
+	if lim.shiftBalance >= 0 {
+		cur := float64(t.countByShift[staffID][sh.ID]) - t.shiftTarget[role][sh.ID] + t.ledger.shiftLead(staffID, sh.ID)
+		x0, x1 := excess(cur, lim.shiftBalance), excess(cur+1, lim.shiftBalance)
+		c += rulePenalty * lim.shiftWeight * (x1*x1 - x0*x0)
+	}
+	if lim.hoursBalance >= 0 {
+		cur := t.hours[staffID] - t.hoursTarget[role] + t.ledger.hoursLead(staffID)
+		x0, x1 := excess(cur, lim.hoursBalance)/8, excess(cur+shiftHours(sh), lim.hoursBalance)/8
+		c += rulePenalty * lim.hoursWeight * (x1*x1 - x0*x0)
+	}
+	return c
+}
+
+// Satisfaction scores a roster built with the tracker against the staff preferences it priced
+func (t *RuleTracker) Satisfaction(staff []database.DepartmentStaff, shifts []database.ShiftRecord, assignments []database.Assignment) []Satisfaction {
+	return t.prefs.satisfaction(staff, shifts, assignments)
+}
+
+// CoversSkill reports whether staffID holds, with a certification valid on d, a skill sh is still short of
+func (t *RuleTracker) CoversSkill(staffID string, d time.Time, sh database.ShiftRecord) bool {
+	date := d.Format("2006-01-02")
+	for _, x := range t.skills.shortfalls(sh.ID, date, t.slots[date+"|"+sh.ID]) {
+		if t.skills.qualified(staffID, x.SkillID, date) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepsSkills reports whether staffID's place on sh on d can go to other ("" to leave it empty)
+// without leaving the shift short of a skill staffID covers; staffID may already have been unplaced
+func (t *RuleTracker) KeepsSkills(staffID, other string, d time.Time, sh database.ShiftRecord) bool {
+	date := d.Format("2006-01-02")
+	var ids []string // the shift's other staff
+	for _, id := range t.slots[date+"|"+sh.ID] {
+		if id != staffID {
+			ids = append(ids, id)
+		}
+	}
+	for _, r := range t.skills.reqs[sh.ID] {
+		if t.skills.qualified(staffID, r.skill, date) && !t.skills.qualified(other, r.skill, date) && t.skills.holders(ids, r.skill, date) < r.min {
+			return false
+		}
+	}
+	return true
+}
+
+// SkillGaps lists the shifts on the month's working days whose placed staff do not hold the skills the
+// shift requires, with MissingSkills filled in
+func (t *RuleTracker) SkillGaps() []UnmetSlot {
+	out := []UnmetSlot{}
+	for _, d := range t.open {
+		date := d.Format("2006-01-02")
+		for _, sh := range t.shifts {
+			if miss := t.skills.shortfalls(sh.ID, date, t.slots[date+"|"+sh.ID]); len(miss) > 0 {
+				out = append(out, UnmetSlot{Date: date, ShiftID: sh.ID, ShiftName: sh.Name, MissingSkills: miss, Candidates: []BlockedCandidate{}})
+			}
+		}
+	}
+	return out
+}
+
+// Place records that staffID works sh on d
+func (t *RuleTracker) Place(staffID string, d time.Time, sh database.ShiftRecord) {
+	key := d.Format("2006-01-02") + "|" + sh.ID
+	t.slots[key] = append(t.slots[key], staffID)
+	if t.ledger.active() {
+		if t.ledgerCount[staffID] == nil {
+			t.ledgerCount[staffID] = t.ledger.newCounts()
+		}
+		t.ledger.count(t.ledgerCount[staffID], d, sh, t.worked[staffID][t.dayOf(d)] == 0, 1)
+	}
+	t.fix(staffID, d, sh)
+	t.hours[staffID] += shiftHours(sh)
+	t.countByShift[staffID][sh.ID]++
+}
+
+// fix puts staffID on sh on d for the run, overlap and sequence rules only
+func (t *RuleTracker) fix(staffID string, d time.Time, sh database.ShiftRecord) {
+	date := d.Format("2006-01-02")
+	if t.worked[staffID] == nil {
+		t.worked[staffID] = map[int]int{}
+		t.nights[staffID] = map[int]int{}
+		t.countByShift[staffID] = map[string]int{}
+		t.intervals[staffID] = map[string][][2]int{}
+	}
+	day := t.dayOf(d)
+	t.worked[staffID][day]++
+	if IsNightShift(sh) {
+		t.nights[staffID][day]++
+	}
+	if s, e, ok := shiftMinutes(sh); ok {
+		t.intervals[staffID][date] = append(t.intervals[staffID][date], [2]int{s, e})
+	}
+	if w, ok := shiftWindowAt(day-1, sh); ok {
+		t.windows[staffID] = append(t.windows[staffID], w)
+	}
+}
+
+// Unplace reverses Place
+func (t *RuleTracker) Unplace(staffID string, d time.Time, sh database.ShiftRecord) {
+	if t.worked[staffID] == nil {
+		return
+	}
+	date := d.Format("2006-01-02")
+	key := date + "|" + sh.ID
+	for i, id := range t.slots[key] {
+		if id == staffID {
+			t.slots[key] = append(t.slots[key][:i:i], t.slots[key][i+1:]...)
+			break
+		}
+	}
+	day := t.dayOf(d)
+	t.worked[staffID][day]--
+	if IsNightShift(sh) {
+		t.nights[staffID][day]--
+	}
+	if cur := t.ledgerCount[staffID]; cur != nil {
+		t.ledger.count(cur, d, sh, t.worked[staffID][day] == 0, -1)
+	}
+	t.hours[staffID] -= shiftHours(sh)
+	t.countByShift[staffID][sh.ID]--
+	if s, e, ok := shiftMinutes(sh); ok {
+		old := t.intervals[staffID][date]
+		for i, iv := range old {
+			if iv[0] == s && iv[1] == e {
+				t.intervals[staffID][date] = append(old[:i:i], old[i+1:]...)
+				break
+			}
+		}
+	}
+	if w, ok := shiftWindowAt(day-1, sh); ok {
+		old := t.windows[staffID]
+		for i, o := range old {
+			if o == w {
+				t.windows[staffID] = append(old[:i:i], old[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package optimizer
+
+import (
+	"strings"
+	"time"
+
+	"nurseshift/schedule-service/internal/infrastructure/database"
+)
+
+// Violation is a hard rule an assignment would break
+type Violation struct {
+	StaffID string `json:"staffId"`
+	ShiftID string `json:"shiftId"`
+	Date    string `json:"date"`
+	Reason  string `json:"reason"`
+}
+
+func assignmentKey(a database.Assignment) string {
+	return a.StaffID + "|" + a.ScheduleDate + "|" + a.ShiftID
+}
+
+// ValidateChanges checks manual edits of in.Month against the same hard rules the generators enforce.
+// current is the month's roster; remove is dropped from it and each add is checked in order on top of the rest.
+// An add on a lapsed certification the shift requires is refused, and so is an edit that leaves a shift
+// short of a required skill it was better covered for.
+func ValidateChanges(in Input, current, remove, add []database.Assignment) ([]Violation, error) {
+	tr, err := NewRuleTracker(in)
+	if err != nil {
+		return nil, err
+	}
+	shiftByID := map[string]database.ShiftRecord{}
+	for _, sh := range in.Shifts {
+		shiftByID[sh.ID] = sh
+	}
+	dropped := map[string]bool{}
+	var touched []string // date|shiftID of the edited shifts
+	for _, a := range remove {
+		dropped[assignmentKey(a)] = true
+		touched = append(touched, a.ScheduleDate+"|"+a.ShiftID)
+	}
+	before := map[string][]string{}
+	for _, a := range current {
+		if a.StaffID != "" {
+			before[a.ScheduleDate+"|"+a.ShiftID] = append(before[a.ScheduleDate+"|"+a.ShiftID], a.StaffID)
+		}
+		if a.StaffID == "" || dropped[assignmentKey(a)] {
+			continue
+		}
+		sh, ok := shiftByID[a.ShiftID]
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if !ok || err != nil {
+			continue
+		}
+		tr.Place(a.StaffID, d, sh)
+	}
+	var out []Violation
+	for _, a := range add {
+		v := Violation{StaffID: a.StaffID, ShiftID: a.ShiftID, Date: a.ScheduleDate}
+		sh, ok := shiftByID[a.ShiftID]
+		d, err := time.Parse("2006-01-02", a.ScheduleDate)
+		if !ok || err != nil {
+			v.Reason = "invalid-shift"
+			out = append(out, v)
+			continue
+		}
+		if v.Reason = tr.Reason(a.StaffID, d, sh); v.Reason != "" {
+			out = append(out, v)
+			continue
+		}
+		if len(tr.skills.lapsed(a.StaffID, a.ShiftID, a.ScheduleDate)) > 0 {
+			v.Reason = "lapsed-certification"
+			out = append(out, v)
+			continue
+		}
+		tr.Place(a.StaffID, d, sh)
+		touched = append(touched, a.ScheduleDate+"|"+a.ShiftID)
+	}
+	seen := map[string]bool{}
+	for _, key := range touched {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		date, shiftID, _ := strings.Cut(key, "|")
+		out = append(out, tr.skills.lostSkills(shiftID, date, before[key], tr.slots[key])...)
+	}
+	return out, nil
+}
@@ -11,6 +11,8 @@ import (
 
 	"database/sql"
 	usecase "nurseshift/setting-service/internal/domain/usecases"
+	"nurseshift/setting-service/internal/infrastructure/access"
+	"nurseshift/setting-service/internal/infrastructure/audit"
 	"nurseshift/setting-service/internal/infrastructure/config"
 	repoimpl "nurseshift/setting-service/internal/infrastructure/repositories"
 	"nurseshift/setting-service/internal/interfaces/http/handlers"
@@ -67,10 +69,12 @@ func main() {
 	}
 	repo := repoimpl.NewPostgresSettingRepository(db, cfg.Database.Schema)
 	uc := usecase.NewSettingUseCase(repo)
-	settingHandler := handlers.NewSettingHandler(uc)
+	guard := access.NewGuard(access.NewRepository(db, cfg.Database.Schema))
+	settingHandler := handlers.NewSettingHandler(uc, guard)
+	auditRepo := audit.NewRepository(db, cfg.Database.Schema)
 
 	// Routes
-	settingroutes.SetupRoutes(app, settingHandler)
+	settingroutes.SetupRoutes(app, settingHandler, auditRepo)
 
 	// Health check
 	app.Get("/health", settingHandler.Health)
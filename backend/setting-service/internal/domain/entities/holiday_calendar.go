@@ -0,0 +1,68 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors of shared holiday calendars
+var (
+	ErrCalendarNotFound     = errors.New("holiday calendar not found")
+	ErrCalendarNotAvailable = errors.New("holiday calendar is not available to the department")
+	ErrHolidayNotInherited  = errors.New("holiday is not inherited by the department")
+)
+
+// Holiday calendar scopes
+const (
+	CalendarScopeNational     = "national"     // public holidays, kept by system admins
+	CalendarScopeOrganization = "organization" // kept by the account that owns the departments
+)
+
+// HolidayCalendar is a shared set of holidays that departments inherit by subscribing to it
+type HolidayCalendar struct {
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	Scope        string     `json:"scope"`
+	OwnerID      *uuid.UUID `json:"ownerId,omitempty"` // organisation calendars: the owning account
+	HolidayCount int        `json:"holidayCount"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// AvailableTo reports whether departments owned by ownerID may inherit the calendar
+func (c HolidayCalendar) AvailableTo(ownerID uuid.UUID) bool {
+	return c.Scope == CalendarScopeNational || (c.OwnerID != nil && *c.OwnerID == ownerID)
+}
+
+// CalendarHoliday is a holiday of a shared calendar; UID identifies it across re-imports
+type CalendarHoliday struct {
+	ID                uuid.UUID       `json:"id"`
+	CalendarID        uuid.UUID       `json:"calendarId"`
+	UID               string          `json:"uid"`
+	Name              string          `json:"name"`
+	StartDate         time.Time       `json:"startDate"`
+	EndDate           time.Time       `json:"endDate"`
+	IsRecurring       bool            `json:"isRecurring"`
+	RecurrencePattern json.RawMessage `json:"recurrencePattern,omitempty"`
+}
+
+// HolidayOccurrence is a dated holiday of a department: one of its own, or inherited from a calendar
+type HolidayOccurrence struct {
+	HolidayID   uuid.UUID  `json:"holidayId"`
+	CalendarID  *uuid.UUID `json:"calendarId,omitempty"` // inherited holidays only
+	Name        string     `json:"name"`
+	StartDate   string     `json:"startDate"`
+	EndDate     string     `json:"endDate"`
+	IsRecurring bool       `json:"isRecurring"`
+	OptedOut    bool       `json:"optedOut"` // an inherited holiday the department works through
+}
+
+// ImportResult counts what an import did to a calendar
+type ImportResult struct {
+	Created  int      `json:"created"`
+	Updated  int      `json:"updated"`
+	Warnings []string `json:"warnings"`
+}
@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -40,8 +41,10 @@ type Holiday struct {
 	StartDate    time.Time
 	EndDate      time.Time
 	IsRecurring  bool
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// RecurrencePattern is how a recurring holiday repeats; empty repeats StartDate's day every year
+	RecurrencePattern json.RawMessage
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 // SettingsAggregate groups department settings for transport
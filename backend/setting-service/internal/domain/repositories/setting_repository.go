@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"time"
+
 	"nurseshift/setting-service/internal/domain/entities"
 
 	"github.com/google/uuid"
@@ -17,9 +19,33 @@ type SettingRepository interface {
 	UpdateShift(ctx context.Context, shift entities.Shift) error
 	UpdateShiftStatus(ctx context.Context, shiftID uuid.UUID, isActive bool) error
 	DeleteShift(ctx context.Context, shiftID uuid.UUID) error
+	GetShiftDepartment(ctx context.Context, shiftID uuid.UUID) (uuid.UUID, error)
 
 	GetHolidays(ctx context.Context, departmentID uuid.UUID) ([]entities.Holiday, error)
 	CreateHoliday(ctx context.Context, holiday entities.Holiday) (uuid.UUID, error)
 	DeleteHoliday(ctx context.Context, holidayID uuid.UUID) error
 	UpdateHoliday(ctx context.Context, holiday entities.Holiday) error
+	GetHolidayDepartment(ctx context.Context, holidayID uuid.UUID) (uuid.UUID, error)
+
+	// ListHolidayOccurrences expands a department's own and inherited holidays into dated
+	// occurrences over from..to, inherited ones the department opted out of flagged
+	ListHolidayOccurrences(ctx context.Context, departmentID uuid.UUID, from, to time.Time) ([]entities.HolidayOccurrence, error)
+	// SetHolidayOptOut makes a department work through an inherited holiday, or observe it again;
+	// ErrHolidayNotInherited when none of its calendars has the holiday
+	SetHolidayOptOut(ctx context.Context, departmentID, calendarHolidayID uuid.UUID, optOut bool) error
+	// GetDepartmentOwner returns the account that owns a department, sql.ErrNoRows when it does not exist
+	GetDepartmentOwner(ctx context.Context, departmentID uuid.UUID) (uuid.UUID, error)
+
+	// ListHolidayCalendars returns national calendars and those of ownerID; every calendar when ownerID is nil
+	ListHolidayCalendars(ctx context.Context, ownerID *uuid.UUID) ([]entities.HolidayCalendar, error)
+	ListDepartmentCalendars(ctx context.Context, departmentID uuid.UUID) ([]entities.HolidayCalendar, error)
+	// GetHolidayCalendar returns a calendar, nil when it does not exist
+	GetHolidayCalendar(ctx context.Context, calendarID uuid.UUID) (*entities.HolidayCalendar, error)
+	CreateHolidayCalendar(ctx context.Context, calendar *entities.HolidayCalendar) error
+	DeleteHolidayCalendar(ctx context.Context, calendarID uuid.UUID) error
+	ListCalendarHolidays(ctx context.Context, calendarID uuid.UUID) ([]entities.CalendarHoliday, error)
+	// UpsertCalendarHolidays adds the holidays to a calendar, replacing those with the same UID
+	UpsertCalendarHolidays(ctx context.Context, calendarID uuid.UUID, holidays []entities.CalendarHoliday) (created, updated int, err error)
+	SubscribeCalendar(ctx context.Context, departmentID, calendarID uuid.UUID) error
+	UnsubscribeCalendar(ctx context.Context, departmentID, calendarID uuid.UUID) error
 }
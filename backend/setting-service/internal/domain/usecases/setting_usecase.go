@@ -2,6 +2,8 @@ package usecases
 
 import (
 	"context"
+	"time"
+
 	"nurseshift/setting-service/internal/domain/entities"
 	"nurseshift/setting-service/internal/domain/repositories"
 
@@ -18,6 +20,23 @@ type SettingUseCase interface {
 	CreateHoliday(ctx context.Context, holiday entities.Holiday) (uuid.UUID, error)
 	DeleteHoliday(ctx context.Context, holidayID uuid.UUID) error
 	UpdateHoliday(ctx context.Context, holiday entities.Holiday) error
+	GetShiftDepartment(ctx context.Context, shiftID uuid.UUID) (uuid.UUID, error)
+	GetHolidayDepartment(ctx context.Context, holidayID uuid.UUID) (uuid.UUID, error)
+
+	ListHolidayOccurrences(ctx context.Context, departmentID uuid.UUID, from, to time.Time) ([]entities.HolidayOccurrence, error)
+	SetHolidayOptOut(ctx context.Context, departmentID, calendarHolidayID uuid.UUID, optOut bool) error
+	GetDepartmentOwner(ctx context.Context, departmentID uuid.UUID) (uuid.UUID, error)
+	ListHolidayCalendars(ctx context.Context, ownerID *uuid.UUID) ([]entities.HolidayCalendar, error)
+	ListDepartmentCalendars(ctx context.Context, departmentID uuid.UUID) ([]entities.HolidayCalendar, error)
+	GetHolidayCalendar(ctx context.Context, calendarID uuid.UUID) (*entities.HolidayCalendar, error)
+	CreateHolidayCalendar(ctx context.Context, calendar *entities.HolidayCalendar) error
+	DeleteHolidayCalendar(ctx context.Context, calendarID uuid.UUID) error
+	ListCalendarHolidays(ctx context.Context, calendarID uuid.UUID) ([]entities.CalendarHoliday, error)
+	ImportCalendarHolidays(ctx context.Context, calendarID uuid.UUID, holidays []entities.CalendarHoliday) (*entities.ImportResult, error)
+	// SubscribeCalendar lets a department inherit a calendar's holidays; ErrCalendarNotAvailable
+	// unless the calendar is national or belongs to the department's owner
+	SubscribeCalendar(ctx context.Context, departmentID, calendarID uuid.UUID) error
+	UnsubscribeCalendar(ctx context.Context, departmentID, calendarID uuid.UUID) error
 }
 
 type SettingUseCaseImpl struct {
@@ -75,3 +94,77 @@ func (uc *SettingUseCaseImpl) DeleteHoliday(ctx context.Context, holidayID uuid.
 func (uc *SettingUseCaseImpl) UpdateHoliday(ctx context.Context, holiday entities.Holiday) error {
 	return uc.repo.UpdateHoliday(ctx, holiday)
 }
+
+func (uc *SettingUseCaseImpl) GetShiftDepartment(ctx context.Context, shiftID uuid.UUID) (uuid.UUID, error) {
+	return uc.repo.GetShiftDepartment(ctx, shiftID)
+}
+
+func (uc *SettingUseCaseImpl) GetHolidayDepartment(ctx context.Context, holidayID uuid.UUID) (uuid.UUID, error) {
+	return uc.repo.GetHolidayDepartment(ctx, holidayID)
+}
+
+func (uc *SettingUseCaseImpl) ListHolidayOccurrences(ctx context.Context, departmentID uuid.UUID, from, to time.Time) ([]entities.HolidayOccurrence, error) {
+	return uc.repo.ListHolidayOccurrences(ctx, departmentID, from, to)
+}
+
+func (uc *SettingUseCaseImpl) SetHolidayOptOut(ctx context.Context, departmentID, calendarHolidayID uuid.UUID, optOut bool) error {
+	return uc.repo.SetHolidayOptOut(ctx, departmentID, calendarHolidayID, optOut)
+}
+
+func (uc *SettingUseCaseImpl) GetDepartmentOwner(ctx context.Context, departmentID uuid.UUID) (uuid.UUID, error) {
+	return uc.repo.GetDepartmentOwner(ctx, departmentID)
+}
+
+func (uc *SettingUseCaseImpl) ListHolidayCalendars(ctx context.Context, ownerID *uuid.UUID) ([]entities.HolidayCalendar, error) {
+	return uc.repo.ListHolidayCalendars(ctx, ownerID)
+}
+
+func (uc *SettingUseCaseImpl) ListDepartmentCalendars(ctx context.Context, departmentID uuid.UUID) ([]entities.HolidayCalendar, error) {
+	return uc.repo.ListDepartmentCalendars(ctx, departmentID)
+}
+
+func (uc *SettingUseCaseImpl) GetHolidayCalendar(ctx context.Context, calendarID uuid.UUID) (*entities.HolidayCalendar, error) {
+	return uc.repo.GetHolidayCalendar(ctx, calendarID)
+}
+
+func (uc *SettingUseCaseImpl) CreateHolidayCalendar(ctx context.Context, calendar *entities.HolidayCalendar) error {
+	return uc.repo.CreateHolidayCalendar(ctx, calendar)
+}
+
+func (uc *SettingUseCaseImpl) DeleteHolidayCalendar(ctx context.Context, calendarID uuid.UUID) error {
+	return uc.repo.DeleteHolidayCalendar(ctx, calendarID)
+}
+
+func (uc *SettingUseCaseImpl) ListCalendarHolidays(ctx context.Context, calendarID uuid.UUID) ([]entities.CalendarHoliday, error) {
+	return uc.repo.ListCalendarHolidays(ctx, calendarID)
+}
+
+func (uc *SettingUseCaseImpl) ImportCalendarHolidays(ctx context.Context, calendarID uuid.UUID, holidays []entities.CalendarHoliday) (*entities.ImportResult, error) {
+	created, updated, err := uc.repo.UpsertCalendarHolidays(ctx, calendarID, holidays)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.ImportResult{Created: created, Updated: updated, Warnings: []string{}}, nil
+}
+
+func (uc *SettingUseCaseImpl) SubscribeCalendar(ctx context.Context, departmentID, calendarID uuid.UUID) error {
+	calendar, err := uc.repo.GetHolidayCalendar(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+	if calendar == nil {
+		return entities.ErrCalendarNotFound
+	}
+	owner, err := uc.repo.GetDepartmentOwner(ctx, departmentID)
+	if err != nil {
+		return err
+	}
+	if !calendar.AvailableTo(owner) {
+		return entities.ErrCalendarNotAvailable
+	}
+	return uc.repo.SubscribeCalendar(ctx, departmentID, calendarID)
+}
+
+func (uc *SettingUseCaseImpl) UnsubscribeCalendar(ctx context.Context, departmentID, calendarID uuid.UUID) error {
+	return uc.repo.UnsubscribeCalendar(ctx, departmentID, calendarID)
+}
@@ -0,0 +1,266 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	domain "nurseshift/setting-service/internal/domain/entities"
+	"nurseshift/setting-service/internal/infrastructure/holiday"
+
+	"github.com/google/uuid"
+)
+
+func nullJSON(b json.RawMessage) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return []byte(b)
+}
+
+// ListHolidayOccurrences expands the department's holidays and those of its calendars over from..to
+func (r *PostgresSettingRepository) ListHolidayOccurrences(ctx context.Context, departmentID uuid.UUID, from, to time.Time) ([]domain.HolidayOccurrence, error) {
+	// recurring rows are expanded here; the others only need to overlap the range
+	query := fmt.Sprintf(`
+		SELECT id, NULL::uuid, name, start_date, end_date, is_recurring, recurrence_pattern, false
+		FROM %s.holidays
+		WHERE department_id = $1 AND (is_recurring OR (start_date <= $3 AND end_date >= $2))
+		UNION ALL
+		SELECT ch.id, ch.calendar_id, ch.name, ch.start_date, ch.end_date, ch.is_recurring, ch.recurrence_pattern,
+			EXISTS (SELECT 1 FROM %s.department_holiday_opt_outs o
+				WHERE o.department_id = $1 AND o.calendar_holiday_id = ch.id)
+		FROM %s.calendar_holidays ch
+		JOIN %s.department_holiday_calendars dc ON dc.calendar_id = ch.calendar_id AND dc.department_id = $1
+		WHERE ch.is_recurring OR (ch.start_date <= $3 AND ch.end_date >= $2)
+	`, r.schema, r.schema, r.schema, r.schema)
+	rows, err := r.db.QueryContext(ctx, query, departmentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.HolidayOccurrence{}
+	for rows.Next() {
+		var (
+			id         uuid.UUID
+			calendarID uuid.NullUUID
+			name       string
+			rule       holiday.Rule
+			raw        []byte
+			optedOut   bool
+		)
+		if err := rows.Scan(&id, &calendarID, &name, &rule.Start, &rule.End, &rule.Recurring, &raw, &optedOut); err != nil {
+			return nil, err
+		}
+		// a pattern that no longer parses falls back to the yearly date of the first occurrence
+		rule.Pattern, _ = holiday.ParsePattern(raw)
+		for _, o := range rule.Occurrences(from, to) {
+			occ := domain.HolidayOccurrence{
+				HolidayID:   id,
+				Name:        name,
+				StartDate:   o.Start.Format("2006-01-02"),
+				EndDate:     o.End.Format("2006-01-02"),
+				IsRecurring: rule.Recurring,
+				OptedOut:    optedOut,
+			}
+			if calendarID.Valid {
+				cid := calendarID.UUID
+				occ.CalendarID = &cid
+			}
+			out = append(out, occ)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartDate < out[j].StartDate })
+	return out, nil
+}
+
+// SetHolidayOptOut records or clears a department's opt-out of an inherited holiday
+func (r *PostgresSettingRepository) SetHolidayOptOut(ctx context.Context, departmentID, calendarHolidayID uuid.UUID, optOut bool) error {
+	query := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM %s.calendar_holidays ch
+			JOIN %s.department_holiday_calendars dc ON dc.calendar_id = ch.calendar_id
+			WHERE ch.id = $2 AND dc.department_id = $1
+		)`, r.schema, r.schema)
+	var inherited bool
+	if err := r.db.QueryRowContext(ctx, query, departmentID, calendarHolidayID).Scan(&inherited); err != nil {
+		return err
+	}
+	if !inherited {
+		return domain.ErrHolidayNotInherited
+	}
+	if optOut {
+		query = fmt.Sprintf(`INSERT INTO %s.department_holiday_opt_outs (department_id, calendar_holiday_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, r.schema)
+	} else {
+		query = fmt.Sprintf(`DELETE FROM %s.department_holiday_opt_outs WHERE department_id = $1 AND calendar_holiday_id = $2`, r.schema)
+	}
+	_, err := r.db.ExecContext(ctx, query, departmentID, calendarHolidayID)
+	return err
+}
+
+// GetDepartmentOwner returns who created the department, or its head when that is unknown
+func (r *PostgresSettingRepository) GetDepartmentOwner(ctx context.Context, departmentID uuid.UUID) (uuid.UUID, error) {
+	query := fmt.Sprintf(`SELECT COALESCE(created_by, head_user_id) FROM %s.departments WHERE id=$1`, r.schema)
+	var owner uuid.NullUUID
+	if err := r.db.QueryRowContext(ctx, query, departmentID).Scan(&owner); err != nil {
+		return uuid.Nil, err
+	}
+	return owner.UUID, nil
+}
+
+const calendarColumns = `c.id, c.name, c.scope, c.owner_id, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM %s.calendar_holidays ch WHERE ch.calendar_id = c.id)`
+
+func (r *PostgresSettingRepository) queryCalendars(ctx context.Context, where string, args ...interface{}) ([]domain.HolidayCalendar, error) {
+	query := fmt.Sprintf(`SELECT `+calendarColumns+` FROM %s.holiday_calendars c `+where+` ORDER BY c.scope, c.name`, r.schema, r.schema)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.HolidayCalendar{}
+	for rows.Next() {
+		var c domain.HolidayCalendar
+		var owner uuid.NullUUID
+		if err := rows.Scan(&c.ID, &c.Name, &c.Scope, &owner, &c.CreatedAt, &c.UpdatedAt, &c.HolidayCount); err != nil {
+			return nil, err
+		}
+		if owner.Valid {
+			c.OwnerID = &owner.UUID
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresSettingRepository) ListHolidayCalendars(ctx context.Context, ownerID *uuid.UUID) ([]domain.HolidayCalendar, error) {
+	if ownerID == nil {
+		return r.queryCalendars(ctx, "")
+	}
+	return r.queryCalendars(ctx, `WHERE c.scope = 'national' OR c.owner_id = $1`, *ownerID)
+}
+
+func (r *PostgresSettingRepository) ListDepartmentCalendars(ctx context.Context, departmentID uuid.UUID) ([]domain.HolidayCalendar, error) {
+	where := fmt.Sprintf(`JOIN %s.department_holiday_calendars dc ON dc.calendar_id = c.id WHERE dc.department_id = $1`, r.schema)
+	return r.queryCalendars(ctx, where, departmentID)
+}
+
+func (r *PostgresSettingRepository) GetHolidayCalendar(ctx context.Context, calendarID uuid.UUID) (*domain.HolidayCalendar, error) {
+	cals, err := r.queryCalendars(ctx, `WHERE c.id = $1`, calendarID)
+	if err != nil || len(cals) == 0 {
+		return nil, err
+	}
+	return &cals[0], nil
+}
+
+func (r *PostgresSettingRepository) CreateHolidayCalendar(ctx context.Context, calendar *domain.HolidayCalendar) error {
+	query := fmt.Sprintf(`INSERT INTO %s.holiday_calendars (name, scope, owner_id) VALUES ($1,$2,$3) RETURNING id, created_at, updated_at`, r.schema)
+	return r.db.QueryRowContext(ctx, query, calendar.Name, calendar.Scope, calendar.OwnerID).
+		Scan(&calendar.ID, &calendar.CreatedAt, &calendar.UpdatedAt)
+}
+
+func (r *PostgresSettingRepository) DeleteHolidayCalendar(ctx context.Context, calendarID uuid.UUID) error {
+	query := fmt.Sprintf(`DELETE FROM %s.holiday_calendars WHERE id=$1`, r.schema)
+	result, err := r.db.ExecContext(ctx, query, calendarID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ErrCalendarNotFound
+	}
+	return nil
+}
+
+func (r *PostgresSettingRepository) ListCalendarHolidays(ctx context.Context, calendarID uuid.UUID) ([]domain.CalendarHoliday, error) {
+	query := fmt.Sprintf(`SELECT id, calendar_id, uid, name, start_date, end_date, is_recurring, recurrence_pattern FROM %s.calendar_holidays WHERE calendar_id=$1 ORDER BY start_date, name`, r.schema)
+	rows, err := r.db.QueryContext(ctx, query, calendarID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.CalendarHoliday{}
+	for rows.Next() {
+		var h domain.CalendarHoliday
+		var pattern []byte
+		if err := rows.Scan(&h.ID, &h.CalendarID, &h.UID, &h.Name, &h.StartDate, &h.EndDate, &h.IsRecurring, &pattern); err != nil {
+			return nil, err
+		}
+		h.RecurrencePattern = pattern
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// UpsertCalendarHolidays writes the holidays in one transaction, matching existing ones by UID
+func (r *PostgresSettingRepository) UpsertCalendarHolidays(ctx context.Context, calendarID uuid.UUID, holidays []domain.CalendarHoliday) (int, int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	// xmax is 0 for a freshly inserted row and set for one the conflict clause updated
+	query := fmt.Sprintf(`
+		INSERT INTO %s.calendar_holidays (calendar_id, uid, name, start_date, end_date, is_recurring, recurrence_pattern)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (calendar_id, uid) DO UPDATE SET
+			name = EXCLUDED.name,
+			start_date = EXCLUDED.start_date,
+			end_date = EXCLUDED.end_date,
+			is_recurring = EXCLUDED.is_recurring,
+			recurrence_pattern = EXCLUDED.recurrence_pattern,
+			updated_at = NOW()
+		RETURNING (xmax = 0)
+	`, r.schema)
+	created, updated := 0, 0
+	for _, h := range holidays {
+		var inserted bool
+		if err := tx.QueryRowContext(ctx, query, calendarID, h.UID, h.Name, h.StartDate, h.EndDate, h.IsRecurring, nullJSON(h.RecurrencePattern)).Scan(&inserted); err != nil {
+			return 0, 0, fmt.Errorf("failed to import holiday %q: %w", h.Name, err)
+		}
+		if inserted {
+			created++
+		} else {
+			updated++
+		}
+	}
+	touch := fmt.Sprintf(`UPDATE %s.holiday_calendars SET updated_at = NOW() WHERE id=$1`, r.schema)
+	if _, err := tx.ExecContext(ctx, touch, calendarID); err != nil {
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
+
+func (r *PostgresSettingRepository) SubscribeCalendar(ctx context.Context, departmentID, calendarID uuid.UUID) error {
+	query := fmt.Sprintf(`INSERT INTO %s.department_holiday_calendars (department_id, calendar_id) VALUES ($1,$2) ON CONFLICT DO NOTHING`, r.schema)
+	_, err := r.db.ExecContext(ctx, query, departmentID, calendarID)
+	return err
+}
+
+// UnsubscribeCalendar also drops the department's opt-outs of the calendar's holidays
+func (r *PostgresSettingRepository) UnsubscribeCalendar(ctx context.Context, departmentID, calendarID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmts := []string{
+		fmt.Sprintf(`DELETE FROM %s.department_holiday_opt_outs o USING %s.calendar_holidays ch
+			WHERE o.calendar_holiday_id = ch.id AND o.department_id = $1 AND ch.calendar_id = $2`, r.schema, r.schema),
+		fmt.Sprintf(`DELETE FROM %s.department_holiday_calendars WHERE department_id = $1 AND calendar_id = $2`, r.schema),
+	}
+	for _, q := range stmts {
+		if _, err := tx.ExecContext(ctx, q, departmentID, calendarID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -12,12 +12,14 @@ import (
 	"github.com/google/uuid"
 )
 
+var _ repo.SettingRepository = (*PostgresSettingRepository)(nil)
+
 type PostgresSettingRepository struct {
 	db     *sql.DB
 	schema string
 }
 
-func NewPostgresSettingRepository(db *sql.DB, schema string) repo.SettingRepository {
+func NewPostgresSettingRepository(db *sql.DB, schema string) *PostgresSettingRepository {
 	return &PostgresSettingRepository{db: db, schema: schema}
 }
 
@@ -116,8 +118,16 @@ func (r *PostgresSettingRepository) DeleteShift(ctx context.Context, shiftID uui
 	return err
 }
 
+// GetShiftDepartment returns the department of a shift, sql.ErrNoRows when the shift does not exist
+func (r *PostgresSettingRepository) GetShiftDepartment(ctx context.Context, shiftID uuid.UUID) (uuid.UUID, error) {
+	query := fmt.Sprintf(`SELECT department_id FROM %s.shifts WHERE id=$1`, r.schema)
+	var departmentID uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, shiftID).Scan(&departmentID)
+	return departmentID, err
+}
+
 func (r *PostgresSettingRepository) GetHolidays(ctx context.Context, departmentID uuid.UUID) ([]domain.Holiday, error) {
-	query := fmt.Sprintf(`SELECT id, department_id, name, start_date, end_date, is_recurring, created_at, updated_at FROM %s.holidays WHERE department_id=$1 ORDER BY start_date`, r.schema)
+	query := fmt.Sprintf(`SELECT id, department_id, name, start_date, end_date, is_recurring, recurrence_pattern, created_at, updated_at FROM %s.holidays WHERE department_id=$1 ORDER BY start_date`, r.schema)
 	rows, err := r.db.QueryContext(ctx, query, departmentID)
 	if err != nil {
 		return nil, err
@@ -126,9 +136,11 @@ func (r *PostgresSettingRepository) GetHolidays(ctx context.Context, departmentI
 	var result []domain.Holiday
 	for rows.Next() {
 		var h domain.Holiday
-		if err := rows.Scan(&h.ID, &h.DepartmentID, &h.Name, &h.StartDate, &h.EndDate, &h.IsRecurring, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		var pattern []byte
+		if err := rows.Scan(&h.ID, &h.DepartmentID, &h.Name, &h.StartDate, &h.EndDate, &h.IsRecurring, &pattern, &h.CreatedAt, &h.UpdatedAt); err != nil {
 			return nil, err
 		}
+		h.RecurrencePattern = pattern
 		result = append(result, h)
 	}
 	return result, nil
@@ -138,8 +150,8 @@ func (r *PostgresSettingRepository) CreateHoliday(ctx context.Context, holiday d
 	if holiday.ID == uuid.Nil {
 		holiday.ID = uuid.New()
 	}
-	query := fmt.Sprintf(`INSERT INTO %s.holidays (id, department_id, name, start_date, end_date, is_recurring, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,NOW(),NOW())`, r.schema)
-	_, err := r.db.ExecContext(ctx, query, holiday.ID, holiday.DepartmentID, holiday.Name, holiday.StartDate, holiday.EndDate, holiday.IsRecurring)
+	query := fmt.Sprintf(`INSERT INTO %s.holidays (id, department_id, name, start_date, end_date, is_recurring, recurrence_pattern, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,NOW(),NOW())`, r.schema)
+	_, err := r.db.ExecContext(ctx, query, holiday.ID, holiday.DepartmentID, holiday.Name, holiday.StartDate, holiday.EndDate, holiday.IsRecurring, nullJSON(holiday.RecurrencePattern))
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -153,7 +165,15 @@ func (r *PostgresSettingRepository) DeleteHoliday(ctx context.Context, holidayID
 }
 
 func (r *PostgresSettingRepository) UpdateHoliday(ctx context.Context, holiday domain.Holiday) error {
-	query := fmt.Sprintf(`UPDATE %s.holidays SET name=$2, start_date=$3, end_date=$4, is_recurring=$5, updated_at=NOW() WHERE id=$1`, r.schema)
-	_, err := r.db.ExecContext(ctx, query, holiday.ID, holiday.Name, holiday.StartDate, holiday.EndDate, holiday.IsRecurring)
+	query := fmt.Sprintf(`UPDATE %s.holidays SET name=$2, start_date=$3, end_date=$4, is_recurring=$5, recurrence_pattern=$6, updated_at=NOW() WHERE id=$1`, r.schema)
+	_, err := r.db.ExecContext(ctx, query, holiday.ID, holiday.Name, holiday.StartDate, holiday.EndDate, holiday.IsRecurring, nullJSON(holiday.RecurrencePattern))
 	return err
 }
+
+// GetHolidayDepartment returns the department of a holiday, sql.ErrNoRows when the holiday does not exist
+func (r *PostgresSettingRepository) GetHolidayDepartment(ctx context.Context, holidayID uuid.UUID) (uuid.UUID, error) {
+	query := fmt.Sprintf(`SELECT department_id FROM %s.holidays WHERE id=$1`, r.schema)
+	var departmentID uuid.UUID
+	err := r.db.QueryRowContext(ctx, query, holidayID).Scan(&departmentID)
+	return departmentID, err
+}
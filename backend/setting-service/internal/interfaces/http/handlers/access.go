@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"nurseshift/setting-service/internal/infrastructure/access"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// authorize answers with a department access denial unless the caller's role in the department
+// allows act on its settings
+func (h *SettingHandler) authorize(c *fiber.Ctx, departmentID string, act access.Action) (bool, error) {
+	userID, _ := c.Locals("userID").(string)
+	role, _ := c.Locals("userRole").(string)
+	if err := h.access.Check(c.Context(), userID, role, departmentID, access.ResourceSetting, act); err != nil {
+		return false, accessError(c, err)
+	}
+	return true, nil
+}
+
+// authorizeShift is authorize for the department of an existing shift
+func (h *SettingHandler) authorizeShift(c *fiber.Ctx, shiftID uuid.UUID, act access.Action) (bool, error) {
+	departmentID, err := h.uc.GetShiftDepartment(context.Background(), shiftID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบเวร"})
+	}
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return h.authorize(c, departmentID.String(), act)
+}
+
+// authorizeHoliday is authorize for the department of an existing holiday
+func (h *SettingHandler) authorizeHoliday(c *fiber.Ctx, holidayID uuid.UUID, act access.Action) (bool, error) {
+	departmentID, err := h.uc.GetHolidayDepartment(context.Background(), holidayID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบวันหยุด"})
+	}
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return h.authorize(c, departmentID.String(), act)
+}
+
+// accessError renders a failed department access check: the denial, or a lookup failure
+func accessError(c *fiber.Ctx, err error) error {
+	var denial *access.Denial
+	if errors.As(err, &denial) {
+		return c.Status(denial.Status).JSON(denial.Body())
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": "ไม่สามารถตรวจสอบสิทธิ์ในแผนกได้", "error": err.Error()})
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	ent "nurseshift/setting-service/internal/domain/entities"
+	"nurseshift/setting-service/internal/infrastructure/access"
+	"nurseshift/setting-service/internal/infrastructure/audit"
+	"nurseshift/setting-service/internal/infrastructure/holiday"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// recurrencePattern validates a holiday's recurrence for storage; nothing is stored for a one-off
+// holiday or a recurring one that repeats on its date
+func recurrencePattern(recurring bool, p *holiday.Pattern) (json.RawMessage, error) {
+	if !recurring || p == nil {
+		return nil, nil
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(p)
+}
+
+// GetHolidayOccurrences returns a department's holidays, its own and inherited, dated over from..to
+// (this year by default) with recurring ones expanded
+func (h *SettingHandler) GetHolidayOccurrences(c *fiber.Ctx) error {
+	departmentID, err := uuid.Parse(c.Query("departmentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "departmentId ไม่ถูกต้อง"})
+	}
+	if ok, err := h.authorize(c, departmentID.String(), access.ActionRead); !ok {
+		return err
+	}
+	year := time.Now().Year()
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบวันที่ต้องเป็น YYYY-MM-DD"})
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบวันที่ต้องเป็น YYYY-MM-DD"})
+		}
+	}
+	if to.Before(from) || to.Sub(from) > 3*366*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ช่วงวันที่ไม่ถูกต้อง (ไม่เกิน 3 ปี)"})
+	}
+
+	occurrences, err := h.uc.ListHolidayOccurrences(context.Background(), departmentID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงวันหยุดสำเร็จ", "data": occurrences})
+}
+
+// SetHolidayOptOut makes a department work through a holiday it inherits from a calendar, or
+// observe it again
+func (h *SettingHandler) SetHolidayOptOut(c *fiber.Ctx) error {
+	var req struct {
+		DepartmentID string `json:"departmentId"`
+		HolidayID    string `json:"holidayId"`
+		OptOut       bool   `json:"optOut"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง"})
+	}
+	departmentID, err1 := uuid.Parse(req.DepartmentID)
+	holidayID, err2 := uuid.Parse(req.HolidayID)
+	if err1 != nil || err2 != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "departmentId หรือ holidayId ไม่ถูกต้อง"})
+	}
+	if ok, err := h.authorize(c, departmentID.String(), access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("holiday.opt_out")
+	if !req.OptOut {
+		rec.Action("holiday.opt_in")
+	}
+	rec.Resource("holiday", holidayID.String())
+	rec.Department(departmentID.String())
+
+	if err := h.uc.SetHolidayOptOut(context.Background(), departmentID, holidayID, req.OptOut); err != nil {
+		if errors.Is(err, ent.ErrHolidayNotInherited) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "แผนกไม่ได้รับวันหยุดนี้จากปฏิทินที่ติดตาม"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+// canManageCalendar reports whether the caller may change a calendar: national calendars are kept by
+// system admins, an organisation's by its owner
+func canManageCalendar(c *fiber.Ctx, calendar *ent.HolidayCalendar) bool {
+	if role, _ := c.Locals("userRole").(string); role == "admin" {
+		return true
+	}
+	userID, _ := c.Locals("userID").(string)
+	return calendar.OwnerID != nil && calendar.OwnerID.String() == userID
+}
+
+// holidayCalendar loads the calendar of the :id param, answering 404 when there is none and 403 when
+// manage is set and the caller may not change it
+func (h *SettingHandler) holidayCalendar(c *fiber.Ctx, manage bool) (*ent.HolidayCalendar, error) {
+	calendarID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "calendar id ไม่ถูกต้อง"})
+	}
+	calendar, err := h.uc.GetHolidayCalendar(context.Background(), calendarID)
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if calendar == nil || (calendar.Scope != ent.CalendarScopeNational && !canManageCalendar(c, calendar)) {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบปฏิทินวันหยุด"})
+	}
+	if manage && !canManageCalendar(c, calendar) {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "ไม่มีสิทธิ์แก้ไขปฏิทินวันหยุดนี้"})
+	}
+	return calendar, nil
+}
+
+// ListHolidayCalendars returns the calendars the caller can use: national ones and their own; with
+// departmentId, the calendars the department inherits from
+func (h *SettingHandler) ListHolidayCalendars(c *fiber.Ctx) error {
+	if v := c.Query("departmentId"); v != "" {
+		departmentID, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "departmentId ไม่ถูกต้อง"})
+		}
+		if ok, err := h.authorize(c, departmentID.String(), access.ActionRead); !ok {
+			return err
+		}
+		calendars, err := h.uc.ListDepartmentCalendars(context.Background(), departmentID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงปฏิทินวันหยุดสำเร็จ", "data": calendars})
+	}
+
+	var ownerID *uuid.UUID
+	if role, _ := c.Locals("userRole").(string); role != "admin" {
+		id, err := uuid.Parse(c.Locals("userID").(string))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"status": "error", "message": "ไม่พบข้อมูลผู้ใช้"})
+		}
+		ownerID = &id
+	}
+	calendars, err := h.uc.ListHolidayCalendars(context.Background(), ownerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงปฏิทินวันหยุดสำเร็จ", "data": calendars})
+}
+
+// CreateHolidayCalendar creates a national calendar (system admins) or one of the caller's organisation
+func (h *SettingHandler) CreateHolidayCalendar(c *fiber.Ctx) error {
+	var req struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง"})
+	}
+	calendar := &ent.HolidayCalendar{Name: req.Name, Scope: req.Scope}
+	switch req.Scope {
+	case ent.CalendarScopeNational:
+		if role, _ := c.Locals("userRole").(string); role != "admin" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "error", "message": "เฉพาะผู้ดูแลระบบเท่านั้นที่สร้างปฏิทินวันหยุดราชการได้"})
+		}
+	case "", ent.CalendarScopeOrganization:
+		ownerID, err := uuid.Parse(c.Locals("userID").(string))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"status": "error", "message": "ไม่พบข้อมูลผู้ใช้"})
+		}
+		calendar.Scope = ent.CalendarScopeOrganization
+		calendar.OwnerID = &ownerID
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "scope ต้องเป็น national หรือ organization"})
+	}
+
+	if err := h.uc.CreateHolidayCalendar(context.Background(), calendar); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("holiday_calendar.create")
+	rec.Resource("holiday_calendar", calendar.ID.String())
+	rec.After(calendar)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "message": "สร้างปฏิทินวันหยุดสำเร็จ", "data": calendar})
+}
+
+// DeleteHolidayCalendar removes a calendar; departments stop inheriting its holidays
+func (h *SettingHandler) DeleteHolidayCalendar(c *fiber.Ctx) error {
+	calendar, err := h.holidayCalendar(c, true)
+	if calendar == nil {
+		return err
+	}
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("holiday_calendar.delete")
+	rec.Resource("holiday_calendar", calendar.ID.String())
+	rec.Before(calendar)
+	if err := h.uc.DeleteHolidayCalendar(context.Background(), calendar.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
+
+// ListCalendarHolidays returns the holidays of a calendar as stored
+func (h *SettingHandler) ListCalendarHolidays(c *fiber.Ctx) error {
+	calendar, err := h.holidayCalendar(c, false)
+	if calendar == nil {
+		return err
+	}
+	holidays, err := h.uc.ListCalendarHolidays(context.Background(), calendar.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "ดึงวันหยุดสำเร็จ", "data": holidays})
+}
+
+// ImportHolidayCalendar reads an iCalendar file, uploaded as "file" or sent as the body, into a
+// calendar. Holidays already imported with the same UID are updated, so a yearly re-import is safe.
+func (h *SettingHandler) ImportHolidayCalendar(c *fiber.Ctx) error {
+	calendar, err := h.holidayCalendar(c, true)
+	if calendar == nil {
+		return err
+	}
+
+	body := c.Body()
+	if fh, err := c.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ไม่สามารถอ่านไฟล์ได้"})
+		}
+		defer f.Close()
+		if body, err = io.ReadAll(f); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ไม่สามารถอ่านไฟล์ได้"})
+		}
+	}
+	parsed, err := holiday.ParseICS(bytes.NewReader(body))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ไฟล์ต้องเป็น iCalendar (.ics)", "error": err.Error()})
+	}
+
+	holidays := make([]ent.CalendarHoliday, 0, len(parsed.Events))
+	for _, e := range parsed.Events {
+		var pattern json.RawMessage
+		if e.Pattern != nil {
+			pattern, _ = json.Marshal(e.Pattern)
+		}
+		holidays = append(holidays, ent.CalendarHoliday{UID: e.UID, Name: e.Name, StartDate: e.Start, EndDate: e.End, IsRecurring: e.Recurring, RecurrencePattern: pattern})
+	}
+	result, err := h.uc.ImportCalendarHolidays(context.Background(), calendar.ID, holidays)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	if parsed.Warnings != nil {
+		result.Warnings = parsed.Warnings
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("holiday_calendar.import")
+	rec.Resource("holiday_calendar", calendar.ID.String())
+	rec.After(result)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "message": "นำเข้าวันหยุดสำเร็จ", "data": result})
+}
+
+// SubscribeHolidayCalendar makes a department inherit a calendar's holidays
+func (h *SettingHandler) SubscribeHolidayCalendar(c *fiber.Ctx) error {
+	return h.setSubscription(c, true)
+}
+
+// UnsubscribeHolidayCalendar stops a department inheriting a calendar's holidays
+func (h *SettingHandler) UnsubscribeHolidayCalendar(c *fiber.Ctx) error {
+	return h.setSubscription(c, false)
+}
+
+func (h *SettingHandler) setSubscription(c *fiber.Ctx, subscribe bool) error {
+	calendarID, err1 := uuid.Parse(c.Params("id"))
+	departmentID, err2 := uuid.Parse(c.Params("departmentId"))
+	if err1 != nil || err2 != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "calendar id หรือ departmentId ไม่ถูกต้อง"})
+	}
+	if ok, err := h.authorize(c, departmentID.String(), access.ActionMutate); !ok {
+		return err
+	}
+
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Resource("holiday_calendar", calendarID.String())
+	rec.Department(departmentID.String())
+	if !subscribe {
+		rec.Action("holiday_calendar.unsubscribe")
+		if err := h.uc.UnsubscribeCalendar(context.Background(), departmentID, calendarID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+	}
+
+	rec.Action("holiday_calendar.subscribe")
+	err := h.uc.SubscribeCalendar(context.Background(), departmentID, calendarID)
+	switch {
+	case errors.Is(err, ent.ErrCalendarNotFound), errors.Is(err, ent.ErrCalendarNotAvailable):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบปฏิทินวันหยุด"})
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "error", "message": "ไม่พบแผนก"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
+}
@@ -6,6 +6,9 @@ import (
 
 	ent "nurseshift/setting-service/internal/domain/entities"
 	usecase "nurseshift/setting-service/internal/domain/usecases"
+	"nurseshift/setting-service/internal/infrastructure/access"
+	"nurseshift/setting-service/internal/infrastructure/audit"
+	"nurseshift/setting-service/internal/infrastructure/holiday"
 
 	"strings"
 
@@ -14,10 +17,15 @@ import (
 )
 
 // SettingHandler handles setting-related HTTP requests
-type SettingHandler struct{ uc usecase.SettingUseCase }
+type SettingHandler struct {
+	uc     usecase.SettingUseCase
+	access *access.Guard
+}
 
 // NewSettingHandler creates a new setting handler
-func NewSettingHandler(uc usecase.SettingUseCase) *SettingHandler { return &SettingHandler{uc: uc} }
+func NewSettingHandler(uc usecase.SettingUseCase, guard *access.Guard) *SettingHandler {
+	return &SettingHandler{uc: uc, access: guard}
+}
 
 // GetSettings returns department settings
 func (h *SettingHandler) GetSettings(c *fiber.Ctx) error {
@@ -29,6 +37,9 @@ func (h *SettingHandler) GetSettings(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "departmentId ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionRead); !ok {
+		return err
+	}
 
 	settings, err := h.uc.GetSettings(context.Background(), departmentID)
 	if err != nil {
@@ -48,6 +59,9 @@ func (h *SettingHandler) UpdateSettings(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "departmentId ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorize(c, departmentIDStr, access.ActionMutate); !ok {
+		return err
+	}
 
 	var req struct {
 		WorkingDays []struct {
@@ -70,6 +84,14 @@ func (h *SettingHandler) UpdateSettings(c *fiber.Ctx) error {
 		}
 		days = append(days, ent.WorkingDay{DayOfWeek: weekdayMap[dayKey], IsWorkingDay: d.Enabled})
 	}
+	rec := audit.From(c.Locals(audit.LocalsKey))
+	rec.Action("setting.update_working_days")
+	rec.Resource("department", departmentIDStr)
+	rec.Department(departmentIDStr)
+	if before, err := h.uc.GetSettings(context.Background(), departmentID); err == nil {
+		rec.Before(before.WorkingDays)
+	}
+	rec.After(days)
 	if err := h.uc.UpdateWorkingDays(context.Background(), departmentID, days); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
@@ -101,6 +123,9 @@ func (h *SettingHandler) CreateShift(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "departmentId ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorize(c, deptID.String(), access.ActionMutate); !ok {
+		return err
+	}
 	// Accept free-text type, normalize trimming only
 	st := strings.TrimSpace(req.Type)
 
@@ -119,6 +144,7 @@ func (h *SettingHandler) CreateShift(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
+	audit.From(c.Locals(audit.LocalsKey)).Resource("shift", id.String())
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "id": id})
 }
 
@@ -128,6 +154,9 @@ func (h *SettingHandler) ToggleShift(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "shift id ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorizeShift(c, shiftID, access.ActionMutate); !ok {
+		return err
+	}
 	var req struct {
 		Enabled bool `json:"enabled"`
 	}
@@ -146,6 +175,9 @@ func (h *SettingHandler) DeleteShift(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "shift id ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorizeShift(c, shiftID, access.ActionMutate); !ok {
+		return err
+	}
 	if err := h.uc.DeleteShift(context.Background(), shiftID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
@@ -158,6 +190,9 @@ func (h *SettingHandler) UpdateShift(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "shift id ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorizeShift(c, shiftID, access.ActionMutate); !ok {
+		return err
+	}
 	var req struct {
 		Name           string `json:"name"`
 		Type           string `json:"type"`
@@ -184,6 +219,8 @@ func (h *SettingHandler) CreateHoliday(c *fiber.Ctx) error {
 		StartDate    string `json:"startDate"`
 		EndDate      string `json:"endDate"`
 		IsRecurring  bool   `json:"isRecurring"`
+		// Recurrence is how a recurring holiday repeats; without it the date repeats every year
+		Recurrence *holiday.Pattern `json:"recurrence"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง"})
@@ -192,15 +229,23 @@ func (h *SettingHandler) CreateHoliday(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "departmentId ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorize(c, deptID.String(), access.ActionMutate); !ok {
+		return err
+	}
 	start, err1 := time.Parse("2006-01-02", req.StartDate)
 	end, err2 := time.Parse("2006-01-02", req.EndDate)
 	if err1 != nil || err2 != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบวันที่ต้องเป็น YYYY-MM-DD"})
 	}
-	id, err := h.uc.CreateHoliday(context.Background(), ent.Holiday{DepartmentID: deptID, Name: req.Name, StartDate: start, EndDate: end, IsRecurring: req.IsRecurring})
+	pattern, err := recurrencePattern(req.IsRecurring, req.Recurrence)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบการเกิดซ้ำไม่ถูกต้อง"})
+	}
+	id, err := h.uc.CreateHoliday(context.Background(), ent.Holiday{DepartmentID: deptID, Name: req.Name, StartDate: start, EndDate: end, IsRecurring: req.IsRecurring, RecurrencePattern: pattern})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
+	audit.From(c.Locals(audit.LocalsKey)).Resource("holiday", id.String())
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "success", "id": id})
 }
 
@@ -210,6 +255,9 @@ func (h *SettingHandler) DeleteHoliday(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "holiday id ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorizeHoliday(c, holidayID, access.ActionMutate); !ok {
+		return err
+	}
 	if err := h.uc.DeleteHoliday(context.Background(), holidayID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
@@ -222,11 +270,15 @@ func (h *SettingHandler) UpdateHoliday(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "holiday id ไม่ถูกต้อง"})
 	}
+	if ok, err := h.authorizeHoliday(c, holidayID, access.ActionMutate); !ok {
+		return err
+	}
 	var req struct {
-		Name        string `json:"name"`
-		StartDate   string `json:"startDate"`
-		EndDate     string `json:"endDate"`
-		IsRecurring bool   `json:"isRecurring"`
+		Name        string           `json:"name"`
+		StartDate   string           `json:"startDate"`
+		EndDate     string           `json:"endDate"`
+		IsRecurring bool             `json:"isRecurring"`
+		Recurrence  *holiday.Pattern `json:"recurrence"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "ข้อมูลไม่ถูกต้อง"})
@@ -237,8 +289,13 @@ func (h *SettingHandler) UpdateHoliday(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบวันที่ต้องเป็น YYYY-MM-DD"})
 	}
 
+	pattern, err := recurrencePattern(req.IsRecurring, req.Recurrence)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "รูปแบบการเกิดซ้ำไม่ถูกต้อง"})
+	}
+
 	// Proper update without recreating
-	if err := h.uc.UpdateHoliday(context.Background(), ent.Holiday{ID: holidayID, Name: req.Name, StartDate: start, EndDate: end, IsRecurring: req.IsRecurring}); err != nil {
+	if err := h.uc.UpdateHoliday(context.Background(), ent.Holiday{ID: holidayID, Name: req.Name, StartDate: start, EndDate: end, IsRecurring: req.IsRecurring, RecurrencePattern: pattern}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "message": err.Error()})
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success"})
@@ -1,23 +1,34 @@
 package routes
 
 import (
+	"nurseshift/setting-service/internal/infrastructure/audit"
 	"nurseshift/setting-service/internal/interfaces/http/handlers"
 	mw "nurseshift/setting-service/internal/interfaces/http/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App, h *handlers.SettingHandler) {
+func SetupRoutes(app *fiber.App, h *handlers.SettingHandler, auditRepo *audit.Repository) {
 	api := app.Group("/api/v1")
 	settings := api.Group("/settings")
 	settings.Use(mw.AuthMiddleware())
+	settings.Use(audit.Middleware(auditRepo, "setting"))
 	settings.Get("/", h.GetSettings)
 	settings.Put("/", h.UpdateSettings)
 	settings.Post("/shifts", h.CreateShift)
 	settings.Put("/shifts/:id", h.UpdateShift)
 	settings.Patch("/shifts/:id/toggle", h.ToggleShift)
 	settings.Delete("/shifts/:id", h.DeleteShift)
+	settings.Get("/holidays", h.GetHolidayOccurrences)
+	settings.Put("/holidays/opt-outs", h.SetHolidayOptOut)
 	settings.Post("/holidays", h.CreateHoliday)
 	settings.Delete("/holidays/:id", h.DeleteHoliday)
 	settings.Put("/holidays/:id", h.UpdateHoliday)
+	settings.Get("/holiday-calendars", h.ListHolidayCalendars)
+	settings.Post("/holiday-calendars", h.CreateHolidayCalendar)
+	settings.Delete("/holiday-calendars/:id", h.DeleteHolidayCalendar)
+	settings.Get("/holiday-calendars/:id/holidays", h.ListCalendarHolidays)
+	settings.Post("/holiday-calendars/:id/import", h.ImportHolidayCalendar)
+	settings.Put("/holiday-calendars/:id/departments/:departmentId", h.SubscribeHolidayCalendar)
+	settings.Delete("/holiday-calendars/:id/departments/:departmentId", h.UnsubscribeHolidayCalendar)
 }
@@ -0,0 +1,181 @@
+package access
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Department roles, as stored in department_users.department_role
+const (
+	RoleHeadNurse = "head_nurse"
+	RoleNurse     = "nurse"
+	RoleAssistant = "assistant"
+	RoleViewer    = "viewer"
+)
+
+// Roles lists the department roles from the most to the least privileged
+var Roles = []string{RoleHeadNurse, RoleNurse, RoleAssistant, RoleViewer}
+
+// IsRole reports whether r is a department role
+func IsRole(r string) bool {
+	for _, x := range Roles {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource is a kind of department data guarded by the policy
+type Resource string
+
+const (
+	ResourceDepartment Resource = "department" // the department itself, its staff and its members
+	ResourceSchedule   Resource = "schedule"   // rosters, versions, swaps and repairs
+	ResourceLeave      Resource = "leave"
+	ResourceSetting    Resource = "setting" // working days, shifts and holidays
+	ResourcePriority   Resource = "priority"
+)
+
+// Action is what a request does to a resource
+type Action string
+
+const (
+	ActionRead    Action = "read"
+	ActionRequest Action = "request" // file a request of one's own: a leave, a shift swap
+	ActionMutate  Action = "mutate"  // create, change, approve or delete on behalf of the department
+)
+
+// Denial codes, stable for clients to branch on
+const (
+	CodeNotMember   = "DEPARTMENT_NOT_MEMBER"
+	CodeRoleDenied  = "DEPARTMENT_ROLE_DENIED"
+	CodeUnknownDept = "DEPARTMENT_NOT_FOUND"
+)
+
+var everyone = []string{RoleHeadNurse, RoleNurse, RoleAssistant, RoleViewer}
+var staff = []string{RoleHeadNurse, RoleNurse, RoleAssistant}
+var heads = []string{RoleHeadNurse}
+
+// Policy lists the department roles allowed each action on each resource. System admins pass every
+// check, and the user who heads or created a department counts as its head nurse.
+var Policy = map[Resource]map[Action][]string{
+	ResourceDepartment: {ActionRead: everyone, ActionMutate: heads},
+	ResourceSchedule:   {ActionRead: everyone, ActionRequest: staff, ActionMutate: heads},
+	ResourceLeave:      {ActionRead: everyone, ActionRequest: staff, ActionMutate: heads},
+	ResourceSetting:    {ActionRead: everyone, ActionMutate: heads},
+	ResourcePriority:   {ActionRead: everyone, ActionMutate: heads},
+}
+
+// Allows reports whether a department role may perform act on res
+func Allows(role string, res Resource, act Action) bool {
+	for _, r := range Policy[res][act] {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrUnknownDepartment is returned by a Membership when the department does not exist
+var ErrUnknownDepartment = errors.New("department not found")
+
+// Denial is a refused department access check
+type Denial struct {
+	Status  int
+	Code    string
+	Message string
+	Role    string // the caller's department role, empty when not a member
+}
+
+func (d *Denial) Error() string {
+	return fmt.Sprintf("%s: %s", d.Code, d.Message)
+}
+
+// Body is the JSON error response of the denial
+func (d *Denial) Body() map[string]interface{} {
+	body := map[string]interface{}{
+		"status":  "error",
+		"code":    d.Code,
+		"message": d.Message,
+	}
+	if d.Role != "" {
+		body["departmentRole"] = d.Role
+	}
+	return body
+}
+
+// Membership looks up the role of a user in a department; "" when the user is not a member
+type Membership interface {
+	DepartmentRole(ctx context.Context, userID, departmentID string) (string, error)
+}
+
+// Guard enforces the policy against department memberships
+type Guard struct {
+	members Membership
+}
+
+// NewGuard creates a guard
+func NewGuard(members Membership) *Guard {
+	return &Guard{members: members}
+}
+
+// Check returns nil when the user may perform act on res in the department, a *Denial when the
+// policy refuses it, or the lookup error. globalRole is the role of the token; "admin" passes.
+func (g *Guard) Check(ctx context.Context, userID, globalRole, departmentID string, res Resource, act Action) error {
+	if globalRole == "admin" {
+		return nil
+	}
+	if userID == "" || departmentID == "" {
+		return &Denial{Status: http.StatusForbidden, Code: CodeNotMember, Message: "คุณไม่ได้เป็นสมาชิกของแผนกนี้"}
+	}
+	if _, err := uuid.Parse(departmentID); err != nil {
+		return &Denial{Status: http.StatusNotFound, Code: CodeUnknownDept, Message: "ไม่พบแผนก"}
+	}
+	role, err := g.members.DepartmentRole(ctx, userID, departmentID)
+	if errors.Is(err, ErrUnknownDepartment) {
+		return &Denial{Status: http.StatusNotFound, Code: CodeUnknownDept, Message: "ไม่พบแผนก"}
+	}
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return &Denial{Status: http.StatusForbidden, Code: CodeNotMember, Message: "คุณไม่ได้เป็นสมาชิกของแผนกนี้"}
+	}
+	if !Allows(role, res, act) {
+		return &Denial{Status: http.StatusForbidden, Code: CodeRoleDenied, Message: "บทบาทของคุณในแผนกนี้ไม่มีสิทธิ์ดำเนินการนี้", Role: role}
+	}
+	return nil
+}
+
+// Repository reads department memberships from PostgreSQL
+type Repository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewRepository creates a membership repository
+func NewRepository(db *sql.DB, schema string) *Repository {
+	return &Repository{db: db, schema: schema}
+}
+
+// DepartmentRole returns the user's department_role, head_nurse for the department's head or creator
+func (r *Repository) DepartmentRole(ctx context.Context, userID, departmentID string) (string, error) {
+	q := fmt.Sprintf(`
+		SELECT CASE WHEN d.head_user_id = $2 OR d.created_by = $2 THEN 'head_nurse'
+		            ELSE COALESCE(du.department_role::text, '') END
+		FROM %[1]s.departments d
+		LEFT JOIN %[1]s.department_users du ON du.department_id = d.id AND du.user_id = $2
+		WHERE d.id = $1`, r.schema)
+	var role string
+	err := r.db.QueryRowContext(ctx, q, departmentID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUnknownDepartment
+	}
+	return role, err
+}
@@ -0,0 +1,160 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+const (
+	testDept  = "6f1c2b9e-3d4a-4e5f-8a7b-9c0d1e2f3a4b"
+	otherDept = "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"
+)
+
+// fakeMembership serves roles from a map keyed by user and department
+type fakeMembership struct {
+	roles map[string]string
+	err   error
+}
+
+func (f *fakeMembership) DepartmentRole(_ context.Context, userID, departmentID string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if departmentID == otherDept {
+		return "", ErrUnknownDepartment
+	}
+	return f.roles[userID+"/"+departmentID], nil
+}
+
+func TestPolicyTable(t *testing.T) {
+	tests := []struct {
+		role    string
+		res     Resource
+		act     Action
+		allowed bool
+	}{
+		{RoleHeadNurse, ResourceSchedule, ActionMutate, true},
+		{RoleNurse, ResourceSchedule, ActionRead, true},
+		{RoleNurse, ResourceSchedule, ActionRequest, true},
+		{RoleNurse, ResourceSchedule, ActionMutate, false},
+		{RoleAssistant, ResourceLeave, ActionRequest, true},
+		{RoleAssistant, ResourceLeave, ActionMutate, false},
+		{RoleViewer, ResourceLeave, ActionRead, true},
+		{RoleViewer, ResourceLeave, ActionRequest, false},
+		{RoleViewer, ResourceSchedule, ActionRequest, false},
+		{RoleHeadNurse, ResourceSetting, ActionMutate, true},
+		{RoleNurse, ResourceSetting, ActionMutate, false},
+		{RoleViewer, ResourceSetting, ActionRead, true},
+		{RoleHeadNurse, ResourcePriority, ActionMutate, true},
+		{RoleAssistant, ResourcePriority, ActionMutate, false},
+		{RoleHeadNurse, ResourceDepartment, ActionMutate, true},
+		{RoleNurse, ResourceDepartment, ActionMutate, false},
+		{RoleViewer, ResourceDepartment, ActionRead, true},
+		{"", ResourceSchedule, ActionRead, false},
+		{"admin", ResourceSchedule, ActionRead, false},
+		{RoleHeadNurse, ResourceSetting, ActionRequest, false},
+	}
+
+	for _, tt := range tests {
+		if got := Allows(tt.role, tt.res, tt.act); got != tt.allowed {
+			t.Errorf("Allows(%q, %s, %s) = %v, want %v", tt.role, tt.res, tt.act, got, tt.allowed)
+		}
+	}
+}
+
+func TestPolicyCoversEveryResource(t *testing.T) {
+	for _, res := range []Resource{ResourceDepartment, ResourceSchedule, ResourceLeave, ResourceSetting, ResourcePriority} {
+		for _, role := range Roles {
+			if !Allows(role, res, ActionRead) {
+				t.Errorf("%s cannot read %s", role, res)
+			}
+		}
+		if !Allows(RoleHeadNurse, res, ActionMutate) {
+			t.Errorf("head nurse cannot mutate %s", res)
+		}
+		if Allows(RoleViewer, res, ActionMutate) || Allows(RoleViewer, res, ActionRequest) {
+			t.Errorf("viewer can write %s", res)
+		}
+	}
+}
+
+func TestGuardCheck(t *testing.T) {
+	members := &fakeMembership{roles: map[string]string{
+		"head/" + testDept:      RoleHeadNurse,
+		"nurse/" + testDept:     RoleNurse,
+		"assistant/" + testDept: RoleAssistant,
+		"viewer/" + testDept:    RoleViewer,
+	}}
+	guard := NewGuard(members)
+
+	tests := []struct {
+		name       string
+		userID     string
+		globalRole string
+		department string
+		res        Resource
+		act        Action
+		wantStatus int // 0 when allowed
+		wantCode   string
+	}{
+		{"admin passes without membership", "root", "admin", testDept, ResourceSchedule, ActionMutate, 0, ""},
+		{"head nurse edits the roster", "head", "user", testDept, ResourceSchedule, ActionMutate, 0, ""},
+		{"nurse reads the roster", "nurse", "user", testDept, ResourceSchedule, ActionRead, 0, ""},
+		{"nurse cannot edit the roster", "nurse", "user", testDept, ResourceSchedule, ActionMutate, http.StatusForbidden, CodeRoleDenied},
+		{"assistant files a leave", "assistant", "user", testDept, ResourceLeave, ActionRequest, 0, ""},
+		{"assistant cannot approve a leave", "assistant", "user", testDept, ResourceLeave, ActionMutate, http.StatusForbidden, CodeRoleDenied},
+		{"viewer reads settings", "viewer", "user", testDept, ResourceSetting, ActionRead, 0, ""},
+		{"viewer cannot file a leave", "viewer", "user", testDept, ResourceLeave, ActionRequest, http.StatusForbidden, CodeRoleDenied},
+		{"outsider cannot read", "stranger", "user", testDept, ResourceSchedule, ActionRead, http.StatusForbidden, CodeNotMember},
+		{"missing user", "", "user", testDept, ResourceSchedule, ActionRead, http.StatusForbidden, CodeNotMember},
+		{"missing department", "head", "user", "", ResourceSchedule, ActionRead, http.StatusForbidden, CodeNotMember},
+		{"malformed department", "head", "user", "not-a-uuid", ResourceSchedule, ActionRead, http.StatusNotFound, CodeUnknownDept},
+		{"unknown department", "head", "user", otherDept, ResourceSchedule, ActionRead, http.StatusNotFound, CodeUnknownDept},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := guard.Check(context.Background(), tt.userID, tt.globalRole, tt.department, tt.res, tt.act)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("expected access, got %v", err)
+				}
+				return
+			}
+			var denial *Denial
+			if !errors.As(err, &denial) {
+				t.Fatalf("expected a denial, got %v", err)
+			}
+			if denial.Status != tt.wantStatus || denial.Code != tt.wantCode {
+				t.Errorf("got %d %s, want %d %s", denial.Status, denial.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGuardCheckLookupError(t *testing.T) {
+	lookupErr := errors.New("connection refused")
+	guard := NewGuard(&fakeMembership{err: lookupErr})
+
+	err := guard.Check(context.Background(), "head", "user", testDept, ResourceSchedule, ActionRead)
+	if !errors.Is(err, lookupErr) {
+		t.Fatalf("expected the lookup error, got %v", err)
+	}
+	var denial *Denial
+	if errors.As(err, &denial) {
+		t.Fatalf("a failed lookup must not be reported as a denial")
+	}
+}
+
+func TestDenialBody(t *testing.T) {
+	body := (&Denial{Status: http.StatusForbidden, Code: CodeRoleDenied, Message: "x", Role: RoleViewer}).Body()
+	if body["status"] != "error" || body["code"] != CodeRoleDenied || body["departmentRole"] != RoleViewer {
+		t.Errorf("unexpected body %v", body)
+	}
+	body = (&Denial{Status: http.StatusForbidden, Code: CodeNotMember, Message: "x"}).Body()
+	if _, ok := body["departmentRole"]; ok {
+		t.Errorf("non-member denial carries a role: %v", body)
+	}
+}
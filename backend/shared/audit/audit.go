@@ -0,0 +1,266 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LocalsKey is the fiber.Ctx locals key under which the audit middleware keeps the request's Recorder
+const LocalsKey = "audit"
+
+// Entry is one row of audit_logs
+type Entry struct {
+	ID           string          `json:"id"`
+	UserID       *string         `json:"userId"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resourceType"`
+	ResourceID   *string         `json:"resourceId"`
+	DepartmentID *string         `json:"departmentId"`
+	OldData      json.RawMessage `json:"oldData"`
+	NewData      json.RawMessage `json:"newData"`
+	IPAddress    *string         `json:"ipAddress"`
+	UserAgent    *string         `json:"userAgent"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// Filter selects audit entries; empty fields do not filter
+type Filter struct {
+	ResourceType string
+	ResourceID   string
+	UserID       string
+	DepartmentID string
+	Mentions     string // an id that appears in the before or after data, e.g. a staff member
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+	Offset       int
+}
+
+// Recorder collects what one request changed. Handlers describe the change through it and the audit
+// middleware writes it after the handler succeeded. All methods are no-ops on a nil Recorder, so
+// handlers may call them on routes without the middleware.
+type Recorder struct {
+	entry   Entry
+	oldData any
+	newData any
+	skip    bool
+}
+
+// NewRecorder starts the record of a request made by userID
+func NewRecorder(userID, ip, userAgent string) *Recorder {
+	r := &Recorder{}
+	r.entry.UserID = uuidPtr(userID)
+	if ip != "" {
+		r.entry.IPAddress = &ip
+	}
+	if userAgent != "" {
+		r.entry.UserAgent = &userAgent
+	}
+	return r
+}
+
+// From returns the Recorder stored in fiber locals, or nil
+func From(v interface{}) *Recorder {
+	r, _ := v.(*Recorder)
+	return r
+}
+
+// Action names the change, e.g. "schedule.edit_shift"; the middleware defaults to method and route
+func (r *Recorder) Action(action string) {
+	if r != nil {
+		r.entry.Action = action
+	}
+}
+
+// Resource sets what was changed; ids that are not UUIDs are kept out of resource_id
+func (r *Recorder) Resource(resourceType, id string) {
+	if r != nil {
+		r.entry.ResourceType = resourceType
+		r.entry.ResourceID = uuidPtr(id)
+	}
+}
+
+// Department ties the change to a department so its head can find it
+func (r *Recorder) Department(id string) {
+	if r != nil {
+		r.entry.DepartmentID = uuidPtr(id)
+	}
+}
+
+// Before snapshots the resource as it was before the change
+func (r *Recorder) Before(v any) {
+	if r != nil {
+		r.oldData = v
+	}
+}
+
+// After snapshots the resource as the change left it
+func (r *Recorder) After(v any) {
+	if r != nil {
+		r.newData = v
+	}
+}
+
+// Skip drops the record, for requests that turned out to change nothing
+func (r *Recorder) Skip() {
+	if r != nil {
+		r.skip = true
+	}
+}
+
+// Describe fills what the handler left unset; the middleware calls it with what the route tells
+func (r *Recorder) Describe(action, resourceType, resourceID, departmentID string) {
+	if r == nil {
+		return
+	}
+	if r.entry.Action == "" {
+		r.entry.Action = action
+	}
+	if r.entry.ResourceType == "" {
+		r.Resource(resourceType, resourceID)
+	}
+	if r.entry.DepartmentID == nil {
+		r.Department(departmentID)
+	}
+}
+
+// HasSnapshot reports whether the handler recorded before or after data
+func (r *Recorder) HasSnapshot() bool {
+	return r != nil && (r.oldData != nil || r.newData != nil)
+}
+
+// Entry returns the entry to write, or false when the record was skipped
+func (r *Recorder) Entry() (*Entry, bool, error) {
+	if r == nil || r.skip {
+		return nil, false, nil
+	}
+	e := r.entry
+	var err error
+	if e.OldData, err = marshal(r.oldData); err != nil {
+		return nil, false, err
+	}
+	if e.NewData, err = marshal(r.newData); err != nil {
+		return nil, false, err
+	}
+	return &e, true, nil
+}
+
+func marshal(v any) (json.RawMessage, error) {
+	switch d := v.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return d, nil
+	}
+	return json.Marshal(v)
+}
+
+func uuidPtr(id string) *string {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil
+	}
+	return &id
+}
+
+// Repository writes and queries audit_logs
+type Repository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewRepository creates an audit repository over schema
+func NewRepository(db *sql.DB, schema string) *Repository {
+	return &Repository{db: db, schema: schema}
+}
+
+// Write stores an entry
+func (r *Repository) Write(ctx context.Context, e *Entry) error {
+	q := fmt.Sprintf(`
+		INSERT INTO %s.audit_logs (user_id, action, resource_type, resource_id, department_id,
+			old_data, new_data, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8::inet, $9)
+		RETURNING id, created_at`, r.schema)
+	return r.db.QueryRowContext(ctx, q, e.UserID, e.Action, e.ResourceType, e.ResourceID, e.DepartmentID,
+		nullJSON(e.OldData), nullJSON(e.NewData), e.IPAddress, e.UserAgent).Scan(&e.ID, &e.CreatedAt)
+}
+
+func nullJSON(b json.RawMessage) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+// List returns entries matching the filter, newest first, and the total number of matches
+func (r *Repository) List(ctx context.Context, f Filter) ([]Entry, int, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ResourceType != "" {
+		add("resource_type = $%d", f.ResourceType)
+	}
+	if f.ResourceID != "" {
+		add("resource_id::text = $%d", f.ResourceID)
+	}
+	if f.UserID != "" {
+		add("user_id::text = $%d", f.UserID)
+	}
+	if f.DepartmentID != "" {
+		add("department_id::text = $%d", f.DepartmentID)
+	}
+	if f.Mentions != "" {
+		add("position($%d in COALESCE(old_data::text, '') || COALESCE(new_data::text, '')) > 0", f.Mentions)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s.audit_logs %s", r.schema, cond), args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	q := fmt.Sprintf(`
+		SELECT id, user_id::text, action, COALESCE(resource_type, ''), resource_id::text, department_id::text,
+			old_data, new_data, host(ip_address), user_agent, created_at
+		FROM %s.audit_logs %s
+		ORDER BY created_at DESC
+		LIMIT %d OFFSET %d`, r.schema, cond, limit, f.Offset)
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var oldData, newData []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Action, &e.ResourceType, &e.ResourceID, &e.DepartmentID,
+			&oldData, &newData, &e.IPAddress, &e.UserAgent, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		e.OldData, e.NewData = oldData, newData
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Writer stores finished entries; *Repository is the production Writer
+type Writer interface {
+	Write(ctx context.Context, e *Entry) error
+}
+
+// Middleware writes every successful write request to audit_logs. Handlers add before/after
+// snapshots through From(c.Locals(LocalsKey)); otherwise the JSON request body is kept as the new
+// data. The resource is the last UUID route parameter, named after the path segment before it
+// (/shifts/:id is a "shift"); routes without one are recorded as defaultType.
+func Middleware(w Writer, defaultType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if From(c.Locals(LocalsKey)) != nil {
+			// already recorded by the middleware of an enclosing group
+			return c.Next()
+		}
+		userID, _ := c.Locals("userID").(string)
+		rec := NewRecorder(userID, c.IP(), c.Get(fiber.HeaderUserAgent))
+		c.Locals(LocalsKey, rec)
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() >= fiber.StatusBadRequest {
+			return nil
+		}
+
+		route := c.Route()
+		resourceType, resourceID := defaultType, ""
+		static := defaultType
+		for _, seg := range strings.Split(route.Path, "/") {
+			if !strings.HasPrefix(seg, ":") {
+				static = seg
+				continue
+			}
+			if v := c.Params(strings.TrimPrefix(seg, ":")); v != "" {
+				if _, err := uuid.Parse(v); err == nil {
+					resourceType, resourceID = singular(static), v
+				}
+			}
+		}
+
+		body := c.Body()
+		isJSON := strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) && json.Valid(body)
+		departmentID := c.Params("departmentId", c.Query("departmentId"))
+		if departmentID == "" && isJSON {
+			var b struct {
+				DepartmentID string `json:"departmentId"`
+			}
+			_ = json.Unmarshal(body, &b)
+			departmentID = b.DepartmentID
+		}
+		rec.Describe(c.Method()+" "+route.Path, resourceType, resourceID, departmentID)
+		if !rec.HasSnapshot() && isJSON {
+			rec.After(json.RawMessage(body))
+		}
+
+		entry, ok, err := rec.Entry()
+		if err == nil && ok {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err = w.Write(ctx, entry)
+		}
+		if err != nil {
+			log.Printf("audit: failed to record %s %s: %v", c.Method(), c.Path(), err)
+		}
+		return nil
+	}
+}
+
+// singular turns a collection segment of the path into a resource type: "holidays" -> "holiday"
+func singular(segment string) string {
+	switch {
+	case strings.HasSuffix(segment, "ies"):
+		return strings.TrimSuffix(segment, "ies") + "y"
+	case strings.HasSuffix(segment, "s"):
+		return strings.TrimSuffix(segment, "s")
+	}
+	return segment
+}
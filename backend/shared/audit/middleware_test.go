@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// memWriter keeps written entries in memory
+type memWriter struct {
+	entries []*Entry
+}
+
+func (w *memWriter) Write(_ context.Context, e *Entry) error {
+	w.entries = append(w.entries, e)
+	return nil
+}
+
+const (
+	deptID  = "7d1c1d8e-6f0b-4a53-9d1e-2b2f0c8f9a01"
+	staffID = "0b5e4c2a-3f7d-4e61-8a9b-5c6d7e8f9a02"
+)
+
+func send(t *testing.T, app *fiber.App, method, path, body string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func str(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+func TestSingular(t *testing.T) {
+	for in, want := range map[string]string{
+		"holidays":   "holiday",
+		"priorities": "priority",
+		"shifts":     "shift",
+		"staff":      "staff",
+		"":           "",
+	} {
+		if got := singular(in); got != want {
+			t.Errorf("singular(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMiddlewareResource(t *testing.T) {
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	tests := []struct {
+		name, route, path            string
+		wantType, wantID, wantAction string
+	}{
+		{"last UUID parameter", "/departments/:id/staff/:staffId", "/departments/" + deptID + "/staff/" + staffID, "staff", staffID, "POST /departments/:id/staff/:staffId"},
+		{"action after the id", "/departments/:id/archive", "/departments/" + deptID + "/archive", "department", deptID, "POST /departments/:id/archive"},
+		{"parameter that is not a UUID", "/departments/:id/staff/:staffId", "/departments/" + deptID + "/staff/latest", "department", deptID, "POST /departments/:id/staff/:staffId"},
+		{"no UUID at all", "/settings/:key", "/settings/theme", "setting", "", "POST /settings/:key"},
+		{"no parameters", "/settings", "/settings", "setting", "", "POST /settings"},
+	}
+	for _, tt := range tests {
+		w := &memWriter{}
+		app := fiber.New()
+		app.Post(tt.route, Middleware(w, "setting"), ok)
+		if status := send(t, app, http.MethodPost, tt.path, ""); status != fiber.StatusOK {
+			t.Fatalf("%s: status %d", tt.name, status)
+		}
+		if len(w.entries) != 1 {
+			t.Fatalf("%s: %d entries, want 1", tt.name, len(w.entries))
+		}
+		e := w.entries[0]
+		if e.ResourceType != tt.wantType || str(e.ResourceID) != tt.wantID || e.Action != tt.wantAction {
+			t.Errorf("%s: recorded %s %q %q, want %s %q %q", tt.name, e.Action, e.ResourceType, str(e.ResourceID), tt.wantAction, tt.wantType, tt.wantID)
+		}
+	}
+}
+
+func TestMiddlewareRecordsOnlySuccessfulWrites(t *testing.T) {
+	w := &memWriter{}
+	app := fiber.New()
+	app.Use(Middleware(w, "leave"))
+	app.Get("/leaves", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Post("/leaves", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusCreated) })
+	app.Put("/leaves/:id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusUnprocessableEntity) })
+	app.Delete("/leaves/:id", func(c *fiber.Ctx) error { return errors.New("boom") })
+	app.Patch("/leaves/:id", func(c *fiber.Ctx) error {
+		From(c.Locals(LocalsKey)).Skip()
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	send(t, app, http.MethodGet, "/leaves", "")
+	send(t, app, http.MethodPut, "/leaves/"+staffID, `{"reason":"x"}`)
+	send(t, app, http.MethodDelete, "/leaves/"+staffID, "")
+	send(t, app, http.MethodPatch, "/leaves/"+staffID, "")
+	if len(w.entries) != 0 {
+		t.Fatalf("recorded %d entries for reads, failures and skipped writes", len(w.entries))
+	}
+	send(t, app, http.MethodPost, "/leaves", `{"departmentId":"`+deptID+`","reason":"sick"}`)
+	if len(w.entries) != 1 {
+		t.Fatalf("%d entries after a created leave, want 1", len(w.entries))
+	}
+	e := w.entries[0]
+	if str(e.DepartmentID) != deptID || string(e.NewData) != `{"departmentId":"`+deptID+`","reason":"sick"}` || e.OldData != nil {
+		t.Errorf("entry = department %q new %s old %s, want the body as new data", str(e.DepartmentID), e.NewData, e.OldData)
+	}
+}
+
+func TestMiddlewareKeepsHandlerSnapshots(t *testing.T) {
+	w := &memWriter{}
+	app := fiber.New()
+	app.Put("/shifts/:id", Middleware(w, "schedule"), func(c *fiber.Ctx) error {
+		rec := From(c.Locals(LocalsKey))
+		rec.Action("schedule.edit_shift")
+		rec.Department(deptID)
+		rec.Before(map[string]string{"name": "เช้า"})
+		rec.After(map[string]string{"name": "บ่าย"})
+		return c.SendStatus(fiber.StatusOK)
+	})
+	send(t, app, http.MethodPut, "/shifts/"+staffID+"?departmentId=ignored", `{"name":"บ่าย","extra":true}`)
+	if len(w.entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(w.entries))
+	}
+	e := w.entries[0]
+	if e.Action != "schedule.edit_shift" || e.ResourceType != "shift" || str(e.ResourceID) != staffID || str(e.DepartmentID) != deptID {
+		t.Errorf("entry = %s %s %q %q", e.Action, e.ResourceType, str(e.ResourceID), str(e.DepartmentID))
+	}
+	if string(e.OldData) != `{"name":"เช้า"}` || string(e.NewData) != `{"name":"บ่าย"}` {
+		t.Errorf("snapshots = %s -> %s, want the handler's", e.OldData, e.NewData)
+	}
+}
+
+func TestMiddlewareInNestedGroupsRecordsOnce(t *testing.T) {
+	w := &memWriter{}
+	app := fiber.New()
+	api := app.Group("/api", Middleware(w, "api"))
+	schedules := api.Group("/schedules", Middleware(w, "schedule"))
+	schedules.Post("/:id/publish", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	send(t, app, http.MethodPost, "/api/schedules/"+staffID+"/publish", "")
+	if len(w.entries) != 1 {
+		t.Fatalf("%d entries for one request through two audited groups, want 1", len(w.entries))
+	}
+	if e := w.entries[0]; e.ResourceType != "schedule" || str(e.ResourceID) != staffID {
+		t.Errorf("entry = %s %q", e.ResourceType, str(e.ResourceID))
+	}
+}
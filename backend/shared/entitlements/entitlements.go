@@ -0,0 +1,320 @@
+package entitlements
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Feature is a capability that only some packages include
+type Feature string
+
+const (
+	FeatureAutoSchedule Feature = "auto_schedule" // rule-based and optimizer roster generation
+	FeatureAISchedule   Feature = "ai_schedule"   // roster generation by the AI model
+)
+
+// Denial codes, stable for clients to branch on
+const (
+	CodeAccountSuspended = "ACCOUNT_SUSPENDED"
+	CodeDepartmentLimit  = "PLAN_DEPARTMENT_LIMIT"
+	CodeStaffLimit       = "PLAN_STAFF_LIMIT"
+	CodeFeatureLocked    = "PLAN_FEATURE_LOCKED"
+)
+
+// upgradeURL is where the frontend lists packages and opens an order
+const upgradeURL = "/packages"
+
+// Plan is a package: what package-service lists for sale and what the other services enforce
+type Plan struct {
+	ID             int // package id shown to the frontend
+	Type           string
+	Name           string
+	Description    string
+	Price          float64 // baht per period
+	DurationDays   int
+	MaxDepartments int
+	MaxStaff       int // active staff across all departments of the account
+	Features       []Feature
+	Highlights     []string // selling points listed after the limits
+	Popular        bool
+}
+
+// plans are ordered from the smallest to the largest package
+var plans = []Plan{
+	{
+		ID: 1, Type: "trial", Name: "แพ็คเกจทดลองใช้", Description: "ทดลองใช้ฟรี 90 วัน",
+		Price: 0, DurationDays: 90, MaxDepartments: 2, MaxStaff: 5,
+		Highlights: []string{"ตารางเวรแบบง่าย"},
+	},
+	{
+		ID: 2, Type: "standard", Name: "แพ็คเกจมาตรฐาน", Description: "เหมาะสำหรับแผนกขนาดกลาง",
+		Price: 990, DurationDays: 30, MaxDepartments: 5, MaxStaff: 25,
+		Features:   []Feature{FeatureAutoSchedule},
+		Highlights: []string{"ตารางเวรอัตโนมัติ", "การแจ้งเตือนแบบเรียลไทม์", "รายงานและสถิติ"},
+		Popular:    true,
+	},
+	{
+		ID: 3, Type: "enterprise", Name: "แพ็คเกจระดับองค์กร", Description: "เหมาะสำหรับองค์กรขนาดใหญ่",
+		Price: 2990, DurationDays: 90, MaxDepartments: 50, MaxStaff: 1000,
+		Features:   []Feature{FeatureAutoSchedule, FeatureAISchedule},
+		Highlights: []string{"ตารางเวรอัตโนมัติด้วย AI", "การแจ้งเตือนแบบเรียลไทม์", "รายงานและสถิติขั้นสูง", "การสำรองข้อมูล", "การสนับสนุนลูกค้าแบบพิเศษ"},
+	},
+}
+
+// Plans returns every package from the smallest to the largest
+func Plans() []Plan {
+	return append([]Plan(nil), plans...)
+}
+
+// PlanFor returns the plan of a package type; unknown types get the trial plan
+func PlanFor(packageType string) Plan {
+	for _, p := range plans {
+		if p.Type == packageType {
+			return p
+		}
+	}
+	return plans[0]
+}
+
+// Has reports whether the plan includes f
+func (p Plan) Has(f Feature) bool {
+	for _, x := range p.Features {
+		if x == f {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeFrom returns the smallest plan above current that satisfies ok, if any
+func upgradeFrom(current string, ok func(Plan) bool) *Plan {
+	above := false
+	for i := range plans {
+		if plans[i].Type == current {
+			above = true
+			continue
+		}
+		if above && ok(plans[i]) {
+			return &plans[i]
+		}
+	}
+	return nil
+}
+
+// Account is the package state of a user as stored on the users row
+type Account struct {
+	UserID         string
+	Status         string
+	PackageType    string
+	MaxDepartments sql.NullInt64 // set from the package on payment approval; overrides the plan default
+	ExpiresAt      sql.NullTime
+}
+
+// Plan returns the plan of the account's package
+func (a *Account) Plan() Plan {
+	return PlanFor(a.PackageType)
+}
+
+// DepartmentLimit is the number of active departments the account may own
+func (a *Account) DepartmentLimit() int {
+	if a.MaxDepartments.Valid && a.MaxDepartments.Int64 > 0 {
+		return int(a.MaxDepartments.Int64)
+	}
+	return a.Plan().MaxDepartments
+}
+
+// Suspended reports whether the account may not write: suspended by cron-service, or past its
+// expiry before the next cron run has caught up
+func (a *Account) Suspended(now time.Time) bool {
+	if a.Status == "suspended" {
+		return true
+	}
+	return a.ExpiresAt.Valid && !a.ExpiresAt.Time.After(now)
+}
+
+// Upgrade tells the client which package lifts a denial
+type Upgrade struct {
+	CurrentPackage   string `json:"currentPackage"`
+	SuggestedPackage string `json:"suggestedPackage,omitempty"`
+	SuggestedName    string `json:"suggestedName,omitempty"`
+	Limit            int    `json:"limit,omitempty"`
+	Used             int    `json:"used,omitempty"`
+	URL              string `json:"url"`
+}
+
+// Denial is a refused operation together with its HTTP status and upgrade hint
+type Denial struct {
+	Status  int
+	Code    string
+	Message string
+	Upgrade Upgrade
+}
+
+func (d *Denial) Error() string {
+	return d.Code
+}
+
+// Body is the JSON error body of the denial, in the services' response shape
+func (d *Denial) Body() map[string]interface{} {
+	return map[string]interface{}{
+		"status":  "error",
+		"message": d.Message,
+		"code":    d.Code,
+		"upgrade": d.Upgrade,
+	}
+}
+
+// ErrUnknownAccount is returned when the user has no users row
+var ErrUnknownAccount = errors.New("account not found")
+
+// Checker reads package state and usage from the database
+type Checker struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewChecker creates a checker over schema
+func NewChecker(db *sql.DB, schema string) *Checker {
+	return &Checker{db: db, schema: schema}
+}
+
+// Account loads the package state of a user
+func (c *Checker) Account(ctx context.Context, userID string) (*Account, error) {
+	q := fmt.Sprintf(`
+		SELECT id::text, status::text, COALESCE(package_type::text, 'trial'), max_departments, subscription_expires_at
+		FROM %s.users WHERE id = $1`, c.schema)
+	var a Account
+	err := c.db.QueryRowContext(ctx, q, userID).Scan(&a.UserID, &a.Status, &a.PackageType, &a.MaxDepartments, &a.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownAccount
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CheckWritable refuses writes by a suspended or expired account
+func (c *Checker) CheckWritable(ctx context.Context, userID string) error {
+	a, err := c.Account(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return suspendedDenial(a)
+}
+
+func suspendedDenial(a *Account) error {
+	if !a.Suspended(time.Now()) {
+		return nil
+	}
+	return &Denial{
+		Status:  http.StatusForbidden,
+		Code:    CodeAccountSuspended,
+		Message: "บัญชีถูกระงับเนื่องจากแพ็คเกจหมดอายุ กรุณาต่ออายุแพ็คเกจเพื่อแก้ไขข้อมูล",
+		Upgrade: Upgrade{CurrentPackage: a.PackageType, SuggestedPackage: a.PackageType, SuggestedName: a.Plan().Name, URL: upgradeURL},
+	}
+}
+
+// CheckFeature refuses f when the account's package does not include it
+func (c *Checker) CheckFeature(ctx context.Context, userID string, f Feature) error {
+	a, err := c.Account(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := suspendedDenial(a); err != nil {
+		return err
+	}
+	return featureDenial(a, f)
+}
+
+func featureDenial(a *Account, f Feature) error {
+	plan := a.Plan()
+	if plan.Has(f) {
+		return nil
+	}
+	d := &Denial{
+		Status:  http.StatusPaymentRequired,
+		Code:    CodeFeatureLocked,
+		Message: fmt.Sprintf("%s ไม่รวมฟีเจอร์นี้ กรุณาอัปเกรดแพ็คเกจ", plan.Name),
+		Upgrade: Upgrade{CurrentPackage: a.PackageType, URL: upgradeURL},
+	}
+	if next := upgradeFrom(plan.Type, func(p Plan) bool { return p.Has(f) }); next != nil {
+		d.Upgrade.SuggestedPackage, d.Upgrade.SuggestedName = next.Type, next.Name
+	}
+	return d
+}
+
+// CheckDepartmentQuota refuses creating another department once the account owns its limit
+func (c *Checker) CheckDepartmentQuota(ctx context.Context, userID string) error {
+	a, err := c.Account(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := suspendedDenial(a); err != nil {
+		return err
+	}
+	var used int
+	q := fmt.Sprintf(`SELECT COUNT(*) FROM %s.departments WHERE created_by = $1 AND is_active = true`, c.schema)
+	if err := c.db.QueryRowContext(ctx, q, userID).Scan(&used); err != nil {
+		return err
+	}
+	return departmentDenial(a, used)
+}
+
+func departmentDenial(a *Account, used int) error {
+	limit := a.DepartmentLimit()
+	if used < limit {
+		return nil
+	}
+	d := &Denial{
+		Status:  http.StatusPaymentRequired,
+		Code:    CodeDepartmentLimit,
+		Message: fmt.Sprintf("แพ็คเกจปัจจุบันสร้างแผนกได้สูงสุด %d แผนก กรุณาอัปเกรดแพ็คเกจ", limit),
+		Upgrade: Upgrade{CurrentPackage: a.PackageType, Limit: limit, Used: used, URL: upgradeURL},
+	}
+	if next := upgradeFrom(a.Plan().Type, func(p Plan) bool { return p.MaxDepartments > used }); next != nil {
+		d.Upgrade.SuggestedPackage, d.Upgrade.SuggestedName = next.Type, next.Name
+	}
+	return d
+}
+
+// CheckStaffQuota refuses adding n staff when the account's departments would exceed the staff limit
+func (c *Checker) CheckStaffQuota(ctx context.Context, userID string, n int) error {
+	a, err := c.Account(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := suspendedDenial(a); err != nil {
+		return err
+	}
+	var used int
+	q := fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s.department_staff s
+		JOIN %s.departments d ON d.id = s.department_id
+		WHERE d.created_by = $1 AND d.is_active = true AND s.is_active = true`, c.schema, c.schema)
+	if err := c.db.QueryRowContext(ctx, q, userID).Scan(&used); err != nil {
+		return err
+	}
+	return staffDenial(a, used, n)
+}
+
+func staffDenial(a *Account, used, n int) error {
+	limit := a.Plan().MaxStaff
+	if used+n <= limit {
+		return nil
+	}
+	d := &Denial{
+		Status:  http.StatusPaymentRequired,
+		Code:    CodeStaffLimit,
+		Message: fmt.Sprintf("แพ็คเกจปัจจุบันมีพนักงานได้สูงสุด %d คน (ใช้ไปแล้ว %d คน) กรุณาอัปเกรดแพ็คเกจ", limit, used),
+		Upgrade: Upgrade{CurrentPackage: a.PackageType, Limit: limit, Used: used, URL: upgradeURL},
+	}
+	if next := upgradeFrom(a.Plan().Type, func(p Plan) bool { return p.MaxStaff >= used+n }); next != nil {
+		d.Upgrade.SuggestedPackage, d.Upgrade.SuggestedName = next.Type, next.Name
+	}
+	return d
+}
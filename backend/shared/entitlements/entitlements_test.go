@@ -0,0 +1,188 @@
+package entitlements
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanFor(t *testing.T) {
+	for _, p := range Plans() {
+		if got := PlanFor(p.Type); got.ID != p.ID {
+			t.Errorf("PlanFor(%q) = plan %d, want %d", p.Type, got.ID, p.ID)
+		}
+	}
+	if got := PlanFor("lifetime"); got.Type != "trial" {
+		t.Errorf("an unknown package gets the %s plan, want trial", got.Type)
+	}
+	all := Plans()
+	all[0].MaxStaff = 1000
+	if PlanFor("trial").MaxStaff == 1000 {
+		t.Error("Plans returned the catalogue itself instead of a copy")
+	}
+}
+
+func TestSuspended(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	expires := func(d time.Duration) sql.NullTime { return sql.NullTime{Time: now.Add(d), Valid: true} }
+	tests := []struct {
+		name string
+		a    Account
+		want bool
+	}{
+		{"active without expiry", Account{Status: "active"}, false},
+		{"suspended by cron", Account{Status: "suspended", ExpiresAt: expires(time.Hour)}, true},
+		{"expires later", Account{Status: "active", ExpiresAt: expires(time.Second)}, false},
+		{"expires right now", Account{Status: "active", ExpiresAt: expires(0)}, true},
+		{"expired before cron ran", Account{Status: "active", ExpiresAt: expires(-time.Second)}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Suspended(now); got != tt.want {
+			t.Errorf("%s: Suspended = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDepartmentLimit(t *testing.T) {
+	tests := []struct {
+		packageType string
+		override    sql.NullInt64
+		want        int
+	}{
+		{"trial", sql.NullInt64{}, 2},
+		{"standard", sql.NullInt64{}, 5},
+		{"standard", sql.NullInt64{Int64: 8, Valid: true}, 8},
+		{"enterprise", sql.NullInt64{Int64: 20, Valid: true}, 20},
+		{"standard", sql.NullInt64{Int64: 0, Valid: true}, 5},
+	}
+	for _, tt := range tests {
+		a := Account{PackageType: tt.packageType, MaxDepartments: tt.override}
+		if got := a.DepartmentLimit(); got != tt.want {
+			t.Errorf("DepartmentLimit(%s, %+v) = %d, want %d", tt.packageType, tt.override, got, tt.want)
+		}
+	}
+}
+
+func TestUpgradeFrom(t *testing.T) {
+	has := func(f Feature) func(Plan) bool { return func(p Plan) bool { return p.Has(f) } }
+	tests := []struct {
+		name    string
+		current string
+		ok      func(Plan) bool
+		want    string
+	}{
+		{"next plan with the feature", "trial", has(FeatureAutoSchedule), "standard"},
+		{"skips plans without the feature", "trial", has(FeatureAISchedule), "enterprise"},
+		{"never suggests the current plan", "standard", has(FeatureAutoSchedule), "enterprise"},
+		{"nothing above the largest plan", "enterprise", has(FeatureAISchedule), ""},
+		{"nothing is big enough", "trial", func(p Plan) bool { return p.MaxStaff > 5000 }, ""},
+		{"smallest plan that fits", "trial", func(p Plan) bool { return p.MaxDepartments > 4 }, "standard"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if p := upgradeFrom(tt.current, tt.ok); p != nil {
+			got = p.Type
+		}
+		if got != tt.want {
+			t.Errorf("%s: upgradeFrom(%s) = %q, want %q", tt.name, tt.current, got, tt.want)
+		}
+	}
+}
+
+// body decodes the JSON a handler would send for err
+func body(t *testing.T, err error) map[string]interface{} {
+	t.Helper()
+	var d *Denial
+	if !errors.As(err, &d) {
+		t.Fatalf("%v is not a *Denial", err)
+	}
+	b, err := json.Marshal(d.Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestDenials(t *testing.T) {
+	past := sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	trial := &Account{Status: "active", PackageType: "trial"}
+
+	if err := suspendedDenial(&Account{Status: "active", PackageType: "standard"}); err != nil {
+		t.Errorf("an active account was denied: %v", err)
+	}
+	if err := featureDenial(&Account{PackageType: "standard"}, FeatureAutoSchedule); err != nil {
+		t.Errorf("a plan with the feature was denied: %v", err)
+	}
+	if err := departmentDenial(trial, 1); err != nil {
+		t.Errorf("a department under the limit was denied: %v", err)
+	}
+	if err := staffDenial(trial, 3, 2); err != nil {
+		t.Errorf("staff up to the limit were denied: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   map[string]interface{}
+	}{
+		{
+			name:   "expired account",
+			err:    suspendedDenial(&Account{Status: "active", PackageType: "standard", ExpiresAt: past}),
+			status: http.StatusForbidden,
+			want: map[string]interface{}{
+				"status": "error", "code": CodeAccountSuspended,
+				"message": "บัญชีถูกระงับเนื่องจากแพ็คเกจหมดอายุ กรุณาต่ออายุแพ็คเกจเพื่อแก้ไขข้อมูล",
+				"upgrade": map[string]interface{}{"currentPackage": "standard", "suggestedPackage": "standard", "suggestedName": "แพ็คเกจมาตรฐาน", "url": "/packages"},
+			},
+		},
+		{
+			name:   "feature locked",
+			err:    featureDenial(trial, FeatureAISchedule),
+			status: http.StatusPaymentRequired,
+			want: map[string]interface{}{
+				"status": "error", "code": CodeFeatureLocked,
+				"message": "แพ็คเกจทดลองใช้ ไม่รวมฟีเจอร์นี้ กรุณาอัปเกรดแพ็คเกจ",
+				"upgrade": map[string]interface{}{"currentPackage": "trial", "suggestedPackage": "enterprise", "suggestedName": "แพ็คเกจระดับองค์กร", "url": "/packages"},
+			},
+		},
+		{
+			name:   "department limit raised by the package",
+			err:    departmentDenial(&Account{PackageType: "standard", MaxDepartments: sql.NullInt64{Int64: 8, Valid: true}}, 8),
+			status: http.StatusPaymentRequired,
+			want: map[string]interface{}{
+				"status": "error", "code": CodeDepartmentLimit,
+				"message": "แพ็คเกจปัจจุบันสร้างแผนกได้สูงสุด 8 แผนก กรุณาอัปเกรดแพ็คเกจ",
+				"upgrade": map[string]interface{}{"currentPackage": "standard", "suggestedPackage": "enterprise", "suggestedName": "แพ็คเกจระดับองค์กร", "limit": 8.0, "used": 8.0, "url": "/packages"},
+			},
+		},
+		{
+			name:   "staff limit on the largest plan",
+			err:    staffDenial(&Account{PackageType: "enterprise"}, 999, 2),
+			status: http.StatusPaymentRequired,
+			want: map[string]interface{}{
+				"status": "error", "code": CodeStaffLimit,
+				"message": "แพ็คเกจปัจจุบันมีพนักงานได้สูงสุด 1000 คน (ใช้ไปแล้ว 999 คน) กรุณาอัปเกรดแพ็คเกจ",
+				"upgrade": map[string]interface{}{"currentPackage": "enterprise", "limit": 1000.0, "used": 999.0, "url": "/packages"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var d *Denial
+		if !errors.As(tt.err, &d) || d.Status != tt.status || d.Error() != tt.want["code"] {
+			t.Errorf("%s: %v, want a %d denial", tt.name, tt.err, tt.status)
+			continue
+		}
+		if got := body(t, tt.err); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: body = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Subscriber receives events from a Dispatcher. Deliver may be called more than once for the same
+// event, so it must be idempotent (see Inbox).
+type Subscriber interface {
+	Name() string
+	Deliver(ctx context.Context, e Event) error
+}
+
+// Func is an in-process subscriber
+type Func struct {
+	ID string
+	Fn func(ctx context.Context, e Event) error
+}
+
+// Name returns the subscriber's name
+func (f Func) Name() string { return f.ID }
+
+// Deliver calls Fn
+func (f Func) Deliver(ctx context.Context, e Event) error { return f.Fn(ctx, e) }
+
+// Webhook posts events as JSON to another service's internal endpoint
+type Webhook struct {
+	ID    string
+	URL   string
+	Token string // sent as X-Internal-Token
+	HTTP  *http.Client
+}
+
+// NewWebhook creates a webhook subscriber posting to url with the shared internal token
+func NewWebhook(name, url, token string) *Webhook {
+	return &Webhook{ID: name, URL: url, Token: token, HTTP: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name returns the subscriber's name
+func (w *Webhook) Name() string { return w.ID }
+
+// Deliver posts e; any status from 300 up is a failure and is retried
+func (w *Webhook) Deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", w.Token)
+	req.Header.Set("X-Event-ID", e.ID.String())
+	req.Header.Set("X-Event-Type", e.Type)
+	resp, err := w.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %d", w.ID, resp.StatusCode)
+	}
+	return nil
+}
+
+// Broker is a message broker the events can be handed to instead of being delivered directly
+type Broker interface {
+	Publish(ctx context.Context, topic string, e Event) error
+}
+
+// BrokerSubscriber publishes every event to a broker under its type as the topic
+type BrokerSubscriber struct {
+	ID     string
+	Broker Broker
+}
+
+// Name returns the subscriber's name
+func (b BrokerSubscriber) Name() string { return b.ID }
+
+// Deliver publishes e to the broker
+func (b BrokerSubscriber) Deliver(ctx context.Context, e Event) error {
+	return b.Broker.Publish(ctx, e.Type, e)
+}
+
+// DispatcherConfig tunes a Dispatcher; zero fields take the defaults
+type DispatcherConfig struct {
+	PollInterval time.Duration // how often the outbox is checked, default 2s
+	BatchSize    int           // events claimed per poll, default 50
+	Lease        time.Duration // how long a claimed batch is hidden from other dispatchers, default 5m
+	Timeout      time.Duration // limit of one delivery to one subscriber, default 30s
+	MaxAttempts  int           // deliveries tried before an event is given up, default 12
+	MaxBackoff   time.Duration // longest wait between attempts, default 1h
+}
+
+type route struct {
+	sub   Subscriber
+	types map[string]bool // nil: every type
+}
+
+// Dispatcher delivers the events of an Outbox to its subscribers at least once. Several instances
+// of a service may run one each: an event is claimed by one of them at a time. Each subscriber's
+// delivery is recorded, so a retry only goes to the subscribers that failed.
+type Dispatcher struct {
+	outbox *Outbox
+	cfg    DispatcherConfig
+	routes []route
+}
+
+// NewDispatcher creates a dispatcher for outbox
+func NewDispatcher(outbox *Outbox, cfg DispatcherConfig) *Dispatcher {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 5 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 12
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	return &Dispatcher{outbox: outbox, cfg: cfg}
+}
+
+// Subscribe delivers events of the given types to sub, or every event when no type is given.
+// Call it before Run.
+func (d *Dispatcher) Subscribe(sub Subscriber, types ...string) {
+	r := route{sub: sub}
+	if len(types) > 0 {
+		r.types = map[string]bool{}
+		for _, t := range types {
+			r.types[t] = true
+		}
+	}
+	d.routes = append(d.routes, r)
+}
+
+// Run dispatches pending events every poll interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := d.DispatchOnce(ctx)
+			if err != nil {
+				log.Printf("Failed to dispatch %s events: %v", d.outbox.source, err)
+			}
+			if err != nil || n < d.cfg.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce claims one batch of due events and delivers them, returning how many were claimed
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	events, attempts, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, e := range events {
+		if err := d.deliver(ctx, e, attempts[i]); err != nil {
+			log.Printf("Failed to record the delivery of event %s: %v", e.ID, err)
+		}
+	}
+	return len(events), nil
+}
+
+func (d *Dispatcher) claim(ctx context.Context) ([]Event, []int, error) {
+	o := d.outbox
+	// UPDATE ... RETURNING yields rows in no particular order, so the batch is sorted afterwards
+	q := fmt.Sprintf(`
+		WITH claimed AS (
+			UPDATE %s.event_outbox SET
+				locked_until = NOW() + $3 * INTERVAL '1 millisecond',
+				attempts = attempts + 1
+			WHERE id IN (
+				SELECT id FROM %s.event_outbox
+				WHERE source = $1 AND dispatched_at IS NULL AND dead_at IS NULL
+				  AND next_attempt_at <= NOW()
+				  AND (locked_until IS NULL OR locked_until < NOW())
+				ORDER BY occurred_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, event_type, aggregate_id, department_id::text, payload, occurred_at, attempts
+		)
+		SELECT * FROM claimed ORDER BY occurred_at`, o.schema, o.schema)
+	rows, err := o.db.QueryContext(ctx, q, o.source, d.cfg.BatchSize, d.cfg.Lease.Milliseconds())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var events []Event
+	var attempts []int
+	for rows.Next() {
+		e := Event{Source: o.source}
+		var dept sql.NullString
+		var payload []byte
+		var n int
+		if err := rows.Scan(&e.ID, &e.Type, &e.AggregateID, &dept, &payload, &e.OccurredAt, &n); err != nil {
+			return nil, nil, err
+		}
+		if dept.Valid {
+			e.DepartmentID = &dept.String
+		}
+		e.Payload = payload
+		events = append(events, e)
+		attempts = append(attempts, n)
+	}
+	return events, attempts, rows.Err()
+}
+
+// deliver sends e to each matching subscriber that has not received it yet, then settles the event:
+// dispatched when all of them succeeded, otherwise retried later or, after the last attempt, dead
+func (d *Dispatcher) deliver(ctx context.Context, e Event, attempt int) error {
+	o := d.outbox
+	done, err := d.delivered(ctx, e)
+	if err != nil {
+		return err
+	}
+	var failures []string
+	for _, r := range d.routes {
+		name := r.sub.Name()
+		if done[name] || (r.types != nil && !r.types[e.Type]) {
+			continue
+		}
+		dctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+		err := r.sub.Deliver(dctx, e)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		q := fmt.Sprintf(`
+			INSERT INTO %s.event_deliveries (event_id, subscriber) VALUES ($1, $2)
+			ON CONFLICT (event_id, subscriber) DO NOTHING`, o.schema)
+		if _, err := o.db.ExecContext(ctx, q, e.ID, name); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) == 0 {
+		q := fmt.Sprintf(`
+			UPDATE %s.event_outbox SET dispatched_at = NOW(), locked_until = NULL, last_error = NULL
+			WHERE id = $1`, o.schema)
+		_, err := o.db.ExecContext(ctx, q, e.ID)
+		return err
+	}
+	lastErr := strings.Join(failures, "; ")
+	if attempt >= d.cfg.MaxAttempts {
+		log.Printf("Giving up event %s (%s) after %d attempts: %s", e.ID, e.Type, attempt, lastErr)
+		q := fmt.Sprintf(`
+			UPDATE %s.event_outbox SET dead_at = NOW(), locked_until = NULL, last_error = $2
+			WHERE id = $1`, o.schema)
+		_, err := o.db.ExecContext(ctx, q, e.ID, lastErr)
+		return err
+	}
+	q := fmt.Sprintf(`
+		UPDATE %s.event_outbox SET next_attempt_at = $2, locked_until = NULL, last_error = $3
+		WHERE id = $1`, o.schema)
+	_, err = o.db.ExecContext(ctx, q, e.ID, time.Now().Add(d.backoff(attempt)), lastErr)
+	return err
+}
+
+func (d *Dispatcher) delivered(ctx context.Context, e Event) (map[string]bool, error) {
+	q := fmt.Sprintf(`SELECT subscriber FROM %s.event_deliveries WHERE event_id = $1`, d.outbox.schema)
+	rows, err := d.outbox.db.QueryContext(ctx, q, e.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		done[name] = true
+	}
+	return done, rows.Err()
+}
+
+// backoff doubles from 5s per attempt up to MaxBackoff
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := 5 * time.Second
+	for i := 1; i < attempt && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	return wait
+}
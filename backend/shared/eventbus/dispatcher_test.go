@@ -0,0 +1,244 @@
+package eventbus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDispatcherDefaults(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherConfig{MaxAttempts: 3})
+	want := DispatcherConfig{
+		PollInterval: 2 * time.Second,
+		BatchSize:    50,
+		Lease:        5 * time.Minute,
+		Timeout:      30 * time.Second,
+		MaxAttempts:  3,
+		MaxBackoff:   time.Hour,
+	}
+	if d.cfg != want {
+		t.Errorf("config = %+v, want %+v", d.cfg, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherConfig{MaxBackoff: time.Minute})
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{4, 40 * time.Second},
+		{5, time.Minute},
+		{12, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	e := testEvent(t, LeaveApproved)
+	status := http.StatusNoContent
+	var got Event
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding the posted event: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	w := NewWebhook("notification-service", srv.URL, "secret")
+	if err := w.Deliver(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != e.ID || got.Type != e.Type || string(got.Payload) != string(e.Payload) {
+		t.Errorf("posted %+v, want %+v", got, e)
+	}
+	for name, want := range map[string]string{
+		"X-Internal-Token": "secret",
+		"X-Event-ID":       e.ID.String(),
+		"X-Event-Type":     LeaveApproved,
+		"Content-Type":     "application/json",
+	} {
+		if header.Get(name) != want {
+			t.Errorf("%s = %q, want %q", name, header.Get(name), want)
+		}
+	}
+
+	for _, status = range []int{http.StatusMultipleChoices, http.StatusUnauthorized, http.StatusInternalServerError} {
+		if err := w.Deliver(context.Background(), e); err == nil {
+			t.Errorf("status %d was taken as delivered", status)
+		}
+	}
+}
+
+// outboxRow is the delivery state of one event_outbox row
+type outboxRow struct {
+	attempts      int
+	nextAttemptAt time.Time
+	lastError     sql.NullString
+	dispatched    bool
+	dead          bool
+}
+
+func readOutboxRow(t *testing.T, db *sql.DB, schema string, e Event) outboxRow {
+	t.Helper()
+	var r outboxRow
+	q := fmt.Sprintf(`
+		SELECT attempts, next_attempt_at, last_error, dispatched_at IS NOT NULL, dead_at IS NOT NULL
+		FROM %s.event_outbox WHERE id = $1`, schema)
+	if err := db.QueryRow(q, e.ID).Scan(&r.attempts, &r.nextAttemptAt, &r.lastError, &r.dispatched, &r.dead); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// makeDue lets the next DispatchOnce claim events that are waiting for their backoff
+func makeDue(t *testing.T, db *sql.DB, schema string) {
+	t.Helper()
+	if _, err := db.Exec(fmt.Sprintf(`UPDATE %s.event_outbox SET next_attempt_at = NOW()`, schema)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDispatcherRetriesOnlyFailedSubscribers(t *testing.T) {
+	db, schema := testSchema(t)
+	ctx := context.Background()
+	outbox := NewOutbox(db, schema, "employee-leave-service")
+	e := testEvent(t, LeaveApproved)
+	if err := outbox.Enqueue(ctx, db, e); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := map[string]int{}
+	down := true
+	d := NewDispatcher(outbox, DispatcherConfig{MaxAttempts: 5})
+	d.Subscribe(Func{ID: "schedule", Fn: func(context.Context, Event) error {
+		calls["schedule"]++
+		return nil
+	}})
+	d.Subscribe(Func{ID: "notification", Fn: func(context.Context, Event) error {
+		calls["notification"]++
+		if down {
+			return errors.New("unavailable")
+		}
+		return nil
+	}})
+	d.Subscribe(Func{ID: "payments", Fn: func(context.Context, Event) error {
+		calls["payments"]++
+		return nil
+	}}, PaymentApproved)
+
+	before := time.Now()
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("first dispatch claimed %d (%v), want 1", n, err)
+	}
+	row := readOutboxRow(t, db, schema, e)
+	if row.dispatched || row.dead || row.attempts != 1 {
+		t.Fatalf("after a failed delivery: %+v, want pending with one attempt", row)
+	}
+	if !strings.Contains(row.lastError.String, "notification: unavailable") {
+		t.Errorf("last_error = %q, want the failing subscriber", row.lastError.String)
+	}
+	if wait := row.nextAttemptAt.Sub(before); wait < 4*time.Second || wait > time.Minute {
+		t.Errorf("next attempt in %v, want about the 5s first backoff", wait)
+	}
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 0 {
+		t.Errorf("dispatch during the backoff claimed %d (%v), want 0", n, err)
+	}
+
+	down = false
+	makeDue(t, db, schema)
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("retry claimed %d (%v), want 1", n, err)
+	}
+	row = readOutboxRow(t, db, schema, e)
+	if !row.dispatched || row.attempts != 2 || row.lastError.Valid {
+		t.Errorf("after the retry: %+v, want dispatched after two attempts", row)
+	}
+	if calls["schedule"] != 1 || calls["notification"] != 2 || calls["payments"] != 0 {
+		t.Errorf("deliveries = %v, want schedule once, notification twice and payments never", calls)
+	}
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	db, schema := testSchema(t)
+	ctx := context.Background()
+	outbox := NewOutbox(db, schema, "payment-service")
+	e := testEvent(t, PaymentApproved)
+	if err := outbox.Enqueue(ctx, db, e); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDispatcher(outbox, DispatcherConfig{MaxAttempts: 2})
+	d.Subscribe(Func{ID: "notification", Fn: func(context.Context, Event) error { return errors.New("unavailable") }})
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		makeDue(t, db, schema)
+		if n, err := d.DispatchOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("attempt %d claimed %d (%v), want 1", attempt, n, err)
+		}
+	}
+	row := readOutboxRow(t, db, schema, e)
+	if !row.dead || row.dispatched || row.attempts != 2 {
+		t.Errorf("after MaxAttempts: %+v, want dead after two attempts", row)
+	}
+	makeDue(t, db, schema)
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 0 {
+		t.Errorf("a dead event was claimed again: %d (%v)", n, err)
+	}
+}
+
+func TestDispatcherDeliversInOccurrenceOrder(t *testing.T) {
+	db, schema := testSchema(t)
+	ctx := context.Background()
+	outbox := NewOutbox(db, schema, "schedule-service")
+	other := NewOutbox(db, schema, "payment-service")
+
+	base := time.Now().Add(-time.Hour)
+	want := []string{"v1", "v2", "v3"}
+	for _, offset := range []int{3, 1, 2} {
+		e := testEvent(t, SchedulePublished)
+		e.AggregateID = fmt.Sprintf("v%d", offset)
+		e.OccurredAt = base.Add(time.Duration(offset) * time.Minute)
+		if err := outbox.Enqueue(ctx, db, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := other.Enqueue(ctx, db, testEvent(t, PaymentApproved)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	d := NewDispatcher(outbox, DispatcherConfig{BatchSize: 2})
+	d.Subscribe(Func{ID: "recorder", Fn: func(_ context.Context, e Event) error {
+		got = append(got, e.AggregateID)
+		return nil
+	}})
+	for {
+		n, err := d.DispatchOnce(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("delivered %v, want %v and nothing from another source", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package eventbus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Domain event types shared by the services
+const (
+	LeaveApproved     = "leave.approved"
+	LeaveRejected     = "leave.rejected"
+	SchedulePublished = "schedule.published"
+	PaymentApproved   = "payment.approved"
+)
+
+// LeavePayload is the payload of leave.approved and leave.rejected
+type LeavePayload struct {
+	LeaveID         string  `json:"leaveId"`
+	StaffID         string  `json:"staffId"`
+	DepartmentID    string  `json:"departmentId"`
+	LeaveType       string  `json:"leaveType"`
+	StartDate       string  `json:"startDate"` // YYYY-MM-DD
+	EndDate         string  `json:"endDate"`
+	Status          string  `json:"status"`
+	DecidedBy       *string `json:"decidedBy,omitempty"`
+	RejectionReason *string `json:"rejectionReason,omitempty"`
+}
+
+// SchedulePublishedPayload is the payload of schedule.published
+type SchedulePublishedPayload struct {
+	VersionID    string `json:"versionId"`
+	VersionNo    int    `json:"versionNo"`
+	DepartmentID string `json:"departmentId"`
+	Month        string `json:"month"` // YYYY-MM
+	PublishedBy  string `json:"publishedBy,omitempty"`
+}
+
+// PaymentApprovedPayload is the payload of payment.approved
+type PaymentApprovedPayload struct {
+	PaymentID    string  `json:"paymentId"`
+	UserID       string  `json:"userId"`
+	PackageID    string  `json:"packageId"`
+	PackageName  string  `json:"packageName"`
+	Amount       float64 `json:"amount"`
+	ExtendedDays int     `json:"extendedDays"`
+	ApprovedBy   string  `json:"approvedBy"`
+}
+
+// Event is a domain event. Its ID stays the same on every delivery, so consumers use it to drop
+// duplicates.
+type Event struct {
+	ID           uuid.UUID       `json:"id"`
+	Type         string          `json:"type"`
+	Source       string          `json:"source"`
+	AggregateID  string          `json:"aggregateId"`
+	DepartmentID *string         `json:"departmentId,omitempty"`
+	Payload      json.RawMessage `json:"payload"`
+	OccurredAt   time.Time       `json:"occurredAt"`
+}
+
+// NewEvent creates an event about aggregateID; payload is marshalled to JSON
+func NewEvent(eventType, aggregateID, departmentID string, payload any) (Event, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	e := Event{ID: uuid.New(), Type: eventType, AggregateID: aggregateID, Payload: raw, OccurredAt: time.Now()}
+	if departmentID != "" {
+		e.DepartmentID = &departmentID
+	}
+	return e, nil
+}
+
+// Decode unmarshals the payload into v
+func (e Event) Decode(v any) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Execer runs a statement; *sql.DB and *sql.Tx both are one
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Outbox stores the events of one service in event_outbox until the Dispatcher delivers them
+type Outbox struct {
+	db     *sql.DB
+	schema string
+	source string
+}
+
+// NewOutbox creates the outbox of the service named source
+func NewOutbox(db *sql.DB, schema, source string) *Outbox {
+	return &Outbox{db: db, schema: schema, source: source}
+}
+
+// Enqueue stores e through exec. Pass the transaction that makes the change the event reports,
+// so that the event is kept exactly when the change commits.
+func (o *Outbox) Enqueue(ctx context.Context, exec Execer, e Event) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	q := fmt.Sprintf(`
+		INSERT INTO %s.event_outbox (id, source, event_type, aggregate_id, department_id, payload, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, o.schema)
+	_, err := exec.ExecContext(ctx, q, e.ID, o.source, e.Type, e.AggregateID, e.DepartmentID, string(e.Payload), e.OccurredAt)
+	return err
+}
+
+// Inbox remembers which events a consumer already handled, in event_inbox
+type Inbox struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewInbox creates an inbox over schema
+func NewInbox(db *sql.DB, schema string) *Inbox {
+	return &Inbox{db: db, schema: schema}
+}
+
+// Handle runs fn unless consumer already handled e, and records e once fn succeeded. A redelivery
+// that arrives while fn is still running waits for it and is then skipped. handled is false when e
+// was a duplicate.
+func (i *Inbox) Handle(ctx context.Context, consumer string, e Event, fn func(ctx context.Context) error) (handled bool, err error) {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	q := fmt.Sprintf(`
+		INSERT INTO %s.event_inbox (consumer, event_id, event_type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (consumer, event_id) DO NOTHING`, i.schema)
+	res, err := tx.ExecContext(ctx, q, consumer, e.ID, e.Type)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if err := fn(ctx); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
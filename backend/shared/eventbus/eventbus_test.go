@@ -0,0 +1,157 @@
+package eventbus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// testSchema runs against TEST_DATABASE_URL in a throwaway schema holding the tables of
+// migration_event_outbox.sql; without the variable the test is skipped
+func testSchema(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	migration, err := os.ReadFile("../../../database/migration_event_outbox.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "eventbus_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	ddl := fmt.Sprintf("CREATE SCHEMA %s;\n%s", schema, strings.ReplaceAll(string(migration), "nurse_shift.", schema+"."))
+	if _, err := db.Exec(ddl); err != nil {
+		_ = db.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		_ = db.Close()
+	})
+	return db, schema
+}
+
+func testEvent(t *testing.T, eventType string) Event {
+	t.Helper()
+	e, err := NewEvent(eventType, uuid.NewString(), uuid.NewString(), LeavePayload{LeaveID: "leave-1", Status: "approved"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestNewEvent(t *testing.T) {
+	e, err := NewEvent(LeaveApproved, "leave-1", "", LeavePayload{LeaveID: "leave-1", Status: "approved"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID == uuid.Nil || e.OccurredAt.IsZero() || e.DepartmentID != nil {
+		t.Errorf("NewEvent = %+v, want an ID, a time and no department", e)
+	}
+	var p LeavePayload
+	if err := e.Decode(&p); err != nil || p.LeaveID != "leave-1" || p.Status != "approved" {
+		t.Errorf("Decode = %+v, %v", p, err)
+	}
+}
+
+func TestEnqueueFollowsTheTransaction(t *testing.T) {
+	db, schema := testSchema(t)
+	ctx := context.Background()
+	outbox := NewOutbox(db, schema, "schedule-service")
+
+	rolledBack := testEvent(t, SchedulePublished)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Enqueue(ctx, tx, rolledBack); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	committed := testEvent(t, SchedulePublished)
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Enqueue(ctx, tx, committed); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT id, source FROM %s.event_outbox`, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		var source string
+		if err := rows.Scan(&id, &source); err != nil {
+			t.Fatal(err)
+		}
+		if source != "schedule-service" {
+			t.Errorf("source = %q, want schedule-service", source)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 1 || ids[0] != committed.ID {
+		t.Errorf("outbox holds %v, want only the committed event %s", ids, committed.ID)
+	}
+}
+
+func TestInboxDropsRedeliveries(t *testing.T) {
+	db, schema := testSchema(t)
+	ctx := context.Background()
+	inbox := NewInbox(db, schema)
+	e := testEvent(t, LeaveApproved)
+
+	calls := 0
+	fail := errors.New("notification store down")
+	handle := func(err error) func(context.Context) error {
+		return func(context.Context) error {
+			calls++
+			return err
+		}
+	}
+
+	if handled, err := inbox.Handle(ctx, "notification-service", e, handle(fail)); handled || !errors.Is(err, fail) {
+		t.Fatalf("failing handler: handled=%v err=%v", handled, err)
+	}
+	if handled, err := inbox.Handle(ctx, "notification-service", e, handle(nil)); !handled || err != nil {
+		t.Fatalf("retry after a failure: handled=%v err=%v, want handled", handled, err)
+	}
+	if handled, err := inbox.Handle(ctx, "notification-service", e, handle(nil)); handled || err != nil {
+		t.Fatalf("redelivery: handled=%v err=%v, want skipped", handled, err)
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2 (the failure and the retry)", calls)
+	}
+	if handled, err := inbox.Handle(ctx, "schedule-service", e, handle(nil)); !handled || err != nil {
+		t.Errorf("another consumer: handled=%v err=%v, want handled", handled, err)
+	}
+
+	var n int
+	if err := db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s.event_inbox WHERE event_id = $1`, schema), e.ID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("event_inbox holds %d rows for the event, want one per consumer", n)
+	}
+}
@@ -0,0 +1,140 @@
+// Package holiday expands recurring holidays into dated occurrences and reads holiday sets from
+// iCalendar files
+package holiday
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Recurrence types
+const (
+	YearlyDate    = "yearly_date"    // the same month and day every year
+	YearlyWeekday = "yearly_weekday" // the nth (or last) weekday of a month every year
+)
+
+// ErrInvalidPattern is returned for a recurrence pattern that does not describe a date
+var ErrInvalidPattern = errors.New("invalid recurrence pattern")
+
+// Pattern is a holiday's recurrence, stored in holidays.recurrence_pattern
+type Pattern struct {
+	Type    string `json:"type"`
+	Month   int    `json:"month"`             // 1-12
+	Day     int    `json:"day,omitempty"`     // yearly_date: day of the month
+	Weekday int    `json:"weekday,omitempty"` // yearly_weekday: 0 = Sunday
+	Week    int    `json:"week,omitempty"`    // yearly_weekday: 1-5, or -1 for the last
+}
+
+// Validate checks the pattern's fields for its type
+func (p Pattern) Validate() error {
+	if p.Month < 1 || p.Month > 12 {
+		return ErrInvalidPattern
+	}
+	switch p.Type {
+	case YearlyDate:
+		if p.Day < 1 || p.Day > 31 {
+			return ErrInvalidPattern
+		}
+	case YearlyWeekday:
+		if p.Weekday < 0 || p.Weekday > 6 || p.Week == 0 || p.Week < -1 || p.Week > 5 {
+			return ErrInvalidPattern
+		}
+	default:
+		return ErrInvalidPattern
+	}
+	return nil
+}
+
+// DateIn returns the pattern's date in year; false when the year has no such date (29 February,
+// a fifth Monday)
+func (p Pattern) DateIn(year int) (time.Time, bool) {
+	month := time.Month(p.Month)
+	switch p.Type {
+	case YearlyDate:
+		d := time.Date(year, month, p.Day, 0, 0, 0, 0, time.UTC)
+		return d, d.Month() == month
+	case YearlyWeekday:
+		if p.Week == -1 {
+			last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+			back := (int(last.Weekday()) - p.Weekday + 7) % 7
+			return last.AddDate(0, 0, -back), true
+		}
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		ahead := (p.Weekday - int(first.Weekday()) + 7) % 7
+		d := first.AddDate(0, 0, ahead+7*(p.Week-1))
+		return d, d.Month() == month
+	}
+	return time.Time{}, false
+}
+
+// ParsePattern reads a stored recurrence pattern; nil for an empty or null value
+func ParsePattern(raw []byte) (*Pattern, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var p Pattern
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, ErrInvalidPattern
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Rule is a holiday as stored: its first (or only) occurrence and how it recurs. A recurring rule
+// without a pattern repeats on the month and day of Start every year. Every occurrence lasts as many
+// days as the first.
+type Rule struct {
+	Start     time.Time
+	End       time.Time
+	Recurring bool
+	Pattern   *Pattern
+}
+
+// Occurrence is a dated holiday, both days included
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Occurrences returns the rule's occurrences that share a day with from..to, earliest first.
+// Recurrence starts in the year of Start.
+func (r Rule) Occurrences(from, to time.Time) []Occurrence {
+	start, end := day(r.Start), day(r.End)
+	from, to = day(from), day(to)
+	overlaps := func(s, e time.Time) bool { return !s.After(to) && !e.Before(from) }
+	if !r.Recurring {
+		if overlaps(start, end) {
+			return []Occurrence{{Start: start, End: end}}
+		}
+		return nil
+	}
+
+	p := Pattern{Type: YearlyDate, Month: int(start.Month()), Day: start.Day()}
+	if r.Pattern != nil {
+		p = *r.Pattern
+	}
+	length := int(end.Sub(start).Hours() / 24)
+	var out []Occurrence
+	// an occurrence late in the year before can run into from
+	for year := from.Year() - 1; year <= to.Year(); year++ {
+		if year < start.Year() {
+			continue
+		}
+		s, ok := p.DateIn(year)
+		if !ok {
+			continue
+		}
+		e := s.AddDate(0, 0, length)
+		if overlaps(s, e) {
+			out = append(out, Occurrence{Start: s, End: e})
+		}
+	}
+	return out
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,123 @@
+package holiday
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestOccurrencesYearlyDate(t *testing.T) {
+	// Songkran, 13-15 April, first observed in 2024
+	r := Rule{Start: date(2024, 4, 13), End: date(2024, 4, 15), Recurring: true}
+	got := r.Occurrences(date(2023, 1, 1), date(2026, 4, 14))
+	if len(got) != 3 {
+		t.Fatalf("got %d occurrences, want 3 (2024-2026)", len(got))
+	}
+	if !got[2].Start.Equal(date(2026, 4, 13)) || !got[2].End.Equal(date(2026, 4, 15)) {
+		t.Errorf("2026 occurrence = %v..%v", got[2].Start, got[2].End)
+	}
+}
+
+func TestOccurrencesWeekday(t *testing.T) {
+	first := Rule{Start: date(2025, 1, 1), End: date(2025, 1, 1), Recurring: true,
+		Pattern: &Pattern{Type: YearlyWeekday, Month: 9, Weekday: int(time.Monday), Week: 1}}
+	got := first.Occurrences(date(2026, 9, 1), date(2026, 9, 30))
+	if len(got) != 1 || !got[0].Start.Equal(date(2026, 9, 7)) {
+		t.Fatalf("first Monday of September 2026 = %v, want 2026-09-07", got)
+	}
+
+	last := Rule{Start: date(2025, 1, 1), End: date(2025, 1, 1), Recurring: true,
+		Pattern: &Pattern{Type: YearlyWeekday, Month: 10, Weekday: int(time.Friday), Week: -1}}
+	got = last.Occurrences(date(2026, 10, 1), date(2026, 10, 31))
+	if len(got) != 1 || !got[0].Start.Equal(date(2026, 10, 30)) {
+		t.Fatalf("last Friday of October 2026 = %v, want 2026-10-30", got)
+	}
+}
+
+func TestOccurrencesCrossYear(t *testing.T) {
+	// New Year's Eve to 2 January runs into the January of the next year
+	r := Rule{Start: date(2024, 12, 31), End: date(2025, 1, 2), Recurring: true}
+	got := r.Occurrences(date(2026, 1, 1), date(2026, 1, 31))
+	if len(got) != 1 || !got[0].Start.Equal(date(2025, 12, 31)) {
+		t.Fatalf("got %v, want the occurrence starting 2025-12-31", got)
+	}
+}
+
+func TestOccurrencesSkipMissingDates(t *testing.T) {
+	// 29 February only exists in leap years
+	leap := Rule{Start: date(2024, 2, 29), End: date(2024, 2, 29), Recurring: true}
+	got := leap.Occurrences(date(2024, 1, 1), date(2028, 12, 31))
+	if len(got) != 2 || !got[0].Start.Equal(date(2024, 2, 29)) || !got[1].Start.Equal(date(2028, 2, 29)) {
+		t.Errorf("29 February 2024-2028 = %v, want 2024 and 2028 only", got)
+	}
+
+	// a fifth Monday of June exists in 2026 (29 June) but not in 2027
+	fifth := Rule{Start: date(2026, 1, 1), End: date(2026, 1, 1), Recurring: true,
+		Pattern: &Pattern{Type: YearlyWeekday, Month: 6, Weekday: int(time.Monday), Week: 5}}
+	got = fifth.Occurrences(date(2026, 1, 1), date(2027, 12, 31))
+	if len(got) != 1 || !got[0].Start.Equal(date(2026, 6, 29)) {
+		t.Errorf("fifth Monday of June 2026-2027 = %v, want 2026-06-29 only", got)
+	}
+}
+
+func TestOccurrencesBoundaries(t *testing.T) {
+	// a recurring rule does not go back before its first year
+	r := Rule{Start: date(2026, 5, 4), End: date(2026, 5, 4), Recurring: true}
+	if got := r.Occurrences(date(2020, 1, 1), date(2025, 12, 31)); len(got) != 0 {
+		t.Errorf("before the first year = %v, want none", got)
+	}
+
+	// a weekday pattern keeps the length of the first occurrence
+	long := Rule{Start: date(2026, 1, 1), End: date(2026, 1, 3), Recurring: true,
+		Pattern: &Pattern{Type: YearlyWeekday, Month: 11, Weekday: int(time.Friday), Week: -1}}
+	got := long.Occurrences(date(2027, 11, 1), date(2027, 11, 30))
+	if len(got) != 1 || !got[0].Start.Equal(date(2027, 11, 26)) || !got[0].End.Equal(date(2027, 11, 28)) {
+		t.Errorf("last Friday of November 2027 = %v, want 26-28 November", got)
+	}
+
+	// a one-off holiday is returned when it touches the range at either end
+	once := Rule{Start: date(2026, 7, 28), End: date(2026, 8, 2)}
+	for _, rng := range [][2]time.Time{
+		{date(2026, 8, 1), date(2026, 8, 31)},
+		{date(2026, 7, 1), date(2026, 7, 28)},
+	} {
+		if got := once.Occurrences(rng[0], rng[1]); len(got) != 1 {
+			t.Errorf("Occurrences(%v, %v) = %v, want the holiday", rng[0], rng[1], got)
+		}
+	}
+	if got := once.Occurrences(date(2026, 8, 3), date(2026, 8, 31)); len(got) != 0 {
+		t.Errorf("after the holiday = %v, want none", got)
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    *Pattern
+		wantErr bool
+	}{
+		{raw: ``},
+		{raw: `null`},
+		{raw: `{"type":"yearly_date","month":4,"day":13}`, want: &Pattern{Type: YearlyDate, Month: 4, Day: 13}},
+		{raw: `{"type":"yearly_weekday","month":9,"weekday":1,"week":-1}`, want: &Pattern{Type: YearlyWeekday, Month: 9, Weekday: 1, Week: -1}},
+		{raw: `{"type":"yearly_date","month":13,"day":1}`, wantErr: true},
+		{raw: `{"type":"yearly_date","month":4}`, wantErr: true},
+		{raw: `{"type":"yearly_weekday","month":9,"weekday":7,"week":1}`, wantErr: true},
+		{raw: `{"type":"yearly_weekday","month":9,"weekday":1,"week":-2}`, wantErr: true},
+		{raw: `{"type":"monthly","month":1,"day":1}`, wantErr: true},
+		{raw: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePattern([]byte(tt.raw))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePattern(%s) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("ParsePattern(%s) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
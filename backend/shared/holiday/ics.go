@@ -0,0 +1,233 @@
+package holiday
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotCalendar is returned for input that has no VCALENDAR
+var ErrNotCalendar = errors.New("not an iCalendar file")
+
+// Bangkok has no daylight saving time, so a fixed +07:00 zone is exact
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+// Event is a holiday read from an iCalendar VEVENT
+type Event struct {
+	UID  string
+	Name string
+	Rule
+}
+
+// Import is what ParseICS read: the holidays, and a note for each event it could not take as is
+type Import struct {
+	Events   []Event
+	Warnings []string
+}
+
+var weekdays = map[string]int{"SU": 0, "MO": 1, "TU": 2, "WE": 3, "TH": 4, "FR": 5, "SA": 6}
+
+// ParseICS reads the all-day and timed events of an iCalendar file (RFC 5545) as holidays. Timed
+// events count the Bangkok days they touch. FREQ=YEARLY rules become recurring holidays, by date or,
+// with BYDAY=1MO style ordinals, by weekday; events with other rules keep their first occurrence
+// and get a warning. Events without a UID are given one from their date and summary.
+func ParseICS(r io.Reader) (*Import, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Import{}
+	seenCalendar := false
+	var props map[string]prop
+	for _, line := range lines {
+		name, p := parseLine(line)
+		switch {
+		case name == "BEGIN" && p.value == "VCALENDAR":
+			seenCalendar = true
+		case name == "BEGIN" && p.value == "VEVENT":
+			props = map[string]prop{}
+		case name == "END" && p.value == "VEVENT" && props != nil:
+			e, warn, err := toEvent(props)
+			if err != nil {
+				out.Warnings = append(out.Warnings, err.Error())
+			} else {
+				out.Events = append(out.Events, *e)
+				if warn != "" {
+					out.Warnings = append(out.Warnings, warn)
+				}
+			}
+			props = nil
+		case props != nil:
+			if _, dup := props[name]; !dup {
+				props[name] = p
+			}
+		}
+	}
+	if !seenCalendar {
+		return nil, ErrNotCalendar
+	}
+	return out, nil
+}
+
+type prop struct {
+	params map[string]string
+	value  string
+}
+
+// unfold joins continuation lines (RFC 5545 3.1)
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+// parseLine splits NAME;PARAM=VALUE:value
+func parseLine(line string) (string, prop) {
+	p := prop{params: map[string]string{}}
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), p
+	}
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	for _, kv := range parts[1:] {
+		if eq := strings.Index(kv, "="); eq > 0 {
+			p.params[strings.ToUpper(kv[:eq])] = strings.Trim(kv[eq+1:], `"`)
+		}
+	}
+	p.value = value
+	return strings.ToUpper(parts[0]), p
+}
+
+func unescapeText(s string) string {
+	r := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(r.Replace(s))
+}
+
+// parseDate reads a DATE or DATE-TIME value; timed reports a DATE-TIME
+func parseDate(p prop) (t time.Time, timed bool, err error) {
+	v := p.value
+	if len(v) == 8 {
+		t, err = time.ParseInLocation("20060102", v, time.UTC)
+		return t, false, err
+	}
+	loc := bangkok
+	if strings.HasSuffix(v, "Z") {
+		loc = time.UTC
+		v = strings.TrimSuffix(v, "Z")
+	} else if tz := p.params["TZID"]; tz != "" {
+		if l, lerr := time.LoadLocation(tz); lerr == nil {
+			loc = l
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", v, loc)
+	if err != nil {
+		return t, true, err
+	}
+	t = t.In(bangkok)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC), true, nil
+}
+
+func toEvent(props map[string]prop) (*Event, string, error) {
+	summary := unescapeText(props["SUMMARY"].value)
+	dtstart, ok := props["DTSTART"]
+	if !ok {
+		return nil, "", fmt.Errorf("event %q has no DTSTART", summary)
+	}
+	start, _, err := parseDate(dtstart)
+	if err != nil {
+		return nil, "", fmt.Errorf("event %q: invalid DTSTART %q", summary, dtstart.value)
+	}
+	end := start
+	if dtend, ok := props["DTEND"]; ok {
+		e, endTimed, err := parseDate(dtend)
+		if err != nil {
+			return nil, "", fmt.Errorf("event %q: invalid DTEND %q", summary, dtend.value)
+		}
+		// an all-day DTEND is the day after; a timed one ending at midnight does not touch that day
+		if !endTimed || (e.Hour() == 0 && e.Minute() == 0 && e.Second() == 0 && e.After(start)) {
+			e = e.AddDate(0, 0, -1)
+		}
+		if !e.Before(start) {
+			end = e
+		}
+	}
+
+	e := &Event{
+		UID:  strings.TrimSpace(props["UID"].value),
+		Name: summary,
+		Rule: Rule{Start: day(start), End: day(end)},
+	}
+	if e.Name == "" {
+		e.Name = "วันหยุด"
+	}
+	if e.UID == "" {
+		e.UID = start.Format("20060102") + "-" + e.Name
+	}
+
+	rrule, ok := props["RRULE"]
+	if !ok {
+		return e, "", nil
+	}
+	parts := map[string]string{}
+	for _, kv := range strings.Split(rrule.value, ";") {
+		if eq := strings.Index(kv, "="); eq > 0 {
+			parts[strings.ToUpper(kv[:eq])] = strings.ToUpper(kv[eq+1:])
+		}
+	}
+	if parts["FREQ"] != "YEARLY" || parts["INTERVAL"] != "" && parts["INTERVAL"] != "1" {
+		return e, fmt.Sprintf("event %q: only yearly recurrence is supported, imported its first occurrence", summary), nil
+	}
+	e.Recurring = true
+	if byday := parts["BYDAY"]; byday != "" {
+		p, ok := weekdayPattern(byday, parts["BYMONTH"], int(e.Start.Month()))
+		if !ok {
+			e.Recurring = false
+			return e, fmt.Sprintf("event %q: unsupported BYDAY %q, imported its first occurrence", summary, byday), nil
+		}
+		e.Pattern = p
+	}
+	return e, "", nil
+}
+
+// weekdayPattern reads BYDAY=1MO (first Monday) or -1FR (last Friday) of BYMONTH
+func weekdayPattern(byday, bymonth string, fallbackMonth int) (*Pattern, bool) {
+	if len(byday) < 3 || strings.Contains(byday, ",") {
+		return nil, false
+	}
+	wd, ok := weekdays[byday[len(byday)-2:]]
+	if !ok {
+		return nil, false
+	}
+	week, err := strconv.Atoi(byday[:len(byday)-2])
+	if err != nil {
+		return nil, false
+	}
+	month := fallbackMonth
+	if bymonth != "" {
+		if month, err = strconv.Atoi(bymonth); err != nil {
+			return nil, false
+		}
+	}
+	p := &Pattern{Type: YearlyWeekday, Month: month, Weekday: wd, Week: week}
+	if p.Validate() != nil {
+		return nil, false
+	}
+	return p, true
+}
@@ -0,0 +1,220 @@
+package holiday
+
+import (
+	"strings"
+	"testing"
+	_ "time/tzdata" // TZID test without a system zone database
+)
+
+// calendar wraps VEVENT lines in a VCALENDAR joined with CRLF
+func calendar(lines ...string) string {
+	return strings.Join(append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...), "END:VCALENDAR"), "\r\n")
+}
+
+func parseOne(t *testing.T, ics string) (Event, []string) {
+	t.Helper()
+	got, err := ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 1 {
+		t.Fatalf("got %d events (warnings %v), want 1", len(got.Events), got.Warnings)
+	}
+	return got.Events[0], got.Warnings
+}
+
+func TestParseICS(t *testing.T) {
+	ics := calendar(
+		"BEGIN:VEVENT",
+		"UID:songkran@example",
+		"DTSTART;VALUE=DATE:20260413",
+		"DTEND;VALUE=DATE:20260416",
+		"RRULE:FREQ=YEARLY",
+		"SUMMARY:วันสงกรานต์",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20260505",
+		"SUMMARY:วันฉัตร",
+		" มงคล",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:weekly",
+		"DTSTART;VALUE=DATE:20260101",
+		"RRULE:FREQ=WEEKLY",
+		"SUMMARY:Weekly",
+		"END:VEVENT",
+	)
+	got, err := ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 3 || len(got.Warnings) != 1 {
+		t.Fatalf("got %d events, %d warnings; want 3, 1", len(got.Events), len(got.Warnings))
+	}
+	songkran := got.Events[0]
+	if !songkran.Recurring || !songkran.End.Equal(date(2026, 4, 15)) {
+		t.Errorf("songkran = %+v, want recurring through 15 April", songkran)
+	}
+	coronation := got.Events[1]
+	if coronation.Name != "วันฉัตรมงคล" || coronation.UID == "" || coronation.Recurring {
+		t.Errorf("coronation = %+v", coronation)
+	}
+	if got.Events[2].Recurring {
+		t.Errorf("weekly event should keep only its first occurrence")
+	}
+
+	if _, err := ParseICS(strings.NewReader("hello")); err != ErrNotCalendar {
+		t.Errorf("err = %v, want ErrNotCalendar", err)
+	}
+}
+
+func TestParseICSRRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		rrule     string
+		recurring bool
+		pattern   *Pattern
+		warn      bool
+	}{
+		{name: "yearly by date", rrule: "FREQ=YEARLY", recurring: true},
+		{name: "yearly with interval 1", rrule: "FREQ=YEARLY;INTERVAL=1", recurring: true},
+		{name: "lower case", rrule: "freq=yearly;byday=2mo;bymonth=8", recurring: true,
+			pattern: &Pattern{Type: YearlyWeekday, Month: 8, Weekday: 1, Week: 2}},
+		{name: "first Monday of September", rrule: "FREQ=YEARLY;BYMONTH=9;BYDAY=1MO", recurring: true,
+			pattern: &Pattern{Type: YearlyWeekday, Month: 9, Weekday: 1, Week: 1}},
+		{name: "last Friday without BYMONTH takes DTSTART's month", rrule: "FREQ=YEARLY;BYDAY=-1FR", recurring: true,
+			pattern: &Pattern{Type: YearlyWeekday, Month: 3, Weekday: 5, Week: -1}},
+		{name: "every other year", rrule: "FREQ=YEARLY;INTERVAL=2", warn: true},
+		{name: "monthly", rrule: "FREQ=MONTHLY;BYMONTHDAY=1", warn: true},
+		{name: "several weekdays", rrule: "FREQ=YEARLY;BYDAY=MO,TU", warn: true},
+		{name: "weekday without ordinal", rrule: "FREQ=YEARLY;BYDAY=MO", warn: true},
+		{name: "sixth Monday", rrule: "FREQ=YEARLY;BYMONTH=9;BYDAY=6MO", warn: true},
+		{name: "unknown weekday", rrule: "FREQ=YEARLY;BYDAY=1XX", warn: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, warnings := parseOne(t, calendar(
+				"BEGIN:VEVENT",
+				"UID:rule",
+				"DTSTART;VALUE=DATE:20260327",
+				"RRULE:"+tt.rrule,
+				"SUMMARY:Holiday",
+				"END:VEVENT",
+			))
+			if e.Recurring != tt.recurring {
+				t.Errorf("recurring = %v, want %v", e.Recurring, tt.recurring)
+			}
+			if (e.Pattern == nil) != (tt.pattern == nil) || e.Pattern != nil && *e.Pattern != *tt.pattern {
+				t.Errorf("pattern = %+v, want %+v", e.Pattern, tt.pattern)
+			}
+			if (len(warnings) > 0) != tt.warn {
+				t.Errorf("warnings = %v, want a warning: %v", warnings, tt.warn)
+			}
+			if !e.Start.Equal(date(2026, 3, 27)) || !e.End.Equal(date(2026, 3, 27)) {
+				t.Errorf("first occurrence = %v..%v, want 27 March", e.Start, e.End)
+			}
+		})
+	}
+}
+
+func TestParseICSDates(t *testing.T) {
+	tests := []struct {
+		name       string
+		props      []string
+		start, end string
+	}{
+		{name: "all-day without DTEND", props: []string{"DTSTART;VALUE=DATE:20261023"},
+			start: "2026-10-23", end: "2026-10-23"},
+		{name: "all-day DTSTART without VALUE", props: []string{"DTSTART:20261023"},
+			start: "2026-10-23", end: "2026-10-23"},
+		{name: "all-day DTEND is exclusive", props: []string{"DTSTART;VALUE=DATE:20261023", "DTEND;VALUE=DATE:20261024"},
+			start: "2026-10-23", end: "2026-10-23"},
+		{name: "all-day over a month end", props: []string{"DTSTART;VALUE=DATE:20261231", "DTEND;VALUE=DATE:20270103"},
+			start: "2026-12-31", end: "2027-01-02"},
+		{name: "all-day DTEND equal to DTSTART", props: []string{"DTSTART;VALUE=DATE:20261023", "DTEND;VALUE=DATE:20261023"},
+			start: "2026-10-23", end: "2026-10-23"},
+		{name: "UTC evening is the next Bangkok day", props: []string{"DTSTART:20261022T180000Z", "DTEND:20261022T200000Z"},
+			start: "2026-10-23", end: "2026-10-23"},
+		{name: "floating time is Bangkok", props: []string{"DTSTART:20261023T090000", "DTEND:20261023T170000"},
+			start: "2026-10-23", end: "2026-10-23"},
+		{name: "TZID", props: []string{"DTSTART;TZID=Asia/Tokyo:20261023T010000", "DTEND;TZID=Asia/Tokyo:20261023T030000"},
+			start: "2026-10-22", end: "2026-10-23"},
+		{name: "timed end at midnight does not touch that day", props: []string{"DTSTART:20261023T000000", "DTEND:20261025T000000"},
+			start: "2026-10-23", end: "2026-10-24"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append([]string{"BEGIN:VEVENT", "UID:d", "SUMMARY:Holiday"}, tt.props...)
+			e, _ := parseOne(t, calendar(append(lines, "END:VEVENT")...))
+			if got := e.Start.Format("2006-01-02"); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := e.End.Format("2006-01-02"); got != tt.end {
+				t.Errorf("end = %s, want %s", got, tt.end)
+			}
+		})
+	}
+}
+
+func TestParseICSFoldedLines(t *testing.T) {
+	// folds may split names, parameters and values, and use a space or a tab
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEV",
+		" ENT",
+		"UID:folded",
+		" @example",
+		"DTST",
+		" ART;VALU",
+		"\tE=DATE:2026",
+		" 0728",
+		"RRULE:FREQ=YEARLY;",
+		" BYMONTH=7",
+		"SUMMARY:วันเฉลิม",
+		" พระชนมพรรษา\\, ",
+		" รัชกาลที่ 10",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\n")
+	e, warnings := parseOne(t, ics)
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v", warnings)
+	}
+	if e.Name != "วันเฉลิมพระชนมพรรษา, รัชกาลที่ 10" {
+		t.Errorf("name = %q", e.Name)
+	}
+	if !e.Start.Equal(date(2026, 7, 28)) || !e.Recurring {
+		t.Errorf("event = %+v, want recurring from 28 July 2026", e)
+	}
+	if e.UID != "folded@example" {
+		t.Errorf("uid = %q, want folded@example", e.UID)
+	}
+}
+
+func TestParseICSSkipsBrokenEvents(t *testing.T) {
+	ics := calendar(
+		"BEGIN:VEVENT",
+		"SUMMARY:No start",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:2026-10-23",
+		"SUMMARY:Bad start",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20261023",
+		"DTSTART;VALUE=DATE:20261130",
+		"SUMMARY:First DTSTART wins",
+		"END:VEVENT",
+	)
+	got, err := ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 1 || len(got.Warnings) != 2 {
+		t.Fatalf("got %d events and warnings %v, want 1 event and 2 warnings", len(got.Events), got.Warnings)
+	}
+	e := got.Events[0]
+	if !e.Start.Equal(date(2026, 10, 23)) || e.UID != "20261023-First DTSTART wins" {
+		t.Errorf("event = %+v", e)
+	}
+}
@@ -0,0 +1,45 @@
+-- Migration Script: Audit Trail
+-- Version: 1.10.0
+-- Date: 2026-10-16
+-- Description: The schedule, leave, department, setting, priority and payment services record
+--              every successful write in audit_logs, with before/after snapshots where the
+--              handler takes them. Adds the columns of schema.sql to databases whose audit_logs
+--              came from schema_current.sql, the department the change belongs to (used to let
+--              department heads read their department's trail) and the indexes of the query API.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.audit_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID REFERENCES nurse_shift.users(id) ON DELETE SET NULL,
+    action VARCHAR(100) NOT NULL,
+    resource_type VARCHAR(100),
+    resource_id UUID,
+    old_data JSONB,
+    new_data JSONB,
+    ip_address INET,
+    user_agent TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE nurse_shift.audit_logs
+    ADD COLUMN IF NOT EXISTS resource_type VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS resource_id UUID,
+    ADD COLUMN IF NOT EXISTS old_data JSONB,
+    ADD COLUMN IF NOT EXISTS new_data JSONB,
+    ADD COLUMN IF NOT EXISTS department_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_resource
+    ON nurse_shift.audit_logs (resource_type, resource_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_department_created
+    ON nurse_shift.audit_logs (department_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_created
+    ON nurse_shift.audit_logs (user_id, created_at DESC);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP INDEX IF EXISTS nurse_shift.idx_audit_logs_user_created;
+-- DROP INDEX IF EXISTS nurse_shift.idx_audit_logs_department_created;
+-- DROP INDEX IF EXISTS nurse_shift.idx_audit_logs_resource;
+-- ALTER TABLE nurse_shift.audit_logs DROP COLUMN IF EXISTS department_id;
@@ -0,0 +1,28 @@
+-- Migration Script: Calendar Feed Tokens
+-- Version: 1.6.0
+-- Date: 2026-10-16
+-- Description: Revocable tokens behind the public iCalendar feeds
+--              (GET /api/v1/calendar/:token.ics). A token covers one staff member or a
+--              whole department; only the sha256 of the secret is stored.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_calendar_tokens (
+    id UUID PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- hex sha256 of the feed secret
+    scope VARCHAR(20) NOT NULL, -- staff | department
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    staff_id UUID, -- set when scope = staff
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_calendar_tokens_dept
+    ON nurse_shift.schedule_calendar_tokens (department_id, created_at DESC);
+
+COMMENT ON TABLE nurse_shift.schedule_calendar_tokens IS 'โทเคนสำหรับสมัครรับปฏิทินตารางเวร (iCalendar)';
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.schedule_calendar_tokens;
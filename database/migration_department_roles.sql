@@ -0,0 +1,42 @@
+-- Migration Script: Department-Scoped Roles
+-- Version: 1.11.0
+-- Date: 2026-10-16
+-- Description: Every service authorizes department data by the caller's role in the department.
+--              Extends department_role with head_nurse (manages the department: rosters, leave
+--              approvals, settings, priorities and members) and viewer (read-only), and adds the
+--              department_role column to databases created from schema.sql. The user set as a
+--              department's head or its creator counts as head_nurse without a row here.
+
+DO $$ BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+        WHERE t.typname = 'department_role' AND n.nspname = 'nurse_shift'
+    ) THEN
+        CREATE TYPE nurse_shift.department_role AS ENUM ('nurse', 'assistant');
+    END IF;
+END $$;
+
+ALTER TYPE nurse_shift.department_role ADD VALUE IF NOT EXISTS 'head_nurse' BEFORE 'nurse';
+ALTER TYPE nurse_shift.department_role ADD VALUE IF NOT EXISTS 'viewer' AFTER 'assistant';
+
+ALTER TABLE nurse_shift.department_users
+    ADD COLUMN IF NOT EXISTS department_role nurse_shift.department_role NOT NULL DEFAULT 'nurse';
+
+CREATE INDEX IF NOT EXISTS idx_department_users_department_role
+    ON nurse_shift.department_users (department_role);
+
+COMMENT ON COLUMN nurse_shift.department_users.department_role IS
+    'Role ของผู้ใช้ในแผนก: head_nurse (หัวหน้าพยาบาล), nurse (พยาบาล), assistant (ผู้ช่วยพยาบาล) หรือ viewer (ดูได้อย่างเดียว)';
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- PostgreSQL cannot drop values from an enum; move the rows back and recreate the type instead:
+-- UPDATE nurse_shift.department_users SET department_role = 'nurse' WHERE department_role IN ('head_nurse', 'viewer');
+-- ALTER TABLE nurse_shift.department_users ALTER COLUMN department_role DROP DEFAULT;
+-- ALTER TYPE nurse_shift.department_role RENAME TO department_role_old;
+-- CREATE TYPE nurse_shift.department_role AS ENUM ('nurse', 'assistant');
+-- ALTER TABLE nurse_shift.department_users ALTER COLUMN department_role TYPE nurse_shift.department_role
+--     USING department_role::text::nurse_shift.department_role;
+-- ALTER TABLE nurse_shift.department_users ALTER COLUMN department_role SET DEFAULT 'nurse';
+-- DROP TYPE nurse_shift.department_role_old;
@@ -0,0 +1,55 @@
+-- Migration Script: Domain Event Outbox
+-- Version: 1.13.0
+-- Date: 2026-10-16
+-- Description: Services announce domain events (leave.approved, leave.rejected,
+--              schedule.published, payment.approved) by writing them to event_outbox in the
+--              transaction that makes the change. Each service's dispatcher claims its own rows
+--              (source) and delivers them at least once to its subscribers, retrying with backoff;
+--              event_deliveries records which subscriber already received an event, and
+--              event_inbox lets consumers drop redeliveries.
+--              cron-service purges delivered events daily.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.event_outbox (
+    id UUID PRIMARY KEY,
+    source VARCHAR(50) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    department_id UUID,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    dispatched_at TIMESTAMP WITH TIME ZONE,
+    dead_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending
+    ON nurse_shift.event_outbox (source, next_attempt_at)
+    WHERE dispatched_at IS NULL AND dead_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_dispatched_at
+    ON nurse_shift.event_outbox (dispatched_at);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.event_deliveries (
+    event_id UUID NOT NULL REFERENCES nurse_shift.event_outbox(id) ON DELETE CASCADE,
+    subscriber VARCHAR(100) NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, subscriber)
+);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.event_inbox (
+    consumer VARCHAR(100) NOT NULL,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (consumer, event_id)
+);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.event_inbox;
+-- DROP TABLE IF EXISTS nurse_shift.event_deliveries;
+-- DROP TABLE IF EXISTS nurse_shift.event_outbox;
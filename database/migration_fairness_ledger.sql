@@ -0,0 +1,27 @@
+-- Migration Script: Staff Fairness Ledger
+-- Version: 1.19.0
+-- Date: 2026-10-16
+-- Description: What each staff member worked per month (shifts by shift type, weekend and public
+--              holiday days, hours), so the optimizer can balance fairness over a rolling window of
+--              months instead of resetting every month. Rows are rebuilt from the roster when a
+--              version is published or on POST /schedules/fairness/refresh, and missing months
+--              are backfilled on read.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.staff_fairness_ledger (
+    staff_id UUID NOT NULL REFERENCES nurse_shift.department_staff(id) ON DELETE CASCADE,
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    month CHAR(7) NOT NULL,
+    shift_counts JSONB NOT NULL DEFAULT '{}',
+    weekend_days INTEGER NOT NULL DEFAULT 0,
+    holiday_days INTEGER NOT NULL DEFAULT 0,
+    hours NUMERIC(7,2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (staff_id, month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_staff_fairness_ledger_dept_month ON nurse_shift.staff_fairness_ledger (department_id, month);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.staff_fairness_ledger;
@@ -0,0 +1,57 @@
+-- Migration Script: Holiday Calendars
+-- Version: 1.15.0
+-- Date: 2026-10-16
+-- Description: Shared holiday calendars: national ones kept by system admins and organisation ones
+--              kept by the account owning the departments, filled from iCalendar (.ics) imports.
+--              Departments subscribe to calendars (department_holiday_calendars) and inherit their
+--              holidays, opting out of single ones (department_holiday_opt_outs). Recurring
+--              holidays repeat yearly on their date or by holidays.recurrence_pattern, e.g. the
+--              first Monday of a month; setting-service and schedule-service expand them.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.holiday_calendars (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('national', 'organization')),
+    owner_id UUID REFERENCES nurse_shift.users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((scope = 'national') = (owner_id IS NULL))
+);
+
+-- Holidays of a calendar; uid is the iCalendar UID so a re-import updates rather than duplicates
+CREATE TABLE IF NOT EXISTS nurse_shift.calendar_holidays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    calendar_id UUID NOT NULL REFERENCES nurse_shift.holiday_calendars(id) ON DELETE CASCADE,
+    uid VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    is_recurring BOOLEAN NOT NULL DEFAULT false,
+    recurrence_pattern JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (end_date >= start_date),
+    UNIQUE (calendar_id, uid)
+);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.department_holiday_calendars (
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    calendar_id UUID NOT NULL REFERENCES nurse_shift.holiday_calendars(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (department_id, calendar_id)
+);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.department_holiday_opt_outs (
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    calendar_holiday_id UUID NOT NULL REFERENCES nurse_shift.calendar_holidays(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (department_id, calendar_holiday_id)
+);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.department_holiday_opt_outs;
+-- DROP TABLE IF EXISTS nurse_shift.department_holiday_calendars;
+-- DROP TABLE IF EXISTS nurse_shift.calendar_holidays;
+-- DROP TABLE IF EXISTS nurse_shift.holiday_calendars;
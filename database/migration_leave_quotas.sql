@@ -0,0 +1,30 @@
+-- Migration Script: Leave Quotas
+-- Version: 1.14.0
+-- Date: 2026-10-16
+-- Description: Departments set a yearly quota per leave type (leave_quotas), granted upfront on
+--              1 January or monthly, with up to carry_over_max_days of last year's unused quota
+--              carried over. employee-leave-service refuses leave that overlaps the staff member's
+--              pending or approved leave or exceeds the balance, and reports balances per year.
+--              Leave types without a quota stay unlimited.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.leave_quotas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    leave_type nurse_shift.leave_type NOT NULL,
+    annual_days NUMERIC(5,2) NOT NULL CHECK (annual_days >= 0),
+    accrual VARCHAR(20) NOT NULL DEFAULT 'upfront' CHECK (accrual IN ('upfront', 'monthly')),
+    carry_over_max_days NUMERIC(5,2) NOT NULL DEFAULT 0 CHECK (carry_over_max_days >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (department_id, leave_type)
+);
+
+-- Overlap and usage lookups of a staff member's leave
+CREATE INDEX IF NOT EXISTS idx_leave_requests_staff_dates
+    ON nurse_shift.leave_requests (staff_id, start_date, end_date);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP INDEX IF EXISTS nurse_shift.idx_leave_requests_staff_dates;
+-- DROP TABLE IF EXISTS nurse_shift.leave_quotas;
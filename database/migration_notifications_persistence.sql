@@ -0,0 +1,23 @@
+-- Migration Script: Notification Persistence
+-- Version: 1.7.0
+-- Date: 2026-10-16
+-- Description: notification-service now stores notifications in nurse_shift.notifications
+--              instead of memory. Adds the columns it reads (read_at, data) to databases
+--              built from schema_current.sql and an index for the per-user listing and the
+--              retention purge.
+
+ALTER TABLE nurse_shift.notifications
+    ADD COLUMN IF NOT EXISTS read_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS data JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_read_created
+    ON nurse_shift.notifications (user_id, is_read, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at
+    ON nurse_shift.notifications (created_at);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP INDEX IF EXISTS nurse_shift.idx_notifications_created_at;
+-- DROP INDEX IF EXISTS nurse_shift.idx_notifications_user_read_created;
@@ -0,0 +1,31 @@
+-- Migration Script: Payment Ledger
+-- Version: 1.8.0
+-- Date: 2026-10-16
+-- Description: payment-service now keeps payments and packages in the database. Adds the
+--              review columns (approved_by, approved_at, rejection_reason, extended_days) and
+--              the slip reference (evidence_url) to databases built from schema_current.sql,
+--              and the package type used to set users.package_type on approval.
+
+ALTER TABLE nurse_shift.packages
+    ADD COLUMN IF NOT EXISTS type nurse_shift.package_type,
+    ADD COLUMN IF NOT EXISTS is_popular BOOLEAN DEFAULT false;
+
+ALTER TABLE nurse_shift.payments
+    ADD COLUMN IF NOT EXISTS payment_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    ADD COLUMN IF NOT EXISTS evidence_url VARCHAR(500), -- storage key of the transfer slip
+    ADD COLUMN IF NOT EXISTS approved_by UUID REFERENCES nurse_shift.users(id),
+    ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS rejection_reason TEXT,
+    ADD COLUMN IF NOT EXISTS extended_days INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_payments_user_created
+    ON nurse_shift.payments (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_payments_pending
+    ON nurse_shift.payments (created_at) WHERE status = 'pending';
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP INDEX IF EXISTS nurse_shift.idx_payments_pending;
+-- DROP INDEX IF EXISTS nurse_shift.idx_payments_user_created;
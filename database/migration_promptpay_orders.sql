@@ -0,0 +1,32 @@
+-- Migration Script: PromptPay Orders and Slip Verification
+-- Version: 1.9.0
+-- Date: 2026-10-16
+-- Description: Orders are paid by PromptPay QR. A payment keeps the order reference carried in
+--              the QR and what was read from the uploaded slip's verification QR (transaction
+--              reference, sending bank) with the verification outcome. A slip transaction can
+--              settle only one payment.
+
+ALTER TABLE nurse_shift.payments
+    ADD COLUMN IF NOT EXISTS reference VARCHAR(25),
+    ADD COLUMN IF NOT EXISTS verification_status VARCHAR(20), -- awaiting_slip, verified, manual_review
+    ADD COLUMN IF NOT EXISTS slip_trans_ref VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS slip_sending_bank VARCHAR(3),
+    ADD COLUMN IF NOT EXISTS slip_note TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_reference
+    ON nurse_shift.payments (reference) WHERE reference IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_slip_trans_ref
+    ON nurse_shift.payments (slip_trans_ref) WHERE slip_trans_ref IS NOT NULL;
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP INDEX IF EXISTS nurse_shift.idx_payments_slip_trans_ref;
+-- DROP INDEX IF EXISTS nurse_shift.idx_payments_reference;
+-- ALTER TABLE nurse_shift.payments
+--     DROP COLUMN IF EXISTS slip_note,
+--     DROP COLUMN IF EXISTS slip_sending_bank,
+--     DROP COLUMN IF EXISTS slip_trans_ref,
+--     DROP COLUMN IF EXISTS verification_status,
+--     DROP COLUMN IF EXISTS reference;
@@ -0,0 +1,29 @@
+-- Migration Script: Live Roster Events
+-- Version: 1.12.0
+-- Date: 2026-10-16
+-- Description: schedule-service publishes roster changes (assignment added, removed or moved,
+--              month regenerated, version published) to per-department Server-Sent Events
+--              streams at GET /api/v1/schedules/events. Every event is kept here for 7 days so
+--              that a reconnecting client resumes from its Last-Event-ID. The service
+--              prunes old rows hourly.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_events (
+    id BIGSERIAL PRIMARY KEY,
+    department_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    month VARCHAR(7),
+    payload JSONB NOT NULL,
+    actor_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_events_department_id
+    ON nurse_shift.schedule_events (department_id, id);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_events_created_at
+    ON nurse_shift.schedule_events (created_at);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.schedule_events;
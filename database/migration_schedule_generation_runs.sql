@@ -0,0 +1,28 @@
+-- Migration Script: Schedule Generation Runs
+-- Version: 1.2.0
+-- Date: 2026-10-16
+-- Description: Persist the generation report (unmet slots + blocked candidates) of every
+--              auto-generate / optimize-generate run.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_generation_runs (
+    id UUID PRIMARY KEY,
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    month VARCHAR(7) NOT NULL, -- YYYY-MM
+    generator VARCHAR(50) NOT NULL, -- enhanced | optimizer:greedy | optimizer:local-search
+    required_slots INTEGER NOT NULL DEFAULT 0,
+    filled_slots INTEGER NOT NULL DEFAULT 0,
+    unmet_total INTEGER NOT NULL DEFAULT 0,
+    report JSONB NOT NULL,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_generation_runs_dept_month
+    ON nurse_shift.schedule_generation_runs (department_id, month, created_at DESC);
+
+COMMENT ON TABLE nurse_shift.schedule_generation_runs IS 'รายงานผลการสร้างตารางเวรแต่ละครั้ง (ช่องที่ยังขาดคน และเหตุผลที่จัดไม่ได้)';
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.schedule_generation_runs;
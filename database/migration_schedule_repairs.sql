@@ -0,0 +1,42 @@
+-- Migration Script: Schedule Repairs
+-- Version: 1.5.0
+-- Date: 2026-10-16
+-- Description: Roster repair proposals created when a leave is approved for a month that
+--              already has a roster. employee-leave-service notifies schedule-service
+--              (POST /api/v1/internal/leave-approved), which stores a minimal-change
+--              change set for the head nurse to apply or discard. A proposal only applies
+--              while the month's roster is unchanged (roster_version).
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_repairs (
+    id UUID PRIMARY KEY,
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    month VARCHAR(7) NOT NULL, -- YYYY-MM
+    leave_id UUID,
+    staff_id UUID NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending | applied | discarded
+    changes JSONB NOT NULL, -- [{action: remove|add, staffId, shiftId, date, reason}]
+    unfilled JSONB NOT NULL,
+    moved INTEGER NOT NULL DEFAULT 0,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    decided_by UUID,
+    decided_at TIMESTAMP WITH TIME ZONE
+);
+
+-- md5 of the month's schedules the repair was computed from; applying is refused once it changes
+ALTER TABLE nurse_shift.schedule_repairs ADD COLUMN IF NOT EXISTS roster_version TEXT;
+
+-- required skills the repair leaves short and refills on lapsed certifications: [{staffId, shiftId, date, reason}]
+ALTER TABLE nurse_shift.schedule_repairs ADD COLUMN IF NOT EXISTS violations JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_schedule_repairs_dept_status
+    ON nurse_shift.schedule_repairs (department_id, status, created_at DESC);
+
+COMMENT ON TABLE nurse_shift.schedule_repairs IS 'ข้อเสนอปรับตารางเวรเมื่อมีการอนุมัติวันลา';
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.schedule_repairs;
@@ -0,0 +1,50 @@
+-- Migration Script: Schedule Swaps
+-- Version: 1.4.0
+-- Date: 2026-10-16
+-- Description: Shift swap marketplace. A staff member offers an assignment, a colleague with
+--              the same role takes it or offers one of their own shifts in return, and the head
+--              nurse approves. Approval rewrites the schedules rows in one transaction and every
+--              step is kept in schedule_swap_events.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_swaps (
+    id UUID PRIMARY KEY,
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    from_staff_id UUID NOT NULL REFERENCES nurse_shift.department_staff(id) ON DELETE CASCADE,
+    shift_id UUID NOT NULL,
+    schedule_date DATE NOT NULL,
+    to_staff_id UUID REFERENCES nurse_shift.department_staff(id) ON DELETE CASCADE,
+    counter_shift_id UUID, -- NULL when the colleague takes the shift outright
+    counter_date DATE,
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open | proposed | approved | rejected | cancelled
+    notes TEXT,
+    requested_by UUID,
+    decided_by UUID,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    decision_note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_swap_events (
+    id UUID PRIMARY KEY,
+    swap_id UUID NOT NULL REFERENCES nurse_shift.schedule_swaps(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL, -- offered | proposed | approved | rejected | cancelled
+    actor_id UUID,
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_swaps_dept_status
+    ON nurse_shift.schedule_swaps (department_id, status, schedule_date);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_swap_events_swap
+    ON nurse_shift.schedule_swap_events (swap_id, created_at);
+
+COMMENT ON TABLE nurse_shift.schedule_swaps IS 'คำขอแลกเวรระหว่างบุคลากร';
+COMMENT ON TABLE nurse_shift.schedule_swap_events IS 'ประวัติการแลกเวร';
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.schedule_swap_events;
+-- DROP TABLE IF EXISTS nurse_shift.schedule_swaps;
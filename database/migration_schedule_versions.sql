@@ -0,0 +1,57 @@
+-- Migration Script: Schedule Versions
+-- Version: 1.3.0
+-- Date: 2026-10-16
+-- Description: Draft / under review / published / archived versions of a department's monthly
+--              schedule. Every generation creates a new draft instead of wiping manual edits;
+--              staff only see the published version. Snapshots, swaps and repairs read
+--              schedules.staff_id, which the generators otherwise add only on their first run.
+
+ALTER TABLE nurse_shift.schedules ADD COLUMN IF NOT EXISTS staff_id UUID REFERENCES nurse_shift.department_staff(id);
+ALTER TABLE nurse_shift.schedules ALTER COLUMN user_id DROP NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_schedules_staff_date_shift ON nurse_shift.schedules (staff_id, schedule_date, shift_id);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_versions (
+    id UUID PRIMARY KEY,
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    month VARCHAR(7) NOT NULL, -- YYYY-MM
+    version_no INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft', -- draft | under_review | published | archived
+    source VARCHAR(50) NOT NULL, -- manual | restore | enhanced | ai | optimizer:<solver>
+    generation_run_id UUID,
+    restored_from UUID,
+    notes TEXT,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    published_by UUID,
+    published_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (department_id, month, version_no)
+);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_version_assignments (
+    version_id UUID NOT NULL REFERENCES nurse_shift.schedule_versions(id) ON DELETE CASCADE,
+    staff_id UUID,
+    user_id UUID,
+    shift_id UUID NOT NULL,
+    schedule_date DATE NOT NULL,
+    status VARCHAR(20) DEFAULT 'assigned',
+    notes TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_versions_dept_month
+    ON nurse_shift.schedule_versions (department_id, month, version_no DESC);
+
+-- at most one published version per department and month
+CREATE UNIQUE INDEX IF NOT EXISTS idx_schedule_versions_one_published
+    ON nurse_shift.schedule_versions (department_id, month) WHERE status = 'published';
+
+CREATE INDEX IF NOT EXISTS idx_schedule_version_assignments_version
+    ON nurse_shift.schedule_version_assignments (version_id);
+
+COMMENT ON TABLE nurse_shift.schedule_versions IS 'เวอร์ชันของตารางเวรรายเดือน (ร่าง / รอตรวจสอบ / เผยแพร่ / เก็บถาวร)';
+COMMENT ON TABLE nurse_shift.schedule_version_assignments IS 'สำเนาการจัดเวรของแต่ละเวอร์ชัน';
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.schedule_version_assignments;
+-- DROP TABLE IF EXISTS nurse_shift.schedule_versions;
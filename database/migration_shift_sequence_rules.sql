@@ -0,0 +1,21 @@
+-- Migration Script: Shift Sequence Rules
+-- Version: 1.18.0
+-- Date: 2026-10-16
+-- Description: Per-department minimum rest hours between the end of one shift and the start of the
+--              next, and shift-type transitions (stored as 'from>to', e.g. 'night>morning') that
+--              may not follow each other within a day. Every generator, the repair of rosters after
+--              leave and the manual edit checks (check-overlap) enforce them as hard rules on real
+--              timestamps, across days.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.shift_sequence_rules (
+    department_id UUID PRIMARY KEY REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    min_rest_hours INTEGER NOT NULL DEFAULT 0 CHECK (min_rest_hours >= 0),
+    forbidden_transitions TEXT[] NOT NULL DEFAULT '{}',
+    updated_by UUID,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.shift_sequence_rules;
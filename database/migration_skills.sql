@@ -0,0 +1,41 @@
+-- Migration Script: Skill and Competency Matrix
+-- Version: 1.17.0
+-- Date: 2026-10-16
+-- Description: Departments define skills such as ICU, charge nurse, ACLS and neonatal (skills),
+--              record each staff member's certifications with an optional expiry date
+--              (staff_skills) and set how many holders of a skill every occurrence of a shift needs
+--              (shift_skill_requirements). The optimizer treats requirements as hard coverage
+--              constraints, counts a certification only up to its expiry and flags assignments that
+--              rely on a lapsed one.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.skills (
+    id UUID PRIMARY KEY,
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (department_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.staff_skills (
+    staff_id UUID NOT NULL REFERENCES nurse_shift.department_staff(id) ON DELETE CASCADE,
+    skill_id UUID NOT NULL REFERENCES nurse_shift.skills(id) ON DELETE CASCADE,
+    certified_on DATE,
+    expires_on DATE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (staff_id, skill_id)
+);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.shift_skill_requirements (
+    shift_id UUID NOT NULL REFERENCES nurse_shift.shifts(id) ON DELETE CASCADE,
+    skill_id UUID NOT NULL REFERENCES nurse_shift.skills(id) ON DELETE CASCADE,
+    min_count INTEGER NOT NULL CHECK (min_count > 0),
+    PRIMARY KEY (shift_id, skill_id)
+);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.shift_skill_requirements;
+-- DROP TABLE IF EXISTS nurse_shift.staff_skills;
+-- DROP TABLE IF EXISTS nurse_shift.skills;
@@ -0,0 +1,45 @@
+-- Migration Script: Staff Preferences
+-- Version: 1.16.0
+-- Date: 2026-10-16
+-- Description: Staff state preferred and avoided shift types and preferred weekdays
+--              (staff_preferences) and request days off per month (staff_request_off_days), capped
+--              and closed a number of days before the month by the department's policy
+--              (schedule_preference_policies, default 4 days and 7 days). The optimizer weighs them
+--              as soft constraints at the weight of the "วันที่ขอหยุด" priority and reports a
+--              satisfaction score per staff member.
+
+CREATE TABLE IF NOT EXISTS nurse_shift.staff_preferences (
+    staff_id UUID PRIMARY KEY REFERENCES nurse_shift.department_staff(id) ON DELETE CASCADE,
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    preferred_shift_types TEXT[] NOT NULL DEFAULT '{}',
+    avoided_shift_types TEXT[] NOT NULL DEFAULT '{}',
+    preferred_weekdays SMALLINT[] NOT NULL DEFAULT '{}',
+    updated_by UUID,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.staff_request_off_days (
+    staff_id UUID NOT NULL REFERENCES nurse_shift.department_staff(id) ON DELETE CASCADE,
+    department_id UUID NOT NULL REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    request_date DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (staff_id, request_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_staff_request_off_days_dept_date
+    ON nurse_shift.staff_request_off_days (department_id, request_date);
+
+CREATE TABLE IF NOT EXISTS nurse_shift.schedule_preference_policies (
+    department_id UUID PRIMARY KEY REFERENCES nurse_shift.departments(id) ON DELETE CASCADE,
+    max_requests_off INTEGER NOT NULL DEFAULT 4 CHECK (max_requests_off >= 0),
+    cutoff_days INTEGER NOT NULL DEFAULT 7 CHECK (cutoff_days >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- DROP TABLE IF EXISTS nurse_shift.schedule_preference_policies;
+-- DROP INDEX IF EXISTS nurse_shift.idx_staff_request_off_days_dept_date;
+-- DROP TABLE IF EXISTS nurse_shift.staff_request_off_days;
+-- DROP TABLE IF EXISTS nurse_shift.staff_preferences;
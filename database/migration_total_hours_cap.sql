@@ -0,0 +1,21 @@
+-- Migration Script: Total Working Hours Cap
+-- Version: 1.20.0
+-- Date: 2026-10-16
+-- Description: The "จำนวนชั่วโมงการทำงานทั้งหมด" priority is a cap on the hours one staff member
+--              works in the rostered month (config.value, default 192) rather than an allowed
+--              spread between staff. The spread stored so far in config.value moves to
+--              config.maxDifference, which the optimizer keeps balancing as a separate soft rule.
+
+UPDATE nurse_shift.scheduling_priorities
+SET config = (config - 'value') || jsonb_build_object('maxDifference', config->'value'),
+    updated_at = NOW()
+WHERE name = 'จำนวนชั่วโมงการทำงานทั้งหมด'
+  AND config ? 'value'
+  AND NOT config ? 'maxDifference';
+
+-- ===================================
+-- ROLLBACK
+-- ===================================
+-- UPDATE nurse_shift.scheduling_priorities
+-- SET config = (config - 'maxDifference' - 'value') || jsonb_build_object('value', config->'maxDifference')
+-- WHERE name = 'จำนวนชั่วโมงการทำงานทั้งหมด' AND config ? 'maxDifference';
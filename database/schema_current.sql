@@ -24,7 +24,7 @@ CREATE TYPE package_type AS ENUM ('standard', 'enterprise', 'trial');
 CREATE TYPE notification_type AS ENUM ('schedule', 'leave', 'system', 'payment', 'reminder', 'holiday');
 CREATE TYPE notification_priority AS ENUM ('low', 'medium', 'high');
 -- NEW: Department role enum for staff in departments
-CREATE TYPE department_role AS ENUM ('nurse', 'assistant');
+CREATE TYPE department_role AS ENUM ('head_nurse', 'nurse', 'assistant', 'viewer');
 
 -- ===================================
 -- CORE TABLES
@@ -401,6 +401,6 @@ COMMENT ON TABLE scheduling_priorities IS 'ความสำคัญในก
 COMMENT ON TABLE user_sessions IS 'เซสชันผู้ใช้';
 COMMENT ON TABLE organizations IS 'องค์กร (สำหรับ multi-tenant)';
 
-COMMENT ON COLUMN department_users.department_role IS 'Role ของผู้ใช้ในแผนก: nurse (พยาบาล) หรือ assistant (ผู้ช่วยพยาบาล)';
+COMMENT ON COLUMN department_users.department_role IS 'Role ของผู้ใช้ในแผนก: head_nurse (หัวหน้าพยาบาล), nurse (พยาบาล), assistant (ผู้ช่วยพยาบาล) หรือ viewer (ดูได้อย่างเดียว)';
 COMMENT ON COLUMN users.role IS 'Role ในระบบ: admin หรือ user';
 COMMENT ON COLUMN users.status IS 'สถานะผู้ใช้: active, inactive, pending, suspended';
@@ -25,7 +25,7 @@ const prioritySettings = {
   maxConsecutiveNightShifts: { min: 1, max: 5, step: 1 },
   maxConsecutiveShifts: { min: 1, max: 10, step: 1 },
   maxConsecutiveWorkHours: { min: 12, max: 72, step: 6 },
-  maxTotalWorkHoursDifference: { min: 8, max: 80, step: 2 },
+  maxTotalWorkHours: { min: 40, max: 320, step: 8 },
 }
 
 export default function PrioritiesPage() {
@@ -0,0 +1,80 @@
+#!/bin/bash
+
+# Shared Package Sync
+# Usage: ./scripts/sync-shared.sh [--check]
+#
+# Code used by several services lives once in backend/shared. Every service is built on its own
+# from its directory (Dockerfile COPY . .), so instead of importing the shared module each service
+# gets a generated copy under internal/infrastructure/<package>. Edit backend/shared, then run this
+# script; --check only reports copies that are out of date and exits 1, so it can run before a
+# commit or a deploy.
+
+set -euo pipefail
+
+ROOT="$(cd "$(dirname "$0")/.." && pwd)"
+SHARED="$ROOT/backend/shared"
+
+# package: services that get a copy
+PACKAGES=(
+    "access: department-service employee-leave-service priority-service schedule-service setting-service"
+    "audit: department-service employee-leave-service payment-service priority-service schedule-service setting-service"
+    "entitlements: department-service package-service schedule-service"
+    "eventbus: employee-leave-service notification-service payment-service schedule-service"
+    "holiday: schedule-service setting-service"
+)
+
+CHECK=false
+if [ "${1:-}" = "--check" ]; then
+    CHECK=true
+fi
+
+header() {
+    echo "// Code generated by scripts/sync-shared.sh from backend/shared/$1. DO NOT EDIT."
+    echo
+}
+
+generated() {
+    head -n 1 "$1" | grep -q "^// Code generated by scripts/sync-shared.sh"
+}
+
+stale=0
+for entry in "${PACKAGES[@]}"; do
+    pkg="${entry%%:*}"
+    for service in ${entry#*:}; do
+        dest="$ROOT/backend/$service/internal/infrastructure/$pkg"
+        rel="backend/$service/internal/infrastructure/$pkg"
+        mkdir -p "$dest"
+        # copies of files that were removed from backend/shared
+        for file in "$dest"/*.go; do
+            [ -e "$file" ] || continue
+            if generated "$file" && [ ! -e "$SHARED/$pkg/$(basename "$file")" ]; then
+                if $CHECK; then
+                    echo "❌ $rel/$(basename "$file") has no source in backend/shared/$pkg"
+                    stale=1
+                else
+                    rm "$file"
+                fi
+            fi
+        done
+        for src in "$SHARED/$pkg"/*.go; do
+            case "$src" in *_test.go) continue ;; esac
+            name="$(basename "$src")"
+            want="$(header "$pkg/$name"; cat "$src")"
+            if [ -e "$dest/$name" ] && [ "$(cat "$dest/$name")" = "$want" ]; then
+                continue
+            fi
+            if $CHECK; then
+                echo "❌ $rel/$name is out of date with backend/shared/$pkg/$name"
+                stale=1
+            else
+                printf '%s\n' "$want" > "$dest/$name"
+                echo "✅ $rel/$name"
+            fi
+        done
+    done
+done
+
+if [ "$stale" -ne 0 ]; then
+    echo "Run ./scripts/sync-shared.sh to regenerate the copies."
+    exit 1
+fi